@@ -0,0 +1,302 @@
+/*
+Package assets loads textures, models, and shaders in the background.
+
+A Manager reads and decodes files from a file system on worker goroutines,
+then creates their GL resources through a gfx.Queue, which the render loop
+flushes on the GL thread. Assets are cached by path, so loading a path again
+returns the same asset.
+
+	q := new(gfx.Queue)
+	m := assets.NewManager(os.DirFS("data"), q)
+	tex := m.Texture("grass.png", nil)
+	for {
+		q.Flush()
+		if s, ok := tex.Value().(*gfx.Sampler2D); ok {
+			// draw with s
+		}
+	}
+
+In development, Watch reloads assets whose files change, replacing their
+values and running their OnLoad callbacks again.
+*/
+package assets
+
+import (
+	"io/fs"
+	"j4k.co/gfx"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Loader reads and decodes the files of an asset from fsys, on a worker
+// goroutine, and returns the function that creates its GL resources, which
+// runs on the GL thread.
+type Loader func(fsys fs.FS, paths []string) (create func() (interface{}, error), err error)
+
+// Manager loads and caches assets.
+type Manager struct {
+	fsys  fs.FS
+	queue *gfx.Queue
+	sem   chan struct{} // limits the number of files decoded at once
+
+	mu     sync.Mutex
+	assets map[string]*Asset
+	stop   chan struct{}
+}
+
+// NewManager returns a manager loading files from fsys, whose GL resources
+// are created when q is flushed.
+func NewManager(fsys fs.FS, q *gfx.Queue) *Manager {
+	return &Manager{
+		fsys:   fsys,
+		queue:  q,
+		sem:    make(chan struct{}, runtime.NumCPU()),
+		assets: make(map[string]*Asset),
+	}
+}
+
+// Asset is a resource loaded by a Manager. Its value is nil until it has
+// loaded, and is replaced when it is reloaded.
+type Asset struct {
+	paths []string
+	load  Loader
+	m     *Manager
+	done  chan struct{}
+
+	mu        sync.Mutex
+	value     interface{}
+	err       error
+	loaded    bool
+	loading   bool
+	modTimes  []time.Time
+	callbacks []func(*Asset)
+}
+
+// Paths returns the paths of the files the asset was loaded from.
+func (a *Asset) Paths() []string {
+	return a.paths
+}
+
+// Done returns a channel that is closed once the asset has first finished
+// loading, successfully or not.
+func (a *Asset) Done() <-chan struct{} {
+	return a.done
+}
+
+// Wait blocks until the asset has first finished loading and returns its
+// value. It must not be called on the GL thread, which has to flush the
+// queue for the asset to finish.
+func (a *Asset) Wait() (interface{}, error) {
+	<-a.done
+	return a.Value(), a.Err()
+}
+
+// Value returns the value of the asset, or nil if it has not loaded.
+func (a *Asset) Value() interface{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.value
+}
+
+// Err returns the error of the last load, if it failed. A failed reload
+// keeps the previous value.
+func (a *Asset) Err() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
+
+// OnLoad registers fn to be called on the GL thread, while the queue is
+// flushed, each time the asset loads or reloads successfully. If it has
+// already loaded, fn is also called in the next flush.
+func (a *Asset) OnLoad(fn func(*Asset)) {
+	a.mu.Lock()
+	a.callbacks = append(a.callbacks, fn)
+	loaded := a.loaded && a.err == nil
+	a.mu.Unlock()
+	if loaded {
+		a.m.queue.Do(func() { fn(a) })
+	}
+}
+
+func key(paths []string) string {
+	return strings.Join(paths, "\n")
+}
+
+// Load returns the asset loaded from paths by load, starting to load it
+// if it is not cached.
+func (m *Manager) Load(load Loader, paths ...string) *Asset {
+	k := key(paths)
+	m.mu.Lock()
+	a, ok := m.assets[k]
+	if !ok {
+		a = &Asset{
+			paths: append([]string(nil), paths...),
+			load:  load,
+			m:     m,
+			done:  make(chan struct{}),
+		}
+		m.assets[k] = a
+	}
+	m.mu.Unlock()
+	if !ok {
+		m.start(a)
+	}
+	return a
+}
+
+// Get returns the cached asset of the given paths, if it has been requested.
+func (m *Manager) Get(paths ...string) (*Asset, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a, ok := m.assets[key(paths)]
+	return a, ok
+}
+
+// Reload loads the cached asset of the given paths again, unless it is
+// already loading.
+func (m *Manager) Reload(paths ...string) {
+	if a, ok := m.Get(paths...); ok {
+		m.start(a)
+	}
+}
+
+// start loads a on a worker goroutine.
+func (m *Manager) start(a *Asset) {
+	a.mu.Lock()
+	if a.loading {
+		a.mu.Unlock()
+		return
+	}
+	a.loading = true
+	a.mu.Unlock()
+	go func() {
+		m.sem <- struct{}{}
+		modTimes := m.modTimes(a.paths)
+		create, err := a.load(m.fsys, a.paths)
+		<-m.sem
+		m.queue.Do(func() {
+			var v interface{}
+			if err == nil {
+				v, err = create()
+			}
+			m.finish(a, v, err, modTimes)
+		})
+	}()
+}
+
+// finish stores the result of loading a, on the GL thread.
+func (m *Manager) finish(a *Asset, v interface{}, err error, modTimes []time.Time) {
+	a.mu.Lock()
+	old := a.value
+	if err == nil {
+		a.value = v
+	}
+	a.err = err
+	a.modTimes = modTimes
+	first := !a.loaded
+	a.loaded, a.loading = true, false
+	callbacks := make([]func(*Asset), len(a.callbacks))
+	copy(callbacks, a.callbacks)
+	a.mu.Unlock()
+
+	if first {
+		close(a.done)
+	}
+	if err != nil {
+		return
+	}
+	if d, ok := old.(gfx.Deleter); ok {
+		d.Delete()
+	}
+	for _, fn := range callbacks {
+		fn(a)
+	}
+}
+
+// modTimes returns the modification times of the named files, with zero
+// times for those that cannot be stat'ed.
+func (m *Manager) modTimes(paths []string) []time.Time {
+	times := make([]time.Time, len(paths))
+	for i, p := range paths {
+		if fi, err := fs.Stat(m.fsys, p); err == nil {
+			times[i] = fi.ModTime()
+		}
+	}
+	return times
+}
+
+// Watch checks the files of loaded assets for changes every interval, and
+// reloads the assets whose files have changed, until Close is called. It is
+// meant for development, to see edits without restarting.
+func (m *Manager) Watch(interval time.Duration) {
+	m.mu.Lock()
+	if m.stop != nil {
+		m.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	m.stop = stop
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				m.reloadChanged()
+			}
+		}
+	}()
+}
+
+func (m *Manager) reloadChanged() {
+	m.mu.Lock()
+	assets := make([]*Asset, 0, len(m.assets))
+	for _, a := range m.assets {
+		assets = append(assets, a)
+	}
+	m.mu.Unlock()
+
+	for _, a := range assets {
+		a.mu.Lock()
+		if !a.loaded || a.loading {
+			a.mu.Unlock()
+			continue
+		}
+		last := a.modTimes
+		a.mu.Unlock()
+		for i, t := range m.modTimes(a.paths) {
+			if !t.Equal(last[i]) {
+				m.start(a)
+				break
+			}
+		}
+	}
+}
+
+// Close stops watching for changes and empties the cache, queueing the
+// deletion of the values of the assets.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+	assets := m.assets
+	m.assets = make(map[string]*Asset)
+	m.mu.Unlock()
+
+	m.queue.Do(func() {
+		for _, a := range assets {
+			if d, ok := a.Value().(gfx.Deleter); ok {
+				d.Delete()
+			}
+		}
+	})
+}
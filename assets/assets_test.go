@@ -0,0 +1,121 @@
+package assets_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	imagepng "image/png"
+	"j4k.co/gfx"
+	"j4k.co/gfx/assets"
+	"j4k.co/gfx/gfxtest"
+	"j4k.co/gfx/terrain"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+const vertSrc = `
+attribute vec3 Position;
+
+void main() {
+	gl_Position = vec4(Position, 1.0);
+}`
+
+const fragSrc = `
+uniform vec4 Color;
+
+void main() {
+	gl_FragColor = Color;
+}`
+
+// flush flushes q until a has loaded or a second has passed.
+func flush(t *testing.T, q *gfx.Queue, a *assets.Asset) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		q.Flush()
+		select {
+		case <-a.Done():
+			return
+		default:
+			time.Sleep(time.Millisecond)
+		}
+	}
+	t.Fatal("timed out loading", a.Paths())
+}
+
+func TestShaderAsset(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	fsys := fstest.MapFS{
+		"basic.vert": {Data: []byte(vertSrc)},
+		"basic.frag": {Data: []byte(fragSrc)},
+	}
+	q := new(gfx.Queue)
+	m := assets.NewManager(fsys, q)
+	defer m.Close()
+
+	a := m.Shader(gfx.DefaultVertexAttributes, "basic.vert", "basic.frag")
+	if b := m.Shader(gfx.DefaultVertexAttributes, "basic.vert", "basic.frag"); b != a {
+		t.Fatal("loading the same paths again gave a different asset")
+	}
+	loads := 0
+	a.OnLoad(func(*assets.Asset) { loads++ })
+	flush(t, q, a)
+	s, ok := a.Value().(*gfx.Shader)
+	if !ok || a.Err() != nil {
+		t.Fatalf("got %v, %v; want a shader", a.Value(), a.Err())
+	}
+	if !s.HasUniform("Color") {
+		t.Error("the shader has no Color uniform")
+	}
+	if loads != 1 {
+		t.Errorf("OnLoad called %d times, want 1", loads)
+	}
+
+	m.Reload("basic.vert", "basic.frag")
+	deadline := time.Now().Add(time.Second)
+	for loads < 2 && time.Now().Before(deadline) {
+		q.Flush()
+		time.Sleep(time.Millisecond)
+	}
+	if loads != 2 || a.Value() == s {
+		t.Errorf("after reloading, OnLoad called %d times and shader replaced %v", loads, a.Value() != s)
+	}
+}
+
+func TestMissingAsset(t *testing.T) {
+	q := new(gfx.Queue)
+	m := assets.NewManager(fstest.MapFS{}, q)
+	a := m.Texture("missing.png", nil)
+	flush(t, q, a)
+	if a.Value() != nil || a.Err() == nil {
+		t.Errorf("got %v, %v; want an error", a.Value(), a.Err())
+	}
+}
+
+func TestTerrainAsset(t *testing.T) {
+	img := image.NewGray16(image.Rect(0, 0, 33, 17))
+	img.SetGray16(4, 4, color.Gray16{0xffff})
+	var png bytes.Buffer
+	if err := imagepng.Encode(&png, img); err != nil {
+		t.Fatal(err)
+	}
+	q := new(gfx.Queue)
+	m := assets.NewManager(fstest.MapFS{"height.png": {Data: png.Bytes()}}, q)
+	defer m.Close()
+
+	a := m.Terrain("height.png", terrain.Config{ChunkCells: 16, HeightScale: 10})
+	flush(t, q, a)
+	tr, ok := a.Value().(*terrain.Terrain)
+	if !ok || a.Err() != nil {
+		t.Fatalf("got %v, %v; want a terrain", a.Value(), a.Err())
+	}
+	chunks := tr.Select(nil, [3]float32{1e6, 0, 1e6}, nil)
+	if len(chunks) != 1 {
+		t.Fatalf("got %d chunks far away, want the root", len(chunks))
+	}
+	if _, max := chunks[0].Bounds(); max != [3]float32{32, 10, 32} {
+		t.Errorf("got bounds up to %v, want the peak and the chunk's size", max)
+	}
+}
@@ -0,0 +1,185 @@
+package assets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/fs"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry/obj"
+	"j4k.co/gfx/terrain"
+	"j4k.co/gfx/texfile"
+	"path"
+	"strings"
+)
+
+// TextureLoader loads a texture from a KTX, KTX2, or DDS file, or from an
+// image in a format registered with the image package, such as PNG or
+// JPEG. The value of the asset is a *gfx.Sampler2D.
+func TextureLoader(opts *gfx.SamplerOptions) Loader {
+	return func(fsys fs.FS, paths []string) (func() (interface{}, error), error) {
+		if len(paths) != 1 {
+			return nil, errors.New("assets: a texture is loaded from one file")
+		}
+		data, err := fs.ReadFile(fsys, paths[0])
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(path.Ext(paths[0])) {
+		case ".ktx", ".ktx2", ".dds":
+			tex, err := texfile.Decode(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			return func() (interface{}, error) {
+				return tex.Sampler2D(opts)
+			}, nil
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("assets: %s: %v", paths[0], err)
+		}
+		img = uploadable(img)
+		return func() (interface{}, error) {
+			return gfx.Image(img, opts)
+		}, nil
+	}
+}
+
+// uploadable converts img to a type gfx.Image uploads without converting,
+// so that the conversion happens on the worker.
+func uploadable(img image.Image) image.Image {
+	switch img.(type) {
+	case *image.NRGBA, *image.RGBA, *image.Alpha, *image.Gray, *image.Gray16:
+		return img
+	}
+	nrgba := image.NewNRGBA(img.Bounds())
+	draw.Draw(nrgba, nrgba.Rect, img, img.Bounds().Min, draw.Src)
+	return nrgba
+}
+
+// Model is a loaded OBJ model, with the geometry of each mesh.
+type Model struct {
+	Meshes    []ModelMesh
+	Materials map[string]*obj.Material
+}
+
+// ModelMesh is the geometry of a model that uses a single material.
+type ModelMesh struct {
+	Material string
+	Geometry *gfx.Geometry
+}
+
+// Delete deletes the geometry of the model.
+func (m *Model) Delete() {
+	for _, mesh := range m.Meshes {
+		mesh.Geometry.Delete()
+	}
+}
+
+// ModelLoader loads a Wavefront OBJ model and its material libraries, with
+// vertices of the given format. The value of the asset is a *Model.
+func ModelLoader(vf gfx.VertexFormat) Loader {
+	return func(fsys fs.FS, paths []string) (func() (interface{}, error), error) {
+		if len(paths) != 1 {
+			return nil, errors.New("assets: a model is loaded from one file")
+		}
+		model, err := obj.LoadFS(fsys, paths[0], vf)
+		if err != nil {
+			return nil, err
+		}
+		return func() (interface{}, error) {
+			m := &Model{Materials: model.Materials}
+			for _, mesh := range model.Meshes {
+				geom, err := gfx.NewGeometry(mesh, gfx.StaticDraw)
+				if err != nil {
+					m.Delete()
+					return nil, err
+				}
+				m.Meshes = append(m.Meshes, ModelMesh{mesh.Material, geom})
+			}
+			return m, nil
+		}, nil
+	}
+}
+
+// ShaderLoader loads a shader from a source file for each stage, whose
+// stages are given by their extensions: .vert, .frag, .tesc, and .tese.
+// The value of the asset is a *gfx.Shader.
+func ShaderLoader(attrs gfx.VertexAttributes) Loader {
+	return func(fsys fs.FS, paths []string) (func() (interface{}, error), error) {
+		srcs := make([]gfx.ShaderSource, len(paths))
+		for i, p := range paths {
+			data, err := fs.ReadFile(fsys, p)
+			if err != nil {
+				return nil, err
+			}
+			switch path.Ext(p) {
+			case ".vert":
+				srcs[i] = gfx.VertexShader(data)
+			case ".frag":
+				srcs[i] = gfx.FragmentShader(data)
+			case ".tesc":
+				srcs[i] = gfx.TessControlShader(data)
+			case ".tese":
+				srcs[i] = gfx.TessEvalShader(data)
+			default:
+				return nil, fmt.Errorf("assets: unknown shader stage of %s", p)
+			}
+		}
+		return func() (interface{}, error) {
+			return gfx.BuildShader(attrs, srcs...)
+		}, nil
+	}
+}
+
+// TerrainLoader loads a terrain from a heightmap image in a format
+// registered with the image package, such as a 16-bit grayscale PNG. The
+// value of the asset is a *terrain.Terrain, whose chunk geometry is built
+// as it is drawn.
+func TerrainLoader(conf terrain.Config) Loader {
+	return func(fsys fs.FS, paths []string) (func() (interface{}, error), error) {
+		if len(paths) != 1 {
+			return nil, errors.New("assets: a terrain is loaded from one heightmap")
+		}
+		data, err := fs.ReadFile(fsys, paths[0])
+		if err != nil {
+			return nil, err
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("assets: %s: %v", paths[0], err)
+		}
+		t, err := terrain.New(terrain.HeightmapFromImage(img), conf)
+		if err != nil {
+			return nil, err
+		}
+		return func() (interface{}, error) {
+			return t, nil
+		}, nil
+	}
+}
+
+// Texture loads the texture at path with TextureLoader.
+func (m *Manager) Texture(path string, opts *gfx.SamplerOptions) *Asset {
+	return m.Load(TextureLoader(opts), path)
+}
+
+// Model loads the OBJ model at path with ModelLoader.
+func (m *Manager) Model(path string, vf gfx.VertexFormat) *Asset {
+	return m.Load(ModelLoader(vf), path)
+}
+
+// Shader loads a shader from the source files at paths with ShaderLoader.
+func (m *Manager) Shader(attrs gfx.VertexAttributes, paths ...string) *Asset {
+	return m.Load(ShaderLoader(attrs), paths...)
+}
+
+// Terrain loads the terrain with the heightmap at path with TerrainLoader.
+func (m *Manager) Terrain(path string, conf terrain.Config) *Asset {
+	return m.Load(TerrainLoader(conf), path)
+}
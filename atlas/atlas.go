@@ -0,0 +1,215 @@
+package atlas
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"j4k.co/gfx"
+	"sort"
+)
+
+// ErrTooLarge is returned when the images do not fit in the atlas.
+var ErrTooLarge = errors.New("atlas: images do not fit")
+
+// Options control packing. The zero value packs into a 2048 pixel wide
+// atlas without padding.
+type Options struct {
+	// Width is the width of the atlas, and the most its height may grow
+	// to. It defaults to 2048.
+	Width int
+
+	// Padding is the number of transparent pixels left around each entry.
+	Padding int
+
+	// Extrude repeats the edge pixels of each entry outward by this many
+	// pixels, within the padding, so filtering at the edges of an entry
+	// doesn't blend in its neighbors. It must not exceed Padding.
+	Extrude int
+}
+
+// Entry is an image placed in the atlas.
+type Entry struct {
+	// Bounds is where the image lies in the atlas, excluding padding.
+	Bounds image.Rectangle
+
+	// UV is the texture rectangle u0, v0, u1, v1 of Bounds, as used by
+	// sprite.Sprite.
+	UV [4]float32
+}
+
+// Atlas is the result of packing.
+type Atlas struct {
+	Image   *image.NRGBA
+	Entries []Entry // in the order the images were given
+}
+
+// Pack places imgs into a single image with a skyline bottom-left packer.
+// The atlas height is rounded up to a power of two.
+func Pack(imgs []image.Image, opts Options) (*Atlas, error) {
+	if opts.Width <= 0 {
+		opts.Width = 2048
+	}
+	if opts.Extrude > opts.Padding {
+		opts.Extrude = opts.Padding
+	}
+	// place tall images first, which packs more tightly
+	order := make([]int, len(imgs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Stable(byHeight{order, imgs})
+
+	sky := skyline{{x: 0, y: 0, w: opts.Width}}
+	places := make([]image.Point, len(imgs))
+	height := 0
+	for _, i := range order {
+		size := imgs[i].Bounds().Size()
+		w, h := size.X+2*opts.Padding, size.Y+2*opts.Padding
+		p, ok := sky.insert(w, h, opts.Width)
+		if !ok {
+			return nil, ErrTooLarge
+		}
+		places[i] = p.Add(image.Pt(opts.Padding, opts.Padding))
+		if p.Y+h > height {
+			height = p.Y + h
+		}
+	}
+	if height > opts.Width {
+		return nil, ErrTooLarge
+	}
+	pow := 1
+	for pow < height {
+		pow <<= 1
+	}
+
+	a := &Atlas{
+		Image:   image.NewNRGBA(image.Rect(0, 0, opts.Width, pow)),
+		Entries: make([]Entry, len(imgs)),
+	}
+	for i, img := range imgs {
+		r := image.Rectangle{places[i], places[i].Add(img.Bounds().Size())}
+		draw.Draw(a.Image, r, img, img.Bounds().Min, draw.Src)
+		if opts.Extrude > 0 {
+			extrude(a.Image, r, opts.Extrude)
+		}
+		a.Entries[i] = Entry{
+			Bounds: r,
+			UV: [4]float32{
+				float32(r.Min.X) / float32(opts.Width),
+				float32(r.Min.Y) / float32(pow),
+				float32(r.Max.X) / float32(opts.Width),
+				float32(r.Max.Y) / float32(pow),
+			},
+		}
+	}
+	return a, nil
+}
+
+// Texture uploads the atlas image. If opts is nil, the zero
+// gfx.SamplerOptions are used.
+func (a *Atlas) Texture(opts *gfx.SamplerOptions) (*gfx.Sampler2D, error) {
+	return gfx.Image(a.Image, opts)
+}
+
+// extrude copies the edge pixels of r outward by n pixels.
+func extrude(img *image.NRGBA, r image.Rectangle, n int) {
+	for i := 1; i <= n; i++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetNRGBA(x, r.Min.Y-i, img.NRGBAAt(x, r.Min.Y))
+			img.SetNRGBA(x, r.Max.Y-1+i, img.NRGBAAt(x, r.Max.Y-1))
+		}
+	}
+	// columns include the extruded rows, which fills the corners
+	for i := 1; i <= n; i++ {
+		for y := r.Min.Y - n; y < r.Max.Y+n; y++ {
+			img.SetNRGBA(r.Min.X-i, y, img.NRGBAAt(r.Min.X, y))
+			img.SetNRGBA(r.Max.X-1+i, y, img.NRGBAAt(r.Max.X-1, y))
+		}
+	}
+}
+
+// byHeight sorts image indices by descending image height.
+type byHeight struct {
+	order []int
+	imgs  []image.Image
+}
+
+func (b byHeight) Len() int      { return len(b.order) }
+func (b byHeight) Swap(i, j int) { b.order[i], b.order[j] = b.order[j], b.order[i] }
+func (b byHeight) Less(i, j int) bool {
+	return b.imgs[b.order[i]].Bounds().Dy() > b.imgs[b.order[j]].Bounds().Dy()
+}
+
+// skyline is the top edge of the packed area, as segments ordered by x.
+type skyline []segment
+
+type segment struct {
+	x, y, w int
+}
+
+// insert finds the lowest position, then leftmost, for a w by h rectangle
+// and raises the skyline over it.
+func (s *skyline) insert(w, h, width int) (image.Point, bool) {
+	best, bestY := -1, 0
+	for i, seg := range *s {
+		if seg.x+w > width {
+			break
+		}
+		y, ok := s.fit(i, w)
+		if ok && (best < 0 || y < bestY) {
+			best, bestY = i, y
+		}
+	}
+	if best < 0 {
+		return image.Point{}, false
+	}
+	p := image.Pt((*s)[best].x, bestY)
+	s.raise(best, p.X, w, bestY+h)
+	return p, true
+}
+
+// fit returns the height at which a rectangle w wide starting at segment i
+// rests.
+func (s skyline) fit(i, w int) (y int, ok bool) {
+	x := s[i].x
+	for remaining := w; remaining > 0; i++ {
+		if i == len(s) {
+			return 0, false
+		}
+		if s[i].y > y {
+			y = s[i].y
+		}
+		remaining -= s[i].w - (x - s[i].x)
+		x = s[i].x + s[i].w
+	}
+	return y, true
+}
+
+// raise replaces the skyline from x to x+w with a segment at height y,
+// starting at segment i.
+func (s *skyline) raise(i, x, w, y int) {
+	end := x + w
+	var out skyline
+	out = append(out, (*s)[:i]...)
+	out = append(out, segment{x, y, w})
+	for _, seg := range (*s)[i:] {
+		if segEnd := seg.x + seg.w; segEnd > end {
+			if seg.x < end {
+				seg.w = segEnd - end
+				seg.x = end
+			}
+			out = append(out, seg)
+		}
+	}
+	// merge neighbors at the same height
+	merged := out[:1]
+	for _, seg := range out[1:] {
+		last := &merged[len(merged)-1]
+		if last.y == seg.y {
+			last.w += seg.w
+		} else {
+			merged = append(merged, seg)
+		}
+	}
+	*s = merged
+}
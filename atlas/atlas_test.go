@@ -0,0 +1,67 @@
+package atlas_test
+
+import (
+	"image"
+	"image/color"
+	"j4k.co/gfx/atlas"
+	"testing"
+)
+
+func solid(w, h int, c color.NRGBA) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return img
+}
+
+func TestPack(t *testing.T) {
+	var imgs []image.Image
+	for i := 0; i < 40; i++ {
+		imgs = append(imgs, solid(5+i*7%23, 3+i*11%29, color.NRGBA{uint8(i), 0, 0, 255}))
+	}
+	a, err := atlas.Pack(imgs, atlas.Options{Width: 128, Padding: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bounds := a.Image.Bounds()
+	for i, e := range a.Entries {
+		if e.Bounds.Size() != imgs[i].Bounds().Size() {
+			t.Fatalf("entry %d is %v, want %v", i, e.Bounds.Size(), imgs[i].Bounds().Size())
+		}
+		if !e.Bounds.In(bounds) {
+			t.Fatalf("entry %d at %v outside atlas %v", i, e.Bounds, bounds)
+		}
+		for j, f := range a.Entries[:i] {
+			if e.Bounds.Inset(-1).Overlaps(f.Bounds) {
+				t.Fatalf("entries %d and %d overlap: %v, %v", i, j, e.Bounds, f.Bounds)
+			}
+		}
+		if got := a.Image.NRGBAAt(e.Bounds.Min.X, e.Bounds.Min.Y).R; got != uint8(i) {
+			t.Fatalf("entry %d holds pixels of image %d", i, got)
+		}
+	}
+	e := a.Entries[0]
+	if e.UV[0] != float32(e.Bounds.Min.X)/128 || e.UV[3] != float32(e.Bounds.Max.Y)/float32(bounds.Dy()) {
+		t.Errorf("bad UV %v for %v", e.UV, e.Bounds)
+	}
+}
+
+func TestExtrude(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	a, err := atlas.Pack([]image.Image{solid(4, 4, red)}, atlas.Options{Width: 16, Padding: 2, Extrude: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := a.Entries[0].Bounds
+	if c := a.Image.NRGBAAt(r.Min.X-2, r.Min.Y-2); c != red {
+		t.Errorf("got corner %v, want %v", c, red)
+	}
+}
+
+func TestTooLarge(t *testing.T) {
+	imgs := []image.Image{solid(10, 10, color.NRGBA{}), solid(10, 10, color.NRGBA{})}
+	if _, err := atlas.Pack(imgs, atlas.Options{Width: 16}); err != atlas.ErrTooLarge {
+		t.Fatalf("got %v, want ErrTooLarge", err)
+	}
+}
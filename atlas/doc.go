@@ -0,0 +1,5 @@
+/*
+Package atlas packs many images into a single texture, so that sprites and
+UI elements drawn from them need fewer texture binds.
+*/
+package atlas
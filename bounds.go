@@ -0,0 +1,156 @@
+package gfx
+
+import (
+	"math"
+	"unsafe"
+)
+
+// BoundsData is implemented by vertex data that knows the bounding box of
+// its positions. Geometry created or copied from it keeps the box.
+type BoundsData interface {
+	Bounds() (min, max [3]float32)
+}
+
+// SphereData is implemented by BoundsData that also knows a bounding
+// sphere of its positions, tighter than the one enclosing its box.
+type SphereData interface {
+	BoundingSphere() (center [3]float32, radius float32)
+}
+
+// bounds is a bounding box and sphere of vertex positions.
+type bounds struct {
+	min, max [3]float32
+	center   [3]float32
+	radius   float32
+	ok       bool
+}
+
+// boxBounds returns the bounds of the box min, max, with the sphere
+// enclosing it.
+func boxBounds(min, max [3]float32) bounds {
+	b := bounds{min: min, max: max, ok: true}
+	var r2 float32
+	for i := range b.center {
+		b.center[i] = (min[i] + max[i]) / 2
+		d := max[i] - b.center[i]
+		r2 += d * d
+	}
+	b.radius = float32(math.Sqrt(float64(r2)))
+	return b
+}
+
+// measureBounds returns the bounds of the positions of the interleaved
+// vertices in verts. Positions come first in every vertex, so they are
+// read at each multiple of stride.
+func measureBounds(verts []byte, stride int) bounds {
+	n := len(verts) / stride
+	if n == 0 {
+		return bounds{}
+	}
+	pos := func(i int) [3]float32 {
+		return *(*[3]float32)(unsafe.Pointer(&verts[i*stride]))
+	}
+	min, max := pos(0), pos(0)
+	for i := 1; i < n; i++ {
+		for j, v := range pos(i) {
+			if v < min[j] {
+				min[j] = v
+			}
+			if v > max[j] {
+				max[j] = v
+			}
+		}
+	}
+	b := boxBounds(min, max)
+	// the sphere around the box center reaching the furthest position
+	var r2 float32
+	for i := 0; i < n; i++ {
+		var d2 float32
+		for j, v := range pos(i) {
+			d := v - b.center[j]
+			d2 += d * d
+		}
+		if d2 > r2 {
+			r2 = d2
+		}
+	}
+	b.radius = float32(math.Sqrt(float64(r2)))
+	return b
+}
+
+// Frustum holds six clip planes as (a, b, c, d) with normals pointing
+// inward, such that a point p is inside when a*x + b*y + c*z + d >= 0 for
+// every plane.
+type Frustum [6][4]float32
+
+// FrustumFromMatrix extracts the frustum planes of a column-major
+// view-projection matrix.
+func FrustumFromMatrix(m [16]float32) Frustum {
+	row := func(i int) [4]float32 {
+		return [4]float32{m[i], m[4+i], m[8+i], m[12+i]}
+	}
+	r0, r1, r2, r3 := row(0), row(1), row(2), row(3)
+	var f Frustum
+	for i := 0; i < 4; i++ {
+		f[0][i] = r3[i] + r0[i] // left
+		f[1][i] = r3[i] - r0[i] // right
+		f[2][i] = r3[i] + r1[i] // bottom
+		f[3][i] = r3[i] - r1[i] // top
+		f[4][i] = r3[i] + r2[i] // near
+		f[5][i] = r3[i] - r2[i] // far
+	}
+	return f
+}
+
+// ContainsBox reports whether any part of the axis-aligned box lies inside
+// the frustum. It may report true for some boxes just outside a corner.
+func (f *Frustum) ContainsBox(min, max [3]float32) bool {
+	for _, p := range f {
+		// test the box corner furthest along the plane normal
+		x, y, z := min[0], min[1], min[2]
+		if p[0] >= 0 {
+			x = max[0]
+		}
+		if p[1] >= 0 {
+			y = max[1]
+		}
+		if p[2] >= 0 {
+			z = max[2]
+		}
+		if p[0]*x+p[1]*y+p[2]*z+p[3] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainsSphere reports whether any part of the sphere lies inside the
+// frustum. Like ContainsBox, it may report true for some spheres just
+// outside a corner.
+func (f *Frustum) ContainsSphere(center [3]float32, radius float32) bool {
+	for _, p := range f {
+		// the planes are not normalized, so scale the radius instead
+		l := float32(math.Sqrt(float64(p[0]*p[0] + p[1]*p[1] + p[2]*p[2])))
+		if p[0]*center[0]+p[1]*center[1]+p[2]*center[2]+p[3] < -radius*l {
+			return false
+		}
+	}
+	return true
+}
+
+// TransformBounds returns the axis-aligned box enclosing the box min, max
+// after transforming it by the column-major matrix m.
+func TransformBounds(min, max [3]float32, m *[16]float32) (tmin, tmax [3]float32) {
+	for i := 0; i < 3; i++ {
+		tmin[i], tmax[i] = m[12+i], m[12+i]
+		for j := 0; j < 3; j++ {
+			a, b := m[j*4+i]*min[j], m[j*4+i]*max[j]
+			if a > b {
+				a, b = b, a
+			}
+			tmin[i] += a
+			tmax[i] += b
+		}
+	}
+	return tmin, tmax
+}
@@ -0,0 +1,230 @@
+package gfx
+
+import (
+	"errors"
+	"j4k.co/gfx/internal/gl"
+	"strconv"
+	"strings"
+)
+
+// caps holds the capabilities of the context, detected by Init or the first
+// time they are needed.
+var caps struct {
+	known   bool
+	version int
+	es      bool
+
+	vao            bool // vertex array objects
+	mapBuffer      bool // glMapBuffer
+	mapBufferRange bool // glMapBufferRange
+	copyUsage      bool // the *_COPY buffer usages
+	textureRG      bool // the RED and RG texture formats
+	sizedFormats   bool // sized internal formats such as RGBA8
+	restart        bool // primitive restart
+	restartFixed   bool // primitive restart at the largest index
+	sync           bool // fence sync objects
+	instancing     bool // instanced drawing and attribute divisors
+	debugOutput    bool // KHR_debug object labels and debug groups
+	seamlessCube   bool // filtering across cube map faces, which needs enabling
+	feedback       bool // transform feedback
+	pointSize      bool // whether PROGRAM_POINT_SIZE has been enabled
+	programBinary  bool // program binaries in at least one format
+	anisotropy     float32
+	glsl           int
+	maxTexSize     int
+	maxTexUnits    int
+	maxAttribs     int
+}
+
+// Capabilities describes the version, limits and features of the current
+// context, as returned by Caps.
+type Capabilities struct {
+	// Version is the OpenGL or OpenGL ES version as major*10 + minor, such
+	// as 33 for OpenGL 3.3, and GLSLVersion the shading language version
+	// as in a #version directive, such as 330.
+	Version     int
+	GLSLVersion int
+	ES          bool
+
+	MaxTextureSize   int // the largest width or height of a texture
+	MaxTextureUnits  int // the texture units of all shader stages together
+	MaxVertexAttribs int
+
+	// MaxAnisotropy is the largest degree of anisotropic filtering, or 0
+	// without EXT_texture_filter_anisotropic.
+	MaxAnisotropy float32
+
+	Instancing       bool // DrawInstanced and instance attributes
+	VertexArrays     bool // vertex array objects
+	MapBufferRange   bool // MapWrite and StreamWrite without copies
+	PrimitiveRestart bool // Geometry.PrimitiveRestart
+	Sync             bool // fences
+	DebugOutput      bool // object labels and debug groups
+	Feedback         bool // BuildFeedbackShader and DrawFeedback
+}
+
+// Caps returns the capabilities of the current context, detecting them
+// if Init has not.
+func Caps() Capabilities {
+	detectCaps()
+	return Capabilities{
+		Version:          caps.version,
+		GLSLVersion:      caps.glsl,
+		ES:               caps.es,
+		MaxTextureSize:   caps.maxTexSize,
+		MaxTextureUnits:  caps.maxTexUnits,
+		MaxVertexAttribs: caps.maxAttribs,
+		MaxAnisotropy:    caps.anisotropy,
+		Instancing:       caps.instancing,
+		VertexArrays:     caps.vao,
+		MapBufferRange:   caps.mapBufferRange,
+		PrimitiveRestart: caps.restart,
+		Sync:             caps.sync,
+		DebugOutput:      caps.debugOutput,
+		Feedback:         caps.feedback,
+	}
+}
+
+// Init detects the version and extensions of the current context, so that
+// gfx can work around what it lacks, such as on OpenGL ES and ANGLE:
+//
+//   - without vertex array objects, attribute pointers are set each time
+//     geometry is bound
+//   - without buffer mapping, buffers are written with glBufferData and
+//     glBufferSubData from a copy
+//   - the copy usages fall back to the matching draw usages
+//   - PixelR8 textures fall back to LUMINANCE, which shaders read the same
+//     from the red channel
+//
+// Init must be called with the context current, and again whenever a
+// different context is made current. If it is never called, detection
+// happens the first time it is needed.
+func Init() error {
+	caps.known = false
+	restart.enabled, restart.index = false, 0
+	ResetState()
+	detectCaps()
+	if caps.version == 0 {
+		return errors.New("gfx: no current GL context")
+	}
+	return nil
+}
+
+func detectCaps() {
+	if caps.known {
+		return
+	}
+	version, es := glVersion()
+	caps.version, caps.es = version, es
+	if es {
+		caps.vao = version >= 30 || hasExtension("GL_OES_vertex_array_object")
+		caps.mapBuffer = false
+		caps.mapBufferRange = version >= 30
+		caps.copyUsage = version >= 30
+		caps.textureRG = version >= 30 || hasExtension("GL_EXT_texture_rg")
+		caps.sizedFormats = version >= 30
+		caps.restart = version >= 30
+		caps.restartFixed = version >= 30
+		caps.sync = version >= 30
+		caps.instancing = version >= 30 || hasExtension("GL_ANGLE_instanced_arrays")
+		caps.debugOutput = version >= 32 || hasExtension("GL_KHR_debug")
+		caps.feedback = version >= 30
+		caps.programBinary = version >= 30 || hasExtension("GL_OES_get_program_binary")
+	} else {
+		caps.vao = version >= 30 || hasExtension("GL_ARB_vertex_array_object")
+		caps.mapBuffer = true
+		caps.mapBufferRange = version >= 30 || hasExtension("GL_ARB_map_buffer_range")
+		caps.copyUsage = true
+		caps.textureRG = version >= 30 || hasExtension("GL_ARB_texture_rg")
+		caps.sizedFormats = true
+		caps.restart = version >= 31
+		caps.restartFixed = version >= 43
+		caps.sync = version >= 32 || hasExtension("GL_ARB_sync")
+		caps.instancing = version >= 33 || hasExtension("GL_ARB_instanced_arrays")
+		caps.debugOutput = version >= 43 || hasExtension("GL_KHR_debug")
+		caps.seamlessCube = version >= 32 || hasExtension("GL_ARB_seamless_cube_map")
+		caps.feedback = version >= 30
+		caps.programBinary = version >= 41 || hasExtension("GL_ARB_get_program_binary")
+	}
+	// drivers may support program binaries in no format at all
+	caps.programBinary = caps.programBinary && getInteger(gl.NUM_PROGRAM_BINARY_FORMATS) > 0
+	caps.pointSize = false
+	caps.glsl = glslVersion(gl.GetString(gl.SHADING_LANGUAGE_VERSION))
+	caps.maxTexSize = getInteger(gl.MAX_TEXTURE_SIZE)
+	caps.maxTexUnits = getInteger(gl.MAX_COMBINED_TEXTURE_IMAGE_UNITS)
+	caps.maxAttribs = getInteger(gl.MAX_VERTEX_ATTRIBS)
+	caps.anisotropy = 0
+	if hasExtension("GL_EXT_texture_filter_anisotropic") || hasExtension("GL_ARB_texture_filter_anisotropic") {
+		var max [1]float32
+		gl.GetFloatv(gl.MAX_TEXTURE_MAX_ANISOTROPY_EXT, max[:])
+		caps.anisotropy = max[0]
+	}
+	caps.known = true
+	checkError("detect capabilities")
+}
+
+// unbindVertexArray unbinds the current vertex array object, if vertex
+// array objects are supported, so that buffer bindings do not change it.
+func unbindVertexArray() {
+	detectCaps()
+	if caps.vao {
+		bindVertexArray(0)
+	}
+}
+
+// enableProgramPointSize lets vertex shaders set gl_PointSize on desktop
+// contexts, where it is otherwise ignored. OpenGL ES always allows it.
+func enableProgramPointSize() {
+	detectCaps()
+	if !caps.es && !caps.pointSize {
+		gl.Enable(gl.PROGRAM_POINT_SIZE)
+		caps.pointSize = true
+	}
+}
+
+func getInteger(pname gl.GLenum) int {
+	var v [1]int32
+	gl.GetIntegerv(pname, v[:])
+	return int(v[0])
+}
+
+// glVersion returns the version of the context as major*10 + minor, such as
+// 33 for OpenGL 3.3, and whether it is OpenGL ES.
+func glVersion() (version int, es bool) {
+	s := gl.GetString(gl.VERSION)
+	es = strings.HasPrefix(s, "OpenGL ES")
+	for _, f := range strings.Fields(s) {
+		major, minor, _ := strings.Cut(f, ".")
+		n, err := strconv.Atoi(major)
+		if err != nil {
+			continue
+		}
+		m := 0
+		if minor != "" && minor[0] >= '0' && minor[0] <= '9' {
+			m = int(minor[0] - '0')
+		}
+		return n*10 + m, es
+	}
+	return 0, es
+}
+
+// hasExtension reports whether the context supports the named extension.
+func hasExtension(name string) bool {
+	version, es := glVersion()
+	if version >= 30 && !es {
+		var n [1]int32
+		gl.GetIntegerv(gl.NUM_EXTENSIONS, n[:])
+		for i := 0; i < int(n[0]); i++ {
+			if gl.GetStringi(gl.EXTENSIONS, uint(i)) == name {
+				return true
+			}
+		}
+		return false
+	}
+	for _, ext := range strings.Fields(gl.GetString(gl.EXTENSIONS)) {
+		if ext == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package gfx_test
+
+import (
+	"fmt"
+	"image"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+func TestCaps(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	c := gfx.Caps()
+	want := gfx.Capabilities{
+		Version:          33,
+		GLSLVersion:      330,
+		MaxTextureSize:   1024,
+		MaxTextureUnits:  48,
+		MaxVertexAttribs: 16,
+		Instancing:       true,
+		VertexArrays:     true,
+		MapBufferRange:   true,
+		PrimitiveRestart: true,
+		Sync:             true,
+		Feedback:         true,
+	}
+	if c != want {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+}
+
+func TestES(t *testing.T) {
+	for _, c := range []struct {
+		version string
+		want    []string
+	}{
+		// copy usages fall back to draw ones, buffers are written from a
+		// copy, and textures have unsized formats, with LUMINANCE for R8
+		{"OpenGL ES 2.0", []string{
+			"BufferData(ARRAY_BUFFER, 36, [0 bytes], STATIC_DRAW)",
+			"BufferSubData(ARRAY_BUFFER, 0, 36, [36 bytes])",
+			"TexImage2D(TEXTURE_2D, 0, LUMINANCE, 2, 2, 0, LUMINANCE, UNSIGNED_BYTE, [4 bytes])",
+			"TexImage2D(TEXTURE_2D, 0, RGBA, 2, 2, 0, RGBA, UNSIGNED_BYTE, [16 bytes])",
+		}},
+		{"OpenGL ES 3.0", []string{
+			"BufferData(ARRAY_BUFFER, 36, [0 bytes], STATIC_COPY)",
+			"BufferSubData(ARRAY_BUFFER, 0, 36, [36 bytes])",
+			"TexImage2D(TEXTURE_2D, 0, R8, 2, 2, 0, RED, UNSIGNED_BYTE, [4 bytes])",
+			"TexImage2D(TEXTURE_2D, 0, RGBA8, 2, 2, 0, RGBA, UNSIGNED_BYTE, [16 bytes])",
+		}},
+	} {
+		rec := gfxtest.Install()
+		rec.Version = c.version
+		gfx.Init()
+
+		b := geometry.NewBuilder(gfx.VertexPosition)
+		b.Position(0, 0, 0).Position(1, 0, 0).Position(0, 1, 0)
+		geom, err := gfx.NewGeometry(b, gfx.StaticCopy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gray, err := gfx.Image(image.NewGray(image.Rect(0, 0, 2, 2)), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		color, err := gfx.Image(image.NewNRGBA(image.Rect(0, 0, 2, 2)), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, call := range rec.Ops("BufferData", "BufferSubData", "MapBuffer", "MapBufferRange", "TexImage2D") {
+			got = append(got, call.String())
+		}
+		if fmt.Sprint(got) != fmt.Sprint(c.want) {
+			t.Errorf("%s: got %v,\nwant %v", c.version, got, c.want)
+		}
+		color.Delete()
+		gray.Delete()
+		geom.Delete()
+		rec.Uninstall()
+	}
+}
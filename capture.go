@@ -0,0 +1,212 @@
+package gfx
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"j4k.co/gfx/internal/gl"
+	"os"
+	"path/filepath"
+	"unsafe"
+)
+
+// Recorder captures frames from the current framebuffer without stalling
+// the GL pipeline. Each Capture call starts an asynchronous readback into
+// one of two pixel buffer objects and collects the readback started by the
+// previous call, so frames arrive one frame late.
+type Recorder struct {
+	pbos    [2]gl.Buffer
+	pending [2]bool
+	cur     int
+	width   int
+	height  int
+	frames  chan *image.NRGBA
+	dropped int
+}
+
+// NewRecorder creates a Recorder for a width x height region at the origin of
+// the framebuffer. Captured frames are delivered on a channel with room for
+// buffered frames; when it is full, frames are dropped rather than blocking
+// the render loop.
+func NewRecorder(width, height, buffered int) *Recorder {
+	r := &Recorder{
+		width:  width,
+		height: height,
+		frames: make(chan *image.NRGBA, buffered),
+	}
+	gl.GenBuffers(r.pbos[:])
+	size := width * height * 4
+	for _, pbo := range r.pbos {
+		pbo.Bind(gl.PIXEL_PACK_BUFFER)
+		gl.BufferData(gl.PIXEL_PACK_BUFFER, size, nil, gl.STREAM_READ)
+	}
+	gl.Buffer(0).Bind(gl.PIXEL_PACK_BUFFER)
+	setFinalizer(r, (*Recorder).finalize)
+	return r
+}
+
+// Frames returns the channel captured frames are sent on. It is closed by
+// Close.
+func (r *Recorder) Frames() <-chan *image.NRGBA {
+	return r.frames
+}
+
+// Dropped returns the number of frames dropped because the channel was full.
+func (r *Recorder) Dropped() int {
+	return r.dropped
+}
+
+// Capture starts reading back the current frame and delivers the frame
+// captured by the previous call, if any. Call it after drawing and before
+// swapping buffers.
+func (r *Recorder) Capture() {
+	pbo := r.pbos[r.cur]
+	pbo.Bind(gl.PIXEL_PACK_BUFFER)
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(0, 0, r.width, r.height, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	r.pending[r.cur] = true
+
+	r.cur = 1 - r.cur
+	if r.pending[r.cur] {
+		r.collect(r.cur)
+	}
+	gl.Buffer(0).Bind(gl.PIXEL_PACK_BUFFER)
+	checkError("Recorder.Capture")
+}
+
+// collect maps the i'th pixel buffer and sends its contents as a frame.
+func (r *Recorder) collect(i int) {
+	r.pending[i] = false
+	r.pbos[i].Bind(gl.PIXEL_PACK_BUFFER)
+	size := r.width * r.height * 4
+	var ptr unsafe.Pointer
+	if detectCaps(); caps.mapBuffer {
+		ptr = gl.MapBuffer(gl.PIXEL_PACK_BUFFER, gl.READ_ONLY)
+	} else {
+		ptr = gl.MapBufferRange(gl.PIXEL_PACK_BUFFER, 0, size, gl.MAP_READ_BIT)
+	}
+	if ptr == nil {
+		r.dropped++
+		return
+	}
+	src := unsafe.Slice((*byte)(ptr), size)
+	img := image.NewNRGBA(image.Rect(0, 0, r.width, r.height))
+	flipRows(img.Pix, src, r.width*4, r.height)
+	gl.UnmapBuffer(gl.PIXEL_PACK_BUFFER)
+	select {
+	case r.frames <- img:
+	default:
+		r.dropped++
+	}
+}
+
+// flipRows copies src to dest while reversing row order, converting between
+// GL's bottom-up and image's top-down layouts.
+func flipRows(dest, src []byte, stride, rows int) {
+	for y := 0; y < rows; y++ {
+		copy(dest[y*stride:(y+1)*stride], src[(rows-1-y)*stride:(rows-y)*stride])
+	}
+}
+
+// Close delivers any pending frame, closes the frames channel, and deletes
+// the pixel buffers.
+func (r *Recorder) Close() {
+	for n := 0; n < 2; n++ {
+		r.cur = 1 - r.cur
+		if r.pending[r.cur] {
+			r.collect(r.cur)
+		}
+	}
+	gl.Buffer(0).Bind(gl.PIXEL_PACK_BUFFER)
+	setFinalizer(r, nil)
+	r.pbos[0].Delete()
+	r.pbos[1].Delete()
+	close(r.frames)
+}
+
+// Capture reads back the region r of the bound framebuffer, in pixels from
+// its bottom left corner, as an image with the top row first. It waits for
+// drawing to finish; use a Recorder to capture frames without stalling.
+func Capture(r image.Rectangle) (*image.NRGBA, error) {
+	if r.Empty() {
+		return nil, fmt.Errorf("gfx: cannot capture empty region %v", r)
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	pix := make([]byte, len(img.Pix))
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(r.Min.X, r.Min.Y, r.Dx(), r.Dy(), gl.RGBA, gl.UNSIGNED_BYTE, pix)
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 4)
+	flipRows(img.Pix, pix, img.Stride, r.Dy())
+	checkError("Capture %v", r)
+	return img, nil
+}
+
+// ReadColor reads back the i'th color buffer as an image with the top row
+// first, converting its values to 8 bits per channel. Multisampled
+// framebuffers must be resolved first.
+func (f *Framebuffer) ReadColor(i int) (*image.NRGBA, error) {
+	if f.samples > 0 {
+		return nil, errors.New("gfx: cannot read multisampled framebuffer")
+	}
+	if i < 0 || i >= len(f.color) {
+		return nil, fmt.Errorf("gfx: framebuffer has no color buffer %d", i)
+	}
+	if f.color[i].format.IsInteger() {
+		return nil, fmt.Errorf("gfx: cannot read %v color buffer as an image", f.color[i].format)
+	}
+	f.fbo.Bind()
+	gl.ReadBuffer(gl.COLOR_ATTACHMENT0 + gl.GLenum(i))
+	img, err := Capture(image.Rect(0, 0, f.width, f.height))
+	gl.Framebuffer(0).Bind()
+	return img, err
+}
+
+// ReadDepth reads back the depth buffer as width times height values in
+// [0, 1], with the top row first. Multisampled framebuffers must be resolved
+// first.
+func (f *Framebuffer) ReadDepth() ([]float32, error) {
+	if f.samples > 0 {
+		return nil, errors.New("gfx: cannot read multisampled framebuffer")
+	}
+	if f.depth == nil {
+		return nil, errors.New("gfx: framebuffer has no depth buffer")
+	}
+	depth := make([]float32, f.width*f.height)
+	f.fbo.Bind()
+	gl.ReadPixels(0, 0, f.width, f.height, gl.DEPTH_COMPONENT, gl.FLOAT, depth)
+	gl.Framebuffer(0).Bind()
+	// flip rows, from GL's bottom-up layout
+	w := f.width
+	for y := 0; y < f.height/2; y++ {
+		top, bottom := depth[y*w:(y+1)*w], depth[(f.height-1-y)*w:(f.height-y)*w]
+		for x := range top {
+			top[x], bottom[x] = bottom[x], top[x]
+		}
+	}
+	checkError("Framebuffer.ReadDepth")
+	return depth, nil
+}
+
+// WritePNGs encodes every frame received from frames as a numbered PNG file
+// in dir, such as dir/frame00000.png, until the channel is closed. It is
+// meant to run on its own goroutine.
+func WritePNGs(frames <-chan *image.NRGBA, dir, prefix string) error {
+	n := 0
+	for img := range frames {
+		name := filepath.Join(dir, fmt.Sprintf("%s%05d.png", prefix, n))
+		f, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		err = png.Encode(f, img)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		n++
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package gfx_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"math"
+	"testing"
+)
+
+func TestCapture(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	// two rows of one pixel, bottom first as GL reads them
+	rec.Pixels = []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	img, err := gfx.Capture(image.Rect(3, 4, 4, 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range rec.Calls {
+		got = append(got, c.String())
+	}
+	want := "[PixelStorei(PACK_ALIGNMENT, 1) ReadPixels(3, 4, 1, 2, RGBA, UNSIGNED_BYTE) PixelStorei(PACK_ALIGNMENT, 4)]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if fmt.Sprint(img.Pix) != "[5 6 7 8 1 2 3 4]" {
+		t.Errorf("got pixels %v, want the top row first", img.Pix)
+	}
+	if _, err := gfx.Capture(image.Rect(0, 0, 0, 4)); err == nil {
+		t.Error("no error for an empty region")
+	}
+}
+
+func TestFramebufferRead(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	color, err := gfx.NewSampler2D(2, 2, gfx.PixelRGBA8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer color.Delete()
+	depth, err := gfx.NewSampler2D(2, 2, gfx.PixelDepth24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer depth.Delete()
+	fb, err := gfx.NewFramebuffer(color, depth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fb.Delete()
+
+	rec.Reset()
+	if _, err := fb.ReadColor(0); err != nil {
+		t.Fatal(err)
+	}
+	if calls := rec.Ops("ReadBuffer", "ReadPixels"); fmt.Sprint(calls) != "[ReadBuffer(COLOR_ATTACHMENT0) ReadPixels(0, 0, 2, 2, RGBA, UNSIGNED_BYTE)]" {
+		t.Errorf("got %v", calls)
+	}
+	if _, err := fb.ReadColor(1); err == nil {
+		t.Error("no error for a missing color buffer")
+	}
+
+	// rows of depths 0 and 1, bottom first
+	rec.Pixels = make([]byte, 16)
+	for i, d := range []float32{0, 0, 1, 1} {
+		binary.LittleEndian.PutUint32(rec.Pixels[4*i:], math.Float32bits(d))
+	}
+	rec.Reset()
+	d, err := fb.ReadDepth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls := rec.Ops("ReadPixels"); fmt.Sprint(calls) != "[ReadPixels(0, 0, 2, 2, DEPTH_COMPONENT, FLOAT)]" {
+		t.Errorf("got %v", calls)
+	}
+	if fmt.Sprint(d) != "[1 1 0 0]" {
+		t.Errorf("got depths %v, want the top row first", d)
+	}
+}
@@ -0,0 +1,51 @@
+package gfx
+
+import (
+	"j4k.co/gfx/internal/gl"
+)
+
+// Vec2Data, Vec3Data, Vec4Data, QuatData, Mat3Data, and Mat4Data are
+// implemented by math types, such as Vec3 and Mat4, that can be assigned to
+// uniforms of the matching GLSL type without copying them into arrays
+// first. Pointer returns the components in GLSL order, with matrices in
+// column-major order. A QuatData is assigned to a vec4.
+type (
+	Vec2Data interface {
+		Pointer() *[2]float32
+	}
+	Vec3Data interface {
+		Pointer() *[3]float32
+	}
+	Vec4Data interface {
+		Pointer() *[4]float32
+	}
+	QuatData interface {
+		Pointer() *[4]float32
+	}
+	Mat3Data interface {
+		Pointer() *[9]float32
+	}
+	Mat4Data interface {
+		Pointer() *[16]float32
+	}
+)
+
+// assignData assigns a value implementing one of the data interfaces to u,
+// and reports whether it did.
+func assignData(v interface{}, u gl.UniformLocation) bool {
+	switch v := v.(type) {
+	case Vec2Data:
+		u.Uniform2fv(1, v.Pointer()[:])
+	case Vec3Data:
+		u.Uniform3fv(1, v.Pointer()[:])
+	case Vec4Data:
+		u.Uniform4fv(1, v.Pointer()[:])
+	case Mat3Data:
+		u.UniformMatrix3f(false, v.Pointer())
+	case Mat4Data:
+		u.UniformMatrix4f(false, v.Pointer())
+	default:
+		return false
+	}
+	return true
+}
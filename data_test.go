@@ -0,0 +1,105 @@
+package gfx_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+const dataShader gfx.FragmentShader = `
+uniform vec2 A;
+uniform vec3 P;
+uniform vec4 Q;
+uniform mat3 N;
+uniform mat4 M;
+
+void main() {
+	gl_FragColor = Q;
+}`
+
+// point, rotation, normalMatrix and transform stand in for math types of other packages,
+// which are not arrays and implement the data interfaces on their pointers.
+type point struct{ v [3]float32 }
+
+func (p *point) Pointer() *[3]float32 { return &p.v }
+
+type rotation struct{ q [4]float32 }
+
+func (r *rotation) Pointer() *[4]float32 { return &r.q }
+
+type normalMatrix struct{ m [9]float32 }
+
+func (m *normalMatrix) Pointer() *[9]float32 { return &m.m }
+
+type transform struct{ m [16]float32 }
+
+func (m *transform) Pointer() *[16]float32 { return &m.m }
+
+// size implements Vec2Data on its value.
+type size struct{ w, h float32 }
+
+func (s size) Pointer() *[2]float32 { return &[2]float32{s.w, s.h} }
+
+type dataUniforms struct {
+	A size          `uniform:"A"`
+	P point         `uniform:"P"`
+	Q *rotation     `uniform:"Q"`
+	N *normalMatrix `uniform:"N"`
+	M transform     `uniform:"M"`
+}
+
+func TestAssignData(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, dataShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	s.SetStrictUniforms(true)
+	s.Use()
+	u := &dataUniforms{
+		A: size{1, 2},
+		P: point{[3]float32{1, 2, 3}},
+		Q: &rotation{[4]float32{0, 0, 0, 1}},
+		N: &normalMatrix{[9]float32{1, 0, 0, 0, 1, 0, 0, 0, 1}},
+	}
+	u.M.m[12] = 5
+	rec.Reset()
+	if err := s.AssignUniforms(u); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprint([]string{
+		`Uniform2fv("A", 1, [1 2])`,
+		`Uniform3fv("P", 1, [1 2 3])`,
+		`Uniform4fv("Q", 1, [0 0 0 1])`,
+		`UniformMatrix3fv("N", 1, false, [1 0 0 0 1 0 0 0 1])`,
+		`UniformMatrix4fv("M", 1, false, [0 0 0 0 0 0 0 0 0 0 0 0 5 0 0 0])`,
+	})
+	if got := fmt.Sprint(rec.Ops("Uniform2fv", "Uniform3fv", "Uniform4fv", "UniformMatrix3fv", "UniformMatrix4fv")); got != want {
+		t.Errorf("got %v,\nwant %v", got, want)
+	}
+
+	// by name, as a value or a pointer
+	for _, v := range []interface{}{point{[3]float32{4, 5, 6}}, &point{[3]float32{4, 5, 6}}} {
+		rec.Reset()
+		if err := s.SetUniform("P", v); err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(rec.Ops("Uniform3fv")); got != `[Uniform3fv("P", 1, [4 5 6])]` {
+			t.Errorf("%T: got %v", v, got)
+		}
+	}
+
+	// strict mode knows the GLSL type of each interface
+	type mismatch struct {
+		P point `uniform:"A"`
+	}
+	want = "gfx: uniforms of gfx_test.mismatch do not match the shader: " +
+		"field P: gfx_test.point cannot be assigned to vec2 'A'"
+	if err := s.AssignUniforms(&mismatch{}); err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
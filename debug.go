@@ -0,0 +1,117 @@
+package gfx
+
+import (
+	"fmt"
+	"j4k.co/gfx/internal/gl"
+	"log"
+	"strconv"
+)
+
+var debug bool
+
+// SetDebug enables or disables debug mode. In debug mode, every gfx call
+// that issues GL commands checks glGetError afterwards and reports any
+// errors to DebugHandler along with the operation that caused them. It is
+// slow, since each check synchronizes with the driver.
+func SetDebug(on bool) {
+	debug = on
+	if on {
+		// discard errors raised before debugging started
+		for gl.GetError() != gl.NO_ERROR {
+		}
+	}
+}
+
+// DebugHandler receives the errors found in debug mode. The default logs
+// them.
+var DebugHandler = func(err *GLError) {
+	log.Print(err)
+}
+
+// GLError is a GL error raised by a gfx operation.
+type GLError struct {
+	Code gl.GLenum
+	Op   string // such as "Sampler2D upload 512x512 RGBA8"
+}
+
+func (e *GLError) Error() string {
+	return fmt.Sprintf("gfx: %s during %s", glErrorName(e.Code), e.Op)
+}
+
+func glErrorName(code gl.GLenum) string {
+	switch code {
+	case gl.INVALID_ENUM:
+		return "GL_INVALID_ENUM"
+	case gl.INVALID_VALUE:
+		return "GL_INVALID_VALUE"
+	case gl.INVALID_OPERATION:
+		return "GL_INVALID_OPERATION"
+	case gl.INVALID_FRAMEBUFFER_OPERATION:
+		return "GL_INVALID_FRAMEBUFFER_OPERATION"
+	case gl.OUT_OF_MEMORY:
+		return "GL_OUT_OF_MEMORY"
+	default:
+		return fmt.Sprintf("GL error 0x%x", uint32(code))
+	}
+}
+
+// checkError reports pending GL errors in debug mode. op and args describe
+// the operation as with fmt.Sprintf, and are only formatted if there is an
+// error.
+func checkError(op string, args ...interface{}) {
+	if !debug {
+		return
+	}
+	for {
+		code := gl.GetError()
+		if code == gl.NO_ERROR {
+			return
+		}
+		if len(args) > 0 {
+			op = fmt.Sprintf(op, args...)
+			args = nil
+		}
+		DebugHandler(&GLError{Code: code, Op: op})
+	}
+}
+
+// lastLabel numbers the names given to objects without labels.
+var lastLabel int
+
+// autoLabel returns a name for a new object of the given kind, such as
+// "Geometry#12", or "" if objects cannot be labeled.
+func autoLabel(kind string) string {
+	if detectCaps(); !caps.debugOutput {
+		return ""
+	}
+	lastLabel++
+	return kind + "#" + strconv.Itoa(lastLabel)
+}
+
+// labelObject names a GL object for GPU debuggers such as RenderDoc and
+// apitrace. The object must have been bound at least once.
+func labelObject(identifier gl.GLenum, name uint32, label string) {
+	if label == "" || name == 0 {
+		return
+	}
+	if detectCaps(); caps.debugOutput {
+		gl.ObjectLabel(identifier, name, label)
+	}
+}
+
+// PushDebugGroup begins a named group of GL commands, which GPU debuggers
+// such as RenderDoc and apitrace show as a unit, such as a render pass.
+// Groups nest, and each must be ended by PopDebugGroup. It does nothing
+// without KHR_debug.
+func PushDebugGroup(name string) {
+	if detectCaps(); caps.debugOutput {
+		gl.PushDebugGroup(gl.DEBUG_SOURCE_APPLICATION, 0, name)
+	}
+}
+
+// PopDebugGroup ends the group begun by the last PushDebugGroup.
+func PopDebugGroup() {
+	if detectCaps(); caps.debugOutput {
+		gl.PopDebugGroup()
+	}
+}
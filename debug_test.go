@@ -0,0 +1,109 @@
+package gfx_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/gfxtest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestObjectLabels(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+	rec.Extensions = []string{"GL_KHR_debug"}
+	gfx.Init()
+	defer gfx.Init()
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	s.SetLabel("tint")
+	b := geometry.NewBuilder(gfx.VertexPosition)
+	b.Position(0, 0, 0).Position(1, 0, 0).Position(0, 1, 0)
+	b.Indices(0, 1, 2)
+	geom, err := gfx.NewGeometry(b, gfx.StaticDraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geom.Delete()
+	geom.SetLabel("triangle")
+
+	var labels []string
+	for _, c := range rec.Ops("ObjectLabel") {
+		// strip the numbers of generated names
+		name, _, _ := strings.Cut(c.Args[2].(string), "#")
+		labels = append(labels, fmt.Sprint(c.Args[0], " ", name))
+	}
+	want := []string{
+		"PROGRAM Shader", "PROGRAM tint",
+		"BUFFER Geometry", "BUFFER Geometry",
+		"BUFFER triangle vertices", "BUFFER triangle indices",
+	}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("got labels %q, want %q", labels, want)
+	}
+
+	rec.Reset()
+	gfx.PushDebugGroup("pass")
+	s.Use()
+	if err := s.SetGeometry(geom); err != nil {
+		t.Fatal(err)
+	}
+	s.Draw()
+	gfx.PopDebugGroup()
+
+	calls := rec.Ops("ObjectLabel", "PushDebugGroup", "PopDebugGroup")
+	if len(calls) != 3 || calls[0].Op != "PushDebugGroup" || calls[1].Args[2] != "tint triangle" || calls[2].Op != "PopDebugGroup" {
+		t.Errorf("got calls %v, want the layout labeled within a debug group", calls)
+	}
+}
+
+func TestDebugErrors(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+	var errs []*gfx.GLError
+	handler := gfx.DebugHandler
+	gfx.DebugHandler = func(err *gfx.GLError) { errs = append(errs, err) }
+	defer func() { gfx.DebugHandler = handler }()
+
+	// errors are not checked outside of debug mode, and those raised before
+	// it are discarded
+	rec.Errors = []gfxtest.Enum{0x0502}
+	tex, err := gfx.NewSampler2D(4, 4, gfx.PixelRGBA8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tex.Delete()
+	if len(rec.Errors) != 1 {
+		t.Errorf("checked errors outside of debug mode")
+	}
+	gfx.SetDebug(true)
+	defer gfx.SetDebug(false)
+	if len(rec.Errors) != 0 || len(errs) != 0 {
+		t.Errorf("got %v reported from before debug mode", errs)
+	}
+
+	// each error is reported with the operation that raised it
+	geom := quad(t)
+	defer geom.Delete()
+	rec.Errors = []gfxtest.Enum{0x0501, 0x0505}
+	if err := geom.SetVerticesAt(0, make([]byte, 4)); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Error())
+	}
+	want := fmt.Sprint([]string{
+		"gfx: GL_INVALID_VALUE during VertexBuffer.SetVerticesAt 4 bytes at 0",
+		"gfx: GL_OUT_OF_MEMORY during VertexBuffer.SetVerticesAt 4 bytes at 0",
+	})
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v,\nwant %v", got, want)
+	}
+}
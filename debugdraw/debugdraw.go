@@ -0,0 +1,272 @@
+/*
+Package debugdraw draws lines, boxes, spheres, axes, frusta and text labels
+for diagnosing transforms, bounds and physics. Shapes are added with
+immediate-style calls during a frame, accumulated into a streaming buffer,
+and drawn together by Flush.
+*/
+package debugdraw
+
+import (
+	"image/color"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/scenes"
+	"j4k.co/gfx/text"
+	"math"
+)
+
+// VertexFormat is the format of the line vertices.
+const VertexFormat = gfx.VertexPosition | gfx.VertexColor
+
+// Segments is the number of lines approximating each circle of a sphere.
+const Segments = 24
+
+var attrs = gfx.VertexAttributes{
+	gfx.VertexPosition: "Position",
+	gfx.VertexColor:    "Color",
+}
+
+const vertexShader gfx.VertexShader = `
+uniform mat4 ViewProjectionM;
+
+attribute vec3 Position;
+attribute vec4 Color;
+
+varying vec4 color;
+
+void main() {
+	color = Color;
+	gl_Position = ViewProjectionM * vec4(Position, 1.0);
+}`
+
+const fragmentShader gfx.FragmentShader = `
+varying vec4 color;
+
+void main() {
+	gl_FragColor = color;
+}`
+
+// Drawer accumulates debug shapes for a frame. Depth testing and blending
+// are left to the caller, who typically draws debug shapes last, with or
+// without depth testing.
+type Drawer struct {
+	// Font draws the labels added with Text. Labels are dropped while it
+	// is nil.
+	Font *text.Font
+
+	shader  *gfx.Shader
+	builder *geometry.Builder
+	dyn     *gfx.DynamicGeometry
+	labels  []label
+	text    *text.Drawer
+	textSDF bool
+}
+
+type label struct {
+	pos   [3]float32
+	s     string
+	color color.NRGBA
+}
+
+// New builds the line shader and returns an empty drawer.
+func New() (*Drawer, error) {
+	shader, err := gfx.BuildShader(attrs, vertexShader, fragmentShader)
+	if err != nil {
+		return nil, err
+	}
+	dyn, err := gfx.NewDynamicGeometry(VertexFormat, false, 2)
+	if err != nil {
+		shader.Delete()
+		return nil, err
+	}
+	return &Drawer{
+		shader:  shader,
+		builder: geometry.NewBuilder(VertexFormat),
+		dyn:     dyn,
+	}, nil
+}
+
+// Delete frees the shaders and buffers of the drawer, but not its font.
+func (d *Drawer) Delete() {
+	d.shader.Delete()
+	d.dyn.Delete()
+	if d.text != nil {
+		d.text.Delete()
+	}
+}
+
+// Line adds a line from a to b.
+func (d *Drawer) Line(a, b [3]float32, c color.NRGBA) {
+	d.builder.Position(a[0], a[1], a[2]).Color(c.R, c.G, c.B, c.A)
+	d.builder.Position(b[0], b[1], b[2]).Color(c.R, c.G, c.B, c.A)
+}
+
+// AABB adds the edges of the axis-aligned box from min to max.
+func (d *Drawer) AABB(min, max [3]float32, c color.NRGBA) {
+	var corners [8][3]float32
+	for i := range corners {
+		corners[i] = min
+		for axis := 0; axis < 3; axis++ {
+			if i&(1<<uint(axis)) != 0 {
+				corners[i][axis] = max[axis]
+			}
+		}
+	}
+	d.box(&corners, c)
+}
+
+// Sphere adds three circles of the sphere, one around each axis.
+func (d *Drawer) Sphere(center [3]float32, radius float32, c color.NRGBA) {
+	for axis := 0; axis < 3; axis++ {
+		u, v := (axis+1)%3, (axis+2)%3
+		prev := center
+		prev[u] += radius
+		for i := 1; i <= Segments; i++ {
+			angle := 2 * math.Pi * float64(i) / Segments
+			p := center
+			p[u] += radius * float32(math.Cos(angle))
+			p[v] += radius * float32(math.Sin(angle))
+			d.Line(prev, p, c)
+			prev = p
+		}
+	}
+}
+
+// Axes adds the x, y and z axes of the transform m, size units long, in
+// red, green and blue.
+func (d *Drawer) Axes(m [16]float32, size float32) {
+	origin := [3]float32{m[12], m[13], m[14]}
+	colors := [3]color.NRGBA{{255, 0, 0, 255}, {0, 255, 0, 255}, {0, 0, 255, 255}}
+	for axis := 0; axis < 3; axis++ {
+		end := origin
+		for i := 0; i < 3; i++ {
+			end[i] += m[axis*4+i] * size
+		}
+		d.Line(origin, end, colors[axis])
+	}
+}
+
+// Frustum adds the edges of the view frustum of the view-projection matrix
+// viewProj, such as that of a camera or shadow caster. It adds nothing if
+// viewProj is singular.
+func (d *Drawer) Frustum(viewProj [16]float32, c color.NRGBA) {
+	inv, ok := scenes.Invert(&viewProj)
+	if !ok {
+		return
+	}
+	var corners [8][3]float32
+	for i := range corners {
+		var p [4]float32
+		for axis := 0; axis < 3; axis++ {
+			ndc := float32(-1)
+			if i&(1<<uint(axis)) != 0 {
+				ndc = 1
+			}
+			for j := range p {
+				p[j] += inv[axis*4+j] * ndc
+			}
+		}
+		for j := range p {
+			p[j] += inv[12+j]
+		}
+		corners[i] = [3]float32{p[0] / p[3], p[1] / p[3], p[2] / p[3]}
+	}
+	d.box(&corners, c)
+}
+
+// box adds the edges between corners that differ in one axis, where bit i
+// of a corner's index selects its side along axis i.
+func (d *Drawer) box(corners *[8][3]float32, c color.NRGBA) {
+	for i := range corners {
+		for axis := 0; axis < 3; axis++ {
+			if j := i | 1<<uint(axis); j != i {
+				d.Line(corners[i], corners[j], c)
+			}
+		}
+	}
+}
+
+// Text adds a label drawn with Font, with its top left corner at the screen
+// position of p.
+func (d *Drawer) Text(p [3]float32, s string, c color.NRGBA) {
+	if d.Font != nil {
+		d.labels = append(d.labels, label{p, s, c})
+	}
+}
+
+// Flush draws the shapes added since the last flush with the view-projection
+// matrix viewProj, and clears them. Lines are drawn with one draw call, then
+// the labels that are in front of the viewer, one draw each, onto a
+// viewport of width by height.
+func (d *Drawer) Flush(viewProj [16]float32, width, height float32) error {
+	defer d.clear()
+	if d.builder.VertexCount() > 0 {
+		geom, err := d.dyn.Update(d.builder)
+		if err != nil {
+			return err
+		}
+		geom.Primitive = gfx.Lines
+		d.shader.Use()
+		if err := d.shader.SetGeometry(geom); err != nil {
+			return err
+		}
+		if err := d.shader.SetUniform("ViewProjectionM", viewProj); err != nil {
+			return err
+		}
+		d.shader.Draw()
+	}
+	if len(d.labels) == 0 || d.Font == nil {
+		return nil
+	}
+	if d.text == nil || d.Font.SDF() != d.textSDF {
+		if d.text != nil {
+			d.text.Delete()
+			d.text = nil
+		}
+		var err error
+		if d.Font.SDF() {
+			d.text, err = text.NewSDFDrawer()
+		} else {
+			d.text, err = text.NewDrawer()
+		}
+		if err != nil {
+			return err
+		}
+		d.textSDF = d.Font.SDF()
+	}
+	d.text.Projection = text.Ortho(width, height)
+	for _, l := range d.labels {
+		x, y, ok := project(&viewProj, l.pos, width, height)
+		if !ok {
+			continue
+		}
+		d.text.Color = [4]float32{
+			float32(l.color.R) / 255, float32(l.color.G) / 255,
+			float32(l.color.B) / 255, float32(l.color.A) / 255,
+		}
+		if err := d.text.Draw(d.Font, l.s, x, y, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Drawer) clear() {
+	d.builder.Clear()
+	d.labels = d.labels[:0]
+}
+
+// project returns the screen position of p, with the origin at the top
+// left, and false if p is behind the viewer.
+func project(m *[16]float32, p [3]float32, width, height float32) (x, y float32, ok bool) {
+	var clip [4]float32
+	for i := range clip {
+		clip[i] = m[i]*p[0] + m[4+i]*p[1] + m[8+i]*p[2] + m[12+i]
+	}
+	if clip[3] <= 0 {
+		return 0, 0, false
+	}
+	x = (clip[0]/clip[3] + 1) / 2 * width
+	y = (1 - clip[1]/clip[3]) / 2 * height
+	return x, y, true
+}
@@ -0,0 +1,45 @@
+package debugdraw_test
+
+import (
+	"image/color"
+	"j4k.co/gfx/debugdraw"
+	"j4k.co/gfx/gfxtest"
+	"j4k.co/gfx/scenes"
+	"testing"
+)
+
+func TestFlush(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	d, err := debugdraw.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Delete()
+
+	white := color.NRGBA{255, 255, 255, 255}
+	d.Line([3]float32{0, 0, 0}, [3]float32{1, 1, 1}, white)
+	d.AABB([3]float32{-1, -1, -1}, [3]float32{1, 1, 1}, white)
+	d.Axes(scenes.Identity, 1)
+	d.Frustum(scenes.Identity, white)
+	d.Sphere([3]float32{}, 1, white)
+
+	rec.Reset()
+	if err := d.Flush(scenes.Identity, 640, 480); err != nil {
+		t.Fatal(err)
+	}
+	lines := 1 + 12 + 3 + 12 + 3*debugdraw.Segments
+	draws := rec.Draws()
+	if len(draws) != 1 || draws[0].Op != "DrawArrays" || draws[0].Args[0] != gfxtest.Enum(0x0001) || draws[0].Args[2] != 2*lines {
+		t.Fatalf("got draws %v, want one DrawArrays(LINES, 0, %d)", draws, 2*lines)
+	}
+
+	rec.Reset()
+	if err := d.Flush(scenes.Identity, 640, 480); err != nil {
+		t.Fatal(err)
+	}
+	if draws := rec.Draws(); len(draws) != 0 {
+		t.Errorf("got draws %v after the shapes were flushed", draws)
+	}
+}
@@ -1,5 +1,9 @@
 /*
 Package gfx provides simple abstractions over OpenGL shaders and
 triangle-based geometry.
+
+Geometry is made from any VertexData; the geometry package provides
+Builder for assembling vertices and indices, and TypedBuffer holds vertices
+as Go structs.
 */
 package gfx
@@ -0,0 +1,238 @@
+package gfx
+
+import (
+	"j4k.co/gfx/internal/gl"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// SortKey orders draws in a DrawQueue. From most to least significant, it
+// holds an 8-bit pass, a 24-bit material ID, and 32 bits of depth.
+type SortKey uint64
+
+// MakeSortKey packs a sort key. depth is expected to be non-negative, such as
+// view-space distance; draws with equal pass and material are sorted front to
+// back.
+func MakeSortKey(pass uint8, material uint32, depth float32) SortKey {
+	return SortKey(pass)<<56 | SortKey(material&0xffffff)<<32 | SortKey(depthBits(depth))
+}
+
+// MakeSortKeyBackToFront is like MakeSortKey but orders draws with equal pass
+// and material back to front, as needed for transparent geometry.
+func MakeSortKeyBackToFront(pass uint8, material uint32, depth float32) SortKey {
+	return SortKey(pass)<<56 | SortKey(material&0xffffff)<<32 | SortKey(^depthBits(depth))
+}
+
+// depthBits maps a non-negative float to bits that sort in the same order.
+func depthBits(depth float32) uint32 {
+	if depth < 0 {
+		depth = 0
+	}
+	return math.Float32bits(depth)
+}
+
+// Pass returns the pass stored in the key.
+func (k SortKey) Pass() uint8 {
+	return uint8(k >> 56)
+}
+
+// Material returns the material ID stored in the key.
+func (k SortKey) Material() uint32 {
+	return uint32(k>>32) & 0xffffff
+}
+
+// UniformBinder is implemented by the Material or Uniforms of a DrawItem
+// that set more than a uniform struct can hold, such as textures by name or
+// uniform blocks. BindUniforms is called with the draw's shader in use.
+type UniformBinder interface {
+	BindUniforms(s *Shader) error
+}
+
+// DrawItem is a single draw submitted to a DrawQueue.
+type DrawItem struct {
+	Key    SortKey
+	Shader *Shader
+	Layout *GeometryLayout
+
+	// Geometry is drawn through the layout cache of the shader, as with
+	// Shader.SetGeometry, if Layout is nil.
+	Geometry *Geometry
+
+	// Material is a uniform struct (see Shader.AssignUniforms) or a
+	// UniformBinder shared by every draw of the material. It is only
+	// assigned when it changes, so it must be comparable.
+	Material interface{}
+
+	// Uniforms holds optional per-draw uniforms, a uniform struct or a
+	// UniformBinder, assigned after Material. They should not overlap the
+	// uniforms of the material, which is not assigned again for the next
+	// draw. A draw with per-draw uniforms is only merged with the draws
+	// after it that have none or the same comparable value, such as a
+	// pointer shared by the parts of one object.
+	Uniforms interface{}
+
+	// State is the optional fixed-function state of the draw, such as its
+	// blend mode. If nil, the state is left as it is.
+	State *State
+
+	// First and Count select a range of indices to draw, or of vertices for
+	// geometry without indices. A zero Count draws everything from First on.
+	First int
+	Count int
+}
+
+// DrawQueue collects draws, sorts them by key, and submits them with as few
+// state changes and draw calls as it can. Consecutive draws that share a
+// shader, geometry, material, and per-draw uniforms are merged into a single
+// multi-draw call, or drawn in turn on OpenGL ES, which lacks it.
+type DrawQueue struct {
+	items []DrawItem
+
+	// scratch space for multi-draw
+	counts  []int32
+	firsts  []int32
+	offsets []uintptr
+}
+
+// Add appends a draw to the queue.
+func (q *DrawQueue) Add(item DrawItem) {
+	q.items = append(q.items, item)
+}
+
+// Len returns the number of queued draws.
+func (q *DrawQueue) Len() int {
+	return len(q.items)
+}
+
+// Reset empties the queue without drawing.
+func (q *DrawQueue) Reset() {
+	for i := range q.items {
+		q.items[i] = DrawItem{}
+	}
+	q.items = q.items[:0]
+}
+
+type drawItemsByKey []DrawItem
+
+func (d drawItemsByKey) Len() int           { return len(d) }
+func (d drawItemsByKey) Less(i, j int) bool { return d[i].Key < d[j].Key }
+func (d drawItemsByKey) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// Sort sorts the queued draws by key. Draws with equal keys keep their
+// submission order.
+func (q *DrawQueue) Sort() {
+	sort.Stable(drawItemsByKey(q.items))
+}
+
+// Flush sorts and draws everything in the queue, then resets it.
+func (q *DrawQueue) Flush() error {
+	q.Sort()
+	defer q.Reset()
+	var (
+		shader   *Shader
+		layout   *GeometryLayout
+		geom     *Geometry
+		material interface{}
+	)
+	for i := 0; i < len(q.items); {
+		item := &q.items[i]
+		if item.Shader != shader {
+			shader = item.Shader
+			shader.Use()
+			layout, geom, material = nil, nil, nil
+		}
+		switch {
+		case item.Layout != nil && item.Layout != layout:
+			if err := shader.SetLayout(item.Layout); err != nil {
+				return err
+			}
+			layout, geom = item.Layout, nil
+		case item.Layout == nil && item.Geometry != geom:
+			if err := shader.SetGeometry(item.Geometry); err != nil {
+				return err
+			}
+			layout, geom = nil, item.Geometry
+		}
+		if item.State != nil {
+			item.State.Apply()
+		}
+		if item.Material != material {
+			if item.Material != nil {
+				if err := bindUniforms(shader, item.Material); err != nil {
+					return err
+				}
+			}
+			material = item.Material
+		}
+		if item.Uniforms != nil {
+			if err := bindUniforms(shader, item.Uniforms); err != nil {
+				return err
+			}
+		}
+		j := i + 1
+		for j < len(q.items) && q.mergeable(item, &q.items[j]) {
+			j++
+		}
+		if j-i == 1 {
+			shader.DrawRange(item.First, item.Count)
+		} else {
+			q.multiDraw(shader, q.items[i:j])
+		}
+		i = j
+	}
+	return nil
+}
+
+// bindUniforms assigns v, a uniform struct or a UniformBinder, to s.
+func bindUniforms(s *Shader, v interface{}) error {
+	if b, ok := v.(UniformBinder); ok {
+		return b.BindUniforms(s)
+	}
+	return s.AssignUniforms(v)
+}
+
+func (q *DrawQueue) mergeable(a, b *DrawItem) bool {
+	if a.Shader != b.Shader || a.Layout != b.Layout || a.Geometry != b.Geometry ||
+		a.Material != b.Material || a.State != b.State {
+		return false
+	}
+	return b.Uniforms == nil || reflect.TypeOf(b.Uniforms).Comparable() && a.Uniforms == b.Uniforms
+}
+
+func (q *DrawQueue) multiDraw(s *Shader, items []DrawItem) {
+	q.counts = q.counts[:0]
+	q.firsts = q.firsts[:0]
+	q.offsets = q.offsets[:0]
+	size := s.indexSize()
+	total := 0
+	for _, item := range items {
+		count := item.Count
+		if count == 0 {
+			count = s.elemCount() - item.First
+		}
+		total += count
+		q.counts = append(q.counts, int32(count))
+		q.firsts = append(q.firsts, int32(item.First))
+		q.offsets = append(q.offsets, uintptr(s.indexOffset+item.First*size))
+	}
+	detectCaps()
+	switch {
+	case caps.es:
+		for i := range q.counts {
+			if !s.indexed {
+				gl.DrawArrays(s.mode(), int(q.firsts[i]), int(q.counts[i]))
+			} else {
+				gl.DrawElements(s.mode(), int(q.counts[i]), s.indexType, q.offsets[i])
+			}
+			s.countDraw(int(q.counts[i]), 1)
+		}
+	case !s.indexed:
+		gl.MultiDrawArrays(s.mode(), q.firsts, q.counts)
+		s.countDraw(total, 1)
+	default:
+		gl.MultiDrawElements(s.mode(), q.counts, s.indexType, q.offsets)
+		s.countDraw(total, 1)
+	}
+	checkError("DrawQueue multi-draw of %d", len(items))
+}
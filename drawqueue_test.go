@@ -0,0 +1,160 @@
+package gfx_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+type tint struct {
+	Tint [4]float32 `uniform:"Tint"`
+}
+
+// quad returns an indexed quad of two triangles.
+func quad(t *testing.T) *gfx.Geometry {
+	b := geometry.NewBuilder(gfx.VertexPosition)
+	b.Position(0, 0, 0).Position(1, 0, 0).Position(1, 1, 0).Position(0, 1, 0)
+	b.Quad(0, 1, 2, 3)
+	geom, err := gfx.NewGeometry(b, gfx.StaticDraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return geom
+}
+
+func TestDrawQueueOrder(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	var shaders [2]*gfx.Shader
+	for i := range shaders {
+		s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, tintShader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Delete()
+		shaders[i] = s
+	}
+	geom := quad(t)
+	defer geom.Delete()
+	rec.Reset()
+	programs := map[interface{}]string{}
+	for i, s := range shaders {
+		s.Use()
+		programs[rec.Calls[i].Args[0]] = fmt.Sprint("shader ", i)
+	}
+
+	red, green := &tint{[4]float32{1, 0, 0, 1}}, &tint{[4]float32{0, 1, 0, 1}}
+	var q gfx.DrawQueue
+	// submitted out of order: transparent, then far, then near
+	q.Add(gfx.DrawItem{Key: gfx.MakeSortKeyBackToFront(1, 0, 5), Shader: shaders[0], Geometry: geom, Material: green})
+	q.Add(gfx.DrawItem{Key: gfx.MakeSortKey(0, 1, 10), Shader: shaders[1], Geometry: geom, Material: red, Uniforms: green})
+	q.Add(gfx.DrawItem{Key: gfx.MakeSortKey(0, 1, 2), Shader: shaders[1], Geometry: geom, Material: red})
+	q.Add(gfx.DrawItem{Key: gfx.MakeSortKey(0, 0, 10), Shader: shaders[0], Geometry: geom, Material: red})
+	rec.Reset()
+	if err := q.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if q.Len() != 0 {
+		t.Errorf("%d draws left after flushing", q.Len())
+	}
+
+	var order []string
+	for _, c := range rec.Ops("UseProgram", "Uniform4fv", "DrawElements") {
+		if c.Op == "UseProgram" {
+			order = append(order, programs[c.Args[0]])
+		} else {
+			order = append(order, c.String())
+		}
+	}
+	// draws in key order, with the near draw of shader 1 before its far one,
+	// which assigns its own uniforms after the material
+	draw := "DrawElements(TRIANGLES, 6, UNSIGNED_SHORT, 0)"
+	want := fmt.Sprint([]string{
+		"shader 0", `Uniform4fv("Tint", 1, [1 0 0 1])`, draw,
+		"shader 1", `Uniform4fv("Tint", 1, [1 0 0 1])`, draw, `Uniform4fv("Tint", 1, [0 1 0 1])`, draw,
+		"shader 0", `Uniform4fv("Tint", 1, [0 1 0 1])`, draw,
+	})
+	if got := fmt.Sprint(order); got != want {
+		t.Errorf("got %v,\nwant %v", got, want)
+	}
+}
+
+func TestDrawQueueMerge(t *testing.T) {
+	for _, version := range []string{"", "OpenGL ES 3.0"} {
+		rec := gfxtest.Install()
+		rec.Version = version
+		gfx.Init()
+
+		s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, tintShader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		geom := quad(t)
+		mat := &tint{[4]float32{1, 1, 1, 1}}
+		var q gfx.DrawQueue
+		q.Add(gfx.DrawItem{Shader: s, Geometry: geom, Material: mat, First: 3, Count: 3})
+		q.Add(gfx.DrawItem{Shader: s, Geometry: geom, Material: mat, Count: 3})
+		rec.Reset()
+		if err := q.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		var draws []string
+		for _, c := range rec.Draws() {
+			draws = append(draws, c.String())
+		}
+		want := "[MultiDrawElements(TRIANGLES, [3 3], UNSIGNED_SHORT, [6 0])]"
+		if version != "" {
+			want = "[DrawElements(TRIANGLES, 3, UNSIGNED_SHORT, 6) DrawElements(TRIANGLES, 3, UNSIGNED_SHORT, 0)]"
+		}
+		if got := fmt.Sprint(draws); got != want {
+			t.Errorf("%q: got %v, want %v", version, got, want)
+		}
+		geom.Delete()
+		s.Delete()
+		rec.Uninstall()
+	}
+}
+
+func TestDrawQueueState(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	geom := quad(t)
+	defer geom.Delete()
+	opaque, blended := &gfx.State{}, &gfx.State{Blend: gfx.AlphaBlend}
+	mat := &tint{[4]float32{1, 1, 1, 1}}
+	gfx.ResetState()
+	opaque.Apply()
+
+	var q gfx.DrawQueue
+	q.Add(gfx.DrawItem{Key: gfx.MakeSortKey(0, 0, 1), Shader: s, Geometry: geom, Material: mat, State: opaque, Count: 3})
+	q.Add(gfx.DrawItem{Key: gfx.MakeSortKeyBackToFront(1, 0, 2), Shader: s, Geometry: geom, Material: mat, State: blended, First: 3})
+	q.Add(gfx.DrawItem{Key: gfx.MakeSortKeyBackToFront(1, 0, 1), Shader: s, Geometry: geom, Material: mat, State: blended, Count: 3})
+	rec.Reset()
+	if err := q.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// the blended draws share their state, so blending is enabled once
+	// between the opaque draw and them
+	var got []string
+	for _, c := range rec.Ops("Enable", "Disable", "DrawElements", "MultiDrawElements") {
+		got = append(got, c.String())
+	}
+	want := fmt.Sprint([]string{
+		"DrawElements(TRIANGLES, 3, UNSIGNED_SHORT, 0)",
+		"Enable(BLEND)",
+		"MultiDrawElements(TRIANGLES, [3 3], UNSIGNED_SHORT, [6 0])",
+	})
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v,\nwant %v", got, want)
+	}
+}
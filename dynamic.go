@@ -0,0 +1,86 @@
+package gfx
+
+import (
+	"fmt"
+	"time"
+)
+
+// DynamicGeometry is geometry whose vertices are replaced every frame, such
+// as sprites, UI or debug lines. It owns two or three sets of buffers and
+// writes each frame's vertices into the next set in turn, so that buffers
+// still being read by the GPU for earlier frames are never written to.
+//
+// Typical use is to fill a builder each frame, then draw the geometry
+// returned by Update:
+//
+//	geom, err := dyn.Update(builder)
+//	...
+//	s.SetGeometry(geom)
+//	s.Draw()
+type DynamicGeometry struct {
+	sets   []*Geometry
+	fences []*Fence
+	cur    int
+}
+
+// NewDynamicGeometry allocates n sets of buffers, 2 or 3, for vertices of
+// format vf, and indices if indexed is set. Double buffering suffices when
+// the vertices are written once per frame; triple buffering keeps a
+// renderer that runs a frame ahead of the GPU from waiting on it.
+func NewDynamicGeometry(vf VertexFormat, indexed bool, n int) (*DynamicGeometry, error) {
+	if n < 2 || n > 3 {
+		return nil, fmt.Errorf("gfx: %d buffer sets for dynamic geometry, want 2 or 3", n)
+	}
+	d := &DynamicGeometry{
+		sets:   make([]*Geometry, n),
+		fences: make([]*Fence, n),
+	}
+	for i := range d.sets {
+		geom := allocGeom(StreamDraw, indexed)
+		geom.VertexBuffer.format = vf
+		d.sets[i] = geom
+	}
+	return d, nil
+}
+
+// Geometry returns the set of buffers written by the last Update, to be
+// drawn this frame.
+func (d *DynamicGeometry) Geometry() *Geometry {
+	return d.sets[d.cur]
+}
+
+// Update fences the current set, which is assumed to be drawn by the
+// commands issued so far, then moves to the next set and copies src into
+// it as CopyFrom does. If the GPU has not yet finished with the next set,
+// Update waits up to a second for it before writing anyway. The primitive
+// settings of the current set carry over to the next.
+func (d *DynamicGeometry) Update(src VertexData) (*Geometry, error) {
+	prev := d.sets[d.cur]
+	detectCaps()
+	if caps.sync {
+		d.fences[d.cur] = NewFence()
+	}
+	d.cur = (d.cur + 1) % len(d.sets)
+	if f := d.fences[d.cur]; f != nil {
+		f.Wait(time.Second)
+		f.Delete()
+		d.fences[d.cur] = nil
+	}
+	geom := d.sets[d.cur]
+	geom.Primitive, geom.PrimitiveRestart = prev.Primitive, prev.PrimitiveRestart
+	if err := geom.CopyFrom(src); err != nil {
+		return nil, err
+	}
+	return geom, nil
+}
+
+// Delete frees all sets of buffers and their fences.
+func (d *DynamicGeometry) Delete() {
+	for i, geom := range d.sets {
+		geom.Delete()
+		if d.fences[i] != nil {
+			d.fences[i].Delete()
+			d.fences[i] = nil
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package gfx_test
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+func TestDynamicGeometry(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	if _, err := gfx.NewDynamicGeometry(gfx.VertexPosition, false, 4); err == nil {
+		t.Fatal("no error for 4 buffer sets")
+	}
+	dyn, err := gfx.NewDynamicGeometry(gfx.VertexPosition, true, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dyn.Delete()
+
+	b := geometry.NewBuilder(gfx.VertexPosition)
+	b.Position(0, 0, 0).Position(1, 0, 0).Position(0, 1, 0)
+	b.Indices(0, 1, 2)
+	var geoms []*gfx.Geometry
+	for frame := 0; frame < 3; frame++ {
+		geom, err := dyn.Update(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if geom != dyn.Geometry() || geom.VertexBuffer.Count() != 3 || geom.IndexBuffer.Count() != 3 {
+			t.Fatalf("frame %d: got %d vertices and %d indices", frame, geom.VertexBuffer.Count(), geom.IndexBuffer.Count())
+		}
+		geoms = append(geoms, geom)
+	}
+	if geoms[0] == geoms[1] || geoms[0] != geoms[2] {
+		t.Error("buffer sets were not rotated")
+	}
+	if n := len(rec.Ops("FenceSync")); n != 3 {
+		t.Errorf("got %d fences, want 3", n)
+	}
+	if n := len(rec.Ops("DeleteSync")); n != 2 {
+		t.Errorf("waited on %d fences, want 2", n)
+	}
+}
@@ -17,6 +17,8 @@ import (
 	"image/png"
 	"io"
 	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/geometry/shapes"
 	"os"
 	"runtime"
 )
@@ -88,7 +90,7 @@ func createTexture(r io.Reader) (gfx.Sampler, error) {
 	if !ok {
 		return nil, errors.New("texture must be an NRGBA image")
 	}
-	return gfx.Image(rgbaImg)
+	return gfx.Image(rgbaImg, nil)
 }
 
 var cube struct {
@@ -99,7 +101,7 @@ var cube struct {
 	worldM         mathgl.Mat4f
 	WorldViewProjM [16]float32 `uniform:"WorldViewProjectionM"`
 	Diffuse        gfx.Sampler `uniform:"Diffuse"`
-	geom           gfx.Geometry
+	geom           *gfx.Geometry
 }
 
 func initScene() (err error) {
@@ -121,52 +123,22 @@ func initScene() (err error) {
 	}
 	defer goph.Close()
 
-	// TODO: create cube geometry. could build out the vertex buffer manually, but this is a good place to do a mesh builder type thing.
-	//cube.geom = ... mesh builder?
-
 	gfx.DefaultVertexAttributes[gfx.VertexPosition] = "Position"
 	gfx.DefaultVertexAttributes[gfx.VertexColor] = "Color"
 	gfx.DefaultVertexAttributes[gfx.VertexTexcoord] = "UV"
 	gfx.DefaultVertexAttributes[gfx.VertexNormal] = "Normal"
 	vfmt := gfx.DefaultVertexAttributes.Format()
-	builder := gfx.BuildGeometry(vfmt)
-	builder.Position(-1, -1, 1).Texcoord(0, 0).Color(1, 1, 1).Normal(0, 0, 1)
-	builder.Position(1, -1, 1).Texcoord(1, 0)
-	builder.Position(1, 1, 1).Texcoord(1, 1)
-	builder.Position(-1, 1, 1).Texcoord(0, 1)
-	builder.Indices(0, 1, 2, 2, 0, 3)
-	builder.Position(-1, -1, -1).Texcoord(0, 0).Normal(0, 0, -1)
-	builder.Position(-1, 1, -1).Texcoord(1, 0)
-	builder.Position(1, 1, -1).Texcoord(1, 1)
-	builder.Position(1, -1, -1).Texcoord(0, 1)
-	builder.Indices(0, 1, 2, 2, 0, 3)
-	builder.Position(-1, 1, -1).Texcoord(0, 0).Normal(0, 1, 0)
-	builder.Position(-1, 1, 1).Texcoord(1, 0)
-	builder.Position(1, 1, 1).Texcoord(1, 1)
-	builder.Position(1, 1, -1).Texcoord(0, 1)
-	builder.Indices(0, 1, 2, 2, 0, 3)
-	builder.Position(-1, -1, -1).Texcoord(0, 0).Normal(0, -1, 0)
-	builder.Position(1, -1, -1).Texcoord(1, 0)
-	builder.Position(1, -1, 1).Texcoord(1, 1)
-	builder.Position(-1, -1, 1).Texcoord(0, 1)
-	builder.Indices(0, 1, 2, 2, 0, 3)
-	builder.Position(1, -1, -1).Texcoord(0, 0).Normal(1, 0, 0)
-	builder.Position(1, 1, -1).Texcoord(1, 0)
-	builder.Position(1, 1, 1).Texcoord(1, 1)
-	builder.Position(1, -1, 1).Texcoord(0, 1)
-	builder.Indices(0, 1, 2, 2, 0, 3)
-	builder.Position(-1, -1, -1).Texcoord(0, 0).Normal(-1, 0, 0)
-	builder.Position(-1, -1, 1).Texcoord(1, 0)
-	builder.Position(-1, 1, 1).Texcoord(1, 1)
-	builder.Position(-1, 1, -1).Texcoord(0, 1)
-	builder.Indices(0, 1, 2, 2, 0, 3)
+	builder := geometry.NewBuilder(vfmt)
+	shapes.Cube(builder, 2)
 	cube.geom, err = gfx.NewGeometry(builder, gfx.StaticDraw)
 	if err != nil {
 		panic(err)
 	}
 
-	shader := gfx.BuildShader(gfx.DefaultVertexAttributes, vs, fs)
-	cube.shader = shader
+	cube.shader, err = gfx.BuildShader(gfx.DefaultVertexAttributes, vs, fs)
+	if err != nil {
+		return err
+	}
 	cube.Diffuse, err = createTexture(goph)
 	if err != nil {
 		panic(err)
@@ -0,0 +1,256 @@
+package gfx
+
+import (
+	"errors"
+	"fmt"
+	"j4k.co/gfx/internal/gl"
+)
+
+var ErrFramebufferIncomplete = errors.New("gfx: framebuffer incomplete")
+
+// Framebuffer is an offscreen render target made of one or more color
+// textures and an optional depth texture, or of multisampled renderbuffers.
+type Framebuffer struct {
+	fbo    gl.Framebuffer
+	width  int
+	height int
+	color  []*Sampler2D
+	depth  *Sampler2D
+
+	// renderbufs are owned by the framebuffer, and samples is their number
+	// of samples per pixel.
+	renderbufs []gl.Renderbuffer
+	hasDepth   bool
+	samples    int
+}
+
+// NewFramebuffer creates a framebuffer that renders into the given
+// textures. Color textures are attached in order, and a texture with a depth
+// format is attached as the depth buffer. All textures must be the same size.
+func NewFramebuffer(textures ...*Sampler2D) (*Framebuffer, error) {
+	if len(textures) == 0 {
+		return nil, ErrFramebufferIncomplete
+	}
+	fb := &Framebuffer{
+		fbo: gl.GenFramebuffer(),
+	}
+	fb.width, fb.height = textures[0].Size()
+	fb.fbo.Bind()
+	fb.SetLabel(autoLabel("Framebuffer"))
+	for _, tex := range textures {
+		w, h := tex.Size()
+		if w != fb.width || h != fb.height {
+			fb.Delete()
+			return nil, errors.New("gfx: framebuffer attachments differ in size")
+		}
+		if tex.format.IsDepth() {
+			fb.depth = tex
+			gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_2D, tex.tex, 0)
+			continue
+		}
+		attachment := gl.COLOR_ATTACHMENT0 + gl.GLenum(len(fb.color))
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, attachment, gl.TEXTURE_2D, tex.tex, 0)
+		fb.color = append(fb.color, tex)
+	}
+	fb.hasDepth = fb.depth != nil
+	if err := fb.complete(len(fb.color)); err != nil {
+		return nil, err
+	}
+	checkError("NewFramebuffer %dx%d", fb.width, fb.height)
+	return fb, nil
+}
+
+// NewCubeFramebuffer creates a framebuffer that renders into a mipmap
+// level of a face of a cube map, such as CubePositiveX, to draw or filter
+// an environment. It has no depth buffer, and Color returns nil.
+func NewCubeFramebuffer(tex *SamplerCube, face, level int) (*Framebuffer, error) {
+	if face < 0 || face >= CubeFaces || level < 0 || level >= tex.levels {
+		return nil, fmt.Errorf("gfx: cube map has no level %d of face %d", level, face)
+	}
+	size, _ := levelSize(tex.size, tex.size, level)
+	fb := &Framebuffer{
+		fbo:    gl.GenFramebuffer(),
+		width:  size,
+		height: size,
+	}
+	fb.fbo.Bind()
+	fb.SetLabel(autoLabel("Framebuffer"))
+	target := gl.TEXTURE_CUBE_MAP_POSITIVE_X + gl.GLenum(face)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, target, tex.tex, level)
+	if err := fb.complete(1); err != nil {
+		return nil, err
+	}
+	checkError("NewCubeFramebuffer %dx%d", size, size)
+	return fb, nil
+}
+
+// NewMultisampleFramebuffer creates a framebuffer of the given size that
+// renders into multisampled renderbuffers, one for each format, with
+// samples samples per pixel. A depth format gives the depth buffer. The
+// renderbuffers cannot be sampled; call Resolve to use what was drawn.
+func NewMultisampleFramebuffer(width, height, samples int, formats ...PixelFormat) (*Framebuffer, error) {
+	if len(formats) == 0 {
+		return nil, ErrFramebufferIncomplete
+	}
+	fb := &Framebuffer{
+		fbo:     gl.GenFramebuffer(),
+		width:   width,
+		height:  height,
+		samples: samples,
+	}
+	fb.fbo.Bind()
+	fb.SetLabel(autoLabel("Framebuffer"))
+	ncolor := 0
+	for _, format := range formats {
+		rb := gl.GenRenderbuffer()
+		fb.renderbufs = append(fb.renderbufs, rb)
+		rb.Bind()
+		gl.RenderbufferStorageMultisample(gl.RENDERBUFFER, samples, gl.GLenum(format.internalFormat()), width, height)
+		attachment := gl.COLOR_ATTACHMENT0 + gl.GLenum(ncolor)
+		if format.IsDepth() {
+			attachment = gl.DEPTH_ATTACHMENT
+			fb.hasDepth = true
+		} else {
+			ncolor++
+		}
+		gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, attachment, gl.RENDERBUFFER, rb)
+	}
+	gl.Renderbuffer(0).Bind()
+	if err := fb.complete(ncolor); err != nil {
+		return nil, err
+	}
+	checkError("NewMultisampleFramebuffer %dx%d %dx", width, height, samples)
+	return fb, nil
+}
+
+// complete sets the draw buffers of the bound framebuffer and checks that it
+// is complete, deleting it if not.
+func (f *Framebuffer) complete(ncolor int) error {
+	if ncolor == 0 {
+		gl.DrawBuffer(gl.NONE)
+		gl.ReadBuffer(gl.NONE)
+	} else {
+		bufs := make([]gl.GLenum, ncolor)
+		for i := range bufs {
+			bufs[i] = gl.COLOR_ATTACHMENT0 + gl.GLenum(i)
+		}
+		gl.DrawBuffers(len(bufs), bufs)
+	}
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	gl.Framebuffer(0).Bind()
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		f.Delete()
+		return ErrFramebufferIncomplete
+	}
+	setFinalizer(f, (*Framebuffer).finalize)
+	return nil
+}
+
+// SetLabel names the framebuffer in GPU debuggers, such as RenderDoc and
+// apitrace, when the context has KHR_debug. Framebuffers are otherwise
+// named like "Framebuffer#4".
+func (f *Framebuffer) SetLabel(name string) {
+	labelObject(gl.FRAMEBUFFER, uint32(f.fbo), name)
+}
+
+// Delete deletes the framebuffer object and its renderbuffers. The attached
+// textures are not deleted.
+func (f *Framebuffer) Delete() {
+	setFinalizer(f, nil)
+	f.fbo.Delete()
+	for _, rb := range f.renderbufs {
+		rb.Delete()
+	}
+	f.renderbufs = nil
+}
+
+// Size returns the width and height of the framebuffer in pixels.
+func (f *Framebuffer) Size() (width, height int) {
+	return f.width, f.height
+}
+
+// Samples returns the number of samples per pixel of a multisampled
+// framebuffer, or 0.
+func (f *Framebuffer) Samples() int {
+	return f.samples
+}
+
+// Resolve copies the first color buffer and the depth buffer, if both
+// framebuffers have one, into dst, averaging the samples of a multisampled
+// framebuffer. If dst is nil, it copies into the default framebuffer. The
+// framebuffers must be the same size. The default framebuffer is bound
+// afterwards.
+func (f *Framebuffer) Resolve(dst *Framebuffer) error {
+	mask := gl.COLOR_BUFFER_BIT
+	var drawfbo gl.Framebuffer
+	if dst != nil {
+		if dst.width != f.width || dst.height != f.height {
+			return fmt.Errorf("gfx: cannot resolve %dx%d framebuffer into %dx%d", f.width, f.height, dst.width, dst.height)
+		}
+		drawfbo = dst.fbo
+		if f.hasDepth && dst.hasDepth {
+			mask |= gl.DEPTH_BUFFER_BIT
+		}
+	}
+	f.fbo.BindTarget(gl.READ_FRAMEBUFFER)
+	gl.ReadBuffer(gl.COLOR_ATTACHMENT0)
+	drawfbo.BindTarget(gl.DRAW_FRAMEBUFFER)
+	gl.BlitFramebuffer(0, 0, f.width, f.height, 0, 0, f.width, f.height, gl.GLbitfield(mask), gl.NEAREST)
+	gl.Framebuffer(0).Bind()
+	checkError("Framebuffer.Resolve")
+	return nil
+}
+
+// Color returns the i'th color texture, or nil for a multisampled
+// framebuffer.
+func (f *Framebuffer) Color(i int) *Sampler2D {
+	if i >= len(f.color) {
+		return nil
+	}
+	return f.color[i]
+}
+
+// Depth returns the depth texture, or nil if there is none or the
+// framebuffer is multisampled.
+func (f *Framebuffer) Depth() *Sampler2D {
+	return f.depth
+}
+
+// Bind makes the framebuffer the current render target and sets the
+// viewport to cover it.
+func (f *Framebuffer) Bind() {
+	f.fbo.Bind()
+	gl.Viewport(0, 0, f.width, f.height)
+}
+
+// SetDrawBuffers binds the framebuffer and limits draws to the given color
+// attachments, in the order fragment shaders write them through
+// gl_FragData or the draw buffers of their outputs (see
+// Shader.FragmentOutput), such as to draw decals into some of the attachments of a
+// GBuffer. Without attachments, all color attachments are drawn again.
+func (f *Framebuffer) SetDrawBuffers(attachments ...int) error {
+	if len(attachments) == 0 {
+		attachments = make([]int, len(f.color))
+		for i := range attachments {
+			attachments[i] = i
+		}
+	}
+	bufs := make([]gl.GLenum, len(attachments))
+	for i, a := range attachments {
+		if a < 0 || a >= len(f.color) {
+			return fmt.Errorf("gfx: framebuffer has no color attachment %d", a)
+		}
+		bufs[i] = gl.COLOR_ATTACHMENT0 + gl.GLenum(a)
+	}
+	f.fbo.Bind()
+	gl.DrawBuffers(len(bufs), bufs)
+	checkError("Framebuffer.SetDrawBuffers %v", attachments)
+	return nil
+}
+
+// BindScreen makes the default framebuffer the current render target and
+// sets the viewport to the given size.
+func BindScreen(width, height int) {
+	gl.Framebuffer(0).Bind()
+	gl.Viewport(0, 0, width, height)
+}
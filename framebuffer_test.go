@@ -0,0 +1,95 @@
+package gfx_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+func TestMultisampleResolve(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	rec.Reset()
+	ms, err := gfx.NewMultisampleFramebuffer(64, 32, 4, gfx.PixelRGBA8, gfx.PixelDepth24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ms.Delete()
+	msName := rec.Ops("GenFramebuffer")[0].Args[0]
+	var got []string
+	for _, c := range rec.Ops("RenderbufferStorageMultisample", "FramebufferRenderbuffer") {
+		got = append(got, c.String())
+	}
+	rbs := rec.Ops("GenRenderbuffer")
+	want := fmt.Sprint([]string{
+		"RenderbufferStorageMultisample(RENDERBUFFER, 4, RGBA8, 64, 32)",
+		fmt.Sprint("FramebufferRenderbuffer(FRAMEBUFFER, COLOR_ATTACHMENT0, RENDERBUFFER, ", rbs[0].Args[0], ")"),
+		"RenderbufferStorageMultisample(RENDERBUFFER, 4, DEPTH_COMPONENT24, 64, 32)",
+		fmt.Sprint("FramebufferRenderbuffer(FRAMEBUFFER, DEPTH_ATTACHMENT, RENDERBUFFER, ", rbs[1].Args[0], ")"),
+	})
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v,\nwant %v", got, want)
+	}
+	if ms.Color(0) != nil || ms.Depth() != nil {
+		t.Error("got textures of a multisampled framebuffer")
+	}
+
+	color, err := gfx.NewSampler2D(64, 32, gfx.PixelRGBA8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer color.Delete()
+	depth, err := gfx.NewSampler2D(64, 32, gfx.PixelDepth24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer depth.Delete()
+	rec.Reset()
+	dst, err := gfx.NewFramebuffer(color, depth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Delete()
+	dstName := rec.Ops("GenFramebuffer")[0].Args[0]
+
+	// 0x4100 is COLOR_BUFFER_BIT|DEPTH_BUFFER_BIT, and 0x4000 the color alone
+	for _, c := range []struct {
+		dst  *gfx.Framebuffer
+		name interface{}
+		mask uint32
+	}{{dst, dstName, 0x4100}, {nil, uint32(0), 0x4000}} {
+		rec.Reset()
+		if err := ms.Resolve(c.dst); err != nil {
+			t.Fatal(err)
+		}
+		got = nil
+		for _, c := range rec.Calls {
+			got = append(got, c.String())
+		}
+		want := fmt.Sprint([]string{
+			fmt.Sprint("BindFramebuffer(READ_FRAMEBUFFER, ", msName, ")"),
+			"ReadBuffer(COLOR_ATTACHMENT0)",
+			fmt.Sprint("BindFramebuffer(DRAW_FRAMEBUFFER, ", c.name, ")"),
+			fmt.Sprint("BlitFramebuffer(0, 0, 64, 32, 0, 0, 64, 32, ", c.mask, ", NEAREST)"),
+			"BindFramebuffer(FRAMEBUFFER, 0)",
+		})
+		if fmt.Sprint(got) != want {
+			t.Errorf("got %v,\nwant %v", got, want)
+		}
+	}
+
+	small, err := gfx.NewMultisampleFramebuffer(32, 32, 4, gfx.PixelRGBA8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer small.Delete()
+	rec.Reset()
+	if err := small.Resolve(dst); err == nil {
+		t.Error("resolved into a framebuffer of another size")
+	}
+	if len(rec.Calls) != 0 {
+		t.Errorf("got %v resolving into a framebuffer of another size", rec.Calls)
+	}
+}
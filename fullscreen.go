@@ -0,0 +1,92 @@
+package gfx
+
+import (
+	"unsafe"
+)
+
+// FullscreenVertexShader draws a single triangle covering the viewport,
+// passing texture coordinates spanning it in the TexCoord varying. It is
+// built with DefaultVertexAttributes, for screen-space passes.
+const FullscreenVertexShader VertexShader = `
+attribute vec3 Position;
+
+varying vec2 TexCoord;
+
+void main() {
+	TexCoord = Position.xy * 0.5 + 0.5;
+	gl_Position = vec4(Position, 1.0);
+}`
+
+// CopyFragmentShader samples the Color texture, for use with
+// FullscreenVertexShader to copy a texture to the render target.
+const CopyFragmentShader FragmentShader = `
+uniform sampler2D Color;
+
+varying vec2 TexCoord;
+
+void main() {
+	gl_FragColor = texture2D(Color, TexCoord);
+}`
+
+var (
+	fullscreenGeom    *Geometry
+	fullscreenLayouts = make(map[*Shader]*GeometryLayout)
+)
+
+// fullscreenTriangle is a triangle whose corners lie outside the viewport
+// so that it covers it, which avoids the diagonal seam of a quad.
+type fullscreenTriangle struct{}
+
+var fullscreenVertices = [9]float32{
+	-1, -1, 0,
+	3, -1, 0,
+	-1, 3, 0,
+}
+
+func (fullscreenTriangle) VertexCount() int           { return 3 }
+func (fullscreenTriangle) VertexFormat() VertexFormat { return VertexPosition }
+func (fullscreenTriangle) CopyVertices(dest *VertexBuffer, usage Usage) error {
+	v := &fullscreenVertices
+	return dest.SetVertices((*[len(v) * 4]byte)(unsafe.Pointer(v))[:], usage)
+}
+
+// FullscreenGeometry returns a triangle covering the viewport, drawn with
+// glDrawArrays, and its layout for s, which must take only positions, such
+// as a shader built with FullscreenVertexShader. Both are created the first
+// time they are needed and shared afterwards; the layout is deleted along
+// with s, and the geometry must not be deleted.
+func FullscreenGeometry(s *Shader) (*Geometry, *GeometryLayout, error) {
+	if layout, ok := fullscreenLayouts[s]; ok {
+		return fullscreenGeom, layout, nil
+	}
+	if s.VertexFormat() != VertexPosition {
+		return nil, nil, ErrBadVertexFormat
+	}
+	if fullscreenGeom == nil {
+		geom, err := NewGeometry(fullscreenTriangle{}, StaticDraw)
+		if err != nil {
+			return nil, nil, err
+		}
+		fullscreenGeom = geom
+	}
+	layout, err := LayoutGeometry(s, fullscreenGeom)
+	if err != nil {
+		return nil, nil, err
+	}
+	fullscreenLayouts[s] = layout
+	return fullscreenGeom, layout, nil
+}
+
+// DrawFullscreen draws the fullscreen triangle with s, which must be in use
+// with its uniforms assigned.
+func DrawFullscreen(s *Shader) error {
+	_, layout, err := FullscreenGeometry(s)
+	if err != nil {
+		return err
+	}
+	if err := s.SetLayout(layout); err != nil {
+		return err
+	}
+	s.Draw()
+	return nil
+}
@@ -0,0 +1,64 @@
+package gfx_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+func TestFullscreen(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	geom, layout, err := gfx.FullscreenGeometry(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vao := rec.Ops("GenVertexArray")
+
+	// the geometry and layout are shared by later calls and draws
+	rec.Reset()
+	geom2, layout2, err := gfx.FullscreenGeometry(s)
+	if err != nil || geom2 != geom || layout2 != layout {
+		t.Errorf("got another geometry or layout, %v", err)
+	}
+	s.Use()
+	for i := 0; i < 2; i++ {
+		if err := gfx.DrawFullscreen(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []string
+	for _, c := range rec.Calls {
+		if c.Op != "UseProgram" {
+			got = append(got, c.String())
+		}
+	}
+	draw := "DrawArrays(TRIANGLES, 0, 3)"
+	want := fmt.Sprint([]string{draw, draw})
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// the layout goes with the shader
+	rec.Reset()
+	s.Delete()
+	if dels := rec.Ops("DeleteVertexArray"); len(dels) != 1 || dels[0].Args[0] != vao[0].Args[0] {
+		t.Errorf("got %v deleting the shader, want its layout deleted", dels)
+	}
+
+	attrs := gfx.VertexAttributes{gfx.VertexPosition: "Position", gfx.VertexColor: "Color"}
+	colored, err := gfx.BuildShader(attrs, colorVertexShader, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer colored.Delete()
+	if err := gfx.DrawFullscreen(colored); err != gfx.ErrBadVertexFormat {
+		t.Errorf("got %v drawing with a shader of colored vertices, want ErrBadVertexFormat", err)
+	}
+}
@@ -0,0 +1,157 @@
+package gfx
+
+import (
+	"j4k.co/gfx/internal/gl"
+	"sync"
+)
+
+// trashbin holds GL objects whose Go values were garbage collected without
+// being deleted. Finalizers run on their own goroutine, where GL calls are
+// not allowed, so they only queue names here for Collect to delete.
+var trashbin struct {
+	sync.Mutex
+	buffers      []gl.Buffer
+	textures     []gl.Texture
+	programs     []gl.Program
+	vaos         []gl.VertexArray
+	framebuffers []gl.Framebuffer
+	renderbufs   []gl.Renderbuffer
+	deleters     []Deleter
+
+	// layouts holds the cache ids of shaders and geometry whose cached
+	// layouts must be evicted.
+	layouts []uint64
+}
+
+// Deleter is implemented by resources that free GL objects, such as
+// Geometry and Shader.
+type Deleter interface {
+	Delete()
+}
+
+// DeleteLater queues d to be deleted by the next Collect. Unlike calling
+// Delete directly, it is safe to call from any goroutine.
+func DeleteLater(d Deleter) {
+	trashbin.Lock()
+	trashbin.deleters = append(trashbin.deleters, d)
+	trashbin.Unlock()
+}
+
+// Collect deletes resources queued with DeleteLater, and the GL objects of
+// resources that were garbage collected without being deleted. It must be
+// called on the GL thread, typically once per frame.
+func Collect() {
+	trashbin.Lock()
+	buffers, textures := trashbin.buffers, trashbin.textures
+	programs, vaos := trashbin.programs, trashbin.vaos
+	framebuffers, deleters := trashbin.framebuffers, trashbin.deleters
+	renderbufs, layouts := trashbin.renderbufs, trashbin.layouts
+	trashbin.buffers, trashbin.textures = nil, nil
+	trashbin.programs, trashbin.vaos = nil, nil
+	trashbin.framebuffers, trashbin.deleters = nil, nil
+	trashbin.renderbufs, trashbin.layouts = nil, nil
+	trashbin.Unlock()
+
+	for _, id := range layouts {
+		evictLayouts(id)
+	}
+	for _, d := range deleters {
+		d.Delete()
+	}
+	if len(buffers) > 0 {
+		gl.DeleteBuffers(buffers)
+	}
+	for _, t := range textures {
+		deleteTexture(t)
+	}
+	for _, p := range programs {
+		deleteProgram(p)
+	}
+	for _, v := range vaos {
+		deleteVertexArray(v)
+	}
+	for _, f := range framebuffers {
+		f.Delete()
+	}
+	for _, r := range renderbufs {
+		r.Delete()
+	}
+}
+
+func trashBuffers(bufs ...gl.Buffer) {
+	trashbin.Lock()
+	for _, b := range bufs {
+		if b != 0 {
+			trashbin.buffers = append(trashbin.buffers, b)
+		}
+	}
+	trashbin.Unlock()
+}
+
+func (g *Geometry) finalize() {
+	collected(g)
+	trashBuffers(g.VertexBuffer.buf, g.IndexBuffer.buf, g.Instances.buf)
+	for _, b := range g.planes {
+		trashBuffers(b.buf)
+	}
+	trashLayouts(g.cacheID)
+}
+
+func (s *Sampler2D) finalize() {
+	collected(s)
+	trashbin.Lock()
+	trashbin.textures = append(trashbin.textures, s.tex)
+	trashbin.Unlock()
+}
+
+func (c *SamplerCube) finalize() {
+	collected(c)
+	trashbin.Lock()
+	trashbin.textures = append(trashbin.textures, c.tex)
+	trashbin.Unlock()
+}
+
+func (s *Shader) finalize() {
+	collected(s)
+	trashbin.Lock()
+	trashbin.programs = append(trashbin.programs, s.prog)
+	trashbin.Unlock()
+	trashLayouts(s.cacheID)
+}
+
+func trashLayouts(id uint64) {
+	if id == 0 {
+		return
+	}
+	trashbin.Lock()
+	trashbin.layouts = append(trashbin.layouts, id)
+	trashbin.Unlock()
+}
+
+func (g *GeometryLayout) finalize() {
+	collected(g)
+	if g.vao == 0 {
+		return
+	}
+	trashbin.Lock()
+	trashbin.vaos = append(trashbin.vaos, g.vao)
+	trashbin.Unlock()
+}
+
+func (f *Framebuffer) finalize() {
+	collected(f)
+	trashbin.Lock()
+	trashbin.framebuffers = append(trashbin.framebuffers, f.fbo)
+	trashbin.renderbufs = append(trashbin.renderbufs, f.renderbufs...)
+	trashbin.Unlock()
+}
+
+func (b *UniformBlock) finalize() {
+	collected(b)
+	trashBuffers(b.buf)
+}
+
+func (r *Recorder) finalize() {
+	collected(r)
+	trashBuffers(r.pbos[:]...)
+}
@@ -0,0 +1,126 @@
+package gfx_test
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// collect runs the garbage collector and gfx.Collect until the recorder
+// has seen calls of each op in want, in the counts given.
+func collect(t *testing.T, rec *gfxtest.Recorder, want map[string]int) {
+	for i := 0; i < 50; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+		gfx.Collect()
+		done := true
+		for op, n := range want {
+			done = done && len(rec.Ops(op)) >= n
+		}
+		if done {
+			break
+		}
+	}
+	for op, n := range want {
+		if got := len(rec.Ops(op)); got != n {
+			t.Errorf("got %d %s calls, want %d", got, op, n)
+		}
+	}
+}
+
+// drain collects the resources other tests dropped, so that they are not
+// counted by collect.
+func drain(rec *gfxtest.Recorder) {
+	for quiet := 0; quiet < 2; {
+		rec.Reset()
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+		gfx.Collect()
+		quiet++
+		if len(rec.Calls) > 0 {
+			quiet = 0
+		}
+	}
+}
+
+func TestCollectFinalized(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+	drain(rec)
+
+	func() {
+		s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, tintShader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := gfx.NewSampler2D(4, 4, gfx.PixelRGBA8); err != nil {
+			t.Fatal(err)
+		}
+		geom := quad(t)
+		if _, err := gfx.LayoutGeometry(s, geom); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	rec.Reset()
+	// nothing is deleted off the GL thread, only by Collect
+	runtime.GC()
+	time.Sleep(time.Millisecond)
+	if len(rec.Calls) != 0 {
+		t.Errorf("got calls %v before Collect", rec.Calls)
+	}
+	collect(t, rec, map[string]int{
+		"DeleteBuffers":     1,
+		"DeleteTexture":     1,
+		"DeleteProgram":     1,
+		"DeleteVertexArray": 1,
+	})
+	if bufs := rec.Ops("DeleteBuffers"); len(bufs) == 1 && len(bufs[0].Args[0].([]uint32)) != 2 {
+		t.Errorf("got %v, want the vertex and index buffers", bufs[0])
+	}
+}
+
+func TestCollectFramebuffers(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+	drain(rec)
+
+	func() {
+		if _, err := gfx.NewMultisampleFramebuffer(16, 16, 4, gfx.PixelRGBA8, gfx.PixelDepth24); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	rec.Reset()
+	collect(t, rec, map[string]int{
+		"DeleteFramebuffer":  1,
+		"DeleteRenderbuffer": 2,
+	})
+}
+
+func TestDeleteLater(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	geom := quad(t)
+	rec.Reset()
+	done := make(chan bool)
+	go func() {
+		gfx.DeleteLater(geom)
+		done <- true
+	}()
+	<-done
+	if len(rec.Calls) != 0 {
+		t.Errorf("got calls %v before Collect", rec.Calls)
+	}
+	gfx.Collect()
+	if bufs := rec.Ops("DeleteBuffers"); len(bufs) != 2 {
+		t.Errorf("got %v, want the vertex and index buffers deleted", bufs)
+	}
+	// the queue is emptied
+	rec.Reset()
+	gfx.Collect()
+	if len(rec.Calls) != 0 {
+		t.Errorf("got calls %v collecting again", rec.Calls)
+	}
+}
@@ -0,0 +1,256 @@
+package gfx
+
+import (
+	"j4k.co/gfx/internal/gl"
+)
+
+// The color attachments of a GBuffer, in the order geometry shaders write
+// them through gl_FragData.
+const (
+	GBufferAlbedo   = iota // RGBA8: base color, and alpha free for use
+	GBufferNormal          // RGBA16F: world-space normal in xyz
+	GBufferMaterial        // RGBA8: metallic, roughness, occlusion, emission
+	GBufferAttachments
+)
+
+// gbufferFormats are the formats of the color attachments.
+var gbufferFormats = [GBufferAttachments]PixelFormat{PixelRGBA8, PixelRGBA16F, PixelRGBA8}
+
+// DirectionalLightShader resolves a GBuffer lit by one directional light,
+// for use with FullscreenVertexShader and GBuffer.Resolve. LightDirection
+// points towards the light in world space.
+const DirectionalLightShader FragmentShader = `
+uniform sampler2D GAlbedo;
+uniform sampler2D GNormal;
+uniform sampler2D GMaterial;
+uniform vec3 LightDirection;
+uniform vec3 LightColor;
+uniform vec3 AmbientColor;
+
+varying vec2 TexCoord;
+
+void main() {
+	vec3 albedo = texture2D(GAlbedo, TexCoord).rgb;
+	vec3 n = texture2D(GNormal, TexCoord).xyz;
+	vec4 material = texture2D(GMaterial, TexCoord);
+	if (dot(n, n) == 0.0) {
+		discard;
+	}
+	float diffuse = max(dot(normalize(n), normalize(LightDirection)), 0.0);
+	vec3 lit = albedo * (LightColor * diffuse + AmbientColor * material.b);
+	gl_FragColor = vec4(lit + albedo * material.a, 1.0);
+}`
+
+// GBuffer is the render target of deferred shading. Geometry is drawn into
+// its attachments once, with shaders writing surface properties rather than
+// colors:
+//
+//	gl_FragData[0] = vec4(albedo, 1.0);
+//	gl_FragData[1] = vec4(normal, 0.0);
+//	gl_FragData[2] = vec4(metallic, roughness, occlusion, emission);
+//
+// and each light is then applied in screen space by Resolve, reading the
+// attachments through whichever of these uniforms its shader declares:
+//
+//	uniform sampler2D GAlbedo;
+//	uniform sampler2D GNormal;
+//	uniform sampler2D GMaterial;
+//	uniform sampler2D GDepth;
+//	uniform vec2 TexelSize;
+//
+// Typical use is to call Begin, draw the scene, then bind the output
+// target and call Resolve once for each light, or to add the same steps to
+// a RenderGraph with AddPass and AddResolvePass.
+type GBuffer struct {
+	width, height int
+
+	color [GBufferAttachments]*Sampler2D
+	depth *Sampler2D
+	fb    *Framebuffer
+}
+
+// lightState adds the contribution of each light without depth testing.
+var lightState = State{
+	Blend:        Additive,
+	NoDepthTest:  true,
+	NoDepthWrite: true,
+	Cull:         CullNone,
+}
+
+// NewGBuffer allocates the attachments of a G-buffer of the given size.
+func NewGBuffer(width, height int) (*GBuffer, error) {
+	g := &GBuffer{}
+	if err := g.Resize(width, height); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Resize reallocates the attachments for a new size. Their contents are
+// lost.
+func (g *GBuffer) Resize(width, height int) error {
+	g.deleteTargets()
+	textures := make([]*Sampler2D, 0, GBufferAttachments+1)
+	for i, format := range gbufferFormats {
+		tex, err := newSampler2D(nil, width, height, format, &renderTargetOptions)
+		if err != nil {
+			g.deleteTargets()
+			return err
+		}
+		g.color[i] = tex
+		textures = append(textures, tex)
+	}
+	depth, err := newSampler2D(nil, width, height, PixelDepth24, &renderTargetOptions)
+	if err != nil {
+		g.deleteTargets()
+		return err
+	}
+	g.depth = depth
+	fb, err := NewFramebuffer(append(textures, depth)...)
+	if err != nil {
+		g.deleteTargets()
+		return err
+	}
+	g.fb = fb
+	g.width, g.height = width, height
+	return nil
+}
+
+func (g *GBuffer) deleteTargets() {
+	if g.fb != nil {
+		g.fb.Delete()
+		g.fb = nil
+	}
+	for i, tex := range g.color {
+		if tex != nil {
+			tex.Delete()
+			g.color[i] = nil
+		}
+	}
+	if g.depth != nil {
+		g.depth.Delete()
+		g.depth = nil
+	}
+}
+
+// Delete frees the attachments.
+func (g *GBuffer) Delete() {
+	g.deleteTargets()
+}
+
+// Size returns the width and height of the G-buffer in pixels.
+func (g *GBuffer) Size() (width, height int) {
+	return g.width, g.height
+}
+
+// Texture returns the color attachment i, such as GBufferNormal.
+func (g *GBuffer) Texture(i int) *Sampler2D {
+	return g.color[i]
+}
+
+// Depth returns the depth attachment.
+func (g *GBuffer) Depth() *Sampler2D {
+	return g.depth
+}
+
+// Framebuffer returns the framebuffer drawing into all attachments.
+func (g *GBuffer) Framebuffer() *Framebuffer {
+	return g.fb
+}
+
+// Begin binds the G-buffer and clears its attachments, for the scene to be
+// drawn into it. Cleared normals are zero, which DirectionalLightShader
+// leaves unlit. It applies the zero State first, so that the clear is not
+// masked by the depth writes Resolve turns off, and the scene is depth
+// tested and written.
+func (g *GBuffer) Begin() {
+	g.fb.Bind()
+	g.clear()
+}
+
+func (g *GBuffer) clear() {
+	var s State
+	s.Apply()
+	gl.ClearColor(0, 0, 0, 0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+}
+
+// GBufferTargets are the attachments of a GBuffer declared on a
+// RenderGraph.
+type GBufferTargets struct {
+	Color [GBufferAttachments]RenderTarget
+	Depth RenderTarget
+}
+
+// AddPass adds a pass to rg that clears the G-buffer and draws the scene
+// into it with draw, as after Begin. It returns the pass and the targets of
+// the attachments, for AddResolvePass and other passes to Read.
+func (g *GBuffer) AddPass(rg *RenderGraph, draw func(*PassContext)) (*RenderPass, GBufferTargets) {
+	var ts GBufferTargets
+	adopted := rg.adopt("gbuffer", g.fb)
+	copy(ts.Color[:], adopted)
+	ts.Depth = adopted[GBufferAttachments]
+	p := rg.AddPass("gbuffer", func(c *PassContext) {
+		g.clear()
+		draw(c)
+	})
+	for _, t := range adopted {
+		p.Write(t)
+	}
+	return p, ts
+}
+
+// AddResolvePass adds a pass to rg that reads the G-buffer targets in and
+// draws into out with resolve, which calls Resolve once for each light. An
+// error from resolve stops the graph.
+func (g *GBuffer) AddResolvePass(rg *RenderGraph, in GBufferTargets, out RenderTarget, resolve func(*PassContext) error) *RenderPass {
+	p := rg.AddPass("gbuffer resolve", func(c *PassContext) {
+		if err := resolve(c); err != nil {
+			c.Fail(err)
+		}
+	})
+	for _, t := range in.Color {
+		p.Read(t)
+	}
+	return p.Read(in.Depth).Write(out)
+}
+
+// gbufferInputs are the texel size input of resolve shaders.
+type gbufferInputs struct {
+	TexelSize [2]float32 `uniform:"TexelSize"`
+}
+
+// Resolve draws a fullscreen pass with s, built with FullscreenVertexShader,
+// into the bound framebuffer, after assigning the attachments it declares
+// and the optional uniform struct uniforms. It applies a State that adds to
+// what is already drawn, without depth testing, so that the passes of
+// several lights accumulate.
+func (g *GBuffer) Resolve(s *Shader, uniforms interface{}) error {
+	lightState.Apply()
+	s.Use()
+	names := [GBufferAttachments]string{"GAlbedo", "GNormal", "GMaterial"}
+	for i, name := range names {
+		if s.HasUniform(name) {
+			if err := s.SetTexture(name, g.color[i]); err != nil {
+				return err
+			}
+		}
+	}
+	if s.HasUniform("GDepth") {
+		if err := s.SetTexture("GDepth", g.depth); err != nil {
+			return err
+		}
+	}
+	if s.HasUniform("TexelSize") {
+		in := gbufferInputs{[2]float32{1 / float32(g.width), 1 / float32(g.height)}}
+		if err := s.AssignUniforms(&in); err != nil {
+			return err
+		}
+	}
+	if uniforms != nil {
+		if err := s.AssignUniforms(uniforms); err != nil {
+			return err
+		}
+	}
+	return DrawFullscreen(s)
+}
@@ -0,0 +1,58 @@
+package gfx_test
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+func TestGBuffer(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	g, err := gfx.NewGBuffer(64, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Delete()
+	calls := rec.Ops("DrawBuffers")
+	if len(calls) != 1 || len(calls[0].Args[0].([]gfxtest.Enum)) != gfx.GBufferAttachments {
+		t.Errorf("got %v, want the three color attachments drawn", calls)
+	}
+	if err := g.Framebuffer().SetDrawBuffers(gfx.GBufferAlbedo, gfx.GBufferMaterial); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Framebuffer().SetDrawBuffers(gfx.GBufferAttachments); err == nil {
+		t.Error("no error drawing into a missing attachment")
+	}
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, gfx.DirectionalLightShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	lights := struct {
+		Direction [3]float32 `uniform:"LightDirection"`
+		Color     [3]float32 `uniform:"LightColor"`
+		Ambient   [3]float32 `uniform:"AmbientColor"`
+	}{[3]float32{0, 1, 0}, [3]float32{1, 1, 1}, [3]float32{0.1, 0.1, 0.1}}
+	rec.Reset()
+	if err := g.Resolve(s, &lights); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"GAlbedo", "GNormal", "GMaterial", "LightDirection"} {
+		if _, ok := rec.Uniform(name); !ok {
+			t.Errorf("%s was not assigned", name)
+		}
+	}
+	if n := len(rec.Draws()); n != 1 {
+		t.Errorf("got %d draws, want 1", n)
+	}
+
+	// Resolve turned depth writes off, which would mask the next clear
+	rec.Reset()
+	g.Begin()
+	if masks := rec.Ops("DepthMask"); len(masks) != 1 || masks[0].Args[0] != true {
+		t.Errorf("got %v before clearing, want depth writes enabled", masks)
+	}
+}
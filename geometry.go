@@ -2,8 +2,11 @@ package gfx
 
 import (
 	"errors"
-	"github.com/go-gl/gl"
+	"fmt"
+	"j4k.co/gfx/internal/gl"
 	"reflect"
+	"strconv"
+	"strings"
 	"unsafe"
 )
 
@@ -19,6 +22,10 @@ const (
 )
 
 func (u Usage) gl() gl.GLenum {
+	detectCaps()
+	if !caps.copyUsage && u >= StaticCopy {
+		u -= StaticCopy
+	}
 	switch u {
 	case StaticDraw:
 		return gl.STATIC_DRAW
@@ -58,16 +65,49 @@ const (
 	VertexUserData1
 	VertexUserData2
 	VertexUserData3
-	MaxVertexFormat = VertexUserData3
+
+	// VertexBoneIndices are four joint indices of a skeleton, as unsigned
+	// bytes read by uvec4 attributes, and VertexBoneWeights the weights of
+	// those joints, as normalized unsigned bytes read by vec4 attributes.
+	VertexBoneIndices
+	VertexBoneWeights
+	MaxVertexFormat = VertexBoneWeights
 )
 
-// AttribBytes gives the byte size of a specific piece of vertex data
+// Packing modifiers, combined with a format to store some of its data more
+// compactly, which roughly halves the size of typical vertices. Shaders
+// declare and read the data as usual.
+const (
+	// VertexHalfTexcoords stores texture coordinates as 16-bit floats.
+	VertexHalfTexcoords VertexFormat = 1 << (24 + iota)
+
+	// VertexPackedNormals stores normals, tangents, and bitangents as
+	// signed normalized 10-bit components, packed as INT_2_10_10_10_REV.
+	VertexPackedNormals
+
+	// VertexIntUserData stores user data as four 32-bit integers, which
+	// shaders read as ivec4 or uvec4 attributes, such as bone indices.
+	VertexIntUserData
+
+	vertexPacking = VertexHalfTexcoords | VertexPackedNormals | VertexIntUserData
+)
+
+// Unpacked returns v without its packing modifiers, the format of the data
+// as shaders declare it.
+func (v VertexFormat) Unpacked() VertexFormat {
+	return v &^ vertexPacking
+}
+
+// AttribBytes gives the byte size of a specific piece of vertex data,
+// unpacked.
 func (v VertexFormat) AttribBytes() int {
 	const fsize = 4
 	switch v {
 	case VertexColor,
-		VertexColor1:
-		// RGBA, 8-bit channels
+		VertexColor1,
+		VertexBoneIndices,
+		VertexBoneWeights:
+		// 8-bit channels
 		return 4
 	case VertexTexcoord,
 		VertexTexcoord1,
@@ -92,7 +132,9 @@ func (v VertexFormat) AttribBytes() int {
 func (v VertexFormat) attribType() gl.GLenum {
 	switch v {
 	case VertexColor,
-		VertexColor1:
+		VertexColor1,
+		VertexBoneIndices,
+		VertexBoneWeights:
 		return gl.UNSIGNED_BYTE
 	default:
 		return gl.FLOAT
@@ -103,7 +145,8 @@ func (v VertexFormat) attribType() gl.GLenum {
 func (v VertexFormat) attribNormalized() bool {
 	switch v {
 	case VertexColor,
-		VertexColor1:
+		VertexColor1,
+		VertexBoneWeights:
 		return true
 	default:
 		return false
@@ -114,7 +157,9 @@ func (v VertexFormat) attribNormalized() bool {
 func (v VertexFormat) attribElems() uint {
 	switch v {
 	case VertexColor,
-		VertexColor1:
+		VertexColor1,
+		VertexBoneIndices,
+		VertexBoneWeights:
 		return 4
 	case VertexTexcoord,
 		VertexTexcoord1,
@@ -135,13 +180,50 @@ func (v VertexFormat) attribElems() uint {
 	}
 }
 
+// AttribSize gives the byte size of the piece of vertex data attrib in
+// vertices of format v, which is smaller than AttribBytes if v packs it.
+func (v VertexFormat) AttribSize(attrib VertexFormat) int {
+	switch {
+	case v&VertexHalfTexcoords != 0 && attrib.isTexcoord():
+		return 4
+	case v&VertexPackedNormals != 0 && attrib.isNormal():
+		return 4
+	}
+	return attrib.AttribBytes()
+}
+
+func (v VertexFormat) isTexcoord() bool {
+	return v >= VertexTexcoord && v <= VertexTexcoord7
+}
+
+func (v VertexFormat) isNormal() bool {
+	return v == VertexNormal || v == VertexTangent || v == VertexBitangent
+}
+
+// attribPointer sets the pointer of the attribute at loc to attrib in
+// vertices of format v, at offset in the bound array buffer.
+func (v VertexFormat) attribPointer(loc gl.AttribLocation, attrib VertexFormat, stride, offset int) {
+	switch {
+	case v&VertexHalfTexcoords != 0 && attrib.isTexcoord():
+		loc.AttribPointer(2, gl.HALF_FLOAT, false, stride, uintptr(offset))
+	case v&VertexPackedNormals != 0 && attrib.isNormal():
+		loc.AttribPointer(4, gl.INT_2_10_10_10_REV, true, stride, uintptr(offset))
+	case v&VertexIntUserData != 0 && attrib >= VertexUserData && attrib <= VertexUserData3:
+		loc.AttribIPointer(4, gl.INT, stride, uintptr(offset))
+	case attrib == VertexBoneIndices:
+		loc.AttribIPointer(4, gl.UNSIGNED_BYTE, stride, uintptr(offset))
+	default:
+		loc.AttribPointer(attrib.attribElems(), attrib.attribType(), attrib.attribNormalized(), stride, uintptr(offset))
+	}
+}
+
 // Stride gives the stride in bytes for a vertex buffer.
 func (v VertexFormat) Stride() int {
 	var i VertexFormat
 	stride := 0
 	for i = 1; i <= MaxVertexFormat; i <<= 1 {
 		if v&i != 0 {
-			stride += i.AttribBytes()
+			stride += v.AttribSize(i)
 		}
 	}
 	return stride
@@ -158,6 +240,38 @@ func (v VertexFormat) Count() int {
 	return count
 }
 
+var vertexFormatNames = [...]string{
+	"Position", "Color", "Color1", "Normal", "Tangent", "Bitangent",
+	"Texcoord", "Texcoord1", "Texcoord2", "Texcoord3", "Texcoord4",
+	"Texcoord5", "Texcoord6", "Texcoord7",
+	"UserData", "UserData1", "UserData2", "UserData3",
+	"BoneIndices", "BoneWeights",
+}
+
+var vertexPackingNames = [...]string{
+	"HalfTexcoords", "PackedNormals", "IntUserData",
+}
+
+// String returns the names of the vertex data in v, such as
+// "Position|Normal|Texcoord", followed by its packing modifiers.
+func (v VertexFormat) String() string {
+	var names []string
+	for i, name := range vertexFormatNames {
+		if v&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	for i, name := range vertexPackingNames {
+		if v&(VertexHalfTexcoords<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "0"
+	}
+	return strings.Join(names, "|")
+}
+
 // VertexAttributes maps shader attributes by name to specific vertex data,
 // and as a whole a complete VertexFormat for geometry.
 type VertexAttributes map[VertexFormat]string
@@ -186,11 +300,24 @@ func (v VertexAttributes) clone() VertexAttributes {
 var ErrBadVertexFormat = errors.New("gfx: bad vertex format")
 var errMapBufferFailed = errors.New("gfx: mapbuffer failed")
 
+// ErrBufferRange is returned by partial updates that fall outside a buffer.
+var ErrBufferRange = errors.New("gfx: update out of buffer range")
+
 // VertexBuffer represents interleaved vertices for a VertexFormat set.
 type VertexBuffer struct {
 	buf    gl.Buffer
 	count  int
 	format VertexFormat
+
+	// cap is the allocated size in bytes, and head the end of the last
+	// write, for StreamWrite.
+	cap  int
+	head int
+
+	// measure asks SetVertices to measure the bounds of the positions it
+	// copies, for geometry whose source does not know them.
+	measure  bool
+	measured bounds
 }
 
 func (b *VertexBuffer) bind() {
@@ -210,28 +337,54 @@ func (b *VertexBuffer) Format() VertexFormat {
 }
 
 func (b *VertexBuffer) SetVertices(src []byte, usage Usage) error {
-	gl.VertexArray(0).Bind()
+	if b.measure && b.format&VertexPosition != 0 {
+		b.measured = measureBounds(src, b.format.Stride())
+	}
+	return b.mapWrite(len(src), usage, func(dest []byte) error {
+		copy(dest, src)
+		return nil
+	})
+}
+
+// MapWrite orphans the buffer, resizes it to size bytes, and calls fn with
+// the mapped buffer memory to fill, avoiding an intermediate copy. If the
+// driver reports that the mapped memory was lost when unmapping, fn is
+// called again with a fresh mapping, so fn must write the whole slice each
+// time and must not keep it. An error from fn is returned after unmapping.
+func (b *VertexBuffer) MapWrite(size int, fn func(dest []byte) error) error {
+	return b.mapWrite(size, StreamDraw, fn)
+}
+
+func (b *VertexBuffer) mapWrite(size int, usage Usage, fn func(dest []byte) error) error {
+	unbindVertexArray()
 	b.bind()
 	// set size of buffer and invalidate it
-	gl.BufferData(gl.ARRAY_BUFFER, len(src), nil, usage.gl())
-	if len(src) > 0 {
+	gl.BufferData(gl.ARRAY_BUFFER, size, nil, usage.gl())
+	b.cap, b.head = size, size
+	detectCaps()
+	if size > 0 && !caps.mapBuffer {
+		data := make([]byte, size)
+		if err := fn(data); err != nil {
+			return err
+		}
+		gl.BufferSubData(gl.ARRAY_BUFFER, 0, size, data)
+	} else if size > 0 {
 		// if unmap returns false, the buffer we wrote to is no longer valid and we
 		// need to try again. though, this is apparently uncommon in modern
-		// drivers. this means it is not feasible to compute/copy vertices directly
-		// into the mapped buffer. however, it would be nice to provide a
-		// failure-capable API to do this.
+		// drivers.
 		const maxretries = 5
 		retries := 0
 		for ; retries < maxretries; retries++ {
 			ptr := gl.MapBuffer(gl.ARRAY_BUFFER, gl.WRITE_ONLY)
-			slicehdr := reflect.SliceHeader{
-				Data: uintptr(ptr),
-				Len:  len(src),
-				Cap:  len(src),
+			if ptr == nil {
+				return errMapBufferFailed
 			}
-			dest := *(*[]byte)(unsafe.Pointer(&slicehdr))
-			copy(dest, src)
-			if gl.UnmapBuffer(gl.ARRAY_BUFFER) {
+			err := fn(bytesAt(ptr, size))
+			ok := gl.UnmapBuffer(gl.ARRAY_BUFFER)
+			if err != nil {
+				return err
+			}
+			if ok {
 				break
 			}
 		}
@@ -239,13 +392,99 @@ func (b *VertexBuffer) SetVertices(src []byte, usage Usage) error {
 			return errMapBufferFailed
 		}
 	}
-	b.count = len(src) / b.format.Stride()
+	b.count = size / b.format.Stride()
+	countUpload(size)
+	checkError("VertexBuffer write %d bytes", size)
+	return nil
+}
+
+// StreamWrite appends size bytes of vertices to the buffer, treated as a
+// ring, and returns the byte offset they were written at. fn is called to
+// fill the mapped range. Ranges are mapped unsynchronized, so data written
+// earlier that may still be in use by the GPU is never overwritten: when the
+// buffer is full it is orphaned and writing starts over at offset zero. The
+// buffer grows as needed to hold size bytes.
+//
+// Typical use is to stream each frame's dynamic vertices and draw them with
+// Shader.DrawRange(offset/stride, size/stride). Count reports the vertices
+// written since the buffer was last orphaned.
+func (b *VertexBuffer) StreamWrite(size int, fn func(dest []byte) error) (offset int, err error) {
+	unbindVertexArray()
+	b.bind()
+	stride := b.format.Stride()
+	// keep each write aligned to whole vertices
+	head := (b.head + stride - 1) / stride * stride
+	if head+size > b.cap {
+		if size > b.cap {
+			b.cap = 2 * b.cap
+			if b.cap < size {
+				b.cap = size
+			}
+		}
+		gl.BufferData(gl.ARRAY_BUFFER, b.cap, nil, gl.STREAM_DRAW)
+		head = 0
+	}
+	if size == 0 {
+		return head, nil
+	}
+	detectCaps()
+	if caps.mapBufferRange {
+		ptr := gl.MapBufferRange(gl.ARRAY_BUFFER, head, size,
+			gl.MAP_WRITE_BIT|gl.MAP_UNSYNCHRONIZED_BIT|gl.MAP_INVALIDATE_RANGE_BIT)
+		if ptr == nil {
+			return 0, errMapBufferFailed
+		}
+		err = fn(bytesAt(ptr, size))
+		if !gl.UnmapBuffer(gl.ARRAY_BUFFER) && err == nil {
+			err = errMapBufferFailed
+		}
+	} else {
+		data := make([]byte, size)
+		if err = fn(data); err == nil {
+			gl.BufferSubData(gl.ARRAY_BUFFER, head, size, data)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	b.head = head + size
+	b.count = b.head / stride
+	countUpload(size)
+	checkError("VertexBuffer.StreamWrite %d bytes", size)
+	return head, nil
+}
+
+// bytesAt returns a slice over size bytes of mapped memory.
+func bytesAt(ptr unsafe.Pointer, size int) []byte {
+	slicehdr := reflect.SliceHeader{
+		Data: uintptr(ptr),
+		Len:  size,
+		Cap:  size,
+	}
+	return *(*[]byte)(unsafe.Pointer(&slicehdr))
+}
+
+// SetVerticesAt overwrites part of the buffer with src, starting offset
+// bytes in, without reallocating it. The range must lie within the
+// vertices previously set with SetVertices, and should cover whole vertices.
+func (b *VertexBuffer) SetVerticesAt(offset int, src []byte) error {
+	if offset < 0 || offset+len(src) > b.count*b.format.Stride() {
+		return ErrBufferRange
+	}
+	if len(src) == 0 {
+		return nil
+	}
+	unbindVertexArray()
+	b.bind()
+	gl.BufferSubData(gl.ARRAY_BUFFER, offset, len(src), src)
+	countUpload(len(src))
+	checkError("VertexBuffer.SetVerticesAt %d bytes at %d", len(src), offset)
 	return nil
 }
 
 type IndexBuffer struct {
-	buf gl.Buffer
-	//offset int
+	buf      gl.Buffer
+	offset   int
 	count    int
 	elemtype gl.GLenum
 }
@@ -258,34 +497,46 @@ func (b *IndexBuffer) Delete() {
 	b.buf.Delete()
 }
 
-/*
+// Slice returns a view of indices [i, j) of the buffer, sharing the same
+// buffer object. Slices are drawn with Shader.DrawSlice, or by assigning one
+// to a Geometry's IndexBuffer before SetGeometry. Deleting a slice deletes
+// the underlying buffer.
 func (b *IndexBuffer) Slice(i, j int) IndexBuffer {
-	if j < i || i < 0 || j < 0 || i >= b.count || j > b.count {
+	if j < i || i < 0 || j > b.count {
 		panic("IndexBuffer.Slice bounds out of range")
 	}
 	return IndexBuffer{
-		buf:    b.buf,
-		offset: b.offset + i,
-		count:  j - i,
+		buf:      b.buf,
+		offset:   b.offset + i,
+		count:    j - i,
+		elemtype: b.elemtype,
 	}
 }
 
+// Offset returns the index the buffer starts at within its buffer object.
 func (b *IndexBuffer) Offset() int {
 	return b.offset
 }
-*/
 
 func (b *IndexBuffer) Count() int {
 	return b.count
 }
 
+// byteOffset gives the offset of the first index in bytes.
+func (b *IndexBuffer) byteOffset() int {
+	if b.elemtype == gl.UNSIGNED_INT {
+		return b.offset * 4
+	}
+	return b.offset * 2
+}
+
 func (b *IndexBuffer) SetIndices(src []uint16, usage Usage) error {
 	buf := uint16buf{buf: src}
 	err := b.setIndices(buf.copyTo, usage, 2*len(src))
 	if err != nil {
 		return err
 	}
-	//b.offset = 0
+	b.offset = 0
 	b.count = len(src)
 	b.elemtype = gl.UNSIGNED_SHORT
 	return nil
@@ -297,17 +548,59 @@ func (b *IndexBuffer) SetIndices32(src []uint32, usage Usage) error {
 	if err != nil {
 		return err
 	}
-	//b.offset = 0
+	b.offset = 0
 	b.count = len(src)
 	b.elemtype = gl.UNSIGNED_INT
 	return nil
 }
 
+// SetIndicesAt overwrites indices starting at index offset, relative to the
+// start of b, without reallocating the buffer. The buffer must hold 16-bit
+// indices and the range must lie within b.
+func (b *IndexBuffer) SetIndicesAt(offset int, src []uint16) error {
+	if b.elemtype != gl.UNSIGNED_SHORT {
+		return errors.New("gfx: index buffer does not hold 16-bit indices")
+	}
+	return b.setIndicesAt(offset, len(src), src)
+}
+
+// SetIndicesAt32 is like SetIndicesAt for a buffer of 32-bit indices.
+func (b *IndexBuffer) SetIndicesAt32(offset int, src []uint32) error {
+	if b.elemtype != gl.UNSIGNED_INT {
+		return errors.New("gfx: index buffer does not hold 32-bit indices")
+	}
+	return b.setIndicesAt(offset, len(src), src)
+}
+
+func (b *IndexBuffer) setIndicesAt(offset, n int, src interface{}) error {
+	if offset < 0 || offset+n > b.count {
+		return ErrBufferRange
+	}
+	if n == 0 {
+		return nil
+	}
+	size := 2
+	if b.elemtype == gl.UNSIGNED_INT {
+		size = 4
+	}
+	unbindVertexArray()
+	b.bind()
+	gl.BufferSubData(gl.ELEMENT_ARRAY_BUFFER, (b.offset+offset)*size, n*size, src)
+	countUpload(n * size)
+	checkError("IndexBuffer update %d indices at %d", n, offset)
+	return nil
+}
+
 func (b *IndexBuffer) setIndices(copyTo func(unsafe.Pointer), usage Usage, size int) error {
-	gl.VertexArray(0).Bind()
+	unbindVertexArray()
 	b.bind()
 	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, size, nil, usage.gl())
-	if size > 0 {
+	detectCaps()
+	if size > 0 && !caps.mapBuffer {
+		data := make([]byte, size)
+		copyTo(unsafe.Pointer(&data[0]))
+		gl.BufferSubData(gl.ELEMENT_ARRAY_BUFFER, 0, size, data)
+	} else if size > 0 {
 		const maxretries = 5
 		retries := 0
 		for ; retries < maxretries; retries++ {
@@ -321,6 +614,8 @@ func (b *IndexBuffer) setIndices(copyTo func(unsafe.Pointer), usage Usage, size
 			return errMapBufferFailed
 		}
 	}
+	countUpload(size)
+	checkError("IndexBuffer write %d bytes", size)
 	return nil
 }
 
@@ -370,15 +665,43 @@ type Geometry struct {
 	usage Usage
 	VertexBuffer
 	IndexBuffer
+
+	// Instances holds optional per-instance vertex data. See SetInstances.
+	Instances VertexBuffer
+
+	// planes hold attributes in buffers of their own. See AddVertexBuffer.
+	planes []*VertexBuffer
+
+	// Primitive is the kind of primitive the vertices form, Triangles by
+	// default.
+	Primitive Primitive
+
+	// PrimitiveRestart makes indices of RestartIndex16 or RestartIndex32,
+	// the largest of their type, end a strip or fan and begin the next. It
+	// requires OpenGL 3.1 or OpenGL ES 3.0.
+	PrimitiveRestart bool
+
+	bounds bounds
+
+	// cacheID identifies the geometry in the layout cache, once assigned.
+	cacheID uint64
+
+	// label names the buffers in GPU debuggers.
+	label string
 }
 
 // NewGeometry copies vertices from src as well as indices if IndexData
-// is implemented, into newly allocated buffer objects.
+// is implemented, into newly allocated buffer objects. The bounds of src
+// are kept if it implements BoundsData, and are otherwise measured from the
+// positions it copies with SetVertices. If src has no
+// indices, no index buffer is allocated and the geometry is drawn with
+// glDrawArrays.
 func NewGeometry(src VertexData, usage Usage) (*Geometry, error) {
 	srcidx, ok := src.(IndexData)
+	ok = ok && srcidx.IndexCount() > 0
 	geom := allocGeom(usage, ok)
 	geom.VertexBuffer.format = src.VertexFormat()
-	err := src.CopyVertices(&geom.VertexBuffer, usage)
+	err := geom.copyVertices(src)
 	if err != nil {
 		return nil, err
 	}
@@ -388,6 +711,8 @@ func NewGeometry(src VertexData, usage Usage) (*Geometry, error) {
 			return nil, err
 		}
 	}
+	geom.copyBounds(src)
+	geom.SetLabel(autoLabel("Geometry"))
 	return geom, nil
 }
 
@@ -403,26 +728,190 @@ func allocGeom(usage Usage, hasIndex bool) *Geometry {
 	} else {
 		geom.VertexBuffer.buf = gl.GenBuffer()
 	}
+	setFinalizer(geom, (*Geometry).finalize)
 	return geom
 }
 
+// SetLabel names the buffers of the geometry in GPU debuggers, such as
+// RenderDoc and apitrace, when the context has KHR_debug: name followed by
+// "vertices", "indices" or "instances". Geometry is otherwise named like
+// "Geometry#12".
+func (g *Geometry) SetLabel(name string) {
+	g.label = name
+	if name == "" {
+		return
+	}
+	labelObject(gl.BUFFER, uint32(g.VertexBuffer.buf), name+" vertices")
+	labelObject(gl.BUFFER, uint32(g.IndexBuffer.buf), name+" indices")
+	labelObject(gl.BUFFER, uint32(g.Instances.buf), name+" instances")
+	for i, b := range g.planes {
+		g.labelPlane(i, b)
+	}
+}
+
+func (g *Geometry) labelPlane(i int, b *VertexBuffer) {
+	if g.label != "" {
+		labelObject(gl.BUFFER, uint32(b.buf), g.label+" vertices "+strconv.Itoa(i+1))
+	}
+}
+
+// Indexed reports whether the geometry has an index buffer.
+func (g *Geometry) Indexed() bool {
+	return g.IndexBuffer.buf != 0
+}
+
 func (g *Geometry) Delete() {
+	setFinalizer(g, nil)
+	evictLayouts(g.cacheID)
 	g.VertexBuffer.Delete()
-	g.IndexBuffer.Delete()
+	if g.Indexed() {
+		g.IndexBuffer.Delete()
+	}
+	if g.Instances.buf != 0 {
+		g.Instances.Delete()
+	}
+	for _, b := range g.planes {
+		b.Delete()
+	}
+	g.planes = nil
+}
+
+// AddVertexBuffer copies vertices of more attributes from src into a new
+// buffer of their own, which is laid out alongside the interleaved vertex
+// buffer, and returns it. src must have as many vertices as the geometry,
+// and none of its attributes. Attributes kept apart can be rewritten on
+// their own, such as colors animated each frame with MapWrite or
+// SetVerticesAt while the positions stay static. Geometry drawn with a
+// GeometryLayout must be laid out again after buffers are added.
+func (g *Geometry) AddVertexBuffer(src VertexData, usage Usage) (*VertexBuffer, error) {
+	vf := src.VertexFormat()
+	if shared := g.VertexFormat().Unpacked() & vf.Unpacked(); shared != 0 {
+		return nil, fmt.Errorf("gfx: geometry already has vertex data %v", shared)
+	}
+	if n := src.VertexCount(); n != g.VertexBuffer.Count() {
+		return nil, fmt.Errorf("gfx: %d vertices added to geometry of %d", n, g.VertexBuffer.Count())
+	}
+	// measure positions kept apart, unless the bounds are known
+	b := &VertexBuffer{buf: gl.GenBuffer(), format: vf, measure: !g.bounds.ok}
+	if err := src.CopyVertices(b, usage); err != nil {
+		b.Delete()
+		return nil, err
+	}
+	if b.measure && b.measured.ok {
+		g.bounds = b.measured
+	}
+	b.measure = false
+	g.planes = append(g.planes, b)
+	g.labelPlane(len(g.planes)-1, b)
+	return b, nil
+}
+
+// VertexFormat returns the format of the vertex data of the geometry, across
+// its vertex buffer and those added with AddVertexBuffer.
+func (g *Geometry) VertexFormat() VertexFormat {
+	vf := g.VertexBuffer.Format()
+	for _, b := range g.planes {
+		vf |= b.Format()
+	}
+	return vf
+}
+
+// AttributeBuffer returns the buffer holding attrib, such as VertexColor,
+// or nil if the geometry has no such data.
+func (g *Geometry) AttributeBuffer(attrib VertexFormat) *VertexBuffer {
+	if g.VertexBuffer.Format()&attrib != 0 {
+		return &g.VertexBuffer
+	}
+	for _, b := range g.planes {
+		if b.Format()&attrib != 0 {
+			return b
+		}
+	}
+	return nil
+}
+
+// SetInstances copies per-instance vertex data from src, allocating the
+// instance buffer on first use. Shaders draw it with DrawInstanced after
+// declaring their instance attributes with SetInstanceAttributes.
+func (g *Geometry) SetInstances(src VertexData, usage Usage) error {
+	fresh := g.Instances.buf == 0
+	if fresh {
+		g.Instances.buf = gl.GenBuffer()
+	}
+	g.Instances.format = src.VertexFormat()
+	if err := src.CopyVertices(&g.Instances, usage); err != nil {
+		return err
+	}
+	if fresh && g.label != "" {
+		labelObject(gl.BUFFER, uint32(g.Instances.buf), g.label+" instances")
+	}
+	return nil
 }
 
 // CopyFrom copies vertices from src as well as indices if IndexData
-// is implemented.
+// is implemented. Copying indices into a geometry created without them
+// allocates an index buffer, after which it must be laid out again with
+// LayoutGeometry.
 func (g *Geometry) CopyFrom(src VertexData) error {
-	err := src.CopyVertices(&g.VertexBuffer, g.usage)
+	err := g.copyVertices(src)
 	if err != nil {
 		return err
 	}
-	if srcidx, ok := src.(IndexData); ok {
+	if srcidx, ok := src.(IndexData); ok && (g.Indexed() || srcidx.IndexCount() > 0) {
+		fresh := !g.Indexed()
+		if fresh {
+			g.IndexBuffer.buf = gl.GenBuffer()
+		}
 		err := srcidx.CopyIndices(&g.IndexBuffer, g.usage)
 		if err != nil {
 			return err
 		}
+		if fresh && g.label != "" {
+			labelObject(gl.BUFFER, uint32(g.IndexBuffer.buf), g.label+" indices")
+		}
 	}
+	g.copyBounds(src)
 	return nil
 }
+
+// copyVertices copies the vertices of src, measuring their bounds if src
+// does not know them.
+func (g *Geometry) copyVertices(src VertexData) error {
+	_, known := src.(BoundsData)
+	g.VertexBuffer.measure = !known
+	g.VertexBuffer.measured = bounds{}
+	err := src.CopyVertices(&g.VertexBuffer, g.usage)
+	g.VertexBuffer.measure = false
+	return err
+}
+
+// Bounds returns the bounding box of the vertex positions, and false if it
+// is unknown.
+func (g *Geometry) Bounds() (min, max [3]float32, ok bool) {
+	return g.bounds.min, g.bounds.max, g.bounds.ok
+}
+
+// BoundingSphere returns a sphere enclosing the vertex positions, and false
+// if it is unknown. It is centered on the bounding box.
+func (g *Geometry) BoundingSphere() (center [3]float32, radius float32, ok bool) {
+	return g.bounds.center, g.bounds.radius, g.bounds.ok
+}
+
+// SetBounds sets the bounding box of the vertex positions, for geometry
+// whose bounds cannot be measured, such as vertices written with MapWrite.
+// The bounding sphere becomes the sphere enclosing the box.
+func (g *Geometry) SetBounds(min, max [3]float32) {
+	g.bounds = boxBounds(min, max)
+}
+
+func (g *Geometry) copyBounds(src VertexData) {
+	b, ok := src.(BoundsData)
+	if !ok {
+		g.bounds = g.VertexBuffer.measured
+		return
+	}
+	g.SetBounds(b.Bounds())
+	if s, ok := src.(SphereData); ok {
+		g.bounds.center, g.bounds.radius = s.BoundingSphere()
+	}
+}
@@ -0,0 +1,66 @@
+package geometry
+
+import (
+	"j4k.co/gfx"
+)
+
+// Append adds the vertices and indices of other to b, so that meshes
+// assembled from reusable parts can be drawn with one call. The appended
+// vertices are transformed by m as Transform does. The indices of other are rebased onto the vertices already in b; if only one of the
+// builders has indices, the vertices of the other are indexed in order.
+// Both builders must have the same format.
+func (b *Builder) Append(other *Builder, m gfx.Mat4) error {
+	if other.vf != b.vf {
+		return gfx.ErrBadVertexFormat
+	}
+	b.fillVertex()
+	other.fillVertex()
+	base := b.VertexCount()
+	n, nidx := other.VertexCount(), other.IndexCount()
+	if n == 0 {
+		return nil
+	}
+
+	if b.IndexCount() == 0 && base > 0 && nidx > 0 {
+		b.sequence(0, base)
+	}
+	if b.IndexCount() > 0 || nidx > 0 {
+		if nidx == 0 {
+			b.sequence(base, n)
+		}
+		for i := 0; i < nidx; i++ {
+			b.IndexBuilder.append(uint32(base + other.vertex(i)))
+		}
+		b.nextidx = uint32(base + n)
+	}
+
+	b.VertexBuilder.Reserve(base + n)
+	b.verts = append(b.verts, other.verts...)
+	b.settle()
+
+	b.transform(base, base+n, &m)
+	return nil
+}
+
+// settle makes the last vertex current and complete after vertices are
+// added or removed in bulk, so that the next vertex is filled from it.
+func (b *VertexBuilder) settle() {
+	b.lastdata = [maxAttribs]int{}
+	b.cur, b.curvf = 0, 0
+	if len(b.verts) == 0 {
+		return
+	}
+	b.cur, b.curvf = len(b.verts)-b.stride, b.vf
+	for v := gfx.VertexFormat(1); v <= gfx.MaxVertexFormat; v <<= 1 {
+		if b.vf&v != 0 {
+			b.lastdata[attribIndex(v)] = b.cur + b.offset(v) + 1
+		}
+	}
+}
+
+// sequence appends indices of the n vertices from first, in order.
+func (b *IndexBuilder) sequence(first, n int) {
+	for i := first; i < first+n; i++ {
+		b.append(uint32(i))
+	}
+}
@@ -0,0 +1,56 @@
+package geometry_test
+
+import (
+	"encoding/binary"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"math"
+	"testing"
+)
+
+func TestAppend(t *testing.T) {
+	part := geometry.NewBuilder(gfx.VertexPosition | gfx.VertexNormal)
+	part.Position(0, 0, 0).Normal(0.6, 0.8, 0)
+	part.Position(1, 0, 0)
+	part.Position(0, 1, 0)
+	part.Indices(0, 1, 2)
+
+	b := geometry.NewBuilder(gfx.VertexPosition | gfx.VertexNormal)
+	if err := b.Append(part, gfx.Ident4()); err != nil {
+		t.Fatal(err)
+	}
+	m := gfx.Translate(gfx.Vec3{5, 0, 0}).Mul(gfx.Scale(gfx.Vec3{2, 1, 1}))
+	if err := b.Append(part, m); err != nil {
+		t.Fatal(err)
+	}
+	if b.VertexCount() != 6 || b.IndexCount() != 6 {
+		t.Fatalf("got %d vertices and %d indices, want 6 and 6", b.VertexCount(), b.IndexCount())
+	}
+	if min, max := b.Bounds(); min != [3]float32{0, 0, 0} || max != [3]float32{7, 1, 0} {
+		t.Errorf("got bounds %v, %v", min, max)
+	}
+	hit, ok := geometry.Raycast(b, [3]float32{6, 0.25, 1}, [3]float32{0, 0, -1})
+	if !ok || hit.Triangle != 1 {
+		t.Errorf("got hit %+v, %v, want the second triangle", hit, ok)
+	}
+
+	// the normal of the fourth vertex is scaled by the inverse transpose
+	data := uploadedVertices(t, b)
+	const stride = 24
+	var normal [3]float32
+	for i := range normal {
+		bits := binary.LittleEndian.Uint32(data[3*stride+12+4*i:])
+		normal[i] = math.Float32frombits(bits)
+	}
+	l := float32(math.Hypot(0.3, 0.8))
+	want := [3]float32{0.3 / l, 0.8 / l, 0}
+	for i := range normal {
+		if math.Abs(float64(normal[i]-want[i])) > 1e-5 {
+			t.Fatalf("got normal %v, want %v", normal, want)
+		}
+	}
+
+	if err := b.Append(geometry.NewBuilder(gfx.VertexPosition), m); err != gfx.ErrBadVertexFormat {
+		t.Errorf("got error %v for mismatched formats", err)
+	}
+}
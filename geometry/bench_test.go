@@ -36,3 +36,20 @@ func BenchmarkBuilderFatVerts(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkBuilderReserved(b *testing.B) {
+	bdr := geometry.NewBuilder(gfx.VertexPosition | gfx.VertexColor |
+		gfx.VertexTexcoord)
+	bdr.Reserve(builderQuads*4, builderQuads*6)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bdr.Clear()
+		for q := 0; q < builderQuads; q++ {
+			bdr.Position(0, 0, 0).Color(128, 0, 255, 255).Texcoord(0, 0)
+			bdr.Position(1, 0, 0)
+			bdr.Position(1, 1, 0)
+			bdr.Position(0, 1, 0)
+			bdr.Indices(0, 1, 2, 2, 0, 3)
+		}
+	}
+}
@@ -2,23 +2,26 @@ package geometry
 
 import (
 	"j4k.co/gfx"
+	"math"
+	"math/bits"
 	"reflect"
 	"unsafe"
 )
 
+// Builder builds the vertices and indices of geometry, and is the VertexData
+// and IndexData that NewGeometry is usually given. It is the one builder
+// of the module; the gfx package cannot provide one of its own without
+// importing this package.
 type Builder struct {
 	VertexBuilder
 	IndexBuilder
 }
 
+// NewBuilder returns a builder of vertices of format vf.
 func NewBuilder(vf gfx.VertexFormat) *Builder {
-	return &Builder{
-		VertexBuilder: VertexBuilder{
-			vf:       vf,
-			stride:   vf.Stride(),
-			lastdata: make(map[gfx.VertexFormat]int, vf.Count()),
-		},
-	}
+	b := &Builder{}
+	b.VertexBuilder.init(vf)
+	return b
 }
 
 func (b *Builder) Clear() {
@@ -26,48 +29,83 @@ func (b *Builder) Clear() {
 	b.IndexBuilder.Clear()
 }
 
+// Reserve grows the builder to hold at least the given numbers of vertices
+// and indices without reallocating, for meshes whose size is known ahead.
+func (b *Builder) Reserve(vertices, indices int) {
+	b.VertexBuilder.Reserve(vertices)
+	b.IndexBuilder.Reserve(indices)
+}
+
+// maxAttribs is the number of bits of a VertexFormat, which index the
+// per-attribute arrays of a VertexBuilder.
+const maxAttribs = 32
+
 type VertexBuilder struct {
-	vf       gfx.VertexFormat
-	stride   int
-	cur      int
-	curvf    gfx.VertexFormat // data that's been set on the current vertex
-	lastdata map[gfx.VertexFormat]int
-	offsets  map[gfx.VertexFormat]int
-	verts    []byte
+	vf     gfx.VertexFormat
+	stride int
+	cur    int
+	curvf  gfx.VertexFormat // data that's been set on the current vertex
+	verts  []byte
+
+	// lastdata holds one more than the offset in verts of the data last set
+	// for each attribute, or 0 if it has not been set, and offsets the
+	// offset of each attribute within a vertex, both indexed by bit.
+	lastdata [maxAttribs]int
+	offsets  [maxAttribs]int
 }
 
 func NewVertexBuilder(vf gfx.VertexFormat) *VertexBuilder {
-	return &VertexBuilder{
-		vf:       vf,
-		stride:   vf.Stride(),
-		lastdata: make(map[gfx.VertexFormat]int, vf.Count()),
+	b := &VertexBuilder{}
+	b.init(vf)
+	return b
+}
+
+func (b *VertexBuilder) init(vf gfx.VertexFormat) {
+	b.vf = vf
+	b.stride = vf.Unpacked().Stride()
+	offs := 0
+	for i := gfx.VertexFormat(1); i <= gfx.MaxVertexFormat; i <<= 1 {
+		if vf&i != 0 {
+			b.offsets[attribIndex(i)] = offs
+			offs += i.AttribBytes()
+		}
 	}
 }
 
+// attribIndex returns the bit position of the attribute v.
+func attribIndex(v gfx.VertexFormat) int {
+	return bits.TrailingZeros32(uint32(v))
+}
+
 // Clear resets buffers to zero length.
 func (b *VertexBuilder) Clear() {
-	b.lastdata = make(map[gfx.VertexFormat]int, len(b.lastdata))
+	b.lastdata = [maxAttribs]int{}
 	b.cur = 0
 	b.curvf = 0
 	b.verts = b.verts[:0]
 }
 
-// TODO: add a test
+// Reserve grows the builder to hold at least n vertices without
+// reallocating.
+func (b *VertexBuilder) Reserve(n int) {
+	if size := n * b.stride; size > cap(b.verts) {
+		b.grow(size - len(b.verts))
+	}
+}
+
+// grow reallocates verts with room for at least n more bytes.
+func (b *VertexBuilder) grow(n int) {
+	size := 2*cap(b.verts) + n
+	verts := make([]byte, len(b.verts), size)
+	copy(verts, b.verts)
+	b.verts = verts
+}
+
 func (b *VertexBuilder) offset(v gfx.VertexFormat) int {
 	if b.vf&v == 0 {
 		panic(gfx.ErrBadVertexFormat)
 	}
-	if b.offsets == nil {
-		offs := 0
-		b.offsets = map[gfx.VertexFormat]int{}
-		for i := gfx.VertexFormat(1); i <= gfx.MaxVertexFormat; i <<= 1 {
-			if b.vf&i != 0 {
-				b.offsets[i] = offs
-				offs += i.AttribBytes()
-			}
-		}
-	}
-	return b.offsets[v]
+	return b.offsets[attribIndex(v)]
 }
 
 func (b *VertexBuilder) next() {
@@ -75,28 +113,26 @@ func (b *VertexBuilder) next() {
 		b.cur += b.stride
 	}
 	b.curvf = 0
-	zeros := make([]uint8, b.stride)
-	b.verts = append(b.verts, zeros...)
+	n := len(b.verts)
+	if n+b.stride > cap(b.verts) {
+		b.grow(b.stride)
+	}
+	b.verts = b.verts[:n+b.stride]
+	v := b.verts[n:]
+	for i := range v {
+		v[i] = 0
+	}
 }
 
 // fillVertex fills the rest of the vertex data using the last set data
 // from a previous vertex
 func (b *VertexBuilder) fillVertex() {
-	/*
-		for i := VertexFormat(1); i <= MaxVertexFormat; i <<= 1 {
-			if g.vf&i != 0 && g.curvf&i == 0 {
-				offs, ok := g.lastdata[i]
-				if ok {
-					data := g.verts[offs : offs+attribBytes(i)/4]
-					g.set(i, data)
-				}
-			}
-		}
-	*/
-	for i, offs := range b.lastdata {
-		if b.vf&i != 0 && b.curvf&i == 0 {
-			data := b.verts[offs : offs+i.AttribBytes()]
-			b.set(i, data)
+	for m := b.vf.Unpacked() &^ b.curvf; m != 0; m &= m - 1 {
+		i := bits.TrailingZeros32(uint32(m))
+		if last := b.lastdata[i]; last != 0 {
+			v := gfx.VertexFormat(1) << uint(i)
+			offs := last - 1
+			b.set(v, b.verts[offs:offs+v.AttribBytes()])
 		}
 	}
 }
@@ -104,7 +140,7 @@ func (b *VertexBuilder) fillVertex() {
 func (b *VertexBuilder) set(v gfx.VertexFormat, data []uint8) {
 	b.curvf |= v
 	offs := b.cur + b.offset(v)
-	b.lastdata[v] = offs
+	b.lastdata[attribIndex(v)] = offs + 1
 	copy(b.verts[offs:offs+len(data)], data)
 }
 
@@ -133,6 +169,12 @@ func (b *VertexBuilder) Color(red, green, blue, alpha uint8) *VertexBuilder {
 	return b
 }
 
+// Color1 sets the secondary vertex color.
+func (b *VertexBuilder) Color1(red, green, blue, alpha uint8) *VertexBuilder {
+	b.set(gfx.VertexColor1, []uint8{red, green, blue, alpha})
+	return b
+}
+
 func (b *VertexBuilder) Colorf(red, green, blue, alpha float32) *VertexBuilder {
 	b.set(gfx.VertexColor, []uint8{
 		uint8(red * 255.0), uint8(green * 255.0), uint8(blue * 255.0), uint8(alpha * 255.0),
@@ -146,12 +188,95 @@ func (b *VertexBuilder) Normal(x, y, z float32) *VertexBuilder {
 	return b
 }
 
+// Tangent sets the vertex tangent, the direction of increasing U texture
+// coordinate. GenerateTangents computes it from the other vertex data.
+func (b *VertexBuilder) Tangent(x, y, z float32) *VertexBuilder {
+	b.setf(gfx.VertexTangent, []float32{x, y, z})
+	return b
+}
+
+// Bitangent sets the vertex bitangent, the direction of increasing V
+// texture coordinate.
+func (b *VertexBuilder) Bitangent(x, y, z float32) *VertexBuilder {
+	b.setf(gfx.VertexBitangent, []float32{x, y, z})
+	return b
+}
+
 // Texcoord sets the vertex texture coordinate.
 func (b *VertexBuilder) Texcoord(u, v float32) *VertexBuilder {
 	b.setf(gfx.VertexTexcoord, []float32{u, v})
 	return b
 }
 
+// TexcoordN sets one of the eight texture coordinates, where slot 0 is
+// VertexTexcoord and slot 7 is VertexTexcoord7, such as lightmap
+// coordinates in slot 1.
+func (b *VertexBuilder) TexcoordN(slot int, u, v float32) *VertexBuilder {
+	b.setf(gfx.VertexTexcoord<<uint(slot), []float32{u, v})
+	return b
+}
+
+// UserDatai sets one of the four user data vectors to integers, for
+// formats with VertexIntUserData.
+func (b *VertexBuilder) UserDatai(slot int, x, y, z, w int32) *VertexBuilder {
+	b.setf(gfx.VertexUserData<<uint(slot), []float32{
+		math.Float32frombits(uint32(x)), math.Float32frombits(uint32(y)),
+		math.Float32frombits(uint32(z)), math.Float32frombits(uint32(w)),
+	})
+	return b
+}
+
+// BoneIndices sets the indices of the four skeleton joints that move the
+// vertex.
+func (b *VertexBuilder) BoneIndices(j0, j1, j2, j3 uint8) *VertexBuilder {
+	b.set(gfx.VertexBoneIndices, []uint8{j0, j1, j2, j3})
+	return b
+}
+
+// BoneWeights sets the weights of the joints set by BoneIndices, which
+// should sum to 1. They are stored with 8 bits of precision.
+func (b *VertexBuilder) BoneWeights(w0, w1, w2, w3 float32) *VertexBuilder {
+	unorm := func(w float32) uint8 {
+		return uint8(math.Round(float64(clamp01(w)) * 255))
+	}
+	b.set(gfx.VertexBoneWeights, []uint8{unorm(w0), unorm(w1), unorm(w2), unorm(w3)})
+	return b
+}
+
+func clamp01(v float32) float32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// Next creates a new vertex without setting any data. It is used to build
+// vertices that have no position, such as per-instance data.
+func (b *VertexBuilder) Next() *VertexBuilder {
+	b.fillVertex()
+	b.next()
+	return b
+}
+
+// UserData sets one of the four user data vectors, where slot 0 is
+// VertexUserData and slot 3 is VertexUserData3.
+func (b *VertexBuilder) UserData(slot int, x, y, z, w float32) *VertexBuilder {
+	b.setf(gfx.VertexUserData<<uint(slot), []float32{x, y, z, w})
+	return b
+}
+
+// Matrix sets the columns of a column-major 4x4 matrix into the four user
+// data vectors, as used for a per-instance model matrix.
+func (b *VertexBuilder) Matrix(m *[16]float32) *VertexBuilder {
+	for i := 0; i < 4; i++ {
+		b.setf(gfx.VertexUserData<<uint(i), m[4*i:4*i+4])
+	}
+	return b
+}
+
 // VertexCount returns the number of vertices available.
 func (b *VertexBuilder) VertexCount() int {
 	return len(b.verts) / b.stride
@@ -165,6 +290,9 @@ func (b *VertexBuilder) CopyVertices(dest *gfx.VertexBuffer, usage gfx.Usage) er
 	if b.VertexFormat() != dest.Format() {
 		return gfx.ErrBadVertexFormat
 	}
+	if b.vf.Unpacked() != b.vf {
+		return dest.SetVertices(b.pack(), usage)
+	}
 	return dest.SetVertices(b.verts, usage)
 }
 
@@ -172,48 +300,240 @@ func (b *VertexBuilder) VertexFormat() gfx.VertexFormat {
 	return b.vf
 }
 
+// Bounds returns the bounding box of the vertex positions. It is empty if
+// there are no vertices or the format has no positions.
+func (b *VertexBuilder) Bounds() (min, max [3]float32) {
+	if b.vf&gfx.VertexPosition == 0 || len(b.verts) == 0 {
+		return
+	}
+	offs := b.offset(gfx.VertexPosition)
+	for i := offs; i < len(b.verts); i += b.stride {
+		p := *(*[3]float32)(unsafe.Pointer(&b.verts[i]))
+		for j, v := range p {
+			if i == offs || v < min[j] {
+				min[j] = v
+			}
+			if i == offs || v > max[j] {
+				max[j] = v
+			}
+		}
+	}
+	return min, max
+}
+
+// BoundingSphere returns the sphere centered on the bounding box that
+// reaches the furthest vertex position.
+func (b *VertexBuilder) BoundingSphere() (center [3]float32, radius float32) {
+	min, max := b.Bounds()
+	for i := range center {
+		center[i] = (min[i] + max[i]) / 2
+	}
+	if b.vf&gfx.VertexPosition == 0 {
+		return center, 0
+	}
+	var r2 float32
+	offs := b.offset(gfx.VertexPosition)
+	for i := offs; i < len(b.verts); i += b.stride {
+		p := *(*[3]float32)(unsafe.Pointer(&b.verts[i]))
+		var d2 float32
+		for j, v := range p {
+			d := v - center[j]
+			d2 += d * d
+		}
+		if d2 > r2 {
+			r2 = d2
+		}
+	}
+	return center, float32(math.Sqrt(float64(r2)))
+}
+
+// IndexBuilder builds 16-bit indices, switching to 32-bit indices once an
+// index no longer fits in 16 bits.
 type IndexBuilder struct {
 	idxs    []uint16
-	nextidx uint16
+	idxs32  []uint32
+	wide    bool
+	nextidx uint32
+
+	// restarts is set once Restart is called, reserving the largest
+	// 16-bit index for the restart index
+	restarts bool
 }
 
 // Indices appends new indices to the buffer that are relative to the maximum index in the buffer.
 func (b *IndexBuilder) Indices(idxs ...uint16) *IndexBuilder {
 	// TODO: could really use a test
 	newnext := b.nextidx
-	for i, idx := range idxs {
-		idx += b.nextidx
-		if idx >= newnext {
-			newnext = idx + 1
+	for _, idx := range idxs {
+		abs := uint32(idx) + b.nextidx
+		if abs >= newnext {
+			newnext = abs + 1
 		}
-		idxs[i] = idx
+		b.append(abs)
 	}
 	b.nextidx = newnext
-	b.idxs = append(b.idxs, idxs...)
 	return b
 }
 
+// Indices32 is like Indices but takes 32-bit indices, for meshes with more
+// than 65536 vertices.
+func (b *IndexBuilder) Indices32(idxs ...uint32) *IndexBuilder {
+	newnext := b.nextidx
+	for _, idx := range idxs {
+		abs := idx + b.nextidx
+		if abs >= newnext {
+			newnext = abs + 1
+		}
+		b.append(abs)
+	}
+	b.nextidx = newnext
+	return b
+}
+
+// Quad appends the two triangles of the quad i0, i1, i2, i3, given in
+// winding order, as (i0, i1, i2) and (i2, i3, i0). Like those of Indices,
+// the indices are relative to the vertices indexed so far.
+func (b *IndexBuilder) Quad(i0, i1, i2, i3 uint16) *IndexBuilder {
+	return b.Indices(i0, i1, i2, i2, i3, i0)
+}
+
+// Strip appends the triangles of a triangle strip, in which each index
+// after the first two makes a triangle with the two before it. Every other
+// triangle is flipped so that all keep the winding of the first. Indices
+// are relative as with Indices.
+func (b *IndexBuilder) Strip(idxs ...uint16) *IndexBuilder {
+	if len(idxs) < 3 {
+		return b
+	}
+	tris := make([]uint16, 0, 3*(len(idxs)-2))
+	for i := 2; i < len(idxs); i++ {
+		if i%2 == 0 {
+			tris = append(tris, idxs[i-2], idxs[i-1], idxs[i])
+		} else {
+			tris = append(tris, idxs[i-1], idxs[i-2], idxs[i])
+		}
+	}
+	return b.Indices(tris...)
+}
+
+// Fan appends the triangles of a triangle fan, in which each index after
+// the first two makes a triangle with the first index and the one before
+// it, such as the outline of a convex polygon. Indices are relative as with
+// Indices.
+func (b *IndexBuilder) Fan(idxs ...uint16) *IndexBuilder {
+	if len(idxs) < 3 {
+		return b
+	}
+	tris := make([]uint16, 0, 3*(len(idxs)-2))
+	for i := 2; i < len(idxs); i++ {
+		tris = append(tris, idxs[0], idxs[i-1], idxs[i])
+	}
+	return b.Indices(tris...)
+}
+
+// Restart appends the restart index of the current index size, ending
+// the strip or fan being indexed, for geometry drawn with
+// PrimitiveRestart. Once Restart is used, indices from 0xffff on widen the
+// builder so that no vertex is mistaken for a restart. Raycast, Weld,
+// GenerateTangents and Append assume triangle lists and must not be used
+// on restarted indices.
+func (b *IndexBuilder) Restart() *IndexBuilder {
+	b.restarts = true
+	if b.wide {
+		b.idxs32 = append(b.idxs32, gfx.RestartIndex32)
+	} else {
+		b.idxs = append(b.idxs, gfx.RestartIndex16)
+	}
+	return b
+}
+
+func (b *IndexBuilder) append(idx uint32) {
+	if !b.wide && (idx > 0xffff || b.restarts && idx == 0xffff) {
+		b.widen()
+	}
+	if b.wide {
+		b.idxs32 = append(b.idxs32, idx)
+	} else {
+		b.idxs = append(b.idxs, uint16(idx))
+	}
+}
+
+// widen converts the buffer to 32-bit indices.
+func (b *IndexBuilder) widen() {
+	b.wide = true
+	b.idxs32 = b.idxs32[:0]
+	for _, idx := range b.idxs {
+		if b.restarts && idx == gfx.RestartIndex16 {
+			b.idxs32 = append(b.idxs32, gfx.RestartIndex32)
+			continue
+		}
+		b.idxs32 = append(b.idxs32, uint32(idx))
+	}
+	b.idxs = b.idxs[:0]
+}
+
 // SetIndices copies idxs into a new buffer.
 func (b *IndexBuilder) SetIndices(idxs ...uint16) {
 	b.nextidx = 0
+	b.wide = false
+	b.idxs32 = b.idxs32[:0]
 	b.idxs = make([]uint16, len(idxs))
 	copy(b.idxs, idxs)
 }
 
+// SetIndices32 copies idxs into a new 32-bit buffer.
+func (b *IndexBuilder) SetIndices32(idxs ...uint32) {
+	b.nextidx = 0
+	b.wide = true
+	b.idxs = b.idxs[:0]
+	b.idxs32 = make([]uint32, len(idxs))
+	copy(b.idxs32, idxs)
+}
+
 // IndexCount returns the number of indices available.
 func (b *IndexBuilder) IndexCount() int {
+	if b.wide {
+		return len(b.idxs32)
+	}
 	return len(b.idxs)
 }
 
+// Wide reports whether the indices are stored as 32-bit values.
+func (b *IndexBuilder) Wide() bool {
+	return b.wide
+}
+
 // CopyIndices copies the indices to dest. If IndexCount() does not
 // match len(buf), an error is returned.
 func (b *IndexBuilder) CopyIndices(dest *gfx.IndexBuffer, usage gfx.Usage) error {
 	// TODO: sanity check on len, as described in doc
+	if b.wide {
+		return dest.SetIndices32(b.idxs32, usage)
+	}
 	return dest.SetIndices(b.idxs, usage)
 }
 
+// Reserve grows the builder to hold at least n indices without
+// reallocating. It reserves 16-bit indices until the builder is widened.
+func (b *IndexBuilder) Reserve(n int) {
+	if b.wide {
+		if n > cap(b.idxs32) {
+			idxs := make([]uint32, len(b.idxs32), n)
+			copy(idxs, b.idxs32)
+			b.idxs32 = idxs
+		}
+	} else if n > cap(b.idxs) {
+		idxs := make([]uint16, len(b.idxs), n)
+		copy(idxs, b.idxs)
+		b.idxs = idxs
+	}
+}
+
 // Clear resets buffers to zero length.
 func (b *IndexBuilder) Clear() {
 	b.idxs = b.idxs[:0]
+	b.idxs32 = b.idxs32[:0]
+	b.wide = false
+	b.restarts = false
 	b.nextidx = 0
 }
@@ -0,0 +1,234 @@
+package geometry
+
+import (
+	"image/color"
+	"j4k.co/gfx"
+	"math"
+)
+
+// Builder2D emits flat shapes into a Builder for user interfaces and HUDs.
+// Shapes take x, y coordinates with y pointing down, as text.Ortho projects
+// them, and are wound counter-clockwise on screen, so they face the viewer
+// through such a projection. Each shape is indexed on its own, and only
+// the vertex data present in the builder's format is written: positions,
+// and normals facing +Z, texcoords and colors when the format includes
+// them. Texcoords run from 0, 0 at the top left of the shape's bounds to
+// 1, 1 at the bottom right.
+//
+// Curved shapes take a number of segments, the lines approximating each
+// curve. If it is zero or less, it is chosen by radius, so that the lines
+// stray at most a quarter of a unit, typically a pixel, from the curve.
+type Builder2D struct {
+	*Builder
+
+	// Z is the depth of the vertices.
+	Z float32
+
+	// Tint is the color of the vertices if the format includes colors.
+	Tint color.NRGBA
+}
+
+// NewBuilder2D returns a 2D builder of vertices of format vf, which
+// must include positions, at depth 0 and tinted white.
+func NewBuilder2D(vf gfx.VertexFormat) *Builder2D {
+	return &Builder2D{
+		Builder: NewBuilder(vf),
+		Tint:    color.NRGBA{255, 255, 255, 255},
+	}
+}
+
+// Rect emits the rectangle with its top left corner at x, y.
+func (b *Builder2D) Rect(x, y, w, h float32) {
+	s := b.shape(x, y, w, h)
+	s.vertex(x, y)
+	s.vertex(x, y+h)
+	s.vertex(x+w, y+h)
+	s.vertex(x+w, y)
+	s.idxs = append(s.idxs, 0, 1, 2, 2, 3, 0)
+	s.flush()
+}
+
+// RoundedRect emits the rectangle with its top left corner at x, y and its
+// corners rounded by radius r, at most half the shorter side, with segs
+// segments per corner.
+func (b *Builder2D) RoundedRect(x, y, w, h, r float32, segs int) {
+	if r > w/2 {
+		r = w / 2
+	}
+	if r > h/2 {
+		r = h / 2
+	}
+	if r <= 0 {
+		b.Rect(x, y, w, h)
+		return
+	}
+	segs = arcSegments(math.Pi/2, r, segs)
+	s := b.shape(x, y, w, h)
+	center := s.vertex(x+w/2, y+h/2)
+	// the corners clockwise on screen from the top left, each continuing
+	// the angles of the one before
+	corners := [4][2]float32{{x + r, y + r}, {x + w - r, y + r}, {x + w - r, y + h - r}, {x + r, y + h - r}}
+	for k, c := range corners {
+		for i := 0; i <= segs; i++ {
+			a := math.Pi * (1 + (float64(k)+float64(i)/float64(segs))/2)
+			sin, cos := math.Sincos(a)
+			s.vertex(c[0]+r*float32(cos), c[1]+r*float32(sin))
+		}
+	}
+	s.fan(center, center+1, uint32(4*(segs+1)), true)
+	s.flush()
+}
+
+// Circle emits the circle of radius r around cx, cy.
+func (b *Builder2D) Circle(cx, cy, r float32, segs int) {
+	b.Ellipse(cx, cy, r, r, segs)
+}
+
+// Ellipse emits the ellipse around cx, cy with radii rx along x and ry
+// along y.
+func (b *Builder2D) Ellipse(cx, cy, rx, ry float32, segs int) {
+	r := rx
+	if ry > r {
+		r = ry
+	}
+	segs = arcSegments(2*math.Pi, r, segs)
+	s := b.shape(cx-rx, cy-ry, 2*rx, 2*ry)
+	center := s.vertex(cx, cy)
+	for i := 0; i < segs; i++ {
+		sin, cos := math.Sincos(2 * math.Pi * float64(i) / float64(segs))
+		s.vertex(cx+rx*float32(cos), cy+ry*float32(sin))
+	}
+	s.fan(center, center+1, uint32(segs), true)
+	s.flush()
+}
+
+// Ring emits the band around cx, cy between the radii inner and outer.
+func (b *Builder2D) Ring(cx, cy, inner, outer float32, segs int) {
+	b.Arc(cx, cy, inner, outer, 0, 2*math.Pi, segs)
+}
+
+// Arc emits the part of the ring around cx, cy between the radii inner and
+// outer from angle a0 to a1 in radians, which turn clockwise on screen
+// from +X. If inner is zero, it is a pie slice. Texcoords span the bounds
+// of the whole outer circle.
+func (b *Builder2D) Arc(cx, cy, inner, outer, a0, a1 float32, segs int) {
+	if a1 < a0 {
+		a0, a1 = a1, a0
+	}
+	if a1-a0 > 2*math.Pi {
+		a1 = a0 + 2*math.Pi
+	}
+	segs = arcSegments(float64(a1-a0), outer, segs)
+	s := b.shape(cx-outer, cy-outer, 2*outer, 2*outer)
+	var center uint32
+	if inner <= 0 {
+		center = s.vertex(cx, cy)
+	}
+	for i := 0; i <= segs; i++ {
+		a := float64(a0) + float64(a1-a0)*float64(i)/float64(segs)
+		sin, cos := math.Sincos(a)
+		s.vertex(cx+outer*float32(cos), cy+outer*float32(sin))
+		if inner > 0 {
+			s.vertex(cx+inner*float32(cos), cy+inner*float32(sin))
+		}
+	}
+	if inner <= 0 {
+		s.fan(center, center+1, uint32(segs+1), false)
+	} else {
+		for i := uint32(0); i < uint32(segs); i++ {
+			o, n := 2*i, 2*i+1
+			s.idxs = append(s.idxs, o, n, n+2, n+2, o+2, o)
+		}
+	}
+	s.flush()
+}
+
+// shape2D gathers the vertices and indices of one shape of a Builder2D.
+type shape2D struct {
+	b          *Builder2D
+	x, y, w, h float32 // the bounds spanned by texcoords
+	normals    bool
+	texcoords  bool
+	colors     bool
+	n          uint32
+	idxs       []uint32
+}
+
+func (b *Builder2D) shape(x, y, w, h float32) *shape2D {
+	vf := b.VertexFormat()
+	return &shape2D{
+		b: b,
+		x: x, y: y, w: w, h: h,
+		normals:   vf&gfx.VertexNormal != 0,
+		texcoords: vf&gfx.VertexTexcoord != 0,
+		colors:    vf&gfx.VertexColor != 0,
+	}
+}
+
+// vertex emits a vertex and returns its index relative to the shape.
+func (s *shape2D) vertex(x, y float32) uint32 {
+	vb := s.b.Position(x, y, s.b.Z)
+	if s.normals {
+		vb.Normal(0, 0, 1)
+	}
+	if s.texcoords {
+		var u, v float32
+		if s.w != 0 {
+			u = (x - s.x) / s.w
+		}
+		if s.h != 0 {
+			v = (y - s.y) / s.h
+		}
+		vb.Texcoord(u, v)
+	}
+	if s.colors {
+		c := s.b.Tint
+		vb.Color(c.R, c.G, c.B, c.A)
+	}
+	s.n++
+	return s.n - 1
+}
+
+// fan emits triangles from center to each line of the n outline vertices
+// from first, which turn clockwise on screen, and from the last back to the
+// first if closed.
+func (s *shape2D) fan(center, first, n uint32, closed bool) {
+	lines := n - 1
+	if closed {
+		lines = n
+	}
+	for i := uint32(0); i < lines; i++ {
+		s.idxs = append(s.idxs, center, first+(i+1)%n, first+i)
+	}
+}
+
+// flush appends the indices to the builder in one call, so they share a
+// single base index.
+func (s *shape2D) flush() {
+	s.b.Indices32(s.idxs...)
+}
+
+// arcSegments returns segs if it is positive, or else the number of lines
+// approximating an arc of angle radians and radius r within a quarter of a
+// unit, at least three for a full circle.
+func arcSegments(angle float64, r float32, segs int) int {
+	if segs > 0 {
+		return segs
+	}
+	const tol, maxSegs = 0.25, 256
+	least := int(math.Ceil(angle * 3 / (2 * math.Pi)))
+	if least < 1 {
+		least = 1
+	}
+	if float64(r) <= tol {
+		return least
+	}
+	n := int(math.Ceil(angle / (2 * math.Acos(1-tol/float64(r)))))
+	switch {
+	case n < least:
+		return least
+	case n > maxSegs:
+		return maxSegs
+	}
+	return n
+}
@@ -0,0 +1,112 @@
+package geometry_test
+
+import (
+	"encoding/binary"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"math"
+	"testing"
+)
+
+func TestBuilder2D(t *testing.T) {
+	tests := []struct {
+		name        string
+		gen         func(*geometry.Builder2D)
+		verts, idxs int
+	}{
+		{"rect", func(b *geometry.Builder2D) { b.Rect(0, 0, 10, 5) }, 4, 6},
+		{"rounded rect", func(b *geometry.Builder2D) { b.RoundedRect(0, 0, 10, 5, 2, 3) }, 1 + 4*4, 16 * 3},
+		{"square corners", func(b *geometry.Builder2D) { b.RoundedRect(0, 0, 10, 5, 0, 3) }, 4, 6},
+		{"circle", func(b *geometry.Builder2D) { b.Circle(0, 0, 5, 8) }, 9, 8 * 3},
+		{"tiny circle", func(b *geometry.Builder2D) { b.Circle(0, 0, 0.1, 0) }, 4, 3 * 3},
+		{"ellipse", func(b *geometry.Builder2D) { b.Ellipse(0, 0, 5, 2, 6) }, 7, 6 * 3},
+		{"ring", func(b *geometry.Builder2D) { b.Ring(0, 0, 3, 5, 8) }, 18, 8 * 6},
+		{"pie", func(b *geometry.Builder2D) { b.Arc(0, 0, 0, 5, 0, math.Pi/2, 4) }, 6, 4 * 3},
+		{"arc", func(b *geometry.Builder2D) { b.Arc(0, 0, 3, 5, math.Pi/2, 0, 4) }, 10, 4 * 6},
+	}
+	for _, tt := range tests {
+		b := geometry.NewBuilder2D(gfx.VertexPosition)
+		b.Rect(-1, -1, 1, 1)
+		tt.gen(b)
+		if b.VertexCount() != 4+tt.verts || b.IndexCount() != 6+tt.idxs {
+			t.Errorf("%s: got %d vertices and %d indices, want %d and %d",
+				tt.name, b.VertexCount()-4, b.IndexCount()-6, tt.verts, tt.idxs)
+			continue
+		}
+
+		// every triangle is counter-clockwise with y down, following the
+		// rect before it
+		pos := uploadedVertices(t, b.Builder)
+		idxs := uploadedIndices(t, b.Builder)
+		vertex := func(i int) (x, y float32) {
+			v := int(binary.LittleEndian.Uint16(idxs[2*i:]))
+			x = math.Float32frombits(binary.LittleEndian.Uint32(pos[12*v:]))
+			y = math.Float32frombits(binary.LittleEndian.Uint32(pos[12*v+4:]))
+			return x, y
+		}
+		for tri := 0; tri < b.IndexCount()/3; tri++ {
+			x0, y0 := vertex(3 * tri)
+			x1, y1 := vertex(3*tri + 1)
+			x2, y2 := vertex(3*tri + 2)
+			if area := (x1-x0)*(y2-y0) - (y1-y0)*(x2-x0); area >= 0 {
+				t.Errorf("%s: triangle %d has signed area %v, want negative", tt.name, tri, area)
+			}
+		}
+	}
+}
+
+func TestBuilder2DCoverage(t *testing.T) {
+	covered := func(b *geometry.Builder2D, x, y float32) bool {
+		_, ok := geometry.Raycast(b.Builder, [3]float32{x, y, 1}, [3]float32{0, 0, -1})
+		return ok
+	}
+	tests := []struct {
+		name    string
+		gen     func(*geometry.Builder2D)
+		in, out [][2]float32
+	}{
+		{"rounded rect", func(b *geometry.Builder2D) { b.RoundedRect(0, 0, 10, 10, 4, 0) },
+			[][2]float32{{5, 5}, {0.1, 5}, {5, 9.9}, {1.5, 1.5}}, [][2]float32{{0.3, 0.3}, {9.7, 9.7}}},
+		{"ring", func(b *geometry.Builder2D) { b.Ring(0, 0, 5, 10, 0) },
+			[][2]float32{{7, 0}, {0, -7}, {-5.5, 5.5}}, [][2]float32{{0, 0}, {4, 0}, {7.5, 7.5}}},
+		{"pie", func(b *geometry.Builder2D) { b.Arc(0, 0, 0, 10, 0, math.Pi/2, 0) },
+			[][2]float32{{3, 3}, {9, 1}}, [][2]float32{{3, -3}, {-3, 3}, {7.5, 7.5}}},
+	}
+	for _, tt := range tests {
+		b := geometry.NewBuilder2D(gfx.VertexPosition)
+		tt.gen(b)
+		for _, p := range tt.in {
+			if !covered(b, p[0], p[1]) {
+				t.Errorf("%s: %v is not covered", tt.name, p)
+			}
+		}
+		for _, p := range tt.out {
+			if covered(b, p[0], p[1]) {
+				t.Errorf("%s: %v is covered", tt.name, p)
+			}
+		}
+	}
+}
+
+func TestBuilder2DTexcoords(t *testing.T) {
+	vf := gfx.VertexPosition | gfx.VertexTexcoord
+	b := geometry.NewBuilder2D(vf)
+	b.Z = 2
+	b.Rect(10, 20, 4, 8)
+	data := uploadedVertices(t, b.Builder)
+	want := [4][5]float32{
+		{10, 20, 2, 0, 0},
+		{10, 28, 2, 0, 1},
+		{14, 28, 2, 1, 1},
+		{14, 20, 2, 1, 0},
+	}
+	for i, w := range want {
+		var v [5]float32
+		for j := range v {
+			v[j] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*vf.Stride()+j*4:]))
+		}
+		if v != w {
+			t.Errorf("vertex %d: got position and texcoord %v, want %v", i, v, w)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package geometry_test
+
+import (
+	"bytes"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+func TestIndicesRelative(t *testing.T) {
+	var b geometry.IndexBuilder
+	b.Indices(0, 1, 2, 2, 1, 3)
+	b.Indices(0, 1, 2)
+	if b.IndexCount() != 9 || b.Wide() {
+		t.Fatalf("got %d indices, wide=%v", b.IndexCount(), b.Wide())
+	}
+}
+
+func TestIndicesWiden(t *testing.T) {
+	b := geometry.NewBuilder(gfx.VertexPosition)
+	for q := 0; q < 20000; q++ {
+		b.Position(0, 0, 0)
+		b.Position(1, 0, 0)
+		b.Position(1, 1, 0)
+		b.Position(0, 1, 0)
+		b.Indices(0, 1, 2, 2, 0, 3)
+	}
+	if !b.Wide() {
+		t.Fatal("builder with 80000 vertices did not switch to 32-bit indices")
+	}
+	if got, want := b.IndexCount(), 20000*6; got != want {
+		t.Fatalf("got %d indices, want %d", got, want)
+	}
+}
+
+func TestClearReuse(t *testing.T) {
+	b := geometry.NewBuilder(gfx.VertexPosition | gfx.VertexColor)
+	b.Reserve(4, 6)
+	b.Position(0, 0, 0).Color(255, 255, 255, 255)
+	b.Position(1, 0, 0)
+	b.Clear()
+	// the color of the cleared vertices must not carry over
+	b.Position(2, 0, 0)
+	data := uploadedVertices(t, b)
+	want := []byte{0, 0, 0, 0x40, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	if !bytes.Equal(data, want) {
+		t.Errorf("got vertex % x, want % x", data, want)
+	}
+}
+
+func TestInstanceMatrix(t *testing.T) {
+	vf := gfx.VertexUserData | gfx.VertexUserData1 | gfx.VertexUserData2 | gfx.VertexUserData3
+	b := geometry.NewVertexBuilder(vf)
+	m := [16]float32{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 5, 6, 7, 1}
+	b.Next().Matrix(&m)
+	b.Next().Matrix(&m)
+	if got := b.VertexCount(); got != 2 {
+		t.Fatalf("got %d instances, want 2", got)
+	}
+}
+
+func TestBounds(t *testing.T) {
+	b := geometry.NewBuilder(gfx.VertexColor | gfx.VertexPosition)
+	b.Position(1, -2, 3).Color(255, 0, 0, 255)
+	b.Position(-4, 5, 0.5)
+	b.Position(0, 0, 9)
+	min, max := b.Bounds()
+	if min != [3]float32{-4, -2, 0.5} || max != [3]float32{1, 5, 9} {
+		t.Fatalf("got bounds %v, %v", min, max)
+	}
+	center, radius := b.BoundingSphere()
+	if center != [3]float32{-1.5, 1.5, 4.75} || radius < 6.04 || radius > 6.05 {
+		t.Fatalf("got bounding sphere %v, %v", center, radius)
+	}
+}
+
+func TestSecondaryData(t *testing.T) {
+	b := geometry.NewBuilder(gfx.VertexPosition | gfx.VertexColor1 | gfx.VertexTexcoord1)
+	b.Position(1, 2, 3).Color1(10, 20, 30, 40).TexcoordN(1, 0.5, 0.25)
+	data := uploadedVertices(t, b)
+	// position, color1, texcoord1
+	want := []byte{
+		0, 0, 0x80, 0x3f, 0, 0, 0, 0x40, 0, 0, 0x40, 0x40,
+		10, 20, 30, 40,
+		0, 0, 0, 0x3f, 0, 0, 0x80, 0x3e,
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("got vertex % x, want % x", data, want)
+	}
+}
+
+func TestPackedData(t *testing.T) {
+	vf := gfx.VertexPosition | gfx.VertexNormal | gfx.VertexTexcoord | gfx.VertexUserData |
+		gfx.VertexPackedNormals | gfx.VertexHalfTexcoords | gfx.VertexIntUserData
+	if got, want := vf.Stride(), 12+4+4+16; got != want {
+		t.Fatalf("got stride %d, want %d", got, want)
+	}
+	b := geometry.NewBuilder(vf)
+	b.Position(1, 2, 3).Normal(0, -1, 1).Texcoord(0.5, -2).UserDatai(0, 1, 2, 3, -1)
+	data := uploadedVertices(t, b)
+	want := []byte{
+		0, 0, 0x80, 0x3f, 0, 0, 0, 0x40, 0, 0, 0x40, 0x40,
+		// 0 | -511<<10 | 511<<20, as INT_2_10_10_10_REV
+		0x00, 0x04, 0xf8, 0x1f,
+		// half floats 0.5 and -2
+		0x00, 0x38, 0x00, 0xc0,
+		1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0, 0xff, 0xff, 0xff, 0xff,
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("got vertex % x, want % x", data, want)
+	}
+}
+
+func TestTopologies(t *testing.T) {
+	b := geometry.NewBuilder(gfx.VertexPosition)
+	for i := 0; i < 13; i++ {
+		b.Position(float32(i), 0, 0)
+	}
+	b.Quad(0, 1, 2, 3)
+	b.Strip(0, 1, 2, 3)
+	b.Fan(0, 1, 2, 3, 4)
+	data := uploadedIndices(t, b)
+	want := []byte{
+		0, 1, 2, 2, 3, 0,
+		4, 5, 6, 6, 5, 7,
+		8, 9, 10, 8, 10, 11, 8, 11, 12,
+	}
+	if len(data) != 2*len(want) {
+		t.Fatalf("got %d bytes of indices, want %d", len(data), 2*len(want))
+	}
+	for i, idx := range want {
+		if data[2*i] != idx || data[2*i+1] != 0 {
+			t.Fatalf("got indices % x, want %v", data, want)
+		}
+	}
+}
+
+func TestRestartWidens(t *testing.T) {
+	var b geometry.IndexBuilder
+	b.Indices32(0, 1, 2).Restart()
+	if b.Wide() {
+		t.Fatal("widened before the restart index was reached")
+	}
+	b.Indices32(0xffff - 3)
+	if !b.Wide() || b.IndexCount() != 5 {
+		t.Fatalf("got %d indices, wide=%v; want 5 wide indices", b.IndexCount(), b.Wide())
+	}
+	var ib gfx.IndexBuffer
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+	if err := b.CopyIndices(&ib, gfx.StaticDraw); err != nil {
+		t.Fatal(err)
+	}
+	defer ib.Delete()
+	uploads := rec.Uploads()
+	last := uploads[len(uploads)-1]
+	data, _ := last.Args[len(last.Args)-1].([]byte)
+	if !bytes.Equal(data[12:16], []byte{0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("got restart index % x, want ff ff ff ff", data[12:16])
+	}
+}
+
+func TestBuilderGeometry(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	b := geometry.NewBuilder(gfx.VertexPosition | gfx.VertexColor)
+	b.Position(0, 0, 0).Color(1, 2, 3, 4)
+	b.Position(1, 0, 0).Color(5, 6, 7, 8)
+	b.Position(1, 1, 0).Color(9, 10, 11, 12)
+	b.Indices(0, 1, 2)
+	geom, err := gfx.NewGeometry(b, gfx.StaticDraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geom.Delete()
+
+	// the vertices and indices are uploaded as built
+	var data [][]byte
+	for _, c := range rec.Uploads() {
+		if d, ok := c.Args[len(c.Args)-1].([]byte); ok {
+			data = append(data, d)
+		}
+	}
+	if len(data) != 2 {
+		t.Fatalf("got uploads %v, want the vertices and indices", rec.Uploads())
+	}
+	stride := (gfx.VertexPosition | gfx.VertexColor).Stride()
+	if len(data[0]) != 3*stride || !bytes.Equal(data[0][12:16], []byte{1, 2, 3, 4}) || !bytes.Equal(data[0][stride+12:stride+16], []byte{5, 6, 7, 8}) {
+		t.Errorf("got vertices % x", data[0])
+	}
+	if !bytes.Equal(data[1], []byte{0, 0, 1, 0, 2, 0}) {
+		t.Errorf("got indices % x, want 16-bit 0, 1, 2", data[1])
+	}
+	if got := geom.VertexFormat(); got != gfx.VertexPosition|gfx.VertexColor {
+		t.Errorf("got vertex format %v", got)
+	}
+}
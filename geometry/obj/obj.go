@@ -0,0 +1,480 @@
+/*
+Package obj decodes Wavefront OBJ models and MTL material libraries into
+geometry builders, one per material, ready to be copied into gfx geometry.
+*/
+package obj
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Material holds the properties of an MTL material. Texture maps are file
+// names relative to the material library.
+type Material struct {
+	Name      string
+	Ambient   [3]float32 // Ka
+	Diffuse   [3]float32 // Kd
+	Specular  [3]float32 // Ks
+	Emissive  [3]float32 // Ke
+	Shininess float32    // Ns
+	Opacity   float32    // d, or 1 - Tr
+
+	DiffuseMap  string // map_Kd
+	SpecularMap string // map_Ks
+	NormalMap   string // norm, map_bump, or bump
+	AlphaMap    string // map_d
+}
+
+// Mesh is the geometry of a model that uses a single material.
+type Mesh struct {
+	Material string
+	*geometry.Builder
+}
+
+// Model is a decoded OBJ file.
+type Model struct {
+	// Meshes are ordered by first use of their material. Faces before any
+	// usemtl statement belong to a mesh with an empty material name.
+	Meshes []*Mesh
+
+	// MaterialLibs lists the mtllib files referenced by the model, and
+	// Materials holds any that were loaded.
+	MaterialLibs []string
+	Materials    map[string]*Material
+}
+
+// Mesh returns the mesh using the named material, or nil.
+func (m *Model) Mesh(material string) *Mesh {
+	for _, mesh := range m.Meshes {
+		if mesh.Material == material {
+			return mesh
+		}
+	}
+	return nil
+}
+
+// SyntaxError reports a malformed line.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("obj: line %d: %s", e.Line, e.Msg)
+}
+
+type corner struct {
+	v, vt, vn int // zero-based, -1 when absent
+}
+
+type group struct {
+	material string
+	faces    [][]corner
+}
+
+// Decode reads an OBJ model from r, building vertices in the given format.
+// Only the data present in vf is written. Normals are generated from faces
+// when the format has normals and the file does not, tangents and
+// bitangents are generated when the format has them, and colors are taken
+// from the diffuse color of the material when materials are loaded by
+// LoadFile, or white otherwise. Material libraries are not loaded.
+func Decode(r io.Reader, vf gfx.VertexFormat) (*Model, error) {
+	return decode(r, vf, nil)
+}
+
+// LoadFile reads an OBJ model from the named file along with the material
+// libraries it references, which are resolved relative to the file.
+func LoadFile(name string, vf gfx.VertexFormat) (*Model, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dir := filepath.Dir(name)
+	return decode(f, vf, func(lib string) (map[string]*Material, error) {
+		mf, err := os.Open(filepath.Join(dir, lib))
+		if err != nil {
+			return nil, err
+		}
+		defer mf.Close()
+		return DecodeMTL(mf)
+	})
+}
+
+// LoadFS is like LoadFile, reading the model and its material libraries
+// from fsys.
+func LoadFS(fsys fs.FS, name string, vf gfx.VertexFormat) (*Model, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dir := path.Dir(name)
+	return decode(f, vf, func(lib string) (map[string]*Material, error) {
+		mf, err := fsys.Open(path.Join(dir, lib))
+		if err != nil {
+			return nil, err
+		}
+		defer mf.Close()
+		return DecodeMTL(mf)
+	})
+}
+
+func decode(r io.Reader, vf gfx.VertexFormat, loadLib func(string) (map[string]*Material, error)) (*Model, error) {
+	var (
+		positions [][3]float32
+		texcoords [][2]float32
+		normals   [][3]float32
+		groups    []*group
+		cur       *group
+	)
+	model := &Model{Materials: make(map[string]*Material)}
+	useGroup := func(material string) {
+		for _, g := range groups {
+			if g.material == material {
+				cur = g
+				return
+			}
+		}
+		cur = &group{material: material}
+		groups = append(groups, cur)
+	}
+
+	sc := bufio.NewScanner(r)
+	lineno := 0
+	for sc.Scan() {
+		lineno++
+		line := strings.TrimSpace(sc.Text())
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		args := fields[1:]
+		switch fields[0] {
+		case "v":
+			v, err := parseFloats(args, 3)
+			if err != nil {
+				return nil, &SyntaxError{lineno, err.Error()}
+			}
+			positions = append(positions, [3]float32{v[0], v[1], v[2]})
+		case "vt":
+			v, err := parseFloats(args, 1)
+			if err != nil {
+				return nil, &SyntaxError{lineno, err.Error()}
+			}
+			var vt [2]float32
+			copy(vt[:], v)
+			texcoords = append(texcoords, vt)
+		case "vn":
+			v, err := parseFloats(args, 3)
+			if err != nil {
+				return nil, &SyntaxError{lineno, err.Error()}
+			}
+			normals = append(normals, [3]float32{v[0], v[1], v[2]})
+		case "f":
+			if len(args) < 3 {
+				return nil, &SyntaxError{lineno, "face with fewer than 3 vertices"}
+			}
+			face := make([]corner, len(args))
+			for i, arg := range args {
+				c, err := parseCorner(arg, len(positions), len(texcoords), len(normals))
+				if err != nil {
+					return nil, &SyntaxError{lineno, err.Error()}
+				}
+				face[i] = c
+			}
+			if cur == nil {
+				useGroup("")
+			}
+			cur.faces = append(cur.faces, face)
+		case "usemtl":
+			useGroup(strings.Join(args, " "))
+		case "mtllib":
+			for _, lib := range args {
+				model.MaterialLibs = append(model.MaterialLibs, lib)
+				if loadLib == nil {
+					continue
+				}
+				mats, err := loadLib(lib)
+				if err != nil {
+					return nil, err
+				}
+				for k, m := range mats {
+					model.Materials[k] = m
+				}
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	var genNormals [][3]float32
+	if vf&gfx.VertexNormal != 0 {
+		genNormals = generateNormals(positions, groups)
+	}
+	for _, g := range groups {
+		mesh := &Mesh{
+			Material: g.material,
+			Builder:  geometry.NewBuilder(vf),
+		}
+		color := [4]uint8{255, 255, 255, 255}
+		if m, ok := model.Materials[g.material]; ok {
+			for i := 0; i < 3; i++ {
+				color[i] = uint8(clamp01(m.Diffuse[i]) * 255)
+			}
+			color[3] = uint8(clamp01(m.Opacity) * 255)
+		}
+		seen := make(map[corner]uint32)
+		var idxs []uint32
+		for _, face := range g.faces {
+			var fan []uint32
+			for _, c := range face {
+				idx, ok := seen[c]
+				if !ok {
+					idx = uint32(len(seen))
+					seen[c] = idx
+					p := positions[c.v]
+					vb := mesh.Position(p[0], p[1], p[2])
+					if vf&gfx.VertexNormal != 0 {
+						n := genNormals[c.v]
+						if c.vn >= 0 {
+							n = normals[c.vn]
+						}
+						vb.Normal(n[0], n[1], n[2])
+					}
+					if vf&gfx.VertexTexcoord != 0 {
+						// set even when absent, as vertices left unset
+						// copy the previous one's
+						var t [2]float32
+						if c.vt >= 0 {
+							t = texcoords[c.vt]
+						}
+						vb.Texcoord(t[0], t[1])
+					}
+					if vf&gfx.VertexColor != 0 {
+						vb.Color(color[0], color[1], color[2], color[3])
+					}
+				}
+				fan = append(fan, idx)
+			}
+			for i := 1; i+1 < len(fan); i++ {
+				idxs = append(idxs, fan[0], fan[i], fan[i+1])
+			}
+		}
+		mesh.Indices32(idxs...)
+		if vf&(gfx.VertexTangent|gfx.VertexBitangent) != 0 {
+			if err := mesh.GenerateTangents(); err != nil {
+				return nil, err
+			}
+		}
+		model.Meshes = append(model.Meshes, mesh)
+	}
+	return model, nil
+}
+
+// generateNormals averages the normals of the faces sharing each position.
+func generateNormals(positions [][3]float32, groups []*group) [][3]float32 {
+	normals := make([][3]float32, len(positions))
+	for _, g := range groups {
+		for _, face := range g.faces {
+			p0 := positions[face[0].v]
+			for i := 1; i+1 < len(face); i++ {
+				p1, p2 := positions[face[i].v], positions[face[i+1].v]
+				n := cross(sub(p1, p0), sub(p2, p0))
+				for _, c := range []corner{face[0], face[i], face[i+1]} {
+					for k := 0; k < 3; k++ {
+						normals[c.v][k] += n[k]
+					}
+				}
+			}
+		}
+	}
+	for i, n := range normals {
+		l := float32(math.Sqrt(float64(n[0]*n[0] + n[1]*n[1] + n[2]*n[2])))
+		if l > 0 {
+			normals[i] = [3]float32{n[0] / l, n[1] / l, n[2] / l}
+		} else {
+			normals[i] = [3]float32{0, 1, 0}
+		}
+	}
+	return normals
+}
+
+func sub(a, b [3]float32) [3]float32 {
+	return [3]float32{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func cross(a, b [3]float32) [3]float32 {
+	return [3]float32{
+		a[1]*b[2] - a[2]*b[1],
+		a[2]*b[0] - a[0]*b[2],
+		a[0]*b[1] - a[1]*b[0],
+	}
+}
+
+func clamp01(v float32) float32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+func parseFloats(args []string, min int) ([]float32, error) {
+	if len(args) < min {
+		return nil, fmt.Errorf("expected %d values, got %d", min, len(args))
+	}
+	vals := make([]float32, len(args))
+	for i, a := range args {
+		v, err := strconv.ParseFloat(a, 32)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = float32(v)
+	}
+	return vals, nil
+}
+
+// parseCorner parses a face vertex such as "1", "1/2", "1//3", or "1/2/3".
+// Negative indices count back from the most recent element.
+func parseCorner(s string, nv, nvt, nvn int) (corner, error) {
+	c := corner{-1, -1, -1}
+	parts := strings.Split(s, "/")
+	if len(parts) > 3 {
+		return c, fmt.Errorf("bad face vertex %q", s)
+	}
+	counts := [3]int{nv, nvt, nvn}
+	dest := [3]*int{&c.v, &c.vt, &c.vn}
+	for i, p := range parts {
+		if p == "" {
+			if i == 0 {
+				return c, fmt.Errorf("bad face vertex %q", s)
+			}
+			continue
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return c, err
+		}
+		if n < 0 {
+			n = counts[i] + n
+		} else {
+			n--
+		}
+		if n < 0 || n >= counts[i] {
+			return c, fmt.Errorf("index out of range in %q", s)
+		}
+		*dest[i] = n
+	}
+	return c, nil
+}
+
+// DecodeMTL reads a material library, returning materials keyed by name.
+func DecodeMTL(r io.Reader) (map[string]*Material, error) {
+	mats := make(map[string]*Material)
+	var cur *Material
+	sc := bufio.NewScanner(r)
+	lineno := 0
+	for sc.Scan() {
+		lineno++
+		line := strings.TrimSpace(sc.Text())
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		key, args := fields[0], fields[1:]
+		if key == "newmtl" {
+			cur = &Material{Name: strings.Join(args, " "), Opacity: 1}
+			mats[cur.Name] = cur
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		var err error
+		switch key {
+		case "Ka":
+			err = parseColor(args, &cur.Ambient)
+		case "Kd":
+			err = parseColor(args, &cur.Diffuse)
+		case "Ks":
+			err = parseColor(args, &cur.Specular)
+		case "Ke":
+			err = parseColor(args, &cur.Emissive)
+		case "Ns":
+			err = parseScalar(args, &cur.Shininess)
+		case "d":
+			err = parseScalar(args, &cur.Opacity)
+		case "Tr":
+			var tr float32
+			err = parseScalar(args, &tr)
+			cur.Opacity = 1 - tr
+		case "map_Kd":
+			cur.DiffuseMap = mapName(args)
+		case "map_Ks":
+			cur.SpecularMap = mapName(args)
+		case "map_d":
+			cur.AlphaMap = mapName(args)
+		case "norm", "map_bump", "bump", "map_Bump":
+			cur.NormalMap = mapName(args)
+		}
+		if err != nil {
+			return nil, &SyntaxError{lineno, err.Error()}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return mats, nil
+}
+
+func parseColor(args []string, dest *[3]float32) error {
+	v, err := parseFloats(args, 1)
+	if err != nil {
+		return err
+	}
+	if len(v) < 3 {
+		// a single value is gray
+		v = []float32{v[0], v[0], v[0]}
+	}
+	copy(dest[:], v)
+	return nil
+}
+
+func parseScalar(args []string, dest *float32) error {
+	v, err := parseFloats(args, 1)
+	if err != nil {
+		return err
+	}
+	*dest = v[0]
+	return nil
+}
+
+// mapName returns the file name of a texture map statement, skipping any
+// options such as "-bm 1.0" that precede it.
+func mapName(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[len(args)-1]
+}
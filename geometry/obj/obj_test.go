@@ -0,0 +1,125 @@
+package obj_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry/obj"
+	"j4k.co/gfx/gfxtest"
+	"math"
+	"strings"
+	"testing"
+)
+
+const quads = `
+# two quads sharing an edge, with different materials
+mtllib quads.mtl
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+v 2 0 0
+v 2 1 0
+vt 0 0
+vt 1 0
+vt 1 1
+vt 0 1
+usemtl red
+f 1/1 2/2 3/3 4/4
+usemtl blue
+f 2/1 5/2 6/3 -4/4
+`
+
+func TestDecode(t *testing.T) {
+	vf := gfx.VertexPosition | gfx.VertexNormal | gfx.VertexTexcoord
+	m, err := obj.Decode(strings.NewReader(quads), vf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Meshes) != 2 || m.Mesh("red") == nil || m.Mesh("blue") == nil {
+		t.Fatalf("got %d meshes, want red and blue", len(m.Meshes))
+	}
+	red := m.Mesh("red")
+	if red.VertexCount() != 4 || red.IndexCount() != 6 {
+		t.Errorf("red: got %d vertices and %d indices, want 4 and 6",
+			red.VertexCount(), red.IndexCount())
+	}
+	if len(m.MaterialLibs) != 1 || m.MaterialLibs[0] != "quads.mtl" {
+		t.Errorf("got material libs %v", m.MaterialLibs)
+	}
+}
+
+// mixed has faces with and without texcoords.
+const mixed = `
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+vt 0.5 0.5
+vn 0 0 1
+f 1/1 2/1 3/1
+f 1//1 3//1 4//1
+`
+
+func TestDecodeMissingTexcoords(t *testing.T) {
+	vf := gfx.VertexPosition | gfx.VertexTexcoord
+	m, err := obj.Decode(strings.NewReader(mixed), vf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Meshes) != 1 || m.Meshes[0].VertexCount() != 6 {
+		t.Fatalf("got %d meshes, want one of 6 vertices", len(m.Meshes))
+	}
+
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+	geom, err := gfx.NewGeometry(m.Meshes[0], gfx.StaticDraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geom.Delete()
+	var data []byte
+	for _, c := range rec.Uploads() {
+		if fmt.Sprint(c.Args[0]) == "ARRAY_BUFFER" {
+			data, _ = c.Args[len(c.Args)-1].([]byte)
+		}
+	}
+	for i := 0; i < 6; i++ {
+		var tc [2]float32
+		for j := range tc {
+			tc[j] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*vf.Stride()+12+4*j:]))
+		}
+		want := [2]float32{0.5, 0.5}
+		if i >= 3 {
+			want = [2]float32{}
+		}
+		if tc != want {
+			t.Errorf("vertex %d: got texcoord %v, want %v", i, tc, want)
+		}
+	}
+}
+
+func TestDecodeBadIndex(t *testing.T) {
+	_, err := obj.Decode(strings.NewReader("v 0 0 0\nf 1 2 3\n"), gfx.VertexPosition)
+	if err == nil {
+		t.Fatal("expected error for out of range index")
+	}
+}
+
+const mtl = `
+newmtl red
+Kd 1 0 0
+d 0.5
+map_Kd -bm 1 red.png
+`
+
+func TestDecodeMTL(t *testing.T) {
+	mats, err := obj.DecodeMTL(strings.NewReader(mtl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	red := mats["red"]
+	if red == nil || red.Diffuse != [3]float32{1, 0, 0} || red.Opacity != 0.5 || red.DiffuseMap != "red.png" {
+		t.Fatalf("got %+v", red)
+	}
+}
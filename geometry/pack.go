@@ -0,0 +1,93 @@
+package geometry
+
+import (
+	"j4k.co/gfx"
+	"math"
+	"unsafe"
+)
+
+// The builder keeps vertices unpacked, so that they can be read back and
+// modified, and packs them as they are copied into a buffer.
+
+// pack returns the vertices packed as the modifiers of the format ask.
+func (b *VertexBuilder) pack() []byte {
+	n := b.VertexCount()
+	out := make([]byte, n*b.vf.Stride())
+	o := 0
+	for v := 0; v < n; v++ {
+		src := b.verts[v*b.stride:]
+		for i := gfx.VertexFormat(1); i <= gfx.MaxVertexFormat; i <<= 1 {
+			if b.vf&i == 0 {
+				continue
+			}
+			size, packed := i.AttribBytes(), b.vf.AttribSize(i)
+			switch {
+			case packed == size:
+				// copied as is, including integer user data
+				copy(out[o:], src[:size])
+			case size == 8:
+				uv := (*[2]float32)(unsafe.Pointer(&src[0]))
+				*(*uint16)(unsafe.Pointer(&out[o])) = halfFloat(uv[0])
+				*(*uint16)(unsafe.Pointer(&out[o+2])) = halfFloat(uv[1])
+			default:
+				n := (*[3]float32)(unsafe.Pointer(&src[0]))
+				*(*uint32)(unsafe.Pointer(&out[o])) = packSnorm10(n[0], n[1], n[2])
+			}
+			o += packed
+			src = src[size:]
+		}
+	}
+	return out
+}
+
+// halfFloat converts f to an IEEE 754 half precision float, rounding to
+// nearest.
+func halfFloat(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23&0xff) - 127 + 15
+	mant := bits & 0x7fffff
+	switch {
+	case bits>>23&0xff == 0xff:
+		// infinity or NaN
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	case exp >= 0x1f:
+		// overflows to infinity
+		return sign | 0x7c00
+	case exp <= 0:
+		// subnormal, or too small for one
+		if exp < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint(14 - exp)
+		h := mant >> shift
+		if mant>>(shift-1)&1 != 0 {
+			h++
+		}
+		return sign | uint16(h)
+	}
+	// a carry out of the mantissa correctly increments the exponent
+	h := uint16(exp)<<10 | uint16(mant>>13)
+	if mant&0x1000 != 0 {
+		h++
+	}
+	return sign | h
+}
+
+// packSnorm10 packs a vector with components in [-1, 1] as
+// INT_2_10_10_10_REV, leaving w zero.
+func packSnorm10(x, y, z float32) uint32 {
+	snorm := func(v float32) uint32 {
+		if v > 1 {
+			v = 1
+		} else if v < -1 {
+			v = -1
+		}
+		return uint32(int32(math.Round(float64(v)*511))) & 0x3ff
+	}
+	return snorm(x) | snorm(y)<<10 | snorm(z)<<20
+}
@@ -0,0 +1,122 @@
+package geometry
+
+import (
+	"j4k.co/gfx"
+	"unsafe"
+)
+
+// Hit is the intersection of a ray with a triangle.
+type Hit struct {
+	// Distance along the ray, in units of the ray direction's length, so
+	// that the hit point is origin + Distance*dir.
+	Distance float32
+
+	// Barycentric coordinates of the hit point, weighting the first,
+	// second, and third vertices of the triangle. They interpolate any
+	// vertex data at the hit point.
+	Barycentric [3]float32
+
+	// Triangle is the index of the triangle hit. Its vertices are indices
+	// 3*Triangle to 3*Triangle+2, or the vertices themselves if the
+	// builder has no indices.
+	Triangle int
+}
+
+// Raycast intersects the ray from origin along dir with the triangles of b,
+// both front and back faces, and returns the nearest hit in front of the
+// origin. It tests every triangle, so large meshes are better split or
+// tested against their bounds first.
+func Raycast(b *Builder, origin, dir [3]float32) (hit Hit, ok bool) {
+	if b.vf&gfx.VertexPosition == 0 {
+		return Hit{}, false
+	}
+	b.fillVertex()
+	n := b.IndexCount()
+	if n == 0 {
+		n = b.VertexCount()
+	}
+	for tri := 0; tri < n/3; tri++ {
+		p0 := b.position(b.vertex(3 * tri))
+		p1 := b.position(b.vertex(3*tri + 1))
+		p2 := b.position(b.vertex(3*tri + 2))
+		t, u, v, found := intersectTriangle(origin, dir, p0, p1, p2)
+		if !found || (ok && t >= hit.Distance) {
+			continue
+		}
+		hit = Hit{
+			Distance:    t,
+			Barycentric: [3]float32{1 - u - v, u, v},
+			Triangle:    tri,
+		}
+		ok = true
+	}
+	return hit, ok
+}
+
+// vertex returns the vertex of the i'th index, or i itself without indices.
+func (b *IndexBuilder) vertex(i int) int {
+	switch {
+	case b.wide:
+		return int(b.idxs32[i])
+	case len(b.idxs) > 0:
+		return int(b.idxs[i])
+	}
+	return i
+}
+
+// position returns the position of the i'th vertex.
+func (b *VertexBuilder) position(i int) [3]float32 {
+	return *b.vec3(gfx.VertexPosition, i)
+}
+
+// vec3 returns the three floats of data v of the i'th vertex, in place.
+func (b *VertexBuilder) vec3(v gfx.VertexFormat, i int) *[3]float32 {
+	return (*[3]float32)(unsafe.Pointer(&b.verts[i*b.stride+b.offset(v)]))
+}
+
+// intersectTriangle intersects a ray with the triangle p0, p1, p2 using the
+// Möller–Trumbore algorithm, returning the distance along the ray and the
+// barycentric coordinates of p1 and p2 at the hit point.
+func intersectTriangle(origin, dir, p0, p1, p2 [3]float32) (t, u, v float32, ok bool) {
+	const epsilon = 1e-7
+	e1 := sub(p1, p0)
+	e2 := sub(p2, p0)
+	p := cross(dir, e2)
+	det := dot(e1, p)
+	if det > -epsilon && det < epsilon {
+		// the ray is parallel to the triangle
+		return 0, 0, 0, false
+	}
+	inv := 1 / det
+	s := sub(origin, p0)
+	u = dot(s, p) * inv
+	if u < 0 || u > 1 {
+		return 0, 0, 0, false
+	}
+	q := cross(s, e1)
+	v = dot(dir, q) * inv
+	if v < 0 || u+v > 1 {
+		return 0, 0, 0, false
+	}
+	t = dot(e2, q) * inv
+	if t < 0 {
+		return 0, 0, 0, false
+	}
+	return t, u, v, true
+}
+
+func sub(a, b [3]float32) [3]float32 {
+	return [3]float32{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func dot(a, b [3]float32) float32 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func cross(a, b [3]float32) [3]float32 {
+	return [3]float32{
+		a[1]*b[2] - a[2]*b[1],
+		a[2]*b[0] - a[0]*b[2],
+		a[0]*b[1] - a[1]*b[0],
+	}
+}
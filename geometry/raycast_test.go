@@ -0,0 +1,38 @@
+package geometry_test
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"testing"
+)
+
+func TestRaycast(t *testing.T) {
+	// two quads facing +z, at z=0 and z=-2
+	b := geometry.NewBuilder(gfx.VertexPosition)
+	for _, z := range []float32{-2, 0} {
+		b.Position(-1, -1, z)
+		b.Position(1, -1, z)
+		b.Position(1, 1, z)
+		b.Position(-1, 1, z)
+		b.Indices(0, 1, 2, 2, 3, 0)
+	}
+
+	hit, ok := geometry.Raycast(b, [3]float32{0.5, -0.5, 5}, [3]float32{0, 0, -1})
+	if !ok {
+		t.Fatal("ray toward the quads missed")
+	}
+	if hit.Distance != 5 || hit.Triangle != 2 {
+		t.Errorf("got distance %v on triangle %d, want 5 on triangle 2", hit.Distance, hit.Triangle)
+	}
+	// the hit point is (0.5, -0.5) in the triangle (-1, -1), (1, -1), (1, 1)
+	if want := [3]float32{0.25, 0.5, 0.25}; hit.Barycentric != want {
+		t.Errorf("got barycentric coordinates %v, want %v", hit.Barycentric, want)
+	}
+
+	if _, ok := geometry.Raycast(b, [3]float32{0, 0, 5}, [3]float32{0, 0, 1}); ok {
+		t.Error("ray away from the quads hit")
+	}
+	if _, ok := geometry.Raycast(b, [3]float32{3, 0, 5}, [3]float32{0, 0, -1}); ok {
+		t.Error("ray beside the quads hit")
+	}
+}
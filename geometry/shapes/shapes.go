@@ -0,0 +1,324 @@
+/*
+Package shapes generates common primitive meshes into a geometry.Builder.
+
+Shapes are centered on the origin with Y up and wound counter-clockwise when
+seen from outside. Only the vertex data present in the builder's format is
+written: positions always, and normals, texcoords, and colors when the format
+includes them. Colors are white.
+*/
+package shapes
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"math"
+)
+
+type emitter struct {
+	b         *geometry.Builder
+	normals   bool
+	texcoords bool
+	colors    bool
+	base      uint32
+	idxs      []uint32
+}
+
+func newEmitter(b *geometry.Builder) *emitter {
+	vf := b.VertexFormat()
+	return &emitter{
+		b:         b,
+		normals:   vf&gfx.VertexNormal != 0,
+		texcoords: vf&gfx.VertexTexcoord != 0,
+		colors:    vf&gfx.VertexColor != 0,
+	}
+}
+
+// vertex emits a vertex and returns its index relative to the shape.
+func (e *emitter) vertex(x, y, z, nx, ny, nz, u, v float32) uint32 {
+	vb := e.b.Position(x, y, z)
+	if e.normals {
+		vb.Normal(nx, ny, nz)
+	}
+	if e.texcoords {
+		vb.Texcoord(u, v)
+	}
+	if e.colors {
+		vb.Color(255, 255, 255, 255)
+	}
+	e.base++
+	return e.base - 1
+}
+
+func (e *emitter) tri(a, b, c uint32) {
+	e.idxs = append(e.idxs, a, b, c)
+}
+
+// quad emits two triangles for a counter-clockwise quad.
+func (e *emitter) quad(a, b, c, d uint32) {
+	e.idxs = append(e.idxs, a, b, c, c, d, a)
+}
+
+// flush appends the indices to the builder in one call, so they share a
+// single base index.
+func (e *emitter) flush() {
+	e.b.Indices32(e.idxs...)
+}
+
+func sincos(a float64) (float32, float32) {
+	s, c := math.Sincos(a)
+	return float32(s), float32(c)
+}
+
+// Cube emits an axis-aligned cube with edges of the given size. Each face
+// has its own four vertices so normals and texcoords are per face.
+func Cube(b *geometry.Builder, size float32) {
+	e := newEmitter(b)
+	h := size / 2
+	faces := [6][3][3]float32{
+		// normal, u axis, v axis
+		{{0, 0, 1}, {1, 0, 0}, {0, 1, 0}},
+		{{0, 0, -1}, {-1, 0, 0}, {0, 1, 0}},
+		{{1, 0, 0}, {0, 0, -1}, {0, 1, 0}},
+		{{-1, 0, 0}, {0, 0, 1}, {0, 1, 0}},
+		{{0, 1, 0}, {1, 0, 0}, {0, 0, -1}},
+		{{0, -1, 0}, {1, 0, 0}, {0, 0, 1}},
+	}
+	corners := [4][2]float32{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}}
+	for _, f := range faces {
+		n, u, v := f[0], f[1], f[2]
+		var idx [4]uint32
+		for i, c := range corners {
+			var p [3]float32
+			for k := 0; k < 3; k++ {
+				p[k] = h * (n[k] + c[0]*u[k] + c[1]*v[k])
+			}
+			idx[i] = e.vertex(p[0], p[1], p[2], n[0], n[1], n[2], (c[0]+1)/2, (c[1]+1)/2)
+		}
+		e.quad(idx[0], idx[1], idx[2], idx[3])
+	}
+	e.flush()
+}
+
+// Plane emits a grid in the XZ plane facing +Y, divided into xsegs by zsegs
+// quads.
+func Plane(b *geometry.Builder, width, depth float32, xsegs, zsegs int) {
+	if xsegs < 1 {
+		xsegs = 1
+	}
+	if zsegs < 1 {
+		zsegs = 1
+	}
+	e := newEmitter(b)
+	for j := 0; j <= zsegs; j++ {
+		v := float32(j) / float32(zsegs)
+		for i := 0; i <= xsegs; i++ {
+			u := float32(i) / float32(xsegs)
+			e.vertex(-width/2+u*width, 0, depth/2-v*depth, 0, 1, 0, u, v)
+		}
+	}
+	row := uint32(xsegs + 1)
+	for j := 0; j < zsegs; j++ {
+		for i := 0; i < xsegs; i++ {
+			a := uint32(j)*row + uint32(i)
+			e.quad(a, a+1, a+1+row, a+row)
+		}
+	}
+	e.flush()
+}
+
+// UVSphere emits a sphere divided into slices around the Y axis and stacks
+// from pole to pole.
+func UVSphere(b *geometry.Builder, radius float32, slices, stacks int) {
+	if slices < 3 {
+		slices = 3
+	}
+	if stacks < 2 {
+		stacks = 2
+	}
+	e := newEmitter(b)
+	for j := 0; j <= stacks; j++ {
+		v := float32(j) / float32(stacks)
+		sinphi, cosphi := sincos(math.Pi * float64(v))
+		for i := 0; i <= slices; i++ {
+			u := float32(i) / float32(slices)
+			sintheta, costheta := sincos(2 * math.Pi * float64(u))
+			nx, ny, nz := sinphi*sintheta, cosphi, sinphi*costheta
+			e.vertex(radius*nx, radius*ny, radius*nz, nx, ny, nz, u, 1-v)
+		}
+	}
+	row := uint32(slices + 1)
+	for j := 0; j < stacks; j++ {
+		for i := 0; i < slices; i++ {
+			a := uint32(j)*row + uint32(i) // upper left
+			d := a + row                   // lower left
+			if j != stacks-1 {
+				e.tri(d, d+1, a+1)
+			}
+			if j != 0 {
+				e.tri(a+1, a, d)
+			}
+		}
+	}
+	e.flush()
+}
+
+// Icosphere emits a sphere made by subdividing an icosahedron, which has
+// more uniform triangles than a UV sphere. Texcoords use a spherical
+// projection and have a visible seam at -Z.
+func Icosphere(b *geometry.Builder, radius float32, subdivisions int) {
+	t := float32((1 + math.Sqrt(5)) / 2)
+	verts := [][3]float32{
+		{-1, t, 0}, {1, t, 0}, {-1, -t, 0}, {1, -t, 0},
+		{0, -1, t}, {0, 1, t}, {0, -1, -t}, {0, 1, -t},
+		{t, 0, -1}, {t, 0, 1}, {-t, 0, -1}, {-t, 0, 1},
+	}
+	faces := [][3]uint32{
+		{0, 11, 5}, {0, 5, 1}, {0, 1, 7}, {0, 7, 10}, {0, 10, 11},
+		{1, 5, 9}, {5, 11, 4}, {11, 10, 2}, {10, 7, 6}, {7, 1, 8},
+		{3, 9, 4}, {3, 4, 2}, {3, 2, 6}, {3, 6, 8}, {3, 8, 9},
+		{4, 9, 5}, {2, 4, 11}, {6, 2, 10}, {8, 6, 7}, {9, 8, 1},
+	}
+	for i := range verts {
+		verts[i] = normalize(verts[i])
+	}
+	for s := 0; s < subdivisions; s++ {
+		mids := make(map[[2]uint32]uint32)
+		midpoint := func(a, b uint32) uint32 {
+			key := [2]uint32{a, b}
+			if b < a {
+				key = [2]uint32{b, a}
+			}
+			if m, ok := mids[key]; ok {
+				return m
+			}
+			va, vb := verts[a], verts[b]
+			verts = append(verts, normalize([3]float32{va[0] + vb[0], va[1] + vb[1], va[2] + vb[2]}))
+			m := uint32(len(verts) - 1)
+			mids[key] = m
+			return m
+		}
+		next := make([][3]uint32, 0, len(faces)*4)
+		for _, f := range faces {
+			ab, bc, ca := midpoint(f[0], f[1]), midpoint(f[1], f[2]), midpoint(f[2], f[0])
+			next = append(next,
+				[3]uint32{f[0], ab, ca},
+				[3]uint32{f[1], bc, ab},
+				[3]uint32{f[2], ca, bc},
+				[3]uint32{ab, bc, ca})
+		}
+		faces = next
+	}
+	e := newEmitter(b)
+	for _, n := range verts {
+		u := 0.5 + float32(math.Atan2(float64(n[0]), float64(n[2]))/(2*math.Pi))
+		v := 0.5 + float32(math.Asin(float64(n[1]))/math.Pi)
+		e.vertex(radius*n[0], radius*n[1], radius*n[2], n[0], n[1], n[2], u, v)
+	}
+	for _, f := range faces {
+		e.tri(f[0], f[1], f[2])
+	}
+	e.flush()
+}
+
+func normalize(v [3]float32) [3]float32 {
+	l := float32(math.Sqrt(float64(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])))
+	return [3]float32{v[0] / l, v[1] / l, v[2] / l}
+}
+
+// Cylinder emits a cylinder around the Y axis. If caps is true, the top and
+// bottom are closed.
+func Cylinder(b *geometry.Builder, radius, height float32, slices int, caps bool) {
+	if slices < 3 {
+		slices = 3
+	}
+	e := newEmitter(b)
+	h := height / 2
+	for i := 0; i <= slices; i++ {
+		u := float32(i) / float32(slices)
+		s, c := sincos(2 * math.Pi * float64(u))
+		e.vertex(radius*s, -h, radius*c, s, 0, c, u, 0)
+		e.vertex(radius*s, h, radius*c, s, 0, c, u, 1)
+	}
+	for i := uint32(0); i < uint32(slices); i++ {
+		e.quad(2*i, 2*i+2, 2*i+3, 2*i+1)
+	}
+	if caps {
+		e.disc(radius, h, 1, slices)
+		e.disc(radius, -h, -1, slices)
+	}
+	e.flush()
+}
+
+// disc emits a cap at height y facing up if dir is positive, or down.
+func (e *emitter) disc(radius, y, dir float32, slices int) {
+	center := e.vertex(0, y, 0, 0, dir, 0, 0.5, 0.5)
+	for i := 0; i <= slices; i++ {
+		s, c := sincos(2 * math.Pi * float64(i) / float64(slices))
+		e.vertex(radius*s, y, radius*c, 0, dir, 0, 0.5+0.5*s, 0.5+0.5*c*dir)
+	}
+	for i := uint32(0); i < uint32(slices); i++ {
+		a, b := center+1+i, center+2+i
+		if dir > 0 {
+			e.tri(center, a, b)
+		} else {
+			e.tri(center, b, a)
+		}
+	}
+}
+
+// Cone emits a cone around the Y axis with its apex at +Y and a closed base.
+func Cone(b *geometry.Builder, radius, height float32, slices int) {
+	if slices < 3 {
+		slices = 3
+	}
+	e := newEmitter(b)
+	h := height / 2
+	// side normals tilt up by the cone's slope
+	l := float32(math.Sqrt(float64(height*height + radius*radius)))
+	ny, nr := radius/l, height/l
+	for i := 0; i <= slices; i++ {
+		u := float32(i) / float32(slices)
+		s, c := sincos(2 * math.Pi * float64(u))
+		e.vertex(radius*s, -h, radius*c, nr*s, ny, nr*c, u, 0)
+		// the apex is duplicated per slice with the normal of the slice's
+		// center, for smoother shading
+		sm, cm := sincos(2 * math.Pi * (float64(i) + 0.5) / float64(slices))
+		e.vertex(0, h, 0, nr*sm, ny, nr*cm, u, 1)
+	}
+	for i := uint32(0); i < uint32(slices); i++ {
+		e.tri(2*i, 2*i+2, 2*i+1)
+	}
+	e.disc(radius, -h, -1, slices)
+	e.flush()
+}
+
+// Torus emits a ring around the Y axis. major is the distance from the
+// center to the middle of the tube and minor is the radius of the tube.
+func Torus(b *geometry.Builder, major, minor float32, majorSegs, minorSegs int) {
+	if majorSegs < 3 {
+		majorSegs = 3
+	}
+	if minorSegs < 3 {
+		minorSegs = 3
+	}
+	e := newEmitter(b)
+	for j := 0; j <= minorSegs; j++ {
+		v := float32(j) / float32(minorSegs)
+		sinphi, cosphi := sincos(2 * math.Pi * float64(v))
+		for i := 0; i <= majorSegs; i++ {
+			u := float32(i) / float32(majorSegs)
+			sintheta, costheta := sincos(2 * math.Pi * float64(u))
+			r := major + minor*cosphi
+			e.vertex(r*sintheta, minor*sinphi, r*costheta,
+				cosphi*sintheta, sinphi, cosphi*costheta, u, v)
+		}
+	}
+	row := uint32(majorSegs + 1)
+	for j := 0; j < minorSegs; j++ {
+		for i := 0; i < majorSegs; i++ {
+			a := uint32(j)*row + uint32(i)
+			e.quad(a, a+1, a+1+row, a+row)
+		}
+	}
+	e.flush()
+}
@@ -0,0 +1,41 @@
+package shapes_test
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/geometry/shapes"
+	"testing"
+)
+
+func TestCounts(t *testing.T) {
+	vf := gfx.VertexPosition | gfx.VertexNormal | gfx.VertexTexcoord
+	tests := []struct {
+		name        string
+		gen         func(*geometry.Builder)
+		verts, idxs int
+	}{
+		{"cube", func(b *geometry.Builder) { shapes.Cube(b, 1) }, 24, 36},
+		{"plane", func(b *geometry.Builder) { shapes.Plane(b, 1, 1, 4, 2) }, 15, 48},
+		{"uvsphere", func(b *geometry.Builder) { shapes.UVSphere(b, 1, 8, 4) }, 45, (8*4*2 - 16) * 3},
+		{"icosphere", func(b *geometry.Builder) { shapes.Icosphere(b, 1, 1) }, 42, 80 * 3},
+		{"cylinder", func(b *geometry.Builder) { shapes.Cylinder(b, 1, 2, 8, true) }, 18 + 2*10, 8*6 + 2*8*3},
+		{"cone", func(b *geometry.Builder) { shapes.Cone(b, 1, 2, 8) }, 18 + 10, 8*3 + 8*3},
+		{"torus", func(b *geometry.Builder) { shapes.Torus(b, 1, 0.25, 8, 4) }, 45, 8 * 4 * 6},
+	}
+	for _, tt := range tests {
+		b := geometry.NewBuilder(vf)
+		tt.gen(b)
+		if b.VertexCount() != tt.verts || b.IndexCount() != tt.idxs {
+			t.Errorf("%s: got %d vertices and %d indices, want %d and %d",
+				tt.name, b.VertexCount(), b.IndexCount(), tt.verts, tt.idxs)
+		}
+	}
+}
+
+func TestPositionOnly(t *testing.T) {
+	b := geometry.NewBuilder(gfx.VertexPosition)
+	shapes.Torus(b, 1, 0.25, 16, 8)
+	if b.VertexCount() == 0 {
+		t.Fatal("no vertices emitted")
+	}
+}
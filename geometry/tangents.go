@@ -0,0 +1,101 @@
+package geometry
+
+import (
+	"j4k.co/gfx"
+	"math"
+	"unsafe"
+)
+
+// GenerateTangents fills the tangents and bitangents of the vertices, for
+// normal mapping, from their positions, normals, and texture coordinates,
+// using Lengyel's method. The tangents of the triangles sharing a vertex are
+// averaged, then made perpendicular to its normal. Either of VertexTangent
+// and VertexBitangent may be left out of the format. It should be called
+// once every triangle has been built.
+func (b *Builder) GenerateTangents() error {
+	need := gfx.VertexPosition | gfx.VertexNormal | gfx.VertexTexcoord
+	if b.vf&need != need || b.vf&(gfx.VertexTangent|gfx.VertexBitangent) == 0 {
+		return gfx.ErrBadVertexFormat
+	}
+	b.fillVertex()
+	nverts := b.VertexCount()
+	n := b.IndexCount()
+	if n == 0 {
+		n = nverts
+	}
+	// sums of the texture space directions of increasing U and V
+	udirs := make([][3]float32, nverts)
+	vdirs := make([][3]float32, nverts)
+	for tri := 0; tri < n/3; tri++ {
+		i0, i1, i2 := b.vertex(3*tri), b.vertex(3*tri+1), b.vertex(3*tri+2)
+		p0 := b.position(i0)
+		e1 := sub(b.position(i1), p0)
+		e2 := sub(b.position(i2), p0)
+		t0 := b.texcoord(i0)
+		t1, t2 := b.texcoord(i1), b.texcoord(i2)
+		du1, dv1 := t1[0]-t0[0], t1[1]-t0[1]
+		du2, dv2 := t2[0]-t0[0], t2[1]-t0[1]
+		det := du1*dv2 - du2*dv1
+		if det == 0 {
+			// degenerate texture coordinates
+			continue
+		}
+		r := 1 / det
+		var udir, vdir [3]float32
+		for k := 0; k < 3; k++ {
+			udir[k] = (e1[k]*dv2 - e2[k]*dv1) * r
+			vdir[k] = (e2[k]*du1 - e1[k]*du2) * r
+		}
+		for _, i := range [3]int{i0, i1, i2} {
+			for k := 0; k < 3; k++ {
+				udirs[i][k] += udir[k]
+				vdirs[i][k] += vdir[k]
+			}
+		}
+	}
+	for i := 0; i < nverts; i++ {
+		normal := *b.vec3(gfx.VertexNormal, i)
+		// Gram-Schmidt orthogonalize against the normal
+		u := udirs[i]
+		d := dot(normal, u)
+		t := normalize([3]float32{u[0] - normal[0]*d, u[1] - normal[1]*d, u[2] - normal[2]*d})
+		if t == ([3]float32{}) {
+			t = perpendicular(normal)
+		}
+		// the bitangent follows V, which may be mirrored
+		bt := cross(normal, t)
+		if dot(bt, vdirs[i]) < 0 {
+			bt = [3]float32{-bt[0], -bt[1], -bt[2]}
+		}
+		if b.vf&gfx.VertexTangent != 0 {
+			*b.vec3(gfx.VertexTangent, i) = t
+		}
+		if b.vf&gfx.VertexBitangent != 0 {
+			*b.vec3(gfx.VertexBitangent, i) = bt
+		}
+	}
+	return nil
+}
+
+// texcoord returns the texture coordinate of the i'th vertex.
+func (b *VertexBuilder) texcoord(i int) [2]float32 {
+	return *(*[2]float32)(unsafe.Pointer(&b.verts[i*b.stride+b.offset(gfx.VertexTexcoord)]))
+}
+
+// normalize returns v scaled to unit length, or zero if v is zero.
+func normalize(v [3]float32) [3]float32 {
+	l := float32(math.Sqrt(float64(dot(v, v))))
+	if l == 0 {
+		return [3]float32{}
+	}
+	return [3]float32{v[0] / l, v[1] / l, v[2] / l}
+}
+
+// perpendicular returns a unit vector perpendicular to the unit vector n.
+func perpendicular(n [3]float32) [3]float32 {
+	axis := [3]float32{1, 0, 0}
+	if n[0] > 0.9 || n[0] < -0.9 {
+		axis = [3]float32{0, 1, 0}
+	}
+	return normalize(cross(axis, n))
+}
@@ -0,0 +1,75 @@
+package geometry_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/gfxtest"
+	"math"
+	"testing"
+)
+
+func TestGenerateTangents(t *testing.T) {
+	vf := gfx.VertexPosition | gfx.VertexNormal | gfx.VertexTexcoord | gfx.VertexTangent | gfx.VertexBitangent
+	b := geometry.NewBuilder(vf)
+	// a quad facing +z, with V mirrored so the bitangent points down
+	b.Position(0, 0, 0).Normal(0, 0, 1).Texcoord(0, 1)
+	b.Position(1, 0, 0).Texcoord(1, 1)
+	b.Position(1, 1, 0).Texcoord(1, 0)
+	b.Position(0, 1, 0).Texcoord(0, 0)
+	b.Indices(0, 1, 2, 2, 3, 0)
+	if err := b.GenerateTangents(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := uploadedVertices(t, b)
+	if len(data) != 4*vf.Stride() {
+		t.Fatalf("got %d bytes of vertices, want %d", len(data), 4*vf.Stride())
+	}
+	for i := 0; i < 4; i++ {
+		// position, normal, tangent, bitangent, texcoord
+		var v [14]float32
+		for j := range v {
+			v[j] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*vf.Stride()+j*4:]))
+		}
+		tangent := [3]float32{v[6], v[7], v[8]}
+		bitangent := [3]float32{v[9], v[10], v[11]}
+		if tangent != [3]float32{1, 0, 0} || bitangent != [3]float32{0, -1, 0} {
+			t.Errorf("vertex %d: got tangent %v and bitangent %v", i, tangent, bitangent)
+		}
+	}
+
+	if err := geometry.NewBuilder(gfx.VertexPosition | gfx.VertexTangent).GenerateTangents(); err != gfx.ErrBadVertexFormat {
+		t.Errorf("got %v without normals and texcoords, want ErrBadVertexFormat", err)
+	}
+}
+
+// uploadedVertices returns the vertices of b as uploaded to a vertex
+// buffer.
+func uploadedVertices(t *testing.T, b *geometry.Builder) []byte {
+	return uploaded(t, b, "ARRAY_BUFFER")
+}
+
+func uploadedIndices(t *testing.T, b *geometry.Builder) []byte {
+	return uploaded(t, b, "ELEMENT_ARRAY_BUFFER")
+}
+
+// uploaded returns the last data uploaded to target when creating geometry
+// from b.
+func uploaded(t *testing.T, b *geometry.Builder, target string) []byte {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+	geom, err := gfx.NewGeometry(b, gfx.StaticDraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geom.Delete()
+	var data []byte
+	for _, c := range rec.Uploads() {
+		if fmt.Sprint(c.Args[0]) == target {
+			data, _ = c.Args[len(c.Args)-1].([]byte)
+		}
+	}
+	return data
+}
@@ -0,0 +1,65 @@
+package geometry
+
+import (
+	"j4k.co/gfx"
+)
+
+// Transform transforms the vertices built so far by m in place: positions
+// by m, tangents and bitangents by its upper 3x3 part, and normals by the
+// inverse transpose of that, renormalized. Generators can then emit shapes
+// at the origin and place them before upload.
+func (b *VertexBuilder) Transform(m gfx.Mat4) {
+	b.fillVertex()
+	b.transform(0, b.VertexCount(), &m)
+}
+
+// transform transforms vertices first through end-1 by m.
+func (b *VertexBuilder) transform(first, end int, m *gfx.Mat4) {
+	normal := normalMatrix(m)
+	for i := first; i < end; i++ {
+		if b.vf&gfx.VertexPosition != 0 {
+			p := b.vec3(gfx.VertexPosition, i)
+			*p = [3]float32(m.Transform(gfx.Vec3(*p)))
+		}
+		for _, v := range []gfx.VertexFormat{gfx.VertexTangent, gfx.VertexBitangent} {
+			if b.vf&v != 0 {
+				t := b.vec3(v, i)
+				*t = mulDir(m, *t)
+			}
+		}
+		if b.vf&gfx.VertexNormal != 0 {
+			nrm := b.vec3(gfx.VertexNormal, i)
+			*nrm = [3]float32(gfx.Vec3(mulDir(&normal, *nrm)).Normalize())
+		}
+	}
+}
+
+// mulDir returns the direction v transformed by the upper 3x3 part of m.
+func mulDir(m *gfx.Mat4, v [3]float32) [3]float32 {
+	return [3]float32{
+		m[0]*v[0] + m[4]*v[1] + m[8]*v[2],
+		m[1]*v[0] + m[5]*v[1] + m[9]*v[2],
+		m[2]*v[0] + m[6]*v[1] + m[10]*v[2],
+	}
+}
+
+// normalMatrix returns the matrix transforming normals for m, the inverse
+// transpose of its upper 3x3 part up to scale, which normals are
+// renormalized after.
+func normalMatrix(m *gfx.Mat4) gfx.Mat4 {
+	c0 := gfx.Vec3{m[0], m[1], m[2]}
+	c1 := gfx.Vec3{m[4], m[5], m[6]}
+	c2 := gfx.Vec3{m[8], m[9], m[10]}
+	// the columns of the cofactor matrix; dividing by the determinant
+	// would give the inverse transpose, but only its sign matters here
+	n0, n1, n2 := c1.Cross(c2), c2.Cross(c0), c0.Cross(c1)
+	if c0.Dot(n0) < 0 {
+		n0, n1, n2 = n0.Mul(-1), n1.Mul(-1), n2.Mul(-1)
+	}
+	return gfx.Mat4{
+		n0[0], n0[1], n0[2], 0,
+		n1[0], n1[1], n1[2], 0,
+		n2[0], n2[1], n2[2], 0,
+		0, 0, 0, 1,
+	}
+}
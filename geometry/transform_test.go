@@ -0,0 +1,40 @@
+package geometry_test
+
+import (
+	"encoding/binary"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"math"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	b := geometry.NewBuilder(gfx.VertexPosition | gfx.VertexNormal)
+	b.Position(-1, -1, 0).Normal(0, 0, 1)
+	b.Position(1, 1, 0)
+	b.Transform(gfx.Translate(gfx.Vec3{0, 0, 3}).Mul(gfx.Rotate(math.Pi/2, gfx.Vec3{1, 0, 0})))
+
+	min, max := b.Bounds()
+	want := [2][3]float32{{-1, 0, 2}, {1, 0, 4}}
+	for i, p := range [2][3]float32{min, max} {
+		for j := range p {
+			if math.Abs(float64(p[j]-want[i][j])) > 1e-5 {
+				t.Fatalf("got bounds %v, %v, want %v", min, max, want)
+			}
+		}
+	}
+	// the normal turns from +z to -y, including the copy filled forward to
+	// the second vertex
+	data := uploadedVertices(t, b)
+	const stride = 24
+	for v := 0; v < 2; v++ {
+		var normal [3]float32
+		for i := range normal {
+			bits := binary.LittleEndian.Uint32(data[v*stride+12+4*i:])
+			normal[i] = math.Float32frombits(bits)
+		}
+		if math.Abs(float64(normal[1]+1)) > 1e-5 || math.Abs(float64(normal[2])) > 1e-5 {
+			t.Errorf("got normal %v for vertex %d, want [0 -1 0]", normal, v)
+		}
+	}
+}
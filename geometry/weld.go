@@ -0,0 +1,148 @@
+package geometry
+
+import (
+	"j4k.co/gfx"
+	"math"
+	"unsafe"
+)
+
+// Weld merges vertices whose data is the same, or differs by at most
+// epsilon in each floating point component, and rewrites the indices to
+// refer to the vertices kept, indexing the builder if it had no indices.
+// Colors and bone data must match exactly. Meshes whose vertices are
+// repeated for each face, as loaded from OBJ files or made by face-based
+// generators, shrink to their distinct vertices. With a positive epsilon,
+// each merged vertex takes the data of the first of its group.
+func (b *Builder) Weld(epsilon float32) {
+	b.fillVertex()
+	n := b.VertexCount()
+	if n == 0 {
+		return
+	}
+	remap := make([]uint32, n)
+	kept := 0
+	if epsilon <= 0 || b.vf&gfx.VertexPosition == 0 {
+		seen := make(map[string]uint32, n)
+		for i := 0; i < n; i++ {
+			v := b.verts[i*b.stride : (i+1)*b.stride]
+			j, ok := seen[string(v)]
+			if !ok {
+				j = uint32(kept)
+				seen[string(v)] = j
+				b.keep(i, kept)
+				kept++
+			}
+			remap[i] = j
+		}
+	} else {
+		// vertices are bucketed by position in cells of size epsilon, so
+		// that a match lies in the same cell or a neighboring one
+		cells := make(map[[3]int32][]uint32, n)
+		floats := b.floatMask()
+		for i := 0; i < n; i++ {
+			c := cellOf(b.position(i), epsilon)
+			j, ok := b.findNear(cells, c, i, floats, epsilon)
+			if !ok {
+				j = uint32(kept)
+				b.keep(i, kept)
+				cells[c] = append(cells[c], j)
+				kept++
+			}
+			remap[i] = j
+		}
+	}
+
+	nidx := b.IndexCount()
+	if nidx == 0 {
+		nidx = n
+	}
+	idxs := make([]uint32, nidx)
+	for i := range idxs {
+		idxs[i] = remap[b.vertex(i)]
+	}
+	b.verts = b.verts[:kept*b.stride]
+	b.settle()
+	b.IndexBuilder.Clear()
+	for _, idx := range idxs {
+		b.IndexBuilder.append(idx)
+	}
+	b.nextidx = uint32(kept)
+}
+
+// keep moves vertex i to slot j, where j <= i.
+func (b *VertexBuilder) keep(i, j int) {
+	if i != j {
+		copy(b.verts[j*b.stride:(j+1)*b.stride], b.verts[i*b.stride:(i+1)*b.stride])
+	}
+}
+
+// floatMask returns which 4-byte words of a vertex hold floats, rather than
+// 8-bit channels.
+func (b *VertexBuilder) floatMask() []bool {
+	mask := make([]bool, b.stride/4)
+	for v := gfx.VertexFormat(1); v <= gfx.MaxVertexFormat; v <<= 1 {
+		if b.vf&v == 0 || byteAttrib(v) {
+			continue
+		}
+		offs := b.offset(v) / 4
+		for k := 0; k < v.AttribBytes()/4; k++ {
+			mask[offs+k] = true
+		}
+	}
+	return mask
+}
+
+// byteAttrib reports whether v is stored as 8-bit channels.
+func byteAttrib(v gfx.VertexFormat) bool {
+	switch v {
+	case gfx.VertexColor, gfx.VertexColor1, gfx.VertexBoneIndices, gfx.VertexBoneWeights:
+		return true
+	}
+	return false
+}
+
+func cellOf(p [3]float32, size float32) [3]int32 {
+	var c [3]int32
+	for i, x := range p {
+		c[i] = int32(math.Floor(float64(x / size)))
+	}
+	return c
+}
+
+// findNear returns the kept vertex within epsilon of vertex i, among those
+// in cell c and its neighbors.
+func (b *VertexBuilder) findNear(cells map[[3]int32][]uint32, c [3]int32, i int, floats []bool, epsilon float32) (uint32, bool) {
+	for dx := int32(-1); dx <= 1; dx++ {
+		for dy := int32(-1); dy <= 1; dy++ {
+			for dz := int32(-1); dz <= 1; dz++ {
+				for _, j := range cells[[3]int32{c[0] + dx, c[1] + dy, c[2] + dz}] {
+					if b.near(i, int(j), floats, epsilon) {
+						return j, true
+					}
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// near reports whether vertices i and j match within epsilon.
+func (b *VertexBuilder) near(i, j int, floats []bool, epsilon float32) bool {
+	vi := b.verts[i*b.stride : (i+1)*b.stride]
+	vj := b.verts[j*b.stride : (j+1)*b.stride]
+	for w, isFloat := range floats {
+		x := *(*uint32)(unsafe.Pointer(&vi[4*w]))
+		y := *(*uint32)(unsafe.Pointer(&vj[4*w]))
+		if !isFloat {
+			if x != y {
+				return false
+			}
+			continue
+		}
+		d := math.Float32frombits(x) - math.Float32frombits(y)
+		if d > epsilon || d < -epsilon {
+			return false
+		}
+	}
+	return true
+}
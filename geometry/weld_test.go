@@ -0,0 +1,46 @@
+package geometry_test
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"testing"
+)
+
+func TestWeld(t *testing.T) {
+	// two triangles of a quad, with their shared corners repeated and one
+	// slightly off
+	b := geometry.NewBuilder(gfx.VertexPosition | gfx.VertexColor)
+	b.Position(0, 0, 0).Color(255, 0, 0, 255)
+	b.Position(1, 0, 0)
+	b.Position(1, 1, 0)
+	b.Position(1, 1.0001, 0)
+	b.Position(0, 1, 0)
+	b.Position(0, 0, 0)
+
+	exact := geometry.NewBuilder(gfx.VertexPosition | gfx.VertexColor)
+	if err := exact.Append(b, gfx.Ident4()); err != nil {
+		t.Fatal(err)
+	}
+	exact.Weld(0)
+	if exact.VertexCount() != 5 || exact.IndexCount() != 6 {
+		t.Fatalf("exact weld left %d vertices and %d indices, want 5 and 6", exact.VertexCount(), exact.IndexCount())
+	}
+
+	b.Weld(0.001)
+	if b.VertexCount() != 4 || b.IndexCount() != 6 {
+		t.Fatalf("weld left %d vertices and %d indices, want 4 and 6", b.VertexCount(), b.IndexCount())
+	}
+	hit, ok := geometry.Raycast(b, [3]float32{0.25, 0.75, 1}, [3]float32{0, 0, -1})
+	if !ok || hit.Triangle != 1 {
+		t.Errorf("got hit %+v, %v, want the second triangle", hit, ok)
+	}
+
+	// a different color keeps a vertex apart
+	c := geometry.NewBuilder(gfx.VertexPosition | gfx.VertexColor)
+	c.Position(0, 0, 0).Color(255, 0, 0, 255)
+	c.Position(0, 0, 0).Color(0, 255, 0, 255)
+	c.Weld(0.001)
+	if c.VertexCount() != 2 {
+		t.Errorf("weld merged vertices of different colors")
+	}
+}
@@ -0,0 +1,291 @@
+package gfx_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/gfxtest"
+	"reflect"
+	"testing"
+)
+
+func TestMeasuredBounds(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	geom, _, err := gfx.FullscreenGeometry(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	min, max, ok := geom.Bounds()
+	if !ok || min != [3]float32{-1, -1, 0} || max != [3]float32{3, 3, 0} {
+		t.Errorf("got bounds %v, %v, %v", min, max, ok)
+	}
+	if center, _, ok := geom.BoundingSphere(); !ok || center != [3]float32{1, 1, 0} {
+		t.Errorf("got bounding sphere center %v, %v", center, ok)
+	}
+}
+
+func TestPlanarGeometry(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	attrs := gfx.VertexAttributes{gfx.VertexPosition: "Position", gfx.VertexColor: "Color"}
+	s, err := gfx.BuildShader(attrs, colorVertexShader, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+
+	type position struct{ P [3]float32 }
+	type color struct{ C [4]uint8 }
+	positions, _ := gfx.NewTypedBuffer[position](gfx.VertexPosition)
+	positions.Append(position{[3]float32{0, 0, 0}}, position{[3]float32{1, 0, 0}}, position{[3]float32{0, 1, 0}})
+	colors, _ := gfx.NewTypedBuffer[color](gfx.VertexColor)
+	colors.Append(color{}, color{}, color{})
+
+	geom, err := gfx.NewGeometry(positions, gfx.StaticDraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geom.Delete()
+	if err := s.SetGeometry(geom); err == nil {
+		t.Fatal("laid out geometry without colors")
+	}
+	if _, err := geom.AddVertexBuffer(positions, gfx.StaticDraw); err == nil {
+		t.Fatal("added positions twice")
+	}
+	colorBuf, err := geom.AddVertexBuffer(colors, gfx.DynamicDraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if geom.VertexFormat() != gfx.VertexPosition|gfx.VertexColor || geom.AttributeBuffer(gfx.VertexColor) != colorBuf {
+		t.Fatalf("got format %v", geom.VertexFormat())
+	}
+
+	rec.Reset()
+	s.Use()
+	if err := s.SetGeometry(geom); err != nil {
+		t.Fatal(err)
+	}
+	var pointers []string
+	for _, c := range rec.Ops("VertexAttribPointer") {
+		pointers = append(pointers, c.String())
+	}
+	want := []string{
+		"VertexAttribPointer(0, 3, FLOAT, false, 12, 0)",
+		"VertexAttribPointer(1, 4, UNSIGNED_BYTE, true, 4, 0)",
+	}
+	if !reflect.DeepEqual(pointers, want) {
+		t.Errorf("got pointers %v, want %v", pointers, want)
+	}
+}
+
+func TestIndexSlice(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	b := geometry.NewBuilder(gfx.VertexPosition)
+	for i := 0; i < 8; i++ {
+		b.Position(float32(i), 0, 0)
+	}
+	b.Quad(0, 1, 2, 3).Quad(4, 5, 6, 7)
+	geom, err := gfx.NewGeometry(b, gfx.StaticDraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geom.Delete()
+	other := quad(t)
+	defer other.Delete()
+
+	second := geom.Slice(6, 12)
+	last := second.Slice(3, 6)
+	if second.Offset() != 6 || second.Count() != 6 || last.Offset() != 9 || last.Count() != 3 {
+		t.Errorf("got slices at %d+%d and %d+%d", second.Offset(), second.Count(), last.Offset(), last.Count())
+	}
+
+	s.Use()
+	if err := s.SetGeometry(geom); err != nil {
+		t.Fatal(err)
+	}
+	rec.Reset()
+	if err := s.DrawSlice(second); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DrawSlice(last); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DrawSlice(other.Slice(0, 3)); err == nil {
+		t.Error("drew a slice of another geometry")
+	}
+	whole := geom.IndexBuffer
+	geom.IndexBuffer = second
+	if err := s.SetGeometry(geom); err != nil {
+		t.Fatal(err)
+	}
+	s.Draw()
+	geom.IndexBuffer = whole
+
+	// offsets are in bytes of 16-bit indices
+	want := fmt.Sprint([]string{
+		"DrawElements(TRIANGLES, 6, UNSIGNED_SHORT, 12)",
+		"DrawElements(TRIANGLES, 3, UNSIGNED_SHORT, 18)",
+		"DrawElements(TRIANGLES, 6, UNSIGNED_SHORT, 12)",
+	})
+	var got []string
+	for _, c := range rec.Draws() {
+		got = append(got, c.String())
+	}
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("no panic slicing out of range")
+		}
+	}()
+	second.Slice(0, 7)
+}
+
+func TestSetAt(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	geom := quad(t)
+	defer geom.Delete()
+	stride := gfx.VertexPosition.Stride()
+	rec.Reset()
+	if err := geom.SetVerticesAt(stride, make([]byte, 2*stride)); err != nil {
+		t.Fatal(err)
+	}
+	if err := geom.SetVerticesAt(3*stride, make([]byte, 2*stride)); err != gfx.ErrBufferRange {
+		t.Errorf("got %v writing past the vertices, want ErrBufferRange", err)
+	}
+	tail := geom.Slice(3, 6)
+	if err := tail.SetIndicesAt(1, []uint16{9, 8}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tail.SetIndicesAt(2, []uint16{9, 8}); err != gfx.ErrBufferRange {
+		t.Errorf("got %v writing past the slice, want ErrBufferRange", err)
+	}
+	if err := geom.SetIndicesAt32(0, []uint32{1}); err == nil {
+		t.Error("wrote 32-bit indices to a 16-bit buffer")
+	}
+
+	// only the ranges are written, without reallocating
+	if n := len(rec.Ops("BufferData")); n != 0 {
+		t.Errorf("reallocated %d times", n)
+	}
+	updates := rec.Ops("BufferSubData")
+	if len(updates) != 2 {
+		t.Fatalf("got updates %v, want 2", updates)
+	}
+	if got := fmt.Sprint(updates[0].Args[:3]); got != fmt.Sprint("[ARRAY_BUFFER ", stride, " ", 2*stride, "]") {
+		t.Errorf("got vertex update %v", got)
+	}
+	// the indices are at 3+1 in the buffer, two bytes each
+	if got := fmt.Sprint(updates[1].Args[:4]); got != "[ELEMENT_ARRAY_BUFFER 8 4 [9 0 8 0]]" {
+		t.Errorf("got index update %v", got)
+	}
+}
+
+func TestMapWrite(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	geom := quad(t)
+	defer geom.Delete()
+	stride := gfx.VertexPosition.Stride()
+	fill := func(v byte) func([]byte) error {
+		return func(dest []byte) error {
+			for i := range dest {
+				dest[i] = v
+			}
+			return nil
+		}
+	}
+	rec.Reset()
+	// the first mapping is lost, so the vertices are written twice
+	rec.UnmapFailures = 1
+	if err := geom.MapWrite(2*stride, fill(7)); err != nil {
+		t.Fatal(err)
+	}
+	if geom.VertexBuffer.Count() != 2 {
+		t.Errorf("got %d vertices, want 2", geom.VertexBuffer.Count())
+	}
+	var ops []string
+	for _, c := range rec.Ops("BufferData", "MapBuffer", "UnmapBuffer") {
+		ops = append(ops, c.String())
+	}
+	want := fmt.Sprint([]string{
+		fmt.Sprint("BufferData(ARRAY_BUFFER, ", 2*stride, ", [0 bytes], STREAM_DRAW)"),
+		"MapBuffer(ARRAY_BUFFER, WRITE_ONLY)", fmt.Sprint("UnmapBuffer(ARRAY_BUFFER, [", 2*stride, " bytes])"),
+		"MapBuffer(ARRAY_BUFFER, WRITE_ONLY)", fmt.Sprint("UnmapBuffer(ARRAY_BUFFER, [", 2*stride, " bytes])"),
+	})
+	if fmt.Sprint(ops) != want {
+		t.Errorf("got %v,\nwant %v", ops, want)
+	}
+	if data := rec.Ops("UnmapBuffer")[1].Args[1].([]byte); data[0] != 7 || data[len(data)-1] != 7 {
+		t.Errorf("wrote %v", data)
+	}
+
+	rec.Reset()
+	rec.UnmapFailures = 5
+	if err := geom.MapWrite(stride, fill(1)); err == nil {
+		t.Error("no error when every mapping is lost")
+	}
+	rec.UnmapFailures = 0
+}
+
+func TestStreamWrite(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	geom := quad(t)
+	defer geom.Delete()
+	stride := gfx.VertexPosition.Stride()
+	noop := func([]byte) error { return nil }
+	// the quad's 4 vertices fill the buffer, so the first write orphans it
+	rec.Reset()
+	var offsets []int
+	for _, n := range []int{2, 1, 3, 6} {
+		offset, err := geom.StreamWrite(n*stride, noop)
+		if err != nil {
+			t.Fatal(err)
+		}
+		offsets = append(offsets, offset/stride)
+	}
+	if fmt.Sprint(offsets) != "[0 2 0 0]" {
+		t.Errorf("wrote at vertices %v, want [0 2 0 0]", offsets)
+	}
+	if geom.VertexBuffer.Count() != 6 {
+		t.Errorf("got %d vertices, want the 6 since the last orphaning", geom.VertexBuffer.Count())
+	}
+
+	// orphaned when full, at the same size or grown to fit
+	var sizes []interface{}
+	for _, c := range rec.Ops("BufferData") {
+		sizes = append(sizes, c.Args[1].(int)/stride)
+	}
+	if fmt.Sprint(sizes) != "[4 4 8]" {
+		t.Errorf("allocated %v vertices, want [4 4 8]", sizes)
+	}
+	var ranges []string
+	for _, c := range rec.Ops("MapBufferRange") {
+		ranges = append(ranges, fmt.Sprint(c.Args[1].(int)/stride, "+", c.Args[2].(int)/stride))
+	}
+	if fmt.Sprint(ranges) != "[0+2 2+1 0+3 0+6]" {
+		t.Errorf("mapped vertices %v", ranges)
+	}
+}
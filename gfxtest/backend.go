@@ -0,0 +1,534 @@
+package gfxtest
+
+import (
+	"fmt"
+	"j4k.co/gfx/internal/gl"
+	"reflect"
+	"strings"
+	"unsafe"
+)
+
+// bytesOf returns a copy of the memory of data, a slice or a pointer, or
+// nil if data is nil or an offset into a bound buffer.
+func bytesOf(data interface{}) []byte {
+	v := reflect.ValueOf(data)
+	var n int
+	switch v.Kind() {
+	case reflect.Slice:
+		n = v.Len() * int(v.Type().Elem().Size())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		n = int(v.Type().Elem().Size())
+	default:
+		return nil
+	}
+	if n == 0 {
+		return []byte{}
+	}
+	return append([]byte(nil), unsafe.Slice((*byte)(unsafe.Pointer(v.Pointer())), n)...)
+}
+
+func (r *Recorder) GenBuffers(bufs []gl.Buffer) {
+	names := make([]uint32, len(bufs))
+	for i := range bufs {
+		bufs[i] = gl.Buffer(r.genName())
+		r.buffers[bufs[i]] = nil
+		names[i] = uint32(bufs[i])
+	}
+	r.record("GenBuffers", names)
+}
+
+func (r *Recorder) DeleteBuffers(bufs []gl.Buffer) {
+	names := make([]uint32, len(bufs))
+	for i, b := range bufs {
+		delete(r.buffers, b)
+		for target, bound := range r.bound {
+			if bound == b {
+				delete(r.bound, target)
+			}
+		}
+		names[i] = uint32(b)
+	}
+	r.record("DeleteBuffers", names)
+}
+
+func (r *Recorder) BindBuffer(target gl.GLenum, buf gl.Buffer) {
+	r.bound[target] = buf
+	r.record("BindBuffer", Enum(target), uint32(buf))
+}
+
+func (r *Recorder) BindBufferBase(target gl.GLenum, index uint, buf gl.Buffer) {
+	r.bound[target] = buf
+	r.record("BindBufferBase", Enum(target), index, uint32(buf))
+}
+
+func (r *Recorder) BufferData(target gl.GLenum, size int, data interface{}, usage gl.GLenum) {
+	b := make([]byte, size)
+	src := bytesOf(data)
+	copy(b, src)
+	if buf, ok := r.bound[target]; ok {
+		r.buffers[buf] = b
+	}
+	r.record("BufferData", Enum(target), size, src, Enum(usage))
+}
+
+func (r *Recorder) BufferSubData(target gl.GLenum, offset, size int, data interface{}) {
+	src := bytesOf(data)
+	if len(src) > size {
+		src = src[:size]
+	}
+	if buf, ok := r.bound[target]; ok {
+		if b := r.buffers[buf]; offset >= 0 && offset <= len(b) {
+			copy(b[offset:], src)
+		}
+	}
+	r.record("BufferSubData", Enum(target), offset, size, src)
+}
+
+// mapBuffer returns a pointer to length bytes at offset in the buffer bound
+// to target, which stay valid until it is given new storage.
+func (r *Recorder) mapBuffer(target gl.GLenum, offset, length int, write bool) unsafe.Pointer {
+	buf, ok := r.bound[target]
+	b := r.buffers[buf]
+	if !ok || offset < 0 || length <= 0 || offset+length > len(b) {
+		return nil
+	}
+	r.mapped[target] = mapping{offset: offset, length: length, write: write}
+	return unsafe.Pointer(&b[offset])
+}
+
+func (r *Recorder) MapBuffer(target, access gl.GLenum) unsafe.Pointer {
+	buf := r.bound[target]
+	r.record("MapBuffer", Enum(target), Enum(access))
+	return r.mapBuffer(target, 0, len(r.buffers[buf]), access != gl.READ_ONLY)
+}
+
+func (r *Recorder) MapBufferRange(target gl.GLenum, offset, length int, access gl.GLbitfield) unsafe.Pointer {
+	r.record("MapBufferRange", Enum(target), offset, length, uint32(access))
+	return r.mapBuffer(target, offset, length, access&gl.MAP_WRITE_BIT != 0)
+}
+
+// UnmapBuffer records a copy of the mapped range as its last argument if
+// it was mapped for writing.
+func (r *Recorder) UnmapBuffer(target gl.GLenum) bool {
+	m, ok := r.mapped[target]
+	if !ok {
+		r.record("UnmapBuffer", Enum(target))
+		return false
+	}
+	delete(r.mapped, target)
+	if !m.write {
+		r.record("UnmapBuffer", Enum(target))
+		return true
+	}
+	b := r.buffers[r.bound[target]]
+	r.record("UnmapBuffer", Enum(target), append([]byte(nil), b[m.offset:m.offset+m.length]...))
+	if r.UnmapFailures > 0 {
+		r.UnmapFailures--
+		return false
+	}
+	return true
+}
+
+func (r *Recorder) GenVertexArray() gl.VertexArray {
+	vao := gl.VertexArray(r.genName())
+	r.record("GenVertexArray", uint32(vao))
+	return vao
+}
+
+func (r *Recorder) BindVertexArray(vao gl.VertexArray) {
+	r.record("BindVertexArray", uint32(vao))
+}
+
+func (r *Recorder) DeleteVertexArray(vao gl.VertexArray) {
+	r.record("DeleteVertexArray", uint32(vao))
+}
+
+func (r *Recorder) VertexAttribPointer(index gl.AttribLocation, size uint, typ gl.GLenum, normalized bool, stride int, pointer interface{}) {
+	offset, _ := pointer.(uintptr)
+	r.record("VertexAttribPointer", int(index), size, Enum(typ), normalized, stride, offset)
+}
+
+func (r *Recorder) VertexAttribIPointer(index gl.AttribLocation, size uint, typ gl.GLenum, stride int, pointer interface{}) {
+	offset, _ := pointer.(uintptr)
+	r.record("VertexAttribIPointer", int(index), size, Enum(typ), stride, offset)
+}
+
+func (r *Recorder) EnableVertexAttribArray(index gl.AttribLocation) {
+	r.record("EnableVertexAttribArray", int(index))
+}
+
+func (r *Recorder) DisableVertexAttribArray(index gl.AttribLocation) {
+	r.record("DisableVertexAttribArray", int(index))
+}
+
+func (r *Recorder) VertexAttribDivisor(index gl.AttribLocation, divisor int) {
+	r.record("VertexAttribDivisor", int(index), divisor)
+}
+
+func (r *Recorder) GenTexture() gl.Texture {
+	tex := gl.Texture(r.genName())
+	r.record("GenTexture", uint32(tex))
+	return tex
+}
+
+func (r *Recorder) BindTexture(target gl.GLenum, tex gl.Texture) {
+	r.record("BindTexture", Enum(target), uint32(tex))
+}
+
+func (r *Recorder) DeleteTexture(tex gl.Texture) {
+	r.record("DeleteTexture", uint32(tex))
+}
+
+func (r *Recorder) ActiveTexture(unit gl.GLenum) {
+	r.record("ActiveTexture", Enum(unit))
+}
+
+func (r *Recorder) TexParameteri(target, pname gl.GLenum, param int) {
+	r.record("TexParameteri", Enum(target), Enum(pname), param)
+}
+
+func (r *Recorder) TexParameterf(target, pname gl.GLenum, param float32) {
+	r.record("TexParameterf", Enum(target), Enum(pname), param)
+}
+
+func (r *Recorder) TexImage2D(target gl.GLenum, level, internalformat, width, height, border int, format, typ gl.GLenum, pixels interface{}) {
+	r.record("TexImage2D", Enum(target), level, Enum(internalformat), width, height, border, Enum(format), Enum(typ), bytesOf(pixels))
+}
+
+func (r *Recorder) TexSubImage2D(target gl.GLenum, level, xoffset, yoffset, width, height int, format, typ gl.GLenum, pixels interface{}) {
+	r.record("TexSubImage2D", Enum(target), level, xoffset, yoffset, width, height, Enum(format), Enum(typ), bytesOf(pixels))
+}
+
+func (r *Recorder) CompressedTexImage2D(target gl.GLenum, level int, internalformat gl.GLenum, width, height, border, imageSize int, data interface{}) {
+	r.record("CompressedTexImage2D", Enum(target), level, Enum(internalformat), width, height, border, imageSize, bytesOf(data))
+}
+
+func (r *Recorder) GenerateMipmap(target gl.GLenum) {
+	r.record("GenerateMipmap", Enum(target))
+}
+
+func (r *Recorder) PixelStorei(pname gl.GLenum, param int) {
+	r.record("PixelStorei", Enum(pname), param)
+}
+
+// ReadPixels copies r.Pixels into pixels, since nothing is rendered.
+func (r *Recorder) ReadPixels(x, y, width, height int, format, typ gl.GLenum, pixels interface{}) {
+	r.record("ReadPixels", x, y, width, height, Enum(format), Enum(typ))
+	if r.Pixels == nil {
+		return
+	}
+	v := reflect.ValueOf(pixels)
+	if v.Kind() != reflect.Slice || v.Len() == 0 {
+		return
+	}
+	n := v.Len() * int(v.Type().Elem().Size())
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(v.Pointer())), n), r.Pixels)
+}
+
+func (r *Recorder) ReadBuffer(mode gl.GLenum) {
+	r.record("ReadBuffer", Enum(mode))
+}
+
+func (r *Recorder) GenFramebuffer() gl.Framebuffer {
+	fb := gl.Framebuffer(r.genName())
+	r.record("GenFramebuffer", uint32(fb))
+	return fb
+}
+
+func (r *Recorder) BindFramebuffer(target gl.GLenum, fb gl.Framebuffer) {
+	r.record("BindFramebuffer", Enum(target), uint32(fb))
+}
+
+func (r *Recorder) DeleteFramebuffer(fb gl.Framebuffer) {
+	r.record("DeleteFramebuffer", uint32(fb))
+}
+
+func (r *Recorder) CheckFramebufferStatus(target gl.GLenum) gl.GLenum {
+	return gl.FRAMEBUFFER_COMPLETE
+}
+
+func (r *Recorder) FramebufferTexture2D(target, attachment, textarget gl.GLenum, tex gl.Texture, level int) {
+	r.record("FramebufferTexture2D", Enum(target), Enum(attachment), Enum(textarget), uint32(tex), level)
+}
+
+func (r *Recorder) FramebufferRenderbuffer(target, attachment, rbtarget gl.GLenum, rb gl.Renderbuffer) {
+	r.record("FramebufferRenderbuffer", Enum(target), Enum(attachment), Enum(rbtarget), uint32(rb))
+}
+
+func (r *Recorder) GenRenderbuffer() gl.Renderbuffer {
+	rb := gl.Renderbuffer(r.genName())
+	r.record("GenRenderbuffer", uint32(rb))
+	return rb
+}
+
+func (r *Recorder) BindRenderbuffer(rb gl.Renderbuffer) {
+	r.record("BindRenderbuffer", uint32(rb))
+}
+
+func (r *Recorder) DeleteRenderbuffer(rb gl.Renderbuffer) {
+	r.record("DeleteRenderbuffer", uint32(rb))
+}
+
+func (r *Recorder) RenderbufferStorageMultisample(target gl.GLenum, samples int, internalformat gl.GLenum, width, height int) {
+	r.record("RenderbufferStorageMultisample", Enum(target), samples, Enum(internalformat), width, height)
+}
+
+func (r *Recorder) BlitFramebuffer(srcX0, srcY0, srcX1, srcY1, dstX0, dstY0, dstX1, dstY1 int, mask gl.GLbitfield, filter gl.GLenum) {
+	r.record("BlitFramebuffer", srcX0, srcY0, srcX1, srcY1, dstX0, dstY0, dstX1, dstY1, uint32(mask), Enum(filter))
+}
+
+func (r *Recorder) DrawBuffers(bufs []gl.GLenum) {
+	enums := make([]Enum, len(bufs))
+	for i, b := range bufs {
+		enums[i] = Enum(b)
+	}
+	r.record("DrawBuffers", enums)
+}
+
+func (r *Recorder) DrawBuffer(mode gl.GLenum) {
+	r.record("DrawBuffer", Enum(mode))
+}
+
+func (r *Recorder) Uniform1i(loc gl.UniformLocation, v int) {
+	r.record("Uniform1i", r.uniformName(loc), v)
+}
+
+func (r *Recorder) Uniform1ui(loc gl.UniformLocation, v uint) {
+	r.record("Uniform1ui", r.uniformName(loc), v)
+}
+
+func (r *Recorder) Uniform1f(loc gl.UniformLocation, v float32) {
+	r.record("Uniform1f", r.uniformName(loc), v)
+}
+
+func (r *Recorder) Uniformiv(loc gl.UniformLocation, size, count int, v []int32) {
+	r.record(fmt.Sprintf("Uniform%div", size), r.uniformName(loc), count, append([]int32(nil), v...))
+}
+
+func (r *Recorder) Uniformuiv(loc gl.UniformLocation, size, count int, v []uint32) {
+	r.record(fmt.Sprintf("Uniform%duiv", size), r.uniformName(loc), count, append([]uint32(nil), v...))
+}
+
+func (r *Recorder) Uniformfv(loc gl.UniformLocation, size, count int, v []float32) {
+	r.record(fmt.Sprintf("Uniform%dfv", size), r.uniformName(loc), count, append([]float32(nil), v...))
+}
+
+func (r *Recorder) UniformMatrixfv(loc gl.UniformLocation, cols, rows, count int, transpose bool, v []float32) {
+	op := fmt.Sprintf("UniformMatrix%dx%dfv", cols, rows)
+	if cols == rows {
+		op = fmt.Sprintf("UniformMatrix%dfv", cols)
+	}
+	r.record(op, r.uniformName(loc), count, transpose, append([]float32(nil), v...))
+}
+
+func (r *Recorder) DrawArrays(mode gl.GLenum, first, count int) {
+	r.record("DrawArrays", Enum(mode), first, count)
+}
+
+func (r *Recorder) DrawElements(mode gl.GLenum, count int, typ gl.GLenum, indices interface{}) {
+	offset, _ := indices.(uintptr)
+	r.record("DrawElements", Enum(mode), count, Enum(typ), offset)
+}
+
+func (r *Recorder) DrawArraysInstanced(mode gl.GLenum, first, count, instances int) {
+	r.record("DrawArraysInstanced", Enum(mode), first, count, instances)
+}
+
+func (r *Recorder) DrawElementsInstanced(mode gl.GLenum, count int, typ gl.GLenum, indices interface{}, instances int) {
+	offset, _ := indices.(uintptr)
+	r.record("DrawElementsInstanced", Enum(mode), count, Enum(typ), offset, instances)
+}
+
+func (r *Recorder) MultiDrawArrays(mode gl.GLenum, first, count []int32) {
+	r.record("MultiDrawArrays", Enum(mode), append([]int32(nil), first...), append([]int32(nil), count...))
+}
+
+func (r *Recorder) MultiDrawElements(mode gl.GLenum, count []int32, typ gl.GLenum, indices []uintptr) {
+	r.record("MultiDrawElements", Enum(mode), append([]int32(nil), count...), Enum(typ), append([]uintptr(nil), indices...))
+}
+
+func (r *Recorder) PatchParameteri(pname gl.GLenum, value int) {
+	r.record("PatchParameteri", Enum(pname), value)
+}
+
+func (r *Recorder) PrimitiveRestartIndex(index uint32) {
+	r.record("PrimitiveRestartIndex", index)
+}
+
+func (r *Recorder) BeginTransformFeedback(mode gl.GLenum) {
+	r.record("BeginTransformFeedback", Enum(mode))
+}
+
+func (r *Recorder) EndTransformFeedback() {
+	r.record("EndTransformFeedback")
+}
+
+func (r *Recorder) Enable(cap gl.GLenum)  { r.record("Enable", Enum(cap)) }
+func (r *Recorder) Disable(cap gl.GLenum) { r.record("Disable", Enum(cap)) }
+
+func (r *Recorder) BlendFuncSeparate(srcRGB, dstRGB, srcAlpha, dstAlpha gl.GLenum) {
+	r.record("BlendFuncSeparate", Enum(srcRGB), Enum(dstRGB), Enum(srcAlpha), Enum(dstAlpha))
+}
+
+func (r *Recorder) BlendEquationSeparate(modeRGB, modeAlpha gl.GLenum) {
+	r.record("BlendEquationSeparate", Enum(modeRGB), Enum(modeAlpha))
+}
+
+func (r *Recorder) DepthFunc(f gl.GLenum)               { r.record("DepthFunc", Enum(f)) }
+func (r *Recorder) DepthMask(flag bool)                 { r.record("DepthMask", flag) }
+func (r *Recorder) CullFace(mode gl.GLenum)             { r.record("CullFace", Enum(mode)) }
+func (r *Recorder) PolygonOffset(factor, units float32) { r.record("PolygonOffset", factor, units) }
+func (r *Recorder) Scissor(x, y, width, height int)     { r.record("Scissor", x, y, width, height) }
+func (r *Recorder) ColorMask(red, green, blue, alpha bool) {
+	r.record("ColorMask", red, green, blue, alpha)
+}
+func (r *Recorder) Viewport(x, y, width, height int) { r.record("Viewport", x, y, width, height) }
+func (r *Recorder) Clear(mask gl.GLbitfield)         { r.record("Clear", uint32(mask)) }
+func (r *Recorder) ClearColor(red, green, blue, alpha float32) {
+	r.record("ClearColor", red, green, blue, alpha)
+}
+func (r *Recorder) ClearBufferuiv(buffer gl.GLenum, drawbuffer int, value []uint32) {
+	r.record("ClearBufferuiv", Enum(buffer), drawbuffer, append([]uint32(nil), value...))
+}
+func (r *Recorder) ClearBufferfv(buffer gl.GLenum, drawbuffer int, value []float32) {
+	r.record("ClearBufferfv", Enum(buffer), drawbuffer, append([]float32(nil), value...))
+}
+
+func (r *Recorder) GenQuery() gl.Query {
+	q := gl.Query(r.genName())
+	r.record("GenQuery", uint32(q))
+	return q
+}
+
+func (r *Recorder) BeginQuery(target gl.GLenum, q gl.Query) {
+	r.record("BeginQuery", Enum(target), uint32(q))
+}
+
+func (r *Recorder) EndQuery(target gl.GLenum) {
+	r.record("EndQuery", Enum(target))
+}
+
+func (r *Recorder) DeleteQuery(q gl.Query) {
+	r.record("DeleteQuery", uint32(q))
+}
+
+// GetQueryObjectuiv reports every result as available, and
+// GetQueryObjectui64v every result as zero.
+func (r *Recorder) GetQueryObjectuiv(q gl.Query, pname gl.GLenum, params []uint32) {
+	for i := range params {
+		params[i] = gl.TRUE
+	}
+}
+
+func (r *Recorder) GetQueryObjectui64v(q gl.Query, pname gl.GLenum, params []uint64) {
+	for i := range params {
+		params[i] = 0
+	}
+}
+
+func (r *Recorder) FenceSync(condition gl.GLenum, flags gl.GLbitfield) uintptr {
+	r.lastSync++
+	r.record("FenceSync", Enum(condition), uint32(flags))
+	return r.lastSync
+}
+
+func (r *Recorder) ClientWaitSync(sync uintptr, flags gl.GLbitfield, timeout uint64) gl.GLenum {
+	return gl.ALREADY_SIGNALED
+}
+
+func (r *Recorder) DeleteSync(sync uintptr) {
+	r.record("DeleteSync", sync)
+}
+
+func (r *Recorder) ObjectLabel(identifier gl.GLenum, name uint32, label string) {
+	r.record("ObjectLabel", Enum(identifier), name, label)
+}
+
+func (r *Recorder) PushDebugGroup(source gl.GLenum, id uint32, message string) {
+	r.record("PushDebugGroup", Enum(source), id, message)
+}
+
+func (r *Recorder) PopDebugGroup() { r.record("PopDebugGroup") }
+
+func (r *Recorder) GetError() gl.GLenum {
+	if len(r.Errors) == 0 {
+		return gl.NO_ERROR
+	}
+	code := r.Errors[0]
+	r.Errors = r.Errors[1:]
+	return gl.GLenum(code)
+}
+
+// GetString describes an OpenGL 3.3 context, or one of Version, which for
+// OpenGL ES has GLSL ES 3.00.
+func (r *Recorder) GetString(name gl.GLenum) string {
+	switch name {
+	case gl.VERSION:
+		if r.Version != "" {
+			return r.Version + " gfxtest"
+		}
+		return "3.3 gfxtest"
+	case gl.SHADING_LANGUAGE_VERSION:
+		if strings.HasPrefix(r.Version, "OpenGL ES") {
+			return "OpenGL ES GLSL ES 3.00 gfxtest"
+		}
+		return "3.30 gfxtest"
+	case gl.VENDOR, gl.RENDERER:
+		return "gfxtest"
+	}
+	return ""
+}
+
+func (r *Recorder) GetStringi(name gl.GLenum, index uint) string {
+	if name == gl.EXTENSIONS && int(index) < len(r.Extensions) {
+		return r.Extensions[index]
+	}
+	return ""
+}
+
+// GetIntegerv reports the number of Extensions and the minimum limits of
+// OpenGL 3.3, one program binary format if the Extensions include
+// GL_ARB_get_program_binary, and zero for anything else.
+func (r *Recorder) GetIntegerv(pname gl.GLenum, data []int32) {
+	for i := range data {
+		data[i] = 0
+	}
+	if len(data) == 0 {
+		return
+	}
+	switch pname {
+	case gl.NUM_EXTENSIONS:
+		data[0] = int32(len(r.Extensions))
+	case gl.MAX_TEXTURE_SIZE:
+		data[0] = 1024
+	case gl.MAX_COMBINED_TEXTURE_IMAGE_UNITS:
+		data[0] = 48
+	case gl.MAX_VERTEX_ATTRIBS:
+		data[0] = 16
+	case gl.NUM_PROGRAM_BINARY_FORMATS:
+		for _, ext := range r.Extensions {
+			if ext == "GL_ARB_get_program_binary" {
+				data[0] = 1
+			}
+		}
+	}
+}
+
+// GetFloatv reports a maximum anisotropy of 16 if the Extensions include
+// GL_EXT_texture_filter_anisotropic, and zero for anything else.
+func (r *Recorder) GetFloatv(pname gl.GLenum, data []float32) {
+	for i := range data {
+		data[i] = 0
+	}
+	if len(data) == 0 || pname != gl.MAX_TEXTURE_MAX_ANISOTROPY_EXT {
+		return
+	}
+	for _, ext := range r.Extensions {
+		if ext == "GL_EXT_texture_filter_anisotropic" {
+			data[0] = 16
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package gfxtest
+
+import (
+	"j4k.co/gfx/internal/gl"
+)
+
+// enumNames names the enumerants gfx uses. Bitfields, and values shared by
+// more than one name, print as numbers.
+var enumNames = map[Enum]string{
+	gl.ACTIVE_ATTRIBUTES:                "ACTIVE_ATTRIBUTES",
+	gl.ACTIVE_UNIFORMS:                  "ACTIVE_UNIFORMS",
+	gl.ALREADY_SIGNALED:                 "ALREADY_SIGNALED",
+	gl.ALWAYS:                           "ALWAYS",
+	gl.ARRAY_BUFFER:                     "ARRAY_BUFFER",
+	gl.BACK:                             "BACK",
+	gl.BLEND:                            "BLEND",
+	gl.BOOL:                             "BOOL",
+	gl.BOOL_VEC2:                        "BOOL_VEC2",
+	gl.BOOL_VEC3:                        "BOOL_VEC3",
+	gl.BOOL_VEC4:                        "BOOL_VEC4",
+	gl.BUFFER:                           "BUFFER",
+	gl.CLAMP_TO_EDGE:                    "CLAMP_TO_EDGE",
+	gl.COLOR:                            "COLOR",
+	gl.COLOR_ATTACHMENT0:                "COLOR_ATTACHMENT0",
+	gl.COMPARE_REF_TO_TEXTURE:           "COMPARE_REF_TO_TEXTURE",
+	gl.COMPILE_STATUS:                   "COMPILE_STATUS",
+	gl.COMPRESSED_RED_RGTC1:             "COMPRESSED_RED_RGTC1",
+	gl.COMPRESSED_RGBA_BPTC_UNORM:       "COMPRESSED_RGBA_BPTC_UNORM",
+	gl.COMPRESSED_RGBA_S3TC_DXT1_EXT:    "COMPRESSED_RGBA_S3TC_DXT1_EXT",
+	gl.COMPRESSED_RGBA_S3TC_DXT3_EXT:    "COMPRESSED_RGBA_S3TC_DXT3_EXT",
+	gl.COMPRESSED_RGBA_S3TC_DXT5_EXT:    "COMPRESSED_RGBA_S3TC_DXT5_EXT",
+	gl.COMPRESSED_RG_RGTC2:              "COMPRESSED_RG_RGTC2",
+	gl.CONDITION_SATISFIED:              "CONDITION_SATISFIED",
+	gl.CONTEXT_PROFILE_MASK:             "CONTEXT_PROFILE_MASK",
+	gl.CULL_FACE:                        "CULL_FACE",
+	gl.DEBUG_SOURCE_APPLICATION:         "DEBUG_SOURCE_APPLICATION",
+	gl.DEPTH:                            "DEPTH",
+	gl.DEPTH_ATTACHMENT:                 "DEPTH_ATTACHMENT",
+	gl.DEPTH_COMPONENT:                  "DEPTH_COMPONENT",
+	gl.DEPTH_COMPONENT24:                "DEPTH_COMPONENT24",
+	gl.DEPTH_TEST:                       "DEPTH_TEST",
+	gl.DOUBLE:                           "DOUBLE",
+	gl.DRAW_FRAMEBUFFER:                 "DRAW_FRAMEBUFFER",
+	gl.DST_ALPHA:                        "DST_ALPHA",
+	gl.DST_COLOR:                        "DST_COLOR",
+	gl.DYNAMIC_COPY:                     "DYNAMIC_COPY",
+	gl.DYNAMIC_DRAW:                     "DYNAMIC_DRAW",
+	gl.ELEMENT_ARRAY_BUFFER:             "ELEMENT_ARRAY_BUFFER",
+	gl.EQUAL:                            "EQUAL",
+	gl.EXTENSIONS:                       "EXTENSIONS",
+	gl.FLOAT:                            "FLOAT",
+	gl.FLOAT_MAT2:                       "FLOAT_MAT2",
+	gl.FLOAT_MAT2x3:                     "FLOAT_MAT2x3",
+	gl.FLOAT_MAT2x4:                     "FLOAT_MAT2x4",
+	gl.FLOAT_MAT3:                       "FLOAT_MAT3",
+	gl.FLOAT_MAT3x2:                     "FLOAT_MAT3x2",
+	gl.FLOAT_MAT3x4:                     "FLOAT_MAT3x4",
+	gl.FLOAT_MAT4:                       "FLOAT_MAT4",
+	gl.FLOAT_MAT4x2:                     "FLOAT_MAT4x2",
+	gl.FLOAT_MAT4x3:                     "FLOAT_MAT4x3",
+	gl.FLOAT_VEC2:                       "FLOAT_VEC2",
+	gl.FLOAT_VEC3:                       "FLOAT_VEC3",
+	gl.FLOAT_VEC4:                       "FLOAT_VEC4",
+	gl.FRAGMENT_SHADER:                  "FRAGMENT_SHADER",
+	gl.FRAMEBUFFER:                      "FRAMEBUFFER",
+	gl.FRAMEBUFFER_COMPLETE:             "FRAMEBUFFER_COMPLETE",
+	gl.FRAMEBUFFER_SRGB:                 "FRAMEBUFFER_SRGB",
+	gl.FRONT:                            "FRONT",
+	gl.FUNC_ADD:                         "FUNC_ADD",
+	gl.FUNC_REVERSE_SUBTRACT:            "FUNC_REVERSE_SUBTRACT",
+	gl.FUNC_SUBTRACT:                    "FUNC_SUBTRACT",
+	gl.GEQUAL:                           "GEQUAL",
+	gl.GREATER:                          "GREATER",
+	gl.HALF_FLOAT:                       "HALF_FLOAT",
+	gl.INT:                              "INT",
+	gl.INTERLEAVED_ATTRIBS:              "INTERLEAVED_ATTRIBS",
+	gl.INT_2_10_10_10_REV:               "INT_2_10_10_10_REV",
+	gl.INT_VEC2:                         "INT_VEC2",
+	gl.INT_VEC3:                         "INT_VEC3",
+	gl.INT_VEC4:                         "INT_VEC4",
+	gl.INVALID_ENUM:                     "INVALID_ENUM",
+	gl.INVALID_FRAMEBUFFER_OPERATION:    "INVALID_FRAMEBUFFER_OPERATION",
+	gl.INVALID_OPERATION:                "INVALID_OPERATION",
+	gl.INVALID_VALUE:                    "INVALID_VALUE",
+	gl.LEQUAL:                           "LEQUAL",
+	gl.LESS:                             "LESS",
+	gl.LINEAR:                           "LINEAR",
+	gl.LINEAR_MIPMAP_LINEAR:             "LINEAR_MIPMAP_LINEAR",
+	gl.LINE_STRIP:                       "LINE_STRIP",
+	gl.LINK_STATUS:                      "LINK_STATUS",
+	gl.LUMINANCE:                        "LUMINANCE",
+	gl.MAX:                              "MAX",
+	gl.MAX_COMBINED_TEXTURE_IMAGE_UNITS: "MAX_COMBINED_TEXTURE_IMAGE_UNITS",
+	gl.MAX_TEXTURE_MAX_ANISOTROPY_EXT:   "MAX_TEXTURE_MAX_ANISOTROPY_EXT",
+	gl.MAX_TEXTURE_SIZE:                 "MAX_TEXTURE_SIZE",
+	gl.MAX_VERTEX_ATTRIBS:               "MAX_VERTEX_ATTRIBS",
+	gl.MIN:                              "MIN",
+	gl.MIRRORED_REPEAT:                  "MIRRORED_REPEAT",
+	gl.NEAREST:                          "NEAREST",
+	gl.NEAREST_MIPMAP_NEAREST:           "NEAREST_MIPMAP_NEAREST",
+	gl.NEVER:                            "NEVER",
+	gl.NOTEQUAL:                         "NOTEQUAL",
+	gl.NUM_EXTENSIONS:                   "NUM_EXTENSIONS",
+	gl.NUM_PROGRAM_BINARY_FORMATS:       "NUM_PROGRAM_BINARY_FORMATS",
+	gl.ONE_MINUS_DST_ALPHA:              "ONE_MINUS_DST_ALPHA",
+	gl.ONE_MINUS_DST_COLOR:              "ONE_MINUS_DST_COLOR",
+	gl.ONE_MINUS_SRC_ALPHA:              "ONE_MINUS_SRC_ALPHA",
+	gl.ONE_MINUS_SRC_COLOR:              "ONE_MINUS_SRC_COLOR",
+	gl.OUT_OF_MEMORY:                    "OUT_OF_MEMORY",
+	gl.PACK_ALIGNMENT:                   "PACK_ALIGNMENT",
+	gl.PATCHES:                          "PATCHES",
+	gl.PATCH_VERTICES:                   "PATCH_VERTICES",
+	gl.PIXEL_PACK_BUFFER:                "PIXEL_PACK_BUFFER",
+	gl.PIXEL_UNPACK_BUFFER:              "PIXEL_UNPACK_BUFFER",
+	gl.POLYGON_OFFSET_FILL:              "POLYGON_OFFSET_FILL",
+	gl.PRIMITIVE_RESTART:                "PRIMITIVE_RESTART",
+	gl.PRIMITIVE_RESTART_FIXED_INDEX:    "PRIMITIVE_RESTART_FIXED_INDEX",
+	gl.PROGRAM:                          "PROGRAM",
+	gl.PROGRAM_BINARY_LENGTH:            "PROGRAM_BINARY_LENGTH",
+	gl.PROGRAM_BINARY_RETRIEVABLE_HINT:  "PROGRAM_BINARY_RETRIEVABLE_HINT",
+	gl.PROGRAM_POINT_SIZE:               "PROGRAM_POINT_SIZE",
+	gl.QUERY_RESULT:                     "QUERY_RESULT",
+	gl.QUERY_RESULT_AVAILABLE:           "QUERY_RESULT_AVAILABLE",
+	gl.R11F_G11F_B10F:                   "R11F_G11F_B10F",
+	gl.R8:                               "R8",
+	gl.RASTERIZER_DISCARD:               "RASTERIZER_DISCARD",
+	gl.READ_FRAMEBUFFER:                 "READ_FRAMEBUFFER",
+	gl.READ_ONLY:                        "READ_ONLY",
+	gl.RED:                              "RED",
+	gl.RENDERBUFFER:                     "RENDERBUFFER",
+	gl.RENDERER:                         "RENDERER",
+	gl.REPEAT:                           "REPEAT",
+	gl.RG32UI:                           "RG32UI",
+	gl.RGB:                              "RGB",
+	gl.RGBA:                             "RGBA",
+	gl.RGBA16F:                          "RGBA16F",
+	gl.RGBA32F:                          "RGBA32F",
+	gl.RGBA8:                            "RGBA8",
+	gl.RG_INTEGER:                       "RG_INTEGER",
+	gl.SAMPLER_2D:                       "SAMPLER_2D",
+	gl.SAMPLER_2D_ARRAY:                 "SAMPLER_2D_ARRAY",
+	gl.SAMPLER_2D_SHADOW:                "SAMPLER_2D_SHADOW",
+	gl.SAMPLER_3D:                       "SAMPLER_3D",
+	gl.SAMPLER_CUBE:                     "SAMPLER_CUBE",
+	gl.SCISSOR_TEST:                     "SCISSOR_TEST",
+	gl.SHADING_LANGUAGE_VERSION:         "SHADING_LANGUAGE_VERSION",
+	gl.SRC_ALPHA:                        "SRC_ALPHA",
+	gl.SRC_COLOR:                        "SRC_COLOR",
+	gl.SRGB8_ALPHA8:                     "SRGB8_ALPHA8",
+	gl.STATIC_COPY:                      "STATIC_COPY",
+	gl.STATIC_DRAW:                      "STATIC_DRAW",
+	gl.STREAM_COPY:                      "STREAM_COPY",
+	gl.STREAM_DRAW:                      "STREAM_DRAW",
+	gl.STREAM_READ:                      "STREAM_READ",
+	gl.SYNC_GPU_COMMANDS_COMPLETE:       "SYNC_GPU_COMMANDS_COMPLETE",
+	gl.TESS_CONTROL_SHADER:              "TESS_CONTROL_SHADER",
+	gl.TESS_EVALUATION_SHADER:           "TESS_EVALUATION_SHADER",
+	gl.TEXTURE:                          "TEXTURE",
+	gl.TEXTURE0:                         "TEXTURE0",
+	gl.TEXTURE_2D:                       "TEXTURE_2D",
+	gl.TEXTURE_COMPARE_FUNC:             "TEXTURE_COMPARE_FUNC",
+	gl.TEXTURE_COMPARE_MODE:             "TEXTURE_COMPARE_MODE",
+	gl.TEXTURE_CUBE_MAP:                 "TEXTURE_CUBE_MAP",
+	gl.TEXTURE_CUBE_MAP_NEGATIVE_X:      "TEXTURE_CUBE_MAP_NEGATIVE_X",
+	gl.TEXTURE_CUBE_MAP_NEGATIVE_Y:      "TEXTURE_CUBE_MAP_NEGATIVE_Y",
+	gl.TEXTURE_CUBE_MAP_NEGATIVE_Z:      "TEXTURE_CUBE_MAP_NEGATIVE_Z",
+	gl.TEXTURE_CUBE_MAP_POSITIVE_X:      "TEXTURE_CUBE_MAP_POSITIVE_X",
+	gl.TEXTURE_CUBE_MAP_POSITIVE_Y:      "TEXTURE_CUBE_MAP_POSITIVE_Y",
+	gl.TEXTURE_CUBE_MAP_POSITIVE_Z:      "TEXTURE_CUBE_MAP_POSITIVE_Z",
+	gl.TEXTURE_CUBE_MAP_SEAMLESS:        "TEXTURE_CUBE_MAP_SEAMLESS",
+	gl.TEXTURE_MAG_FILTER:               "TEXTURE_MAG_FILTER",
+	gl.TEXTURE_MAX_ANISOTROPY_EXT:       "TEXTURE_MAX_ANISOTROPY_EXT",
+	gl.TEXTURE_MAX_LEVEL:                "TEXTURE_MAX_LEVEL",
+	gl.TEXTURE_MIN_FILTER:               "TEXTURE_MIN_FILTER",
+	gl.TEXTURE_WRAP_R:                   "TEXTURE_WRAP_R",
+	gl.TEXTURE_WRAP_S:                   "TEXTURE_WRAP_S",
+	gl.TEXTURE_WRAP_T:                   "TEXTURE_WRAP_T",
+	gl.TIME_ELAPSED:                     "TIME_ELAPSED",
+	gl.TRANSFORM_FEEDBACK_BUFFER:        "TRANSFORM_FEEDBACK_BUFFER",
+	gl.TRIANGLES:                        "TRIANGLES",
+	gl.TRIANGLE_FAN:                     "TRIANGLE_FAN",
+	gl.TRIANGLE_STRIP:                   "TRIANGLE_STRIP",
+	gl.UNIFORM_BUFFER:                   "UNIFORM_BUFFER",
+	gl.UNPACK_ALIGNMENT:                 "UNPACK_ALIGNMENT",
+	gl.UNSIGNED_BYTE:                    "UNSIGNED_BYTE",
+	gl.UNSIGNED_INT:                     "UNSIGNED_INT",
+	gl.UNSIGNED_INT_VEC2:                "UNSIGNED_INT_VEC2",
+	gl.UNSIGNED_INT_VEC3:                "UNSIGNED_INT_VEC3",
+	gl.UNSIGNED_INT_VEC4:                "UNSIGNED_INT_VEC4",
+	gl.UNSIGNED_SHORT:                   "UNSIGNED_SHORT",
+	gl.VENDOR:                           "VENDOR",
+	gl.VERSION:                          "VERSION",
+	gl.VERTEX_ARRAY:                     "VERTEX_ARRAY",
+	gl.VERTEX_SHADER:                    "VERTEX_SHADER",
+	gl.WRITE_ONLY:                       "WRITE_ONLY",
+}
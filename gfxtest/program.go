@@ -0,0 +1,376 @@
+package gfxtest
+
+import (
+	"fmt"
+	"j4k.co/gfx/internal/gl"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type shaderObject struct {
+	typ    gl.GLenum
+	source string
+	log    string // info log of a failed compile
+}
+
+// program is a linked program, with the uniforms and attributes declared by
+// its sources treated as active.
+type program struct {
+	shaders  []*shaderObject
+	linked   bool
+	uniforms []variable
+	attribs  []variable
+	blocks   []string
+	bindings map[uint]uint // uniform block bindings by index
+}
+
+type variable struct {
+	name     string
+	typ      gl.GLenum
+	size     int
+	location int
+}
+
+var (
+	uniformRe = regexp.MustCompile(`(?m)^\s*(?:layout\s*\([^)]*\)\s*)?uniform\s+(?:(?:lowp|mediump|highp)\s+)?(\w+)\s+(\w+)\s*(?:\[\s*(\d+)\s*\])?\s*;`)
+	attribRe  = regexp.MustCompile(`(?m)^\s*(?:layout\s*\([^)]*\)\s*)?(?:in|attribute)\s+(?:(?:lowp|mediump|highp)\s+)?(\w+)\s+(\w+)\s*(?:\[\s*(\d+)\s*\])?\s*;`)
+	blockRe   = regexp.MustCompile(`(?m)^\s*(?:layout\s*\([^)]*\)\s*)?uniform\s+(\w+)\s*\{`)
+)
+
+// glslTypes maps the GLSL types that can be uniforms or attributes to their
+// enumerants.
+var glslTypes = map[string]gl.GLenum{
+	"float":           gl.FLOAT,
+	"vec2":            gl.FLOAT_VEC2,
+	"vec3":            gl.FLOAT_VEC3,
+	"vec4":            gl.FLOAT_VEC4,
+	"double":          gl.DOUBLE,
+	"int":             gl.INT,
+	"ivec2":           gl.INT_VEC2,
+	"ivec3":           gl.INT_VEC3,
+	"ivec4":           gl.INT_VEC4,
+	"uint":            gl.UNSIGNED_INT,
+	"uvec2":           gl.UNSIGNED_INT_VEC2,
+	"uvec3":           gl.UNSIGNED_INT_VEC3,
+	"uvec4":           gl.UNSIGNED_INT_VEC4,
+	"bool":            gl.BOOL,
+	"bvec2":           gl.BOOL_VEC2,
+	"bvec3":           gl.BOOL_VEC3,
+	"bvec4":           gl.BOOL_VEC4,
+	"mat2":            gl.FLOAT_MAT2,
+	"mat3":            gl.FLOAT_MAT3,
+	"mat4":            gl.FLOAT_MAT4,
+	"mat2x3":          gl.FLOAT_MAT2x3,
+	"mat2x4":          gl.FLOAT_MAT2x4,
+	"mat3x2":          gl.FLOAT_MAT3x2,
+	"mat3x4":          gl.FLOAT_MAT3x4,
+	"mat4x2":          gl.FLOAT_MAT4x2,
+	"mat4x3":          gl.FLOAT_MAT4x3,
+	"sampler2D":       gl.SAMPLER_2D,
+	"sampler3D":       gl.SAMPLER_3D,
+	"samplerCube":     gl.SAMPLER_CUBE,
+	"sampler2DShadow": gl.SAMPLER_2D_SHADOW,
+	"sampler2DArray":  gl.SAMPLER_2D_ARRAY,
+}
+
+// link collects the variables declared by the sources of p. Uniforms take
+// a location per array element, and attributes one per matrix column.
+func (p *program) link() {
+	p.uniforms, p.attribs, p.blocks = nil, nil, nil
+	seen := make(map[string]bool)
+	var uloc, aloc int
+	for _, s := range p.shaders {
+		for _, m := range uniformRe.FindAllStringSubmatch(s.source, -1) {
+			v, ok := declared(m)
+			if !ok || seen[v.name] {
+				continue
+			}
+			seen[v.name] = true
+			v.location = uloc
+			uloc += v.size
+			p.uniforms = append(p.uniforms, v)
+		}
+		for _, m := range blockRe.FindAllStringSubmatch(s.source, -1) {
+			p.blocks = append(p.blocks, m[1])
+		}
+		if s.typ != gl.VERTEX_SHADER {
+			continue
+		}
+		for _, m := range attribRe.FindAllStringSubmatch(s.source, -1) {
+			v, ok := declared(m)
+			if !ok {
+				continue
+			}
+			v.location = aloc
+			aloc += v.size * columns(m[1])
+			p.attribs = append(p.attribs, v)
+		}
+	}
+}
+
+// declared returns the variable of a declaration matched by uniformRe or
+// attribRe.
+func declared(m []string) (variable, bool) {
+	typ, ok := glslTypes[m[1]]
+	if !ok {
+		return variable{}, false
+	}
+	v := variable{name: m[2], typ: typ, size: 1}
+	if m[3] != "" {
+		v.size, _ = strconv.Atoi(m[3])
+	}
+	return v, true
+}
+
+// columns returns the number of columns of a GLSL matrix type, or 1.
+func columns(typ string) int {
+	if !strings.HasPrefix(typ, "mat") {
+		return 1
+	}
+	n, _ := strconv.Atoi(typ[3:4])
+	return n
+}
+
+// find returns the location of the named variable, which may index an
+// array, or -1.
+func find(vars []variable, name string) int {
+	index := 0
+	if i := strings.IndexByte(name, '['); i >= 0 && strings.HasSuffix(name, "]") {
+		n, err := strconv.Atoi(name[i+1 : len(name)-1])
+		if err != nil {
+			return -1
+		}
+		name, index = name[:i], n
+	}
+	for _, v := range vars {
+		if v.name == name && index < v.size {
+			return v.location + index
+		}
+	}
+	return -1
+}
+
+// active returns the size, type, and name of the i'th variable as
+// glGetActiveUniform and glGetActiveAttrib do.
+func active(vars []variable, i int) (int, gl.GLenum, string) {
+	if i < 0 || i >= len(vars) {
+		return 0, 0, ""
+	}
+	v := vars[i]
+	name := v.name
+	if v.size > 1 {
+		name += "[0]"
+	}
+	return v.size, v.typ, name
+}
+
+// uniformName returns the name of the uniform at loc in the current
+// program, as recorded in place of the location.
+func (r *Recorder) uniformName(loc gl.UniformLocation) string {
+	if r.current != nil {
+		for _, v := range r.current.uniforms {
+			if int(loc) >= v.location && int(loc) < v.location+v.size {
+				if v.size > 1 {
+					return v.name + "[" + strconv.Itoa(int(loc)-v.location) + "]"
+				}
+				return v.name
+			}
+		}
+	}
+	return "location " + strconv.Itoa(int(loc))
+}
+
+func (r *Recorder) CreateShader(typ gl.GLenum) gl.Shader {
+	s := gl.Shader(r.genName())
+	r.shaders[s] = &shaderObject{typ: typ}
+	r.record("CreateShader", Enum(typ), uint32(s))
+	return s
+}
+
+func (r *Recorder) ShaderSource(s gl.Shader, src string) {
+	if obj := r.shaders[s]; obj != nil {
+		obj.source = src
+	}
+	r.record("ShaderSource", uint32(s), src)
+}
+
+// CompileShader fails to compile a source with an #error directive, as GL
+// does, logging its message in the format of common drivers.
+func (r *Recorder) CompileShader(s gl.Shader) {
+	if obj := r.shaders[s]; obj != nil {
+		obj.log = ""
+		for i, line := range strings.Split(obj.source, "\n") {
+			if msg := strings.TrimSpace(line); strings.HasPrefix(msg, "#error") {
+				obj.log = fmt.Sprintf("0(%d) : error C0000: %s\n", i+1, strings.TrimSpace(msg[len("#error"):]))
+				break
+			}
+		}
+	}
+	r.record("CompileShader", uint32(s))
+}
+
+func (r *Recorder) GetShaderi(s gl.Shader, pname gl.GLenum) int {
+	switch pname {
+	case gl.COMPILE_STATUS:
+		if obj := r.shaders[s]; obj != nil && obj.log != "" {
+			return 0
+		}
+		return gl.TRUE
+	}
+	return 0
+}
+
+func (r *Recorder) GetShaderInfoLog(s gl.Shader) string {
+	if obj := r.shaders[s]; obj != nil {
+		return obj.log
+	}
+	return ""
+}
+
+func (r *Recorder) DeleteShader(s gl.Shader) {
+	delete(r.shaders, s)
+	r.record("DeleteShader", uint32(s))
+}
+
+func (r *Recorder) CreateProgram() gl.Program {
+	p := gl.Program(r.genName())
+	r.programs[p] = &program{bindings: make(map[uint]uint)}
+	r.record("CreateProgram", uint32(p))
+	return p
+}
+
+func (r *Recorder) AttachShader(p gl.Program, s gl.Shader) {
+	if prog, obj := r.programs[p], r.shaders[s]; prog != nil && obj != nil {
+		prog.shaders = append(prog.shaders, obj)
+	}
+	r.record("AttachShader", uint32(p), uint32(s))
+}
+
+func (r *Recorder) DetachShader(p gl.Program, s gl.Shader) {
+	if prog, obj := r.programs[p], r.shaders[s]; prog != nil && obj != nil {
+		for i, attached := range prog.shaders {
+			if attached == obj {
+				prog.shaders = append(prog.shaders[:i], prog.shaders[i+1:]...)
+				break
+			}
+		}
+	}
+	r.record("DetachShader", uint32(p), uint32(s))
+}
+
+func (r *Recorder) LinkProgram(p gl.Program) {
+	if prog := r.programs[p]; prog != nil {
+		prog.link()
+		prog.linked = true
+	}
+	r.record("LinkProgram", uint32(p))
+}
+
+func (r *Recorder) UseProgram(p gl.Program) {
+	r.current = r.programs[p]
+	r.record("UseProgram", uint32(p))
+}
+
+func (r *Recorder) DeleteProgram(p gl.Program) {
+	if r.current == r.programs[p] {
+		r.current = nil
+	}
+	delete(r.programs, p)
+	r.record("DeleteProgram", uint32(p))
+}
+
+func (r *Recorder) GetProgrami(p gl.Program, pname gl.GLenum) int {
+	prog := r.programs[p]
+	if prog == nil {
+		return 0
+	}
+	switch pname {
+	case gl.LINK_STATUS:
+		if prog.linked {
+			return gl.TRUE
+		}
+	case gl.ACTIVE_UNIFORMS:
+		return len(prog.uniforms)
+	case gl.ACTIVE_ATTRIBUTES:
+		return len(prog.attribs)
+	}
+	return 0
+}
+
+func (r *Recorder) GetProgramInfoLog(p gl.Program) string { return "" }
+
+func (r *Recorder) GetUniformLocation(p gl.Program, name string) gl.UniformLocation {
+	r.record("GetUniformLocation", uint32(p), name)
+	if prog := r.programs[p]; prog != nil {
+		return gl.UniformLocation(find(prog.uniforms, name))
+	}
+	return -1
+}
+
+func (r *Recorder) GetAttribLocation(p gl.Program, name string) gl.AttribLocation {
+	if prog := r.programs[p]; prog != nil {
+		return gl.AttribLocation(find(prog.attribs, name))
+	}
+	return -1
+}
+
+func (r *Recorder) BindFragDataLocation(p gl.Program, color int, name string) {
+	r.record("BindFragDataLocation", uint32(p), color, name)
+}
+
+func (r *Recorder) TransformFeedbackVaryings(p gl.Program, names []string, mode gl.GLenum) {
+	r.record("TransformFeedbackVaryings", uint32(p), append([]string(nil), names...), Enum(mode))
+}
+
+func (r *Recorder) GetActiveUniform(p gl.Program, index int) (int, gl.GLenum, string) {
+	if prog := r.programs[p]; prog != nil {
+		return active(prog.uniforms, index)
+	}
+	return 0, 0, ""
+}
+
+func (r *Recorder) GetActiveAttrib(p gl.Program, index int) (int, gl.GLenum, string) {
+	if prog := r.programs[p]; prog != nil {
+		return active(prog.attribs, index)
+	}
+	return 0, 0, ""
+}
+
+func (r *Recorder) GetUniformBlockIndex(p gl.Program, name string) uint {
+	if prog := r.programs[p]; prog != nil {
+		for i, block := range prog.blocks {
+			if block == name {
+				return uint(i)
+			}
+		}
+	}
+	return gl.INVALID_INDEX
+}
+
+func (r *Recorder) UniformBlockBinding(p gl.Program, index, binding uint) {
+	if prog := r.programs[p]; prog != nil {
+		prog.bindings[index] = binding
+	}
+	r.record("UniformBlockBinding", uint32(p), index, binding)
+}
+
+func (r *Recorder) ProgramParameteri(p gl.Program, pname gl.GLenum, value int) {
+	r.record("ProgramParameteri", uint32(p), Enum(pname), value)
+}
+
+// GetProgramBinary returns no binary, and programs loaded with
+// ProgramBinary fail to link, so that programs are always built from
+// source.
+func (r *Recorder) GetProgramBinary(p gl.Program, bufSize int, length *int, format *gl.GLenum, binary interface{}) {
+	if length != nil {
+		*length = 0
+	}
+}
+
+func (r *Recorder) ProgramBinary(p gl.Program, format gl.GLenum, binary interface{}, length int) {
+	if prog := r.programs[p]; prog != nil {
+		prog.linked = false
+	}
+	r.record("ProgramBinary", uint32(p), Enum(format), length)
+}
@@ -0,0 +1,191 @@
+/*
+Package gfxtest provides a fake GL backend for testing code built on gfx
+without a GPU.
+
+Install replaces the backend with a Recorder, which logs the buffer uploads,
+state changes, uniform assignments, and draw calls gfx makes, and simulates
+enough of GL for them to succeed: shaders compile unless they have an
+#error directive, the uniforms and attributes a program declares are active,
+buffers keep their contents, and framebuffers are complete. Nothing is
+rendered.
+
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+	// build shaders and geometry, and draw with gfx as usual
+	for _, call := range rec.Draws() {
+		t.Log(call)
+	}
+*/
+package gfxtest
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/internal/gl"
+	"strings"
+)
+
+// Call is a GL call made by gfx. Op is the name of the GL function without
+// its gl prefix, such as "DrawArrays". Enumerants among the arguments are
+// given as Enums, object names as uint32s, and buffer and texture data as
+// copies in []byte. Calls that set uniforms have the name of the uniform as
+// their first argument in place of its location.
+type Call struct {
+	Op   string
+	Args []interface{}
+}
+
+func (c Call) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		switch arg := arg.(type) {
+		case []byte:
+			args[i] = fmt.Sprintf("[%d bytes]", len(arg))
+		case string:
+			args[i] = fmt.Sprintf("%q", arg)
+		default:
+			args[i] = fmt.Sprint(arg)
+		}
+	}
+	return c.Op + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Enum is a GL enumerant, which prints as its name.
+type Enum uint32
+
+func (e Enum) String() string {
+	if name, ok := enumNames[e]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04X", uint32(e))
+}
+
+// Recorder is a GL backend that records the calls made to it.
+type Recorder struct {
+	// Calls holds every call that changed GL state, in order. Queries,
+	// such as glGetError, are left out, except for glGetUniformLocation,
+	// which is recorded to show when locations are looked up.
+	Calls []Call
+
+	// Extensions lists the extensions the context reports, none by
+	// default. Set it and call gfx.Init again to test code that depends on
+	// them.
+	Extensions []string
+
+	// Version is the version the context reports, such as "OpenGL ES 3.0",
+	// or OpenGL 3.3 if empty. Set it and call gfx.Init again to test code
+	// that depends on it.
+	Version string
+
+	// UnmapFailures is the number of following glUnmapBuffer calls that
+	// report the mapped memory was lost, as drivers may on a mode switch.
+	UnmapFailures int
+
+	// Errors are the GL errors glGetError reports, in order, such as
+	// Enum(0x0501) for GL_INVALID_VALUE. None are raised by default.
+	Errors []Enum
+
+	// Pixels are the bytes glReadPixels reads back, copied into the start
+	// of the memory it is given, for testing how gfx arranges them. Pixels
+	// are left as they are if it is nil.
+	Pixels []byte
+
+	prev     gl.Backend
+	lastName uint32
+	lastSync uintptr
+
+	buffers  map[gl.Buffer][]byte
+	bound    map[gl.GLenum]gl.Buffer // buffer bindings by target
+	mapped   map[gl.GLenum]mapping
+	shaders  map[gl.Shader]*shaderObject
+	programs map[gl.Program]*program
+	current  *program
+}
+
+// mapping is a buffer range mapped into memory.
+type mapping struct {
+	offset, length int
+	write          bool
+}
+
+// Install makes a new Recorder the backend of gfx and calls gfx.Init, so
+// that gfx sees the capabilities of an OpenGL 3.3 context. State gfx keeps
+// between calls, such as cached geometry layouts, is not reset.
+func Install() *Recorder {
+	r := &Recorder{
+		buffers:  make(map[gl.Buffer][]byte),
+		bound:    make(map[gl.GLenum]gl.Buffer),
+		mapped:   make(map[gl.GLenum]mapping),
+		shaders:  make(map[gl.Shader]*shaderObject),
+		programs: make(map[gl.Program]*program),
+	}
+	r.prev = gl.SetBackend(r)
+	gfx.Init()
+	r.Reset()
+	return r
+}
+
+// Uninstall restores the backend that was in place before Install.
+func (r *Recorder) Uninstall() {
+	gl.SetBackend(r.prev)
+}
+
+// Reset clears the recorded calls. GL objects and their state are kept.
+func (r *Recorder) Reset() {
+	r.Calls = nil
+}
+
+// Ops returns the recorded calls to the named GL functions, in order.
+func (r *Recorder) Ops(ops ...string) []Call {
+	var calls []Call
+	for _, c := range r.Calls {
+		for _, op := range ops {
+			if c.Op == op {
+				calls = append(calls, c)
+				break
+			}
+		}
+	}
+	return calls
+}
+
+// Draws returns the recorded draw calls.
+func (r *Recorder) Draws() []Call {
+	return r.Ops("DrawArrays", "DrawElements", "DrawArraysInstanced",
+		"DrawElementsInstanced", "MultiDrawArrays", "MultiDrawElements")
+}
+
+// Uploads returns the recorded calls that wrote buffer or texture data,
+// including the writes to mapped buffers, which are recorded as
+// UnmapBuffer calls with the data written.
+func (r *Recorder) Uploads() []Call {
+	var calls []Call
+	for _, c := range r.Ops("BufferData", "BufferSubData", "UnmapBuffer",
+		"TexImage2D", "TexSubImage2D", "CompressedTexImage2D") {
+		if c.Op != "UnmapBuffer" || len(c.Args) > 1 {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Uniform returns the last recorded call that set the named uniform, and
+// whether there was one.
+func (r *Recorder) Uniform(name string) (Call, bool) {
+	for i := len(r.Calls) - 1; i >= 0; i-- {
+		c := r.Calls[i]
+		if strings.HasPrefix(c.Op, "Uniform") && len(c.Args) > 0 && c.Args[0] == name {
+			return c, true
+		}
+	}
+	return Call{}, false
+}
+
+func (r *Recorder) record(op string, args ...interface{}) {
+	r.Calls = append(r.Calls, Call{Op: op, Args: args})
+}
+
+func (r *Recorder) genName() uint32 {
+	r.lastName++
+	return r.lastName
+}
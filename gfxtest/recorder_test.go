@@ -0,0 +1,83 @@
+package gfxtest_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"reflect"
+	"testing"
+)
+
+const tintShader gfx.FragmentShader = `
+uniform vec4 Tint;
+
+varying vec2 TexCoord;
+
+void main() {
+	gl_FragColor = Tint;
+}`
+
+func TestRecordDraw(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	if !s.HasUniform("Tint") {
+		t.Fatal("Tint is not an active uniform")
+	}
+	rec.Reset()
+	s.Use()
+	if err := s.SetUniform("Tint", [4]float32{1, 0.5, 0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gfx.DrawFullscreen(s); err != nil {
+		t.Fatal(err)
+	}
+
+	call, ok := rec.Uniform("Tint")
+	if !ok {
+		t.Fatal("Tint was not set")
+	}
+	want := gfxtest.Call{Op: "Uniform4fv", Args: []interface{}{"Tint", 1, []float32{1, 0.5, 0, 1}}}
+	if !reflect.DeepEqual(call, want) {
+		t.Errorf("got %v, want %v", call, want)
+	}
+	draws := rec.Draws()
+	if len(draws) != 1 || draws[0].String() != "DrawArrays(TRIANGLES, 0, 3)" {
+		t.Errorf("got draws %v, want one DrawArrays(TRIANGLES, 0, 3)", draws)
+	}
+	uploads := rec.Uploads()
+	if len(uploads) == 0 {
+		t.Fatal("the fullscreen triangle was not uploaded")
+	}
+	last := uploads[len(uploads)-1]
+	if data, _ := last.Args[len(last.Args)-1].([]byte); len(data) != 9*4 {
+		t.Errorf("got %d bytes of vertices, want %d", len(data), 9*4)
+	}
+}
+
+func TestInstallNested(t *testing.T) {
+	outer := gfxtest.Install()
+	defer outer.Uninstall()
+
+	inner := gfxtest.Install()
+	outer.Reset()
+	tex, err := gfx.NewSampler2D(4, 4, gfx.PixelRGBA8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner.Uninstall()
+	// calls after Uninstall go to the backend installed before
+	tex.Delete()
+
+	if got := len(inner.Ops("GenTexture", "TexImage2D")); got != 2 {
+		t.Errorf("inner recorder got %v, want the texture created", inner.Calls)
+	}
+	if got := fmt.Sprint(outer.Calls); got != "[DeleteTexture(1)]" {
+		t.Errorf("outer recorder got %v, want the texture deleted", got)
+	}
+}
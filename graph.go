@@ -0,0 +1,555 @@
+package gfx
+
+import (
+	"errors"
+	"fmt"
+	"j4k.co/gfx/internal/gl"
+)
+
+// RenderTarget is a handle to a texture declared on a RenderGraph.
+type RenderTarget int
+
+// TargetDesc describes a transient render target.
+type TargetDesc struct {
+	Width  int
+	Height int
+	Format PixelFormat
+}
+
+type graphTarget struct {
+	name     string
+	desc     TargetDesc
+	imported *Sampler2D
+	screen   bool
+	tex      *Sampler2D // assigned at compile time
+	first    int
+	last     int
+}
+
+// RenderPass is a node in a RenderGraph. Passes declare which targets they
+// read and write, and the graph uses those declarations to order passes,
+// cull unused ones, and allocate transient textures.
+type RenderPass struct {
+	name   string
+	reads  []RenderTarget
+	writes []RenderTarget
+	fn     func(*PassContext)
+	keep   bool
+	order  int
+	state  State
+}
+
+// Read declares that the pass samples from t.
+func (p *RenderPass) Read(t RenderTarget) *RenderPass {
+	p.reads = append(p.reads, t)
+	return p
+}
+
+// Write declares that the pass renders into t. A pass may write several
+// color targets and one depth target, which together form its framebuffer.
+func (p *RenderPass) Write(t RenderTarget) *RenderPass {
+	p.writes = append(p.writes, t)
+	return p
+}
+
+// Keep marks the pass as having side effects so it is never culled.
+func (p *RenderPass) Keep() *RenderPass {
+	p.keep = true
+	return p
+}
+
+// SetState sets the State applied before the pass runs. Passes otherwise
+// start from the zero State, whatever the pass before them left applied.
+func (p *RenderPass) SetState(s State) *RenderPass {
+	p.state = s
+	return p
+}
+
+// PassContext is handed to a pass while it executes.
+type PassContext struct {
+	graph *RenderGraph
+	pass  *RenderPass
+	fb    *Framebuffer
+	err   error
+}
+
+// Name returns the name of the executing pass.
+func (c *PassContext) Name() string {
+	return c.pass.name
+}
+
+// Texture returns the texture backing t. Targets a pass did not declare with
+// Read or Write yield nil.
+func (c *PassContext) Texture(t RenderTarget) *Sampler2D {
+	for _, r := range c.pass.reads {
+		if r == t {
+			return c.graph.targets[t].tex
+		}
+	}
+	for _, w := range c.pass.writes {
+		if w == t {
+			return c.graph.targets[t].tex
+		}
+	}
+	return nil
+}
+
+// Bind binds the framebuffer of the pass's written targets again and sets
+// the viewport to match it, such as after drawing into other framebuffers.
+func (c *PassContext) Bind() {
+	if c.fb != nil {
+		c.fb.Bind()
+		return
+	}
+	for _, t := range c.pass.writes {
+		if tgt := c.graph.targets[t]; tgt.screen {
+			BindScreen(tgt.desc.Width, tgt.desc.Height)
+			return
+		}
+	}
+	gl.Framebuffer(0).Bind()
+}
+
+// Fail stops the graph after the pass, with Execute returning err.
+func (c *PassContext) Fail(err error) {
+	c.err = err
+}
+
+// RenderGraph schedules render passes that declare the targets they read and
+// write. Transient targets are allocated when the graph is compiled, and
+// targets whose lifetimes do not overlap share the same texture.
+//
+// Between passes, the graph makes the transitions their declarations call
+// for: each pass starts from its State, the textures it writes are unbound
+// from texture units so that no draw samples what it renders into, and
+// written textures with mipmaps have them regenerated for later readers.
+//
+// A graph is typically rebuilt every frame with Reset followed by the same
+// Transient/Import/AddPass calls; allocated textures and framebuffers are
+// pooled across Reset calls. ShadowMap, GBuffer and PostChain add their
+// passes to a graph with AddPass and AddPasses methods.
+type RenderGraph struct {
+	passes   []*RenderPass
+	targets  []*graphTarget
+	schedule []*RenderPass
+	compiled bool
+
+	pool []*pooledTexture
+	fbos map[fbKey]*Framebuffer
+
+	// adopted are the framebuffers of subsystems whose passes write their
+	// own textures, used instead of creating others. They are not deleted.
+	adopted map[fbKey]*Framebuffer
+}
+
+// maxPassColors is the number of color targets a pass may write, the
+// draw buffers of the most capable contexts.
+const maxPassColors = 8
+
+// fbKey identifies the framebuffer made of the textures a pass writes.
+type fbKey struct {
+	color [maxPassColors]*Sampler2D
+	depth *Sampler2D
+}
+
+type pooledTexture struct {
+	tex  *Sampler2D
+	desc TargetDesc
+	busy int // index of last scheduled use of the current occupant
+}
+
+var ErrGraphCycle = errors.New("gfx: render graph has a cycle")
+
+// NewRenderGraph returns an empty render graph.
+func NewRenderGraph() *RenderGraph {
+	return &RenderGraph{
+		fbos:    make(map[fbKey]*Framebuffer),
+		adopted: make(map[fbKey]*Framebuffer),
+	}
+}
+
+// Reset removes all passes and targets while keeping pooled resources for
+// reuse.
+func (g *RenderGraph) Reset() {
+	g.passes = g.passes[:0]
+	g.targets = g.targets[:0]
+	g.schedule = g.schedule[:0]
+	g.compiled = false
+	for k := range g.adopted {
+		delete(g.adopted, k)
+	}
+}
+
+// Delete frees all pooled textures and framebuffers.
+func (g *RenderGraph) Delete() {
+	for _, fb := range g.fbos {
+		fb.Delete()
+	}
+	for _, p := range g.pool {
+		p.tex.Delete()
+	}
+	g.fbos = make(map[fbKey]*Framebuffer)
+	g.pool = nil
+	g.Reset()
+}
+
+// Transient declares a target that only lives for the duration of the graph.
+func (g *RenderGraph) Transient(name string, desc TargetDesc) RenderTarget {
+	g.targets = append(g.targets, &graphTarget{name: name, desc: desc})
+	g.compiled = false
+	return RenderTarget(len(g.targets) - 1)
+}
+
+// Import declares a target backed by an existing texture. Passes writing to
+// imported targets are never culled.
+func (g *RenderGraph) Import(name string, tex *Sampler2D) RenderTarget {
+	w, h := tex.Size()
+	g.targets = append(g.targets, &graphTarget{
+		name:     name,
+		desc:     TargetDesc{Width: w, Height: h, Format: tex.Format()},
+		imported: tex,
+		tex:      tex,
+	})
+	g.compiled = false
+	return RenderTarget(len(g.targets) - 1)
+}
+
+// Screen declares the default framebuffer as a target of the given size.
+// Passes writing to it are never culled.
+func (g *RenderGraph) Screen(width, height int) RenderTarget {
+	g.targets = append(g.targets, &graphTarget{
+		name:   "screen",
+		desc:   TargetDesc{Width: width, Height: height},
+		screen: true,
+	})
+	g.compiled = false
+	return RenderTarget(len(g.targets) - 1)
+}
+
+// adopt imports the textures of fb and has passes writing all of them
+// render through fb. It returns their targets, colors first.
+func (g *RenderGraph) adopt(name string, fb *Framebuffer) []RenderTarget {
+	var key fbKey
+	var ts []RenderTarget
+	for i, tex := range fb.color {
+		key.color[i] = tex
+		ts = append(ts, g.Import(fmt.Sprintf("%s %d", name, i), tex))
+	}
+	if fb.depth != nil {
+		key.depth = fb.depth
+		ts = append(ts, g.Import(name+" depth", fb.depth))
+	}
+	g.adopted[key] = fb
+	return ts
+}
+
+// AddPass adds a pass that runs fn when the graph executes.
+func (g *RenderGraph) AddPass(name string, fn func(*PassContext)) *RenderPass {
+	p := &RenderPass{name: name, fn: fn, order: len(g.passes)}
+	g.passes = append(g.passes, p)
+	g.compiled = false
+	return p
+}
+
+// Compile orders the passes, culls passes that contribute nothing to an
+// imported or screen target, and assigns textures to transient targets.
+func (g *RenderGraph) Compile() error {
+	for _, p := range g.passes {
+		for _, t := range p.uses() {
+			if t < 0 || int(t) >= len(g.targets) {
+				return fmt.Errorf("gfx: pass %q uses an undeclared target", p.name)
+			}
+		}
+		for _, t := range p.writes {
+			if g.targets[t].screen && len(p.writes) > 1 {
+				return fmt.Errorf("gfx: pass %q writes the screen and other targets", p.name)
+			}
+		}
+	}
+	live := g.cull()
+	sched, err := g.sort(live)
+	if err != nil {
+		return err
+	}
+	g.schedule = sched
+	if err := g.allocate(); err != nil {
+		return err
+	}
+	g.compiled = true
+	return nil
+}
+
+// cull walks backwards from passes with side effects and marks every pass
+// that produces something they read.
+func (g *RenderGraph) cull() []bool {
+	live := make([]bool, len(g.passes))
+	var stack []*RenderPass
+	for _, p := range g.passes {
+		if p.keep || g.writesExternal(p) {
+			live[p.order] = true
+			stack = append(stack, p)
+		}
+	}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, dep := range g.deps(p) {
+			if !live[dep.order] {
+				live[dep.order] = true
+				stack = append(stack, dep)
+			}
+		}
+	}
+	return live
+}
+
+func (g *RenderGraph) writesExternal(p *RenderPass) bool {
+	for _, t := range p.writes {
+		tgt := g.targets[t]
+		if tgt.screen || tgt.imported != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// deps returns the passes p must run after. A pass depends on writers of
+// the targets it reads, and on earlier writers and readers of previous
+// contents of the targets it writes. Reads of a target with no earlier writer depend on all of its
+// writers, which lets passes be declared out of order.
+func (g *RenderGraph) deps(p *RenderPass) []*RenderPass {
+	var deps []*RenderPass
+	for _, t := range p.reads {
+		var earlier, all []*RenderPass
+		for _, q := range g.passes {
+			if q != p && q.writesTo(t) {
+				all = append(all, q)
+				if q.order < p.order {
+					earlier = append(earlier, q)
+				}
+			}
+		}
+		if len(earlier) > 0 {
+			deps = append(deps, earlier...)
+		} else {
+			deps = append(deps, all...)
+		}
+	}
+	for _, t := range p.writes {
+		for _, q := range g.passes[:p.order] {
+			if q.writesTo(t) || q.readsFrom(t) && g.writtenBefore(t, q.order) {
+				deps = append(deps, q)
+			}
+		}
+	}
+	return deps
+}
+
+// writtenBefore reports whether a pass declared before order writes t.
+func (g *RenderGraph) writtenBefore(t RenderTarget, order int) bool {
+	for _, q := range g.passes[:order] {
+		if q.writesTo(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// uses returns every target the pass reads or writes.
+func (p *RenderPass) uses() []RenderTarget {
+	ts := make([]RenderTarget, 0, len(p.reads)+len(p.writes))
+	ts = append(ts, p.reads...)
+	return append(ts, p.writes...)
+}
+
+func (p *RenderPass) writesTo(t RenderTarget) bool {
+	for _, w := range p.writes {
+		if w == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RenderPass) readsFrom(t RenderTarget) bool {
+	for _, r := range p.reads {
+		if r == t {
+			return true
+		}
+	}
+	return false
+}
+
+// sort topologically sorts live passes, preferring declaration order.
+func (g *RenderGraph) sort(live []bool) ([]*RenderPass, error) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(g.passes))
+	sched := make([]*RenderPass, 0, len(g.passes))
+	var visit func(p *RenderPass) error
+	visit = func(p *RenderPass) error {
+		switch state[p.order] {
+		case visiting:
+			return ErrGraphCycle
+		case done:
+			return nil
+		}
+		state[p.order] = visiting
+		for _, dep := range g.deps(p) {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[p.order] = done
+		sched = append(sched, p)
+		return nil
+	}
+	for _, p := range g.passes {
+		if !live[p.order] {
+			continue
+		}
+		if err := visit(p); err != nil {
+			return nil, err
+		}
+	}
+	return sched, nil
+}
+
+// allocate computes the lifetime of each transient target within the
+// schedule and assigns pooled textures, aliasing targets with matching
+// descriptions whose lifetimes do not overlap.
+func (g *RenderGraph) allocate() error {
+	for _, t := range g.targets {
+		t.first, t.last = -1, -1
+		if t.imported == nil && !t.screen {
+			t.tex = nil
+		}
+	}
+	for i, p := range g.schedule {
+		for _, t := range p.uses() {
+			tgt := g.targets[t]
+			if tgt.first < 0 {
+				tgt.first = i
+			}
+			tgt.last = i
+		}
+	}
+	for _, p := range g.pool {
+		p.busy = -1
+	}
+	for i := range g.schedule {
+		for _, t := range g.targets {
+			if t.first != i || t.imported != nil || t.screen {
+				continue
+			}
+			tex, err := g.acquire(t.desc, t.first, t.last)
+			if err != nil {
+				return fmt.Errorf("gfx: allocating target %q: %v", t.name, err)
+			}
+			t.tex = tex
+		}
+	}
+	return nil
+}
+
+func (g *RenderGraph) acquire(desc TargetDesc, first, last int) (*Sampler2D, error) {
+	for _, p := range g.pool {
+		if p.desc == desc && p.busy < first {
+			p.busy = last
+			return p.tex, nil
+		}
+	}
+	tex, err := NewSampler2D(desc.Width, desc.Height, desc.Format)
+	if err != nil {
+		return nil, err
+	}
+	g.pool = append(g.pool, &pooledTexture{tex: tex, desc: desc, busy: last})
+	return tex, nil
+}
+
+// Execute runs the scheduled passes, compiling the graph first if needed.
+// Before each pass, the framebuffer made of its written targets is bound,
+// the viewport set to match it, and the pass's State applied. Execute stops
+// at the first pass that fails.
+func (g *RenderGraph) Execute() error {
+	if !g.compiled {
+		if err := g.Compile(); err != nil {
+			return err
+		}
+	}
+	for _, p := range g.schedule {
+		c := &PassContext{graph: g, pass: p}
+		fb, err := g.framebuffer(p)
+		if err != nil {
+			return err
+		}
+		c.fb = fb
+		for _, t := range p.writes {
+			if tex := g.targets[t].tex; tex != nil {
+				unbindTexture(tex.tex)
+			}
+		}
+		c.Bind()
+		p.state.Apply()
+		p.fn(c)
+		if c.err != nil {
+			return fmt.Errorf("gfx: pass %q: %v", p.name, c.err)
+		}
+		for _, t := range p.writes {
+			if tex := g.targets[t].tex; tex != nil && tex.mipmaps {
+				tex.GenerateMipmaps()
+			}
+		}
+	}
+	return nil
+}
+
+// framebuffer returns the framebuffer made of the textures p writes, or nil
+// if it writes the screen or nothing.
+func (g *RenderGraph) framebuffer(p *RenderPass) (*Framebuffer, error) {
+	var key fbKey
+	ncolor := 0
+	for _, t := range p.writes {
+		tgt := g.targets[t]
+		if tgt.screen {
+			return nil, nil
+		}
+		if tgt.tex == nil {
+			return nil, fmt.Errorf("gfx: target %q could not be allocated", tgt.name)
+		}
+		switch {
+		case tgt.tex.format.IsDepth():
+			if key.depth != nil {
+				return nil, fmt.Errorf("gfx: pass %q writes more than one depth target", p.name)
+			}
+			key.depth = tgt.tex
+		case ncolor == maxPassColors:
+			return nil, fmt.Errorf("gfx: pass %q writes more than %d color targets", p.name, maxPassColors)
+		default:
+			key.color[ncolor] = tgt.tex
+			ncolor++
+		}
+	}
+	if key == (fbKey{}) {
+		return nil, nil
+	}
+	if fb, ok := g.adopted[key]; ok {
+		return fb, nil
+	}
+	if fb, ok := g.fbos[key]; ok {
+		return fb, nil
+	}
+	texs := append([]*Sampler2D(nil), key.color[:ncolor]...)
+	if key.depth != nil {
+		texs = append(texs, key.depth)
+	}
+	fb, err := NewFramebuffer(texs...)
+	if err != nil {
+		return nil, err
+	}
+	g.fbos[key] = fb
+	return fb, nil
+}
@@ -0,0 +1,91 @@
+package gfx_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"strings"
+	"testing"
+)
+
+func TestRenderGraph(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	shadow, err := gfx.NewShadowMap(128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shadow.Delete()
+	gbuf, err := gfx.NewGBuffer(64, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gbuf.Delete()
+	post, err := gfx.NewPostChain(64, 32, gfx.PixelRGBA8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer post.Delete()
+	light, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, gfx.DirectionalLightShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer light.Delete()
+
+	g := gfx.NewRenderGraph()
+	defer g.Delete()
+	screen := g.Screen(64, 32)
+	_, shadowDepth := shadow.AddPass(g, func(*gfx.PassContext) {})
+	_, gb := gbuf.AddPass(g, func(*gfx.PassContext) {})
+	lit := g.Transient("lit", gfx.TargetDesc{Width: 64, Height: 32, Format: gfx.PixelRGBA8})
+	gbuf.AddResolvePass(g, gb, lit, func(*gfx.PassContext) error {
+		return gbuf.Resolve(light, nil)
+	}).Read(shadowDepth)
+	scene, _ := post.AddPasses(g, screen, func(*gfx.PassContext) {})
+	scene.Read(lit)
+
+	rec.Reset()
+	if err := g.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if gens := rec.Ops("GenFramebuffer"); len(gens) != 1 {
+		t.Errorf("got %v, want a framebuffer for the transient target alone", gens)
+	}
+	if n := len(rec.Draws()); n != 2 {
+		t.Errorf("got %d draws, want the resolve and the copy to the screen", n)
+	}
+	rec.Reset()
+	if err := g.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, c := range rec.Ops("BindTexture") {
+		found = found || c.String() == "BindTexture(TEXTURE_2D, 0)"
+	}
+	if !found {
+		t.Error("the G-buffer read by the last frame was not unbound before drawing into it")
+	}
+
+	// passes start from their State, and a failing pass stops the graph
+	g.Reset()
+	screen = g.Screen(64, 32)
+	g.AddPass("fail", func(c *gfx.PassContext) {
+		c.Fail(fmt.Errorf("boom"))
+	}).Write(screen).SetState(gfx.State{NoColorWrite: gfx.ColorRed})
+	rec.Reset()
+	if err := g.Execute(); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("got error %v, want the pass's", err)
+	}
+	if masks := rec.Ops("ColorMask"); len(masks) != 1 || masks[0].String() != "ColorMask(false, true, true, true)" {
+		t.Errorf("got %v, want the pass's State applied", masks)
+	}
+
+	g.Reset()
+	screen = g.Screen(64, 32)
+	color := g.Transient("color", gfx.TargetDesc{Width: 64, Height: 32, Format: gfx.PixelRGBA8})
+	g.AddPass("mixed", func(*gfx.PassContext) {}).Write(color).Write(screen)
+	if err := g.Compile(); err == nil {
+		t.Error("no error writing the screen and another target")
+	}
+}
@@ -0,0 +1,70 @@
+//go:build darwin && cgo
+// +build darwin,cgo
+
+package headless
+
+/*
+#cgo CFLAGS: -DGL_SILENCE_DEPRECATION
+#cgo LDFLAGS: -framework OpenGL
+#include <OpenGL/OpenGL.h>
+
+static CGLError createContext(CGLContextObj *ctx) {
+	CGLPixelFormatAttribute attribs[] = {
+		kCGLPFAOpenGLProfile, (CGLPixelFormatAttribute)kCGLOGLPVersion_3_2_Core,
+		kCGLPFAColorSize, (CGLPixelFormatAttribute)24,
+		kCGLPFAAlphaSize, (CGLPixelFormatAttribute)8,
+		kCGLPFADepthSize, (CGLPixelFormatAttribute)24,
+		kCGLPFAAllowOfflineRenderers,
+		(CGLPixelFormatAttribute)0,
+	};
+	CGLPixelFormatObj pix;
+	GLint n;
+	CGLError err = CGLChoosePixelFormat(attribs, &pix, &n);
+	if (err != kCGLNoError) {
+		return err;
+	}
+	if (pix == NULL) {
+		return kCGLBadPixelFormat;
+	}
+	err = CGLCreateContext(pix, NULL, ctx);
+	CGLReleasePixelFormat(pix);
+	return err;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+)
+
+// nativeContext is a CGL context without a drawable; everything is rendered
+// into the framebuffer object.
+type nativeContext struct {
+	context C.CGLContextObj
+}
+
+func newNative(width, height int) (*nativeContext, error) {
+	c := &nativeContext{}
+	if err := C.createContext(&c.context); err != C.kCGLNoError {
+		return nil, cglError("create context", err)
+	}
+	return c, nil
+}
+
+func (c *nativeContext) makeCurrent() error {
+	if err := C.CGLSetCurrentContext(c.context); err != C.kCGLNoError {
+		return cglError("make current", err)
+	}
+	return nil
+}
+
+func (c *nativeContext) destroy() {
+	if C.CGLGetCurrentContext() == c.context {
+		C.CGLSetCurrentContext(nil)
+	}
+	C.CGLDestroyContext(c.context)
+}
+
+func cglError(op string, err C.CGLError) error {
+	return fmt.Errorf("headless: cannot %s: %s", op, C.GoString(C.CGLErrorString(err)))
+}
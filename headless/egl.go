@@ -0,0 +1,106 @@
+//go:build linux && cgo
+// +build linux,cgo
+
+package headless
+
+/*
+#cgo LDFLAGS: -lEGL
+#include <string.h>
+#include <EGL/egl.h>
+#include <EGL/eglext.h>
+
+// display returns the surfaceless display when Mesa provides one, which
+// needs no X or Wayland server, and the default display otherwise.
+static EGLDisplay display(void) {
+	const char *exts = eglQueryString(EGL_NO_DISPLAY, EGL_EXTENSIONS);
+	PFNEGLGETPLATFORMDISPLAYEXTPROC getPlatformDisplay =
+		(PFNEGLGETPLATFORMDISPLAYEXTPROC)eglGetProcAddress("eglGetPlatformDisplayEXT");
+	if (exts && strstr(exts, "EGL_MESA_platform_surfaceless") && getPlatformDisplay) {
+		return getPlatformDisplay(EGL_PLATFORM_SURFACELESS_MESA, EGL_DEFAULT_DISPLAY, NULL);
+	}
+	return eglGetDisplay(EGL_DEFAULT_DISPLAY);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+)
+
+// nativeContext is an EGL context and, where the driver supports them, a
+// pbuffer surface.
+type nativeContext struct {
+	display C.EGLDisplay // a uintptr to cgo
+	context C.EGLContext
+	surface C.EGLSurface
+}
+
+func newNative(width, height int) (*nativeContext, error) {
+	c := &nativeContext{display: C.display()}
+	if c.display == 0 {
+		return nil, eglError("get display")
+	}
+	if C.eglInitialize(c.display, nil, nil) == C.EGL_FALSE {
+		return nil, eglError("initialize")
+	}
+	// desktop GL where the driver has it, OpenGL ES 3 otherwise
+	renderable, api := C.EGLint(C.EGL_OPENGL_BIT), C.EGLenum(C.EGL_OPENGL_API)
+	var ctxAttribs []C.EGLint
+	if C.eglBindAPI(api) == C.EGL_FALSE {
+		renderable, api = C.EGL_OPENGL_ES3_BIT, C.EGL_OPENGL_ES_API
+		ctxAttribs = []C.EGLint{C.EGL_CONTEXT_CLIENT_VERSION, 3}
+		if C.eglBindAPI(api) == C.EGL_FALSE {
+			c.destroy()
+			return nil, eglError("bind API")
+		}
+	}
+	ctxAttribs = append(ctxAttribs, C.EGL_NONE)
+	attribs := []C.EGLint{
+		C.EGL_SURFACE_TYPE, C.EGL_PBUFFER_BIT,
+		C.EGL_RENDERABLE_TYPE, renderable,
+		C.EGL_RED_SIZE, 8,
+		C.EGL_GREEN_SIZE, 8,
+		C.EGL_BLUE_SIZE, 8,
+		C.EGL_ALPHA_SIZE, 8,
+		C.EGL_DEPTH_SIZE, 24,
+		C.EGL_NONE,
+	}
+	var config C.EGLConfig
+	var n C.EGLint
+	if C.eglChooseConfig(c.display, &attribs[0], &config, 1, &n) == C.EGL_FALSE || n == 0 {
+		c.destroy()
+		return nil, eglError("choose config")
+	}
+	c.context = C.eglCreateContext(c.display, config, nil, &ctxAttribs[0])
+	if c.context == nil {
+		c.destroy()
+		return nil, eglError("create context")
+	}
+	// rendering goes to a framebuffer object, so drivers that cannot make
+	// a pbuffer may still manage without a surface
+	surfAttribs := []C.EGLint{C.EGL_WIDTH, C.EGLint(width), C.EGL_HEIGHT, C.EGLint(height), C.EGL_NONE}
+	c.surface = C.eglCreatePbufferSurface(c.display, config, &surfAttribs[0])
+	return c, nil
+}
+
+func (c *nativeContext) makeCurrent() error {
+	if C.eglMakeCurrent(c.display, c.surface, c.surface, c.context) == C.EGL_FALSE {
+		return eglError("make current")
+	}
+	return nil
+}
+
+func (c *nativeContext) destroy() {
+	C.eglMakeCurrent(c.display, nil, nil, nil)
+	if c.surface != nil {
+		C.eglDestroySurface(c.display, c.surface)
+	}
+	if c.context != nil {
+		C.eglDestroyContext(c.display, c.context)
+	}
+	C.eglTerminate(c.display)
+}
+
+func eglError(op string) error {
+	return fmt.Errorf("headless: cannot %s: EGL error 0x%x", op, int(C.eglGetError()))
+}
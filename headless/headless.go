@@ -0,0 +1,120 @@
+/*
+Package headless creates OpenGL contexts that render offscreen, without a
+window system, so that tests, golden image comparisons, and renderers running
+on servers can use gfx.
+
+Contexts are created with EGL on Linux, preferring Mesa's surfaceless
+platform, and with CGL on macOS. A context is current on one OS thread, so
+the goroutine using it should call runtime.LockOSThread before New and make
+all of its GL calls itself.
+*/
+package headless
+
+import (
+	"fmt"
+	"image"
+	"j4k.co/gfx"
+	"j4k.co/gfx/internal/gl"
+)
+
+// Context is an offscreen GL context and a framebuffer of a fixed size to
+// render into.
+type Context struct {
+	native        *nativeContext
+	fb            *gfx.Framebuffer
+	color, depth  *gfx.Sampler2D
+	width, height int
+}
+
+// New creates a context with an RGBA8 color buffer and a 24 bit depth buffer
+// of the given size, makes it current on the calling thread, and binds its
+// framebuffer.
+func New(width, height int) (*Context, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("headless: invalid size %dx%d", width, height)
+	}
+	native, err := newNative(width, height)
+	if err != nil {
+		return nil, err
+	}
+	c := &Context{native: native, width: width, height: height}
+	if err := c.init(); err != nil {
+		c.Delete()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Context) init() error {
+	if err := c.MakeCurrent(); err != nil {
+		return err
+	}
+	if err := gl.Init(); err != nil {
+		return err
+	}
+	if err := gfx.Init(); err != nil {
+		return err
+	}
+	return c.newFramebuffer()
+}
+
+// newFramebuffer creates the color and depth buffers of the context in its
+// current GL context, and binds them.
+func (c *Context) newFramebuffer() error {
+	var err error
+	if c.color, err = gfx.NewSampler2D(c.width, c.height, gfx.PixelRGBA8); err != nil {
+		return err
+	}
+	if c.depth, err = gfx.NewSampler2D(c.width, c.height, gfx.PixelDepth24); err != nil {
+		return err
+	}
+	if c.fb, err = gfx.NewFramebuffer(c.color, c.depth); err != nil {
+		return err
+	}
+	c.fb.Bind()
+	return nil
+}
+
+// MakeCurrent makes the context current on the calling thread.
+func (c *Context) MakeCurrent() error {
+	if err := c.native.makeCurrent(); err != nil {
+		return err
+	}
+	return gfx.Init()
+}
+
+// Framebuffer returns the framebuffer of the context. Rebind it after
+// rendering into other framebuffers.
+func (c *Context) Framebuffer() *gfx.Framebuffer {
+	return c.fb
+}
+
+// Size returns the width and height of the framebuffer.
+func (c *Context) Size() (width, height int) {
+	return c.width, c.height
+}
+
+// Image reads back what has been rendered into the framebuffer.
+func (c *Context) Image() (*image.NRGBA, error) {
+	return c.fb.ReadColor(0)
+}
+
+// Delete frees the framebuffer and destroys the context.
+func (c *Context) Delete() {
+	if c.fb != nil {
+		c.fb.Delete()
+		c.fb = nil
+	}
+	if c.color != nil {
+		c.color.Delete()
+		c.color = nil
+	}
+	if c.depth != nil {
+		c.depth.Delete()
+		c.depth = nil
+	}
+	if c.native != nil {
+		c.native.destroy()
+		c.native = nil
+	}
+}
@@ -0,0 +1,59 @@
+package headless
+
+import (
+	"fmt"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+func TestFramebuffer(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	// the recorder stands in for the native context
+	c := &Context{width: 2, height: 1}
+	if err := c.newFramebuffer(); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, call := range rec.Ops("TexImage2D", "FramebufferTexture2D", "Viewport") {
+		got = append(got, call.String())
+	}
+	want := fmt.Sprint([]string{
+		"TexImage2D(TEXTURE_2D, 0, RGBA8, 2, 1, 0, RGBA, UNSIGNED_BYTE, [0 bytes])",
+		"TexImage2D(TEXTURE_2D, 0, DEPTH_COMPONENT24, 2, 1, 0, DEPTH_COMPONENT, UNSIGNED_INT, [0 bytes])",
+		"FramebufferTexture2D(FRAMEBUFFER, COLOR_ATTACHMENT0, TEXTURE_2D, 1, 0)",
+		"FramebufferTexture2D(FRAMEBUFFER, DEPTH_ATTACHMENT, TEXTURE_2D, 2, 0)",
+		"Viewport(0, 0, 2, 1)",
+	})
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v,\nwant %v", got, want)
+	}
+	// the framebuffer is left bound
+	if binds := rec.Ops("BindFramebuffer"); binds[len(binds)-1].String() != "BindFramebuffer(FRAMEBUFFER, 3)" {
+		t.Errorf("got %v, want framebuffer 3 bound last", binds)
+	}
+
+	rec.Pixels = []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	rec.Reset()
+	img, err := c.Image()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls := rec.Ops("ReadPixels"); fmt.Sprint(calls) != "[ReadPixels(0, 0, 2, 1, RGBA, UNSIGNED_BYTE)]" {
+		t.Errorf("got %v", calls)
+	}
+	if fmt.Sprint(img.Pix) != "[1 2 3 4 5 6 7 8]" {
+		t.Errorf("got pixels %v", img.Pix)
+	}
+
+	rec.Reset()
+	c.Delete()
+	got = nil
+	for _, call := range rec.Calls {
+		got = append(got, call.String())
+	}
+	if want := "[DeleteFramebuffer(3) DeleteTexture(1) DeleteTexture(2)]"; fmt.Sprint(got) != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
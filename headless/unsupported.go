@@ -0,0 +1,18 @@
+//go:build !(linux && cgo) && !(darwin && cgo)
+// +build !linux !cgo
+// +build !darwin !cgo
+
+package headless
+
+import (
+	"errors"
+)
+
+type nativeContext struct{}
+
+func newNative(width, height int) (*nativeContext, error) {
+	return nil, errors.New("headless: offscreen contexts are not supported on this platform")
+}
+
+func (c *nativeContext) makeCurrent() error { return nil }
+func (c *nativeContext) destroy()           {}
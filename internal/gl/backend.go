@@ -0,0 +1,186 @@
+// Package gl is the subset of OpenGL that gfx uses, dispatched to a Backend.
+//
+// Its API mirrors the github.com/go-gl/gl package that gfx was written
+// against, so that the rest of gfx reads as plain GL code. Every call is
+// forwarded to the current Backend, which by default is go-gl itself. Other
+// bindings, such as generated core profile bindings, golang.org/x/mobile/gl,
+// or WebGL, and recording backends for tests implement Backend and are
+// installed with SetBackend.
+package gl
+
+import (
+	"unsafe"
+)
+
+// Backend implements the GL calls gfx makes. Object names are passed as the
+// types of this package, and data as slices, nil, or a uintptr offset into
+// the bound buffer, as with go-gl.
+type Backend interface {
+	// buffers
+	GenBuffers(bufs []Buffer)
+	DeleteBuffers(bufs []Buffer)
+	BindBuffer(target GLenum, buf Buffer)
+	BindBufferBase(target GLenum, index uint, buf Buffer)
+	BufferData(target GLenum, size int, data interface{}, usage GLenum)
+	BufferSubData(target GLenum, offset, size int, data interface{})
+	MapBuffer(target, access GLenum) unsafe.Pointer
+	MapBufferRange(target GLenum, offset, length int, access GLbitfield) unsafe.Pointer
+	UnmapBuffer(target GLenum) bool
+
+	// vertex arrays and attributes
+	GenVertexArray() VertexArray
+	BindVertexArray(vao VertexArray)
+	DeleteVertexArray(vao VertexArray)
+	VertexAttribPointer(index AttribLocation, size uint, typ GLenum, normalized bool, stride int, pointer interface{})
+	VertexAttribIPointer(index AttribLocation, size uint, typ GLenum, stride int, pointer interface{})
+	EnableVertexAttribArray(index AttribLocation)
+	DisableVertexAttribArray(index AttribLocation)
+	VertexAttribDivisor(index AttribLocation, divisor int)
+
+	// textures
+	GenTexture() Texture
+	BindTexture(target GLenum, tex Texture)
+	DeleteTexture(tex Texture)
+	ActiveTexture(unit GLenum)
+	TexParameteri(target, pname GLenum, param int)
+	TexParameterf(target, pname GLenum, param float32)
+	TexImage2D(target GLenum, level, internalformat, width, height, border int, format, typ GLenum, pixels interface{})
+	TexSubImage2D(target GLenum, level, xoffset, yoffset, width, height int, format, typ GLenum, pixels interface{})
+	CompressedTexImage2D(target GLenum, level int, internalformat GLenum, width, height, border, imageSize int, data interface{})
+	GenerateMipmap(target GLenum)
+	PixelStorei(pname GLenum, param int)
+	ReadPixels(x, y, width, height int, format, typ GLenum, pixels interface{})
+	ReadBuffer(mode GLenum)
+
+	// framebuffers and renderbuffers
+	GenFramebuffer() Framebuffer
+	BindFramebuffer(target GLenum, fb Framebuffer)
+	DeleteFramebuffer(fb Framebuffer)
+	CheckFramebufferStatus(target GLenum) GLenum
+	FramebufferTexture2D(target, attachment, textarget GLenum, tex Texture, level int)
+	FramebufferRenderbuffer(target, attachment, rbtarget GLenum, rb Renderbuffer)
+	GenRenderbuffer() Renderbuffer
+	BindRenderbuffer(rb Renderbuffer)
+	DeleteRenderbuffer(rb Renderbuffer)
+	RenderbufferStorageMultisample(target GLenum, samples int, internalformat GLenum, width, height int)
+	BlitFramebuffer(srcX0, srcY0, srcX1, srcY1, dstX0, dstY0, dstX1, dstY1 int, mask GLbitfield, filter GLenum)
+	DrawBuffers(bufs []GLenum)
+	DrawBuffer(mode GLenum)
+
+	// shaders and programs
+	CreateShader(typ GLenum) Shader
+	ShaderSource(s Shader, src string)
+	CompileShader(s Shader)
+	GetShaderi(s Shader, pname GLenum) int
+	GetShaderInfoLog(s Shader) string
+	DeleteShader(s Shader)
+	CreateProgram() Program
+	AttachShader(p Program, s Shader)
+	DetachShader(p Program, s Shader)
+	LinkProgram(p Program)
+	UseProgram(p Program)
+	DeleteProgram(p Program)
+	GetProgrami(p Program, pname GLenum) int
+	GetProgramInfoLog(p Program) string
+	GetUniformLocation(p Program, name string) UniformLocation
+	GetAttribLocation(p Program, name string) AttribLocation
+	BindFragDataLocation(p Program, color int, name string)
+	TransformFeedbackVaryings(p Program, names []string, mode GLenum)
+	GetActiveUniform(p Program, index int) (size int, typ GLenum, name string)
+	GetActiveAttrib(p Program, index int) (size int, typ GLenum, name string)
+	GetUniformBlockIndex(p Program, name string) uint
+	UniformBlockBinding(p Program, index, binding uint)
+	ProgramParameteri(p Program, pname GLenum, value int)
+	GetProgramBinary(p Program, bufSize int, length *int, format *GLenum, binary interface{})
+	ProgramBinary(p Program, format GLenum, binary interface{}, length int)
+
+	// uniforms of the current program. Vectors and arrays of them are
+	// passed as count vectors of size components; matrices as count
+	// column-major matrices of cols columns and rows rows.
+	Uniform1i(loc UniformLocation, v int)
+	Uniform1ui(loc UniformLocation, v uint)
+	Uniform1f(loc UniformLocation, v float32)
+	Uniformiv(loc UniformLocation, size, count int, v []int32)
+	Uniformuiv(loc UniformLocation, size, count int, v []uint32)
+	Uniformfv(loc UniformLocation, size, count int, v []float32)
+	UniformMatrixfv(loc UniformLocation, cols, rows, count int, transpose bool, v []float32)
+
+	// drawing
+	DrawArrays(mode GLenum, first, count int)
+	DrawElements(mode GLenum, count int, typ GLenum, indices interface{})
+	DrawArraysInstanced(mode GLenum, first, count, instances int)
+	DrawElementsInstanced(mode GLenum, count int, typ GLenum, indices interface{}, instances int)
+	MultiDrawArrays(mode GLenum, first, count []int32)
+	MultiDrawElements(mode GLenum, count []int32, typ GLenum, indices []uintptr)
+	PatchParameteri(pname GLenum, value int)
+	PrimitiveRestartIndex(index uint32)
+	BeginTransformFeedback(mode GLenum)
+	EndTransformFeedback()
+
+	// state
+	Enable(cap GLenum)
+	Disable(cap GLenum)
+	BlendFuncSeparate(srcRGB, dstRGB, srcAlpha, dstAlpha GLenum)
+	BlendEquationSeparate(modeRGB, modeAlpha GLenum)
+	DepthFunc(f GLenum)
+	DepthMask(flag bool)
+	CullFace(mode GLenum)
+	PolygonOffset(factor, units float32)
+	Scissor(x, y, width, height int)
+	ColorMask(r, g, b, a bool)
+	Viewport(x, y, width, height int)
+	Clear(mask GLbitfield)
+	ClearColor(r, g, b, a float32)
+	ClearBufferuiv(buffer GLenum, drawbuffer int, value []uint32)
+	ClearBufferfv(buffer GLenum, drawbuffer int, value []float32)
+
+	// queries and sync objects
+	GenQuery() Query
+	BeginQuery(target GLenum, q Query)
+	EndQuery(target GLenum)
+	DeleteQuery(q Query)
+	GetQueryObjectuiv(q Query, pname GLenum, params []uint32)
+	GetQueryObjectui64v(q Query, pname GLenum, params []uint64)
+	FenceSync(condition GLenum, flags GLbitfield) uintptr
+	ClientWaitSync(sync uintptr, flags GLbitfield, timeout uint64) GLenum
+	DeleteSync(sync uintptr)
+
+	// debug output
+	ObjectLabel(identifier GLenum, name uint32, label string)
+	PushDebugGroup(source GLenum, id uint32, message string)
+	PopDebugGroup()
+
+	// context
+	GetError() GLenum
+	GetString(name GLenum) string
+	GetStringi(name GLenum, index uint) string
+	GetIntegerv(pname GLenum, data []int32)
+	GetFloatv(pname GLenum, data []float32)
+}
+
+var backend Backend
+
+// SetBackend makes b the backend of every call that follows, returning the
+// previous one. It must not be called while GL objects made with the
+// previous backend are still in use.
+func SetBackend(b Backend) Backend {
+	old := backend
+	backend = b
+	return old
+}
+
+// Init prepares the backend to make calls into the current context, for
+// backends that need it, such as go-gl, which loads its entry points with
+// GLEW.
+func Init() error {
+	if b, ok := backend.(interface{ Init() error }); ok {
+		return b.Init()
+	}
+	return nil
+}
+
+// CurrentBackend returns the backend calls are dispatched to, which is nil
+// if gfx was built without go-gl and none has been set.
+func CurrentBackend() Backend {
+	return backend
+}
@@ -0,0 +1,208 @@
+package gl
+
+// The enums gfx uses, with the values of the OpenGL registry.
+const (
+	ACTIVE_ATTRIBUTES                = 0x8B89
+	ACTIVE_UNIFORMS                  = 0x8B86
+	ALREADY_SIGNALED                 = 0x911A
+	ALWAYS                           = 0x0207
+	ARRAY_BUFFER                     = 0x8892
+	BACK                             = 0x0405
+	BLEND                            = 0x0BE2
+	BOOL                             = 0x8B56
+	BOOL_VEC2                        = 0x8B57
+	BOOL_VEC3                        = 0x8B58
+	BOOL_VEC4                        = 0x8B59
+	BUFFER                           = 0x82E0
+	CLAMP_TO_EDGE                    = 0x812F
+	COLOR                            = 0x1800
+	COLOR_ATTACHMENT0                = 0x8CE0
+	COLOR_BUFFER_BIT                 = 0x00004000
+	COMPARE_REF_TO_TEXTURE           = 0x884E
+	COMPILE_STATUS                   = 0x8B81
+	COMPRESSED_RED_RGTC1             = 0x8DBB
+	COMPRESSED_RGBA_BPTC_UNORM       = 0x8E8C
+	COMPRESSED_RGBA_S3TC_DXT1_EXT    = 0x83F1
+	COMPRESSED_RGBA_S3TC_DXT3_EXT    = 0x83F2
+	COMPRESSED_RGBA_S3TC_DXT5_EXT    = 0x83F3
+	COMPRESSED_RG_RGTC2              = 0x8DBD
+	CONDITION_SATISFIED              = 0x911C
+	CONTEXT_CORE_PROFILE_BIT         = 0x00000001
+	CONTEXT_PROFILE_MASK             = 0x9126
+	CULL_FACE                        = 0x0B44
+	DEBUG_SOURCE_APPLICATION         = 0x824A
+	DEPTH                            = 0x1801
+	DEPTH_ATTACHMENT                 = 0x8D00
+	DEPTH_BUFFER_BIT                 = 0x00000100
+	DEPTH_COMPONENT                  = 0x1902
+	DEPTH_COMPONENT24                = 0x81A6
+	DEPTH_TEST                       = 0x0B71
+	DOUBLE                           = 0x140A
+	DRAW_FRAMEBUFFER                 = 0x8CA9
+	DST_ALPHA                        = 0x0304
+	DST_COLOR                        = 0x0306
+	DYNAMIC_COPY                     = 0x88EA
+	DYNAMIC_DRAW                     = 0x88E8
+	ELEMENT_ARRAY_BUFFER             = 0x8893
+	EQUAL                            = 0x0202
+	EXTENSIONS                       = 0x1F03
+	FLOAT                            = 0x1406
+	FLOAT_MAT2                       = 0x8B5A
+	FLOAT_MAT2x3                     = 0x8B65
+	FLOAT_MAT2x4                     = 0x8B66
+	FLOAT_MAT3                       = 0x8B5B
+	FLOAT_MAT3x2                     = 0x8B67
+	FLOAT_MAT3x4                     = 0x8B68
+	FLOAT_MAT4                       = 0x8B5C
+	FLOAT_MAT4x2                     = 0x8B69
+	FLOAT_MAT4x3                     = 0x8B6A
+	FLOAT_VEC2                       = 0x8B50
+	FLOAT_VEC3                       = 0x8B51
+	FLOAT_VEC4                       = 0x8B52
+	FRAGMENT_SHADER                  = 0x8B30
+	FRAMEBUFFER                      = 0x8D40
+	FRAMEBUFFER_COMPLETE             = 0x8CD5
+	FRAMEBUFFER_SRGB                 = 0x8DB9
+	FRONT                            = 0x0404
+	FUNC_ADD                         = 0x8006
+	FUNC_REVERSE_SUBTRACT            = 0x800B
+	FUNC_SUBTRACT                    = 0x800A
+	GEQUAL                           = 0x0206
+	GREATER                          = 0x0204
+	HALF_FLOAT                       = 0x140B
+	INT                              = 0x1404
+	INTERLEAVED_ATTRIBS              = 0x8C8C
+	INT_2_10_10_10_REV               = 0x8D9F
+	INT_VEC2                         = 0x8B53
+	INT_VEC3                         = 0x8B54
+	INT_VEC4                         = 0x8B55
+	INVALID_ENUM                     = 0x0500
+	INVALID_FRAMEBUFFER_OPERATION    = 0x0506
+	INVALID_INDEX                    = 0xFFFFFFFF
+	INVALID_OPERATION                = 0x0502
+	INVALID_VALUE                    = 0x0501
+	LEQUAL                           = 0x0203
+	LESS                             = 0x0201
+	LINEAR                           = 0x2601
+	LINEAR_MIPMAP_LINEAR             = 0x2703
+	LINES                            = 0x0001
+	LINE_STRIP                       = 0x0003
+	LINK_STATUS                      = 0x8B82
+	LUMINANCE                        = 0x1909
+	MAP_INVALIDATE_BUFFER_BIT        = 0x0008
+	MAP_INVALIDATE_RANGE_BIT         = 0x0004
+	MAP_READ_BIT                     = 0x0001
+	MAP_UNSYNCHRONIZED_BIT           = 0x0020
+	MAP_WRITE_BIT                    = 0x0002
+	MAX                              = 0x8008
+	MAX_COMBINED_TEXTURE_IMAGE_UNITS = 0x8B4D
+	MAX_TEXTURE_MAX_ANISOTROPY_EXT   = 0x84FF
+	MAX_TEXTURE_SIZE                 = 0x0D33
+	MAX_VERTEX_ATTRIBS               = 0x8869
+	MIN                              = 0x8007
+	MIRRORED_REPEAT                  = 0x8370
+	NEAREST                          = 0x2600
+	NEAREST_MIPMAP_NEAREST           = 0x2700
+	NEVER                            = 0x0200
+	NONE                             = 0
+	NOTEQUAL                         = 0x0205
+	NO_ERROR                         = 0
+	NUM_EXTENSIONS                   = 0x821D
+	NUM_PROGRAM_BINARY_FORMATS       = 0x87FE
+	ONE                              = 1
+	ONE_MINUS_DST_ALPHA              = 0x0305
+	ONE_MINUS_DST_COLOR              = 0x0307
+	ONE_MINUS_SRC_ALPHA              = 0x0303
+	ONE_MINUS_SRC_COLOR              = 0x0301
+	OUT_OF_MEMORY                    = 0x0505
+	PACK_ALIGNMENT                   = 0x0D05
+	PATCHES                          = 0x000E
+	PATCH_VERTICES                   = 0x8E72
+	PIXEL_PACK_BUFFER                = 0x88EB
+	PIXEL_UNPACK_BUFFER              = 0x88EC
+	POINTS                           = 0x0000
+	POLYGON_OFFSET_FILL              = 0x8037
+	PRIMITIVE_RESTART                = 0x8F9D
+	PRIMITIVE_RESTART_FIXED_INDEX    = 0x8D69
+	PROGRAM                          = 0x82E2
+	PROGRAM_BINARY_LENGTH            = 0x8741
+	PROGRAM_BINARY_RETRIEVABLE_HINT  = 0x8257
+	PROGRAM_POINT_SIZE               = 0x8642
+	QUERY_RESULT                     = 0x8866
+	QUERY_RESULT_AVAILABLE           = 0x8867
+	R11F_G11F_B10F                   = 0x8C3A
+	R8                               = 0x8229
+	RASTERIZER_DISCARD               = 0x8C89
+	READ_FRAMEBUFFER                 = 0x8CA8
+	READ_ONLY                        = 0x88B8
+	RED                              = 0x1903
+	RENDERBUFFER                     = 0x8D41
+	RENDERER                         = 0x1F01
+	REPEAT                           = 0x2901
+	RG32UI                           = 0x823C
+	RGB                              = 0x1907
+	RGBA                             = 0x1908
+	RGBA16F                          = 0x881A
+	RGBA32F                          = 0x8814
+	RGBA8                            = 0x8058
+	RG_INTEGER                       = 0x8228
+	SAMPLER_2D                       = 0x8B5E
+	SAMPLER_2D_ARRAY                 = 0x8DC1
+	SAMPLER_2D_SHADOW                = 0x8B62
+	SAMPLER_3D                       = 0x8B5F
+	SAMPLER_CUBE                     = 0x8B60
+	SCISSOR_TEST                     = 0x0C11
+	SHADING_LANGUAGE_VERSION         = 0x8B8C
+	SRC_ALPHA                        = 0x0302
+	SRC_COLOR                        = 0x0300
+	SRGB8_ALPHA8                     = 0x8C43
+	STATIC_COPY                      = 0x88E6
+	STATIC_DRAW                      = 0x88E4
+	STREAM_COPY                      = 0x88E2
+	STREAM_DRAW                      = 0x88E0
+	STREAM_READ                      = 0x88E1
+	SYNC_FLUSH_COMMANDS_BIT          = 0x00000001
+	SYNC_GPU_COMMANDS_COMPLETE       = 0x9117
+	TESS_CONTROL_SHADER              = 0x8E88
+	TESS_EVALUATION_SHADER           = 0x8E87
+	TEXTURE                          = 0x1702
+	TEXTURE0                         = 0x84C0
+	TEXTURE_2D                       = 0x0DE1
+	TEXTURE_COMPARE_FUNC             = 0x884D
+	TEXTURE_COMPARE_MODE             = 0x884C
+	TEXTURE_CUBE_MAP                 = 0x8513
+	TEXTURE_CUBE_MAP_NEGATIVE_X      = 0x8516
+	TEXTURE_CUBE_MAP_NEGATIVE_Y      = 0x8518
+	TEXTURE_CUBE_MAP_NEGATIVE_Z      = 0x851A
+	TEXTURE_CUBE_MAP_POSITIVE_X      = 0x8515
+	TEXTURE_CUBE_MAP_POSITIVE_Y      = 0x8517
+	TEXTURE_CUBE_MAP_POSITIVE_Z      = 0x8519
+	TEXTURE_CUBE_MAP_SEAMLESS        = 0x884F
+	TEXTURE_MAG_FILTER               = 0x2800
+	TEXTURE_MAX_ANISOTROPY_EXT       = 0x84FE
+	TEXTURE_MAX_LEVEL                = 0x813D
+	TEXTURE_MIN_FILTER               = 0x2801
+	TEXTURE_WRAP_R                   = 0x8072
+	TEXTURE_WRAP_S                   = 0x2802
+	TEXTURE_WRAP_T                   = 0x2803
+	TIME_ELAPSED                     = 0x88BF
+	TRANSFORM_FEEDBACK_BUFFER        = 0x8C8E
+	TRIANGLES                        = 0x0004
+	TRIANGLE_FAN                     = 0x0006
+	TRIANGLE_STRIP                   = 0x0005
+	TRUE                             = 1
+	UNIFORM_BUFFER                   = 0x8A11
+	UNPACK_ALIGNMENT                 = 0x0CF5
+	UNSIGNED_BYTE                    = 0x1401
+	UNSIGNED_INT                     = 0x1405
+	UNSIGNED_INT_VEC2                = 0x8DC6
+	UNSIGNED_INT_VEC3                = 0x8DC7
+	UNSIGNED_INT_VEC4                = 0x8DC8
+	UNSIGNED_SHORT                   = 0x1403
+	VENDOR                           = 0x1F00
+	VERSION                          = 0x1F02
+	VERTEX_ARRAY                     = 0x8074
+	VERTEX_SHADER                    = 0x8B31
+	WRITE_ONLY                       = 0x88B9
+	ZERO                             = 0
+)
@@ -0,0 +1,367 @@
+package gl
+
+import (
+	"unsafe"
+)
+
+type (
+	GLenum     uint32
+	GLbitfield uint32
+)
+
+// Object names.
+type (
+	Buffer       uint32
+	Texture      uint32
+	Framebuffer  uint32
+	Renderbuffer uint32
+	VertexArray  uint32
+	Program      uint32
+	Shader       uint32
+	Query        uint32
+)
+
+type (
+	UniformLocation int
+	AttribLocation  int
+)
+
+func GenBuffer() Buffer {
+	var b [1]Buffer
+	backend.GenBuffers(b[:])
+	return b[0]
+}
+
+func GenBuffers(bufs []Buffer)    { backend.GenBuffers(bufs) }
+func DeleteBuffers(bufs []Buffer) { backend.DeleteBuffers(bufs) }
+
+func (b Buffer) Bind(target GLenum) { backend.BindBuffer(target, b) }
+func (b Buffer) Delete()            { backend.DeleteBuffers([]Buffer{b}) }
+
+func (b Buffer) BindBufferBase(target GLenum, index uint) {
+	backend.BindBufferBase(target, index, b)
+}
+
+func BufferData(target GLenum, size int, data interface{}, usage GLenum) {
+	backend.BufferData(target, size, data, usage)
+}
+
+func BufferSubData(target GLenum, offset, size int, data interface{}) {
+	backend.BufferSubData(target, offset, size, data)
+}
+
+func MapBuffer(target, access GLenum) unsafe.Pointer {
+	return backend.MapBuffer(target, access)
+}
+
+func MapBufferRange(target GLenum, offset, length int, access GLbitfield) unsafe.Pointer {
+	return backend.MapBufferRange(target, offset, length, access)
+}
+
+func UnmapBuffer(target GLenum) bool { return backend.UnmapBuffer(target) }
+
+func GenVertexArray() VertexArray { return backend.GenVertexArray() }
+func (v VertexArray) Bind()       { backend.BindVertexArray(v) }
+func (v VertexArray) Delete()     { backend.DeleteVertexArray(v) }
+
+func (a AttribLocation) AttribPointer(size uint, typ GLenum, normalized bool, stride int, pointer interface{}) {
+	backend.VertexAttribPointer(a, size, typ, normalized, stride, pointer)
+}
+
+func (a AttribLocation) AttribIPointer(size uint, typ GLenum, stride int, pointer interface{}) {
+	backend.VertexAttribIPointer(a, size, typ, stride, pointer)
+}
+
+func (a AttribLocation) EnableArray()              { backend.EnableVertexAttribArray(a) }
+func (a AttribLocation) DisableArray()             { backend.DisableVertexAttribArray(a) }
+func (a AttribLocation) AttribDivisor(divisor int) { backend.VertexAttribDivisor(a, divisor) }
+
+func GenTexture() Texture              { return backend.GenTexture() }
+func (t Texture) Bind(target GLenum)   { backend.BindTexture(target, t) }
+func (t Texture) Delete()              { backend.DeleteTexture(t) }
+func ActiveTexture(unit GLenum)        { backend.ActiveTexture(unit) }
+func GenerateMipmap(target GLenum)     { backend.GenerateMipmap(target) }
+func PixelStorei(pname GLenum, p int)  { backend.PixelStorei(pname, p) }
+func ReadBuffer(mode GLenum)           { backend.ReadBuffer(mode) }
+func TexParameteri(t, p GLenum, v int) { backend.TexParameteri(t, p, v) }
+
+func TexParameterf(target, pname GLenum, param float32) {
+	backend.TexParameterf(target, pname, param)
+}
+
+func TexImage2D(target GLenum, level, internalformat, width, height, border int, format, typ GLenum, pixels interface{}) {
+	backend.TexImage2D(target, level, internalformat, width, height, border, format, typ, pixels)
+}
+
+func TexSubImage2D(target GLenum, level, xoffset, yoffset, width, height int, format, typ GLenum, pixels interface{}) {
+	backend.TexSubImage2D(target, level, xoffset, yoffset, width, height, format, typ, pixels)
+}
+
+func CompressedTexImage2D(target GLenum, level int, internalformat GLenum, width, height, border, imageSize int, data interface{}) {
+	backend.CompressedTexImage2D(target, level, internalformat, width, height, border, imageSize, data)
+}
+
+func ReadPixels(x, y, width, height int, format, typ GLenum, pixels interface{}) {
+	backend.ReadPixels(x, y, width, height, format, typ, pixels)
+}
+
+func GenFramebuffer() Framebuffer                 { return backend.GenFramebuffer() }
+func (f Framebuffer) Bind()                       { backend.BindFramebuffer(FRAMEBUFFER, f) }
+func (f Framebuffer) BindTarget(target GLenum)    { backend.BindFramebuffer(target, f) }
+func (f Framebuffer) Delete()                     { backend.DeleteFramebuffer(f) }
+func CheckFramebufferStatus(target GLenum) GLenum { return backend.CheckFramebufferStatus(target) }
+
+func FramebufferTexture2D(target, attachment, textarget GLenum, tex Texture, level int) {
+	backend.FramebufferTexture2D(target, attachment, textarget, tex, level)
+}
+
+func FramebufferRenderbuffer(target, attachment, rbtarget GLenum, rb Renderbuffer) {
+	backend.FramebufferRenderbuffer(target, attachment, rbtarget, rb)
+}
+
+func GenRenderbuffer() Renderbuffer { return backend.GenRenderbuffer() }
+func (r Renderbuffer) Bind()        { backend.BindRenderbuffer(r) }
+func (r Renderbuffer) Delete()      { backend.DeleteRenderbuffer(r) }
+
+func RenderbufferStorageMultisample(target GLenum, samples int, internalformat GLenum, width, height int) {
+	backend.RenderbufferStorageMultisample(target, samples, internalformat, width, height)
+}
+
+func BlitFramebuffer(srcX0, srcY0, srcX1, srcY1, dstX0, dstY0, dstX1, dstY1 int, mask GLbitfield, filter GLenum) {
+	backend.BlitFramebuffer(srcX0, srcY0, srcX1, srcY1, dstX0, dstY0, dstX1, dstY1, mask, filter)
+}
+
+func DrawBuffers(n int, bufs []GLenum) { backend.DrawBuffers(bufs[:n]) }
+func DrawBuffer(mode GLenum)           { backend.DrawBuffer(mode) }
+
+func CreateShader(typ GLenum) Shader    { return backend.CreateShader(typ) }
+func (s Shader) Source(src string)      { backend.ShaderSource(s, src) }
+func (s Shader) Compile()               { backend.CompileShader(s) }
+func (s Shader) Get(pname GLenum) int   { return backend.GetShaderi(s, pname) }
+func (s Shader) GetInfoLog() string     { return backend.GetShaderInfoLog(s) }
+func (s Shader) Delete()                { backend.DeleteShader(s) }
+func CreateProgram() Program            { return backend.CreateProgram() }
+func (p Program) AttachShader(s Shader) { backend.AttachShader(p, s) }
+func (p Program) DetachShader(s Shader) { backend.DetachShader(p, s) }
+func (p Program) Link()                 { backend.LinkProgram(p) }
+func (p Program) Use()                  { backend.UseProgram(p) }
+func (p Program) Delete()               { backend.DeleteProgram(p) }
+func (p Program) Get(pname GLenum) int  { return backend.GetProgrami(p, pname) }
+func (p Program) GetInfoLog() string    { return backend.GetProgramInfoLog(p) }
+
+func (p Program) GetUniformLocation(name string) UniformLocation {
+	return backend.GetUniformLocation(p, name)
+}
+
+func (p Program) GetAttribLocation(name string) AttribLocation {
+	return backend.GetAttribLocation(p, name)
+}
+
+func (p Program) BindFragDataLocation(color int, name string) {
+	backend.BindFragDataLocation(p, color, name)
+}
+
+func (p Program) TransformFeedbackVaryings(names []string, mode GLenum) {
+	backend.TransformFeedbackVaryings(p, names, mode)
+}
+
+func (p Program) GetActiveUniform(index int) (Size int, Type GLenum, Name string) {
+	return backend.GetActiveUniform(p, index)
+}
+
+func (p Program) GetActiveAttrib(index int) (Size int, Type GLenum, Name string) {
+	return backend.GetActiveAttrib(p, index)
+}
+
+func (p Program) GetUniformBlockIndex(name string) uint {
+	return backend.GetUniformBlockIndex(p, name)
+}
+
+func (p Program) UniformBlockBinding(index, binding uint) {
+	backend.UniformBlockBinding(p, index, binding)
+}
+
+func ProgramParameteri(p Program, pname GLenum, value int) {
+	backend.ProgramParameteri(p, pname, value)
+}
+
+func GetProgramBinary(p Program, bufSize int, length *int, format *GLenum, binary interface{}) {
+	backend.GetProgramBinary(p, bufSize, length, format, binary)
+}
+
+func ProgramBinary(p Program, format GLenum, binary interface{}, length int) {
+	backend.ProgramBinary(p, format, binary, length)
+}
+
+func (u UniformLocation) Uniform1i(v int)     { backend.Uniform1i(u, v) }
+func (u UniformLocation) Uniform1ui(v uint)   { backend.Uniform1ui(u, v) }
+func (u UniformLocation) Uniform1f(v float32) { backend.Uniform1f(u, v) }
+
+func (u UniformLocation) Uniform2iv(count int, v []int32)   { backend.Uniformiv(u, 2, count, v) }
+func (u UniformLocation) Uniform3iv(count int, v []int32)   { backend.Uniformiv(u, 3, count, v) }
+func (u UniformLocation) Uniform4iv(count int, v []int32)   { backend.Uniformiv(u, 4, count, v) }
+func (u UniformLocation) Uniform2uiv(count int, v []uint32) { backend.Uniformuiv(u, 2, count, v) }
+func (u UniformLocation) Uniform3uiv(count int, v []uint32) { backend.Uniformuiv(u, 3, count, v) }
+func (u UniformLocation) Uniform4uiv(count int, v []uint32) { backend.Uniformuiv(u, 4, count, v) }
+func (u UniformLocation) Uniform2fv(count int, v []float32) { backend.Uniformfv(u, 2, count, v) }
+func (u UniformLocation) Uniform3fv(count int, v []float32) { backend.Uniformfv(u, 3, count, v) }
+func (u UniformLocation) Uniform4fv(count int, v []float32) { backend.Uniformfv(u, 4, count, v) }
+
+func (u UniformLocation) UniformMatrix2f(transpose bool, m *[4]float32) {
+	backend.UniformMatrixfv(u, 2, 2, 1, transpose, m[:])
+}
+
+func (u UniformLocation) UniformMatrix3f(transpose bool, m *[9]float32) {
+	backend.UniformMatrixfv(u, 3, 3, 1, transpose, m[:])
+}
+
+func (u UniformLocation) UniformMatrix4f(transpose bool, m *[16]float32) {
+	backend.UniformMatrixfv(u, 4, 4, 1, transpose, m[:])
+}
+
+func (u UniformLocation) UniformMatrix4fv(transpose bool, list ...[16]float32) {
+	u.matrices(4, 4, transpose, list)
+}
+
+func (u UniformLocation) UniformMatrix2x3fv(transpose bool, list ...[6]float32) {
+	u.matrices(2, 3, transpose, list)
+}
+
+func (u UniformLocation) UniformMatrix3x2fv(transpose bool, list ...[6]float32) {
+	u.matrices(3, 2, transpose, list)
+}
+
+func (u UniformLocation) UniformMatrix2x4fv(transpose bool, list ...[8]float32) {
+	u.matrices(2, 4, transpose, list)
+}
+
+func (u UniformLocation) UniformMatrix4x2fv(transpose bool, list ...[8]float32) {
+	u.matrices(4, 2, transpose, list)
+}
+
+func (u UniformLocation) UniformMatrix3x4fv(transpose bool, list ...[12]float32) {
+	u.matrices(3, 4, transpose, list)
+}
+
+func (u UniformLocation) UniformMatrix4x3fv(transpose bool, list ...[12]float32) {
+	u.matrices(4, 3, transpose, list)
+}
+
+// matrices passes list, a slice of float32 arrays of cols*rows elements, to
+// the backend as one flat slice.
+func (u UniformLocation) matrices(cols, rows int, transpose bool, list interface{}) {
+	var v []float32
+	switch l := list.(type) {
+	case [][6]float32:
+		if len(l) > 0 {
+			v = unsafe.Slice(&l[0][0], len(l)*6)
+		}
+	case [][8]float32:
+		if len(l) > 0 {
+			v = unsafe.Slice(&l[0][0], len(l)*8)
+		}
+	case [][12]float32:
+		if len(l) > 0 {
+			v = unsafe.Slice(&l[0][0], len(l)*12)
+		}
+	case [][16]float32:
+		if len(l) > 0 {
+			v = unsafe.Slice(&l[0][0], len(l)*16)
+		}
+	}
+	if len(v) == 0 {
+		return
+	}
+	backend.UniformMatrixfv(u, cols, rows, len(v)/(cols*rows), transpose, v)
+}
+
+func DrawArrays(mode GLenum, first, count int) { backend.DrawArrays(mode, first, count) }
+
+func DrawElements(mode GLenum, count int, typ GLenum, indices interface{}) {
+	backend.DrawElements(mode, count, typ, indices)
+}
+
+func DrawArraysInstanced(mode GLenum, first, count, instances int) {
+	backend.DrawArraysInstanced(mode, first, count, instances)
+}
+
+func DrawElementsInstanced(mode GLenum, count int, typ GLenum, indices interface{}, instances int) {
+	backend.DrawElementsInstanced(mode, count, typ, indices, instances)
+}
+
+func MultiDrawArrays(mode GLenum, first, count []int32) {
+	backend.MultiDrawArrays(mode, first, count)
+}
+
+func MultiDrawElements(mode GLenum, count []int32, typ GLenum, indices []uintptr) {
+	backend.MultiDrawElements(mode, count, typ, indices)
+}
+
+func PatchParameteri(pname GLenum, value int) { backend.PatchParameteri(pname, value) }
+func PrimitiveRestartIndex(index uint32)      { backend.PrimitiveRestartIndex(index) }
+func BeginTransformFeedback(mode GLenum)      { backend.BeginTransformFeedback(mode) }
+func EndTransformFeedback()                   { backend.EndTransformFeedback() }
+
+func Enable(cap GLenum)                       { backend.Enable(cap) }
+func Disable(cap GLenum)                      { backend.Disable(cap) }
+func BlendEquationSeparate(rgb, alpha GLenum) { backend.BlendEquationSeparate(rgb, alpha) }
+func DepthFunc(f GLenum)                      { backend.DepthFunc(f) }
+func DepthMask(flag bool)                     { backend.DepthMask(flag) }
+func CullFace(mode GLenum)                    { backend.CullFace(mode) }
+func PolygonOffset(factor, units float32)     { backend.PolygonOffset(factor, units) }
+func Scissor(x, y, width, height int)         { backend.Scissor(x, y, width, height) }
+func ColorMask(r, g, b, a bool)               { backend.ColorMask(r, g, b, a) }
+func Viewport(x, y, width, height int)        { backend.Viewport(x, y, width, height) }
+func Clear(mask GLbitfield)                   { backend.Clear(mask) }
+func ClearColor(r, g, b, a float32)           { backend.ClearColor(r, g, b, a) }
+
+func ClearBufferuiv(buffer GLenum, drawbuffer int, value []uint32) {
+	backend.ClearBufferuiv(buffer, drawbuffer, value)
+}
+
+func ClearBufferfv(buffer GLenum, drawbuffer int, value []float32) {
+	backend.ClearBufferfv(buffer, drawbuffer, value)
+}
+
+func BlendFuncSeparate(srcRGB, dstRGB, srcAlpha, dstAlpha GLenum) {
+	backend.BlendFuncSeparate(srcRGB, dstRGB, srcAlpha, dstAlpha)
+}
+
+func GenQuery() Query               { return backend.GenQuery() }
+func (q Query) Begin(target GLenum) { backend.BeginQuery(target, q) }
+func (q Query) End(target GLenum)   { backend.EndQuery(target) }
+func (q Query) Delete()             { backend.DeleteQuery(q) }
+
+func (q Query) GetObjectuiv(pname GLenum, params []uint32) {
+	backend.GetQueryObjectuiv(q, pname, params)
+}
+
+func (q Query) GetObjectui64v(pname GLenum, params []uint64) {
+	backend.GetQueryObjectui64v(q, pname, params)
+}
+
+func FenceSync(condition GLenum, flags GLbitfield) uintptr {
+	return backend.FenceSync(condition, flags)
+}
+
+func ClientWaitSync(sync uintptr, flags GLbitfield, timeout uint64) GLenum {
+	return backend.ClientWaitSync(sync, flags, timeout)
+}
+
+func DeleteSync(sync uintptr) { backend.DeleteSync(sync) }
+
+func ObjectLabel(identifier GLenum, name uint32, label string) {
+	backend.ObjectLabel(identifier, name, label)
+}
+
+func PushDebugGroup(source GLenum, id uint32, message string) {
+	backend.PushDebugGroup(source, id, message)
+}
+
+func PopDebugGroup() { backend.PopDebugGroup() }
+
+func GetError() GLenum                          { return backend.GetError() }
+func GetString(name GLenum) string              { return backend.GetString(name) }
+func GetStringi(name GLenum, index uint) string { return backend.GetStringi(name, index) }
+func GetIntegerv(pname GLenum, data []int32)    { backend.GetIntegerv(pname, data) }
+func GetFloatv(pname GLenum, data []float32)    { backend.GetFloatv(pname, data) }
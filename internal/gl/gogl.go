@@ -0,0 +1,409 @@
+//go:build !nogogl
+// +build !nogogl
+
+package gl
+
+import (
+	"fmt"
+	"github.com/go-gl/gl"
+	"unsafe"
+)
+
+func init() {
+	backend = goglBackend{}
+}
+
+// goglBackend is the default Backend, calling github.com/go-gl/gl. It is left
+// out of builds with the nogogl tag, for platforms go-gl does not support.
+type goglBackend struct{}
+
+func (goglBackend) Init() error {
+	if err := gl.Init(); err != 0 {
+		return fmt.Errorf("gl: GLEW initialization failed with error %d", err)
+	}
+	return nil
+}
+
+func (goglBackend) GenBuffers(bufs []Buffer) {
+	gl.GenBuffers(*(*[]gl.Buffer)(unsafe.Pointer(&bufs)))
+}
+
+func (goglBackend) DeleteBuffers(bufs []Buffer) {
+	gl.DeleteBuffers(*(*[]gl.Buffer)(unsafe.Pointer(&bufs)))
+}
+
+func (goglBackend) BindBuffer(target GLenum, buf Buffer) {
+	gl.Buffer(buf).Bind(gl.GLenum(target))
+}
+
+func (goglBackend) BindBufferBase(target GLenum, index uint, buf Buffer) {
+	gl.Buffer(buf).BindBufferBase(gl.GLenum(target), index)
+}
+
+func (goglBackend) BufferData(target GLenum, size int, data interface{}, usage GLenum) {
+	gl.BufferData(gl.GLenum(target), size, data, gl.GLenum(usage))
+}
+
+func (goglBackend) BufferSubData(target GLenum, offset, size int, data interface{}) {
+	gl.BufferSubData(gl.GLenum(target), offset, size, data)
+}
+
+func (goglBackend) MapBuffer(target, access GLenum) unsafe.Pointer {
+	return gl.MapBuffer(gl.GLenum(target), gl.GLenum(access))
+}
+
+func (goglBackend) MapBufferRange(target GLenum, offset, length int, access GLbitfield) unsafe.Pointer {
+	return gl.MapBufferRange(gl.GLenum(target), offset, length, gl.GLbitfield(access))
+}
+
+func (goglBackend) UnmapBuffer(target GLenum) bool {
+	return gl.UnmapBuffer(gl.GLenum(target))
+}
+
+func (goglBackend) GenVertexArray() VertexArray       { return VertexArray(gl.GenVertexArray()) }
+func (goglBackend) BindVertexArray(vao VertexArray)   { gl.VertexArray(vao).Bind() }
+func (goglBackend) DeleteVertexArray(vao VertexArray) { gl.VertexArray(vao).Delete() }
+
+func (goglBackend) VertexAttribPointer(index AttribLocation, size uint, typ GLenum, normalized bool, stride int, pointer interface{}) {
+	gl.AttribLocation(index).AttribPointer(size, gl.GLenum(typ), normalized, stride, pointer)
+}
+
+func (goglBackend) VertexAttribIPointer(index AttribLocation, size uint, typ GLenum, stride int, pointer interface{}) {
+	gl.AttribLocation(index).AttribIPointer(size, gl.GLenum(typ), stride, pointer)
+}
+
+func (goglBackend) EnableVertexAttribArray(index AttribLocation) {
+	gl.AttribLocation(index).EnableArray()
+}
+
+func (goglBackend) DisableVertexAttribArray(index AttribLocation) {
+	gl.AttribLocation(index).DisableArray()
+}
+
+func (goglBackend) VertexAttribDivisor(index AttribLocation, divisor int) {
+	gl.AttribLocation(index).AttribDivisor(divisor)
+}
+
+func (goglBackend) GenTexture() Texture                    { return Texture(gl.GenTexture()) }
+func (goglBackend) BindTexture(target GLenum, tex Texture) { gl.Texture(tex).Bind(gl.GLenum(target)) }
+func (goglBackend) DeleteTexture(tex Texture)              { gl.Texture(tex).Delete() }
+func (goglBackend) ActiveTexture(unit GLenum)              { gl.ActiveTexture(gl.GLenum(unit)) }
+func (goglBackend) GenerateMipmap(target GLenum)           { gl.GenerateMipmap(gl.GLenum(target)) }
+func (goglBackend) PixelStorei(pname GLenum, param int)    { gl.PixelStorei(gl.GLenum(pname), param) }
+func (goglBackend) ReadBuffer(mode GLenum)                 { gl.ReadBuffer(gl.GLenum(mode)) }
+
+func (goglBackend) TexParameteri(target, pname GLenum, param int) {
+	gl.TexParameteri(gl.GLenum(target), gl.GLenum(pname), param)
+}
+
+func (goglBackend) TexParameterf(target, pname GLenum, param float32) {
+	gl.TexParameterf(gl.GLenum(target), gl.GLenum(pname), param)
+}
+
+func (goglBackend) TexImage2D(target GLenum, level, internalformat, width, height, border int, format, typ GLenum, pixels interface{}) {
+	gl.TexImage2D(gl.GLenum(target), level, internalformat, width, height, border, gl.GLenum(format), gl.GLenum(typ), pixels)
+}
+
+func (goglBackend) TexSubImage2D(target GLenum, level, xoffset, yoffset, width, height int, format, typ GLenum, pixels interface{}) {
+	gl.TexSubImage2D(gl.GLenum(target), level, xoffset, yoffset, width, height, gl.GLenum(format), gl.GLenum(typ), pixels)
+}
+
+func (goglBackend) CompressedTexImage2D(target GLenum, level int, internalformat GLenum, width, height, border, imageSize int, data interface{}) {
+	gl.CompressedTexImage2D(gl.GLenum(target), level, gl.GLenum(internalformat), width, height, border, imageSize, data)
+}
+
+func (goglBackend) ReadPixels(x, y, width, height int, format, typ GLenum, pixels interface{}) {
+	gl.ReadPixels(x, y, width, height, gl.GLenum(format), gl.GLenum(typ), pixels)
+}
+
+func (goglBackend) GenFramebuffer() Framebuffer { return Framebuffer(gl.GenFramebuffer()) }
+
+func (goglBackend) BindFramebuffer(target GLenum, fb Framebuffer) {
+	gl.Framebuffer(fb).BindTarget(gl.GLenum(target))
+}
+
+func (goglBackend) DeleteFramebuffer(fb Framebuffer) { gl.Framebuffer(fb).Delete() }
+
+func (goglBackend) CheckFramebufferStatus(target GLenum) GLenum {
+	return GLenum(gl.CheckFramebufferStatus(gl.GLenum(target)))
+}
+
+func (goglBackend) FramebufferTexture2D(target, attachment, textarget GLenum, tex Texture, level int) {
+	gl.FramebufferTexture2D(gl.GLenum(target), gl.GLenum(attachment), gl.GLenum(textarget), gl.Texture(tex), level)
+}
+
+func (goglBackend) FramebufferRenderbuffer(target, attachment, rbtarget GLenum, rb Renderbuffer) {
+	gl.FramebufferRenderbuffer(gl.GLenum(target), gl.GLenum(attachment), gl.GLenum(rbtarget), gl.Renderbuffer(rb))
+}
+
+func (goglBackend) GenRenderbuffer() Renderbuffer      { return Renderbuffer(gl.GenRenderbuffer()) }
+func (goglBackend) BindRenderbuffer(rb Renderbuffer)   { gl.Renderbuffer(rb).Bind() }
+func (goglBackend) DeleteRenderbuffer(rb Renderbuffer) { gl.Renderbuffer(rb).Delete() }
+
+func (goglBackend) RenderbufferStorageMultisample(target GLenum, samples int, internalformat GLenum, width, height int) {
+	gl.RenderbufferStorageMultisample(gl.GLenum(target), samples, gl.GLenum(internalformat), width, height)
+}
+
+func (goglBackend) BlitFramebuffer(srcX0, srcY0, srcX1, srcY1, dstX0, dstY0, dstX1, dstY1 int, mask GLbitfield, filter GLenum) {
+	gl.BlitFramebuffer(srcX0, srcY0, srcX1, srcY1, dstX0, dstY0, dstX1, dstY1, gl.GLbitfield(mask), gl.GLenum(filter))
+}
+
+func (goglBackend) DrawBuffers(bufs []GLenum) {
+	gl.DrawBuffers(len(bufs), *(*[]gl.GLenum)(unsafe.Pointer(&bufs)))
+}
+
+func (goglBackend) DrawBuffer(mode GLenum) { gl.DrawBuffer(gl.GLenum(mode)) }
+
+func (goglBackend) CreateShader(typ GLenum) Shader        { return Shader(gl.CreateShader(gl.GLenum(typ))) }
+func (goglBackend) ShaderSource(s Shader, src string)     { gl.Shader(s).Source(src) }
+func (goglBackend) CompileShader(s Shader)                { gl.Shader(s).Compile() }
+func (goglBackend) GetShaderi(s Shader, pname GLenum) int { return gl.Shader(s).Get(gl.GLenum(pname)) }
+func (goglBackend) GetShaderInfoLog(s Shader) string      { return gl.Shader(s).GetInfoLog() }
+func (goglBackend) DeleteShader(s Shader)                 { gl.Shader(s).Delete() }
+func (goglBackend) CreateProgram() Program                { return Program(gl.CreateProgram()) }
+func (goglBackend) AttachShader(p Program, s Shader)      { gl.Program(p).AttachShader(gl.Shader(s)) }
+func (goglBackend) DetachShader(p Program, s Shader)      { gl.Program(p).DetachShader(gl.Shader(s)) }
+func (goglBackend) LinkProgram(p Program)                 { gl.Program(p).Link() }
+func (goglBackend) UseProgram(p Program)                  { gl.Program(p).Use() }
+func (goglBackend) DeleteProgram(p Program)               { gl.Program(p).Delete() }
+func (goglBackend) GetProgramInfoLog(p Program) string    { return gl.Program(p).GetInfoLog() }
+
+func (goglBackend) GetProgrami(p Program, pname GLenum) int {
+	return gl.Program(p).Get(gl.GLenum(pname))
+}
+
+func (goglBackend) GetUniformLocation(p Program, name string) UniformLocation {
+	return UniformLocation(gl.Program(p).GetUniformLocation(name))
+}
+
+func (goglBackend) GetAttribLocation(p Program, name string) AttribLocation {
+	return AttribLocation(gl.Program(p).GetAttribLocation(name))
+}
+
+func (goglBackend) BindFragDataLocation(p Program, color int, name string) {
+	gl.Program(p).BindFragDataLocation(color, name)
+}
+
+func (goglBackend) TransformFeedbackVaryings(p Program, names []string, mode GLenum) {
+	gl.Program(p).TransformFeedbackVaryings(names, gl.GLenum(mode))
+}
+
+func (goglBackend) GetActiveUniform(p Program, index int) (int, GLenum, string) {
+	size, typ, name := gl.Program(p).GetActiveUniform(index)
+	return size, GLenum(typ), name
+}
+
+func (goglBackend) GetActiveAttrib(p Program, index int) (int, GLenum, string) {
+	size, typ, name := gl.Program(p).GetActiveAttrib(index)
+	return size, GLenum(typ), name
+}
+
+func (goglBackend) GetUniformBlockIndex(p Program, name string) uint {
+	return gl.Program(p).GetUniformBlockIndex(name)
+}
+
+func (goglBackend) UniformBlockBinding(p Program, index, binding uint) {
+	gl.Program(p).UniformBlockBinding(index, binding)
+}
+
+func (goglBackend) ProgramParameteri(p Program, pname GLenum, value int) {
+	gl.ProgramParameteri(gl.Program(p), gl.GLenum(pname), value)
+}
+
+func (goglBackend) GetProgramBinary(p Program, bufSize int, length *int, format *GLenum, binary interface{}) {
+	gl.GetProgramBinary(gl.Program(p), bufSize, length, (*gl.GLenum)(format), binary)
+}
+
+func (goglBackend) ProgramBinary(p Program, format GLenum, binary interface{}, length int) {
+	gl.ProgramBinary(gl.Program(p), gl.GLenum(format), binary, length)
+}
+
+func (goglBackend) Uniform1i(loc UniformLocation, v int)     { gl.UniformLocation(loc).Uniform1i(v) }
+func (goglBackend) Uniform1ui(loc UniformLocation, v uint)   { gl.UniformLocation(loc).Uniform1ui(v) }
+func (goglBackend) Uniform1f(loc UniformLocation, v float32) { gl.UniformLocation(loc).Uniform1f(v) }
+
+func (goglBackend) Uniformiv(loc UniformLocation, size, count int, v []int32) {
+	u := gl.UniformLocation(loc)
+	switch size {
+	case 1:
+		u.Uniform1iv(count, v)
+	case 2:
+		u.Uniform2iv(count, v)
+	case 3:
+		u.Uniform3iv(count, v)
+	case 4:
+		u.Uniform4iv(count, v)
+	}
+}
+
+func (goglBackend) Uniformuiv(loc UniformLocation, size, count int, v []uint32) {
+	u := gl.UniformLocation(loc)
+	switch size {
+	case 1:
+		u.Uniform1uiv(count, v)
+	case 2:
+		u.Uniform2uiv(count, v)
+	case 3:
+		u.Uniform3uiv(count, v)
+	case 4:
+		u.Uniform4uiv(count, v)
+	}
+}
+
+func (goglBackend) Uniformfv(loc UniformLocation, size, count int, v []float32) {
+	u := gl.UniformLocation(loc)
+	switch size {
+	case 1:
+		u.Uniform1fv(count, v)
+	case 2:
+		u.Uniform2fv(count, v)
+	case 3:
+		u.Uniform3fv(count, v)
+	case 4:
+		u.Uniform4fv(count, v)
+	}
+}
+
+func (goglBackend) UniformMatrixfv(loc UniformLocation, cols, rows, count int, transpose bool, v []float32) {
+	if count == 0 || len(v) < count*cols*rows {
+		return
+	}
+	u := gl.UniformLocation(loc)
+	p := unsafe.Pointer(&v[0])
+	switch cols*10 + rows {
+	case 22:
+		u.UniformMatrix2fv(transpose, unsafe.Slice((*[4]float32)(p), count)...)
+	case 33:
+		u.UniformMatrix3fv(transpose, unsafe.Slice((*[9]float32)(p), count)...)
+	case 44:
+		u.UniformMatrix4fv(transpose, unsafe.Slice((*[16]float32)(p), count)...)
+	case 23:
+		u.UniformMatrix2x3fv(transpose, unsafe.Slice((*[6]float32)(p), count)...)
+	case 32:
+		u.UniformMatrix3x2fv(transpose, unsafe.Slice((*[6]float32)(p), count)...)
+	case 24:
+		u.UniformMatrix2x4fv(transpose, unsafe.Slice((*[8]float32)(p), count)...)
+	case 42:
+		u.UniformMatrix4x2fv(transpose, unsafe.Slice((*[8]float32)(p), count)...)
+	case 34:
+		u.UniformMatrix3x4fv(transpose, unsafe.Slice((*[12]float32)(p), count)...)
+	case 43:
+		u.UniformMatrix4x3fv(transpose, unsafe.Slice((*[12]float32)(p), count)...)
+	}
+}
+
+func (goglBackend) DrawArrays(mode GLenum, first, count int) {
+	gl.DrawArrays(gl.GLenum(mode), first, count)
+}
+
+func (goglBackend) DrawElements(mode GLenum, count int, typ GLenum, indices interface{}) {
+	gl.DrawElements(gl.GLenum(mode), count, gl.GLenum(typ), indices)
+}
+
+func (goglBackend) DrawArraysInstanced(mode GLenum, first, count, instances int) {
+	gl.DrawArraysInstanced(gl.GLenum(mode), first, count, instances)
+}
+
+func (goglBackend) DrawElementsInstanced(mode GLenum, count int, typ GLenum, indices interface{}, instances int) {
+	gl.DrawElementsInstanced(gl.GLenum(mode), count, gl.GLenum(typ), indices, instances)
+}
+
+func (goglBackend) MultiDrawArrays(mode GLenum, first, count []int32) {
+	gl.MultiDrawArrays(gl.GLenum(mode), first, count)
+}
+
+func (goglBackend) MultiDrawElements(mode GLenum, count []int32, typ GLenum, indices []uintptr) {
+	gl.MultiDrawElements(gl.GLenum(mode), count, gl.GLenum(typ), indices)
+}
+
+func (goglBackend) PatchParameteri(pname GLenum, value int) {
+	gl.PatchParameteri(gl.GLenum(pname), value)
+}
+
+func (goglBackend) PrimitiveRestartIndex(index uint32) {
+	gl.PrimitiveRestartIndex(uint(index))
+}
+
+func (goglBackend) BeginTransformFeedback(mode GLenum) {
+	gl.BeginTransformFeedback(gl.GLenum(mode))
+}
+
+func (goglBackend) EndTransformFeedback() {
+	gl.EndTransformFeedback()
+}
+
+func (goglBackend) Enable(cap GLenum)                   { gl.Enable(gl.GLenum(cap)) }
+func (goglBackend) Disable(cap GLenum)                  { gl.Disable(gl.GLenum(cap)) }
+func (goglBackend) DepthFunc(f GLenum)                  { gl.DepthFunc(gl.GLenum(f)) }
+func (goglBackend) DepthMask(flag bool)                 { gl.DepthMask(flag) }
+func (goglBackend) CullFace(mode GLenum)                { gl.CullFace(gl.GLenum(mode)) }
+func (goglBackend) PolygonOffset(factor, units float32) { gl.PolygonOffset(factor, units) }
+func (goglBackend) Scissor(x, y, width, height int)     { gl.Scissor(x, y, width, height) }
+func (goglBackend) ColorMask(r, g, b, a bool)           { gl.ColorMask(r, g, b, a) }
+func (goglBackend) Viewport(x, y, width, height int)    { gl.Viewport(x, y, width, height) }
+func (goglBackend) Clear(mask GLbitfield)               { gl.Clear(gl.GLbitfield(mask)) }
+func (goglBackend) ClearColor(r, g, b, a float32)       { gl.ClearColor(r, g, b, a) }
+
+func (goglBackend) ClearBufferuiv(buffer GLenum, drawbuffer int, value []uint32) {
+	gl.ClearBufferuiv(gl.GLenum(buffer), drawbuffer, value)
+}
+
+func (goglBackend) ClearBufferfv(buffer GLenum, drawbuffer int, value []float32) {
+	gl.ClearBufferfv(gl.GLenum(buffer), drawbuffer, value)
+}
+
+func (goglBackend) BlendFuncSeparate(srcRGB, dstRGB, srcAlpha, dstAlpha GLenum) {
+	gl.BlendFuncSeparate(gl.GLenum(srcRGB), gl.GLenum(dstRGB), gl.GLenum(srcAlpha), gl.GLenum(dstAlpha))
+}
+
+func (goglBackend) BlendEquationSeparate(modeRGB, modeAlpha GLenum) {
+	gl.BlendEquationSeparate(gl.GLenum(modeRGB), gl.GLenum(modeAlpha))
+}
+
+func (goglBackend) GenQuery() Query                   { return Query(gl.GenQuery()) }
+func (goglBackend) BeginQuery(target GLenum, q Query) { gl.Query(q).Begin(gl.GLenum(target)) }
+func (goglBackend) EndQuery(target GLenum)            { gl.Query(0).End(gl.GLenum(target)) }
+func (goglBackend) DeleteQuery(q Query)               { gl.Query(q).Delete() }
+
+func (goglBackend) GetQueryObjectuiv(q Query, pname GLenum, params []uint32) {
+	gl.Query(q).GetObjectuiv(gl.GLenum(pname), params)
+}
+
+func (goglBackend) GetQueryObjectui64v(q Query, pname GLenum, params []uint64) {
+	gl.Query(q).GetObjectui64v(gl.GLenum(pname), params)
+}
+
+func (goglBackend) FenceSync(condition GLenum, flags GLbitfield) uintptr {
+	return gl.FenceSync(gl.GLenum(condition), gl.GLbitfield(flags))
+}
+
+func (goglBackend) ClientWaitSync(sync uintptr, flags GLbitfield, timeout uint64) GLenum {
+	return GLenum(gl.ClientWaitSync(sync, gl.GLbitfield(flags), timeout))
+}
+
+func (goglBackend) DeleteSync(sync uintptr) { gl.DeleteSync(sync) }
+
+func (goglBackend) ObjectLabel(identifier GLenum, name uint32, label string) {
+	gl.ObjectLabel(gl.GLenum(identifier), uint(name), label)
+}
+
+func (goglBackend) PushDebugGroup(source GLenum, id uint32, message string) {
+	gl.PushDebugGroup(gl.GLenum(source), uint(id), message)
+}
+
+func (goglBackend) PopDebugGroup() { gl.PopDebugGroup() }
+
+func (goglBackend) GetError() GLenum             { return GLenum(gl.GetError()) }
+func (goglBackend) GetString(name GLenum) string { return gl.GetString(gl.GLenum(name)) }
+
+func (goglBackend) GetStringi(name GLenum, index uint) string {
+	return gl.GetStringi(gl.GLenum(name), index)
+}
+
+func (goglBackend) GetIntegerv(pname GLenum, data []int32) {
+	gl.GetIntegerv(gl.GLenum(pname), data)
+}
+
+func (goglBackend) GetFloatv(pname GLenum, data []float32) {
+	gl.GetFloatv(gl.GLenum(pname), data)
+}
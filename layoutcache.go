@@ -0,0 +1,88 @@
+package gfx
+
+import (
+	"j4k.co/gfx/internal/gl"
+)
+
+// layoutCache holds the vertex array objects made by Shader.SetGeometry.
+// Shaders and geometry are keyed by ids rather than pointers, so that the
+// cache does not keep them from being garbage collected; their finalizers
+// queue the ids for Collect to evict.
+var (
+	layoutCache = make(map[layoutKey]*cachedLayout)
+	lastCacheID uint64
+)
+
+type layoutKey struct {
+	shader, geom uint64
+}
+
+type cachedLayout struct {
+	vao gl.VertexArray
+
+	// the buffers and instance format the vao was made for, which change
+	// if instances or vertex buffers are added to the geometry
+	vertbuf, idxbuf, instbuf gl.Buffer
+	instFormat               VertexFormat
+	planes                   int
+}
+
+func cacheID(id *uint64) uint64 {
+	if *id == 0 {
+		lastCacheID++
+		*id = lastCacheID
+	}
+	return *id
+}
+
+// SetGeometry binds geom for drawing, laying it out for the shader as
+// LayoutGeometry does the first time the pair is drawn, and reusing the
+// layout afterwards. Layouts are freed when the shader or geometry is
+// deleted, or by Collect after either is garbage collected.
+func (s *Shader) SetGeometry(geom *Geometry) error {
+	key := layoutKey{cacheID(&s.cacheID), cacheID(&geom.cacheID)}
+	l := layoutCache[key]
+	if l != nil && (l.vertbuf != geom.VertexBuffer.buf || l.idxbuf != geom.IndexBuffer.buf ||
+		l.instbuf != geom.Instances.buf || l.instFormat != geom.Instances.format ||
+		l.planes != len(geom.planes)) {
+		if l.vao != 0 {
+			deleteVertexArray(l.vao)
+		}
+		delete(layoutCache, key)
+		l = nil
+	}
+	if l == nil {
+		vao, err := s.layout(geom)
+		if err != nil {
+			return err
+		}
+		l = &cachedLayout{
+			vao:        vao,
+			vertbuf:    geom.VertexBuffer.buf,
+			idxbuf:     geom.IndexBuffer.buf,
+			instbuf:    geom.Instances.buf,
+			instFormat: geom.Instances.format,
+			planes:     len(geom.planes),
+		}
+		layoutCache[key] = l
+	}
+	s.bindGeometry(l.vao, geom, geom.planes)
+	checkError("SetGeometry")
+	return nil
+}
+
+// evictLayouts deletes the cached layouts of the shader or geometry with
+// the given id.
+func evictLayouts(id uint64) {
+	if id == 0 {
+		return
+	}
+	for key, l := range layoutCache {
+		if key.shader == id || key.geom == id {
+			if l.vao != 0 {
+				deleteVertexArray(l.vao)
+			}
+			delete(layoutCache, key)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package gfx_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+func TestLayoutCache(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	var shaders [2]*gfx.Shader
+	for i := range shaders {
+		s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, tintShader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shaders[i] = s
+	}
+	defer shaders[0].Delete()
+	geom := quad(t)
+	rec.Reset()
+	for _, s := range []*gfx.Shader{shaders[0], shaders[0], shaders[1], shaders[0]} {
+		s.Use()
+		if err := s.SetGeometry(geom); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// one layout for each shader, bound again when the shader goes back to
+	// the geometry
+	vaos := rec.Ops("GenVertexArray")
+	if len(vaos) != 2 {
+		t.Fatalf("got %v, want a vertex array for each shader", vaos)
+	}
+	a, b := vaos[0].Args[0], vaos[1].Args[0]
+	var got []string
+	for _, c := range rec.Ops("BindVertexArray") {
+		got = append(got, fmt.Sprint(c.Args[0]))
+	}
+	if want := fmt.Sprint([]interface{}{a, b, a}); fmt.Sprint(got) != want {
+		t.Errorf("bound vertex arrays %v, want %v", got, want)
+	}
+
+	// deleting the geometry frees both layouts
+	rec.Reset()
+	geom.Delete()
+	got = nil
+	for _, c := range rec.Ops("DeleteVertexArray") {
+		got = append(got, fmt.Sprint(c.Args[0]))
+	}
+	if len(got) != 2 || got[0] == got[1] {
+		t.Errorf("deleted vertex arrays %v, want %v and %v", got, a, b)
+	}
+
+	// as does deleting the shader, and a new layout is made after
+	geom = quad(t)
+	defer geom.Delete()
+	shaders[1].Use()
+	if err := shaders[1].SetGeometry(geom); err != nil {
+		t.Fatal(err)
+	}
+	vao := rec.Ops("GenVertexArray")[0].Args[0]
+	rec.Reset()
+	shaders[1].Delete()
+	if calls := rec.Ops("DeleteVertexArray"); len(calls) != 1 || calls[0].Args[0] != vao {
+		t.Errorf("got %v deleting the shader, want vertex array %v deleted", calls, vao)
+	}
+}
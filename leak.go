@@ -0,0 +1,139 @@
+package gfx
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// leaks tracks the resources created while leak checking is on, by the
+// address of their Go values.
+var leaks struct {
+	sync.Mutex
+	on        bool
+	seq       uint64
+	live      map[uintptr]*leakRecord
+	collected []*leakRecord
+}
+
+type leakRecord struct {
+	seq       uint64
+	kind      string
+	pcs       []uintptr
+	collected bool
+}
+
+// SetLeakCheck enables or disables leak checking. While it is on, every
+// resource gfx creates records the stack that created it, until it is
+// deleted, and CheckLeaks reports those that were not. Capturing stacks
+// slows down creation, so it is meant for debugging.
+func SetLeakCheck(on bool) {
+	leaks.Lock()
+	leaks.on = on
+	if on && leaks.live == nil {
+		leaks.live = make(map[uintptr]*leakRecord)
+	}
+	leaks.Unlock()
+}
+
+// Leak is a resource that was not deleted.
+type Leak struct {
+	Kind      string // type of the resource, such as "*gfx.Sampler2D"
+	Collected bool   // whether it was garbage collected without being deleted
+	Stack     string // stack that created it
+}
+
+func (l Leak) String() string {
+	state := "not deleted"
+	if l.Collected {
+		state = "garbage collected without Delete"
+	}
+	return fmt.Sprintf("gfx: %s %s, created at:\n%s", l.Kind, state, l.Stack)
+}
+
+// CheckLeaks returns the resources created while leak checking was on that
+// are still alive, and those garbage collected since the last call without
+// being deleted, in the order they were created. To check at exit, defer it
+// in main after deleting everything:
+//
+//	gfx.SetLeakCheck(true)
+//	defer func() {
+//		for _, l := range gfx.CheckLeaks() {
+//			log.Print(l)
+//		}
+//	}()
+//
+// Garbage collected resources are only found once their finalizers have
+// run, which may be some time after they become unreachable.
+func CheckLeaks() []Leak {
+	leaks.Lock()
+	records := leaks.collected
+	leaks.collected = nil
+	for _, r := range leaks.live {
+		records = append(records, r)
+	}
+	leaks.Unlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].seq < records[j].seq
+	})
+	found := make([]Leak, len(records))
+	for i, r := range records {
+		found[i] = Leak{Kind: r.kind, Collected: r.collected, Stack: r.stack()}
+	}
+	return found
+}
+
+func (r *leakRecord) stack() string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(r.pcs)
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		if !more {
+			return b.String()
+		}
+	}
+}
+
+// setFinalizer sets the finalizer of a resource, as runtime.SetFinalizer,
+// and tracks the resource for leak checking. A nil finalizer, set when the
+// resource is deleted, stops tracking it.
+func setFinalizer(obj, finalizer interface{}) {
+	runtime.SetFinalizer(obj, finalizer)
+	addr := reflect.ValueOf(obj).Pointer()
+	leaks.Lock()
+	defer leaks.Unlock()
+	if finalizer == nil {
+		delete(leaks.live, addr)
+		return
+	}
+	if !leaks.on {
+		return
+	}
+	pcs := make([]uintptr, 32)
+	// skip runtime.Callers and setFinalizer
+	pcs = pcs[:runtime.Callers(2, pcs)]
+	leaks.seq++
+	leaks.live[addr] = &leakRecord{
+		seq:  leaks.seq,
+		kind: fmt.Sprintf("%T", obj),
+		pcs:  pcs,
+	}
+}
+
+// collected records that a tracked resource was garbage collected without
+// being deleted. It is called by finalizers.
+func collected(obj interface{}) {
+	addr := reflect.ValueOf(obj).Pointer()
+	leaks.Lock()
+	if r, ok := leaks.live[addr]; ok {
+		delete(leaks.live, addr)
+		r.collected = true
+		leaks.collected = append(leaks.collected, r)
+	}
+	leaks.Unlock()
+}
@@ -0,0 +1,34 @@
+package gfx_test
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"strings"
+	"testing"
+)
+
+func TestLeakCheck(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+	gfx.SetLeakCheck(true)
+	defer gfx.SetLeakCheck(false)
+
+	deleted, err := gfx.NewSampler2D(4, 4, gfx.PixelRGBA8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := gfx.NewSampler2D(4, 4, gfx.PixelRGBA8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer kept.Delete()
+	deleted.Delete()
+
+	leaks := gfx.CheckLeaks()
+	if len(leaks) != 1 {
+		t.Fatalf("got %d leaks, want 1", len(leaks))
+	}
+	if l := leaks[0]; l.Kind != "*gfx.Sampler2D" || l.Collected || !strings.Contains(l.Stack, "TestLeakCheck") {
+		t.Errorf("got leak %v, want the kept sampler", l)
+	}
+}
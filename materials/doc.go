@@ -0,0 +1,26 @@
+/*
+Package materials implements metallic-roughness physically based materials
+with stock shaders. A Material holds the factors and textures of a surface,
+as glTF describes them, and a Library builds the shader variant each
+material needs and turns it into a scenes.Material, lit by the lights of
+the scene through scenes.Renderer.
+
+Typical use with the glTF loader is:
+
+	lib := materials.NewLibrary()
+	scene, err := doc.Load(materials.VertexFormat)
+	...
+	err = lib.AttachGLTF(scene)
+	...
+	renderer.Render(scene.Root, cam)
+
+Image-based lighting is baked from an equirectangular panorama by
+NewEnvironment, or step by step with EquirectToCube, Irradiance,
+PrefilterSpecular and BRDFLUT, and lights the materials made after it is
+set as the Environment of a Library.
+
+Colors are shaded in linear space. Base color and emissive textures should
+have sRGB formats, and the result be drawn into an sRGB or floating point
+target, to be displayed correctly.
+*/
+package materials
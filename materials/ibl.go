@@ -0,0 +1,376 @@
+package materials
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+)
+
+// SpecularLevels is the number of roughness levels of prefiltered specular
+// maps, from smooth at level 0 to fully rough at the last.
+const SpecularLevels = 5
+
+// Environment is the image-based lighting of a scene: the diffuse and
+// specular light arriving from every direction, filtered from an
+// environment map, and the lookup table of the split-sum approximation.
+// Materials made by a Library whose Environment is set are lit by it in
+// place of the ambient color of the scene.
+type Environment struct {
+	Irradiance *gfx.SamplerCube `uniform:"IrradianceMap"`
+	Specular   *gfx.SamplerCube `uniform:"SpecularMap"`
+	BRDF       *gfx.Sampler2D   `uniform:"BRDFLUT"`
+}
+
+// NewEnvironment bakes the image-based lighting of an equirectangular
+// panorama, converting it to a cube map with size by size faces, then
+// convolving that into a 32 by 32 irradiance map, a specular map of the
+// same size as the cube map, and a 128 by 128 BRDF lookup table. The
+// panorama is best given in a floating point format. The render
+// target must be bound again afterwards.
+func NewEnvironment(panorama *gfx.Sampler2D, size int) (*Environment, error) {
+	cube, err := EquirectToCube(panorama, size)
+	if err != nil {
+		return nil, err
+	}
+	defer cube.Delete()
+	e := &Environment{}
+	if e.Irradiance, err = Irradiance(cube, 32); err == nil {
+		if e.Specular, err = PrefilterSpecular(cube, size); err == nil {
+			e.BRDF, err = BRDFLUT(128)
+		}
+	}
+	if err != nil {
+		e.Delete()
+		return nil, err
+	}
+	return e, nil
+}
+
+// Delete frees the maps of the environment.
+func (e *Environment) Delete() {
+	for _, c := range []*gfx.SamplerCube{e.Irradiance, e.Specular} {
+		if c != nil {
+			c.Delete()
+		}
+	}
+	if e.BRDF != nil {
+		e.BRDF.Delete()
+	}
+	*e = Environment{}
+}
+
+// EquirectToCube renders an equirectangular panorama, with its top at t = 1
+// as images loaded with FlipY are, into the faces of a new mipmapped
+// PixelRGBA16F cube map with size by size faces. The render target must
+// be bound again afterwards.
+func EquirectToCube(panorama *gfx.Sampler2D, size int) (*gfx.SamplerCube, error) {
+	cube, err := gfx.NewSamplerCube(size, gfx.PixelRGBA16F, true)
+	if err != nil {
+		return nil, err
+	}
+	err = runPass(equirectShader, cube, 0, func(s *gfx.Shader) error {
+		return s.SetTexture("Panorama", panorama)
+	})
+	if err != nil {
+		cube.Delete()
+		return nil, err
+	}
+	cube.GenerateMipmaps()
+	return cube, nil
+}
+
+// Irradiance convolves the environment env into a new PixelRGBA16F cube map
+// of the light arriving at surfaces facing each direction, with size by
+// size faces. Irradiance varies slowly, so 32 suffices. The render
+// target must be bound again afterwards.
+func Irradiance(env *gfx.SamplerCube, size int) (*gfx.SamplerCube, error) {
+	cube, err := gfx.NewSamplerCube(size, gfx.PixelRGBA16F, false)
+	if err != nil {
+		return nil, err
+	}
+	err = runPass(irradianceShader, cube, 0, func(s *gfx.Shader) error {
+		return s.SetCubeTexture("Environment", env)
+	})
+	if err != nil {
+		cube.Delete()
+		return nil, err
+	}
+	return cube, nil
+}
+
+// PrefilterSpecular filters the mipmapped environment env into a new
+// PixelRGBA16F cube map with size by size faces, holding the reflection of
+// surfaces of increasing roughness in its first SpecularLevels levels. size
+// must be at least 1<<(SpecularLevels-1). The render target must be bound
+// again afterwards.
+func PrefilterSpecular(env *gfx.SamplerCube, size int) (*gfx.SamplerCube, error) {
+	if size < 1<<(SpecularLevels-1) {
+		return nil, fmt.Errorf("materials: %d is too small for %d specular levels", size, SpecularLevels)
+	}
+	cube, err := gfx.NewSamplerCube(size, gfx.PixelRGBA16F, true)
+	if err != nil {
+		return nil, err
+	}
+	for level := 0; level < SpecularLevels; level++ {
+		in := prefilterInputs{
+			Roughness:       float32(level) / (SpecularLevels - 1),
+			EnvironmentSize: float32(env.Size()),
+		}
+		err = runPass(prefilterShader, cube, level, func(s *gfx.Shader) error {
+			if err := s.SetCubeTexture("Environment", env); err != nil {
+				return err
+			}
+			return s.AssignUniforms(&in)
+		})
+		if err != nil {
+			cube.Delete()
+			return nil, err
+		}
+	}
+	return cube, nil
+}
+
+type prefilterInputs struct {
+	Roughness       float32 `uniform:"Roughness"`
+	EnvironmentSize float32 `uniform:"EnvironmentSize"`
+}
+
+// BRDFLUT bakes the scale and bias applied to F0 by the split-sum
+// approximation of the specular BRDF into the red and green channels of a
+// new size by size PixelRGBA16F texture, indexed by the cosine of the view
+// angle and the roughness. The render target must be bound again afterwards.
+func BRDFLUT(size int) (*gfx.Sampler2D, error) {
+	lut, err := gfx.NewSampler2D(size, size, gfx.PixelRGBA16F)
+	if err != nil {
+		return nil, err
+	}
+	fb, err := gfx.NewFramebuffer(lut)
+	if err != nil {
+		lut.Delete()
+		return nil, err
+	}
+	defer fb.Delete()
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, brdfShader)
+	if err != nil {
+		lut.Delete()
+		return nil, err
+	}
+	defer s.Delete()
+	fb.Bind()
+	passState.Apply()
+	s.Use()
+	if err := gfx.DrawFullscreen(s); err != nil {
+		lut.Delete()
+		return nil, err
+	}
+	return lut, nil
+}
+
+// passState draws filtering passes over whatever the target holds.
+var passState = gfx.State{
+	NoDepthTest:  true,
+	NoDepthWrite: true,
+	Cull:         gfx.CullNone,
+}
+
+type faceInput struct {
+	Face int `uniform:"Face"`
+}
+
+// runPass builds the fullscreen pass fs, which follows cubeFaceSource, and
+// draws it into each face of level of dst, after setup assigns its inputs.
+func runPass(fs gfx.FragmentShader, dst *gfx.SamplerCube, level int, setup func(*gfx.Shader) error) error {
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, cubeFaceSource+fs)
+	if err != nil {
+		return err
+	}
+	defer s.Delete()
+	passState.Apply()
+	for face := 0; face < gfx.CubeFaces; face++ {
+		fb, err := gfx.NewCubeFramebuffer(dst, face, level)
+		if err != nil {
+			return err
+		}
+		fb.Bind()
+		s.Use()
+		err = setup(s)
+		if err == nil {
+			err = s.AssignUniforms(&faceInput{face})
+		}
+		if err == nil {
+			err = gfx.DrawFullscreen(s)
+		}
+		fb.Delete()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cubeFaceSource gives the direction of each pixel of the face being drawn
+// into, from the fullscreen texture coordinates.
+const cubeFaceSource gfx.FragmentShader = `
+uniform int Face;
+
+varying vec2 TexCoord;
+
+const float PI = 3.14159265;
+
+vec3 faceDirection() {
+	vec2 p = TexCoord * 2.0 - 1.0;
+	if (Face == 0) {
+		return normalize(vec3(1.0, -p.y, -p.x));
+	} else if (Face == 1) {
+		return normalize(vec3(-1.0, -p.y, p.x));
+	} else if (Face == 2) {
+		return normalize(vec3(p.x, 1.0, p.y));
+	} else if (Face == 3) {
+		return normalize(vec3(p.x, -1.0, -p.y));
+	} else if (Face == 4) {
+		return normalize(vec3(p.x, -p.y, 1.0));
+	}
+	return normalize(vec3(-p.x, -p.y, -1.0));
+}
+
+// tangentFrame returns a basis with N as its z axis.
+mat3 tangentFrame(vec3 N) {
+	vec3 up = abs(N.y) < 0.999 ? vec3(0.0, 1.0, 0.0) : vec3(1.0, 0.0, 0.0);
+	vec3 right = normalize(cross(up, N));
+	return mat3(right, cross(N, right), N);
+}
+`
+
+const equirectShader gfx.FragmentShader = `
+uniform sampler2D Panorama;
+
+void main() {
+	vec3 d = faceDirection();
+	vec2 uv = vec2(atan(d.z, d.x) / (2.0 * PI) + 0.5, asin(clamp(d.y, -1.0, 1.0)) / PI + 0.5);
+	gl_FragColor = vec4(texture2D(Panorama, uv).rgb, 1.0);
+}`
+
+// irradianceShader integrates the cosine-weighted hemisphere around each
+// direction in fixed steps.
+const irradianceShader gfx.FragmentShader = `
+uniform samplerCube Environment;
+
+const float Step = 0.025;
+
+void main() {
+	vec3 N = faceDirection();
+	mat3 frame = tangentFrame(N);
+	vec3 sum = vec3(0.0);
+	float n = 0.0;
+	for (float phi = 0.0; phi < 2.0 * PI; phi += Step) {
+		for (float theta = 0.0; theta < 0.5 * PI; theta += Step) {
+			vec3 t = vec3(sin(theta) * cos(phi), sin(theta) * sin(phi), cos(theta));
+			sum += textureCube(Environment, frame * t).rgb * cos(theta) * sin(theta);
+			n += 1.0;
+		}
+	}
+	gl_FragColor = vec4(PI * sum / n, 1.0);
+}`
+
+// importanceSource samples the GGX distribution with the Hammersley
+// sequence, computing its radical inverse without integer bit operations.
+const importanceSource = `
+const int Samples = 256;
+
+float radicalInverse(int i) {
+	float r = 0.0;
+	float f = 0.5;
+	int n = i;
+	for (int b = 0; b < 16; b++) {
+		if (n == 0) {
+			break;
+		}
+		int q = n / 2;
+		r += float(n - q * 2) * f;
+		f *= 0.5;
+		n = q;
+	}
+	return r;
+}
+
+// importanceGGX returns a half vector around the z axis for sample i.
+vec3 importanceGGX(int i, float roughness) {
+	float a = roughness * roughness;
+	float u = float(i) / float(Samples);
+	float v = radicalInverse(i);
+	float phi = 2.0 * PI * u;
+	float cosTheta = sqrt((1.0 - v) / (1.0 + (a * a - 1.0) * v));
+	float sinTheta = sqrt(1.0 - cosTheta * cosTheta);
+	return vec3(sinTheta * cos(phi), sinTheta * sin(phi), cosTheta);
+}
+`
+
+// prefilterShader assumes the view direction is the normal, and samples
+// lower resolution levels of the environment for less likely directions to
+// avoid aliasing.
+const prefilterShader gfx.FragmentShader = `
+uniform samplerCube Environment;
+uniform float Roughness;
+uniform float EnvironmentSize;
+` + importanceSource + `
+void main() {
+	vec3 N = faceDirection();
+	mat3 frame = tangentFrame(N);
+	float a = Roughness * Roughness;
+	float texel = 4.0 * PI / (6.0 * EnvironmentSize * EnvironmentSize);
+	vec3 sum = vec3(0.0);
+	float weight = 0.0;
+	for (int i = 0; i < Samples; i++) {
+		vec3 H = frame * importanceGGX(i, Roughness);
+		vec3 L = 2.0 * dot(N, H) * H - N;
+		float NdotL = dot(N, L);
+		if (NdotL <= 0.0) {
+			continue;
+		}
+		float NdotH = max(dot(N, H), 0.0);
+		float d = NdotH * NdotH * (a * a - 1.0) + 1.0;
+		float pdf = a * a / (PI * d * d) / 4.0 + 0.0001;
+		float lod = 0.0;
+		if (Roughness > 0.0) {
+			lod = 0.5 * log2(1.0 / (float(Samples) * pdf * texel));
+		}
+		sum += textureCubeLod(Environment, L, max(lod, 0.0)).rgb * NdotL;
+		weight += NdotL;
+	}
+	gl_FragColor = vec4(sum / weight, 1.0);
+}`
+
+// brdfShader integrates the specular BRDF over the hemisphere, with the
+// geometry term remapped for image-based lighting.
+const brdfShader gfx.FragmentShader = `
+varying vec2 TexCoord;
+
+const float PI = 3.14159265;
+` + importanceSource + `
+float geometry(float NdotX, float k) {
+	return NdotX / (NdotX * (1.0 - k) + k);
+}
+
+void main() {
+	float NdotV = max(TexCoord.x, 0.001);
+	float roughness = TexCoord.y;
+	vec3 V = vec3(sqrt(1.0 - NdotV * NdotV), 0.0, NdotV);
+	float k = roughness * roughness / 2.0;
+	float scale = 0.0;
+	float bias = 0.0;
+	for (int i = 0; i < Samples; i++) {
+		vec3 H = importanceGGX(i, roughness);
+		vec3 L = 2.0 * dot(V, H) * H - V;
+		float NdotL = max(L.z, 0.0);
+		if (NdotL <= 0.0) {
+			continue;
+		}
+		float NdotH = max(H.z, 0.0);
+		float VdotH = max(dot(V, H), 0.0);
+		float g = geometry(NdotL, k) * geometry(NdotV, k);
+		float visibility = g * VdotH / max(NdotH * NdotV, 0.0001);
+		float fc = pow(1.0 - VdotH, 5.0);
+		scale += (1.0 - fc) * visibility;
+		bias += fc * visibility;
+	}
+	gl_FragColor = vec4(scale / float(Samples), bias / float(Samples), 0.0, 1.0);
+}`
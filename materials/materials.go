@@ -0,0 +1,297 @@
+package materials
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/scenes"
+	"j4k.co/gfx/scenes/gltf"
+	"strings"
+)
+
+// Features select the variant of the stock shaders a material is drawn
+// with, so that materials without a texture do not pay for sampling it.
+type Features uint8
+
+const (
+	BaseColorMap Features = 1 << iota
+	MetallicRoughnessMap
+	NormalMap
+	OcclusionMap
+	EmissiveMap
+	AlphaMask
+	ImageBasedLighting // lit by the Environment of the Library
+)
+
+// featureDefines are the macros defined in the shaders of each feature, in
+// order of their bits.
+var featureDefines = [...]string{
+	"BASE_COLOR_MAP",
+	"METALLIC_ROUGHNESS_MAP",
+	"NORMAL_MAP",
+	"OCCLUSION_MAP",
+	"EMISSIVE_MAP",
+	"ALPHA_MASK",
+	"IMAGE_BASED_LIGHTING",
+}
+
+func (f Features) String() string {
+	var names []string
+	for i, name := range featureDefines {
+		if f&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "0"
+	}
+	return strings.Join(names, "|")
+}
+
+// AlphaMode is how the alpha of the base color is treated.
+type AlphaMode uint8
+
+const (
+	Opaque AlphaMode = iota // alpha is ignored
+	Mask                    // fragments with alpha below AlphaCutoff are discarded
+	Blend                   // the surface is alpha blended
+)
+
+// Material is a metallic-roughness surface, with the parameters of a glTF
+// material. Each map is optional, and multiplies its factor where given.
+// Since a material is part of the uniforms of the scenes.Material made from
+// it, changes to its factors are seen by the next draw; changes to its maps
+// or modes need Library.Material to be called again.
+type Material struct {
+	BaseColor [4]float32 `uniform:"BaseColorFactor"`
+	// BaseColorMap holds the color in RGB and the alpha in A.
+	BaseColorMap *gfx.Sampler2D
+
+	Metallic  float32 `uniform:"MetallicFactor"`
+	Roughness float32 `uniform:"RoughnessFactor"`
+	// MetallicRoughnessMap holds the roughness in G and metallic in B.
+	MetallicRoughnessMap *gfx.Sampler2D
+
+	// NormalMap holds tangent space normals, and needs geometry with
+	// tangents. NormalScale scales their X and Y.
+	NormalMap   *gfx.Sampler2D
+	NormalScale float32 `uniform:"NormalScale"`
+
+	// OcclusionMap holds ambient occlusion in R, applied to the ambient
+	// light with OcclusionStrength.
+	OcclusionMap      *gfx.Sampler2D
+	OcclusionStrength float32 `uniform:"OcclusionStrength"`
+
+	Emissive    [3]float32 `uniform:"EmissiveFactor"`
+	EmissiveMap *gfx.Sampler2D
+
+	AlphaMode   AlphaMode
+	AlphaCutoff float32 `uniform:"AlphaCutoff"`
+
+	// DoubleSided materials are drawn without back face culling, and lit
+	// from either side.
+	DoubleSided bool
+}
+
+// New returns a white, rough dielectric material.
+func New() *Material {
+	return &Material{
+		BaseColor:         [4]float32{1, 1, 1, 1},
+		Roughness:         1,
+		NormalScale:       1,
+		OcclusionStrength: 1,
+		AlphaCutoff:       0.5,
+	}
+}
+
+// FromGLTF returns a material with the parameters and textures of a
+// material loaded from glTF.
+func FromGLTF(src *gltf.Material) *Material {
+	m := &Material{
+		BaseColor:            src.BaseColor,
+		BaseColorMap:         src.BaseColorTexture,
+		Metallic:             src.Metallic,
+		Roughness:            src.Roughness,
+		MetallicRoughnessMap: src.MetallicRoughnessTexture,
+		NormalMap:            src.NormalTexture,
+		NormalScale:          src.NormalScale,
+		OcclusionMap:         src.OcclusionTexture,
+		OcclusionStrength:    src.OcclusionStrength,
+		Emissive:             src.Emissive,
+		EmissiveMap:          src.EmissiveTexture,
+		AlphaCutoff:          src.AlphaCutoff,
+		DoubleSided:          src.DoubleSided,
+	}
+	switch src.AlphaMode {
+	case "MASK":
+		m.AlphaMode = Mask
+	case "BLEND":
+		m.AlphaMode = Blend
+	}
+	return m
+}
+
+// Features returns the shader features m needs, other than
+// ImageBasedLighting, which is given by the Library.
+func (m *Material) Features() Features {
+	var f Features
+	if m.BaseColorMap != nil {
+		f |= BaseColorMap
+	}
+	if m.MetallicRoughnessMap != nil {
+		f |= MetallicRoughnessMap
+	}
+	if m.NormalMap != nil {
+		f |= NormalMap
+	}
+	if m.OcclusionMap != nil {
+		f |= OcclusionMap
+	}
+	if m.EmissiveMap != nil {
+		f |= EmissiveMap
+	}
+	if m.AlphaMode == Mask {
+		f |= AlphaMask
+	}
+	return f
+}
+
+// textures returns the maps of m by sampler uniform name.
+func (m *Material) textures() map[string]*gfx.Sampler2D {
+	t := make(map[string]*gfx.Sampler2D)
+	for name, tex := range map[string]*gfx.Sampler2D{
+		"BaseColorMap":         m.BaseColorMap,
+		"MetallicRoughnessMap": m.MetallicRoughnessMap,
+		"NormalMap":            m.NormalMap,
+		"OcclusionMap":         m.OcclusionMap,
+		"EmissiveMap":          m.EmissiveMap,
+	} {
+		if tex != nil {
+			t[name] = tex
+		}
+	}
+	return t
+}
+
+// Library builds the variants of the stock shaders as materials need them,
+// and keeps them until it is deleted.
+type Library struct {
+	// Environment lights the materials made while it is set, in place of
+	// the ambient color of the scene.
+	Environment *Environment
+
+	shaders map[Features]*gfx.Shader
+}
+
+// NewLibrary returns a library without any shaders built.
+func NewLibrary() *Library {
+	return &Library{shaders: make(map[Features]*gfx.Shader)}
+}
+
+// Delete frees the shaders built by the library. Materials made by it can
+// no longer be drawn.
+func (l *Library) Delete() {
+	for f, s := range l.shaders {
+		s.Delete()
+		delete(l.shaders, f)
+	}
+}
+
+// Shader returns the variant of the stock shaders with features f,
+// building it the first time it is asked for, or the *gfx.ShaderError of
+// building it. It uses the attributes
+// named in VertexFormat, the matrices assigned by scenes.Renderer, and the
+// Lights block described by scenes.LightBinding.
+func (l *Library) Shader(f Features) (*gfx.Shader, error) {
+	if s := l.shaders[f]; s != nil {
+		return s, nil
+	}
+	defines := variant(f)
+	s, err := gfx.BuildShader(attrs,
+		gfx.VertexShader(defines+string(vertexShader)),
+		gfx.FragmentShader(defines+string(fragmentShader)))
+	if err != nil {
+		return nil, err
+	}
+	s.SetLabel("materials." + f.String())
+	l.shaders[f] = s
+	return s, nil
+}
+
+// variant returns the #define lines of the features f. The stock shaders
+// have no #version directive, so the lines can start them.
+func variant(f Features) string {
+	var b strings.Builder
+	for i, name := range featureDefines {
+		if f&(1<<uint(i)) != 0 {
+			fmt.Fprintf(&b, "#define %s 1\n", name)
+		}
+	}
+	if f&ImageBasedLighting != 0 {
+		fmt.Fprintf(&b, "#define SPECULAR_LEVELS %d.0\n", SpecularLevels)
+	}
+	return b.String()
+}
+
+// uniforms are the uniforms of materials made by a Library, with env nil
+// unless they are lit by it.
+type uniforms struct {
+	*Material
+	*Environment
+}
+
+// Material returns a lit scenes.Material drawing m with the shader variant
+// it needs, the uniforms of m and the library's Environment, and the maps of
+// m as textures. Blend materials are alpha blended, and double sided ones
+// are not culled.
+func (l *Library) Material(m *Material) (*scenes.Material, error) {
+	f := m.Features()
+	if l.Environment != nil {
+		f |= ImageBasedLighting
+	}
+	s, err := l.Shader(f)
+	if err != nil {
+		return nil, err
+	}
+	mat := &scenes.Material{
+		Shader:   s,
+		Uniforms: &uniforms{m, l.Environment},
+		Textures: m.textures(),
+		Lit:      true,
+	}
+	if m.AlphaMode == Blend {
+		mat.State.Blend = gfx.AlphaBlend
+		mat.State.NoDepthWrite = true
+	}
+	if m.DoubleSided {
+		mat.State.Cull = gfx.CullNone
+	}
+	return mat, nil
+}
+
+// AttachGLTF attaches a scenes.Mesh to each node of a loaded glTF scene for
+// each primitive of its gltf.Mesh, drawn with a material made from the
+// primitive's, so that scenes.Renderer draws the scene. Primitives sharing
+// a glTF material share the material made from it. The scene should be
+// loaded with VertexFormat.
+func (l *Library) AttachGLTF(scene *gltf.Scene) error {
+	made := make(map[*gltf.Material]*scenes.Material)
+	var err error
+	scene.Root.Walk(func(n *scenes.Node) bool {
+		var mesh *gltf.Mesh
+		if err != nil || !n.Component(&mesh) {
+			return true
+		}
+		for _, p := range mesh.Primitives {
+			mat := made[p.Material]
+			if mat == nil {
+				if mat, err = l.Material(FromGLTF(p.Material)); err != nil {
+					return false
+				}
+				made[p.Material] = mat
+			}
+			n.Attach(&scenes.Mesh{Geometry: p.Geometry, Material: mat})
+		}
+		return true
+	})
+	return err
+}
@@ -0,0 +1,151 @@
+package materials_test
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/geometry/shapes"
+	"j4k.co/gfx/gfxtest"
+	"j4k.co/gfx/materials"
+	"j4k.co/gfx/scenes"
+	"j4k.co/gfx/scenes/gltf"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFromGLTF(t *testing.T) {
+	tex := new(gfx.Sampler2D)
+	m := materials.FromGLTF(&gltf.Material{
+		BaseColor:        [4]float32{1, 0, 0, 1},
+		BaseColorTexture: tex,
+		Metallic:         1,
+		Roughness:        0.5,
+		NormalTexture:    tex,
+		NormalScale:      1,
+		AlphaMode:        "MASK",
+		AlphaCutoff:      0.25,
+		DoubleSided:      true,
+	})
+	if m.BaseColor != [4]float32{1, 0, 0, 1} || m.Roughness != 0.5 || m.AlphaCutoff != 0.25 || !m.DoubleSided {
+		t.Errorf("got %+v", m)
+	}
+	want := materials.BaseColorMap | materials.NormalMap | materials.AlphaMask
+	if f := m.Features(); f != want {
+		t.Errorf("got features %v, want %v", f, want)
+	}
+}
+
+func TestLibrary(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	lib := materials.NewLibrary()
+	defer lib.Delete()
+	plain, err := lib.Shader(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	textured, err := lib.Shader(materials.BaseColorMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := lib.Shader(materials.BaseColorMap); again != textured || textured == plain {
+		t.Error("variants are not built once per set of features")
+	}
+
+	tex, err := gfx.NewSampler2D(4, 4, gfx.PixelSRGBA8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tex.Delete()
+	m := materials.New()
+	m.BaseColorMap = tex
+	m.DoubleSided = true
+	mat, err := lib.Material(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mat.Shader != textured || mat.Textures["BaseColorMap"] != tex || !mat.Lit || mat.State.Cull != gfx.CullNone {
+		t.Errorf("got %+v", mat)
+	}
+
+	b := geometry.NewBuilder(materials.VertexFormat)
+	shapes.Cube(b, 1)
+	geom, err := gfx.NewGeometry(b, gfx.StaticDraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geom.Delete()
+	root := scenes.NewNode("root")
+	cube := scenes.NewNode("cube")
+	cube.SetPosition(0, 0, -5)
+	cube.Attach(&scenes.Mesh{Geometry: geom, Material: mat})
+	root.Add(cube)
+	root.Attach(&scenes.DirectionalLight{Color: [3]float32{1, 1, 1}, Intensity: 1})
+	cam := scenes.NewPerspective(math.Pi/2, 1, 0.1, 100)
+	root.Attach(cam)
+
+	renderer := scenes.NewRenderer()
+	defer renderer.Delete()
+	// factors are read from the material when drawn
+	m.BaseColor = [4]float32{0.5, 0.5, 0.5, 1}
+	if err := renderer.Render(root, cam); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rec.Draws()); n != 1 {
+		t.Errorf("got %d draws, want 1", n)
+	}
+	c, ok := rec.Uniform("BaseColorFactor")
+	if !ok || !reflect.DeepEqual(c.Args[len(c.Args)-1], []float32{0.5, 0.5, 0.5, 1}) {
+		t.Errorf("got base color %v", c)
+	}
+	if _, ok := rec.Uniform("BaseColorMap"); !ok {
+		t.Error("base color map was not assigned")
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	panorama, err := gfx.NewSampler2D(64, 32, gfx.PixelRGBA16F)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer panorama.Delete()
+	env, err := materials.NewEnvironment(panorama, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer env.Delete()
+	// six faces converted, six convolved, six per specular level, and the
+	// lookup table
+	want := 6 + 6 + 6*materials.SpecularLevels + 1
+	if n := len(rec.Draws()); n != want {
+		t.Errorf("got %d draws, want %d", n, want)
+	}
+	if env.Irradiance.Size() != 32 || env.Specular.Levels() < materials.SpecularLevels {
+		t.Errorf("got irradiance of %d and %d specular levels", env.Irradiance.Size(), env.Specular.Levels())
+	}
+	if _, err := materials.PrefilterSpecular(env.Irradiance, 8); err == nil {
+		t.Error("no error prefiltering too few levels")
+	}
+
+	lib := materials.NewLibrary()
+	defer lib.Delete()
+	lib.Environment = env
+	mat, err := lib.Material(materials.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Reset()
+	mat.Shader.Use()
+	if err := mat.Shader.AssignUniforms(mat.Uniforms); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"IrradianceMap", "SpecularMap", "BRDFLUT", "BaseColorFactor"} {
+		if _, ok := rec.Uniform(name); !ok {
+			t.Errorf("%s was not assigned", name)
+		}
+	}
+}
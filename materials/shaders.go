@@ -0,0 +1,205 @@
+package materials
+
+import (
+	"j4k.co/gfx"
+)
+
+// VertexFormat is the format of the geometry drawn with the stock shaders.
+// Tangents are only read by variants with a normal map.
+const VertexFormat = gfx.VertexPosition | gfx.VertexNormal | gfx.VertexTexcoord | gfx.VertexTangent
+
+var attrs = gfx.VertexAttributes{
+	gfx.VertexPosition: "Position",
+	gfx.VertexNormal:   "Normal",
+	gfx.VertexTexcoord: "Texcoord",
+	gfx.VertexTangent:  "Tangent",
+}
+
+const vertexShader gfx.VertexShader = `
+uniform mat4 WorldM;
+uniform mat4 ViewM;
+uniform mat4 ProjectionM;
+
+attribute vec3 Position;
+attribute vec3 Normal;
+attribute vec2 Texcoord;
+#ifdef NORMAL_MAP
+attribute vec3 Tangent;
+varying vec3 tangent;
+#endif
+
+varying vec3 worldPos;
+varying vec3 normal;
+varying vec2 texcoord;
+
+void main() {
+	vec4 world = WorldM * vec4(Position, 1.0);
+	worldPos = world.xyz;
+	normal = mat3(WorldM) * Normal;
+#ifdef NORMAL_MAP
+	tangent = mat3(WorldM) * Tangent;
+#endif
+	texcoord = Texcoord;
+	gl_Position = ProjectionM * ViewM * world;
+}`
+
+// fragmentShader shades with the GGX distribution, Smith-Schlick geometry
+// and Schlick Fresnel terms, and attenuates point and spot lights as
+// KHR_lights_punctual does.
+const fragmentShader gfx.FragmentShader = `
+struct LightSource {
+	vec4 Position;
+	vec4 Direction;
+	vec4 Color;
+	vec4 Cone;
+};
+layout(std140) uniform Lights {
+	vec4 Ambient;
+	int LightCount;
+	LightSource Light[8];
+};
+
+uniform mat4 ViewM;
+uniform vec4 BaseColorFactor;
+uniform float MetallicFactor;
+uniform float RoughnessFactor;
+uniform vec3 EmissiveFactor;
+#ifdef BASE_COLOR_MAP
+uniform sampler2D BaseColorMap;
+#endif
+#ifdef METALLIC_ROUGHNESS_MAP
+uniform sampler2D MetallicRoughnessMap;
+#endif
+#ifdef NORMAL_MAP
+uniform sampler2D NormalMap;
+uniform float NormalScale;
+varying vec3 tangent;
+#endif
+#ifdef OCCLUSION_MAP
+uniform sampler2D OcclusionMap;
+uniform float OcclusionStrength;
+#endif
+#ifdef EMISSIVE_MAP
+uniform sampler2D EmissiveMap;
+#endif
+#ifdef ALPHA_MASK
+uniform float AlphaCutoff;
+#endif
+#ifdef IMAGE_BASED_LIGHTING
+uniform samplerCube IrradianceMap;
+uniform samplerCube SpecularMap;
+uniform sampler2D BRDFLUT;
+#endif
+
+varying vec3 worldPos;
+varying vec3 normal;
+varying vec2 texcoord;
+
+const float PI = 3.14159265;
+
+vec3 fresnel(vec3 f0, float cosTheta) {
+	return f0 + (1.0 - f0) * pow(1.0 - cosTheta, 5.0);
+}
+
+float distribution(float NdotH, float alpha) {
+	float a2 = alpha * alpha;
+	float d = NdotH * NdotH * (a2 - 1.0) + 1.0;
+	return a2 / (PI * d * d);
+}
+
+float visibility(float NdotL, float NdotV, float roughness) {
+	float k = (roughness + 1.0) * (roughness + 1.0) / 8.0;
+	float g1 = NdotL / (NdotL * (1.0 - k) + k);
+	float g2 = NdotV / (NdotV * (1.0 - k) + k);
+	return g1 * g2 / max(4.0 * NdotL * NdotV, 0.0001);
+}
+
+void main() {
+	vec4 base = BaseColorFactor;
+#ifdef BASE_COLOR_MAP
+	base *= texture2D(BaseColorMap, texcoord);
+#endif
+#ifdef ALPHA_MASK
+	if (base.a < AlphaCutoff) {
+		discard;
+	}
+#endif
+	float metallic = MetallicFactor;
+	float roughness = RoughnessFactor;
+#ifdef METALLIC_ROUGHNESS_MAP
+	vec4 mr = texture2D(MetallicRoughnessMap, texcoord);
+	roughness *= mr.g;
+	metallic *= mr.b;
+#endif
+	roughness = clamp(roughness, 0.04, 1.0);
+
+	vec3 N = normalize(normal);
+#ifdef NORMAL_MAP
+	vec3 T = normalize(tangent - N * dot(N, tangent));
+	vec3 B = cross(N, T);
+	vec3 tn = texture2D(NormalMap, texcoord).xyz * 2.0 - 1.0;
+	tn.xy *= NormalScale;
+	N = normalize(mat3(T, B, N) * tn);
+#endif
+	if (!gl_FrontFacing) {
+		N = -N;
+	}
+	// the camera position, from the inverse of the rigid view matrix
+	vec3 eye = -(transpose(mat3(ViewM)) * ViewM[3].xyz);
+	vec3 V = normalize(eye - worldPos);
+	float NdotV = max(dot(N, V), 0.0001);
+
+	vec3 diffuse = base.rgb * (1.0 - metallic);
+	vec3 f0 = mix(vec3(0.04), base.rgb, metallic);
+	float alpha = roughness * roughness;
+
+	vec3 color = vec3(0.0);
+	for (int i = 0; i < LightCount; i++) {
+		LightSource light = Light[i];
+		vec3 L;
+		float attenuation = 1.0;
+		if (light.Position.w == 0.0) {
+			L = -light.Direction.xyz;
+		} else {
+			vec3 d = light.Position.xyz - worldPos;
+			float dist2 = max(dot(d, d), 0.0001);
+			L = d * inversesqrt(dist2);
+			attenuation = 1.0 / dist2;
+			if (light.Direction.w > 0.0) {
+				float r = sqrt(dist2) / light.Direction.w;
+				attenuation *= clamp(1.0 - r * r * r * r, 0.0, 1.0);
+			}
+			attenuation *= smoothstep(light.Cone.y, light.Cone.x, dot(-L, light.Direction.xyz));
+		}
+		float NdotL = dot(N, L);
+		if (NdotL <= 0.0 || attenuation <= 0.0) {
+			continue;
+		}
+		vec3 H = normalize(L + V);
+		vec3 F = fresnel(f0, max(dot(H, V), 0.0));
+		vec3 specular = F * distribution(max(dot(N, H), 0.0), alpha) * visibility(NdotL, NdotV, roughness);
+		color += ((1.0 - F) * diffuse / PI + specular) * light.Color.rgb * NdotL * attenuation;
+	}
+
+	float occlusion = 1.0;
+#ifdef OCCLUSION_MAP
+	occlusion = mix(1.0, texture2D(OcclusionMap, texcoord).r, OcclusionStrength);
+#endif
+#ifdef IMAGE_BASED_LIGHTING
+	// the split-sum approximation, with Fresnel at the view angle
+	vec3 Fv = f0 + (max(vec3(1.0 - roughness), f0) - f0) * pow(1.0 - NdotV, 5.0);
+	vec3 irradiance = textureCube(IrradianceMap, N).rgb;
+	vec3 R = reflect(-V, N);
+	vec3 prefiltered = textureCubeLod(SpecularMap, R, roughness * (SPECULAR_LEVELS - 1.0)).rgb;
+	vec2 brdf = texture2D(BRDFLUT, vec2(NdotV, roughness)).rg;
+	color += ((1.0 - Fv) * diffuse * irradiance + prefiltered * (Fv * brdf.x + brdf.y)) * occlusion;
+#else
+	color += Ambient.rgb * base.rgb * occlusion;
+#endif
+
+	vec3 emissive = EmissiveFactor;
+#ifdef EMISSIVE_MAP
+	emissive *= texture2D(EmissiveMap, texcoord).rgb;
+#endif
+	gl_FragColor = vec4(color + emissive, base.a);
+}`
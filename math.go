@@ -0,0 +1,231 @@
+package gfx
+
+import (
+	"math"
+)
+
+// Vec2, Vec3, and Vec4 are vectors, and can be assigned to uniforms of the
+// matching GLSL type directly.
+type (
+	Vec2 [2]float32
+	Vec3 [3]float32
+	Vec4 [4]float32
+)
+
+// Quat is a rotation quaternion of x, y, z, and w. Rotations must be of unit
+// length.
+type Quat [4]float32
+
+// Mat4 is a 4x4 matrix in column-major order, as GLSL expects. Its zero
+// value is not the identity; see Ident4.
+type Mat4 [16]float32
+
+func (v *Vec2) Pointer() *[2]float32  { return (*[2]float32)(v) }
+func (v *Vec3) Pointer() *[3]float32  { return (*[3]float32)(v) }
+func (v *Vec4) Pointer() *[4]float32  { return (*[4]float32)(v) }
+func (q *Quat) Pointer() *[4]float32  { return (*[4]float32)(q) }
+func (m *Mat4) Pointer() *[16]float32 { return (*[16]float32)(m) }
+
+func (v Vec2) Add(w Vec2) Vec2     { return Vec2{v[0] + w[0], v[1] + w[1]} }
+func (v Vec2) Sub(w Vec2) Vec2     { return Vec2{v[0] - w[0], v[1] - w[1]} }
+func (v Vec2) Mul(s float32) Vec2  { return Vec2{v[0] * s, v[1] * s} }
+func (v Vec2) Dot(w Vec2) float32  { return v[0]*w[0] + v[1]*w[1] }
+func (v Vec2) Len() float32        { return sqrt(v.Dot(v)) }
+func (v Vec2) Vec3(z float32) Vec3 { return Vec3{v[0], v[1], z} }
+func (v Vec3) Add(w Vec3) Vec3     { return Vec3{v[0] + w[0], v[1] + w[1], v[2] + w[2]} }
+func (v Vec3) Sub(w Vec3) Vec3     { return Vec3{v[0] - w[0], v[1] - w[1], v[2] - w[2]} }
+func (v Vec3) Mul(s float32) Vec3  { return Vec3{v[0] * s, v[1] * s, v[2] * s} }
+func (v Vec3) Dot(w Vec3) float32  { return v[0]*w[0] + v[1]*w[1] + v[2]*w[2] }
+func (v Vec3) Len() float32        { return sqrt(v.Dot(v)) }
+func (v Vec3) Vec4(w float32) Vec4 { return Vec4{v[0], v[1], v[2], w} }
+func (v Vec4) Add(w Vec4) Vec4     { return Vec4{v[0] + w[0], v[1] + w[1], v[2] + w[2], v[3] + w[3]} }
+func (v Vec4) Sub(w Vec4) Vec4     { return Vec4{v[0] - w[0], v[1] - w[1], v[2] - w[2], v[3] - w[3]} }
+func (v Vec4) Mul(s float32) Vec4  { return Vec4{v[0] * s, v[1] * s, v[2] * s, v[3] * s} }
+func (v Vec4) Dot(w Vec4) float32  { return v[0]*w[0] + v[1]*w[1] + v[2]*w[2] + v[3]*w[3] }
+func (v Vec4) Len() float32        { return sqrt(v.Dot(v)) }
+func (v Vec4) Vec3() Vec3          { return Vec3{v[0], v[1], v[2]} }
+
+// Cross returns the cross product v × w.
+func (v Vec3) Cross(w Vec3) Vec3 {
+	return Vec3{
+		v[1]*w[2] - v[2]*w[1],
+		v[2]*w[0] - v[0]*w[2],
+		v[0]*w[1] - v[1]*w[0],
+	}
+}
+
+// Normalize returns v scaled to unit length, or v if it has none.
+func (v Vec3) Normalize() Vec3 {
+	l := v.Len()
+	if l == 0 {
+		return v
+	}
+	return v.Mul(1 / l)
+}
+
+func sqrt(x float32) float32 {
+	return float32(math.Sqrt(float64(x)))
+}
+
+// Ident4 returns the identity matrix.
+func Ident4() Mat4 {
+	return Mat4{
+		1, 0, 0, 0,
+		0, 1, 0, 0,
+		0, 0, 1, 0,
+		0, 0, 0, 1,
+	}
+}
+
+// Mul returns m * n, which applies n first.
+func (m Mat4) Mul(n Mat4) Mat4 {
+	var r Mat4
+	for c := 0; c < 4; c++ {
+		for row := 0; row < 4; row++ {
+			var sum float32
+			for k := 0; k < 4; k++ {
+				sum += m[k*4+row] * n[c*4+k]
+			}
+			r[c*4+row] = sum
+		}
+	}
+	return r
+}
+
+// MulVec4 returns m * v.
+func (m Mat4) MulVec4(v Vec4) Vec4 {
+	var r Vec4
+	for row := 0; row < 4; row++ {
+		r[row] = m[row]*v[0] + m[4+row]*v[1] + m[8+row]*v[2] + m[12+row]*v[3]
+	}
+	return r
+}
+
+// Transform returns the point p transformed by m, divided by w.
+func (m Mat4) Transform(p Vec3) Vec3 {
+	v := m.MulVec4(p.Vec4(1))
+	if v[3] != 0 && v[3] != 1 {
+		return v.Vec3().Mul(1 / v[3])
+	}
+	return v.Vec3()
+}
+
+// Transpose returns m with its rows and columns swapped.
+func (m Mat4) Transpose() Mat4 {
+	var r Mat4
+	for c := 0; c < 4; c++ {
+		for row := 0; row < 4; row++ {
+			r[row*4+c] = m[c*4+row]
+		}
+	}
+	return r
+}
+
+// Translate returns the matrix translating by v.
+func Translate(v Vec3) Mat4 {
+	m := Ident4()
+	m[12], m[13], m[14] = v[0], v[1], v[2]
+	return m
+}
+
+// Scale returns the matrix scaling by v along each axis.
+func Scale(v Vec3) Mat4 {
+	return Mat4{
+		v[0], 0, 0, 0,
+		0, v[1], 0, 0,
+		0, 0, v[2], 0,
+		0, 0, 0, 1,
+	}
+}
+
+// Rotate returns the matrix rotating by angle radians counterclockwise about
+// axis, which must be of unit length.
+func Rotate(angle float32, axis Vec3) Mat4 {
+	return QuatRotate(angle, axis).Mat4()
+}
+
+// Perspective returns a perspective projection matrix with a vertical field
+// of view of fovy radians, mapping depths from near to far onto [-1, 1].
+func Perspective(fovy, aspect, near, far float32) Mat4 {
+	f := float32(1 / math.Tan(float64(fovy)/2))
+	return Mat4{
+		f / aspect, 0, 0, 0,
+		0, f, 0, 0,
+		0, 0, (far + near) / (near - far), -1,
+		0, 0, 2 * far * near / (near - far), 0,
+	}
+}
+
+// Ortho returns an orthographic projection matrix of the given volume.
+func Ortho(left, right, bottom, top, near, far float32) Mat4 {
+	return Mat4{
+		2 / (right - left), 0, 0, 0,
+		0, 2 / (top - bottom), 0, 0,
+		0, 0, -2 / (far - near), 0,
+		-(right + left) / (right - left), -(top + bottom) / (top - bottom), -(far + near) / (far - near), 1,
+	}
+}
+
+// LookAt returns a view matrix for an eye at eye looking at target, with up
+// giving the upward direction.
+func LookAt(eye, target, up Vec3) Mat4 {
+	f := target.Sub(eye).Normalize()
+	s := f.Cross(up).Normalize()
+	u := s.Cross(f)
+	return Mat4{
+		s[0], u[0], -f[0], 0,
+		s[1], u[1], -f[1], 0,
+		s[2], u[2], -f[2], 0,
+		-s.Dot(eye), -u.Dot(eye), f.Dot(eye), 1,
+	}
+}
+
+// QuatIdent returns the quaternion of no rotation.
+func QuatIdent() Quat {
+	return Quat{0, 0, 0, 1}
+}
+
+// QuatRotate returns the quaternion rotating by angle radians
+// counterclockwise about axis, which must be of unit length.
+func QuatRotate(angle float32, axis Vec3) Quat {
+	s := float32(math.Sin(float64(angle) / 2))
+	c := float32(math.Cos(float64(angle) / 2))
+	return Quat{axis[0] * s, axis[1] * s, axis[2] * s, c}
+}
+
+// Mul returns the product q * r, which rotates by r first.
+func (q Quat) Mul(r Quat) Quat {
+	return Quat{
+		q[3]*r[0] + q[0]*r[3] + q[1]*r[2] - q[2]*r[1],
+		q[3]*r[1] - q[0]*r[2] + q[1]*r[3] + q[2]*r[0],
+		q[3]*r[2] + q[0]*r[1] - q[1]*r[0] + q[2]*r[3],
+		q[3]*r[3] - q[0]*r[0] - q[1]*r[1] - q[2]*r[2],
+	}
+}
+
+// Normalize returns q scaled to unit length.
+func (q Quat) Normalize() Quat {
+	l := Vec4(q).Len()
+	if l == 0 {
+		return QuatIdent()
+	}
+	return Quat(Vec4(q).Mul(1 / l))
+}
+
+// Rotate returns v rotated by q.
+func (q Quat) Rotate(v Vec3) Vec3 {
+	u := Vec3{q[0], q[1], q[2]}
+	t := u.Cross(v).Mul(2)
+	return v.Add(t.Mul(q[3])).Add(u.Cross(t))
+}
+
+// Mat4 returns the rotation matrix of q.
+func (q Quat) Mat4() Mat4 {
+	x, y, z, w := q[0], q[1], q[2], q[3]
+	return Mat4{
+		1 - 2*(y*y+z*z), 2 * (x*y + z*w), 2 * (x*z - y*w), 0,
+		2 * (x*y - z*w), 1 - 2*(x*x+z*z), 2 * (y*z + x*w), 0,
+		2 * (x*z + y*w), 2 * (y*z - x*w), 1 - 2*(x*x+y*y), 0,
+		0, 0, 0, 1,
+	}
+}
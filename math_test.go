@@ -0,0 +1,71 @@
+package gfx_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+func TestMathUniforms(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, dataShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	s.SetStrictUniforms(true)
+	s.Use()
+
+	// the view puts the origin 5 in front of the eye
+	view := gfx.LookAt(gfx.Vec3{0, 0, 5}, gfx.Vec3{}, gfx.Vec3{0, 1, 0})
+	u := &struct {
+		A gfx.Vec2 `uniform:"A"`
+		P gfx.Vec3 `uniform:"P"`
+		Q gfx.Quat `uniform:"Q"`
+		M gfx.Mat4 `uniform:"M"`
+	}{
+		A: gfx.Vec2{3, 4}.Mul(0.5),
+		P: view.Transform(gfx.Vec3{}),
+		Q: gfx.QuatIdent(),
+		// scales, then maps [0, 2] onto [-1, 1]
+		M: gfx.Ortho(0, 2, 0, 2, -1, 1).Mul(gfx.Scale(gfx.Vec3{2, 2, 2})),
+	}
+	rec.Reset()
+	if err := s.AssignUniforms(u); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprint([]string{
+		`Uniform2fv("A", 1, [1.5 2])`,
+		`Uniform3fv("P", 1, [0 0 -5])`,
+		`Uniform4fv("Q", 1, [0 0 0 1])`,
+		`UniformMatrix4fv("M", 1, false, [2 0 0 0 0 2 0 0 0 0 -2 0 -1 -1 0 1])`,
+	})
+	if got := fmt.Sprint(rec.Ops("Uniform2fv", "Uniform3fv", "Uniform4fv", "UniformMatrix4fv")); got != want {
+		t.Errorf("got %v,\nwant %v", got, want)
+	}
+
+	// by name, as a value or a pointer
+	m := gfx.Translate(gfx.Vec3{1, 2, 3})
+	for _, v := range []interface{}{m, &m} {
+		rec.Reset()
+		if err := s.SetUniform("M", v); err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(rec.Ops("UniformMatrix4fv")); got != `[UniformMatrix4fv("M", 1, false, [1 0 0 0 0 1 0 0 0 0 1 0 1 2 3 1])]` {
+			t.Errorf("%T: got %v", v, got)
+		}
+	}
+
+	// strict mode knows the GLSL type of each
+	type mismatch struct {
+		P gfx.Vec4 `uniform:"P"`
+	}
+	want = "gfx: uniforms of gfx_test.mismatch do not match the shader: " +
+		"field P: gfx.Vec4 cannot be assigned to vec3 'P'"
+	if err := s.AssignUniforms(&mismatch{}); err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,333 @@
+/*
+Package particles simulates and draws particles on the GPU. The state of
+each particle lives in a vertex buffer, which an update shader advances
+each frame through transform feedback into a second buffer, ping-ponging
+between the two, so that hundreds of thousands of particles are simulated
+without reading them back or uploading them. A render pass then draws the
+particles of the current buffer as points.
+
+Typical use is:
+
+	sys, err := particles.New(200000, particles.UpdateShader)
+	...
+	sys.Emitter.Direction = [3]float32{0, 1, 0}
+	...
+	// each frame
+	err = sys.Update(dt)
+	...
+	st := gfx.State{Blend: gfx.Additive, NoDepthWrite: true}
+	st.Apply()
+	err = sys.Draw(viewProj)
+
+Transform feedback needs OpenGL 3.0 or OpenGL ES 3.0.
+*/
+package particles
+
+import (
+	"encoding/binary"
+	"j4k.co/gfx"
+	"math"
+)
+
+// VertexFormat is the format of the particle buffers: the position of each
+// particle in VertexPosition, its velocity in VertexNormal, and its age and
+// lifetime in seconds in VertexTexcoord.
+const VertexFormat = gfx.VertexPosition | gfx.VertexNormal | gfx.VertexTexcoord
+
+var attrs = gfx.VertexAttributes{
+	gfx.VertexPosition: "Position",
+	gfx.VertexNormal:   "Velocity",
+	gfx.VertexTexcoord: "Life",
+}
+
+// Varyings are the outputs an update shader writes the next state of a
+// particle to, in the order of VertexFormat.
+var Varyings = []string{"outPosition", "outVelocity", "outLife"}
+
+// UpdateShader is the stock update shader. Particles not yet born have a
+// negative age, and are born once it reaches zero. A particle whose age
+// reaches its lifetime is born again at the Emitter, with the remainder of
+// its age, so that particles are emitted at a steady rate. Its random
+// numbers are seeded by the time and the state of each particle, whose
+// ages differ from the start, so that it needs no more than GLSL 1.10.
+const UpdateShader gfx.VertexShader = `
+uniform float DeltaTime;
+uniform float Time;
+uniform vec3 EmitterPosition;
+uniform vec3 EmitterDirection;
+uniform float Spread;
+uniform vec2 Speed;
+uniform vec2 Lifetime;
+uniform vec3 Gravity;
+uniform float Drag;
+
+attribute vec3 Position;
+attribute vec3 Velocity;
+attribute vec2 Life;
+
+varying vec3 outPosition;
+varying vec3 outVelocity;
+varying vec2 outLife;
+
+float random(float seed) {
+	return fract(sin(seed * 12.9898) * 43758.5453);
+}
+
+void main() {
+	vec3 p = Position;
+	vec3 v = Velocity;
+	float age = Life.x + DeltaTime;
+	float life = Life.y;
+	if (age >= life) {
+		float seed = Time + 78.233 * age + dot(Position + Velocity, vec3(0.731, 0.179, 0.413));
+		// a direction in the cone of Spread around +Z, turned to
+		// EmitterDirection
+		float z = mix(cos(Spread), 1.0, random(seed));
+		float a = 6.2831853 * random(seed + 1.0);
+		vec3 d = vec3(sqrt(1.0 - z * z) * vec2(cos(a), sin(a)), z);
+		vec3 n = normalize(EmitterDirection);
+		vec3 t = abs(n.z) < 0.999 ? normalize(cross(vec3(0.0, 0.0, 1.0), n)) : vec3(1.0, 0.0, 0.0);
+		vec3 b = cross(n, t);
+		v = (t * d.x + b * d.y + n * d.z) * mix(Speed.x, Speed.y, random(seed + 2.0));
+		p = EmitterPosition;
+		age -= life;
+		life = mix(Lifetime.x, Lifetime.y, random(seed + 3.0));
+	} else if (age >= 0.0) {
+		v += Gravity * DeltaTime;
+		v *= max(1.0 - Drag * DeltaTime, 0.0);
+		p += v * DeltaTime;
+	}
+	outPosition = p;
+	outVelocity = v;
+	outLife = vec2(age, life);
+}`
+
+// discardShader completes the update program, since OpenGL ES links no
+// program without a fragment shader. Nothing is rasterized.
+const discardShader gfx.FragmentShader = `
+void main() {
+	gl_FragColor = vec4(0.0);
+}`
+
+const vertexShader gfx.VertexShader = `
+uniform mat4 ViewProjectionM;
+uniform float PointSize;
+uniform vec4 StartColor;
+uniform vec4 EndColor;
+
+attribute vec3 Position;
+attribute vec2 Life;
+
+varying vec4 color;
+
+void main() {
+	if (Life.x < 0.0 || Life.x >= Life.y) {
+		// not yet born
+		gl_Position = vec4(2.0, 2.0, 2.0, 1.0);
+		gl_PointSize = 0.0;
+		return;
+	}
+	color = mix(StartColor, EndColor, Life.x / Life.y);
+	gl_Position = ViewProjectionM * vec4(Position, 1.0);
+	gl_PointSize = PointSize / gl_Position.w;
+}`
+
+const fragmentShader gfx.FragmentShader = `
+varying vec4 color;
+
+void main() {
+	vec2 d = gl_PointCoord * 2.0 - 1.0;
+	float fade = 1.0 - dot(d, d);
+	if (fade <= 0.0) {
+		discard;
+	}
+	gl_FragColor = vec4(color.rgb, color.a * fade);
+}`
+
+// Emitter describes where the stock update shader emits particles, and how
+// they move afterwards.
+type Emitter struct {
+	Position  [3]float32 `uniform:"EmitterPosition"`
+	Direction [3]float32 `uniform:"EmitterDirection"`
+	// Spread is the half angle in radians of the cone around Direction
+	// that particles are emitted in.
+	Spread float32 `uniform:"Spread"`
+	// Speed and Lifetime are the ranges particles are given a random speed
+	// and lifetime in, in units per second and seconds.
+	Speed    [2]float32 `uniform:"Speed"`
+	Lifetime [2]float32 `uniform:"Lifetime"`
+	Gravity  [3]float32 `uniform:"Gravity"`
+	// Drag is the fraction of velocity lost per second.
+	Drag float32 `uniform:"Drag"`
+}
+
+// Look describes how Draw draws particles: as round points fading from
+// StartColor at birth to EndColor at the end of their life, Size pixels
+// across at a distance of one unit.
+type Look struct {
+	StartColor [4]float32 `uniform:"StartColor"`
+	EndColor   [4]float32 `uniform:"EndColor"`
+	Size       float32    `uniform:"PointSize"`
+}
+
+// System is a fixed number of particles advanced by an update shader.
+type System struct {
+	Emitter Emitter
+	Look    Look
+
+	// Uniforms holds the uniforms of a custom update shader, assigned by
+	// Update after those of the stock shader, if not nil.
+	Uniforms interface{}
+
+	update *gfx.Shader
+	render *gfx.Shader
+	state  [2]*gfx.Geometry
+	cur    int
+	time   float32
+}
+
+type updateUniforms struct {
+	*Emitter
+	DeltaTime float32 `uniform:"DeltaTime"`
+	Time      float32 `uniform:"Time"`
+}
+
+type drawUniforms struct {
+	*Look
+	ViewProjection [16]float32 `uniform:"ViewProjectionM"`
+}
+
+// New returns a system of count particles advanced by the update shader,
+// such as UpdateShader, which reads the attributes Position, Velocity and
+// Life, and writes the next state of each particle to Varyings. The
+// emitter points up and emits particles for one to two seconds.
+func New(count int, update gfx.VertexShader) (*System, error) {
+	s := &System{
+		Emitter: Emitter{
+			Direction: [3]float32{0, 1, 0},
+			Spread:    0.5,
+			Speed:     [2]float32{1, 2},
+			Lifetime:  [2]float32{1, 2},
+		},
+		Look: Look{
+			StartColor: [4]float32{1, 1, 1, 1},
+			EndColor:   [4]float32{1, 1, 1, 0},
+			Size:       32,
+		},
+	}
+	var err error
+	s.update, err = gfx.BuildFeedbackShader(attrs, Varyings, update, discardShader)
+	if err != nil {
+		return nil, err
+	}
+	s.update.SetLabel("particles.update")
+	s.render, err = gfx.BuildShader(attrs, vertexShader, fragmentShader)
+	if err != nil {
+		s.update.Delete()
+		return nil, err
+	}
+	s.render.SetLabel("particles.render")
+	for i := range s.state {
+		s.state[i], err = gfx.NewGeometry(s.seed(count), gfx.DynamicCopy)
+		if err != nil {
+			s.Delete()
+			return nil, err
+		}
+		s.state[i].Primitive = gfx.Points
+	}
+	return s, nil
+}
+
+// Delete frees the shaders and buffers of the system.
+func (s *System) Delete() {
+	s.update.Delete()
+	s.render.Delete()
+	for _, g := range s.state {
+		if g != nil {
+			g.Delete()
+		}
+	}
+}
+
+// Count returns the number of particles.
+func (s *System) Count() int {
+	return s.state[s.cur].VertexBuffer.Count()
+}
+
+// Reset returns every particle to before its birth, with births spread over
+// the longest lifetime of the Emitter.
+func (s *System) Reset() error {
+	s.time = 0
+	return s.seed(s.Count()).CopyVertices(&s.state[s.cur].VertexBuffer, gfx.DynamicCopy)
+}
+
+// Update advances the particles by dt seconds with one draw of the update
+// shader into the other buffer, which becomes the current one.
+func (s *System) Update(dt float32) error {
+	s.time += dt
+	src, dest := s.state[s.cur], s.state[1-s.cur]
+	s.update.Use()
+	if err := s.update.SetGeometry(src); err != nil {
+		return err
+	}
+	err := s.update.AssignUniforms(&updateUniforms{&s.Emitter, dt, s.time})
+	if err != nil {
+		return err
+	}
+	if s.Uniforms != nil {
+		if err := s.update.AssignUniforms(s.Uniforms); err != nil {
+			return err
+		}
+	}
+	if err := s.update.DrawFeedback(&dest.VertexBuffer); err != nil {
+		return err
+	}
+	s.cur = 1 - s.cur
+	return nil
+}
+
+// Draw draws the living particles as points with the view-projection matrix
+// viewProj. Depth testing and blending are left to the caller, who
+// typically draws particles after opaque geometry, blended additively
+// without writing depth.
+func (s *System) Draw(viewProj [16]float32) error {
+	s.render.Use()
+	if err := s.render.SetGeometry(s.state[s.cur]); err != nil {
+		return err
+	}
+	if err := s.render.AssignUniforms(&drawUniforms{&s.Look, viewProj}); err != nil {
+		return err
+	}
+	s.render.Draw()
+	return nil
+}
+
+// Geometry returns the current state of the particles, in VertexFormat, to
+// be drawn with a custom shader. It changes with each Update.
+func (s *System) Geometry() *gfx.Geometry {
+	return s.state[s.cur]
+}
+
+// seed is the state of particles before their birth, in VertexFormat.
+type seed struct {
+	count int
+	span  float32 // the ages of the particles are spread over span seconds
+}
+
+func (s *System) seed(count int) seed {
+	return seed{count, s.Emitter.Lifetime[1]}
+}
+
+func (d seed) VertexCount() int               { return d.count }
+func (d seed) VertexFormat() gfx.VertexFormat { return VertexFormat }
+
+func (d seed) CopyVertices(dest *gfx.VertexBuffer, usage gfx.Usage) error {
+	stride := VertexFormat.Stride()
+	buf := make([]byte, d.count*stride)
+	for i := 0; i < d.count; i++ {
+		// the age, after the position and velocity
+		age := -d.span * float32(i) / float32(d.count)
+		binary.LittleEndian.PutUint32(buf[i*stride+24:], math.Float32bits(age))
+	}
+	return dest.SetVertices(buf, usage)
+}
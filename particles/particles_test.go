@@ -0,0 +1,66 @@
+package particles_test
+
+import (
+	"fmt"
+	"j4k.co/gfx/gfxtest"
+	"j4k.co/gfx/particles"
+	"j4k.co/gfx/scenes"
+	"reflect"
+	"testing"
+)
+
+func TestSystem(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	const count = 100000
+	sys, err := particles.New(count, particles.UpdateShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sys.Delete()
+	varyings := rec.Ops("TransformFeedbackVaryings")
+	if len(varyings) != 1 || !reflect.DeepEqual(varyings[0].Args[1], particles.Varyings) {
+		t.Errorf("got varyings %v, want %v", varyings, particles.Varyings)
+	}
+
+	first := sys.Geometry()
+	rec.Reset()
+	if err := sys.Update(1.0 / 60); err != nil {
+		t.Fatal(err)
+	}
+	if sys.Geometry() == first {
+		t.Error("update did not swap buffers")
+	}
+	var ops []string
+	for _, c := range rec.Ops("Enable", "Disable", "BeginTransformFeedback", "DrawArrays", "EndTransformFeedback") {
+		ops = append(ops, fmt.Sprint(c.Op, c.Args))
+	}
+	// 0x0000 is POINTS
+	want := []string{
+		"Enable[RASTERIZER_DISCARD]",
+		"BeginTransformFeedback[0x0000]",
+		fmt.Sprintf("DrawArrays[0x0000 0 %d]", count),
+		"EndTransformFeedback[]",
+		"Disable[RASTERIZER_DISCARD]",
+	}
+	if !reflect.DeepEqual(ops, want) {
+		t.Errorf("got %v, want %v", ops, want)
+	}
+	if _, ok := rec.Uniform("DeltaTime"); !ok {
+		t.Error("DeltaTime was not assigned")
+	}
+
+	rec.Reset()
+	if err := sys.Draw(scenes.Identity); err != nil {
+		t.Fatal(err)
+	}
+	draws := rec.Draws()
+	// 0x0000 is POINTS
+	if len(draws) != 1 || draws[0].Args[0] != gfxtest.Enum(0x0000) || draws[0].Args[2] != count {
+		t.Errorf("got draws %v, want the particles drawn as points", draws)
+	}
+	if _, ok := rec.Uniform("StartColor"); !ok {
+		t.Error("StartColor was not assigned")
+	}
+}
@@ -0,0 +1,108 @@
+package gfx
+
+import (
+	"j4k.co/gfx/internal/gl"
+)
+
+// PickingFragmentShader writes the ObjectID uniform and the primitive index
+// of each fragment into an unsigned integer render target. It requires GLSL
+// 1.50, so vertex shaders linked with it must be compatible with that
+// version.
+const PickingFragmentShader FragmentShader = `#version 150
+uniform uint ObjectID;
+out uvec2 PickID;
+
+void main() {
+	PickID = uvec2(ObjectID, uint(gl_PrimitiveID) + 1u);
+}`
+
+// BuildPickingShader builds a picking variant of a shader from its vertex
+// shader and PickingFragmentShader.
+func BuildPickingShader(attrs VertexAttributes, vs VertexShader) (*Shader, error) {
+	return BuildShader(attrs, vs, PickingFragmentShader)
+}
+
+// Picker renders object and triangle IDs into an offscreen integer render
+// target and reads back the IDs under a given pixel, for exact selection.
+//
+// Typical use is to call Begin, draw pickable objects with a picking shader,
+// calling SetObject before each, then call End and Pick.
+type Picker struct {
+	fb    *Framebuffer
+	ids   *Sampler2D
+	depth *Sampler2D
+}
+
+// NewPicker allocates a picking target of the given size, which is usually
+// the size of the window.
+func NewPicker(width, height int) (*Picker, error) {
+	ids, err := NewSampler2D(width, height, PixelRG32UI)
+	if err != nil {
+		return nil, err
+	}
+	depth, err := NewSampler2D(width, height, PixelDepth24)
+	if err != nil {
+		ids.Delete()
+		return nil, err
+	}
+	fb, err := NewFramebuffer(ids, depth)
+	if err != nil {
+		ids.Delete()
+		depth.Delete()
+		return nil, err
+	}
+	return &Picker{
+		fb:    fb,
+		ids:   ids,
+		depth: depth,
+	}, nil
+}
+
+// Delete frees the picking target.
+func (p *Picker) Delete() {
+	p.fb.Delete()
+	p.ids.Delete()
+	p.depth.Delete()
+}
+
+// Begin binds the picking target, sets the viewport to its size, and clears
+// it. ID zero means no object. It applies the zero State so that IDs and
+// depth are written and depth is tested.
+func (p *Picker) Begin() {
+	var s State
+	s.Apply()
+	p.fb.Bind()
+	// integer attachments are cleared by value; glClear leaves them
+	// undefined
+	gl.ClearBufferuiv(gl.COLOR, 0, []uint32{0, 0, 0, 0})
+	gl.ClearBufferfv(gl.DEPTH, 0, []float32{1})
+}
+
+// SetObject sets the ObjectID uniform of a picking shader for subsequent
+// draws. The shader must be in use.
+func (p *Picker) SetObject(s *Shader, id uint32) {
+	s.uniformLocation("ObjectID").Uniform1ui(uint(id))
+}
+
+// End restores the default framebuffer.
+func (p *Picker) End() {
+	gl.Framebuffer(0).Bind()
+}
+
+// Pick reads back the object ID and triangle index at x, y, where the origin
+// is the top left corner. ok is false if no object covers the pixel.
+func (p *Picker) Pick(x, y int) (object uint32, triangle int, ok bool) {
+	w, h := p.fb.Size()
+	if x < 0 || y < 0 || x >= w || y >= h {
+		return 0, 0, false
+	}
+	var data [2]uint32
+	p.fb.fbo.Bind()
+	gl.ReadBuffer(gl.COLOR_ATTACHMENT0)
+	gl.ReadPixels(x, h-1-y, 1, 1, gl.RG_INTEGER, gl.UNSIGNED_INT, data[:])
+	gl.Framebuffer(0).Bind()
+	if data[1] == 0 {
+		return 0, 0, false
+	}
+	return data[0], int(data[1] - 1), true
+}
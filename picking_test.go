@@ -0,0 +1,40 @@
+package gfx_test
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+func TestPicker(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	p, err := gfx.NewPicker(64, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Delete()
+	st := gfx.State{NoDepthWrite: true, NoColorWrite: gfx.ColorRed}
+	st.Apply()
+	rec.Reset()
+	p.Begin()
+	for _, want := range []string{
+		"DepthMask(true)",
+		"ColorMask(true, true, true, true)",
+		"Viewport(0, 0, 64, 32)",
+		"ClearBufferuiv(COLOR, 0, [0 0 0 0])",
+		"ClearBufferfv(DEPTH, 0, [1])",
+	} {
+		found := false
+		for _, c := range rec.Calls {
+			found = found || c.String() == want
+		}
+		if !found {
+			t.Errorf("Begin did not call %s", want)
+		}
+	}
+	if clears := rec.Ops("Clear"); len(clears) != 0 {
+		t.Errorf("got %v, want the integer IDs cleared by value", clears)
+	}
+}
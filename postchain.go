@@ -0,0 +1,234 @@
+package gfx
+
+// PostPass is a post-processing effect of a PostChain. Its shader is built
+// from FullscreenVertexShader and a fragment shader reading the TexCoord
+// varying. The chain assigns whichever of these inputs the shader declares:
+//
+//	uniform sampler2D Color; // the output of the previous pass, or the scene
+//	uniform sampler2D Depth; // the depth of the scene
+//	uniform vec2 TexelSize;  // the size of a texel of Color
+type PostPass struct {
+	Shader *Shader
+
+	// Uniforms is an optional uniform struct (see Shader.AssignUniforms)
+	// assigned before the pass is drawn.
+	Uniforms interface{}
+
+	// Textures maps additional sampler uniform names to textures.
+	Textures map[string]*Sampler2D
+
+	// Disabled passes are skipped.
+	Disabled bool
+}
+
+// PostChain renders a scene into an offscreen target and runs it through a
+// chain of post-processing passes, ping-ponging between two color targets,
+// with the last pass drawing into the destination.
+//
+// Typical use is to call Begin, clear and draw the scene, then call Run,
+// or to add the same steps to a RenderGraph with AddPasses.
+type PostChain struct {
+	Passes []*PostPass
+
+	width, height int
+	format        PixelFormat
+
+	color [2]*Sampler2D
+	depth *Sampler2D
+
+	// scene renders into color[0] and depth, and fbs into color alone
+	scene *Framebuffer
+	fbs   [2]*Framebuffer
+
+	copy *Shader
+}
+
+// postState draws passes without depth testing or culling.
+var postState = State{
+	NoDepthTest:  true,
+	NoDepthWrite: true,
+	Cull:         CullNone,
+}
+
+// NewPostChain allocates the targets of a post-processing chain of the
+// given size, with color targets of the given format.
+func NewPostChain(width, height int, format PixelFormat, passes ...*PostPass) (*PostChain, error) {
+	c := &PostChain{
+		Passes: passes,
+		format: format,
+	}
+	if err := c.Resize(width, height); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Delete frees the targets of the chain. The passes' shaders and textures
+// are not deleted.
+func (c *PostChain) Delete() {
+	c.deleteTargets()
+	if c.copy != nil {
+		c.copy.Delete()
+		c.copy = nil
+	}
+}
+
+func (c *PostChain) deleteTargets() {
+	if c.scene == nil {
+		return
+	}
+	c.scene.Delete()
+	for i := range c.fbs {
+		c.fbs[i].Delete()
+		c.color[i].Delete()
+	}
+	c.depth.Delete()
+	c.scene = nil
+}
+
+// Size returns the width and height of the chain's targets.
+func (c *PostChain) Size() (width, height int) {
+	return c.width, c.height
+}
+
+// Resize reallocates the targets of the chain, such as when the window is
+// resized. It does nothing if the size is unchanged.
+func (c *PostChain) Resize(width, height int) error {
+	if c.scene != nil && width == c.width && height == c.height {
+		return nil
+	}
+	c.deleteTargets()
+	var err error
+	for i := range c.color {
+		c.color[i], err = NewSampler2D(width, height, c.format)
+		if err != nil {
+			c.deleteTargets()
+			return err
+		}
+		c.fbs[i], err = NewFramebuffer(c.color[i])
+		if err != nil {
+			c.deleteTargets()
+			return err
+		}
+	}
+	c.depth, err = NewSampler2D(width, height, PixelDepth24)
+	if err != nil {
+		c.deleteTargets()
+		return err
+	}
+	c.scene, err = NewFramebuffer(c.color[0], c.depth)
+	if err != nil {
+		c.deleteTargets()
+		return err
+	}
+	c.width, c.height = width, height
+	return nil
+}
+
+// Begin binds the scene target, for the caller to clear and draw into.
+func (c *PostChain) Begin() {
+	c.scene.Bind()
+}
+
+// Run draws the enabled passes in order, each reading the output of the
+// previous one, with the last drawing into dst. If dst is nil, it draws into
+// the default framebuffer with the viewport set to the chain's size. Without
+// enabled passes, the scene is copied to dst.
+func (c *PostChain) Run(dst *Framebuffer) error {
+	return c.run(func() { c.bindOutput(dst) })
+}
+
+// AddPasses adds two passes to g: one that draws the scene into the chain
+// with scene, which clears and draws as after Begin, and one that runs the
+// chain into out, as Run. Passes whose Textures are written by other passes
+// of g must declare them with Read on the returned post pass.
+func (c *PostChain) AddPasses(g *RenderGraph, out RenderTarget, scene func(*PassContext)) (scenePass, post *RenderPass) {
+	ts := g.adopt("post scene", c.scene)
+	scenePass = g.AddPass("post scene", scene).Write(ts[0]).Write(ts[1])
+	post = g.AddPass("post", func(pc *PassContext) {
+		if err := c.run(pc.Bind); err != nil {
+			pc.Fail(err)
+		}
+	}).Read(ts[0]).Read(ts[1]).Write(out)
+	return scenePass, post
+}
+
+// run runs the chain as Run, with bindOutput binding the destination.
+func (c *PostChain) run(bindOutput func()) error {
+	last := -1
+	for i, p := range c.Passes {
+		if !p.Disabled {
+			last = i
+		}
+	}
+	postState.Apply()
+	if last < 0 {
+		if c.copy == nil {
+			s, err := BuildShader(DefaultVertexAttributes, FullscreenVertexShader, CopyFragmentShader)
+			if err != nil {
+				return err
+			}
+			c.copy = s
+		}
+		bindOutput()
+		return c.draw(&PostPass{Shader: c.copy}, c.color[0])
+	}
+	in := 0
+	for i, p := range c.Passes[:last+1] {
+		if p.Disabled {
+			continue
+		}
+		if i == last {
+			bindOutput()
+		} else {
+			c.fbs[1-in].Bind()
+		}
+		if err := c.draw(p, c.color[in]); err != nil {
+			return err
+		}
+		in = 1 - in
+	}
+	return nil
+}
+
+func (c *PostChain) bindOutput(dst *Framebuffer) {
+	if dst != nil {
+		dst.Bind()
+	} else {
+		BindScreen(c.width, c.height)
+	}
+}
+
+// texelSize is the TexelSize input of passes.
+type texelSize struct {
+	TexelSize [2]float32 `uniform:"TexelSize"`
+}
+
+// draw draws p reading from color into the bound framebuffer.
+func (c *PostChain) draw(p *PostPass, color *Sampler2D) error {
+	s := p.Shader
+	s.Use()
+	if s.HasUniform("Color") {
+		s.SetTexture("Color", color)
+	}
+	if s.HasUniform("Depth") {
+		s.SetTexture("Depth", c.depth)
+	}
+	if s.HasUniform("TexelSize") {
+		ts := texelSize{[2]float32{1 / float32(c.width), 1 / float32(c.height)}}
+		if err := s.AssignUniforms(&ts); err != nil {
+			return err
+		}
+	}
+	if p.Uniforms != nil {
+		if err := s.AssignUniforms(p.Uniforms); err != nil {
+			return err
+		}
+	}
+	for name, tex := range p.Textures {
+		if err := s.SetTexture(name, tex); err != nil {
+			return err
+		}
+	}
+	return DrawFullscreen(s)
+}
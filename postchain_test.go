@@ -0,0 +1,81 @@
+package gfx_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+const blurShader gfx.FragmentShader = `
+uniform sampler2D Color;
+uniform sampler2D Depth;
+uniform vec2 TexelSize;
+
+varying vec2 TexCoord;
+
+void main() {
+	gl_FragColor = texture2D(Color, TexCoord + TexelSize) * texture2D(Depth, TexCoord).r;
+}`
+
+func TestPostChain(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, blurShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	rec.Reset()
+	c, err := gfx.NewPostChain(64, 32, gfx.PixelRGBA8,
+		&gfx.PostPass{Shader: s},
+		&gfx.PostPass{Shader: s, Disabled: true},
+		&gfx.PostPass{Shader: s},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Delete()
+	fbs := rec.Ops("GenFramebuffer")
+	rec.Reset()
+	if err := c.Run(nil); err != nil {
+		t.Fatal(err)
+	}
+	// each target stays on the texture unit it was created on: the color
+	// targets on 0 and 1, and the depth on 2
+	var got []string
+	for _, c := range rec.Ops("BindFramebuffer", "Uniform1i", "Uniform2fv", "DrawArrays") {
+		got = append(got, c.String())
+	}
+	draw := "DrawArrays(TRIANGLES, 0, 3)"
+	texel := `Uniform2fv("TexelSize", 1, [0.015625 0.03125])`
+	want := fmt.Sprint([]string{
+		fmt.Sprint("BindFramebuffer(FRAMEBUFFER, ", fbs[1].Args[0], ")"),
+		`Uniform1i("Color", 0)`, `Uniform1i("Depth", 2)`, texel, draw,
+		"BindFramebuffer(FRAMEBUFFER, 0)",
+		`Uniform1i("Color", 1)`, texel, draw,
+	})
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v,\nwant %v", got, want)
+	}
+
+	// without enabled passes the scene is copied
+	for _, p := range c.Passes {
+		p.Disabled = true
+	}
+	rec.Reset()
+	if err := c.Run(nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rec.Ops("CreateProgram")); n != 1 {
+		t.Errorf("built %d copy shaders, want 1", n)
+	}
+	got = nil
+	for _, c := range rec.Ops("BindFramebuffer", "Uniform1i", "DrawArrays") {
+		got = append(got, c.String())
+	}
+	if want := fmt.Sprint([]string{"BindFramebuffer(FRAMEBUFFER, 0)", `Uniform1i("Color", 0)`, draw}); fmt.Sprint(got) != want {
+		t.Errorf("got %v copying, want %v", got, want)
+	}
+}
@@ -0,0 +1,404 @@
+package gfx
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"j4k.co/gfx/internal/gl"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Preprocessor rewrites shader sources before they are compiled, so that
+// common code can be shared between shaders.
+type Preprocessor struct {
+	// Includes resolves #include "file" directives. Names are relative to
+	// the directory of the including file, or to the root for the sources
+	// given to BuildShader.
+	Includes fs.FS
+
+	// Defines are inserted as "#define name value" lines at the top of each
+	// source, after its #version directive.
+	Defines map[string]string
+
+	// Version, such as "150" or "330 core", is inserted as a #version
+	// directive in sources that have none.
+	Version string
+
+	// Profile selects the GLSL dialect that vertex and fragment sources are
+	// translated to. The default, ProfileAuto, picks it from the current
+	// context.
+	Profile GLSLProfile
+}
+
+// GLSLProfile is the dialect of GLSL the preprocessor targets.
+//
+// Sources written for GLSL 1.20 and earlier, using attribute, varying,
+// gl_FragColor, and texture2D, are translated to GLSL 3.30 core for core
+// profile contexts. Sources written for GLSL 1.30 and later, using in, out,
+// and texture, are translated to GLSL 1.20 for contexts that only support
+// that. The translation is textual and covers the common subset of the two;
+// it expects in and out qualifiers of globals to start their line, and
+// texture to be called with a sampler uniform.
+type GLSLProfile uint8
+
+const (
+	ProfileAuto   GLSLProfile = iota // detected from the current context
+	ProfileNone                      // sources are left as written
+	ProfileLegacy                    // GLSL 1.20
+	ProfileCore                      // GLSL 3.30 core
+)
+
+// ShaderPreprocessor is applied to every source given to BuildShader. Its
+// zero value leaves sources without #include directives unchanged, unless
+// they are written for a GLSL version the context does not support.
+var ShaderPreprocessor Preprocessor
+
+// Process returns the text of src with includes resolved, the version and
+// defines inserted, and the source translated to the GLSL profile.
+// ProfileAuto queries the current context, so it must be called with one.
+func (p *Preprocessor) Process(src ShaderSource) (string, error) {
+	text := src.source()
+	lines := strings.Split(text, "\n")
+	// the #version directive must come before anything else but comments
+	version := -1
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if strings.HasPrefix(line, "#version") {
+			version = i
+		}
+		break
+	}
+	srcVersion := 110 // the version of sources without a #version directive
+	if version >= 0 {
+		srcVersion = glslVersion(strings.TrimPrefix(strings.TrimSpace(lines[version]), "#version"))
+	} else if p.Version != "" {
+		srcVersion = glslVersion(p.Version)
+	}
+	profile := p.Profile
+	if profile == ProfileAuto {
+		profile = contextProfile()
+	}
+	typ := src.typ()
+	stage := typ == gl.VERTEX_SHADER || typ == gl.FRAGMENT_SHADER
+	toCore := stage && profile == ProfileCore && srcVersion < 130
+	toLegacy := stage && profile == ProfileLegacy && srcVersion >= 130
+	if !toCore && !toLegacy && len(p.Defines) == 0 && p.Version == "" && !strings.Contains(text, "#include") {
+		return text, nil
+	}
+	var buf bytes.Buffer
+	for _, line := range lines[:version+1] {
+		if strings.HasPrefix(strings.TrimSpace(line), "#version") {
+			continue
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	switch {
+	case toCore:
+		buf.WriteString("#version 330 core\n")
+	case toLegacy:
+		buf.WriteString("#version 120\n")
+	case version >= 0:
+		buf.WriteString(lines[version])
+		buf.WriteByte('\n')
+	case p.Version != "":
+		fmt.Fprintf(&buf, "#version %s\n", p.Version)
+	}
+	names := make([]string, 0, len(p.Defines))
+	for name := range p.Defines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&buf, "#define %s %s\n", name, p.Defines[name])
+	}
+	var body bytes.Buffer
+	if err := p.include(&body, lines[version+1:], ".", nil); err != nil {
+		return "", err
+	}
+	switch {
+	case toCore:
+		buf.WriteString(translateCore(body.String(), typ))
+	case toLegacy:
+		buf.WriteString(translateLegacy(body.String(), typ))
+	default:
+		buf.Write(body.Bytes())
+	}
+	return buf.String(), nil
+}
+
+// include writes lines to buf, replacing #include directives with the
+// contents of the files they name. stack holds the files being included,
+// to catch cycles.
+func (p *Preprocessor) include(buf *bytes.Buffer, lines []string, dir string, stack []string) error {
+	for _, line := range lines {
+		directive := strings.TrimSpace(line)
+		if !strings.HasPrefix(directive, "#include") {
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+			continue
+		}
+		file, err := strconv.Unquote(strings.TrimSpace(strings.TrimPrefix(directive, "#include")))
+		if err != nil {
+			return fmt.Errorf("gfx: malformed directive %q", directive)
+		}
+		if p.Includes == nil {
+			return fmt.Errorf("gfx: cannot include %q without an include file system", file)
+		}
+		name := path.Join(dir, file)
+		for _, s := range stack {
+			if s == name {
+				return fmt.Errorf("gfx: %q includes itself", name)
+			}
+		}
+		data, err := fs.ReadFile(p.Includes, name)
+		if err != nil {
+			return err
+		}
+		src := strings.TrimSuffix(string(data), "\n")
+		if err := p.include(buf, strings.Split(src, "\n"), path.Dir(name), append(stack, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// glslVersion parses a version such as "330 core" or, as reported by the
+// driver, "1.20 NVIDIA", returning 330 or 120. It returns 0 if there is no
+// version.
+func glslVersion(s string) int {
+	for _, f := range strings.Fields(s) {
+		major, minor, dotted := strings.Cut(f, ".")
+		n, err := strconv.Atoi(major)
+		if err != nil {
+			continue
+		}
+		if !dotted {
+			return n
+		}
+		if len(minor) > 2 {
+			minor = minor[:2]
+		}
+		m, _ := strconv.Atoi(minor)
+		if len(minor) == 1 {
+			m *= 10
+		}
+		return n*100 + m
+	}
+	return 0
+}
+
+// contextProfile returns the profile sources must be translated to for the
+// current context: ProfileCore for core profile contexts, ProfileLegacy for
+// contexts without GLSL 1.30, or ProfileNone.
+func contextProfile() GLSLProfile {
+	// GLSL ES is left as written
+	if detectCaps(); caps.es {
+		return ProfileNone
+	}
+	v := glslVersion(gl.GetString(gl.SHADING_LANGUAGE_VERSION))
+	if v != 0 && v < 130 {
+		return ProfileLegacy
+	}
+	// the profile mask was added with GLSL 1.50 in GL 3.2
+	if v >= 150 {
+		var mask [1]int32
+		gl.GetIntegerv(gl.CONTEXT_PROFILE_MASK, mask[:])
+		if mask[0]&gl.CONTEXT_CORE_PROFILE_BIT != 0 {
+			return ProfileCore
+		}
+	}
+	return ProfileNone
+}
+
+var (
+	legacyWordRe = regexp.MustCompile(`\b(attribute|varying|gl_FragColor|gl_FragData|texture2D|texture3D|textureCube|texture2DLod|textureCubeLod|texture2DProj)\b`)
+	coreQualRe   = regexp.MustCompile(`(?m)^(\s*)(?:layout\s*\([^)]*\)\s*)?(in|out)\b`)
+	coreOutRe    = regexp.MustCompile(`(?m)^\s*(?:layout\s*\([^)]*\)\s*)?out\s+vec4\s+(\w+)\s*;[^\n]*\n?`)
+	fragOutRe    = regexp.MustCompile(`(?m)^\s*(?:layout\s*\(\s*location\s*=\s*(\d+)\s*\)\s*)?out\s+vec4\s+(\w+)\s*;`)
+	samplerRe    = regexp.MustCompile(`\buniform\s+(?:(?:lowp|mediump|highp)\s+)?(sampler\w+)\s+(\w+)`)
+	textureRe    = regexp.MustCompile(`\btexture\s*\(\s*(\w+)`)
+)
+
+// legacyTextureFuncs are the texture functions of GLSL 1.20, with the
+// function of GLSL 3.30 each becomes and the parameters of its overloads.
+// Overloads taking a bias are only available to fragment shaders.
+var legacyTextureFuncs = map[string]struct {
+	to           string
+	params, bias []string
+}{
+	"texture2D":      {"texture", []string{"sampler2D s, vec2 p"}, []string{"sampler2D s, vec2 p, float bias"}},
+	"texture3D":      {"texture", []string{"sampler3D s, vec3 p"}, []string{"sampler3D s, vec3 p, float bias"}},
+	"textureCube":    {"texture", []string{"samplerCube s, vec3 p"}, []string{"samplerCube s, vec3 p, float bias"}},
+	"texture2DLod":   {"textureLod", []string{"sampler2D s, vec2 p, float lod"}, nil},
+	"textureCubeLod": {"textureLod", []string{"samplerCube s, vec3 p, float lod"}, nil},
+	"texture2DProj":  {"textureProj", []string{"sampler2D s, vec3 p", "sampler2D s, vec4 p"}, nil},
+}
+
+// textureNameRe matches a variable named texture, which hides the texture
+// function of GLSL 1.30 and later.
+var textureNameRe = regexp.MustCompile(`\btexture\b`)
+
+// translateCore rewrites the body of a GLSL 1.20 vertex or fragment shader
+// for GLSL 3.30 core. If a variable is named texture, the texture functions
+// are called through wrappers declared before it, rather than renaming it
+// and the uniform or attribute it may be.
+func translateCore(src string, typ gl.GLenum) string {
+	var fragColor, fragData bool
+	hidden := textureNameRe.MatchString(src)
+	var wrapped []string
+	src = legacyWordRe.ReplaceAllStringFunc(src, func(word string) string {
+		switch word {
+		case "attribute":
+			return "in"
+		case "varying":
+			if typ == gl.VERTEX_SHADER {
+				return "out"
+			}
+			return "in"
+		case "gl_FragColor":
+			fragColor = true
+			return "gfx_FragColor"
+		case "gl_FragData":
+			fragData = true
+			return "gfx_FragData"
+		}
+		if hidden {
+			wrapped = append(wrapped, word)
+			return "gfx_" + word
+		}
+		return legacyTextureFuncs[word].to
+	})
+	if len(wrapped) > 0 {
+		src = textureWrappers(wrapped, typ) + src
+	}
+	switch {
+	case fragColor:
+		src = "out vec4 gfx_FragColor;\n" + src
+	case fragData:
+		src = "out vec4 gfx_FragData[gl_MaxDrawBuffers];\n" + src
+	}
+	return src
+}
+
+// textureWrappers declares the legacy texture functions of names as
+// gfx_-prefixed functions calling those of GLSL 3.30.
+func textureWrappers(names []string, typ gl.GLenum) string {
+	sort.Strings(names)
+	var buf strings.Builder
+	for i, name := range names {
+		if i > 0 && name == names[i-1] {
+			continue
+		}
+		f := legacyTextureFuncs[name]
+		overloads := f.params
+		if typ == gl.FRAGMENT_SHADER {
+			overloads = append(overloads[:len(overloads):len(overloads)], f.bias...)
+		}
+		for _, params := range overloads {
+			var args []string
+			for _, param := range strings.Split(params, ", ") {
+				args = append(args, param[strings.LastIndexByte(param, ' ')+1:])
+			}
+			fmt.Fprintf(&buf, "vec4 gfx_%s(%s) { return %s(%s); }\n", name, params, f.to, strings.Join(args, ", "))
+		}
+	}
+	return buf.String()
+}
+
+// fragmentOutputs returns the vec4 outputs declared by the preprocessed
+// fragment shader src, indexed by draw buffer. Outputs without a location
+// take the buffer after the highest one assigned so far.
+func fragmentOutputs(src string) []string {
+	var outs []string
+	for _, m := range fragOutRe.FindAllStringSubmatch(src, -1) {
+		i := len(outs)
+		if m[1] != "" {
+			i, _ = strconv.Atoi(m[1])
+		}
+		for len(outs) <= i {
+			outs = append(outs, "")
+		}
+		outs[i] = m[2]
+	}
+	return outs
+}
+
+// translateLegacy rewrites the body of a GLSL 1.30 or later vertex or
+// fragment shader for GLSL 1.20.
+func translateLegacy(src string, typ gl.GLenum) string {
+	if typ == gl.FRAGMENT_SHADER {
+		outs := coreOutRe.FindAllStringSubmatch(src, -1)
+		src = coreOutRe.ReplaceAllString(src, "")
+		for i, out := range outs {
+			to := "gl_FragColor"
+			if len(outs) > 1 {
+				to = fmt.Sprintf("gl_FragData[%d]", i)
+			}
+			src = regexp.MustCompile(`\b`+out[1]+`\b`).ReplaceAllString(src, to)
+		}
+	}
+	src = translateQualifiers(src, typ)
+	samplers := make(map[string]string)
+	for _, m := range samplerRe.FindAllStringSubmatch(src, -1) {
+		samplers[m[2]] = m[1]
+	}
+	return textureRe.ReplaceAllStringFunc(src, func(call string) string {
+		fn := "texture2D"
+		switch samplers[textureRe.FindStringSubmatch(call)[1]] {
+		case "sampler3D":
+			fn = "texture3D"
+		case "samplerCube":
+			fn = "textureCube"
+		case "sampler2DShadow":
+			fn = "shadow2D"
+		}
+		return fn + strings.TrimPrefix(call, "texture")
+	})
+}
+
+// translateQualifiers rewrites the in and out qualifiers starting lines of
+// src as attribute or varying, except within parameter lists and blocks,
+// where they qualify function parameters.
+func translateQualifiers(src string, typ gl.GLenum) string {
+	var buf strings.Builder
+	depth, last := 0, 0
+	for _, m := range coreQualRe.FindAllStringSubmatchIndex(src, -1) {
+		depth += nesting(src[last:m[0]])
+		buf.WriteString(src[last:m[0]])
+		last = m[1]
+		if depth > 0 {
+			buf.WriteString(src[m[0]:m[1]])
+			continue
+		}
+		buf.WriteString(src[m[2]:m[3]])
+		if src[m[4]:m[5]] == "in" && typ == gl.VERTEX_SHADER {
+			buf.WriteString("attribute")
+		} else {
+			buf.WriteString("varying")
+		}
+	}
+	buf.WriteString(src[last:])
+	return buf.String()
+}
+
+// nesting returns the number of parentheses and braces s opens, less those
+// it closes, outside line comments.
+func nesting(s string) int {
+	n := 0
+	for _, line := range strings.Split(s, "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		n += strings.Count(line, "(") + strings.Count(line, "{")
+		n -= strings.Count(line, ")") + strings.Count(line, "}")
+	}
+	return n
+}
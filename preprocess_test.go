@@ -0,0 +1,155 @@
+package gfx
+
+import (
+	"j4k.co/gfx/internal/gl"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTranslateCore(t *testing.T) {
+	tests := []struct {
+		name      string
+		typ       gl.GLenum
+		src, want string
+	}{
+		{"vertex", gl.VERTEX_SHADER,
+			"attribute vec3 Position;\nvarying vec2 uv;\nuniform sampler2D heights;\nvoid main() {\n\tfloat h = texture2DLod(heights, uv, 0.0).r;\n}\n",
+			"in vec3 Position;\nout vec2 uv;\nuniform sampler2D heights;\nvoid main() {\n\tfloat h = textureLod(heights, uv, 0.0).r;\n}\n"},
+		{"fragment", gl.FRAGMENT_SHADER,
+			"varying vec2 uv;\nuniform samplerCube env;\nvoid main() {\n\tgl_FragColor = textureCube(env, vec3(uv, 1.0));\n}\n",
+			"out vec4 gfx_FragColor;\nin vec2 uv;\nuniform samplerCube env;\nvoid main() {\n\tgfx_FragColor = texture(env, vec3(uv, 1.0));\n}\n"},
+		{"fragment data", gl.FRAGMENT_SHADER,
+			"void main() {\n\tgl_FragData[0] = vec4(1.0);\n\tgl_FragData[1] = vec4(0.0);\n}\n",
+			"out vec4 gfx_FragData[gl_MaxDrawBuffers];\nvoid main() {\n\tgfx_FragData[0] = vec4(1.0);\n\tgfx_FragData[1] = vec4(0.0);\n}\n"},
+		{"uniform named texture", gl.FRAGMENT_SHADER,
+			"uniform sampler2D texture;\nvarying vec2 uv;\nvoid main() {\n\tgl_FragColor = texture2D(texture, uv) + texture2D(texture, uv, 1.0);\n}\n",
+			"out vec4 gfx_FragColor;\n" +
+				"vec4 gfx_texture2D(sampler2D s, vec2 p) { return texture(s, p); }\n" +
+				"vec4 gfx_texture2D(sampler2D s, vec2 p, float bias) { return texture(s, p, bias); }\n" +
+				"uniform sampler2D texture;\nin vec2 uv;\nvoid main() {\n\tgfx_FragColor = gfx_texture2D(texture, uv) + gfx_texture2D(texture, uv, 1.0);\n}\n"},
+		{"attribute named texture", gl.VERTEX_SHADER,
+			"attribute vec2 texture;\nuniform sampler2D heights;\nvoid main() {\n\tgl_Position = texture2DProj(heights, vec3(texture, 1.0));\n}\n",
+			"vec4 gfx_texture2DProj(sampler2D s, vec3 p) { return textureProj(s, p); }\n" +
+				"vec4 gfx_texture2DProj(sampler2D s, vec4 p) { return textureProj(s, p); }\n" +
+				"in vec2 texture;\nuniform sampler2D heights;\nvoid main() {\n\tgl_Position = gfx_texture2DProj(heights, vec3(texture, 1.0));\n}\n"},
+	}
+	for _, tt := range tests {
+		if got := translateCore(tt.src, tt.typ); got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTranslateLegacy(t *testing.T) {
+	tests := []struct {
+		name      string
+		typ       gl.GLenum
+		src, want string
+	}{
+		{"vertex", gl.VERTEX_SHADER,
+			"layout(location = 0) in vec3 Position;\nin vec2 Texcoord;\nout vec2 uv;\n",
+			"attribute vec3 Position;\nattribute vec2 Texcoord;\nvarying vec2 uv;\n"},
+		{"fragment", gl.FRAGMENT_SHADER,
+			"in vec2 uv;\nuniform sampler2D tex;\nout vec4 color;\nvoid main() {\n\tcolor = texture(tex, uv);\n}\n",
+			"varying vec2 uv;\nuniform sampler2D tex;\nvoid main() {\n\tgl_FragColor = texture2D(tex, uv);\n}\n"},
+		{"fragment outputs", gl.FRAGMENT_SHADER,
+			"layout(location = 0) out vec4 albedo;\nlayout(location = 1) out vec4 normal;\nvoid main() {\n\talbedo = vec4(1.0);\n\tnormal = vec4(0.0);\n}\n",
+			"void main() {\n\tgl_FragData[0] = vec4(1.0);\n\tgl_FragData[1] = vec4(0.0);\n}\n"},
+		{"samplers", gl.FRAGMENT_SHADER,
+			"uniform mediump sampler3D volume;\nuniform samplerCube env;\nuniform sampler2DShadow shadow;\nvoid main() {\n\tgl_FragColor = texture(volume, p) + texture(env, p) + texture(shadow, p);\n}\n",
+			"uniform mediump sampler3D volume;\nuniform samplerCube env;\nuniform sampler2DShadow shadow;\nvoid main() {\n\tgl_FragColor = texture3D(volume, p) + textureCube(env, p) + shadow2D(shadow, p);\n}\n"},
+		{"parameters", gl.VERTEX_SHADER,
+			"in vec3 Position;\nvoid split(\n\tin vec3 v, // (x, y\n\tout float x,\n\tout float y) {\n\tx = v.x;\n\ty = v.y;\n}\nout vec2 uv;\n",
+			"attribute vec3 Position;\nvoid split(\n\tin vec3 v, // (x, y\n\tout float x,\n\tout float y) {\n\tx = v.x;\n\ty = v.y;\n}\nvarying vec2 uv;\n"},
+	}
+	for _, tt := range tests {
+		if got := translateLegacy(tt.src, tt.typ); got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFragmentOutputs(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"void main() {}", nil},
+		{"out vec4 color;", []string{"color"}},
+		{"out vec4 a;\nlayout(location = 2) out vec4 b;\nout vec4 c;\n", []string{"a", "", "b", "c"}},
+		{"layout (location=1) out vec4 normal;\nlayout(location = 0) out vec4 albedo;\n", []string{"albedo", "normal"}},
+	}
+	for _, tt := range tests {
+		if got := fragmentOutputs(tt.src); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("fragmentOutputs(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestGLSLVersion(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"330 core", 330},
+		{" 100", 100},
+		{"1.20 NVIDIA via Cg compiler", 120},
+		{"4.60.0 NVIDIA 535.54", 460},
+		{"OpenGL ES GLSL ES 3.00", 300},
+		{"1.5", 150},
+	}
+	for _, tt := range tests {
+		if got := glslVersion(tt.s); got != tt.want {
+			t.Errorf("glslVersion(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestPreprocessor(t *testing.T) {
+	includes := fstest.MapFS{
+		"lib/light.glsl": {Data: []byte("#include \"math.glsl\"\nfloat light();\n")},
+		"lib/math.glsl":  {Data: []byte("const float PI = 3.14159;\n")},
+		"cycle.glsl":     {Data: []byte("#include \"cycle.glsl\"\n")},
+	}
+	tests := []struct {
+		name string
+		p    Preprocessor
+		src  ShaderSource
+		want string
+		err  string
+	}{
+		{"unchanged", Preprocessor{Profile: ProfileNone},
+			VertexShader("void main() {}"), "void main() {}", ""},
+		{"includes and defines", Preprocessor{Includes: includes, Defines: map[string]string{"B": "2", "A": "1"}, Profile: ProfileNone},
+			VertexShader("// light\n#version 330\n#include \"lib/light.glsl\"\nvoid main() {}"),
+			"// light\n#version 330\n#define A 1\n#define B 2\nconst float PI = 3.14159;\nfloat light();\nvoid main() {}\n", ""},
+		{"version", Preprocessor{Version: "150", Profile: ProfileNone},
+			FragmentShader("void main() {}"), "#version 150\nvoid main() {}\n", ""},
+		{"to core", Preprocessor{Profile: ProfileCore},
+			VertexShader("attribute vec3 Position;"), "#version 330 core\nin vec3 Position;\n", ""},
+		{"to legacy", Preprocessor{Profile: ProfileLegacy},
+			VertexShader("#version 330\nin vec3 Position;"), "#version 120\nattribute vec3 Position;\n", ""},
+		{"cycle", Preprocessor{Includes: includes, Profile: ProfileNone},
+			VertexShader("#include \"cycle.glsl\""), "", "includes itself"},
+		{"missing", Preprocessor{Includes: includes, Profile: ProfileNone},
+			VertexShader("#include \"lib/missing.glsl\""), "", "missing.glsl"},
+		{"no file system", Preprocessor{Profile: ProfileNone},
+			VertexShader("#include \"lib/math.glsl\""), "", "without an include file system"},
+		{"malformed", Preprocessor{Includes: includes, Profile: ProfileNone},
+			VertexShader("#include <math.glsl>"), "", "malformed"},
+	}
+	for _, tt := range tests {
+		got, err := tt.p.Process(tt.src)
+		switch {
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: got error %v, want one about %q", tt.name, err, tt.err)
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case got != tt.want:
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package gfx
+
+import (
+	"j4k.co/gfx/internal/gl"
+)
+
+// Primitive is the kind of primitive the vertices of geometry form.
+type Primitive uint8
+
+const (
+	Triangles Primitive = iota
+	TriangleStrip
+	TriangleFan
+	Lines
+	LineStrip
+	Points
+)
+
+func (p Primitive) gl() gl.GLenum {
+	switch p {
+	case TriangleStrip:
+		return gl.TRIANGLE_STRIP
+	case TriangleFan:
+		return gl.TRIANGLE_FAN
+	case Lines:
+		return gl.LINES
+	case LineStrip:
+		return gl.LINE_STRIP
+	case Points:
+		return gl.POINTS
+	default:
+		return gl.TRIANGLES
+	}
+}
+
+// RestartIndex16 and RestartIndex32 are the indices that end a strip or
+// fan and begin the next in geometry with PrimitiveRestart set, for 16-bit
+// and 32-bit indices, so that many strips are drawn with one call.
+const (
+	RestartIndex16 = 0xffff
+	RestartIndex32 = 0xffffffff
+)
+
+// restart is the primitive restart state of the context, as last set by
+// setPrimitiveRestart.
+var restart struct {
+	enabled bool
+	index   uint32
+}
+
+// setPrimitiveRestart enables or disables primitive restart for drawing
+// indices of type typ. OpenGL 4.3 and OpenGL ES 3.0 restart at the largest
+// index of each type; OpenGL 3.1 is given the index to restart at.
+// Without either, it does nothing.
+func setPrimitiveRestart(on bool, typ gl.GLenum) {
+	detectCaps()
+	if !caps.restart {
+		return
+	}
+	index := uint32(RestartIndex16)
+	if typ == gl.UNSIGNED_INT {
+		index = RestartIndex32
+	}
+	cap := gl.GLenum(gl.PRIMITIVE_RESTART)
+	if caps.restartFixed {
+		cap = gl.PRIMITIVE_RESTART_FIXED_INDEX
+	}
+	if on != restart.enabled {
+		if on {
+			gl.Enable(cap)
+		} else {
+			gl.Disable(cap)
+		}
+		restart.enabled = on
+	}
+	if on && !caps.restartFixed && index != restart.index {
+		gl.PrimitiveRestartIndex(index)
+		restart.index = index
+	}
+}
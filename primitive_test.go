@@ -0,0 +1,57 @@
+package gfx_test
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/gfxtest"
+	"reflect"
+	"testing"
+)
+
+func TestPrimitiveRestart(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+
+	// two quads as separate strips
+	b := geometry.NewBuilder(gfx.VertexPosition)
+	for i := 0; i < 8; i++ {
+		b.Position(float32(i/2), float32(i%2), 0)
+	}
+	b.Indices32(0, 1, 2, 3).Restart().Indices32(4, 5, 6, 7)
+	geom, err := gfx.NewGeometry(b, gfx.StaticDraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geom.Delete()
+	geom.Primitive = gfx.TriangleStrip
+	geom.PrimitiveRestart = true
+
+	rec.Reset()
+	s.Use()
+	if err := s.SetGeometry(geom); err != nil {
+		t.Fatal(err)
+	}
+	s.Draw()
+	if err := s.DrawSlices(geom.IndexBuffer.Slice(0, 4), geom.IndexBuffer.Slice(5, 9)); err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	for _, c := range rec.Ops("Enable", "PrimitiveRestartIndex", "DrawElements", "MultiDrawElements") {
+		calls = append(calls, c.String())
+	}
+	want := []string{
+		"Enable(PRIMITIVE_RESTART)",
+		"PrimitiveRestartIndex(65535)",
+		"DrawElements(TRIANGLE_STRIP, 9, UNSIGNED_SHORT, 0)",
+		"MultiDrawElements(TRIANGLE_STRIP, [4 4], UNSIGNED_SHORT, [0 10])",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("got calls %v, want %v", calls, want)
+	}
+}
@@ -0,0 +1,131 @@
+package gfx
+
+import (
+	"j4k.co/gfx/internal/gl"
+	"time"
+)
+
+// FrameStats counts the work submitted to GL during a frame, for finding
+// bottlenecks.
+type FrameStats struct {
+	DrawCalls int
+
+	// Triangles counts the triangles, or patches, of all draws, including
+	// each instance.
+	Triangles int
+
+	// BufferUploads counts writes to vertex, index, and uniform buffers, and
+	// UploadBytes their total size.
+	BufferUploads int
+	UploadBytes   int
+
+	TextureBinds int
+
+	// Timers holds the latest GPU time measured by each timer, by name.
+	// Results arrive a few frames after they are measured.
+	Timers map[string]time.Duration
+}
+
+var (
+	frameStats FrameStats
+	lastStats  FrameStats
+
+	// pendingTimers are timer queries waiting for results, in the order
+	// they were issued.
+	pendingTimers []timerQuery
+	freeQueries   []gl.Query
+	timerResults  = make(map[string]time.Duration)
+	timing        bool
+)
+
+type timerQuery struct {
+	name  string
+	query gl.Query
+}
+
+// Stats returns the counts of the last frame ended with EndFrame.
+func Stats() FrameStats {
+	return lastStats
+}
+
+// EndFrame finishes counting the current frame and collects the results of
+// timers that have become available. Call it once per frame, such as before
+// swapping buffers.
+func EndFrame() {
+	n := 0
+	for _, t := range pendingTimers {
+		var avail [1]uint32
+		t.query.GetObjectuiv(gl.QUERY_RESULT_AVAILABLE, avail[:])
+		if avail[0] == 0 {
+			// later queries cannot have finished either
+			break
+		}
+		var ns [1]uint64
+		t.query.GetObjectui64v(gl.QUERY_RESULT, ns[:])
+		timerResults[t.name] = time.Duration(ns[0])
+		freeQueries = append(freeQueries, t.query)
+		n++
+	}
+	pendingTimers = append(pendingTimers[:0], pendingTimers[n:]...)
+
+	lastStats = frameStats
+	lastStats.Timers = make(map[string]time.Duration, len(timerResults))
+	for name, d := range timerResults {
+		lastStats.Timers[name] = d
+	}
+	frameStats = FrameStats{}
+	checkError("EndFrame")
+}
+
+// BeginTimer starts measuring the GPU time taken by the commands issued
+// until EndTimer, reported in Stats under name. Timers cannot be nested.
+func BeginTimer(name string) {
+	if timing {
+		panic("gfx: BeginTimer called while a timer is running")
+	}
+	var q gl.Query
+	if n := len(freeQueries); n > 0 {
+		q, freeQueries = freeQueries[n-1], freeQueries[:n-1]
+	} else {
+		q = gl.GenQuery()
+	}
+	q.Begin(gl.TIME_ELAPSED)
+	pendingTimers = append(pendingTimers, timerQuery{name, q})
+	timing = true
+}
+
+// EndTimer stops the timer started by BeginTimer.
+func EndTimer() {
+	if !timing {
+		panic("gfx: EndTimer called without BeginTimer")
+	}
+	pendingTimers[len(pendingTimers)-1].query.End(gl.TIME_ELAPSED)
+	timing = false
+	checkError("EndTimer")
+}
+
+// countDraw counts a draw call of count vertices or indices. Strips and
+// fans are counted as one, ignoring restarts, and lines and points as no
+// triangles.
+func (s *Shader) countDraw(count, instances int) {
+	frameStats.DrawCalls++
+	units.draw++
+	tris := 0
+	switch {
+	case s.patchVertices > 0:
+		tris = count / s.patchVertices
+	case s.primitive == Triangles:
+		tris = count / 3
+	case s.primitive == TriangleStrip || s.primitive == TriangleFan:
+		if count > 2 {
+			tris = count - 2
+		}
+	}
+	frameStats.Triangles += tris * instances
+}
+
+// countUpload counts a buffer write of size bytes.
+func countUpload(size int) {
+	frameStats.BufferUploads++
+	frameStats.UploadBytes += size
+}
@@ -0,0 +1,88 @@
+package gfx_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	gfx.EndFrame()
+	geom := quad(t)
+	defer geom.Delete()
+	tex, err := gfx.NewSampler2D(4, 4, gfx.PixelRGBA8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tex.Delete()
+	s.Use()
+	if err := s.SetGeometry(geom); err != nil {
+		t.Fatal(err)
+	}
+	s.Draw()
+	s.DrawInstanced(3)
+	s.DrawRange(0, 3)
+	gfx.EndFrame()
+
+	// two triangles, three instances of them, and one; the geometry's
+	// vertices and indices; and binding the new texture
+	want := gfx.FrameStats{DrawCalls: 3, Triangles: 9, BufferUploads: 2, UploadBytes: 4*12 + 6*2, TextureBinds: 1}
+	got := gfx.Stats()
+	got.Timers = nil
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	gfx.EndFrame()
+	if got := gfx.Stats(); got.DrawCalls != 0 || got.BufferUploads != 0 {
+		t.Errorf("got %+v for an empty frame", got)
+	}
+}
+
+func TestTimers(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	rec.Reset()
+	gfx.BeginTimer("shadows")
+	gfx.EndTimer()
+	gfx.EndFrame()
+	gfx.BeginTimer("shadows")
+	gfx.EndTimer()
+	var got []string
+	for _, c := range rec.Ops("GenQuery", "BeginQuery", "EndQuery") {
+		got = append(got, c.String())
+	}
+	// the query is reused once its result is collected
+	q := rec.Ops("GenQuery")[0].Args[0]
+	want := fmt.Sprint([]string{
+		fmt.Sprintf("GenQuery(%v)", q),
+		fmt.Sprintf("BeginQuery(TIME_ELAPSED, %v)", q), "EndQuery(TIME_ELAPSED)",
+		fmt.Sprintf("BeginQuery(TIME_ELAPSED, %v)", q), "EndQuery(TIME_ELAPSED)",
+	})
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v,\nwant %v", got, want)
+	}
+	gfx.EndFrame()
+	if d, ok := gfx.Stats().Timers["shadows"]; !ok || d != time.Duration(0) {
+		t.Errorf("got timers %v, want the recorder's zero result for shadows", gfx.Stats().Timers)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("no panic for EndTimer without BeginTimer")
+			}
+		}()
+		gfx.EndTimer()
+	}()
+}
@@ -0,0 +1,144 @@
+package gfx
+
+import (
+	"fmt"
+	"image"
+	"sync"
+	"time"
+)
+
+// Queue holds functions to run on the GL thread, so that goroutines loading
+// assets can create textures and buffers. Functions are queued from any
+// goroutine and run in order by Flush, which the render loop calls, usually
+// once per frame. The zero value is an empty queue.
+type Queue struct {
+	mu    sync.Mutex
+	funcs []func()
+}
+
+// Future is the result of a function queued with Call, available once the
+// function has run.
+type Future struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// Done returns a channel that is closed once the result is available.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the result is available and returns it. Calling it on
+// the GL thread before the function has run blocks forever, since Flush
+// cannot run meanwhile.
+func (f *Future) Wait() (interface{}, error) {
+	<-f.done
+	return f.value, f.err
+}
+
+// Ready reports whether the result is available, in which case Wait
+// returns it without blocking.
+func (f *Future) Ready() bool {
+	select {
+	case <-f.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Do queues fn to run on the GL thread. It is safe to call from any
+// goroutine, including from functions run by Flush, which then run in the
+// next Flush.
+func (q *Queue) Do(fn func()) {
+	q.mu.Lock()
+	q.funcs = append(q.funcs, fn)
+	q.mu.Unlock()
+}
+
+// Call queues fn to run on the GL thread and returns the future of its
+// result. If fn panics, the panic is recovered as the error of the future.
+func (q *Queue) Call(fn func() (interface{}, error)) *Future {
+	f := &Future{done: make(chan struct{})}
+	q.Do(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				f.err = fmt.Errorf("gfx: queued call panicked: %v", r)
+			}
+			close(f.done)
+		}()
+		f.value, f.err = fn()
+	})
+	return f
+}
+
+// Image converts img to pixels on the calling goroutine and queues the
+// creation of a texture from them, as with the Image function. The value
+// of the future is a *Sampler2D.
+func (q *Queue) Image(img image.Image, opts *SamplerOptions) *Future {
+	var o SamplerOptions
+	if opts != nil {
+		o = *opts
+	}
+	pix, alpha := imagePixels(img, &o)
+	size := img.Bounds().Size()
+	return q.Call(func() (interface{}, error) {
+		if alpha {
+			return imageAlpha(pix, size.X, size.Y, &o)
+		}
+		return imageRGBA(pix, size.X, size.Y, &o)
+	})
+}
+
+// Geometry queues the creation of geometry from src, which must not be
+// changed until the future is done. The value of the future is a
+// *Geometry.
+func (q *Queue) Geometry(src VertexData, usage Usage) *Future {
+	return q.Call(func() (interface{}, error) {
+		return NewGeometry(src, usage)
+	})
+}
+
+// Len returns the number of functions waiting to run.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.funcs)
+}
+
+// Flush runs the queued functions in the order they were queued, and
+// returns how many ran. It must be called on the GL thread.
+func (q *Queue) Flush() int {
+	return q.FlushFor(0)
+}
+
+// FlushFor is like Flush, but stops after the function that brings the time
+// spent past budget, leaving the rest queued, to spread uploads over
+// several frames. A budget of 0 runs every function. If a function queued
+// with Do panics, the functions after it stay queued.
+func (q *Queue) FlushFor(budget time.Duration) int {
+	q.mu.Lock()
+	funcs := q.funcs
+	q.funcs = nil
+	q.mu.Unlock()
+
+	n := 0
+	defer func() {
+		if n < len(funcs) {
+			q.mu.Lock()
+			q.funcs = append(funcs[n:len(funcs):len(funcs)], q.funcs...)
+			q.mu.Unlock()
+		}
+	}()
+	start := time.Now()
+	for n < len(funcs) {
+		fn := funcs[n]
+		n++
+		fn()
+		if budget > 0 && time.Since(start) >= budget {
+			break
+		}
+	}
+	return n
+}
@@ -0,0 +1,38 @@
+package gfx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQueuePanic(t *testing.T) {
+	var q Queue
+	f := q.Call(func() (interface{}, error) {
+		panic("boom")
+	})
+	ran := false
+	g := q.Call(func() (interface{}, error) {
+		ran = true
+		return 1, nil
+	})
+	if n := q.Flush(); n != 2 || !ran {
+		t.Fatalf("ran %d functions, want both", n)
+	}
+	if _, err := f.Wait(); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("got error %v, want the panic", err)
+	}
+	if v, err := g.Wait(); v != 1 || err != nil {
+		t.Errorf("got %v, %v after the panic, want 1", v, err)
+	}
+
+	// a panic from Do leaves the functions after it queued
+	q.Do(func() { panic("boom") })
+	q.Do(func() {})
+	func() {
+		defer func() { recover() }()
+		q.Flush()
+	}()
+	if n := q.Len(); n != 1 {
+		t.Errorf("got %d functions queued after the panic, want 1", n)
+	}
+}
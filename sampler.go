@@ -1,66 +1,695 @@
 package gfx
 
 import (
-	"github.com/go-gl/gl"
+	"errors"
+	"fmt"
 	"image"
+	"image/draw"
+	"j4k.co/gfx/internal/gl"
+	"unsafe"
 )
 
+// PixelFormat describes the storage format of texture data.
+type PixelFormat uint8
+
+const (
+	PixelRGBA8 PixelFormat = iota
+	PixelR8
+	PixelDepth24
+	PixelRG32UI
+
+	// Floating point formats, for high dynamic range rendering and lookup
+	// tables. R11FG11FB10F packs unsigned red, green, and blue into 32 bits.
+	PixelRGBA16F
+	PixelRGBA32F
+	PixelR11FG11FB10F
+
+	// PixelSRGBA8 holds 8-bit color encoded in sRGB, which is converted to
+	// linear values when sampled, and from linear values when rendered into
+	// with State.FramebufferSRGB set.
+	PixelSRGBA8
+
+	// Block compressed formats, which store blocks of 4x4 pixels and are
+	// created with NewSampler2DLevels. BC1 through BC3 are also known as
+	// DXT1, DXT3, and DXT5; BC4 holds red and BC5 red and green.
+	PixelBC1
+	PixelBC2
+	PixelBC3
+	PixelBC4
+	PixelBC5
+	PixelBC7
+)
+
+// internalFormat gives the sized GL internal format, or the unsized one on
+// contexts without sized formats.
+func (f PixelFormat) internalFormat() int {
+	detectCaps()
+	if !caps.sizedFormats && !f.IsCompressed() {
+		return int(f.format())
+	}
+	switch f {
+	case PixelR8:
+		if !caps.textureRG {
+			return gl.LUMINANCE
+		}
+		return gl.R8
+	case PixelDepth24:
+		return gl.DEPTH_COMPONENT24
+	case PixelRG32UI:
+		return gl.RG32UI
+	case PixelRGBA16F:
+		return gl.RGBA16F
+	case PixelRGBA32F:
+		return gl.RGBA32F
+	case PixelR11FG11FB10F:
+		return gl.R11F_G11F_B10F
+	case PixelSRGBA8:
+		return gl.SRGB8_ALPHA8
+	case PixelBC1:
+		return gl.COMPRESSED_RGBA_S3TC_DXT1_EXT
+	case PixelBC2:
+		return gl.COMPRESSED_RGBA_S3TC_DXT3_EXT
+	case PixelBC3:
+		return gl.COMPRESSED_RGBA_S3TC_DXT5_EXT
+	case PixelBC4:
+		return gl.COMPRESSED_RED_RGTC1
+	case PixelBC5:
+		return gl.COMPRESSED_RG_RGTC2
+	case PixelBC7:
+		return gl.COMPRESSED_RGBA_BPTC_UNORM
+	default:
+		return gl.RGBA8
+	}
+}
+
+// format gives the GL format of client pixel data.
+func (f PixelFormat) format() gl.GLenum {
+	switch f {
+	case PixelR8:
+		if detectCaps(); !caps.textureRG {
+			return gl.LUMINANCE
+		}
+		return gl.RED
+	case PixelDepth24:
+		return gl.DEPTH_COMPONENT
+	case PixelRG32UI:
+		return gl.RG_INTEGER
+	case PixelR11FG11FB10F:
+		return gl.RGB
+	default:
+		return gl.RGBA
+	}
+}
+
+// typ gives the GL type of client pixel data.
+func (f PixelFormat) typ() gl.GLenum {
+	switch f {
+	case PixelDepth24, PixelRG32UI:
+		return gl.UNSIGNED_INT
+	case PixelRGBA16F, PixelRGBA32F, PixelR11FG11FB10F:
+		return gl.FLOAT
+	default:
+		return gl.UNSIGNED_BYTE
+	}
+}
+
+func (f PixelFormat) String() string {
+	switch f {
+	case PixelRGBA8:
+		return "RGBA8"
+	case PixelR8:
+		return "R8"
+	case PixelDepth24:
+		return "Depth24"
+	case PixelRG32UI:
+		return "RG32UI"
+	case PixelRGBA16F:
+		return "RGBA16F"
+	case PixelRGBA32F:
+		return "RGBA32F"
+	case PixelR11FG11FB10F:
+		return "R11FG11FB10F"
+	case PixelSRGBA8:
+		return "SRGBA8"
+	case PixelBC1:
+		return "BC1"
+	case PixelBC2:
+		return "BC2"
+	case PixelBC3:
+		return "BC3"
+	case PixelBC4:
+		return "BC4"
+	case PixelBC5:
+		return "BC5"
+	case PixelBC7:
+		return "BC7"
+	default:
+		return "unknown"
+	}
+}
+
+// IsDepth reports whether the format holds depth values.
+func (f PixelFormat) IsDepth() bool {
+	return f == PixelDepth24
+}
+
+// IsFloat reports whether the format holds floating point values, which
+// are uploaded from float32 data.
+func (f PixelFormat) IsFloat() bool {
+	return f == PixelRGBA16F || f == PixelRGBA32F || f == PixelR11FG11FB10F
+}
+
+// IsCompressed reports whether the format is block compressed.
+func (f PixelFormat) IsCompressed() bool {
+	return f >= PixelBC1 && f <= PixelBC7
+}
+
+// DataSize returns the size in bytes of the pixel data of a width by height
+// image, as given to NewSampler2DLevels or SetPixels. Floating point formats
+// take float32 components.
+func (f PixelFormat) DataSize(width, height int) int {
+	if f.IsCompressed() {
+		blocks := ((width + 3) / 4) * ((height + 3) / 4)
+		if f == PixelBC1 || f == PixelBC4 {
+			return blocks * 8
+		}
+		return blocks * 16
+	}
+	size := f.channels()
+	switch {
+	case f.IsFloat(), f == PixelRG32UI, f == PixelDepth24:
+		size *= 4
+	}
+	return width * height * size
+}
+
+// channels gives the number of components of each pixel of client data.
+func (f PixelFormat) channels() int {
+	switch f {
+	case PixelR8, PixelDepth24:
+		return 1
+	case PixelRG32UI:
+		return 2
+	case PixelR11FG11FB10F:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// IsInteger reports whether the format holds unnormalized integers, which
+// can only be sampled with nearest filtering.
+func (f PixelFormat) IsInteger() bool {
+	return f == PixelRG32UI
+}
+
+// Filter selects how texels are sampled when a texture is minified or
+// magnified.
+type Filter uint8
+
+const (
+	FilterLinear Filter = iota
+	FilterNearest
+)
+
+// Wrap selects how texture coordinates outside [0, 1] are handled.
+type Wrap uint8
+
+const (
+	WrapRepeat Wrap = iota
+	WrapClamp
+	WrapMirror
+)
+
+func (w Wrap) gl() int {
+	switch w {
+	case WrapClamp:
+		return gl.CLAMP_TO_EDGE
+	case WrapMirror:
+		return gl.MIRRORED_REPEAT
+	default:
+		return gl.REPEAT
+	}
+}
+
+// SamplerOptions configures how a texture is sampled. The zero value gives
+// linear filtering, repeating coordinates, and no mipmaps.
+type SamplerOptions struct {
+	MinFilter Filter
+	MagFilter Filter
+	WrapS     Wrap
+	WrapT     Wrap
+
+	// Mipmaps generates a mipmap chain for the texture and uses it when
+	// minifying.
+	Mipmaps bool
+
+	// Anisotropy sets the maximum degree of anisotropic filtering. Values of
+	// 1 or less disable it. It is limited to Caps().MaxAnisotropy, and
+	// ignored without EXT_texture_filter_anisotropic.
+	Anisotropy float32
+
+	// Compare makes a depth texture sampled through a sampler2DShadow return
+	// the result of comparing the reference depth with the stored depth using
+	// CompareFunc, which is usually CompareLessEqual. With linear filtering
+	// the results of neighboring texels are blended.
+	Compare     bool
+	CompareFunc CompareFunc
+
+	// SRGB stores color images given to Image as PixelSRGBA8, for images
+	// whose colors are sRGB encoded, as most are. It has no effect on
+	// existing textures.
+	SRGB bool
+
+	// PremultiplyAlpha multiplies the colors of images given to Image by
+	// their alpha, for drawing with the PremultipliedAlpha blend mode.
+	// *image.RGBA images are already premultiplied.
+	PremultiplyAlpha bool
+
+	// FlipY flips images given to Image vertically, so that the bottom row
+	// of the image is at texture coordinate t = 0, where GL puts the origin.
+	FlipY bool
+}
+
 type Sampler2D struct {
-	tex gl.Texture
+	tex     gl.Texture
+	width   int
+	height  int
+	format  PixelFormat
+	mipmaps bool
 }
 
-// Image takes an image and returns a 2D Sampler. Currently only takes
-// *image.NRGBA, *image.RGBA, *image.Alpha, and *image.Gray. No processing is
-// done on the image data, such as premultiplying alpha or linearization.
-func Image(img image.Image) (*Sampler2D, error) {
+// Image takes an image and returns a 2D Sampler. *image.Alpha, *image.Gray,
+// and *image.Gray16 images give single channel PixelR8 textures, keeping
+// the high byte of 16-bit gray. Other images give PixelRGBA8 textures;
+// *image.NRGBA and *image.RGBA are uploaded as they are, and any other type,
+// such as *image.Paletted or *image.YCbCr, is converted to NRGBA first.
+// Alpha is premultiplied and the image flipped only as asked by opts, and
+// no other processing is done, such as linearization. If opts is nil, the
+// zero SamplerOptions are used.
+func Image(img image.Image, opts *SamplerOptions) (*Sampler2D, error) {
+	var o SamplerOptions
+	if opts != nil {
+		o = *opts
+	}
+	pix, alpha := imagePixels(img, &o)
+	size := img.Bounds().Size()
+	if alpha {
+		return imageAlpha(pix, size.X, size.Y, &o)
+	}
+	return imageRGBA(pix, size.X, size.Y, &o)
+}
+
+// imagePixels converts img to the pixels Image uploads, and reports whether
+// they are single channel. It makes no GL calls.
+func imagePixels(img image.Image, o *SamplerOptions) (pix []byte, alpha bool) {
 	switch img.(type) {
+	case *image.Alpha, *image.Gray, *image.Gray16:
+		pix, alpha = alphaPixels(img), true
+	default:
+		if o.PremultiplyAlpha {
+			pix = premultipliedPixels(img)
+		} else {
+			pix = rgbaPixels(img)
+		}
+	}
+	if rows := img.Bounds().Dy(); o.FlipY && rows > 0 {
+		flipped := make([]byte, len(pix))
+		flipRows(flipped, pix, len(pix)/rows, rows)
+		pix = flipped
+	}
+	return pix, alpha
+}
+
+// FromPixels creates a texture from pix, which holds tightly packed rows of
+// pixel data of the given format, DataSize bytes in all. It is an escape
+// hatch for image data decoded by other means.
+func FromPixels(pix []byte, width, height int, format PixelFormat, opts *SamplerOptions) (*Sampler2D, error) {
+	if n := format.DataSize(width, height); len(pix) != n {
+		return nil, fmt.Errorf("gfx: %d bytes given for %dx%d %v texture, want %d", len(pix), width, height, format, n)
+	}
+	if format.IsCompressed() {
+		return NewSampler2DLevels([][]byte{pix}, width, height, format, opts)
+	}
+	return newSampler2D(pix, width, height, format, opts)
+}
+
+// rgbaPixels returns the pixels of img as tightly packed 8-bit RGBA,
+// converting them to NRGBA if img is not already NRGBA or RGBA.
+func rgbaPixels(img image.Image) []byte {
+	b := img.Bounds()
+	switch img := img.(type) {
 	case *image.NRGBA:
-		nrgba := img.(*image.NRGBA)
-		size := nrgba.Rect.Size()
-		return imageRGBA(nrgba.Pix, size.X, size.Y)
+		return packRows(img.Pix[img.PixOffset(b.Min.X, b.Min.Y):], img.Stride, 4*b.Dx(), b.Dy())
 	case *image.RGBA:
-		rgba := img.(*image.RGBA)
-		size := rgba.Rect.Size()
-		return imageRGBA(rgba.Pix, size.X, size.Y)
+		return packRows(img.Pix[img.PixOffset(b.Min.X, b.Min.Y):], img.Stride, 4*b.Dx(), b.Dy())
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Rect, img, b.Min, draw.Src)
+	return dst.Pix
+}
+
+// premultipliedPixels is like rgbaPixels, but converts img to RGBA, whose
+// colors are premultiplied by alpha.
+func premultipliedPixels(img image.Image) []byte {
+	if _, ok := img.(*image.RGBA); ok {
+		return rgbaPixels(img)
+	}
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Rect, img, b.Min, draw.Src)
+	return dst.Pix
+}
+
+// alphaPixels returns the pixels of img as tightly packed 8-bit values,
+// converting them to alpha if img is not already Alpha or Gray.
+func alphaPixels(img image.Image) []byte {
+	b := img.Bounds()
+	switch img := img.(type) {
 	case *image.Alpha:
-		alpha := img.(*image.Alpha)
-		size := alpha.Rect.Size()
-		return imageAlpha(alpha.Pix, size.X, size.Y)
+		return packRows(img.Pix[img.PixOffset(b.Min.X, b.Min.Y):], img.Stride, b.Dx(), b.Dy())
 	case *image.Gray:
-		gray := img.(*image.Gray)
-		size := gray.Rect.Size()
-		return imageAlpha(gray.Pix, size.X, size.Y)
-	default:
-		return nil, image.ErrFormat
+		return packRows(img.Pix[img.PixOffset(b.Min.X, b.Min.Y):], img.Stride, b.Dx(), b.Dy())
+	case *image.Gray16:
+		dst := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(dst, dst.Rect, img, b.Min, draw.Src)
+		return dst.Pix
+	}
+	dst := image.NewAlpha(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Rect, img, b.Min, draw.Src)
+	return dst.Pix
+}
+
+// FloatImage creates a texture of a floating point format from pix, which
+// holds rows of width pixels from the bottom up, with three components per
+// pixel for PixelR11FG11FB10F and four otherwise. Float textures may also be
+// rendered into, such as with NewSampler2D and NewFramebuffer.
+func FloatImage(pix []float32, width, height int, format PixelFormat, opts *SamplerOptions) (*Sampler2D, error) {
+	if !format.IsFloat() {
+		return nil, fmt.Errorf("gfx: %v is not a floating point format", format)
+	}
+	if n := width * height * format.channels(); len(pix) != n {
+		return nil, fmt.Errorf("gfx: %d floats given for %dx%d %v texture, want %d", len(pix), width, height, format, n)
+	}
+	return newSampler2D(float32Bytes(pix), width, height, format, opts)
+}
+
+// float32Bytes returns the memory of f as a byte slice.
+func float32Bytes(f []float32) []byte {
+	if len(f) == 0 {
+		return []byte{}
 	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&f[0])), len(f)*4)
+}
+
+// renderTargetOptions are used for textures without initial contents.
+var renderTargetOptions = SamplerOptions{
+	WrapS: WrapClamp,
+	WrapT: WrapClamp,
+}
+
+// NewSampler2D allocates a texture of the given size and format with
+// undefined contents, typically to be rendered into through a Framebuffer.
+// It clamps texture coordinates and is linearly filtered.
+func NewSampler2D(width, height int, format PixelFormat) (*Sampler2D, error) {
+	return newSampler2D(nil, width, height, format, &renderTargetOptions)
+}
+
+// SetLabel names the texture in GPU debuggers, such as RenderDoc and
+// apitrace, when the context has KHR_debug. Textures are otherwise named
+// like "Sampler2D#7".
+func (s *Sampler2D) SetLabel(name string) {
+	labelObject(gl.TEXTURE, uint32(s.tex), name)
 }
 
 func (s *Sampler2D) Delete() {
-	s.tex.Delete()
+	setFinalizer(s, nil)
+	deleteTexture(s.tex)
+}
+
+// Size returns the width and height of the texture in pixels.
+func (s *Sampler2D) Size() (width, height int) {
+	return s.width, s.height
+}
+
+// Format returns the pixel format of the texture.
+func (s *Sampler2D) Format() PixelFormat {
+	return s.format
+}
+
+// SetPixels replaces a width by height region of the texture at x, y with
+// pix, which holds tightly packed rows in the texture's pixel format.
+func (s *Sampler2D) SetPixels(x, y, width, height int, pix []byte) error {
+	if s.format.IsCompressed() {
+		return fmt.Errorf("gfx: cannot set pixels of %v texture", s.format)
+	}
+	if x < 0 || y < 0 || x+width > s.width || y+height > s.height {
+		return fmt.Errorf("gfx: region %dx%d at %d,%d outside %dx%d texture", width, height, x, y, s.width, s.height)
+	}
+	s.bind()
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, x, y, width, height, s.format.format(), s.format.typ(), pix)
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 4)
+	if s.mipmaps {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+	checkError("Sampler2D.SetPixels %dx%d %v", width, height, s.format)
+	return nil
+}
+
+// Update replaces the region r of the texture with img, which must be the
+// same size as r, without reallocating the texture. The region is in the
+// coordinates of the image the texture was created from. img is converted
+// to the format of the texture as it would be by Image.
+func (s *Sampler2D) Update(r image.Rectangle, img image.Image) error {
+	b := img.Bounds()
+	if b.Size() != r.Size() {
+		return fmt.Errorf("gfx: %v image does not fit %v region", b.Size(), r)
+	}
+	var pix []byte
+	switch s.format {
+	case PixelRGBA8, PixelSRGBA8:
+		pix = rgbaPixels(img)
+	case PixelR8:
+		pix = alphaPixels(img)
+	default:
+		return fmt.Errorf("gfx: cannot update %v texture from an image", s.format)
+	}
+	return s.SetPixels(r.Min.X, r.Min.Y, r.Dx(), r.Dy(), pix)
+}
+
+// packRows returns rows of rowBytes bytes from pix, which holds rows stride
+// bytes apart, without gaps between them.
+func packRows(pix []byte, stride, rowBytes, rows int) []byte {
+	if stride == rowBytes || rows == 0 {
+		return pix[:rowBytes*rows]
+	}
+	packed := make([]byte, 0, rowBytes*rows)
+	for y := 0; y < rows; y++ {
+		packed = append(packed, pix[y*stride:y*stride+rowBytes]...)
+	}
+	return packed
 }
 
+// bind binds the texture to change it, to the unit it is bound to if any.
 func (s *Sampler2D) bind() {
-	s.tex.Bind(gl.TEXTURE_2D)
+	textureUnit(s.tex, gl.TEXTURE_2D, false)
 }
 
-func imageRGBA(pix []byte, width, height int) (*Sampler2D, error) {
+// SetOptions changes how the texture is sampled. Enabling mipmaps generates
+// them from the current contents.
+func (s *Sampler2D) SetOptions(opts SamplerOptions) {
+	s.bind()
+	if opts.Mipmaps && !s.mipmaps {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+	s.mipmaps = opts.Mipmaps
+	s.setFilter(opts.MinFilter, opts.MagFilter)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, opts.WrapS.gl())
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, opts.WrapT.gl())
+	setAnisotropy(opts.Anisotropy)
+	if s.format.IsDepth() {
+		s.setCompare(opts.Compare, opts.CompareFunc)
+	}
+	checkError("Sampler2D.SetOptions")
+}
+
+// SetFilter changes the minification and magnification filters.
+func (s *Sampler2D) SetFilter(min, mag Filter) {
+	s.bind()
+	s.setFilter(min, mag)
+}
+
+// SetWrap changes how the S and T texture coordinates wrap.
+func (s *Sampler2D) SetWrap(ws, wt Wrap) {
+	s.bind()
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, ws.gl())
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, wt.gl())
+}
+
+// SetCompare enables or disables depth comparison of a depth texture, for
+// sampling it through a sampler2DShadow.
+func (s *Sampler2D) SetCompare(enabled bool, fn CompareFunc) {
+	s.bind()
+	s.setCompare(enabled, fn)
+}
+
+func (s *Sampler2D) setCompare(enabled bool, fn CompareFunc) {
+	if !enabled {
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_COMPARE_MODE, gl.NONE)
+		return
+	}
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_COMPARE_MODE, gl.COMPARE_REF_TO_TEXTURE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_COMPARE_FUNC, int(fn.gl()))
+}
+
+// SetAnisotropy sets the maximum degree of anisotropic filtering, limited
+// to Caps().MaxAnisotropy. It does nothing if anisotropic filtering is not
+// supported.
+func (s *Sampler2D) SetAnisotropy(max float32) {
+	s.bind()
+	setAnisotropy(max)
+}
+
+// setAnisotropy sets the anisotropy of the bound texture, where values of
+// 1 or less disable it.
+func setAnisotropy(max float32) {
+	detectCaps()
+	if caps.anisotropy == 0 {
+		return
+	}
+	if max < 1 {
+		max = 1
+	}
+	if max > caps.anisotropy {
+		max = caps.anisotropy
+	}
+	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MAX_ANISOTROPY_EXT, max)
+}
+
+// GenerateMipmaps regenerates the mipmap chain from the base level, and
+// enables mipmapped minification if it was not already.
+func (s *Sampler2D) GenerateMipmaps() {
+	s.bind()
+	gl.GenerateMipmap(gl.TEXTURE_2D)
+	if !s.mipmaps {
+		s.mipmaps = true
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
+	}
+	checkError("Sampler2D.GenerateMipmaps")
+}
+
+// setFilter sets filter parameters on the bound texture. Integer formats
+// always use nearest filtering.
+func (s *Sampler2D) setFilter(min, mag Filter) {
+	if s.format.IsInteger() {
+		min, mag = FilterNearest, FilterNearest
+	}
+	minf, magf := gl.LINEAR, gl.LINEAR
+	if mag == FilterNearest {
+		magf = gl.NEAREST
+	}
+	switch {
+	case min == FilterNearest && s.mipmaps:
+		minf = gl.NEAREST_MIPMAP_NEAREST
+	case min == FilterNearest:
+		minf = gl.NEAREST
+	case s.mipmaps:
+		minf = gl.LINEAR_MIPMAP_LINEAR
+	}
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, minf)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, magf)
+}
+
+func imageRGBA(pix []byte, width, height int, opts *SamplerOptions) (*Sampler2D, error) {
+	if opts != nil && opts.SRGB {
+		return newSampler2D(pix, width, height, PixelSRGBA8, opts)
+	}
+	return newSampler2D(pix, width, height, PixelRGBA8, opts)
+}
+
+func imageAlpha(pix []byte, width, height int, opts *SamplerOptions) (*Sampler2D, error) {
+	return newSampler2D(pix, width, height, PixelR8, opts)
+}
+
+// NewSampler2DLevels creates a texture from a chain of mipmap levels, the
+// first being width by height pixels and each after it half the size of the
+// one before, rounded down. The chain may stop short of 1x1. Each level must
+// hold DataSize bytes. This is the only way to create textures of
+// compressed formats. opts.Mipmaps is ignored; the levels given are used.
+func NewSampler2DLevels(levels [][]byte, width, height int, format PixelFormat, opts *SamplerOptions) (*Sampler2D, error) {
+	if len(levels) == 0 {
+		return nil, errors.New("gfx: no texture levels given")
+	}
+	for i, pix := range levels {
+		w, h := levelSize(width, height, i)
+		if n := format.DataSize(w, h); len(pix) != n {
+			return nil, fmt.Errorf("gfx: level %d of %v texture has %d bytes, want %d", i, format, len(pix), n)
+		}
+	}
+	var o SamplerOptions
+	if opts != nil {
+		o = *opts
+	}
 	s := &Sampler2D{
-		tex: gl.GenTexture(),
+		tex:    gl.GenTexture(),
+		width:  width,
+		height: height,
+		format: format,
 	}
+	setFinalizer(s, (*Sampler2D).finalize)
 	s.bind()
-	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
-	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
-	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, pix)
+	s.SetLabel(autoLabel("Sampler2D"))
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	for i, pix := range levels {
+		w, h := levelSize(width, height, i)
+		if format.IsCompressed() {
+			gl.CompressedTexImage2D(gl.TEXTURE_2D, i, gl.GLenum(format.internalFormat()), w, h, 0, len(pix), pix)
+		} else {
+			gl.TexImage2D(gl.TEXTURE_2D, i, format.internalFormat(), w, h, 0, format.format(), format.typ(), pix)
+		}
+	}
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 4)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAX_LEVEL, len(levels)-1)
+	// mark the levels as present so SetOptions doesn't generate them
+	s.mipmaps = len(levels) > 1
+	o.Mipmaps = s.mipmaps
+	s.SetOptions(o)
+	checkError("NewSampler2DLevels %dx%d %v, %d levels", width, height, format, len(levels))
 	return s, nil
 }
 
-func imageAlpha(pix []byte, width, height int) (*Sampler2D, error) {
+// levelSize gives the size of mipmap level i of a width by height texture.
+func levelSize(width, height, i int) (int, int) {
+	width, height = width>>uint(i), height>>uint(i)
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	return width, height
+}
+
+func newSampler2D(pix []byte, width, height int, format PixelFormat, opts *SamplerOptions) (*Sampler2D, error) {
+	if opts == nil {
+		opts = &SamplerOptions{}
+	}
 	s := &Sampler2D{
-		tex: gl.GenTexture(),
+		tex:    gl.GenTexture(),
+		width:  width,
+		height: height,
+		format: format,
 	}
+	setFinalizer(s, (*Sampler2D).finalize)
 	s.bind()
-	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
-	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
-	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.R8, width, height, 0, gl.RED, gl.UNSIGNED_BYTE, pix)
+	s.SetLabel(autoLabel("Sampler2D"))
+	if pix == nil {
+		gl.TexImage2D(gl.TEXTURE_2D, 0, format.internalFormat(), width, height, 0, format.format(), format.typ(), nil)
+	} else {
+		gl.TexImage2D(gl.TEXTURE_2D, 0, format.internalFormat(), width, height, 0, format.format(), format.typ(), pix)
+	}
+	s.SetOptions(*opts)
+	checkError("Sampler2D upload %dx%d %v", width, height, format)
 	return s, nil
 }
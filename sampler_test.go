@@ -0,0 +1,254 @@
+package gfx_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"math"
+	"testing"
+)
+
+func TestAnisotropy(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+	rec.Extensions = []string{"GL_EXT_texture_filter_anisotropic"}
+	gfx.Init()
+
+	tex, err := gfx.NewSampler2D(16, 16, gfx.PixelRGBA8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tex.Delete()
+	for _, tt := range []struct {
+		anisotropy float32
+		want       string
+	}{
+		{8, "TexParameterf(TEXTURE_2D, TEXTURE_MAX_ANISOTROPY_EXT, 8)"},
+		{64, "TexParameterf(TEXTURE_2D, TEXTURE_MAX_ANISOTROPY_EXT, 16)"},
+		{0, "TexParameterf(TEXTURE_2D, TEXTURE_MAX_ANISOTROPY_EXT, 1)"},
+	} {
+		rec.Reset()
+		tex.SetOptions(gfx.SamplerOptions{Anisotropy: tt.anisotropy})
+		if calls := rec.Ops("TexParameterf"); len(calls) != 1 || calls[0].String() != tt.want {
+			t.Errorf("anisotropy %v: got %v, want %s", tt.anisotropy, calls, tt.want)
+		}
+	}
+}
+
+func TestFloatImage(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	for _, c := range []struct {
+		format gfx.PixelFormat
+		pix    []float32
+		want   string
+	}{
+		{gfx.PixelRGBA32F, []float32{1, 2, 3, 4, -1, 0.5, 1e6, 0}, "TexImage2D(TEXTURE_2D, 0, RGBA32F, 2, 1, 0, RGBA, FLOAT, [32 bytes])"},
+		{gfx.PixelRGBA16F, []float32{1, 2, 3, 4, -1, 0.5, 1e6, 0}, "TexImage2D(TEXTURE_2D, 0, RGBA16F, 2, 1, 0, RGBA, FLOAT, [32 bytes])"},
+		{gfx.PixelR11FG11FB10F, []float32{1, 2, 3, 4, 5, 6}, "TexImage2D(TEXTURE_2D, 0, R11F_G11F_B10F, 2, 1, 0, RGB, FLOAT, [24 bytes])"},
+	} {
+		rec.Reset()
+		tex, err := gfx.FloatImage(c.pix, 2, 1, c.format, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		up := rec.Ops("TexImage2D")
+		if len(up) != 1 || up[0].String() != c.want {
+			t.Errorf("%v: got %v, want %s", c.format, up, c.want)
+			tex.Delete()
+			continue
+		}
+		// the floats are uploaded as they are, for GL to convert
+		data := up[0].Args[8].([]byte)
+		for i, f := range c.pix {
+			if got := math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:])); got != f {
+				t.Errorf("%v: got %v for component %d, want %v", c.format, got, i, f)
+			}
+		}
+		tex.Delete()
+	}
+
+	if _, err := gfx.FloatImage(make([]float32, 8), 2, 1, gfx.PixelRGBA8, nil); err == nil {
+		t.Error("no error for a format that is not floating point")
+	}
+	if _, err := gfx.FloatImage(make([]float32, 6), 2, 1, gfx.PixelRGBA32F, nil); err == nil {
+		t.Error("no error for too few floats")
+	}
+
+	// float textures can be rendered into
+	tex, err := gfx.NewSampler2D(8, 8, gfx.PixelRGBA16F)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tex.Delete()
+	fb, err := gfx.NewFramebuffer(tex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fb.Delete()
+}
+
+func TestSRGB(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	for _, c := range []struct {
+		img  image.Image
+		srgb bool
+		want string
+	}{
+		{image.NewNRGBA(image.Rect(0, 0, 2, 2)), true, "SRGB8_ALPHA8"},
+		{image.NewNRGBA(image.Rect(0, 0, 2, 2)), false, "RGBA8"},
+		// single channel images are not color
+		{image.NewGray(image.Rect(0, 0, 2, 2)), true, "R8"},
+	} {
+		rec.Reset()
+		tex, err := gfx.Image(c.img, &gfx.SamplerOptions{SRGB: c.srgb})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if up := rec.Ops("TexImage2D"); len(up) != 1 || fmt.Sprint(up[0].Args[2]) != c.want {
+			t.Errorf("%T, sRGB %v: got %v, want %s", c.img, c.srgb, up, c.want)
+		}
+		tex.Delete()
+	}
+
+	gfx.ResetState()
+	rec.Reset()
+	s := gfx.State{FramebufferSRGB: true}
+	s.Apply()
+	s.FramebufferSRGB = false
+	s.Apply()
+	var got []string
+	for _, c := range rec.Ops("Enable", "Disable") {
+		if fmt.Sprint(c.Args[0]) == "FRAMEBUFFER_SRGB" {
+			got = append(got, c.String())
+		}
+	}
+	if fmt.Sprint(got) != "[Enable(FRAMEBUFFER_SRGB) Disable(FRAMEBUFFER_SRGB)]" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	tex, err := gfx.NewSampler2D(8, 8, gfx.PixelRGBA8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tex.Delete()
+	// a sub-image, whose rows are further apart than its width
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for i := range img.Pix {
+		img.Pix[i] = byte(i)
+	}
+	sub := img.SubImage(image.Rect(1, 1, 3, 2))
+	rec.Reset()
+	if err := tex.Update(image.Rect(5, 6, 7, 7), sub); err != nil {
+		t.Fatal(err)
+	}
+	up := rec.Ops("TexSubImage2D")
+	want := "TexSubImage2D(TEXTURE_2D, 0, 5, 6, 2, 1, RGBA, UNSIGNED_BYTE, [8 bytes])"
+	if len(up) != 1 || up[0].String() != want {
+		t.Fatalf("got %v, want %s", up, want)
+	}
+	if got := fmt.Sprint(up[0].Args[8]); got != "[20 21 22 23 24 25 26 27]" {
+		t.Errorf("uploaded %v, want the pixels at 1,1 and 2,1", got)
+	}
+
+	if err := tex.Update(image.Rect(0, 0, 4, 4), sub); err == nil {
+		t.Error("no error for an image of another size than the region")
+	}
+	if err := tex.Update(image.Rect(6, 6, 10, 10), img); err == nil {
+		t.Error("no error for a region outside the texture")
+	}
+}
+
+func TestImageTypes(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	r := image.Rect(0, 0, 2, 1)
+	pal := image.NewPaletted(r, color.Palette{color.NRGBA{1, 2, 3, 255}, color.NRGBA{5, 6, 7, 255}})
+	pal.Pix[1] = 1
+	gray := image.NewGray16(r)
+	gray.SetGray16(1, 0, color.Gray16{0xABCD})
+	ycc := image.NewYCbCr(r, image.YCbCrSubsampleRatio444)
+	for i := range ycc.Y {
+		ycc.Y[i], ycc.Cb[i], ycc.Cr[i] = 255, 128, 128
+	}
+	for _, c := range []struct {
+		img    image.Image
+		format string
+		pix    string
+	}{
+		{pal, "RGBA8", "[1 2 3 255 5 6 7 255]"},
+		// the high byte of each gray
+		{gray, "R8", "[0 171]"},
+		{ycc, "RGBA8", "[255 255 255 255 255 255 255 255]"},
+	} {
+		rec.Reset()
+		tex, err := gfx.Image(c.img, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		up := rec.Ops("TexImage2D")
+		if len(up) != 1 || fmt.Sprint(up[0].Args[2]) != c.format || fmt.Sprint(up[0].Args[8]) != c.pix {
+			t.Errorf("%T: got %v, want %s %s", c.img, up, c.format, c.pix)
+		}
+		tex.Delete()
+	}
+
+	rec.Reset()
+	tex, err := gfx.FromPixels(make([]byte, 8*4*3), 4, 3, gfx.PixelRG32UI, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tex.Delete()
+	want := "TexImage2D(TEXTURE_2D, 0, RG32UI, 4, 3, 0, RG_INTEGER, UNSIGNED_INT, [96 bytes])"
+	if up := rec.Ops("TexImage2D"); len(up) != 1 || up[0].String() != want {
+		t.Errorf("got %v, want %s", up, want)
+	}
+	if _, err := gfx.FromPixels(make([]byte, 95), 4, 3, gfx.PixelRG32UI, nil); err == nil {
+		t.Error("no error for too few bytes")
+	}
+}
+
+func TestImagePreprocess(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	// a top row of half transparent white over an opaque red one
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 2))
+	img.SetNRGBA(0, 0, color.NRGBA{255, 255, 255, 128})
+	img.SetNRGBA(0, 1, color.NRGBA{255, 0, 0, 255})
+	for _, c := range []struct {
+		opts gfx.SamplerOptions
+		pix  string
+	}{
+		{gfx.SamplerOptions{}, "[255 255 255 128 255 0 0 255]"},
+		{gfx.SamplerOptions{PremultiplyAlpha: true}, "[128 128 128 128 255 0 0 255]"},
+		{gfx.SamplerOptions{FlipY: true}, "[255 0 0 255 255 255 255 128]"},
+		{gfx.SamplerOptions{PremultiplyAlpha: true, FlipY: true}, "[255 0 0 255 128 128 128 128]"},
+	} {
+		rec.Reset()
+		tex, err := gfx.Image(img, &c.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if up := rec.Ops("TexImage2D"); len(up) != 1 || fmt.Sprint(up[0].Args[8]) != c.pix {
+			t.Errorf("%+v: got %v, want %s", c.opts, up, c.pix)
+		}
+		tex.Delete()
+	}
+	// the image is left as it was
+	if img.Pix[0] != 255 || img.Pix[4] != 255 {
+		t.Errorf("image changed to %v", img.Pix)
+	}
+}
@@ -0,0 +1,194 @@
+package gfx
+
+import (
+	"errors"
+	"fmt"
+	"j4k.co/gfx/internal/gl"
+)
+
+// The faces of a cube map, in the order GL numbers them.
+const (
+	CubePositiveX = iota
+	CubeNegativeX
+	CubePositiveY
+	CubeNegativeY
+	CubePositiveZ
+	CubeNegativeZ
+	CubeFaces
+)
+
+// SamplerCube is a cube map texture, sampled with a direction through a
+// samplerCube uniform, such as for environment maps.
+type SamplerCube struct {
+	tex    gl.Texture
+	size   int
+	format PixelFormat
+	levels int
+}
+
+// NewSamplerCube allocates a cube map with size by size faces of undefined
+// contents, typically to be rendered into through NewCubeFramebuffer. If
+// mipmaps is set, the full mipmap chain is allocated and used when
+// minifying. It clamps directions to the faces and is linearly filtered,
+// across the edges of faces where the context supports it. Cube maps of
+// compressed formats are filled with SetPixels, as they cannot be rendered
+// into.
+func NewSamplerCube(size int, format PixelFormat, mipmaps bool) (*SamplerCube, error) {
+	levels := 1
+	if mipmaps {
+		for size>>uint(levels) > 0 {
+			levels++
+		}
+	}
+	return newSamplerCube(size, format, levels, nil)
+}
+
+// NewSamplerCubeLevels creates a cube map from the pixel data of each
+// mipmap level of each face, such as CubePositiveX, starting with the base
+// level of size by size pixels. Every face must have the same number of
+// levels. Compressed formats are uploaded as they are.
+func NewSamplerCubeLevels(faces [CubeFaces][][]byte, size int, format PixelFormat) (*SamplerCube, error) {
+	levels := len(faces[0])
+	if levels == 0 {
+		return nil, errors.New("gfx: no cube map levels given")
+	}
+	for face, pixs := range faces {
+		if len(pixs) != levels {
+			return nil, fmt.Errorf("gfx: cube map face %d has %d levels, want %d", face, len(pixs), levels)
+		}
+		for i, pix := range pixs {
+			w, _ := levelSize(size, size, i)
+			if n := format.DataSize(w, w); len(pix) != n {
+				return nil, fmt.Errorf("gfx: level %d of %v cube map face %d has %d bytes, want %d", i, format, face, len(pix), n)
+			}
+		}
+	}
+	return newSamplerCube(size, format, levels, &faces)
+}
+
+// newSamplerCube creates a cube map with the given number of levels, filled
+// from faces if it is not nil.
+func newSamplerCube(size int, format PixelFormat, levels int, faces *[CubeFaces][][]byte) (*SamplerCube, error) {
+	if format.IsDepth() {
+		return nil, fmt.Errorf("gfx: cannot make a %v cube map", format)
+	}
+	c := &SamplerCube{
+		tex:    gl.GenTexture(),
+		size:   size,
+		format: format,
+		levels: levels,
+	}
+	setFinalizer(c, (*SamplerCube).finalize)
+	c.bind()
+	c.SetLabel(autoLabel("SamplerCube"))
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	for face := 0; face < CubeFaces; face++ {
+		for i := 0; i < c.levels; i++ {
+			var pix []byte
+			if faces != nil {
+				pix = faces[face][i]
+			}
+			c.upload(face, i, pix)
+		}
+	}
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 4)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MAX_LEVEL, c.levels-1)
+	minf := gl.LINEAR
+	if c.levels > 1 {
+		minf = gl.LINEAR_MIPMAP_LINEAR
+	}
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MIN_FILTER, minf)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	for _, wrap := range []gl.GLenum{gl.TEXTURE_WRAP_S, gl.TEXTURE_WRAP_T, gl.TEXTURE_WRAP_R} {
+		gl.TexParameteri(gl.TEXTURE_CUBE_MAP, wrap, gl.CLAMP_TO_EDGE)
+	}
+	detectCaps()
+	if caps.seamlessCube {
+		gl.Enable(gl.TEXTURE_CUBE_MAP_SEAMLESS)
+	}
+	checkError("SamplerCube upload %dx%d %v, %d levels", size, size, format, c.levels)
+	return c, nil
+}
+
+// SetLabel names the texture in GPU debuggers, such as RenderDoc and
+// apitrace, when the context has KHR_debug. Cube maps are otherwise named
+// like "SamplerCube#3".
+func (c *SamplerCube) SetLabel(name string) {
+	labelObject(gl.TEXTURE, uint32(c.tex), name)
+}
+
+func (c *SamplerCube) Delete() {
+	setFinalizer(c, nil)
+	deleteTexture(c.tex)
+}
+
+// Size returns the width and height of each face in pixels.
+func (c *SamplerCube) Size() int {
+	return c.size
+}
+
+// Format returns the pixel format of the texture.
+func (c *SamplerCube) Format() PixelFormat {
+	return c.format
+}
+
+// Levels returns the number of mipmap levels, which is 1 without mipmaps.
+func (c *SamplerCube) Levels() int {
+	return c.levels
+}
+
+// SetPixels replaces the mipmap level of a face, such as CubePositiveX,
+// with pix, which holds tightly packed rows in the texture's pixel format.
+func (c *SamplerCube) SetPixels(face, level int, pix []byte) error {
+	if face < 0 || face >= CubeFaces || level < 0 || level >= c.levels {
+		return fmt.Errorf("gfx: cube map has no level %d of face %d", level, face)
+	}
+	w, _ := levelSize(c.size, c.size, level)
+	if n := c.format.DataSize(w, w); len(pix) != n {
+		return fmt.Errorf("gfx: %dx%d %v face has %d bytes, want %d", w, w, c.format, len(pix), n)
+	}
+	c.bind()
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	if c.format.IsCompressed() {
+		c.upload(face, level, pix)
+	} else {
+		gl.TexSubImage2D(gl.TEXTURE_CUBE_MAP_POSITIVE_X+gl.GLenum(face), level, 0, 0, w, w, c.format.format(), c.format.typ(), pix)
+	}
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 4)
+	checkError("SamplerCube.SetPixels %dx%d %v", w, w, c.format)
+	return nil
+}
+
+// upload specifies a level of a face of the bound texture from pix, or
+// leaves it undefined if pix is nil. Compressed levels are replaced whole,
+// as there is no uncompressed data to update them from.
+func (c *SamplerCube) upload(face, level int, pix []byte) {
+	w, _ := levelSize(c.size, c.size, level)
+	target := gl.TEXTURE_CUBE_MAP_POSITIVE_X + gl.GLenum(face)
+	var data interface{}
+	if pix != nil {
+		data = pix
+	}
+	if c.format.IsCompressed() {
+		gl.CompressedTexImage2D(target, level, gl.GLenum(c.format.internalFormat()), w, w, 0, c.format.DataSize(w, w), data)
+	} else {
+		gl.TexImage2D(target, level, c.format.internalFormat(), w, w, 0, c.format.format(), c.format.typ(), data)
+	}
+}
+
+// GenerateMipmaps regenerates the mipmap levels from the base level of each
+// face. It does nothing without mipmaps or for compressed formats, whose
+// levels are set with SetPixels.
+func (c *SamplerCube) GenerateMipmaps() {
+	if c.levels == 1 || c.format.IsCompressed() {
+		return
+	}
+	c.bind()
+	gl.GenerateMipmap(gl.TEXTURE_CUBE_MAP)
+	checkError("SamplerCube.GenerateMipmaps")
+}
+
+// bind binds the texture to change it, to the unit it is bound to if any.
+func (c *SamplerCube) bind() {
+	textureUnit(c.tex, gl.TEXTURE_CUBE_MAP, false)
+}
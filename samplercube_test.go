@@ -0,0 +1,102 @@
+package gfx_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+func TestSamplerCube(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	cube, err := gfx.NewSamplerCube(16, gfx.PixelRGBA8, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cube.Delete()
+	if n := cube.Levels(); n != 5 {
+		t.Errorf("got %d levels, want 5", n)
+	}
+	if n := len(rec.Ops("TexImage2D")); n != gfx.CubeFaces*5 {
+		t.Errorf("allocated %d images, want %d", n, gfx.CubeFaces*5)
+	}
+	if err := cube.SetPixels(gfx.CubeNegativeZ, 4, make([]byte, 4)); err != nil {
+		t.Error(err)
+	}
+	if err := cube.SetPixels(gfx.CubeFaces, 0, make([]byte, 16*16*4)); err == nil {
+		t.Error("no error setting a missing face")
+	}
+
+	fb, err := gfx.NewCubeFramebuffer(cube, gfx.CubePositiveY, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fb.Delete()
+	if w, h := fb.Size(); w != 4 || h != 4 {
+		t.Errorf("got %dx%d framebuffer, want 4x4", w, h)
+	}
+	c := rec.Ops("FramebufferTexture2D")[0]
+	if fmt.Sprint(c.Args[2]) != "TEXTURE_CUBE_MAP_POSITIVE_Y" || c.Args[4] != 2 {
+		t.Errorf("attached %v, want level 2 of the +Y face", c.Args)
+	}
+
+	const fs gfx.FragmentShader = `
+uniform samplerCube Sky;
+
+varying vec2 TexCoord;
+
+void main() {
+	gl_FragColor = textureCube(Sky, vec3(TexCoord, 1.0));
+}`
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	s.Use()
+	rec.Reset()
+	if err := s.SetCubeTexture("Sky", cube); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rec.Uniform("Sky"); !ok {
+		t.Error("Sky was not assigned")
+	}
+	binds := rec.Ops("BindTexture")
+	if len(binds) > 0 && fmt.Sprint(binds[0].Args[0]) != "TEXTURE_CUBE_MAP" {
+		t.Errorf("got %v, want the cube map target", binds[0])
+	}
+}
+
+func TestCompressedSamplerCube(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	cube, err := gfx.NewSamplerCube(8, gfx.PixelBC1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cube.Delete()
+	allocs := rec.Ops("CompressedTexImage2D")
+	if len(allocs) != gfx.CubeFaces*4 || rec.Ops("TexImage2D") != nil {
+		t.Fatalf("got %d compressed images, want %d", len(allocs), gfx.CubeFaces*4)
+	}
+	// each level is at least a 4x4 block of 8 bytes
+	if got := fmt.Sprint(allocs[3].Args[6:]); got != "[8 []]" {
+		t.Errorf("got %v for the 1x1 level, want a block of undefined contents", got)
+	}
+
+	rec.Reset()
+	if err := cube.SetPixels(gfx.CubeNegativeY, 1, make([]byte, 8)); err != nil {
+		t.Fatal(err)
+	}
+	if c := rec.Ops("CompressedTexImage2D", "TexSubImage2D"); len(c) != 1 || c[0].String() != "CompressedTexImage2D(TEXTURE_CUBE_MAP_NEGATIVE_Y, 1, COMPRESSED_RGBA_S3TC_DXT1_EXT, 4, 4, 0, 8, [8 bytes])" {
+		t.Errorf("got %v, want the level replaced", c)
+	}
+	rec.Reset()
+	cube.GenerateMipmaps()
+	if c := rec.Ops("GenerateMipmap"); c != nil {
+		t.Errorf("got %v for a compressed cube map", c)
+	}
+}
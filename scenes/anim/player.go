@@ -0,0 +1,206 @@
+/*
+Package anim plays keyframe animation clips on scene nodes.
+
+A Player is a component that plays one scenes.Clip at a time, posing the
+nodes its channels animate each time the scene is updated, so that the
+pose is current when the scene is rendered:
+
+	p := anim.NewPlayer()
+	root.Attach(p)
+	p.Play(walk, true)
+	...
+	p.CrossFade(run, 0.3, true)
+	root.Update(dt)
+	renderer.Render(root, cam)
+*/
+package anim
+
+import (
+	"j4k.co/gfx/scenes"
+	"math"
+)
+
+// Player is a component that plays animation clips. The node it is
+// attached to only determines when it updates; the clips pose the nodes of
+// their own channels.
+type Player struct {
+	// Speed scales the time the player advances by each update. It may be
+	// negative to play clips backward.
+	Speed float32
+
+	cur, prev track
+	fade      float32 // time since the crossfade began
+	fadeTime  float32 // length of the crossfade, or 0 if none
+	paused    bool
+}
+
+// track is a clip being played.
+type track struct {
+	clip *scenes.Clip
+	time float32
+	loop bool
+}
+
+// NewPlayer returns a player at normal speed with no clip.
+func NewPlayer() *Player {
+	return &Player{Speed: 1}
+}
+
+// Play starts playing c from its beginning, at once, and unpauses the
+// player. If loop is set, the clip repeats; otherwise it holds its last
+// pose when it ends.
+func (p *Player) Play(c *scenes.Clip, loop bool) {
+	p.cur = track{clip: c, loop: loop}
+	p.prev = track{}
+	p.fadeTime = 0
+	p.paused = false
+	p.cur.pose()
+}
+
+// CrossFade starts playing c from its beginning, blending from the pose of
+// the current clip, which keeps playing, to that of c over the given
+// duration in seconds. Channels animated by only one of the clips are not
+// blended. Without a current clip, it is the same as Play.
+func (p *Player) CrossFade(c *scenes.Clip, duration float32, loop bool) {
+	if p.cur.clip == nil || duration <= 0 {
+		p.Play(c, loop)
+		return
+	}
+	p.prev = p.cur
+	p.cur = track{clip: c, loop: loop}
+	p.fade, p.fadeTime = 0, duration
+	p.paused = false
+	p.pose()
+}
+
+// Stop stops playing, leaving the nodes in their current pose.
+func (p *Player) Stop() {
+	p.cur, p.prev = track{}, track{}
+	p.fadeTime = 0
+}
+
+// Pause stops advancing time until Resume is called.
+func (p *Player) Pause() { p.paused = true }
+
+// Resume continues playing after Pause.
+func (p *Player) Resume() { p.paused = false }
+
+// Paused reports whether the player is paused.
+func (p *Player) Paused() bool { return p.paused }
+
+// Clip returns the clip being played, or nil.
+func (p *Player) Clip() *scenes.Clip { return p.cur.clip }
+
+// Time returns the time into the clip being played, in seconds.
+func (p *Player) Time() float32 { return p.cur.time }
+
+// Seek moves to time t of the clip being played and poses its nodes.
+func (p *Player) Seek(t float32) {
+	if p.cur.clip == nil {
+		return
+	}
+	p.cur.time = t
+	p.cur.wrap()
+	p.pose()
+}
+
+// Done reports whether the clip being played has ended, which a looping
+// clip never does.
+func (p *Player) Done() bool {
+	c := p.cur.clip
+	if c == nil {
+		return true
+	}
+	if p.cur.loop {
+		return false
+	}
+	if p.Speed < 0 {
+		return p.cur.time <= 0
+	}
+	return p.cur.time >= c.Duration()
+}
+
+// Update implements scenes.Updater, advancing the clips by dt seconds and
+// posing their nodes.
+func (p *Player) Update(n *scenes.Node, dt float64) {
+	if p.cur.clip == nil || p.paused {
+		return
+	}
+	step := float32(dt) * p.Speed
+	p.cur.advance(step)
+	if p.fadeTime > 0 {
+		p.prev.advance(step)
+		p.fade += float32(dt)
+		if p.fade >= p.fadeTime {
+			p.prev = track{}
+			p.fadeTime = 0
+		}
+	}
+	p.pose()
+}
+
+// pose sets the nodes of the current clip, blended with the previous clip
+// while crossfading.
+func (p *Player) pose() {
+	if p.fadeTime == 0 {
+		p.cur.pose()
+		return
+	}
+	f := p.fade / p.fadeTime
+	p.prev.pose()
+	for i := range p.cur.clip.Channels {
+		ch := &p.cur.clip.Channels[i]
+		if !animated(ch) {
+			continue
+		}
+		v := ch.Sample(p.cur.time)
+		if from, ok := p.prev.find(ch.Node, ch.Path); ok {
+			v = ch.Blend(from.Sample(p.prev.time), v, f)
+		}
+		ch.Set(v)
+	}
+}
+
+func (t *track) advance(dt float32) {
+	t.time += dt
+	t.wrap()
+}
+
+// wrap brings the time of the track back into the clip, repeating it if it
+// loops and stopping at either end otherwise.
+func (t *track) wrap() {
+	d := t.clip.Duration()
+	switch {
+	case t.loop && d > 0:
+		t.time = float32(math.Mod(float64(t.time), float64(d)))
+		if t.time < 0 {
+			t.time += d
+		}
+	case t.time > d:
+		t.time = d
+	case t.time < 0:
+		t.time = 0
+	}
+}
+
+func (t *track) pose() {
+	if t.clip != nil {
+		t.clip.Apply(t.time)
+	}
+}
+
+// find returns the channel of the track animating the given property.
+func (t *track) find(n *scenes.Node, path scenes.ChannelPath) (*scenes.Channel, bool) {
+	for i := range t.clip.Channels {
+		ch := &t.clip.Channels[i]
+		if ch.Node == n && ch.Path == path && animated(ch) {
+			return ch, true
+		}
+	}
+	return nil, false
+}
+
+// animated reports whether ch has keyframes to sample.
+func animated(ch *scenes.Channel) bool {
+	return len(ch.Times) > 0 && len(ch.Values) >= len(ch.Times)
+}
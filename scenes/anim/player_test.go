@@ -0,0 +1,81 @@
+package anim_test
+
+import (
+	"j4k.co/gfx/scenes"
+	"j4k.co/gfx/scenes/anim"
+	"math"
+	"testing"
+)
+
+func slide(n *scenes.Node, to float32) *scenes.Clip {
+	return &scenes.Clip{Channels: []scenes.Channel{{
+		Node:   n,
+		Path:   scenes.Translation,
+		Times:  []float32{0, 1},
+		Values: [][4]float32{{0, 0, 0}, {to, 0, 0}},
+	}}}
+}
+
+func x(n *scenes.Node) float32 { return n.Position()[0] }
+
+func near(a, b float32) bool { return math.Abs(float64(a-b)) < 1e-5 }
+
+func TestPlayLoop(t *testing.T) {
+	root := scenes.NewNode("root")
+	p := anim.NewPlayer()
+	root.Attach(p)
+
+	p.Play(slide(root, 4), true)
+	root.Update(0.25)
+	if !near(x(root), 1) {
+		t.Fatalf("x = %v after 0.25s, want 1", x(root))
+	}
+	root.Update(1)
+	if !near(p.Time(), 0.25) || !near(x(root), 1) {
+		t.Fatalf("time %v, x %v after looping, want 0.25 and 1", p.Time(), x(root))
+	}
+
+	p.Pause()
+	root.Update(0.5)
+	if !near(x(root), 1) {
+		t.Fatalf("x = %v while paused, want 1", x(root))
+	}
+	p.Resume()
+	if p.Done() {
+		t.Fatal("looping clip done")
+	}
+}
+
+func TestPlayOnce(t *testing.T) {
+	root := scenes.NewNode("root")
+	p := anim.NewPlayer()
+	root.Attach(p)
+
+	p.Play(slide(root, 4), false)
+	root.Update(3)
+	if !p.Done() || !near(x(root), 4) {
+		t.Fatalf("done %v, x %v, want the last pose", p.Done(), x(root))
+	}
+}
+
+func TestCrossFade(t *testing.T) {
+	root := scenes.NewNode("root")
+	p := anim.NewPlayer()
+	root.Attach(p)
+
+	p.Play(slide(root, 4), true)
+	root.Update(0.5) // x = 2
+	p.CrossFade(slide(root, -4), 1, true)
+	if !near(x(root), 2) {
+		t.Fatalf("x = %v as the fade begins, want 2", x(root))
+	}
+	root.Update(0.25)
+	// a quarter of the way from the old clip at 3 to the new one at -1
+	if want := 3 + (-1-3)*float32(0.25); !near(x(root), want) {
+		t.Fatalf("x = %v during the fade, want %v", x(root), want)
+	}
+	root.Update(1)
+	if want := float32(-1); !near(x(root), want) {
+		t.Fatalf("x = %v after the fade, want %v", x(root), want)
+	}
+}
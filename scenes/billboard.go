@@ -0,0 +1,96 @@
+package scenes
+
+// BillboardMode is how a Billboard turns to face the camera.
+type BillboardMode uint8
+
+const (
+	// Spherical billboards turn freely, keeping their +Y axis as close to
+	// the camera's up as possible, such as for sprites and labels.
+	Spherical BillboardMode = iota
+	// Cylindrical billboards only turn about their Axis, such as for trees
+	// and other upright impostors.
+	Cylindrical
+)
+
+// Billboard is a component that turns its node to face the camera each
+// time a Renderer draws, or FaceCamera is called, so that the node's +Z axis
+// points towards the camera. It suits sprites in 3D, health bars, and
+// impostors: quads showing a picture of a distant object in place of its
+// geometry. The rotation of the node is replaced, while its position and
+// scale are kept, and its children turn with it.
+type Billboard struct {
+	Mode BillboardMode
+
+	// Axis is the world space axis a Cylindrical billboard turns about,
+	// +Y if zero.
+	Axis [3]float32
+}
+
+// FaceCamera turns the nodes with a Billboard under root to face cam, as
+// Renderer.Render does before drawing. It does nothing if the camera is
+// detached.
+func FaceCamera(root *Node, cam *Camera) {
+	if cam.node == nil {
+		return
+	}
+	eye := cam.node.WorldMatrix()
+	root.Walk(func(n *Node) bool {
+		for _, c := range n.components {
+			if b, ok := c.(*Billboard); ok {
+				b.face(n, cam, &eye)
+				break
+			}
+		}
+		return true
+	})
+}
+
+// face turns n to face the camera cam, whose world transform is eye.
+func (b *Billboard) face(n *Node, cam *Camera, eye *[16]float32) {
+	pos := n.WorldPosition()
+	// towards the camera, or opposite its view direction if orthographic,
+	// where all lines of sight are parallel
+	z := [3]float32{eye[8], eye[9], eye[10]}
+	if !cam.Orthographic {
+		z = sub([3]float32{eye[12], eye[13], eye[14]}, pos)
+	}
+	var x, y [3]float32
+	switch b.Mode {
+	case Cylindrical:
+		y = b.Axis
+		if y == ([3]float32{}) {
+			y = [3]float32{0, 1, 0}
+		}
+		y = normalize(y)
+		x = cross(y, z)
+		if dot(x, x) == 0 {
+			// looking along the axis
+			return
+		}
+		x = normalize(x)
+		z = cross(x, y)
+	default:
+		if dot(z, z) == 0 {
+			return
+		}
+		z = normalize(z)
+		x = cross([3]float32{eye[4], eye[5], eye[6]}, z)
+		if dot(x, x) == 0 {
+			x = [3]float32{eye[0], eye[1], eye[2]}
+		}
+		x = normalize(x)
+		y = cross(z, x)
+	}
+	m := [16]float32{
+		x[0], x[1], x[2], 0,
+		y[0], y[1], y[2], 0,
+		z[0], z[1], z[2], 0,
+		0, 0, 0, 1,
+	}
+	q := matrixQuat(&m)
+	if n.parent != nil {
+		parent := n.parent.WorldMatrix()
+		q = MulQuat(conjugate(matrixQuat(&parent)), q)
+	}
+	n.SetRotation(q)
+}
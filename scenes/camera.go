@@ -0,0 +1,173 @@
+package scenes
+
+import (
+	"math"
+)
+
+// Camera is a component that views the scene from its node. The node's
+// world transform places the camera, which looks down its -Z axis with +Y
+// up.
+type Camera struct {
+	// Orthographic selects an orthographic projection instead of a
+	// perspective one.
+	Orthographic bool
+
+	// FieldOfView is the vertical field of view in radians of a
+	// perspective camera.
+	FieldOfView float32
+
+	// Height is the height of the view volume of an orthographic camera.
+	Height float32
+
+	// Aspect is the ratio of width to height of the view.
+	Aspect float32
+
+	Near, Far float32
+
+	node          *Node
+	width, height int // viewport size, for ScreenRay
+}
+
+// NewPerspective returns a perspective camera with a vertical field of view
+// of fov radians.
+func NewPerspective(fov, aspect, near, far float32) *Camera {
+	return &Camera{FieldOfView: fov, Aspect: aspect, Near: near, Far: far}
+}
+
+// NewOrthographic returns an orthographic camera viewing a volume height
+// units tall.
+func NewOrthographic(height, aspect, near, far float32) *Camera {
+	return &Camera{Orthographic: true, Height: height, Aspect: aspect, Near: near, Far: far}
+}
+
+// Attach implements Attacher.
+func (c *Camera) Attach(n *Node) {
+	c.node = n
+}
+
+// Detach implements Detacher.
+func (c *Camera) Detach(n *Node) {
+	if c.node == n {
+		c.node = nil
+	}
+}
+
+// Node returns the node the camera is attached to, or nil.
+func (c *Camera) Node() *Node {
+	return c.node
+}
+
+// SetViewport sets the aspect ratio from the size of the viewport, which
+// ScreenRay also uses.
+func (c *Camera) SetViewport(width, height int) {
+	c.width, c.height = width, height
+	if height > 0 {
+		c.Aspect = float32(width) / float32(height)
+	}
+}
+
+// Projection returns the projection matrix.
+func (c *Camera) Projection() [16]float32 {
+	if c.Orthographic {
+		w := c.Height * c.Aspect
+		return Ortho(-w/2, w/2, -c.Height/2, c.Height/2, c.Near, c.Far)
+	}
+	return Perspective(c.FieldOfView, c.Aspect, c.Near, c.Far)
+}
+
+// View returns the view matrix, the inverse of the camera node's world
+// transform. A detached camera sits at the origin.
+func (c *Camera) View() [16]float32 {
+	if c.node == nil {
+		return Identity
+	}
+	world := c.node.WorldMatrix()
+	view, _ := Invert(&world)
+	return view
+}
+
+// ViewProjection returns the projection matrix times the view matrix.
+func (c *Camera) ViewProjection() [16]float32 {
+	view, proj := c.View(), c.Projection()
+	return MulMatrix(&proj, &view)
+}
+
+// LookAt rotates the camera's node to face target, a point in world space,
+// keeping the node's +Y axis as close to up as possible. It does nothing if
+// the camera is detached.
+func (c *Camera) LookAt(target, up [3]float32) {
+	n := c.node
+	if n == nil {
+		return
+	}
+	eye := n.WorldPosition()
+	m := LookAt(eye, target, up)
+	// the camera's world rotation is the inverse of the view rotation
+	q := conjugate(matrixQuat(&m))
+	if n.parent != nil {
+		parent := n.parent.WorldMatrix()
+		pq := matrixQuat(&parent)
+		q = MulQuat(conjugate(pq), q)
+	}
+	n.SetRotation(q)
+}
+
+// Perspective returns a perspective projection matrix with a vertical field
+// of view of fovy radians, mapping depths from near to far onto [-1, 1].
+func Perspective(fovy, aspect, near, far float32) [16]float32 {
+	f := float32(1 / math.Tan(float64(fovy)/2))
+	return [16]float32{
+		f / aspect, 0, 0, 0,
+		0, f, 0, 0,
+		0, 0, (far + near) / (near - far), -1,
+		0, 0, 2 * far * near / (near - far), 0,
+	}
+}
+
+// Ortho returns an orthographic projection matrix of the given volume.
+func Ortho(left, right, bottom, top, near, far float32) [16]float32 {
+	return [16]float32{
+		2 / (right - left), 0, 0, 0,
+		0, 2 / (top - bottom), 0, 0,
+		0, 0, -2 / (far - near), 0,
+		-(right + left) / (right - left), -(top + bottom) / (top - bottom), -(far + near) / (far - near), 1,
+	}
+}
+
+// LookAt returns a view matrix for an eye at eye looking at target, with up
+// giving the upward direction.
+func LookAt(eye, target, up [3]float32) [16]float32 {
+	f := normalize(sub(target, eye))
+	s := normalize(cross(f, up))
+	u := cross(s, f)
+	return [16]float32{
+		s[0], u[0], -f[0], 0,
+		s[1], u[1], -f[1], 0,
+		s[2], u[2], -f[2], 0,
+		-dot(s, eye), -dot(u, eye), dot(f, eye), 1,
+	}
+}
+
+func sub(a, b [3]float32) [3]float32 {
+	return [3]float32{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func dot(a, b [3]float32) float32 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func cross(a, b [3]float32) [3]float32 {
+	return [3]float32{
+		a[1]*b[2] - a[2]*b[1],
+		a[2]*b[0] - a[0]*b[2],
+		a[0]*b[1] - a[1]*b[0],
+	}
+}
+
+func normalize(v [3]float32) [3]float32 {
+	l := float32(math.Sqrt(float64(dot(v, v))))
+	if l == 0 {
+		return v
+	}
+	return [3]float32{v[0] / l, v[1] / l, v[2] / l}
+}
@@ -0,0 +1,5 @@
+/*
+Package scenes provides a scene graph of nodes to which arbitrary components
+can be attached, for organizing and rendering gfx objects.
+*/
+package scenes
@@ -0,0 +1,431 @@
+/*
+Package gltf loads glTF 2.0 assets, in either .gltf or binary .glb form, into
+gfx geometry, textures, and a scenes node hierarchy.
+
+Decoding is split from loading: Decode only parses the asset, so vertex data
+and images can be read without a GL context, while Load creates GL objects
+and must run on the GL thread.
+*/
+package gltf
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrVersion     = errors.New("gltf: unsupported version")
+	ErrUnsupported = errors.New("gltf: unsupported feature")
+)
+
+type textureInfo struct {
+	Index    int     `json:"index"`
+	TexCoord int     `json:"texCoord"`
+	Scale    float32 `json:"scale"`
+	Strength float32 `json:"strength"`
+}
+
+type sampler struct {
+	MagFilter int `json:"magFilter"`
+	MinFilter int `json:"minFilter"`
+	WrapS     int `json:"wrapS"`
+	WrapT     int `json:"wrapT"`
+}
+
+type document struct {
+	Asset struct {
+		Version string `json:"version"`
+	} `json:"asset"`
+	Scene  *int `json:"scene"`
+	Scenes []struct {
+		Name  string `json:"name"`
+		Nodes []int  `json:"nodes"`
+	} `json:"scenes"`
+	Nodes []struct {
+		Name        string    `json:"name"`
+		Children    []int     `json:"children"`
+		Mesh        *int      `json:"mesh"`
+		Matrix      []float32 `json:"matrix"`
+		Translation []float32 `json:"translation"`
+		Rotation    []float32 `json:"rotation"`
+		Scale       []float32 `json:"scale"`
+	} `json:"nodes"`
+	Meshes []struct {
+		Name       string `json:"name"`
+		Primitives []struct {
+			Attributes map[string]int `json:"attributes"`
+			Indices    *int           `json:"indices"`
+			Material   *int           `json:"material"`
+			Mode       *int           `json:"mode"`
+		} `json:"primitives"`
+	} `json:"meshes"`
+	Materials []struct {
+		Name                 string `json:"name"`
+		PbrMetallicRoughness *struct {
+			BaseColorFactor          []float32    `json:"baseColorFactor"`
+			BaseColorTexture         *textureInfo `json:"baseColorTexture"`
+			MetallicFactor           *float32     `json:"metallicFactor"`
+			RoughnessFactor          *float32     `json:"roughnessFactor"`
+			MetallicRoughnessTexture *textureInfo `json:"metallicRoughnessTexture"`
+		} `json:"pbrMetallicRoughness"`
+		NormalTexture    *textureInfo `json:"normalTexture"`
+		OcclusionTexture *textureInfo `json:"occlusionTexture"`
+		EmissiveTexture  *textureInfo `json:"emissiveTexture"`
+		EmissiveFactor   []float32    `json:"emissiveFactor"`
+		AlphaMode        string       `json:"alphaMode"`
+		AlphaCutoff      *float32     `json:"alphaCutoff"`
+		DoubleSided      bool         `json:"doubleSided"`
+	} `json:"materials"`
+	Textures []struct {
+		Sampler *int `json:"sampler"`
+		Source  *int `json:"source"`
+	} `json:"textures"`
+	Images []struct {
+		URI        string `json:"uri"`
+		MimeType   string `json:"mimeType"`
+		BufferView *int   `json:"bufferView"`
+	} `json:"images"`
+	Samplers  []sampler `json:"samplers"`
+	Accessors []struct {
+		BufferView    *int            `json:"bufferView"`
+		ByteOffset    int             `json:"byteOffset"`
+		ComponentType int             `json:"componentType"`
+		Normalized    bool            `json:"normalized"`
+		Count         int             `json:"count"`
+		Type          string          `json:"type"`
+		Sparse        json.RawMessage `json:"sparse"`
+	} `json:"accessors"`
+	BufferViews []struct {
+		Buffer     int `json:"buffer"`
+		ByteOffset int `json:"byteOffset"`
+		ByteLength int `json:"byteLength"`
+		ByteStride int `json:"byteStride"`
+	} `json:"bufferViews"`
+	Buffers []struct {
+		URI        string `json:"uri"`
+		ByteLength int    `json:"byteLength"`
+	} `json:"buffers"`
+}
+
+// Document is a decoded glTF asset with its buffers resolved.
+type Document struct {
+	doc     document
+	buffers [][]byte
+	open    func(uri string) (io.ReadCloser, error)
+}
+
+const (
+	glbMagic     = 0x46546c67 // "glTF"
+	glbChunkJSON = 0x4e4f534a
+	glbChunkBin  = 0x004e4942
+)
+
+// Decode reads a .gltf or .glb asset from r. External buffers and images are
+// opened with open, which may be nil if the asset is self-contained.
+func Decode(r io.Reader, open func(uri string) (io.ReadCloser, error)) (*Document, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	d := &Document{open: open}
+	var bin []byte
+	if len(data) >= 12 && binary.LittleEndian.Uint32(data) == glbMagic {
+		data, bin, err = splitGLB(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(data, &d.doc); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(d.doc.Asset.Version, "2.") {
+		return nil, ErrVersion
+	}
+	d.buffers = make([][]byte, len(d.doc.Buffers))
+	for i, b := range d.doc.Buffers {
+		switch {
+		case b.URI == "" && i == 0 && bin != nil:
+			d.buffers[i] = bin
+		case b.URI == "":
+			return nil, fmt.Errorf("gltf: buffer %d has no data", i)
+		default:
+			d.buffers[i], err = d.readURI(b.URI)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(d.buffers[i]) < b.ByteLength {
+			return nil, fmt.Errorf("gltf: buffer %d is shorter than its byteLength", i)
+		}
+	}
+	return d, nil
+}
+
+// LoadFile decodes the named .gltf or .glb file, resolving external
+// resources relative to it.
+func LoadFile(name string) (*Document, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dir := filepath.Dir(name)
+	return Decode(f, func(uri string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(dir, filepath.FromSlash(uri)))
+	})
+}
+
+func splitGLB(data []byte) (jsonChunk, bin []byte, err error) {
+	if binary.LittleEndian.Uint32(data[4:]) != 2 {
+		return nil, nil, ErrVersion
+	}
+	length := int(binary.LittleEndian.Uint32(data[8:]))
+	if length < 12 {
+		return nil, nil, errors.New("gltf: glb length is shorter than its header")
+	}
+	if length > len(data) {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	data = data[12:length]
+	for len(data) >= 8 {
+		size := int(binary.LittleEndian.Uint32(data))
+		typ := binary.LittleEndian.Uint32(data[4:])
+		if size < 0 || 8+size > len(data) {
+			return nil, nil, io.ErrUnexpectedEOF
+		}
+		chunk := data[8 : 8+size]
+		switch typ {
+		case glbChunkJSON:
+			jsonChunk = chunk
+		case glbChunkBin:
+			bin = chunk
+		}
+		data = data[8+size:]
+	}
+	if jsonChunk == nil {
+		return nil, nil, errors.New("gltf: glb has no JSON chunk")
+	}
+	return jsonChunk, bin, nil
+}
+
+func (d *Document) readURI(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "data:") {
+		i := strings.Index(uri, ";base64,")
+		if i < 0 {
+			return nil, fmt.Errorf("gltf: unsupported data URI")
+		}
+		return base64.StdEncoding.DecodeString(uri[i+len(";base64,"):])
+	}
+	if d.open == nil {
+		return nil, fmt.Errorf("gltf: cannot open external resource %q", uri)
+	}
+	rc, err := d.open(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+// bufferView returns the bytes of a buffer view.
+func (d *Document) bufferView(i int) ([]byte, int, error) {
+	if i < 0 || i >= len(d.doc.BufferViews) {
+		return nil, 0, fmt.Errorf("gltf: buffer view %d out of range", i)
+	}
+	bv := d.doc.BufferViews[i]
+	if bv.Buffer < 0 || bv.Buffer >= len(d.buffers) {
+		return nil, 0, fmt.Errorf("gltf: buffer %d out of range", bv.Buffer)
+	}
+	buf := d.buffers[bv.Buffer]
+	if bv.ByteOffset < 0 || bv.ByteLength < 0 || bv.ByteStride < 0 {
+		return nil, 0, fmt.Errorf("gltf: buffer view %d has a negative offset, length, or stride", i)
+	}
+	if bv.ByteOffset > len(buf) || bv.ByteLength > len(buf)-bv.ByteOffset {
+		return nil, 0, fmt.Errorf("gltf: buffer view %d out of bounds", i)
+	}
+	return buf[bv.ByteOffset : bv.ByteOffset+bv.ByteLength], bv.ByteStride, nil
+}
+
+var typeComponents = map[string]int{
+	"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4, "MAT2": 4, "MAT3": 9, "MAT4": 16,
+}
+
+const (
+	compByte          = 5120
+	compUnsignedByte  = 5121
+	compShort         = 5122
+	compUnsignedShort = 5123
+	compUnsignedInt   = 5125
+	compFloat         = 5126
+)
+
+func componentSize(typ int) int {
+	switch typ {
+	case compByte, compUnsignedByte:
+		return 1
+	case compShort, compUnsignedShort:
+		return 2
+	default:
+		return 4
+	}
+}
+
+// floats reads accessor i as float32 values, returning them with the number
+// of components per element. Normalized integers are mapped to [0, 1] or
+// [-1, 1].
+func (d *Document) floats(i int) ([]float32, int, error) {
+	if i < 0 || i >= len(d.doc.Accessors) {
+		return nil, 0, fmt.Errorf("gltf: accessor %d out of range", i)
+	}
+	a := d.doc.Accessors[i]
+	if len(a.Sparse) > 0 {
+		return nil, 0, ErrUnsupported
+	}
+	comps := typeComponents[a.Type]
+	if comps == 0 {
+		return nil, 0, fmt.Errorf("gltf: accessor %d has unknown type %q", i, a.Type)
+	}
+	if a.Count < 0 || a.ByteOffset < 0 {
+		return nil, 0, fmt.Errorf("gltf: accessor %d has a negative count or offset", i)
+	}
+	if a.BufferView == nil {
+		// all zeros
+		if a.Count > math.MaxInt32/comps {
+			return nil, 0, fmt.Errorf("gltf: accessor %d count %d too large", i, a.Count)
+		}
+		return make([]float32, a.Count*comps), comps, nil
+	}
+	data, stride, err := d.bufferView(*a.BufferView)
+	if err != nil {
+		return nil, 0, err
+	}
+	csize := componentSize(a.ComponentType)
+	if stride == 0 {
+		stride = comps * csize
+	}
+	if !inBounds(len(data), a.ByteOffset, a.Count, stride, comps*csize) {
+		return nil, 0, fmt.Errorf("gltf: accessor %d out of bounds", i)
+	}
+	out := make([]float32, a.Count*comps)
+	for e := 0; e < a.Count; e++ {
+		base := a.ByteOffset + e*stride
+		for c := 0; c < comps; c++ {
+			p := data[base+c*csize:]
+			var v float32
+			switch a.ComponentType {
+			case compFloat:
+				v = math.Float32frombits(binary.LittleEndian.Uint32(p))
+			case compUnsignedByte:
+				v = float32(p[0])
+				if a.Normalized {
+					v /= 255
+				}
+			case compByte:
+				v = float32(int8(p[0]))
+				if a.Normalized {
+					v = clampNorm(v / 127)
+				}
+			case compUnsignedShort:
+				v = float32(binary.LittleEndian.Uint16(p))
+				if a.Normalized {
+					v /= 65535
+				}
+			case compShort:
+				v = float32(int16(binary.LittleEndian.Uint16(p)))
+				if a.Normalized {
+					v = clampNorm(v / 32767)
+				}
+			case compUnsignedInt:
+				v = float32(binary.LittleEndian.Uint32(p))
+			default:
+				return nil, 0, fmt.Errorf("gltf: accessor %d has unknown component type %d", i, a.ComponentType)
+			}
+			out[e*comps+c] = v
+		}
+	}
+	return out, comps, nil
+}
+
+// indices reads accessor i as unsigned integer indices.
+func (d *Document) indices(i int) ([]uint32, error) {
+	if i < 0 || i >= len(d.doc.Accessors) {
+		return nil, fmt.Errorf("gltf: accessor %d out of range", i)
+	}
+	a := d.doc.Accessors[i]
+	if a.BufferView == nil || len(a.Sparse) > 0 || a.Type != "SCALAR" {
+		return nil, ErrUnsupported
+	}
+	if a.Count < 0 || a.ByteOffset < 0 {
+		return nil, fmt.Errorf("gltf: accessor %d has a negative count or offset", i)
+	}
+	data, stride, err := d.bufferView(*a.BufferView)
+	if err != nil {
+		return nil, err
+	}
+	csize := componentSize(a.ComponentType)
+	if stride == 0 {
+		stride = csize
+	}
+	if !inBounds(len(data), a.ByteOffset, a.Count, stride, csize) {
+		return nil, fmt.Errorf("gltf: accessor %d out of bounds", i)
+	}
+	out := make([]uint32, a.Count)
+	for e := range out {
+		p := data[a.ByteOffset+e*stride:]
+		switch a.ComponentType {
+		case compUnsignedByte:
+			out[e] = uint32(p[0])
+		case compUnsignedShort:
+			out[e] = uint32(binary.LittleEndian.Uint16(p))
+		case compUnsignedInt:
+			out[e] = binary.LittleEndian.Uint32(p)
+		default:
+			return nil, fmt.Errorf("gltf: accessor %d has invalid index type %d", i, a.ComponentType)
+		}
+	}
+	return out, nil
+}
+
+// inBounds reports whether count elements of size bytes, stride bytes apart
+// from offset, fit in n bytes, without overflowing on large counts.
+func inBounds(n, offset, count, stride, size int) bool {
+	if count == 0 {
+		return true
+	}
+	avail := n - offset - size
+	return avail >= 0 && count-1 <= avail/stride
+}
+
+// clampNorm clamps a normalized signed value to -1, since the most negative
+// integer maps slightly below it.
+func clampNorm(v float32) float32 {
+	if v < -1 {
+		return -1
+	}
+	return v
+}
+
+// imageData returns the encoded bytes of image i.
+func (d *Document) imageData(i int) ([]byte, error) {
+	if i < 0 || i >= len(d.doc.Images) {
+		return nil, fmt.Errorf("gltf: image %d out of range", i)
+	}
+	img := d.doc.Images[i]
+	if img.BufferView != nil {
+		data, _, err := d.bufferView(*img.BufferView)
+		return data, err
+	}
+	if img.URI == "" {
+		return nil, fmt.Errorf("gltf: image %d has no data", i)
+	}
+	return d.readURI(img.URI)
+}
@@ -0,0 +1,154 @@
+package gltf_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/scenes/gltf"
+	"math"
+	"strings"
+	"testing"
+)
+
+// triangle returns the binary buffer of a single triangle: three float
+// positions followed by three uint16 indices.
+func triangle() []byte {
+	var buf bytes.Buffer
+	for _, f := range []float32{0, 0, 0, 1, 0, 0, 0, 1, 0} {
+		binary.Write(&buf, binary.LittleEndian, math.Float32bits(f))
+	}
+	binary.Write(&buf, binary.LittleEndian, []uint16{0, 1, 2})
+	return buf.Bytes()
+}
+
+const triangleJSON = `{
+	"asset": {"version": "2.0"},
+	"scenes": [{"nodes": [0]}],
+	"nodes": [{"name": "tri", "mesh": 0, "translation": [1, 2, 3]}],
+	"meshes": [{"primitives": [{"attributes": {"POSITION": 0}, "indices": 1}]}],
+	"accessors": [
+		{"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3"},
+		{"bufferView": 1, "componentType": 5123, "count": 3, "type": "SCALAR"}
+	],
+	"bufferViews": [
+		{"buffer": 0, "byteOffset": 0, "byteLength": 36},
+		{"buffer": 0, "byteOffset": 36, "byteLength": 6}
+	],
+	"buffers": [{"byteLength": 42%s}]
+}`
+
+func checkTriangle(t *testing.T, d *gltf.Document) {
+	if d.MeshCount() != 1 || d.PrimitiveCount(0) != 1 {
+		t.Fatalf("got %d meshes", d.MeshCount())
+	}
+	b, err := d.Primitive(0, 0, gfx.VertexPosition|gfx.VertexNormal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.VertexCount() != 3 || b.IndexCount() != 3 {
+		t.Errorf("got %d vertices and %d indices, want 3 and 3", b.VertexCount(), b.IndexCount())
+	}
+}
+
+func TestDecodeDataURI(t *testing.T) {
+	uri := `, "uri": "data:application/octet-stream;base64,` +
+		base64.StdEncoding.EncodeToString(triangle()) + `"`
+	d, err := gltf.Decode(bytes.NewReader([]byte(fmt.Sprintf(triangleJSON, uri))), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTriangle(t, d)
+}
+
+// glb packs the triangle into a binary glTF.
+func glb() []byte {
+	js := []byte(fmt.Sprintf(triangleJSON, ""))
+	for len(js)%4 != 0 {
+		js = append(js, ' ')
+	}
+	bin := triangle()
+	for len(bin)%4 != 0 {
+		bin = append(bin, 0)
+	}
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint32{
+		0x46546c67, 2, uint32(12 + 8 + len(js) + 8 + len(bin)),
+		uint32(len(js)), 0x4e4f534a,
+	})
+	buf.Write(js)
+	binary.Write(&buf, binary.LittleEndian, []uint32{uint32(len(bin)), 0x004e4942})
+	buf.Write(bin)
+	return buf.Bytes()
+}
+
+func TestDecodeGLB(t *testing.T) {
+	d, err := gltf.Decode(bytes.NewReader(glb()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTriangle(t, d)
+}
+
+func TestDecodeTruncatedGLB(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		modify func(b []byte) []byte
+	}{
+		{"header length", func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[8:], 8)
+			return b
+		}},
+		{"file", func(b []byte) []byte { return b[:len(b)-4] }},
+		{"chunk", func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[12:], 0xfffffff0)
+			return b
+		}},
+		{"no chunks", func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[8:], 12)
+			return b
+		}},
+	} {
+		if _, err := gltf.Decode(bytes.NewReader(tc.modify(glb())), nil); err == nil {
+			t.Errorf("%s: no error", tc.name)
+		}
+	}
+}
+
+func TestDecodeNegative(t *testing.T) {
+	uri := `, "uri": "data:application/octet-stream;base64,` +
+		base64.StdEncoding.EncodeToString(triangle()) + `"`
+	valid := fmt.Sprintf(triangleJSON, uri)
+	for _, tc := range []struct{ old, new string }{
+		{`"componentType": 5126, "count": 3`, `"componentType": 5126, "count": -3`},
+		{`"componentType": 5123, "count": 3`, `"componentType": 5123, "count": -3`},
+		{`"bufferView": 0,`, `"bufferView": 0, "byteOffset": -4,`},
+		{`"bufferView": 1,`, `"bufferView": 1, "byteOffset": -2,`},
+		{`"byteOffset": 36, "byteLength": 6`, `"byteOffset": -36, "byteLength": 6`},
+		{`"byteOffset": 0, "byteLength": 36`, `"byteOffset": 0, "byteLength": -36`},
+		{`"byteOffset": 0, "byteLength": 36`, `"byteOffset": 0, "byteLength": 36, "byteStride": -12`},
+		{`"byteOffset": 36, "byteLength": 6`, `"byteOffset": 36, "byteLength": 600`},
+		{`"componentType": 5126, "count": 3`, `"componentType": 5126, "count": 4611686018427387904`},
+		{`"bufferView": 0, "componentType": 5126, "count": 3`, `"componentType": 5126, "count": 4611686018427387904`},
+	} {
+		js := strings.Replace(valid, tc.old, tc.new, 1)
+		if js == valid {
+			t.Fatalf("%s is not in the document", tc.old)
+		}
+		d, err := gltf.Decode(strings.NewReader(js), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.Primitive(0, 0, gfx.VertexPosition); err == nil {
+			t.Errorf("%s: no error", tc.new)
+		}
+	}
+}
+
+func TestDecodeVersion(t *testing.T) {
+	_, err := gltf.Decode(bytes.NewReader([]byte(`{"asset": {"version": "1.0"}}`)), nil)
+	if err != gltf.ErrVersion {
+		t.Errorf("got %v, want ErrVersion", err)
+	}
+}
@@ -0,0 +1,219 @@
+package gltf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"math"
+)
+
+const modeTriangles = 4
+
+// MeshCount returns the number of meshes in the document.
+func (d *Document) MeshCount() int {
+	return len(d.doc.Meshes)
+}
+
+// PrimitiveCount returns the number of primitives in a mesh.
+func (d *Document) PrimitiveCount(mesh int) int {
+	return len(d.doc.Meshes[mesh].Primitives)
+}
+
+// Primitive decodes primitive prim of mesh into a builder of format vf. Only
+// the data present in vf is written; normals are generated when the format
+// has them and the primitive does not, as are tangents and bitangents, and
+// missing colors are white. Only triangle lists are supported.
+func (d *Document) Primitive(mesh, prim int, vf gfx.VertexFormat) (*geometry.Builder, error) {
+	if mesh < 0 || mesh >= len(d.doc.Meshes) {
+		return nil, fmt.Errorf("gltf: mesh %d out of range", mesh)
+	}
+	m := d.doc.Meshes[mesh]
+	if prim < 0 || prim >= len(m.Primitives) {
+		return nil, fmt.Errorf("gltf: primitive %d out of range", prim)
+	}
+	p := m.Primitives[prim]
+	if p.Mode != nil && *p.Mode != modeTriangles {
+		return nil, ErrUnsupported
+	}
+	posAcc, ok := p.Attributes["POSITION"]
+	if !ok {
+		return nil, fmt.Errorf("gltf: mesh %d primitive %d has no positions", mesh, prim)
+	}
+	pos, comps, err := d.floats(posAcc)
+	if err != nil {
+		return nil, err
+	}
+	if comps != 3 {
+		return nil, fmt.Errorf("gltf: positions must be VEC3")
+	}
+	count := len(pos) / 3
+
+	var idxs []uint32
+	if p.Indices != nil {
+		idxs, err = d.indices(*p.Indices)
+		if err != nil {
+			return nil, err
+		}
+		for _, idx := range idxs {
+			if int(idx) >= count {
+				return nil, fmt.Errorf("gltf: index %d out of range", idx)
+			}
+		}
+	} else {
+		idxs = make([]uint32, count)
+		for i := range idxs {
+			idxs[i] = uint32(i)
+		}
+	}
+
+	// attribute reads optional attribute name, requiring it to have want
+	// components per vertex (or either 3 or 4 when want is 0).
+	attribute := func(name string, want int) ([]float32, int, error) {
+		acc, ok := p.Attributes[name]
+		if !ok {
+			return nil, 0, nil
+		}
+		data, comps, err := d.floats(acc)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(data) != count*comps || (want != 0 && comps != want) || (want == 0 && comps != 3 && comps != 4) {
+			return nil, 0, fmt.Errorf("gltf: attribute %s does not match positions", name)
+		}
+		return data, comps, nil
+	}
+	var normals, texcoords, colors []float32
+	var colorComps int
+	if vf&gfx.VertexNormal != 0 {
+		normals, _, err = attribute("NORMAL", 3)
+		if err != nil {
+			return nil, err
+		}
+		if normals == nil {
+			normals = generateNormals(pos, idxs)
+		}
+	}
+	if vf&gfx.VertexTexcoord != 0 {
+		texcoords, _, err = attribute("TEXCOORD_0", 2)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if vf&gfx.VertexColor != 0 {
+		colors, colorComps, err = attribute("COLOR_0", 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var tangents []float32
+	wantTangents := vf&(gfx.VertexTangent|gfx.VertexBitangent) != 0
+	if wantTangents && normals != nil {
+		tangents, _, err = attribute("TANGENT", 4)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b := geometry.NewBuilder(vf)
+	for i := 0; i < count; i++ {
+		vb := b.Position(pos[i*3], pos[i*3+1], pos[i*3+2])
+		if normals != nil {
+			vb.Normal(normals[i*3], normals[i*3+1], normals[i*3+2])
+		}
+		if texcoords != nil {
+			vb.Texcoord(texcoords[i*2], texcoords[i*2+1])
+		}
+		if vf&gfx.VertexColor != 0 {
+			if colors == nil {
+				vb.Color(255, 255, 255, 255)
+			} else {
+				c := colors[i*colorComps:]
+				a := float32(1)
+				if colorComps == 4 {
+					a = c[3]
+				}
+				vb.Colorf(c[0], c[1], c[2], a)
+			}
+		}
+		if tangents != nil {
+			// w gives the handedness of the bitangent
+			t := [3]float32{tangents[i*4], tangents[i*4+1], tangents[i*4+2]}
+			w := tangents[i*4+3]
+			if vf&gfx.VertexTangent != 0 {
+				vb.Tangent(t[0], t[1], t[2])
+			}
+			if vf&gfx.VertexBitangent != 0 {
+				n := normals[i*3:]
+				vb.Bitangent(
+					w*(n[1]*t[2]-n[2]*t[1]),
+					w*(n[2]*t[0]-n[0]*t[2]),
+					w*(n[0]*t[1]-n[1]*t[0]))
+			}
+		}
+	}
+	b.Indices32(idxs...)
+	if wantTangents && tangents == nil {
+		if err := b.GenerateTangents(); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// generateNormals averages the normals of the triangles sharing each vertex.
+func generateNormals(pos []float32, idxs []uint32) []float32 {
+	normals := make([]float32, len(pos))
+	vec := func(i uint32) [3]float32 {
+		return [3]float32{pos[i*3], pos[i*3+1], pos[i*3+2]}
+	}
+	for t := 0; t+2 < len(idxs); t += 3 {
+		p0, p1, p2 := vec(idxs[t]), vec(idxs[t+1]), vec(idxs[t+2])
+		e1 := [3]float32{p1[0] - p0[0], p1[1] - p0[1], p1[2] - p0[2]}
+		e2 := [3]float32{p2[0] - p0[0], p2[1] - p0[1], p2[2] - p0[2]}
+		n := [3]float32{
+			e1[1]*e2[2] - e1[2]*e2[1],
+			e1[2]*e2[0] - e1[0]*e2[2],
+			e1[0]*e2[1] - e1[1]*e2[0],
+		}
+		for _, idx := range idxs[t : t+3] {
+			for k := 0; k < 3; k++ {
+				normals[idx*3+uint32(k)] += n[k]
+			}
+		}
+	}
+	for i := 0; i < len(normals); i += 3 {
+		n := normals[i : i+3]
+		l := float32(math.Sqrt(float64(n[0]*n[0] + n[1]*n[1] + n[2]*n[2])))
+		if l > 0 {
+			n[0], n[1], n[2] = n[0]/l, n[1]/l, n[2]/l
+		} else {
+			n[0], n[1], n[2] = 0, 1, 0
+		}
+	}
+	return normals
+}
+
+// Image decodes image i. PNG and JPEG images are supported. The result is an
+// *image.NRGBA or *image.RGBA, ready to pass to gfx.Image.
+func (d *Document) Image(i int) (image.Image, error) {
+	data, err := d.imageData(i)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("gltf: image %d: %v", i, err)
+	}
+	switch img.(type) {
+	case *image.NRGBA, *image.RGBA:
+		return img, nil
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Rect, img, img.Bounds().Min, draw.Src)
+	return rgba, nil
+}
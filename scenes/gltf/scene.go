@@ -0,0 +1,387 @@
+package gltf
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/scenes"
+	"math"
+)
+
+// decompose splits a column-major affine matrix into translation, rotation,
+// and scale, assuming it has no shear.
+func decompose(m []float32) (t [3]float32, q [4]float32, scale [3]float32) {
+	t = [3]float32{m[12], m[13], m[14]}
+	var r [9]float32
+	for c := 0; c < 3; c++ {
+		col := m[c*4 : c*4+3]
+		s := float32(math.Sqrt(float64(col[0]*col[0] + col[1]*col[1] + col[2]*col[2])))
+		scale[c] = s
+		if s != 0 {
+			r[c*3], r[c*3+1], r[c*3+2] = col[0]/s, col[1]/s, col[2]/s
+		}
+	}
+	// r is column-major: r[c*3+row]
+	m00, m11, m22 := r[0], r[4], r[8]
+	trace := m00 + m11 + m22
+	switch {
+	case trace > 0:
+		s := float32(math.Sqrt(float64(trace+1))) * 2
+		q = [4]float32{(r[5] - r[7]) / s, (r[6] - r[2]) / s, (r[1] - r[3]) / s, s / 4}
+	case m00 > m11 && m00 > m22:
+		s := float32(math.Sqrt(float64(1+m00-m11-m22))) * 2
+		q = [4]float32{s / 4, (r[3] + r[1]) / s, (r[6] + r[2]) / s, (r[5] - r[7]) / s}
+	case m11 > m22:
+		s := float32(math.Sqrt(float64(1+m11-m00-m22))) * 2
+		q = [4]float32{(r[3] + r[1]) / s, s / 4, (r[7] + r[5]) / s, (r[6] - r[2]) / s}
+	default:
+		s := float32(math.Sqrt(float64(1+m22-m00-m11))) * 2
+		q = [4]float32{(r[6] + r[2]) / s, (r[7] + r[5]) / s, s / 4, (r[1] - r[3]) / s}
+	}
+	return t, q, scale
+}
+
+// Material holds the metallic-roughness parameters of a glTF material.
+// Texture fields are nil when the material has no such texture.
+type Material struct {
+	Name string
+
+	BaseColor        [4]float32
+	BaseColorTexture *gfx.Sampler2D
+
+	Metallic                 float32
+	Roughness                float32
+	MetallicRoughnessTexture *gfx.Sampler2D // roughness in G, metallic in B
+
+	NormalTexture     *gfx.Sampler2D
+	NormalScale       float32
+	OcclusionTexture  *gfx.Sampler2D // occlusion in R
+	OcclusionStrength float32
+
+	Emissive        [3]float32
+	EmissiveTexture *gfx.Sampler2D
+
+	// AlphaMode is "OPAQUE", "MASK", or "BLEND". AlphaCutoff applies to
+	// MASK.
+	AlphaMode   string
+	AlphaCutoff float32
+	DoubleSided bool
+}
+
+// Primitive is a piece of a mesh drawn with a single material.
+type Primitive struct {
+	Geometry *gfx.Geometry
+	Material *Material
+}
+
+// Mesh is attached as a component to nodes that draw it. A mesh may be
+// shared by several nodes.
+type Mesh struct {
+	Name       string
+	Primitives []Primitive
+}
+
+// Scene is a loaded glTF scene and the GL objects it owns.
+type Scene struct {
+	// Root is a node named after the scene, holding its root nodes. Nodes
+	// that draw a mesh carry a *Mesh component.
+	Root *scenes.Node
+
+	Meshes    []*Mesh
+	Materials []*Material
+	Textures  []*gfx.Sampler2D
+}
+
+// Delete frees the geometry and textures of the scene.
+func (s *Scene) Delete() {
+	for _, m := range s.Meshes {
+		for _, p := range m.Primitives {
+			p.Geometry.Delete()
+		}
+	}
+	for _, t := range s.Textures {
+		if t != nil {
+			t.Delete()
+		}
+	}
+	s.Meshes, s.Materials, s.Textures = nil, nil, nil
+}
+
+// Load creates the geometry, textures, and node hierarchy of the document's
+// default scene, with vertices of format vf. It must be called on the GL
+// thread. If the document has no scenes, every node without a parent is a
+// root.
+func (d *Document) Load(vf gfx.VertexFormat) (*Scene, error) {
+	l := &loader{
+		d:         d,
+		vf:        vf,
+		scene:     &Scene{Root: scenes.NewNode("")},
+		meshes:    make([]*Mesh, len(d.doc.Meshes)),
+		materials: make([]*Material, len(d.doc.Materials)),
+		textures:  make([]*gfx.Sampler2D, len(d.doc.Textures)),
+		visited:   make([]bool, len(d.doc.Nodes)),
+	}
+	if err := l.load(); err != nil {
+		l.scene.Delete()
+		return nil, err
+	}
+	return l.scene, nil
+}
+
+type loader struct {
+	d         *Document
+	vf        gfx.VertexFormat
+	scene     *Scene
+	meshes    []*Mesh
+	materials []*Material
+	textures  []*gfx.Sampler2D
+	visited   []bool
+}
+
+func (l *loader) load() error {
+	doc := &l.d.doc
+	var roots []int
+	switch {
+	case len(doc.Scenes) > 0:
+		i := 0
+		if doc.Scene != nil {
+			i = *doc.Scene
+		}
+		if i < 0 || i >= len(doc.Scenes) {
+			return fmt.Errorf("gltf: scene %d out of range", i)
+		}
+		l.scene.Root.Name = doc.Scenes[i].Name
+		roots = doc.Scenes[i].Nodes
+	default:
+		child := make([]bool, len(doc.Nodes))
+		for _, n := range doc.Nodes {
+			for _, c := range n.Children {
+				if c >= 0 && c < len(child) {
+					child[c] = true
+				}
+			}
+		}
+		for i, c := range child {
+			if !c {
+				roots = append(roots, i)
+			}
+		}
+	}
+	for _, i := range roots {
+		n, err := l.node(i)
+		if err != nil {
+			return err
+		}
+		l.scene.Root.Add(n)
+	}
+	return nil
+}
+
+func (l *loader) node(i int) (*scenes.Node, error) {
+	if i < 0 || i >= len(l.d.doc.Nodes) {
+		return nil, fmt.Errorf("gltf: node %d out of range", i)
+	}
+	if l.visited[i] {
+		return nil, fmt.Errorf("gltf: node %d has more than one parent", i)
+	}
+	l.visited[i] = true
+	src := l.d.doc.Nodes[i]
+	n := scenes.NewNode(src.Name)
+
+	t, q, s := [3]float32{}, scenes.IdentityQuat, [3]float32{1, 1, 1}
+	if len(src.Matrix) == 16 {
+		t, q, s = decompose(src.Matrix)
+	} else {
+		copy(t[:], src.Translation)
+		copy(q[:], src.Rotation)
+		copy(s[:], src.Scale)
+	}
+	n.SetPosition(t[0], t[1], t[2])
+	n.SetRotation(q)
+	n.SetScale(s[0], s[1], s[2])
+
+	if src.Mesh != nil {
+		m, err := l.mesh(*src.Mesh)
+		if err != nil {
+			return nil, err
+		}
+		n.Attach(m)
+	}
+	for _, c := range src.Children {
+		child, err := l.node(c)
+		if err != nil {
+			return nil, err
+		}
+		n.Add(child)
+	}
+	return n, nil
+}
+
+func (l *loader) mesh(i int) (*Mesh, error) {
+	if i < 0 || i >= len(l.meshes) {
+		return nil, fmt.Errorf("gltf: mesh %d out of range", i)
+	}
+	if l.meshes[i] != nil {
+		return l.meshes[i], nil
+	}
+	src := l.d.doc.Meshes[i]
+	m := &Mesh{Name: src.Name}
+	l.meshes[i] = m
+	l.scene.Meshes = append(l.scene.Meshes, m)
+	for j, p := range src.Primitives {
+		b, err := l.d.Primitive(i, j, l.vf)
+		if err != nil {
+			return nil, err
+		}
+		var mat *Material
+		if p.Material != nil {
+			mat, err = l.material(*p.Material)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			mat = defaultMaterial()
+		}
+		geom, err := gfx.NewGeometry(b, gfx.StaticDraw)
+		if err != nil {
+			return nil, err
+		}
+		m.Primitives = append(m.Primitives, Primitive{geom, mat})
+	}
+	return m, nil
+}
+
+func defaultMaterial() *Material {
+	return &Material{
+		BaseColor:         [4]float32{1, 1, 1, 1},
+		Metallic:          1,
+		Roughness:         1,
+		NormalScale:       1,
+		OcclusionStrength: 1,
+		AlphaMode:         "OPAQUE",
+		AlphaCutoff:       0.5,
+	}
+}
+
+func (l *loader) material(i int) (*Material, error) {
+	if i < 0 || i >= len(l.materials) {
+		return nil, fmt.Errorf("gltf: material %d out of range", i)
+	}
+	if l.materials[i] != nil {
+		return l.materials[i], nil
+	}
+	src := l.d.doc.Materials[i]
+	m := defaultMaterial()
+	m.Name = src.Name
+	var err error
+	if pbr := src.PbrMetallicRoughness; pbr != nil {
+		copy(m.BaseColor[:], pbr.BaseColorFactor)
+		if pbr.MetallicFactor != nil {
+			m.Metallic = *pbr.MetallicFactor
+		}
+		if pbr.RoughnessFactor != nil {
+			m.Roughness = *pbr.RoughnessFactor
+		}
+		if m.BaseColorTexture, err = l.texture(pbr.BaseColorTexture); err != nil {
+			return nil, err
+		}
+		if m.MetallicRoughnessTexture, err = l.texture(pbr.MetallicRoughnessTexture); err != nil {
+			return nil, err
+		}
+	}
+	if m.NormalTexture, err = l.texture(src.NormalTexture); err != nil {
+		return nil, err
+	}
+	if src.NormalTexture != nil && src.NormalTexture.Scale != 0 {
+		m.NormalScale = src.NormalTexture.Scale
+	}
+	if m.OcclusionTexture, err = l.texture(src.OcclusionTexture); err != nil {
+		return nil, err
+	}
+	if src.OcclusionTexture != nil && src.OcclusionTexture.Strength != 0 {
+		m.OcclusionStrength = src.OcclusionTexture.Strength
+	}
+	if m.EmissiveTexture, err = l.texture(src.EmissiveTexture); err != nil {
+		return nil, err
+	}
+	copy(m.Emissive[:], src.EmissiveFactor)
+	if src.AlphaMode != "" {
+		m.AlphaMode = src.AlphaMode
+	}
+	if src.AlphaCutoff != nil {
+		m.AlphaCutoff = *src.AlphaCutoff
+	}
+	m.DoubleSided = src.DoubleSided
+	l.materials[i] = m
+	l.scene.Materials = append(l.scene.Materials, m)
+	return m, nil
+}
+
+// GL sampler enums used by glTF.
+const (
+	glNearest              = 9728
+	glNearestMipmapNearest = 9984
+	glNearestMipmapLinear  = 9986
+	glLinearMipmapLinear   = 9987
+	glClampToEdge          = 33071
+	glMirroredRepeat       = 33648
+)
+
+func (l *loader) texture(info *textureInfo) (*gfx.Sampler2D, error) {
+	if info == nil {
+		return nil, nil
+	}
+	i := info.Index
+	if i < 0 || i >= len(l.textures) {
+		return nil, fmt.Errorf("gltf: texture %d out of range", i)
+	}
+	if l.textures[i] != nil {
+		return l.textures[i], nil
+	}
+	src := l.d.doc.Textures[i]
+	if src.Source == nil {
+		return nil, fmt.Errorf("gltf: texture %d has no image", i)
+	}
+	img, err := l.d.Image(*src.Source)
+	if err != nil {
+		return nil, err
+	}
+	opts := &gfx.SamplerOptions{Mipmaps: true}
+	if src.Sampler != nil {
+		if *src.Sampler < 0 || *src.Sampler >= len(l.d.doc.Samplers) {
+			return nil, fmt.Errorf("gltf: sampler %d out of range", *src.Sampler)
+		}
+		opts = samplerOptions(l.d.doc.Samplers[*src.Sampler])
+	}
+	tex, err := gfx.Image(img, opts)
+	if err != nil {
+		return nil, err
+	}
+	l.textures[i] = tex
+	l.scene.Textures = append(l.scene.Textures, tex)
+	return tex, nil
+}
+
+func samplerOptions(s sampler) *gfx.SamplerOptions {
+	wrap := func(w int) gfx.Wrap {
+		switch w {
+		case glClampToEdge:
+			return gfx.WrapClamp
+		case glMirroredRepeat:
+			return gfx.WrapMirror
+		}
+		return gfx.WrapRepeat
+	}
+	opts := &gfx.SamplerOptions{
+		WrapS:   wrap(s.WrapS),
+		WrapT:   wrap(s.WrapT),
+		Mipmaps: s.MinFilter == 0 || s.MinFilter >= glNearestMipmapNearest && s.MinFilter <= glLinearMipmapLinear,
+	}
+	if s.MagFilter == glNearest {
+		opts.MagFilter = gfx.FilterNearest
+	}
+	switch s.MinFilter {
+	case glNearest, glNearestMipmapNearest, glNearestMipmapLinear:
+		opts.MinFilter = gfx.FilterNearest
+	}
+	return opts
+}
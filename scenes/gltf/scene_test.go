@@ -0,0 +1,47 @@
+package gltf
+
+import (
+	"j4k.co/gfx/scenes"
+	"math"
+	"testing"
+)
+
+func TestDecompose(t *testing.T) {
+	h := float32(math.Sqrt(0.5))
+	tests := []struct {
+		name string
+		q    [4]float32
+	}{
+		{"identity", [4]float32{0, 0, 0, 1}},
+		{"quarter turn about y", [4]float32{0, h, 0, h}},
+		{"third turn about xyz", [4]float32{0.5, 0.5, 0.5, 0.5}},
+		{"half turn about x", [4]float32{1, 0, 0, 0}},
+		{"half turn about y", [4]float32{0, 1, 0, 0}},
+		{"half turn about z", [4]float32{0, 0, 1, 0}},
+	}
+	near := func(a, b float32) bool { return math.Abs(float64(a-b)) < 1e-5 }
+	for _, tt := range tests {
+		trans, scale := [3]float32{1, -2, 3}, [3]float32{2, 3, 0.5}
+		m := scenes.Compose(trans, tt.q, scale)
+		gotT, gotQ, gotS := decompose(m[:])
+		// q and -q are the same rotation
+		sign := float32(1)
+		if gotQ[0]*tt.q[0]+gotQ[1]*tt.q[1]+gotQ[2]*tt.q[2]+gotQ[3]*tt.q[3] < 0 {
+			sign = -1
+		}
+		for i := 0; i < 4; i++ {
+			if i < 3 && (!near(gotT[i], trans[i]) || !near(gotS[i], scale[i])) ||
+				!near(sign*gotQ[i], tt.q[i]) {
+				t.Errorf("%s: got %v, %v, %v, want %v, %v, %v", tt.name, gotT, gotQ, gotS, trans, tt.q, scale)
+				break
+			}
+		}
+		back := scenes.Compose(gotT, gotQ, gotS)
+		for i := range m {
+			if !near(back[i], m[i]) {
+				t.Errorf("%s: composed again to %v, want %v", tt.name, back, m)
+				break
+			}
+		}
+	}
+}
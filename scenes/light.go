@@ -0,0 +1,115 @@
+package scenes
+
+import (
+	"math"
+)
+
+// MaxLights is the most lights uploaded to lit materials. Lights beyond it
+// are ignored.
+const MaxLights = 8
+
+// LightBinding is the uniform buffer binding point of the light block given
+// to materials with Lit set, which is declared in GLSL as:
+//
+//	struct Light {
+//		vec4 Position;  // w is 0 for directional lights and 1 otherwise
+//		vec4 Direction; // w is the range, or 0 for no limit
+//		vec4 Color;     // color times intensity
+//		vec4 Cone;      // cosines of the inner and outer spot angles
+//	};
+//	layout(std140) uniform Lights {
+//		vec4 Ambient;
+//		int LightCount;
+//		Light Light[8];
+//	};
+//
+// A light contributes smoothstep(Cone.y, Cone.x, dot(-L, Direction.xyz))
+// of its color, where L points from the surface to the light, which leaves
+// directional and point lights unattenuated.
+const LightBinding = 0
+
+// DirectionalLight is a component lighting the scene from infinitely far
+// away, along the -Z axis of its node, like the sun.
+type DirectionalLight struct {
+	Color     [3]float32
+	Intensity float32
+}
+
+// PointLight is a component emitting light in all directions from the
+// position of its node.
+type PointLight struct {
+	Color     [3]float32
+	Intensity float32
+
+	// Range is the distance at which the light has no effect, or 0 for no
+	// limit.
+	Range float32
+}
+
+// SpotLight is a component emitting a cone of light from the position of
+// its node along its -Z axis.
+type SpotLight struct {
+	Color     [3]float32
+	Intensity float32
+	Range     float32
+
+	// InnerAngle and OuterAngle are the half-angles in radians of the cone,
+	// between which the light fades out.
+	InnerAngle, OuterAngle float32
+}
+
+// lightUniform is a light in world space, as laid out in the light block.
+type lightUniform struct {
+	Position  [4]float32
+	Direction [4]float32
+	Color     [4]float32
+	Cone      [4]float32
+}
+
+// lightBlock is the Go side of the Lights block.
+type lightBlock struct {
+	Ambient [4]float32              `uniform:"Ambient"`
+	Count   int32                   `uniform:"LightCount"`
+	Lights  [MaxLights]lightUniform `uniform:"Light"`
+}
+
+var noCone = [4]float32{-1, -2, 0, 0}
+
+// addLight appends c to the block if it is a light component of n.
+func (b *lightBlock) addLight(n *Node, c Component) {
+	var l lightUniform
+	switch c := c.(type) {
+	case *DirectionalLight:
+		l.Color = intensity(c.Color, c.Intensity)
+		l.Cone = noCone
+	case *PointLight:
+		l.Color = intensity(c.Color, c.Intensity)
+		l.Direction[3] = c.Range
+		l.Cone = noCone
+	case *SpotLight:
+		l.Color = intensity(c.Color, c.Intensity)
+		l.Direction[3] = c.Range
+		l.Cone = [4]float32{
+			float32(math.Cos(float64(c.InnerAngle))),
+			float32(math.Cos(float64(c.OuterAngle))),
+			0, 0,
+		}
+	default:
+		return
+	}
+	if b.Count == MaxLights {
+		return
+	}
+	m := n.WorldMatrix()
+	dir := normalize([3]float32{-m[8], -m[9], -m[10]})
+	l.Direction[0], l.Direction[1], l.Direction[2] = dir[0], dir[1], dir[2]
+	if _, ok := c.(*DirectionalLight); !ok {
+		l.Position = [4]float32{m[12], m[13], m[14], 1}
+	}
+	b.Lights[b.Count] = l
+	b.Count++
+}
+
+func intensity(c [3]float32, i float32) [4]float32 {
+	return [4]float32{c[0] * i, c[1] * i, c[2] * i, 1}
+}
@@ -0,0 +1,109 @@
+package scenes_test
+
+import (
+	"encoding/binary"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"j4k.co/gfx/scenes"
+	"math"
+	"testing"
+)
+
+const litShader gfx.FragmentShader = `
+struct Light {
+	vec4 Position;
+	vec4 Direction;
+	vec4 Color;
+	vec4 Cone;
+};
+layout(std140) uniform Lights {
+	vec4 Ambient;
+	int LightCount;
+	Light Light[8];
+};
+
+void main() {
+	gl_FragColor = Ambient + Light[0].Color;
+}`
+
+func TestRenderLights(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	geom := triangle(t)
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, worldShader, litShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	r := scenes.NewRenderer()
+	defer r.Delete()
+	r.Ambient = [3]float32{0.1, 0.2, 0.3}
+	cam := scenes.NewPerspective(math.Pi/2, 1, 0.1, 100)
+
+	root := scenes.NewNode("root")
+	for _, c := range []scenes.Component{
+		&scenes.DirectionalLight{Color: [3]float32{1, 1, 1}, Intensity: 2},
+		&scenes.PointLight{Color: [3]float32{1, 0, 0}, Intensity: 1, Range: 10},
+		&scenes.SpotLight{Color: [3]float32{0, 0, 1}, Intensity: 1, Range: 5, OuterAngle: math.Pi / 3},
+	} {
+		n := scenes.NewNode("light")
+		n.SetPosition(1, 2, 3)
+		n.Attach(c)
+		root.Add(n)
+	}
+	lit := &scenes.Material{Shader: s, Lit: true}
+	for _, z := range []float32{-5, -6} {
+		n := scenes.NewNode("mesh")
+		n.SetPosition(0, 0, z)
+		n.Attach(&scenes.Mesh{Geometry: geom, Material: lit})
+		root.Add(n)
+	}
+	rec.Reset()
+	if err := r.Render(root, cam); err != nil {
+		t.Fatal(err)
+	}
+
+	// the block is uploaded and bound once for both meshes
+	uploads := rec.Ops("BufferSubData")
+	if len(uploads) != 1 {
+		t.Fatalf("got uploads %v, want 1", uploads)
+	}
+	if n := len(rec.Ops("BindBufferBase")); n != 1 {
+		t.Errorf("bound the block %d times, want 1", n)
+	}
+	data := uploads[0].Args[3].([]byte)
+	vec4 := func(offset int) [4]float32 {
+		var v [4]float32
+		for i := range v {
+			v[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[offset+4*i:]))
+		}
+		return v
+	}
+	if a := vec4(0); a != [4]float32{0.1, 0.2, 0.3, 1} {
+		t.Errorf("got ambient %v", a)
+	}
+	if n := binary.LittleEndian.Uint32(data[16:]); n != 3 {
+		t.Errorf("got %d lights, want 3", n)
+	}
+	// each light is four vec4s from byte 32: position, direction, color and
+	// cone
+	light := func(i, field int) [4]float32 { return vec4(32 + 64*i + 16*field) }
+	for _, c := range []struct {
+		light, field int
+		want         [4]float32
+	}{
+		{0, 0, [4]float32{0, 0, 0, 0}},
+		{0, 1, [4]float32{0, 0, -1, 0}},
+		{0, 2, [4]float32{2, 2, 2, 1}},
+		{1, 0, [4]float32{1, 2, 3, 1}},
+		{1, 1, [4]float32{0, 0, -1, 10}},
+		{1, 2, [4]float32{1, 0, 0, 1}},
+		{1, 3, [4]float32{-1, -2, 0, 0}},
+		{2, 3, [4]float32{1, float32(math.Cos(float64(float32(math.Pi / 3)))), 0, 0}},
+	} {
+		if got := light(c.light, c.field); got != c.want {
+			t.Errorf("light %d field %d: got %v, want %v", c.light, c.field, got, c.want)
+		}
+	}
+}
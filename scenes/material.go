@@ -0,0 +1,72 @@
+package scenes
+
+import (
+	"j4k.co/gfx"
+)
+
+// Material describes how a mesh is shaded. A material may be shared by any
+// number of meshes; the renderer only reassigns its uniforms, textures, and
+// state when the material changes between draws.
+type Material struct {
+	Shader *gfx.Shader
+
+	// Uniforms is an optional uniform struct (see gfx.Shader.AssignUniforms)
+	// assigned before drawing meshes of the material.
+	Uniforms interface{}
+
+	// Textures maps sampler uniform names to the textures bound to them.
+	Textures map[string]*gfx.Sampler2D
+
+	// Lit materials are given the lights of the scene; see Renderer.
+	Lit bool
+
+	// State is the fixed-function state the material is drawn with, such as
+	// gfx.State{Blend: gfx.AlphaBlend}. Materials that blend are drawn after
+	// opaque ones.
+	State gfx.State
+
+	// id identifies the material in sort keys, once assigned.
+	id uint64
+}
+
+// lastMaterialID is the id last assigned to a material.
+var lastMaterialID uint64
+
+// key returns the id of m, assigning it the first time.
+func (m *Material) key() uint64 {
+	if m.id == 0 {
+		lastMaterialID++
+		m.id = lastMaterialID
+	}
+	return m.id
+}
+
+// Clone returns a copy of m sharing its shader and textures, for making
+// variants of a material.
+func (m *Material) Clone() *Material {
+	c := *m
+	c.id = 0
+	if m.Textures != nil {
+		c.Textures = make(map[string]*gfx.Sampler2D, len(m.Textures))
+		for name, tex := range m.Textures {
+			c.Textures[name] = tex
+		}
+	}
+	return &c
+}
+
+// bind assigns the uniforms and textures of m to its shader, which must be
+// in use.
+func (m *Material) bind() error {
+	if m.Uniforms != nil {
+		if err := m.Shader.AssignUniforms(m.Uniforms); err != nil {
+			return err
+		}
+	}
+	for name, tex := range m.Textures {
+		if err := m.Shader.SetTexture(name, tex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package scenes_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"j4k.co/gfx/scenes"
+	"math"
+	"testing"
+)
+
+const textureShader gfx.FragmentShader = `
+uniform sampler2D Texture;
+
+void main() {
+	gl_FragColor = texture2D(Texture, vec2(0.0));
+}`
+
+func TestMaterial(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	geom := triangle(t)
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, worldShader, textureShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	var texs [2]*gfx.Sampler2D
+	for i := range texs {
+		if texs[i], err = gfx.NewSampler2D(4, 4, gfx.PixelRGBA8); err != nil {
+			t.Fatal(err)
+		}
+		defer texs[i].Delete()
+	}
+	r := scenes.NewRenderer()
+	defer r.Delete()
+	cam := scenes.NewPerspective(math.Pi/2, 1, 0.1, 100)
+
+	mat := &scenes.Material{
+		Shader:   s,
+		Textures: map[string]*gfx.Sampler2D{"Texture": texs[0]},
+		State:    gfx.State{Cull: gfx.CullNone},
+	}
+	variant := mat.Clone()
+	variant.Textures["Texture"] = texs[1]
+	if mat.Textures["Texture"] != texs[0] {
+		t.Error("changing the textures of a clone changed the original")
+	}
+	root := scenes.NewNode("root")
+	for i, m := range []*scenes.Material{mat, mat, variant} {
+		n := scenes.NewNode("mesh")
+		n.SetPosition(0, 0, -float32(i+2))
+		n.Attach(&scenes.Mesh{Geometry: geom, Material: m})
+		root.Add(n)
+	}
+	rec.Reset()
+	if err := r.Render(root, cam); err != nil {
+		t.Fatal(err)
+	}
+
+	// the shared material is bound once for both of its meshes, then the
+	// variant binds its own texture
+	var got []string
+	for _, c := range rec.Ops("Uniform1i", "DrawArrays") {
+		got = append(got, c.String())
+	}
+	draw := "DrawArrays(TRIANGLES, 0, 3)"
+	want := fmt.Sprint([]string{
+		`Uniform1i("Texture", 0)`, draw, draw,
+		`Uniform1i("Texture", 1)`, draw,
+	})
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v,\nwant %v", got, want)
+	}
+	var state []string
+	for _, c := range rec.Ops("Disable", "Enable") {
+		state = append(state, c.String())
+	}
+	if fmt.Sprint(state) != "[Disable(BLEND) Enable(DEPTH_TEST) Disable(CULL_FACE) Disable(SCISSOR_TEST) Disable(FRAMEBUFFER_SRGB)]" {
+		t.Errorf("got state %v, want culling state once", state)
+	}
+}
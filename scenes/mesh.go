@@ -0,0 +1,39 @@
+package scenes
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+)
+
+// Mesh is a component that draws geometry with a material at the world
+// transform of its node.
+type Mesh struct {
+	Geometry *gfx.Geometry
+	Material *Material
+
+	// Source optionally keeps the triangles the geometry was built from,
+	// for Pick to test. Without them, Pick only tests the geometry's
+	// bounds.
+	Source *geometry.Builder
+}
+
+// Drawable is implemented by components that draw ranges of geometry chosen
+// for each camera, such as terrain, whose chunks are selected by their
+// distance to it. Renderer.Render calls AppendParts for each Drawable in
+// the scene, on the GL thread, and draws the parts like meshes at the world
+// transform of its node.
+type Drawable interface {
+	AppendParts(dst []Part, n *Node, cam *Camera) ([]Part, error)
+}
+
+// Part is a range of geometry drawn by a Drawable. Consecutive parts of the
+// same geometry and material are drawn together where they can be.
+type Part struct {
+	Geometry *gfx.Geometry
+	Material *Material
+
+	// First and Count select a range of indices, or of vertices for
+	// geometry without indices. A zero Count draws everything from First
+	// on.
+	First, Count int
+}
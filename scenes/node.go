@@ -0,0 +1,187 @@
+package scenes
+
+import (
+	"reflect"
+)
+
+// Component is any user value attached to a Node, such as a script, a
+// physics body, or an audio emitter. Components may optionally implement
+// Attacher, Detacher, and Updater to receive lifecycle callbacks.
+type Component interface{}
+
+// Attacher is implemented by components that want to know when they are
+// attached to a node.
+type Attacher interface {
+	Attach(n *Node)
+}
+
+// Detacher is implemented by components that want to know when they are
+// detached from a node.
+type Detacher interface {
+	Detach(n *Node)
+}
+
+// Updater is implemented by components that are updated once per frame.
+type Updater interface {
+	Update(n *Node, dt float64)
+}
+
+// Node is an element of the scene graph. It has a parent, children, a
+// transform relative to its parent, and a list of components. The zero
+// value is an unnamed node with an identity transform, like one from
+// NewNode.
+type Node struct {
+	Name       string
+	parent     *Node
+	children   []*Node
+	components []Component
+	xf         transform
+}
+
+// NewNode returns a new node with no parent and an identity transform.
+func NewNode(name string) *Node {
+	n := &Node{Name: name}
+	n.xf.init()
+	return n
+}
+
+// Parent returns the parent node, or nil if n is a root.
+func (n *Node) Parent() *Node {
+	return n.parent
+}
+
+// Children returns the child nodes. The slice must not be modified.
+func (n *Node) Children() []*Node {
+	return n.children
+}
+
+// Add appends child to the children of n, removing it from its previous
+// parent. It panics if child is n or one of its ancestors, which would make
+// the graph a cycle.
+func (n *Node) Add(child *Node) {
+	for p := n; p != nil; p = p.parent {
+		if p == child {
+			panic("scenes: adding a node to itself or its descendant")
+		}
+	}
+	if child.parent != nil {
+		child.parent.Remove(child)
+	}
+	child.parent = n
+	n.children = append(n.children, child)
+	child.invalidateWorld()
+}
+
+// Remove removes child from the children of n. It reports whether child was
+// found.
+func (n *Node) Remove(child *Node) bool {
+	for i, c := range n.children {
+		if c == child {
+			copy(n.children[i:], n.children[i+1:])
+			n.children[len(n.children)-1] = nil
+			n.children = n.children[:len(n.children)-1]
+			child.parent = nil
+			child.invalidateWorld()
+			return true
+		}
+	}
+	return false
+}
+
+// Walk calls fn for n and each of its descendants in depth-first order. If
+// fn returns false, the children of that node are skipped.
+func (n *Node) Walk(fn func(*Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for _, c := range n.children {
+		c.Walk(fn)
+	}
+}
+
+// Attach adds c to the components of n and calls its Attach method if it
+// implements Attacher.
+func (n *Node) Attach(c Component) {
+	n.components = append(n.components, c)
+	if a, ok := c.(Attacher); ok {
+		a.Attach(n)
+	}
+}
+
+// Detach removes c from the components of n and calls its Detach method if
+// it implements Detacher. It reports whether c was found. Components of
+// types that cannot be compared, such as slices, maps and funcs, are never
+// found; attach pointers to them instead.
+func (n *Node) Detach(c Component) bool {
+	if c != nil && !reflect.TypeOf(c).Comparable() {
+		return false
+	}
+	for i, v := range n.components {
+		if v == c {
+			copy(n.components[i:], n.components[i+1:])
+			n.components[len(n.components)-1] = nil
+			n.components = n.components[:len(n.components)-1]
+			if d, ok := c.(Detacher); ok {
+				d.Detach(n)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Components returns the components attached to n. The slice must not be
+// modified.
+func (n *Node) Components() []Component {
+	return n.components
+}
+
+// Component finds the first component assignable to the type pointed to by
+// ptr, and if found, sets *ptr to it and returns true. ptr must be a non-nil
+// pointer to an interface or to a concrete component type, for example:
+//
+//	var body *Body
+//	if node.Component(&body) { ... }
+func (n *Node) Component(ptr interface{}) bool {
+	val := reflect.ValueOf(ptr)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		panic("scenes: Component requires a non-nil pointer")
+	}
+	elem := val.Elem()
+	typ := elem.Type()
+	for _, c := range n.components {
+		if c == nil {
+			continue
+		}
+		cv := reflect.ValueOf(c)
+		if cv.Type().AssignableTo(typ) {
+			elem.Set(cv)
+			return true
+		}
+	}
+	return false
+}
+
+// EachComponent calls fn for every component of n whose type is assignable
+// to typ. typ is typically obtained with reflect.TypeOf on a value of the
+// wanted type, or reflect.TypeOf((*Iface)(nil)).Elem() for an interface.
+func (n *Node) EachComponent(typ reflect.Type, fn func(Component)) {
+	for _, c := range n.components {
+		if c != nil && reflect.TypeOf(c).AssignableTo(typ) {
+			fn(c)
+		}
+	}
+}
+
+// Update calls Update on every component implementing Updater for n and all
+// of its descendants.
+func (n *Node) Update(dt float64) {
+	n.Walk(func(node *Node) bool {
+		for _, c := range node.components {
+			if u, ok := c.(Updater); ok {
+				u.Update(node, dt)
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,237 @@
+package scenes_test
+
+import (
+	"j4k.co/gfx/scenes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type counter struct {
+	attached, detached, updates int
+}
+
+func (c *counter) Attach(n *scenes.Node)             { c.attached++ }
+func (c *counter) Detach(n *scenes.Node)             { c.detached++ }
+func (c *counter) Update(n *scenes.Node, dt float64) { c.updates++ }
+
+type tag string
+
+func TestComponentLifecycle(t *testing.T) {
+	root := scenes.NewNode("root")
+	child := scenes.NewNode("child")
+	root.Add(child)
+
+	c := &counter{}
+	child.Attach(c)
+	child.Attach(tag("enemy"))
+	root.Update(1.0 / 60)
+	if c.attached != 1 || c.updates != 1 {
+		t.Fatalf("got %+v, want 1 attach and 1 update", *c)
+	}
+
+	var found *counter
+	if !child.Component(&found) || found != c {
+		t.Fatal("Component did not find *counter")
+	}
+	var u scenes.Updater
+	if !child.Component(&u) {
+		t.Fatal("Component did not find Updater")
+	}
+	n := 0
+	child.EachComponent(reflect.TypeOf(tag("")), func(scenes.Component) { n++ })
+	if n != 1 {
+		t.Fatalf("EachComponent visited %d tags, want 1", n)
+	}
+
+	if !child.Detach(c) || c.detached != 1 {
+		t.Fatal("Detach did not detach")
+	}
+	if child.Component(&found) {
+		t.Fatal("detached component still found")
+	}
+
+	// components that cannot be compared are never found, without panicking
+	path := []float32{0, 1, 2}
+	child.Attach(path)
+	if child.Detach(path) {
+		t.Error("Detach found a slice component")
+	}
+	if !child.Detach(tag("enemy")) {
+		t.Error("Detach did not find a comparable component after a slice")
+	}
+}
+
+func TestReparent(t *testing.T) {
+	a := scenes.NewNode("a")
+	b := scenes.NewNode("b")
+	c := scenes.NewNode("c")
+	a.Add(c)
+	b.Add(c)
+	if len(a.Children()) != 0 || c.Parent() != b {
+		t.Fatal("Add did not reparent node")
+	}
+}
+
+func TestAddCycle(t *testing.T) {
+	a := scenes.NewNode("a")
+	b := scenes.NewNode("b")
+	a.Add(b)
+	for _, n := range []*scenes.Node{a, b} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("no panic adding %s under b", n.Name)
+				}
+			}()
+			b.Add(n)
+		}()
+	}
+	if b.Parent() != a || len(b.Children()) != 0 {
+		t.Error("a rejected Add changed the graph")
+	}
+}
+
+func near(a, b [16]float32) bool {
+	for i := range a {
+		if d := a[i] - b[i]; d > 1e-5 || d < -1e-5 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestWorldMatrix(t *testing.T) {
+	root := scenes.NewNode("root")
+	child := scenes.NewNode("child")
+	root.Add(child)
+
+	// a quarter turn about z maps the child's x offset onto y
+	root.SetRotation(scenes.AxisAngle([3]float32{0, 0, 1}, math.Pi/2))
+	root.SetScale(2, 2, 2)
+	child.SetPosition(1, 0, 0)
+	if p := child.WorldPosition(); math.Abs(float64(p[0])) > 1e-5 || math.Abs(float64(p[1]-2)) > 1e-5 {
+		t.Fatalf("got world position %v, want [0 2 0]", p)
+	}
+
+	// moving the parent invalidates the cached child matrix
+	root.SetPosition(5, 0, 0)
+	want := [16]float32{0, 2, 0, 0, -2, 0, 0, 0, 0, 0, 2, 0, 5, 2, 0, 1}
+	if m := child.WorldMatrix(); !near(m, want) {
+		t.Fatalf("got %v, want %v", m, want)
+	}
+
+	// reparenting does too
+	root.Remove(child)
+	if p := child.WorldPosition(); p != [3]float32{1, 0, 0} {
+		t.Fatalf("got %v after removal, want [1 0 0]", p)
+	}
+}
+
+func TestZeroNode(t *testing.T) {
+	var n scenes.Node
+	if m := n.WorldMatrix(); m != scenes.Identity {
+		t.Errorf("got world matrix %v, want the identity", m)
+	}
+	if s := n.Scale(); s != [3]float32{1, 1, 1} {
+		t.Errorf("got scale %v, want [1 1 1]", s)
+	}
+
+	// a zero node positioned before anything else keeps a unit scale
+	child := &scenes.Node{Name: "child"}
+	child.SetPosition(1, 2, 3)
+	n.Add(child)
+	want := scenes.Identity
+	want[12], want[13], want[14] = 1, 2, 3
+	if m := child.WorldMatrix(); m != want {
+		t.Errorf("got %v, want %v", m, want)
+	}
+}
+
+func TestInvert(t *testing.T) {
+	m := scenes.Compose([3]float32{1, 2, 3}, scenes.AxisAngle([3]float32{0, 1, 0}, 0.5), [3]float32{2, 3, 4})
+	inv, ok := scenes.Invert(&m)
+	if !ok {
+		t.Fatal("matrix reported singular")
+	}
+	if p := scenes.MulMatrix(&m, &inv); !near(p, scenes.Identity) {
+		t.Fatalf("m * inverse = %v, want identity", p)
+	}
+	if _, ok := scenes.Invert(&[16]float32{}); ok {
+		t.Fatal("zero matrix reported invertible")
+	}
+}
+
+func TestCameraLookAt(t *testing.T) {
+	root := scenes.NewNode("root")
+	root.SetRotation(scenes.AxisAngle([3]float32{0, 1, 0}, 1))
+	node := scenes.NewNode("camera")
+	node.SetPosition(3, 4, 5)
+	root.Add(node)
+	cam := scenes.NewPerspective(math.Pi/3, 1.5, 0.1, 100)
+	node.Attach(cam)
+
+	target := [3]float32{-2, 1, 7}
+	cam.LookAt(target, [3]float32{0, 1, 0})
+	vp := cam.ViewProjection()
+	var clip [4]float32
+	for r := 0; r < 4; r++ {
+		clip[r] = vp[r]*target[0] + vp[4+r]*target[1] + vp[8+r]*target[2] + vp[12+r]
+	}
+	if x, y := clip[0]/clip[3], clip[1]/clip[3]; math.Abs(float64(x)) > 1e-4 || math.Abs(float64(y)) > 1e-4 {
+		t.Fatalf("target projected to (%v, %v), want the center", x, y)
+	}
+	if clip[3] <= 0 {
+		t.Fatal("target is behind the camera")
+	}
+}
+
+func TestBillboard(t *testing.T) {
+	root := scenes.NewNode("root")
+	root.SetRotation(scenes.AxisAngle([3]float32{0, 1, 0}, 1))
+	sprite := scenes.NewNode("sprite")
+	sprite.SetPosition(0, 1, 0)
+	sprite.SetScale(2, 2, 2)
+	sprite.Attach(&scenes.Billboard{})
+	root.Add(sprite)
+	tree := scenes.NewNode("tree")
+	tree.Attach(&scenes.Billboard{Mode: scenes.Cylindrical})
+	root.Add(tree)
+	eye := scenes.NewNode("camera")
+	eye.SetPosition(3, 5, 4)
+	cam := scenes.NewPerspective(math.Pi/3, 1, 0.1, 100)
+	eye.Attach(cam)
+	cam.LookAt([3]float32{0, 0, 0}, [3]float32{0, 1, 0})
+	root.Add(eye)
+
+	scenes.FaceCamera(root, cam)
+	// the sprite's +Z points at the camera, keeping its scale
+	m := sprite.WorldMatrix()
+	p := sprite.WorldPosition()
+	z := [3]float32{m[8] / 2, m[9] / 2, m[10] / 2}
+	to := eye.WorldPosition()
+	d := [3]float32{to[0] - p[0], to[1] - p[1], to[2] - p[2]}
+	l := float32(math.Sqrt(float64(d[0]*d[0] + d[1]*d[1] + d[2]*d[2])))
+	if !near3(z, [3]float32{d[0] / l, d[1] / l, d[2] / l}) {
+		t.Errorf("sprite faces %v, want %v", z, d)
+	}
+	// the tree stays upright, turning only to face the camera horizontally
+	m = tree.WorldMatrix()
+	if y := [3]float32{m[4], m[5], m[6]}; !near3(y, [3]float32{0, 1, 0}) {
+		t.Errorf("tree is not upright: %v", y)
+	}
+	to = eye.WorldPosition()
+	l = float32(math.Sqrt(float64(to[0]*to[0] + to[2]*to[2])))
+	if z := [3]float32{m[8], m[9], m[10]}; !near3(z, [3]float32{to[0] / l, 0, to[2] / l}) {
+		t.Errorf("tree faces %v", z)
+	}
+}
+
+func near3(a, b [3]float32) bool {
+	for i := range a {
+		if math.Abs(float64(a[i]-b[i])) > 1e-4 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,168 @@
+package scenes
+
+import (
+	"j4k.co/gfx/geometry"
+)
+
+// Hit is a mesh found by Pick.
+type Hit struct {
+	Node *Node
+	Mesh *Mesh
+
+	// Point is the world position of the hit, Distance away from the ray
+	// origin.
+	Point    [3]float32
+	Distance float32
+
+	// Triangle is the index of the triangle hit in Mesh.Source, and
+	// Barycentric the coordinates of Point in it, as with geometry.Hit.
+	// Triangle is -1 for meshes without a source, whose bounds were hit.
+	Triangle    int
+	Barycentric [3]float32
+}
+
+// ScreenRay returns the world space ray through the point x, y of the
+// viewport given to SetViewport, in pixels from its top left corner, as
+// cursor positions usually are. The ray starts on the near plane, and dir
+// has unit length.
+func (c *Camera) ScreenRay(x, y float32) (origin, dir [3]float32) {
+	var nx, ny float32
+	if c.width > 0 && c.height > 0 {
+		nx = 2*x/float32(c.width) - 1
+		ny = 1 - 2*y/float32(c.height)
+	}
+	viewProj := c.ViewProjection()
+	inv, _ := Invert(&viewProj)
+	near := unproject(&inv, [3]float32{nx, ny, -1})
+	far := unproject(&inv, [3]float32{nx, ny, 1})
+	return near, normalize(sub(far, near))
+}
+
+// unproject transforms p by m, dividing by w.
+func unproject(m *[16]float32, p [3]float32) [3]float32 {
+	var v [4]float32
+	for i := range v {
+		v[i] = m[i]*p[0] + m[4+i]*p[1] + m[8+i]*p[2] + m[12+i]
+	}
+	if v[3] == 0 {
+		return [3]float32{v[0], v[1], v[2]}
+	}
+	return [3]float32{v[0] / v[3], v[1] / v[3], v[2] / v[3]}
+}
+
+// Pick returns the nearest Mesh attached to root or its descendants under
+// the point x, y of cam's viewport, in pixels from its top left corner.
+// Meshes are first tested against their geometry bounds, then against the
+// triangles of their Source. Meshes with a Source but no geometry are
+// tested too, so that invisible meshes can be picked.
+func Pick(root *Node, cam *Camera, x, y float32) (Hit, bool) {
+	origin, dir := cam.ScreenRay(x, y)
+	return Raycast(root, origin, dir)
+}
+
+// Raycast returns the nearest Mesh attached to root or its descendants hit
+// by the world space ray from origin along dir, as with Pick.
+func Raycast(root *Node, origin, dir [3]float32) (hit Hit, ok bool) {
+	dir = normalize(dir)
+	root.Walk(func(n *Node) bool {
+		for _, c := range n.components {
+			m, isMesh := c.(*Mesh)
+			if !isMesh || (m.Geometry == nil && m.Source == nil) {
+				continue
+			}
+			world := n.WorldMatrix()
+			inv, invertible := Invert(&world)
+			if !invertible {
+				continue
+			}
+			// The ray in the mesh's space, with dir transformed linearly,
+			// has the same distances along it as the world ray.
+			lo := transformPoint(&inv, origin)
+			ld := transformDir(&inv, dir)
+			var d float32
+			tri := -1
+			var bary [3]float32
+			if m.Geometry != nil {
+				min, max, known := m.Geometry.Bounds()
+				if !known && m.Source == nil {
+					continue
+				}
+				if known {
+					t, boxHit := intersectBox(lo, ld, min, max)
+					if !boxHit || (ok && t >= hit.Distance) {
+						continue
+					}
+					d = t
+				}
+			}
+			if m.Source != nil {
+				gh, triHit := geometry.Raycast(m.Source, lo, ld)
+				if !triHit {
+					continue
+				}
+				d, tri, bary = gh.Distance, gh.Triangle, gh.Barycentric
+			}
+			if ok && d >= hit.Distance {
+				continue
+			}
+			hit = Hit{
+				Node:        n,
+				Mesh:        m,
+				Distance:    d,
+				Triangle:    tri,
+				Barycentric: bary,
+			}
+			for i := range hit.Point {
+				hit.Point[i] = origin[i] + d*dir[i]
+			}
+			ok = true
+		}
+		return true
+	})
+	return hit, ok
+}
+
+func transformPoint(m *[16]float32, p [3]float32) [3]float32 {
+	return [3]float32{
+		m[0]*p[0] + m[4]*p[1] + m[8]*p[2] + m[12],
+		m[1]*p[0] + m[5]*p[1] + m[9]*p[2] + m[13],
+		m[2]*p[0] + m[6]*p[1] + m[10]*p[2] + m[14],
+	}
+}
+
+func transformDir(m *[16]float32, v [3]float32) [3]float32 {
+	return [3]float32{
+		m[0]*v[0] + m[4]*v[1] + m[8]*v[2],
+		m[1]*v[0] + m[5]*v[1] + m[9]*v[2],
+		m[2]*v[0] + m[6]*v[1] + m[10]*v[2],
+	}
+}
+
+// intersectBox returns the distance along the ray to where it enters the
+// box min, max, or 0 if it starts inside.
+func intersectBox(origin, dir, min, max [3]float32) (float32, bool) {
+	tmin, tmax := float32(0), float32(1e30)
+	for i := 0; i < 3; i++ {
+		if dir[i] == 0 {
+			if origin[i] < min[i] || origin[i] > max[i] {
+				return 0, false
+			}
+			continue
+		}
+		t0 := (min[i] - origin[i]) / dir[i]
+		t1 := (max[i] - origin[i]) / dir[i]
+		if t0 > t1 {
+			t0, t1 = t1, t0
+		}
+		if t0 > tmin {
+			tmin = t0
+		}
+		if t1 < tmax {
+			tmax = t1
+		}
+		if tmin > tmax {
+			return 0, false
+		}
+	}
+	return tmin, true
+}
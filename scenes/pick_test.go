@@ -0,0 +1,51 @@
+package scenes_test
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/geometry/shapes"
+	"j4k.co/gfx/scenes"
+	"math"
+	"testing"
+)
+
+func TestPick(t *testing.T) {
+	cube := geometry.NewBuilder(gfx.VertexPosition | gfx.VertexNormal | gfx.VertexTexcoord)
+	shapes.Cube(cube, 2)
+
+	root := scenes.NewNode("root")
+	near, far := scenes.NewNode("near"), scenes.NewNode("far")
+	near.SetPosition(0, 0, -5)
+	far.SetPosition(0, 0, -10)
+	far.SetScale(4, 4, 4)
+	for _, n := range []*scenes.Node{far, near} {
+		n.Attach(&scenes.Mesh{Source: cube})
+		root.Add(n)
+	}
+	cam := scenes.NewPerspective(math.Pi/2, 1, 0.1, 100)
+	camNode := scenes.NewNode("camera")
+	camNode.Attach(cam)
+	root.Add(camNode)
+	cam.SetViewport(100, 100)
+
+	hit, ok := scenes.Pick(root, cam, 50, 50)
+	if !ok || hit.Node != near {
+		t.Fatalf("got %v, %v; want the near cube", hit.Node, ok)
+	}
+	// the ray starts on the near plane
+	if d := hit.Distance; d < 3.89 || d > 3.91 {
+		t.Errorf("got distance %v, want 3.9", d)
+	}
+	if p := hit.Point; math.Abs(float64(p[2]+4)) > 0.01 {
+		t.Errorf("got point %v, want z=-4", p)
+	}
+
+	// off the near cube's edge, but within the larger far one
+	hit, ok = scenes.Pick(root, cam, 80, 50)
+	if !ok || hit.Node != far {
+		t.Errorf("got %v, %v; want the far cube", hit.Node, ok)
+	}
+	if _, ok := scenes.Pick(root, cam, 99, 1); ok {
+		t.Error("picked something in the corner of the view")
+	}
+}
@@ -0,0 +1,97 @@
+package scenes
+
+import (
+	"j4k.co/gfx"
+	"sort"
+)
+
+// renderItem is a mesh or part queued for drawing.
+type renderItem struct {
+	r     *Renderer
+	part  Part
+	bones *gfx.BonePalette // set for skinned meshes
+	world [16]float32
+
+	// owner is the index of the item whose uniforms the item shares, the
+	// first part of its Drawable or the item itself
+	owner int
+
+	transparent bool
+	rank        materialRank
+	depth       float32 // view space distance
+}
+
+// materialRank orders materials by the IDs of their shader and material.
+type materialRank struct {
+	shader, material uint64
+}
+
+func (a materialRank) less(b materialRank) bool {
+	if a.shader != b.shader {
+		return a.shader < b.shader
+	}
+	return a.material < b.material
+}
+
+func (r *Renderer) enqueue(p Part, bones *gfx.BonePalette, world *[16]float32, depth float32, owner int) {
+	r.queue = append(r.queue, renderItem{
+		r:           r,
+		part:        p,
+		bones:       bones,
+		world:       *world,
+		owner:       owner,
+		transparent: p.Material.State.Blend.Enabled,
+		rank:        materialRank{p.Material.Shader.ID(), p.Material.key()},
+		depth:       depth,
+	})
+}
+
+// submit adds the queued items to the draw queue. Opaque items are keyed by
+// the rank of their material among the opaque materials of the frame, in
+// pass 0, and transparent items back to front in pass 1.
+func (r *Renderer) submit() {
+	r.ranks = r.ranks[:0]
+	for i := range r.queue {
+		if !r.queue[i].transparent {
+			r.ranks = append(r.ranks, r.queue[i].rank)
+		}
+	}
+	sort.Slice(r.ranks, func(i, j int) bool { return r.ranks[i].less(r.ranks[j]) })
+	n := 0
+	for i, rank := range r.ranks {
+		if i == 0 || rank != r.ranks[n-1] {
+			r.ranks[n] = rank
+			n++
+		}
+	}
+	r.ranks = r.ranks[:n]
+
+	for i := range r.queue {
+		item := &r.queue[i]
+		mat := item.part.Material
+		d := gfx.DrawItem{
+			Shader:   mat.Shader,
+			Geometry: item.part.Geometry,
+			Material: materialBinding{r, mat},
+			State:    &mat.State,
+			First:    item.part.First,
+			Count:    item.part.Count,
+		}
+		if item.transparent {
+			d.Key = gfx.MakeSortKeyBackToFront(1, 0, item.depth)
+		} else {
+			rank := sort.Search(len(r.ranks), func(i int) bool { return !r.ranks[i].less(item.rank) })
+			d.Key = gfx.MakeSortKey(0, uint32(rank), item.depth)
+		}
+		if item.bones != nil || usesMatrices(mat.Shader) {
+			d.Uniforms = &r.queue[item.owner]
+		}
+		r.draws.Add(d)
+	}
+}
+
+// viewDepth returns the distance of p in front of the camera with the given
+// view matrix.
+func viewDepth(view *[16]float32, p [3]float32) float32 {
+	return -(view[2]*p[0] + view[6]*p[1] + view[10]*p[2] + view[14])
+}
@@ -0,0 +1,202 @@
+package scenes
+
+import (
+	"j4k.co/gfx"
+)
+
+// Renderer draws the meshes of a scene graph.
+//
+// Along with the uniforms and textures of its material, each mesh is drawn
+// with the matrix uniforms WorldM, ViewM, ProjectionM, and
+// WorldViewProjectionM, each of which is only assigned if the shader uses it.
+// Materials with Lit set are also given the lights of the scene as the
+// uniform block described by LightBinding.
+type Renderer struct {
+	// Ambient is the color of the ambient light given to lit materials.
+	Ambient [3]float32
+
+	lights     *gfx.UniformBlock
+	lightData  lightBlock
+	lightsSent bool // lightData has been uploaded this frame
+
+	queue []renderItem
+	parts []Part
+	ranks []materialRank
+	draws gfx.DrawQueue
+
+	// matrices of the frame being rendered
+	view, proj, viewProj [16]float32
+}
+
+type (
+	worldUniform struct {
+		M [16]float32 `uniform:"WorldM"`
+	}
+	viewUniform struct {
+		M [16]float32 `uniform:"ViewM"`
+	}
+	projectionUniform struct {
+		M [16]float32 `uniform:"ProjectionM"`
+	}
+	wvpUniform struct {
+		M [16]float32 `uniform:"WorldViewProjectionM"`
+	}
+)
+
+// NewRenderer returns a new renderer.
+func NewRenderer() *Renderer {
+	return &Renderer{}
+}
+
+// Delete frees the light uniform block held by the renderer.
+func (r *Renderer) Delete() {
+	if r.lights != nil {
+		r.lights.Delete()
+		r.lights = nil
+	}
+}
+
+// Render draws every Mesh and SkinnedMesh attached to root and its
+// descendants, as seen by cam, and the parts of every Drawable. Meshes and
+// parts without geometry, a material, or a shader are skipped, as are those
+// with geometry bounds outside the camera's frustum. Skinned meshes are
+// given the bone palette of their skeleton's current pose. Nodes with a
+// Billboard are first turned to face cam.
+//
+// The meshes are drawn through a gfx.DrawQueue. Opaque meshes are drawn
+// first, sorted by shader and material to minimize state changes, then front
+// to back. Meshes whose material blends are drawn after them, back to front.
+// The GL state of the last material drawn is left in place.
+func (r *Renderer) Render(root *Node, cam *Camera) error {
+	FaceCamera(root, cam)
+	r.view, r.proj = cam.View(), cam.Projection()
+	r.viewProj = MulMatrix(&r.proj, &r.view)
+	view := &r.view
+	frustum := gfx.FrustumFromMatrix(r.viewProj)
+
+	r.lightData.Count = 0
+	r.lightsSent = false
+	a := r.Ambient
+	r.lightData.Ambient = [4]float32{a[0], a[1], a[2], 1}
+
+	r.queue = r.queue[:0]
+	var err error
+	root.Walk(func(n *Node) bool {
+		for _, c := range n.components {
+			var bones *gfx.BonePalette
+			r.parts = r.parts[:0]
+			switch c := c.(type) {
+			case *Mesh:
+				r.parts = append(r.parts, Part{Geometry: c.Geometry, Material: c.Material})
+			case *SkinnedMesh:
+				r.parts = append(r.parts, Part{Geometry: c.Geometry, Material: c.Material})
+				bones = c.bones(n)
+			case Drawable:
+				if r.parts, err = c.AppendParts(r.parts, n, cam); err != nil {
+					return false
+				}
+			default:
+				r.lightData.addLight(n, c)
+				continue
+			}
+			world := n.WorldMatrix()
+			owner := -1
+			for _, p := range r.parts {
+				if p.Geometry == nil || p.Material == nil || p.Material.Shader == nil {
+					continue
+				}
+				center := [3]float32{world[12], world[13], world[14]}
+				if min, max, ok := p.Geometry.Bounds(); ok && bones == nil {
+					min, max = gfx.TransformBounds(min, max, &world)
+					if !frustum.ContainsBox(min, max) {
+						continue
+					}
+					for i := range center {
+						center[i] = (min[i] + max[i]) / 2
+					}
+				}
+				if owner < 0 {
+					owner = len(r.queue)
+				}
+				r.enqueue(p, bones, &world, viewDepth(view, center), owner)
+			}
+		}
+		return err == nil
+	})
+	if err == nil {
+		r.submit()
+		err = r.draws.Flush()
+	}
+	for i := range r.queue {
+		r.queue[i] = renderItem{}
+	}
+	for i := range r.parts {
+		r.parts[i] = Part{}
+	}
+	return err
+}
+
+// materialBinding binds a material and, if it is lit, the lights of the
+// frame. It is comparable, so the draw queue only binds a material when it
+// changes.
+type materialBinding struct {
+	r   *Renderer
+	mat *Material
+}
+
+func (b materialBinding) BindUniforms(s *gfx.Shader) error {
+	if b.mat.Lit {
+		if err := b.r.bindLights(s); err != nil {
+			return err
+		}
+	}
+	return b.mat.bind()
+}
+
+// BindUniforms assigns the bone palette and matrix uniforms of the item.
+func (item *renderItem) BindUniforms(s *gfx.Shader) error {
+	r, world := item.r, &item.world
+	if item.bones != nil {
+		if err := item.bones.Apply(s); err != nil {
+			return err
+		}
+	}
+	var err error
+	if s.HasUniform("WorldM") {
+		err = s.AssignUniforms(&worldUniform{*world})
+	}
+	if err == nil && s.HasUniform("ViewM") {
+		err = s.AssignUniforms(&viewUniform{r.view})
+	}
+	if err == nil && s.HasUniform("ProjectionM") {
+		err = s.AssignUniforms(&projectionUniform{r.proj})
+	}
+	if err == nil && s.HasUniform("WorldViewProjectionM") {
+		err = s.AssignUniforms(&wvpUniform{MulMatrix(&r.viewProj, world)})
+	}
+	return err
+}
+
+// usesMatrices reports whether s has any of the matrix uniforms.
+func usesMatrices(s *gfx.Shader) bool {
+	return s.HasUniform("WorldM") || s.HasUniform("ViewM") ||
+		s.HasUniform("ProjectionM") || s.HasUniform("WorldViewProjectionM")
+}
+
+// bindLights uploads the lights if they have not been yet this frame, and
+// binds them to the Lights block of s.
+func (r *Renderer) bindLights(s *gfx.Shader) error {
+	if !r.lightsSent {
+		var err error
+		if r.lights == nil {
+			r.lights, err = gfx.NewUniformBlock(LightBinding, &r.lightData)
+		} else {
+			err = r.lights.Update(&r.lightData)
+		}
+		if err != nil {
+			return err
+		}
+		r.lightsSent = true
+	}
+	return s.SetUniformBlock("Lights", r.lights)
+}
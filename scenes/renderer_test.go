@@ -0,0 +1,147 @@
+package scenes_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/gfxtest"
+	"j4k.co/gfx/scenes"
+	"math"
+	"runtime"
+	"testing"
+	"time"
+)
+
+const tintShader gfx.FragmentShader = `
+uniform vec4 Tint;
+
+void main() {
+	gl_FragColor = Tint;
+}`
+
+const worldShader gfx.VertexShader = `
+attribute vec3 Position;
+uniform mat4 WorldM;
+
+void main() {
+	gl_Position = WorldM * vec4(Position, 1.0);
+}`
+
+// triangle returns a small triangle geometry, which is deleted at the end of
+// the test.
+func triangle(t *testing.T) *gfx.Geometry {
+	b := geometry.NewBuilder(gfx.VertexPosition)
+	b.Position(0, 0, 0).Position(1, 0, 0).Position(0, 1, 0)
+	geom, err := gfx.NewGeometry(b, gfx.StaticDraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(geom.Delete)
+	return geom
+}
+
+// tintShaders builds n shaders drawing a flat color, which are deleted at the
+// end of the test.
+func tintShaders(t *testing.T, n int) []*gfx.Shader {
+	var ss []*gfx.Shader
+	for i := 0; i < n; i++ {
+		s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, worldShader, tintShader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(s.Delete)
+		ss = append(ss, s)
+	}
+	return ss
+}
+
+func TestRenderReleasesMaterials(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	geom := triangle(t)
+	s := tintShaders(t, 1)[0]
+	r := scenes.NewRenderer()
+	defer r.Delete()
+	cam := scenes.NewPerspective(math.Pi/2, 1, 0.1, 100)
+
+	collected := make(chan bool, 1)
+	func() {
+		mat := &scenes.Material{Shader: s}
+		runtime.SetFinalizer(mat, func(*scenes.Material) { collected <- true })
+		root := scenes.NewNode("root")
+		n := scenes.NewNode("mesh")
+		n.SetPosition(0, 0, -5)
+		n.Attach(&scenes.Mesh{Geometry: geom, Material: mat})
+		root.Add(n)
+		if err := r.Render(root, cam); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		runtime.GC()
+		select {
+		case <-collected:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Error("the renderer kept a drawn material from being collected")
+}
+
+func TestRenderOrder(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	geom := triangle(t)
+	ss := tintShaders(t, 2)
+	r := scenes.NewRenderer()
+	defer r.Delete()
+	cam := scenes.NewPerspective(math.Pi/2, 1, 0.1, 100)
+
+	type tint struct {
+		Tint [4]float32 `uniform:"Tint"`
+	}
+	a := &scenes.Material{Shader: ss[0], Uniforms: &tint{[4]float32{1, 0, 0, 1}}}
+	b := &scenes.Material{Shader: ss[1], Uniforms: &tint{[4]float32{2, 0, 0, 1}}}
+	c := &scenes.Material{Shader: ss[0], Uniforms: &tint{[4]float32{3, 0, 0, 1}}}
+	c.State.Blend = gfx.AlphaBlend
+	root := scenes.NewNode("root")
+	for _, m := range []struct {
+		mat *scenes.Material
+		z   float32
+	}{{a, -5}, {c, -3}, {b, -10}, {a, -20}, {c, -30}} {
+		n := scenes.NewNode("mesh")
+		n.SetPosition(0, 0, m.z)
+		n.Attach(&scenes.Mesh{Geometry: geom, Material: m.mat})
+		root.Add(n)
+	}
+	rec.Reset()
+	if err := r.Render(root, cam); err != nil {
+		t.Fatal(err)
+	}
+
+	// each draw as the tint of its material and the z of its position
+	var tint0, z float32
+	var draws []string
+	tints := 0
+	for _, call := range rec.Calls {
+		switch call.Op {
+		case "Uniform4fv":
+			tint0 = call.Args[2].([]float32)[0]
+			tints++
+		case "UniformMatrix4fv":
+			z = call.Args[3].([]float32)[14]
+		case "DrawArrays":
+			draws = append(draws, fmt.Sprint(tint0, z))
+		}
+	}
+	// a, then b, opaque front to back, then c back to front
+	want := "[1 -5 1 -20 2 -10 3 -30 3 -3]"
+	if got := fmt.Sprint(draws); got != want {
+		t.Errorf("got draws %v, want %v", got, want)
+	}
+	if tints != 3 {
+		t.Errorf("assigned materials %d times, want once each", tints)
+	}
+}
@@ -0,0 +1,202 @@
+package scenes
+
+import (
+	"j4k.co/gfx"
+	"math"
+)
+
+// Skeleton is the set of joint nodes that deform a skinned mesh. Animating
+// the joints, by moving their nodes or with a Clip, moves the vertices
+// weighted to them.
+type Skeleton struct {
+	Joints []*Node
+
+	// InverseBind holds the inverse of the world matrix of each joint in
+	// the pose the mesh was modeled in, relative to the mesh. A nil slice
+	// means the joints were at the origin of the mesh.
+	InverseBind [][16]float32
+}
+
+// Palette fills dst with the bone matrices of the skeleton for a mesh
+// attached to meshNode, resizing it to the number of joints, and returns
+// it.
+func (s *Skeleton) Palette(meshNode *Node, dst [][16]float32) [][16]float32 {
+	if cap(dst) < len(s.Joints) {
+		dst = make([][16]float32, len(s.Joints))
+	}
+	dst = dst[:len(s.Joints)]
+	world := meshNode.WorldMatrix()
+	inv, _ := Invert(&world)
+	for i, j := range s.Joints {
+		jw := j.WorldMatrix()
+		m := MulMatrix(&inv, &jw)
+		if i < len(s.InverseBind) {
+			m = MulMatrix(&m, &s.InverseBind[i])
+		}
+		dst[i] = m
+	}
+	return dst
+}
+
+// ChannelPath is the property of a node that a Channel animates.
+type ChannelPath uint8
+
+const (
+	Translation ChannelPath = iota // xyz of the position
+	Rotation                       // quaternion
+	Scale                          // xyz of the scale
+)
+
+// Channel animates one property of a node with keyframes, linearly
+// interpolating between them. Rotations are normalized linear interpolations
+// along the shorter path.
+type Channel struct {
+	Node   *Node
+	Path   ChannelPath
+	Times  []float32    // in seconds, increasing
+	Values [][4]float32 // a value for each time; w is unused but for rotations
+}
+
+// Clip is an animation of the channels of some nodes, such as the joints
+// of a skeleton.
+type Clip struct {
+	Name     string
+	Channels []Channel
+}
+
+// Duration returns the time of the last keyframe of the clip.
+func (c *Clip) Duration() float32 {
+	var d float32
+	for _, ch := range c.Channels {
+		if n := len(ch.Times); n > 0 && ch.Times[n-1] > d {
+			d = ch.Times[n-1]
+		}
+	}
+	return d
+}
+
+// Apply poses the nodes of the clip as they are at time t, in seconds.
+// Before the first keyframe and after the last, channels hold their first
+// and last values.
+func (c *Clip) Apply(t float32) {
+	for i := range c.Channels {
+		ch := &c.Channels[i]
+		if len(ch.Times) == 0 || len(ch.Values) < len(ch.Times) {
+			continue
+		}
+		ch.Set(ch.Sample(t))
+	}
+}
+
+// Sample returns the value of the channel at time t, which must have at
+// least one keyframe.
+func (ch *Channel) Sample(t float32) [4]float32 {
+	n := len(ch.Times)
+	if t <= ch.Times[0] {
+		return ch.Values[0]
+	}
+	if t >= ch.Times[n-1] {
+		return ch.Values[n-1]
+	}
+	// find the keyframe after t
+	k := 1
+	for ch.Times[k] < t {
+		k++
+	}
+	t0, t1 := ch.Times[k-1], ch.Times[k]
+	f := (t - t0) / (t1 - t0)
+	return ch.Blend(ch.Values[k-1], ch.Values[k], f)
+}
+
+// Set sets the animated property of the node of the channel to v.
+func (ch *Channel) Set(v [4]float32) {
+	switch ch.Path {
+	case Translation:
+		ch.Node.SetPosition(v[0], v[1], v[2])
+	case Rotation:
+		ch.Node.SetRotation(v)
+	case Scale:
+		ch.Node.SetScale(v[0], v[1], v[2])
+	}
+}
+
+// Blend returns the value a fraction f of the way from a to b, for values
+// of the channel's property.
+func (ch *Channel) Blend(a, b [4]float32, f float32) [4]float32 {
+	if ch.Path == Rotation {
+		return LerpQuat(a, b, f)
+	}
+	return lerp4(a, b, f)
+}
+
+func lerp4(a, b [4]float32, f float32) [4]float32 {
+	var v [4]float32
+	for i := range v {
+		v[i] = a[i] + (b[i]-a[i])*f
+	}
+	return v
+}
+
+// SkinnedMesh is a component that draws geometry deformed by a skeleton,
+// whose bone matrices the renderer uploads with a gfx.BonePalette for each
+// draw. Its geometry is typically laid out with gfx.SkinAttributes and
+// drawn with gfx.SkinVertexShader. It is not culled against the camera, as
+// its bounds change with the pose.
+type SkinnedMesh struct {
+	Mesh
+	Skeleton *Skeleton
+
+	// Clip, if set, is played by Update, which advances Time by the frame
+	// time scaled by Speed, and wraps it around if Loop is set. To blend
+	// between clips, leave it nil and attach an anim.Player instead.
+	Clip  *Clip
+	Time  float32
+	Speed float32
+	Loop  bool
+
+	palette *gfx.BonePalette
+}
+
+// NewSkinnedMesh returns a skinned mesh playing clips at normal speed.
+func NewSkinnedMesh(geom *gfx.Geometry, mat *Material, skel *Skeleton) *SkinnedMesh {
+	return &SkinnedMesh{
+		Mesh:     Mesh{Geometry: geom, Material: mat},
+		Skeleton: skel,
+		Speed:    1,
+	}
+}
+
+// Update implements Updater, playing the clip of the mesh.
+func (m *SkinnedMesh) Update(n *Node, dt float64) {
+	if m.Clip == nil {
+		return
+	}
+	m.Time += float32(dt) * m.Speed
+	if d := m.Clip.Duration(); m.Loop && d > 0 {
+		m.Time = float32(math.Mod(float64(m.Time), float64(d)))
+		if m.Time < 0 {
+			m.Time += d
+		}
+	}
+	m.Clip.Apply(m.Time)
+}
+
+// bones updates the palette of the mesh for its pose on node n.
+func (m *SkinnedMesh) bones(n *Node) *gfx.BonePalette {
+	if m.palette == nil {
+		m.palette = &gfx.BonePalette{}
+	}
+	if m.Skeleton != nil {
+		m.palette.Matrices = m.Skeleton.Palette(n, m.palette.Matrices)
+	}
+	return m.palette
+}
+
+// Delete frees the uniform buffer of the bone palette. The geometry and
+// material are not deleted.
+func (m *SkinnedMesh) Delete() {
+	if m.palette != nil {
+		m.palette.Delete()
+		m.palette = nil
+	}
+}
@@ -0,0 +1,69 @@
+package scenes_test
+
+import (
+	"j4k.co/gfx/scenes"
+	"math"
+	"testing"
+)
+
+func TestClipApply(t *testing.T) {
+	n := scenes.NewNode("joint")
+	half := scenes.AxisAngle([3]float32{0, 0, 1}, math.Pi/2)
+	clip := &scenes.Clip{Channels: []scenes.Channel{
+		{Node: n, Path: scenes.Translation, Times: []float32{0, 2}, Values: [][4]float32{{0, 0, 0}, {4, 2, 0}}},
+		{Node: n, Path: scenes.Rotation, Times: []float32{0, 2}, Values: [][4]float32{scenes.IdentityQuat, half}},
+	}}
+	if d := clip.Duration(); d != 2 {
+		t.Fatalf("duration %v, want 2", d)
+	}
+
+	clip.Apply(1)
+	if p := n.Position(); p != [3]float32{2, 1, 0} {
+		t.Errorf("position %v, want [2 1 0]", p)
+	}
+	want := scenes.AxisAngle([3]float32{0, 0, 1}, math.Pi/4)
+	if q := n.Rotation(); !near4(q, want) {
+		t.Errorf("rotation %v, want %v", q, want)
+	}
+
+	clip.Apply(5)
+	if p := n.Position(); p != [3]float32{4, 2, 0} {
+		t.Errorf("position past the end %v, want [4 2 0]", p)
+	}
+}
+
+func TestSkeletonPalette(t *testing.T) {
+	root := scenes.NewNode("root")
+	mesh := scenes.NewNode("mesh")
+	joint := scenes.NewNode("joint")
+	root.Add(mesh)
+	root.Add(joint)
+	root.SetPosition(5, 0, 0)
+	joint.SetPosition(0, 1, 0)
+
+	// the joint was bound at (0, 1, 0), so at rest it does not move vertices
+	skel := &scenes.Skeleton{
+		Joints:      []*scenes.Node{joint},
+		InverseBind: [][16]float32{scenes.Compose([3]float32{0, -1, 0}, scenes.IdentityQuat, [3]float32{1, 1, 1})},
+	}
+	pal := skel.Palette(mesh, nil)
+	if len(pal) != 1 || !near(pal[0], scenes.Identity) {
+		t.Fatalf("rest palette %v, want identity", pal)
+	}
+
+	joint.SetPosition(0, 3, 0)
+	pal = skel.Palette(mesh, pal)
+	want := scenes.Compose([3]float32{0, 2, 0}, scenes.IdentityQuat, [3]float32{1, 1, 1})
+	if !near(pal[0], want) {
+		t.Fatalf("moved palette %v, want %v", pal[0], want)
+	}
+}
+
+func near4(a, b [4]float32) bool {
+	for i := range a {
+		if math.Abs(float64(a[i]-b[i])) > 1e-5 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,277 @@
+package scenes
+
+import (
+	"math"
+)
+
+// Matrices are column-major [16]float32 values, as expected by gfx uniforms.
+
+// Identity is the identity matrix.
+var Identity = [16]float32{
+	1, 0, 0, 0,
+	0, 1, 0, 0,
+	0, 0, 1, 0,
+	0, 0, 0, 1,
+}
+
+// IdentityQuat is the quaternion of no rotation.
+var IdentityQuat = [4]float32{0, 0, 0, 1}
+
+// transform is the spatial state of a Node.
+type transform struct {
+	position [3]float32
+	rotation [4]float32 // unit quaternion x, y, z, w
+	scale    [3]float32
+
+	local, world           [16]float32
+	localDirty, worldDirty bool
+	ready                  bool // set by init
+}
+
+// init sets the identity transform if t is the zero value, so that nodes
+// declared as Node{} behave like those from NewNode.
+func (t *transform) init() {
+	if t.ready {
+		return
+	}
+	t.rotation = IdentityQuat
+	t.scale = [3]float32{1, 1, 1}
+	t.local, t.world = Identity, Identity
+	t.ready = true
+}
+
+// Position returns the position of n relative to its parent.
+func (n *Node) Position() [3]float32 {
+	return n.xf.position
+}
+
+// Rotation returns the rotation of n relative to its parent, as a unit
+// quaternion x, y, z, w.
+func (n *Node) Rotation() [4]float32 {
+	n.xf.init()
+	return n.xf.rotation
+}
+
+// Scale returns the scale of n along its local axes.
+func (n *Node) Scale() [3]float32 {
+	n.xf.init()
+	return n.xf.scale
+}
+
+// SetPosition sets the position of n relative to its parent.
+func (n *Node) SetPosition(x, y, z float32) {
+	n.xf.init()
+	n.xf.position = [3]float32{x, y, z}
+	n.invalidateLocal()
+}
+
+// SetRotation sets the rotation of n relative to its parent. q should be a
+// unit quaternion x, y, z, w.
+func (n *Node) SetRotation(q [4]float32) {
+	n.xf.init()
+	n.xf.rotation = q
+	n.invalidateLocal()
+}
+
+// SetScale sets the scale of n along its local axes.
+func (n *Node) SetScale(x, y, z float32) {
+	n.xf.init()
+	n.xf.scale = [3]float32{x, y, z}
+	n.invalidateLocal()
+}
+
+// LocalMatrix returns the transform of n relative to its parent, which
+// scales, then rotates, then translates.
+func (n *Node) LocalMatrix() [16]float32 {
+	n.xf.init()
+	if n.xf.localDirty {
+		n.xf.local = Compose(n.xf.position, n.xf.rotation, n.xf.scale)
+		n.xf.localDirty = false
+	}
+	return n.xf.local
+}
+
+// WorldMatrix returns the transform of n relative to the root of its tree.
+// It is cached, and only recomputed after n or one of its ancestors moves.
+func (n *Node) WorldMatrix() [16]float32 {
+	n.xf.init()
+	if n.xf.worldDirty {
+		local := n.LocalMatrix()
+		if n.parent == nil {
+			n.xf.world = local
+		} else {
+			parent := n.parent.WorldMatrix()
+			n.xf.world = MulMatrix(&parent, &local)
+		}
+		n.xf.worldDirty = false
+	}
+	return n.xf.world
+}
+
+// WorldPosition returns the position of n relative to the root of its tree.
+func (n *Node) WorldPosition() [3]float32 {
+	m := n.WorldMatrix()
+	return [3]float32{m[12], m[13], m[14]}
+}
+
+func (n *Node) invalidateLocal() {
+	n.xf.localDirty = true
+	n.invalidateWorld()
+}
+
+// invalidateWorld marks the world matrices of n and its descendants dirty.
+// A node with a dirty world matrix always has dirty descendants, so the walk
+// stops at nodes that are already dirty.
+func (n *Node) invalidateWorld() {
+	if n.xf.worldDirty {
+		return
+	}
+	n.xf.worldDirty = true
+	for _, c := range n.children {
+		c.invalidateWorld()
+	}
+}
+
+// Compose returns the matrix that scales by s, rotates by the unit
+// quaternion q, then translates by t.
+func Compose(t [3]float32, q [4]float32, s [3]float32) [16]float32 {
+	x, y, z, w := q[0], q[1], q[2], q[3]
+	return [16]float32{
+		(1 - 2*(y*y+z*z)) * s[0], 2 * (x*y + z*w) * s[0], 2 * (x*z - y*w) * s[0], 0,
+		2 * (x*y - z*w) * s[1], (1 - 2*(x*x+z*z)) * s[1], 2 * (y*z + x*w) * s[1], 0,
+		2 * (x*z + y*w) * s[2], 2 * (y*z - x*w) * s[2], (1 - 2*(x*x+y*y)) * s[2], 0,
+		t[0], t[1], t[2], 1,
+	}
+}
+
+// MulMatrix returns a * b, which applies b first.
+func MulMatrix(a, b *[16]float32) [16]float32 {
+	var m [16]float32
+	for c := 0; c < 4; c++ {
+		for r := 0; r < 4; r++ {
+			var sum float32
+			for k := 0; k < 4; k++ {
+				sum += a[k*4+r] * b[c*4+k]
+			}
+			m[c*4+r] = sum
+		}
+	}
+	return m
+}
+
+// AxisAngle returns the quaternion rotating by angle radians about axis,
+// which must be of unit length.
+func AxisAngle(axis [3]float32, angle float32) [4]float32 {
+	s := float32(math.Sin(float64(angle) / 2))
+	c := float32(math.Cos(float64(angle) / 2))
+	return [4]float32{axis[0] * s, axis[1] * s, axis[2] * s, c}
+}
+
+// Invert returns the inverse of m, and false if m is singular.
+func Invert(m *[16]float32) ([16]float32, bool) {
+	var inv [16]float32
+	inv[0] = m[5]*m[10]*m[15] - m[5]*m[11]*m[14] - m[9]*m[6]*m[15] + m[9]*m[7]*m[14] + m[13]*m[6]*m[11] - m[13]*m[7]*m[10]
+	inv[4] = -m[4]*m[10]*m[15] + m[4]*m[11]*m[14] + m[8]*m[6]*m[15] - m[8]*m[7]*m[14] - m[12]*m[6]*m[11] + m[12]*m[7]*m[10]
+	inv[8] = m[4]*m[9]*m[15] - m[4]*m[11]*m[13] - m[8]*m[5]*m[15] + m[8]*m[7]*m[13] + m[12]*m[5]*m[11] - m[12]*m[7]*m[9]
+	inv[12] = -m[4]*m[9]*m[14] + m[4]*m[10]*m[13] + m[8]*m[5]*m[14] - m[8]*m[6]*m[13] - m[12]*m[5]*m[10] + m[12]*m[6]*m[9]
+	inv[1] = -m[1]*m[10]*m[15] + m[1]*m[11]*m[14] + m[9]*m[2]*m[15] - m[9]*m[3]*m[14] - m[13]*m[2]*m[11] + m[13]*m[3]*m[10]
+	inv[5] = m[0]*m[10]*m[15] - m[0]*m[11]*m[14] - m[8]*m[2]*m[15] + m[8]*m[3]*m[14] + m[12]*m[2]*m[11] - m[12]*m[3]*m[10]
+	inv[9] = -m[0]*m[9]*m[15] + m[0]*m[11]*m[13] + m[8]*m[1]*m[15] - m[8]*m[3]*m[13] - m[12]*m[1]*m[11] + m[12]*m[3]*m[9]
+	inv[13] = m[0]*m[9]*m[14] - m[0]*m[10]*m[13] - m[8]*m[1]*m[14] + m[8]*m[2]*m[13] + m[12]*m[1]*m[10] - m[12]*m[2]*m[9]
+	inv[2] = m[1]*m[6]*m[15] - m[1]*m[7]*m[14] - m[5]*m[2]*m[15] + m[5]*m[3]*m[14] + m[13]*m[2]*m[7] - m[13]*m[3]*m[6]
+	inv[6] = -m[0]*m[6]*m[15] + m[0]*m[7]*m[14] + m[4]*m[2]*m[15] - m[4]*m[3]*m[14] - m[12]*m[2]*m[7] + m[12]*m[3]*m[6]
+	inv[10] = m[0]*m[5]*m[15] - m[0]*m[7]*m[13] - m[4]*m[1]*m[15] + m[4]*m[3]*m[13] + m[12]*m[1]*m[7] - m[12]*m[3]*m[5]
+	inv[14] = -m[0]*m[5]*m[14] + m[0]*m[6]*m[13] + m[4]*m[1]*m[14] - m[4]*m[2]*m[13] - m[12]*m[1]*m[6] + m[12]*m[2]*m[5]
+	inv[3] = -m[1]*m[6]*m[11] + m[1]*m[7]*m[10] + m[5]*m[2]*m[11] - m[5]*m[3]*m[10] - m[9]*m[2]*m[7] + m[9]*m[3]*m[6]
+	inv[7] = m[0]*m[6]*m[11] - m[0]*m[7]*m[10] - m[4]*m[2]*m[11] + m[4]*m[3]*m[10] + m[8]*m[2]*m[7] - m[8]*m[3]*m[6]
+	inv[11] = -m[0]*m[5]*m[11] + m[0]*m[7]*m[9] + m[4]*m[1]*m[11] - m[4]*m[3]*m[9] - m[8]*m[1]*m[7] + m[8]*m[3]*m[5]
+	inv[15] = m[0]*m[5]*m[10] - m[0]*m[6]*m[9] - m[4]*m[1]*m[10] + m[4]*m[2]*m[9] + m[8]*m[1]*m[6] - m[8]*m[2]*m[5]
+
+	det := m[0]*inv[0] + m[1]*inv[4] + m[2]*inv[8] + m[3]*inv[12]
+	if det == 0 {
+		return Identity, false
+	}
+	det = 1 / det
+	for i := range inv {
+		inv[i] *= det
+	}
+	return inv, true
+}
+
+// MulQuat returns the quaternion product a * b, which rotates by b first.
+func MulQuat(a, b [4]float32) [4]float32 {
+	return [4]float32{
+		a[3]*b[0] + a[0]*b[3] + a[1]*b[2] - a[2]*b[1],
+		a[3]*b[1] - a[0]*b[2] + a[1]*b[3] + a[2]*b[0],
+		a[3]*b[2] + a[0]*b[1] - a[1]*b[0] + a[2]*b[3],
+		a[3]*b[3] - a[0]*b[0] - a[1]*b[1] - a[2]*b[2],
+	}
+}
+
+// LerpQuat interpolates the rotations a and b by f along the shorter path,
+// normalizing the result. It is cheaper than spherical interpolation and
+// close to it for the small steps between keyframes.
+func LerpQuat(a, b [4]float32, f float32) [4]float32 {
+	if a[0]*b[0]+a[1]*b[1]+a[2]*b[2]+a[3]*b[3] < 0 {
+		b = [4]float32{-b[0], -b[1], -b[2], -b[3]}
+	}
+	var q [4]float32
+	var l float32
+	for i := range q {
+		q[i] = a[i] + (b[i]-a[i])*f
+		l += q[i] * q[i]
+	}
+	l = float32(math.Sqrt(float64(l)))
+	if l == 0 {
+		return IdentityQuat
+	}
+	for i := range q {
+		q[i] /= l
+	}
+	return q
+}
+
+func conjugate(q [4]float32) [4]float32 {
+	return [4]float32{-q[0], -q[1], -q[2], q[3]}
+}
+
+// matrixQuat returns the rotation of m as a unit quaternion, ignoring any
+// scale.
+func matrixQuat(m *[16]float32) [4]float32 {
+	var r [3][3]float64 // r[row][col]
+	for c := 0; c < 3; c++ {
+		col := [3]float32{m[c*4], m[c*4+1], m[c*4+2]}
+		col = normalize(col)
+		for i := 0; i < 3; i++ {
+			r[i][c] = float64(col[i])
+		}
+	}
+	var x, y, z, w float64
+	switch tr := r[0][0] + r[1][1] + r[2][2]; {
+	case tr > 0:
+		s := math.Sqrt(tr+1) * 2
+		w = s / 4
+		x = (r[2][1] - r[1][2]) / s
+		y = (r[0][2] - r[2][0]) / s
+		z = (r[1][0] - r[0][1]) / s
+	case r[0][0] > r[1][1] && r[0][0] > r[2][2]:
+		s := math.Sqrt(1+r[0][0]-r[1][1]-r[2][2]) * 2
+		w = (r[2][1] - r[1][2]) / s
+		x = s / 4
+		y = (r[0][1] + r[1][0]) / s
+		z = (r[0][2] + r[2][0]) / s
+	case r[1][1] > r[2][2]:
+		s := math.Sqrt(1+r[1][1]-r[0][0]-r[2][2]) * 2
+		w = (r[0][2] - r[2][0]) / s
+		x = (r[0][1] + r[1][0]) / s
+		y = s / 4
+		z = (r[1][2] + r[2][1]) / s
+	default:
+		s := math.Sqrt(1+r[2][2]-r[0][0]-r[1][1]) * 2
+		w = (r[1][0] - r[0][1]) / s
+		x = (r[0][2] + r[2][0]) / s
+		y = (r[1][2] + r[2][1]) / s
+		z = s / 4
+	}
+	return [4]float32{float32(x), float32(y), float32(z), float32(w)}
+}
@@ -3,8 +3,12 @@ package gfx
 import (
 	"errors"
 	"fmt"
-	"github.com/go-gl/gl"
+	"j4k.co/gfx/internal/gl"
+	"log"
 	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 	"unsafe"
 )
 
@@ -12,10 +16,36 @@ type Shader struct {
 	prog         gl.Program
 	vertexAttrs  VertexAttributes
 	vertexFormat VertexFormat
-	texlocs      []gl.UniformLocation
+	instAttrs    VertexAttributes
+	instFormat   VertexFormat
+	samplerUnits map[gl.UniformLocation]int // the units sampler uniforms are set to
+	uniformLocs  map[string]gl.UniformLocation
+	strict       bool
+	checked      map[reflect.Type]error
 	indexCount   int
 	indexOffset  int
 	indexType    gl.GLenum
+	indexBuf     gl.Buffer
+	indexed      bool
+	vertexCount  int
+	primitive    Primitive
+
+	// cacheID identifies the shader in the layout cache, once assigned.
+	cacheID uint64
+
+	// label names the program and its layouts in GPU debuggers.
+	label string
+
+	// patchVertices is the number of vertices per patch for shaders with
+	// tessellation stages, or 0 to draw triangles.
+	patchVertices int
+
+	// outputs are the fragment outputs by draw buffer, with "" for
+	// buffers no output is bound to.
+	outputs []string
+
+	// varyings are the vertex outputs captured by DrawFeedback.
+	varyings []string
 }
 
 type ShaderSource interface {
@@ -26,6 +56,12 @@ type ShaderSource interface {
 type VertexShader string
 type FragmentShader string
 
+// TessControlShader and TessEvalShader are tessellation stages. A shader
+// with a tessellation evaluation stage draws patches instead of triangles;
+// see SetPatchVertices.
+type TessControlShader string
+type TessEvalShader string
+
 func (v VertexShader) typ() gl.GLenum {
 	return gl.VERTEX_SHADER
 }
@@ -42,57 +78,309 @@ func (f FragmentShader) source() string {
 	return string(f)
 }
 
-func BuildShader(attrs VertexAttributes, srcs ...ShaderSource) *Shader {
+func (t TessControlShader) typ() gl.GLenum {
+	return gl.TESS_CONTROL_SHADER
+}
+
+func (t TessControlShader) source() string {
+	return string(t)
+}
+
+func (t TessEvalShader) typ() gl.GLenum {
+	return gl.TESS_EVALUATION_SHADER
+}
+
+func (t TessEvalShader) source() string {
+	return string(t)
+}
+
+// ShaderError describes a shader that failed to compile or a program that
+// failed to link.
+type ShaderError struct {
+	Stage string // "vertex", "tess control", "tess eval", "fragment", or "link"
+	Log   string // info log reported by the driver
+
+	// Line is the first source line the log refers to, or 0 if it could not
+	// be determined. Source holds the text of that line.
+	Line   int
+	Source string
+}
+
+func (e *ShaderError) Error() string {
+	msg := fmt.Sprintf("gfx: %s shader error", e.Stage)
+	if e.Stage == "link" {
+		msg = "gfx: shader link error"
+	}
+	if e.Line > 0 {
+		msg += fmt.Sprintf(" at line %d: %s", e.Line, strings.TrimSpace(e.Source))
+	}
+	return msg + "\n" + e.Log
+}
+
+// logLineRe matches the source location of common info log formats, such as
+// "0(12) : error" and "ERROR: 0:12: ".
+var logLineRe = regexp.MustCompile(`(?m)^(?:ERROR: |WARNING: )?\d+[:(](\d+)\)?`)
+
+func newShaderError(stage, log, src string) *ShaderError {
+	err := &ShaderError{Stage: stage, Log: log}
+	if m := logLineRe.FindStringSubmatch(log); m != nil {
+		err.Line, _ = strconv.Atoi(m[1])
+		lines := strings.Split(src, "\n")
+		if err.Line > 0 && err.Line <= len(lines) {
+			err.Source = lines[err.Line-1]
+		}
+	}
+	return err
+}
+
+func stageName(typ gl.GLenum) string {
+	switch typ {
+	case gl.VERTEX_SHADER:
+		return "vertex"
+	case gl.TESS_CONTROL_SHADER:
+		return "tess control"
+	case gl.TESS_EVALUATION_SHADER:
+		return "tess eval"
+	case gl.FRAGMENT_SHADER:
+		return "fragment"
+	default:
+		return "unknown"
+	}
+}
+
+// BuildShader preprocesses the given sources with ShaderPreprocessor, then
+// compiles and links them into a shader program, or loads the program from
+// ShaderCacheDir. If preprocessing, compiling, or linking fails, the
+// returned error is a *ShaderError.
+func BuildShader(attrs VertexAttributes, srcs ...ShaderSource) (*Shader, error) {
+	return buildShader(attrs, nil, srcs)
+}
+
+// BuildFeedbackShader builds a shader like BuildShader whose vertex outputs
+// named in varyings are captured by DrawFeedback, interleaved in the order
+// given, such as to advance a simulation held in vertex buffers on the GPU.
+// Transform feedback needs OpenGL 3.0 or OpenGL ES 3.0.
+func BuildFeedbackShader(attrs VertexAttributes, varyings []string, srcs ...ShaderSource) (*Shader, error) {
+	detectCaps()
+	if !caps.feedback {
+		return nil, errors.New("gfx: transform feedback is not supported")
+	}
+	if len(varyings) == 0 {
+		return nil, errors.New("gfx: no varyings to capture")
+	}
+	return buildShader(attrs, append([]string(nil), varyings...), srcs)
+}
+
+func buildShader(attrs VertexAttributes, varyings []string, srcs []ShaderSource) (*Shader, error) {
 	shader := &Shader{
 		vertexAttrs:  attrs.clone(),
 		vertexFormat: attrs.Format(),
+		varyings:     varyings,
+	}
+	pointSize := false
+	texts := make([]string, len(srcs))
+	for i, src := range srcs {
+		text, err := ShaderPreprocessor.Process(src)
+		if err != nil {
+			return nil, &ShaderError{Stage: stageName(src.typ()), Log: err.Error()}
+		}
+		texts[i] = text
+		if src.typ() == gl.FRAGMENT_SHADER {
+			shader.outputs = fragmentOutputs(text)
+		}
+		if src.typ() == gl.TESS_EVALUATION_SHADER {
+			shader.patchVertices = 3
+		}
+		if src.typ() == gl.VERTEX_SHADER && strings.Contains(text, "gl_PointSize") {
+			pointSize = true
+		}
+	}
+	if pointSize {
+		enableProgramPointSize()
 	}
 	shader.prog = gl.CreateProgram()
-	ss := make([]gl.Shader, len(srcs))
+	shader.SetLabel(autoLabel("Shader"))
+	var key string
+	detectCaps()
+	if ShaderCacheDir != "" && caps.programBinary {
+		key = shaderCacheKey(srcs, texts, varyings)
+		if loadProgramBinary(shader.prog, key) {
+			shader.resetUniformLocations()
+			setFinalizer(shader, (*Shader).finalize)
+			checkError("BuildShader")
+			return shader, nil
+		}
+		gl.ProgramParameteri(shader.prog, gl.PROGRAM_BINARY_RETRIEVABLE_HINT, gl.TRUE)
+	}
+	ss := make([]gl.Shader, 0, len(srcs))
+	// No longer need shader objects with a fully built program.
+	release := func() {
+		for _, s := range ss {
+			shader.prog.DetachShader(s)
+			s.Delete()
+		}
+	}
 	for i, src := range srcs {
 		s := gl.CreateShader(src.typ())
-		s.Source(src.source())
-		s.Compile()
-		println(s.GetInfoLog())
 		shader.prog.AttachShader(s)
-		ss[i] = s
+		ss = append(ss, s)
+		s.Source(texts[i])
+		s.Compile()
+		if s.Get(gl.COMPILE_STATUS) == 0 {
+			err := newShaderError(stageName(src.typ()), s.GetInfoLog(), texts[i])
+			release()
+			deleteProgram(shader.prog)
+			return nil, err
+		}
+	}
+	for i, name := range shader.outputs {
+		if name != "" {
+			shader.prog.BindFragDataLocation(i, name)
+		}
+	}
+	if len(varyings) > 0 {
+		shader.prog.TransformFeedbackVaryings(varyings, gl.INTERLEAVED_ATTRIBS)
 	}
 	shader.prog.Link()
-	println(shader.prog.GetInfoLog())
-
-	// No longer need shader objects with a fully built program.
-	for _, s := range ss {
-		shader.prog.DetachShader(s)
-		s.Delete()
+	release()
+	if shader.prog.Get(gl.LINK_STATUS) == 0 {
+		err := &ShaderError{Stage: "link", Log: shader.prog.GetInfoLog()}
+		deleteProgram(shader.prog)
+		return nil, err
 	}
-	return shader
+	if key != "" {
+		saveProgramBinary(shader.prog, key)
+	}
+	shader.resetUniformLocations()
+	setFinalizer(shader, (*Shader).finalize)
+	checkError("BuildShader")
+	return shader, nil
 }
 
 func (s *Shader) Delete() {
-	s.prog.Delete()
+	setFinalizer(s, nil)
+	evictLayouts(s.cacheID)
+	if layout, ok := fullscreenLayouts[s]; ok {
+		layout.Delete()
+		delete(fullscreenLayouts, s)
+	}
+	deleteProgram(s.prog)
+}
+
+// ID returns a number identifying the shader, unique among the shaders of
+// the process and assigned the first time it is needed, such as for sort
+// keys that must not keep the shader from being garbage collected.
+func (s *Shader) ID() uint64 {
+	return cacheID(&s.cacheID)
+}
+
+// SetLabel names the program in GPU debuggers, such as RenderDoc and
+// apitrace, when the context has KHR_debug. Shaders are otherwise named
+// like "Shader#3", and their geometry layouts after the shader and the
+// geometry.
+func (s *Shader) SetLabel(name string) {
+	s.label = name
+	labelObject(gl.PROGRAM, uint32(s.prog), name)
 }
 
 func (s *Shader) VertexFormat() VertexFormat {
 	return s.vertexFormat
 }
 
-// texunit finds a previously assigned texture unit for loc, or
-// selects the next one.
-func (s *Shader) texunit(loc gl.UniformLocation) int {
-	for i, v := range s.texlocs {
-		if loc == v {
+// SetInstanceAttributes maps shader attributes by name to per-instance
+// vertex data, which is advanced once per instance rather than once per
+// vertex. Mapping the same name to consecutive formats, such as
+// VertexUserData through VertexUserData3, feeds the columns of a matrix
+// attribute. It must be called before LayoutGeometry.
+func (s *Shader) SetInstanceAttributes(attrs VertexAttributes) {
+	s.instAttrs = attrs.clone()
+	s.instFormat = attrs.Format()
+}
+
+// SetPatchVertices sets the number of vertices in each patch drawn by a
+// shader with tessellation stages. It defaults to 3, treating each triangle
+// of indices as a patch. It must only be called on shaders with a
+// tessellation evaluation stage.
+func (s *Shader) SetPatchVertices(n int) {
+	s.patchVertices = n
+}
+
+// mode gives the primitive mode to draw with, that of the current geometry,
+// and sets the patch size if drawing patches.
+func (s *Shader) mode() gl.GLenum {
+	if s.patchVertices > 0 {
+		gl.PatchParameteri(gl.PATCH_VERTICES, s.patchVertices)
+		return gl.PATCHES
+	}
+	return s.primitive.gl()
+}
+
+// FragmentOutput returns the draw buffer that the fragment output name
+// writes to, or -1 if the shader has no such output. Outputs declared with
+// "out vec4" in GLSL 1.30 and later are bound to draw buffers in the order
+// they are declared, unless given a location with a layout qualifier, so
+// that a shader writing
+//
+//	out vec4 Color;
+//	out vec4 Velocity;
+//
+// draws Velocity into color attachment 1 of the bound framebuffer, as it
+// would if translated to gl_FragData for a legacy context. Outputs are only
+// known by name on contexts with named outputs; FragmentOutput returns -1
+// on others.
+func (s *Shader) FragmentOutput(name string) int {
+	for i, out := range s.outputs {
+		if out == name {
 			return i
 		}
 	}
-	v := len(s.texlocs)
-	s.texlocs = append(s.texlocs, loc)
-	return v
+	return -1
+}
+
+// InstanceFormat returns the format of per-instance data expected by the
+// shader.
+func (s *Shader) InstanceFormat() VertexFormat {
+	return s.instFormat
+}
+
+// resetUniformLocations clears cached uniform locations. It must be called
+// whenever the program is linked.
+func (s *Shader) resetUniformLocations() {
+	s.uniformLocs = make(map[string]gl.UniformLocation)
+	s.samplerUnits = nil
+	s.checked = nil
+}
+
+// uniformLocation returns the location of the named uniform, querying GL
+// only the first time a name is seen. Unknown names are cached as -1.
+func (s *Shader) uniformLocation(name string) gl.UniformLocation {
+	if u, ok := s.uniformLocs[name]; ok {
+		return u
+	}
+	u := s.prog.GetUniformLocation(name)
+	s.uniformLocs[name] = u
+	return u
+}
+
+// HasUniform reports whether the program has an active uniform variable
+// with the given name. Uniforms that are declared but unused may be
+// optimized away by the driver.
+func (s *Shader) HasUniform(name string) bool {
+	return s.uniformLocation(name) >= 0
 }
 
 // Use puts the shader as the active program to bind data to and execute.
 func (s *Shader) Use() {
-	s.prog.Use()
-	s.texlocs = s.texlocs[:]
+	useProgram(s.prog)
+}
+
+// SetStrictUniforms enables or disables strict uniform checking. In strict
+// mode, AssignUniforms checks every "uniform" field of a struct type the
+// first time it is assigned, and returns an error listing all fields whose
+// uniform does not exist or has a type the field cannot be assigned to,
+// without assigning any of them.
+func (s *Shader) SetStrictUniforms(strict bool) {
+	s.strict = strict
 }
 
 // AssignUniforms takes struct fields with "uniform" tag and assigns their values
@@ -100,6 +388,11 @@ func (s *Shader) Use() {
 func (s *Shader) AssignUniforms(data interface{}) error {
 	var err error
 	val := reflect.ValueOf(data)
+	if s.strict {
+		if err := s.checkUniforms(val.Type().Elem()); err != nil {
+			return err
+		}
+	}
 	ptr := val.Pointer()
 	val = val.Elem()
 	typ := val.Type()
@@ -135,42 +428,115 @@ func (s *Shader) AssignUniforms(data interface{}) error {
 	return nil
 }
 
+// SetUniform assigns value to the named uniform variable. value may be of
+// any type a "uniform" field of AssignUniforms may have, or a pointer to
+// one.
+func (s *Shader) SetUniform(name string, value interface{}) error {
+	val := reflect.ValueOf(value)
+	if !val.IsValid() || val.Kind() == reflect.Ptr && val.IsNil() {
+		return fmt.Errorf("gfx: invalid uniform value for '%s'", name)
+	}
+	typ := val.Type()
+	ptr := unsafe.Pointer(nil)
+	if typ.Kind() != reflect.Ptr {
+		// assign reads primitives through a pointer
+		p := reflect.New(typ)
+		p.Elem().Set(val)
+		ptr = unsafe.Pointer(p.Pointer())
+	}
+	return s.assign(ptr, val, typ, name)
+}
+
 func (s *Shader) assign(ptr unsafe.Pointer, val reflect.Value, typ reflect.Type, name string) error {
-	u := s.prog.GetUniformLocation(name)
+	u := s.uniformLocation(name)
 	if u < 0 {
 		return fmt.Errorf("gfx: unknown uniform variable '%s'", name)
 	}
 	if typ.Kind() == reflect.Ptr {
 		if s.assignPrimitive(unsafe.Pointer(val.Pointer()), typ.Elem(), u) {
+			checkError("assign uniform %s", name)
 			return nil
 		}
 	} else if s.assignPrimitive(ptr, typ, u) {
+		checkError("assign uniform %s", name)
 		return nil
 	}
 	iface := val.Interface()
 	switch iface.(type) {
 	// special types
 	case *Sampler2D:
-		sampler := iface.(*Sampler2D)
-		texunit := s.texunit(u)
-		gl.ActiveTexture(gl.TEXTURE0 + gl.GLenum(texunit))
-		sampler.bind()
-		u.Uniform1i(texunit)
+		if err := s.bindSampler(u, iface.(*Sampler2D).tex, gl.TEXTURE_2D); err != nil {
+			return err
+		}
+	case *SamplerCube:
+		if err := s.bindSampler(u, iface.(*SamplerCube).tex, gl.TEXTURE_CUBE_MAP); err != nil {
+			return err
+		}
 	default:
-		return fmt.Errorf("gfx: invalid uniform type %v", typ)
+		// math types may implement the data interfaces on their pointer
+		if !assignData(iface, u) && (typ.Kind() == reflect.Ptr || !assignData(reflect.NewAt(typ, ptr).Interface(), u)) {
+			return fmt.Errorf("gfx: invalid uniform type %v", typ)
+		}
 	}
+	checkError("assign uniform %s", name)
 	return nil
 }
 
+// SetTexture binds tex to a texture unit and assigns the unit to the named
+// sampler uniform.
+func (s *Shader) SetTexture(name string, tex *Sampler2D) error {
+	u := s.uniformLocation(name)
+	if u < 0 {
+		return fmt.Errorf("gfx: unknown uniform variable '%s'", name)
+	}
+	if err := s.bindSampler(u, tex.tex, gl.TEXTURE_2D); err != nil {
+		return err
+	}
+	checkError("SetTexture %s", name)
+	return nil
+}
+
+// SetCubeTexture binds tex to a texture unit and assigns the unit to the
+// named samplerCube uniform.
+func (s *Shader) SetCubeTexture(name string, tex *SamplerCube) error {
+	u := s.uniformLocation(name)
+	if u < 0 {
+		return fmt.Errorf("gfx: unknown uniform variable '%s'", name)
+	}
+	if err := s.bindSampler(u, tex.tex, gl.TEXTURE_CUBE_MAP); err != nil {
+		return err
+	}
+	checkError("SetCubeTexture %s", name)
+	return nil
+}
+
+// assignPrimitive assigns the value of Go type typ at ptr to u, and reports
+// whether typ is a supported primitive type. Arrays of up to four elements
+// are vectors, and arrays of 9 or 16 floats are 3x3 or 4x4 matrices. Arrays
+// of float arrays, such as [2][3]float32, are matrices of that many columns
+// and rows, in this case a mat2x3. float64 values are converted to float32.
+// Slices of [4]float32 and [16]float32 are uniform arrays of vec4 and mat4.
 func (s *Shader) assignPrimitive(ptr unsafe.Pointer, typ reflect.Type, u gl.UniformLocation) bool {
 	switch typ.Kind() {
 	// basic primitives
+	case reflect.Bool:
+		v := 0
+		if *(*bool)(ptr) {
+			v = 1
+		}
+		u.Uniform1i(v)
 	case reflect.Int:
 		u.Uniform1i(*(*int)(ptr))
 	case reflect.Int32:
 		u.Uniform1i(int(*(*int32)(ptr)))
+	case reflect.Uint:
+		u.Uniform1ui(*(*uint)(ptr))
+	case reflect.Uint32:
+		u.Uniform1ui(uint(*(*uint32)(ptr)))
 	case reflect.Float32:
 		u.Uniform1f(*(*float32)(ptr))
+	case reflect.Float64:
+		u.Uniform1f(float32(*(*float64)(ptr)))
 	// arrays represent vectors or matrices
 	case reflect.Array:
 		size := typ.Len()
@@ -190,6 +556,20 @@ func (s *Shader) assignPrimitive(ptr unsafe.Pointer, typ reflect.Type, u gl.Unif
 			default:
 				return false
 			}
+		case reflect.Uint32:
+			switch size {
+			case 2:
+				slice := (*(*[2]uint32)(ptr))[:]
+				u.Uniform2uiv(1, slice)
+			case 3:
+				slice := (*(*[3]uint32)(ptr))[:]
+				u.Uniform3uiv(1, slice)
+			case 4:
+				slice := (*(*[4]uint32)(ptr))[:]
+				u.Uniform4uiv(1, slice)
+			default:
+				return false
+			}
 		case reflect.Float32:
 			switch size {
 			case 2:
@@ -210,6 +590,37 @@ func (s *Shader) assignPrimitive(ptr unsafe.Pointer, typ reflect.Type, u gl.Unif
 			default:
 				return false
 			}
+		case reflect.Float64:
+			if size > 16 {
+				return false
+			}
+			var conv [16]float32
+			for i := 0; i < size; i++ {
+				conv[i] = float32(*(*float64)(unsafe.Pointer(uintptr(ptr) + uintptr(i)*8)))
+			}
+			return s.assignPrimitive(unsafe.Pointer(&conv), reflect.ArrayOf(size, float32Type), u)
+		case reflect.Array:
+			if elemtyp.Elem().Kind() != reflect.Float32 {
+				return false
+			}
+			return assignMatrix(ptr, size, elemtyp.Len(), u)
+		default:
+			return false
+		}
+	// slices represent arrays of vectors or matrices
+	case reflect.Slice:
+		elemtyp := typ.Elem()
+		if elemtyp.Kind() != reflect.Array || elemtyp.Elem().Kind() != reflect.Float32 {
+			return false
+		}
+		switch elemtyp.Len() {
+		case 4:
+			list := *(*[][4]float32)(ptr)
+			if len(list) > 0 {
+				u.Uniform4fv(len(list), unsafe.Slice(&list[0][0], len(list)*4))
+			}
+		case 16:
+			u.UniformMatrix4fv(false, *(*[][16]float32)(ptr)...)
 		default:
 			return false
 		}
@@ -219,73 +630,351 @@ func (s *Shader) assignPrimitive(ptr unsafe.Pointer, typ reflect.Type, u gl.Unif
 	return true
 }
 
+var float32Type = reflect.TypeOf(float32(0))
+
+// assignMatrix assigns the column-major matrix of cols columns and rows
+// rows at ptr to u, and reports whether that size of matrix exists.
+func assignMatrix(ptr unsafe.Pointer, cols, rows int, u gl.UniformLocation) bool {
+	switch [2]int{cols, rows} {
+	case [2]int{2, 2}:
+		u.UniformMatrix2f(false, (*[4]float32)(ptr))
+	case [2]int{3, 3}:
+		u.UniformMatrix3f(false, (*[9]float32)(ptr))
+	case [2]int{4, 4}:
+		u.UniformMatrix4f(false, (*[16]float32)(ptr))
+	case [2]int{2, 3}:
+		u.UniformMatrix2x3fv(false, *(*[6]float32)(ptr))
+	case [2]int{3, 2}:
+		u.UniformMatrix3x2fv(false, *(*[6]float32)(ptr))
+	case [2]int{2, 4}:
+		u.UniformMatrix2x4fv(false, *(*[8]float32)(ptr))
+	case [2]int{4, 2}:
+		u.UniformMatrix4x2fv(false, *(*[8]float32)(ptr))
+	case [2]int{3, 4}:
+		u.UniformMatrix3x4fv(false, *(*[12]float32)(ptr))
+	case [2]int{4, 3}:
+		u.UniformMatrix4x3fv(false, *(*[12]float32)(ptr))
+	default:
+		return false
+	}
+	return true
+}
+
 type GeometryLayout struct {
 	vao    gl.VertexArray
-	idxbuf *IndexBuffer
+	geom   *Geometry
+	planes []*VertexBuffer
 	shader *Shader
 }
 
 // LayoutGeometry builds a vertex array object holding vertex attribute locations and
-// buffer pointers for the given geometry.
-func LayoutGeometry(s *Shader, geom *Geometry) *GeometryLayout {
-	vertices := geom.VertexBuffer
-	if s.vertexFormat != vertices.Format() {
-		panic("moo")
+// buffer pointers for the given geometry. The vertex format of the geometry,
+// and of its instances if it has any, must match that of the shader. In
+// debug mode, attributes of the shader that are fed by neither format are
+// logged.
+func LayoutGeometry(s *Shader, geom *Geometry) (*GeometryLayout, error) {
+	vao, err := s.layout(geom)
+	if err != nil {
+		return nil, err
+	}
+	layout := &GeometryLayout{
+		vao:    vao,
+		geom:   geom,
+		planes: append([]*VertexBuffer(nil), geom.planes...),
+		shader: s,
+	}
+	setFinalizer(layout, (*GeometryLayout).finalize)
+	checkError("LayoutGeometry")
+	return layout, nil
+}
+
+// layout checks the formats of geom against the shader and creates a vertex
+// array object pointing into its buffers. Without vertex array object
+// support, it returns 0, and the attribute pointers are set each time the
+// geometry is bound instead.
+func (s *Shader) layout(geom *Geometry) (gl.VertexArray, error) {
+	if err := formatMismatch("vertex", geom.VertexFormat().Unpacked(), s.vertexFormat); err != nil {
+		return 0, err
+	}
+	if geom.Instances.Format() != 0 {
+		if err := formatMismatch("instance", geom.Instances.Format().Unpacked(), s.instFormat); err != nil {
+			return 0, err
+		}
+	}
+	if debug {
+		s.logUnfedAttributes()
+	}
+	detectCaps()
+	if !caps.vao {
+		return 0, nil
 	}
 	vao := gl.GenVertexArray()
-	vao.Bind()
+	bindVertexArray(vao)
+	if s.label != "" && geom.label != "" {
+		labelObject(gl.VERTEX_ARRAY, uint32(vao), s.label+" "+geom.label)
+	}
+	s.pointBuffers(&geom.VertexBuffer, geom.planes, &geom.Instances, &geom.IndexBuffer)
+	return vao, nil
+}
 
-	var attrib gl.AttribLocation
-	vertices.bind()
+// pointBuffers binds the buffers of a geometry and sets the attribute
+// pointers into them, returning the set of attribute locations enabled.
+func (s *Shader) pointBuffers(vertbuf *VertexBuffer, planes []*VertexBuffer, instbuf *VertexBuffer, idxbuf *IndexBuffer) uint32 {
+	vertbuf.bind()
+	enabled := s.pointAttribs(s.vertexAttrs, vertbuf.Format(), 0)
+	for _, b := range planes {
+		b.bind()
+		enabled |= s.pointAttribs(s.vertexAttrs, b.Format(), 0)
+	}
+	if instbuf.Format() != 0 {
+		instbuf.bind()
+		enabled |= s.pointAttribs(s.instAttrs, instbuf.Format(), 1)
+	}
+	idxbuf.bind()
+	return enabled
+}
 
-	var i VertexFormat
+// formatMismatch describes how the format of geometry data differs from
+// the format the shader expects, or returns nil if they match.
+func formatMismatch(kind string, have, want VertexFormat) error {
+	if have == want {
+		return nil
+	}
+	msg := fmt.Sprintf("gfx: geometry %s format %v does not match shader format %v", kind, have, want)
+	if missing := want &^ have; missing != 0 {
+		msg += fmt.Sprintf("; missing %v", missing)
+	}
+	if extra := have &^ want; extra != 0 {
+		msg += fmt.Sprintf("; unmapped %v", extra)
+	}
+	return errors.New(msg)
+}
+
+// logUnfedAttributes logs the active attributes of the shader that are not
+// mapped to vertex or instance data, which read a constant value.
+func (s *Shader) logUnfedAttributes() {
+	mapped := make(map[string]bool)
+	for _, name := range s.vertexAttrs {
+		mapped[name] = true
+	}
+	for _, name := range s.instAttrs {
+		mapped[name] = true
+	}
+	for _, v := range s.Attributes() {
+		if !mapped[v.Name] && !strings.HasPrefix(v.Name, "gl_") {
+			log.Printf("gfx: shader attribute %q is not in the vertex format", v.Name)
+		}
+	}
+}
+
+// pointAttribs sets attribute pointers for interleaved data of format vf in
+// the currently bound array buffer, and returns the set of attribute
+// locations enabled. Attributes the shader does not use are skipped. Every
+// part of vf has a name in attrs, as vf.Unpacked() is attrs.Format().
+func (s *Shader) pointAttribs(attrs VertexAttributes, vf VertexFormat, divisor int) uint32 {
+	var (
+		i      VertexFormat
+		attrib gl.AttribLocation
+		prev   string
+	)
+	var enabled uint32
 	offset := 0
-	stride := s.vertexFormat.Stride()
+	stride := vf.Stride()
 	for i = 1; i <= MaxVertexFormat; i <<= 1 {
-		if s.vertexFormat&i == 0 {
+		if vf&i == 0 {
 			continue
 		}
-		name, ok := s.vertexAttrs[i]
-		if !ok {
-			// TODO: return error or something?
-			break
+		name := attrs[i]
+		if name == prev && attrib >= 0 {
+			// next column of a matrix attribute
+			attrib++
+		} else {
+			attrib = s.prog.GetAttribLocation(name)
 		}
-		attrib = s.prog.GetAttribLocation(name)
+		prev = name
 		if attrib >= 0 {
-			attrib.AttribPointer(i.attribElems(), i.attribType(), i.attribNormalized(), stride, uintptr(offset))
+			vf.attribPointer(attrib, i, stride, offset)
 			attrib.EnableArray()
+			enabled |= 1 << uint(attrib)
+			if divisor != 0 {
+				attrib.AttribDivisor(divisor)
+			}
 		}
-		offset += i.AttribBytes()
-	}
-
-	geom.IndexBuffer.bind()
-
-	layout := &GeometryLayout{
-		vao:    vao,
-		idxbuf: &geom.IndexBuffer,
-		shader: s,
+		offset += vf.AttribSize(i)
 	}
-	return layout
+	return enabled
 }
 
 func (g *GeometryLayout) Delete() {
-	g.vao.Delete()
+	setFinalizer(g, nil)
+	if g.vao != 0 {
+		deleteVertexArray(g.vao)
+	}
 }
 
-// SetGeometry binds the underlying vertex array object that holds the buffer pointers.
-func (s *Shader) SetGeometry(layout *GeometryLayout) error {
+// SetLayout binds the underlying vertex array object that holds the buffer pointers.
+func (s *Shader) SetLayout(layout *GeometryLayout) error {
 	if layout.shader != s {
 		return errors.New("gfx: geometry layout not compatible with this shader")
 	}
-	s.indexCount = layout.idxbuf.Count()
-	s.indexType = layout.idxbuf.elemtype
-	//s.indexOffset = indices.Offset()
-	layout.vao.Bind()
+	s.bindGeometry(layout.vao, layout.geom, layout.planes)
+	checkError("SetLayout")
 	return nil
 }
 
+// bindGeometry binds vao, or sets the attribute pointers into the buffers
+// of geom if it is 0, records the counts and primitive of geom for drawing,
+// and enables primitive restart for it.
+func (s *Shader) bindGeometry(vao gl.VertexArray, geom *Geometry, planes []*VertexBuffer) {
+	vertbuf, instbuf, idxbuf := &geom.VertexBuffer, &geom.Instances, &geom.IndexBuffer
+	s.indexed = idxbuf.buf != 0
+	s.indexCount = idxbuf.Count()
+	s.indexType = idxbuf.elemtype
+	s.indexOffset = idxbuf.byteOffset()
+	s.indexBuf = idxbuf.buf
+	s.vertexCount = vertbuf.Count()
+	s.primitive = geom.Primitive
+	setPrimitiveRestart(geom.PrimitiveRestart && s.indexed, s.indexType)
+	if vao != 0 {
+		bindVertexArray(vao)
+		return
+	}
+	// without vertex array objects, attribute arrays stay enabled until
+	// disabled, so those of the previous geometry are disabled here
+	enabled := s.pointBuffers(vertbuf, planes, instbuf, idxbuf)
+	for i := 0; i < 32; i++ {
+		if (enabledAttribs&^enabled)&(1<<uint(i)) != 0 {
+			gl.AttribLocation(i).DisableArray()
+		}
+	}
+	enabledAttribs = enabled
+}
+
+// enabledAttribs is the set of attribute arrays enabled by bindGeometry
+// without vertex array objects.
+var enabledAttribs uint32
+
 // Draw makes a glDrawElements call using the previously set uniforms and
-// geometry.
+// geometry, or a glDrawArrays call if the geometry has no indices.
 func (s *Shader) Draw() {
-	gl.DrawElements(gl.TRIANGLES, s.indexCount, s.indexType, uintptr(s.indexOffset))
+	if !s.indexed {
+		gl.DrawArrays(s.mode(), 0, s.vertexCount)
+	} else {
+		gl.DrawElements(s.mode(), s.indexCount, s.indexType, uintptr(s.indexOffset))
+	}
+	s.countDraw(s.elemCount(), 1)
+	checkError("Draw")
+}
+
+// DrawInstanced draws count instances of the previously set geometry with a
+// single glDrawElementsInstanced call, or glDrawArraysInstanced if the
+// geometry has no indices. Per-instance attributes are read from the
+// geometry's instance buffer.
+func (s *Shader) DrawInstanced(count int) {
+	if !s.indexed {
+		gl.DrawArraysInstanced(s.mode(), 0, s.vertexCount, count)
+	} else {
+		gl.DrawElementsInstanced(s.mode(), s.indexCount, s.indexType, uintptr(s.indexOffset), count)
+	}
+	s.countDraw(s.elemCount(), count)
+	checkError("DrawInstanced %d", count)
+}
+
+// DrawRange draws count indices starting at first, relative to the
+// geometry's index buffer, or count vertices if the geometry has no indices.
+// A zero count draws everything from first on.
+func (s *Shader) DrawRange(first, count int) {
+	if count == 0 {
+		count = s.elemCount() - first
+	}
+	if !s.indexed {
+		gl.DrawArrays(s.mode(), first, count)
+	} else {
+		gl.DrawElements(s.mode(), count, s.indexType, uintptr(s.indexOffset+first*s.indexSize()))
+	}
+	s.countDraw(count, 1)
+	checkError("DrawRange %d, %d", first, count)
+}
+
+// DrawSlice draws a slice of the index buffer of the previously set
+// geometry, as returned by IndexBuffer.Slice.
+func (s *Shader) DrawSlice(indices IndexBuffer) error {
+	if !s.indexed || indices.buf != s.indexBuf {
+		return errors.New("gfx: index slice is not from the current geometry")
+	}
+	gl.DrawElements(s.mode(), indices.count, s.indexType, uintptr(indices.byteOffset()))
+	s.countDraw(indices.count, 1)
+	checkError("DrawSlice")
+	return nil
+}
+
+// DrawSlices draws several slices of the index buffer of the previously set
+// geometry, as returned by IndexBuffer.Slice, with a single
+// glMultiDrawElements call, such as the submeshes or strips of a terrain
+// patch. On OpenGL ES, which lacks it, each slice is drawn in turn.
+func (s *Shader) DrawSlices(slices ...IndexBuffer) error {
+	counts := make([]int32, len(slices))
+	offsets := make([]uintptr, len(slices))
+	total := 0
+	for i, indices := range slices {
+		if !s.indexed || indices.buf != s.indexBuf {
+			return errors.New("gfx: index slice is not from the current geometry")
+		}
+		counts[i] = int32(indices.count)
+		offsets[i] = uintptr(indices.byteOffset())
+		total += indices.count
+	}
+	detectCaps()
+	if caps.es {
+		for i := range slices {
+			gl.DrawElements(s.mode(), int(counts[i]), s.indexType, offsets[i])
+		}
+	} else if len(slices) > 0 {
+		gl.MultiDrawElements(s.mode(), counts, s.indexType, offsets)
+	}
+	s.countDraw(total, 1)
+	checkError("DrawSlices %d", len(slices))
+	return nil
+}
+
+// DrawFeedback runs each vertex of the previously set geometry once through
+// a shader built with BuildFeedbackShader, without rasterizing anything,
+// and writes the captured varyings of vertex i to vertex i of dest, whose
+// format must interleave them in the same order. Indices are ignored. dest
+// must hold at least as many vertices, and not be read by the geometry,
+// so simulations ping-pong between two buffers.
+func (s *Shader) DrawFeedback(dest *VertexBuffer) error {
+	if len(s.varyings) == 0 {
+		return errors.New("gfx: shader captures no varyings")
+	}
+	if dest.count < s.vertexCount {
+		return fmt.Errorf("gfx: feedback of %d vertices into a buffer of %d", s.vertexCount, dest.count)
+	}
+	dest.buf.BindBufferBase(gl.TRANSFORM_FEEDBACK_BUFFER, 0)
+	gl.Enable(gl.RASTERIZER_DISCARD)
+	gl.BeginTransformFeedback(gl.POINTS)
+	gl.DrawArrays(gl.POINTS, 0, s.vertexCount)
+	gl.EndTransformFeedback()
+	gl.Disable(gl.RASTERIZER_DISCARD)
+	gl.Buffer(0).BindBufferBase(gl.TRANSFORM_FEEDBACK_BUFFER, 0)
+	s.countDraw(s.vertexCount, 1)
+	checkError("DrawFeedback %d", s.vertexCount)
+	return nil
+}
+
+// elemCount gives the number of indices, or vertices if the geometry has no
+// indices.
+func (s *Shader) elemCount() int {
+	if !s.indexed {
+		return s.vertexCount
+	}
+	return s.indexCount
+}
+
+// indexSize gives the size in bytes of a single index.
+func (s *Shader) indexSize() int {
+	if s.indexType == gl.UNSIGNED_INT {
+		return 4
+	}
+	return 2
 }
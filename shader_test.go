@@ -0,0 +1,604 @@
+package gfx_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/gfxtest"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const tintShader gfx.FragmentShader = `
+uniform vec4 Tint;
+
+varying vec2 TexCoord;
+
+void main() {
+	gl_FragColor = Tint;
+}`
+
+const colorVertexShader gfx.VertexShader = `
+attribute vec3 Position;
+attribute vec4 Color;
+
+varying vec4 FragColor;
+
+void main() {
+	FragColor = Color;
+	gl_Position = vec4(Position, 1.0);
+}`
+
+const textureShader gfx.FragmentShader = `
+uniform sampler2D Texture;
+
+varying vec2 TexCoord;
+
+void main() {
+	gl_FragColor = texture2D(Texture, TexCoord);
+}`
+
+func TestBindFragmentOutputs(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	const fs gfx.FragmentShader = `#version 330 core
+out vec4 Color;
+layout(location = 2) out vec4 Bright;
+out vec4 Velocity;
+
+void main() {
+	Color = vec4(1.0);
+	Bright = vec4(0.0);
+	Velocity = vec4(0.0);
+}`
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, colorVertexShader, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	for name, want := range map[string]int{"Color": 0, "Bright": 2, "Velocity": 3, "Normal": -1} {
+		if got := s.FragmentOutput(name); got != want {
+			t.Errorf("FragmentOutput(%q) = %d, want %d", name, got, want)
+		}
+	}
+	var bound []string
+	for _, call := range rec.Ops("BindFragDataLocation") {
+		bound = append(bound, fmt.Sprint(call.Args[1:]))
+	}
+	want := []string{"[0 Color]", "[2 Bright]", "[3 Velocity]"}
+	if !reflect.DeepEqual(bound, want) {
+		t.Errorf("bound %v, want %v", bound, want)
+	}
+}
+
+const moveShader gfx.VertexShader = `
+attribute vec3 Position;
+
+varying vec3 Moved;
+
+void main() {
+	Moved = Position + vec3(0.0, 1.0, 0.0);
+}`
+
+func TestDrawFeedback(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	attrs := gfx.VertexAttributes{gfx.VertexPosition: "Position"}
+	s, err := gfx.BuildFeedbackShader(attrs, []string{"Moved"}, moveShader, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	if calls := rec.Ops("TransformFeedbackVaryings"); len(calls) != 1 || fmt.Sprint(calls[0].Args[1:]) != "[[Moved] INTERLEAVED_ATTRIBS]" {
+		t.Errorf("got %v", calls)
+	}
+
+	b := geometry.NewBuilder(gfx.VertexPosition)
+	for i := 0; i < 4; i++ {
+		b.Position(float32(i), 0, 0)
+	}
+	src, err := gfx.NewGeometry(b, gfx.DynamicCopy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Delete()
+	dest, err := gfx.NewGeometry(b, gfx.DynamicCopy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dest.Delete()
+
+	s.Use()
+	if err := s.SetGeometry(src); err != nil {
+		t.Fatal(err)
+	}
+	rec.Reset()
+	if err := s.DrawFeedback(&dest.VertexBuffer); err != nil {
+		t.Fatal(err)
+	}
+	bind := rec.Ops("BindBufferBase")
+	if len(bind) != 2 || fmt.Sprint(bind[0].Args[:2]) != "[TRANSFORM_FEEDBACK_BUFFER 0]" || bind[1].Args[2] != uint32(0) {
+		t.Errorf("got bindings %v", bind)
+	}
+	// 0x0000 is POINTS
+	draws := rec.Draws()
+	if len(draws) != 1 || draws[0].Args[0] != gfxtest.Enum(0x0000) || draws[0].Args[2] != 4 {
+		t.Errorf("got draws %v, want the 4 vertices as points", draws)
+	}
+
+	b.Clear()
+	b.Position(0, 0, 0)
+	small, err := gfx.NewGeometry(b, gfx.DynamicCopy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer small.Delete()
+	if err := s.DrawFeedback(&small.VertexBuffer); err == nil {
+		t.Error("no error capturing into too small a buffer")
+	}
+	plain, err := gfx.BuildShader(attrs, moveShader, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Delete()
+	if err := plain.DrawFeedback(&dest.VertexBuffer); err == nil {
+		t.Error("no error capturing from a shader without varyings")
+	}
+}
+
+const typesShader gfx.FragmentShader = `
+uniform bool B;
+uniform uint U;
+uniform uint U32;
+uniform float F;
+uniform vec3 V;
+uniform mat2 M2;
+uniform mat2x3 M23;
+uniform mat3x2 M32;
+uniform mat2x4 M24;
+uniform mat4x2 M42;
+uniform mat3x4 M34;
+uniform mat4x3 M43;
+
+void main() {
+	gl_FragColor = vec4(F);
+}`
+
+// typesUniforms has a field of each Go type assigned to typesShader.
+type typesUniforms struct {
+	B   bool          `uniform:"B"`
+	U   uint          `uniform:"U"`
+	U32 uint32        `uniform:"U32"`
+	F   float64       `uniform:"F"`
+	V   [3]float64    `uniform:"V"`
+	M2  [2][2]float32 `uniform:"M2"`
+	M23 [2][3]float32 `uniform:"M23"`
+	M32 [3][2]float32 `uniform:"M32"`
+	M24 [2][4]float32 `uniform:"M24"`
+	M42 [4][2]float32 `uniform:"M42"`
+	M34 [3][4]float32 `uniform:"M34"`
+	M43 [4][3]float32 `uniform:"M43"`
+}
+
+func TestAssignUniformTypes(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, typesShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	s.SetStrictUniforms(true)
+	s.Use()
+	u := &typesUniforms{
+		B: true, U: 7, U32: 8, F: 0.5, V: [3]float64{1, 2, 3},
+		M2:  [2][2]float32{{1, 2}, {3, 4}},
+		M23: [2][3]float32{{1, 2, 3}, {4, 5, 6}},
+		M32: [3][2]float32{{1, 2}, {3, 4}, {5, 6}},
+		M24: [2][4]float32{{1, 2, 3, 4}, {5, 6, 7, 8}},
+		M42: [4][2]float32{{1, 2}, {3, 4}, {5, 6}, {7, 8}},
+		M34: [3][4]float32{{1, 2, 3, 4}, {5, 6, 7, 8}, {9, 10, 11, 12}},
+		M43: [4][3]float32{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}, {10, 11, 12}},
+	}
+	rec.Reset()
+	if err := s.AssignUniforms(u); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`Uniform1i("B", 1)`,
+		`Uniform1ui("U", 7)`,
+		`Uniform1ui("U32", 8)`,
+		`Uniform1f("F", 0.5)`,
+		`Uniform3fv("V", 1, [1 2 3])`,
+		`UniformMatrix2fv("M2", 1, false, [1 2 3 4])`,
+		`UniformMatrix2x3fv("M23", 1, false, [1 2 3 4 5 6])`,
+		`UniformMatrix3x2fv("M32", 1, false, [1 2 3 4 5 6])`,
+		`UniformMatrix2x4fv("M24", 1, false, [1 2 3 4 5 6 7 8])`,
+		`UniformMatrix4x2fv("M42", 1, false, [1 2 3 4 5 6 7 8])`,
+		`UniformMatrix3x4fv("M34", 1, false, [1 2 3 4 5 6 7 8 9 10 11 12])`,
+		`UniformMatrix4x3fv("M43", 1, false, [1 2 3 4 5 6 7 8 9 10 11 12])`,
+	} {
+		name := strings.Split(want, `"`)[1]
+		if c, ok := rec.Uniform(name); !ok || c.String() != want {
+			t.Errorf("got %v, want %s", c, want)
+		}
+	}
+
+	// a false bool is 0
+	u.B = false
+	if err := s.AssignUniforms(u); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := rec.Uniform("B"); c.String() != `Uniform1i("B", 0)` {
+		t.Errorf("got %v for false", c)
+	}
+}
+
+func TestSetUniform(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, typesShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	s.Use()
+	rec.Reset()
+	m := [2][3]float32{{1, 2, 3}, {4, 5, 6}}
+	for _, set := range []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"F", float32(2), `Uniform1f("F", 2)`},
+		{"B", true, `Uniform1i("B", 1)`},
+		{"V", &[3]float32{1, 2, 3}, `Uniform3fv("V", 1, [1 2 3])`},
+		{"M23", m, `UniformMatrix2x3fv("M23", 1, false, [1 2 3 4 5 6])`},
+		{"M23", &m, `UniformMatrix2x3fv("M23", 1, false, [1 2 3 4 5 6])`},
+	} {
+		rec.Reset()
+		if err := s.SetUniform(set.name, set.value); err != nil {
+			t.Errorf("%T: %v", set.value, err)
+			continue
+		}
+		if c, _ := rec.Uniform(set.name); c.String() != set.want {
+			t.Errorf("%T: got %v, want %s", set.value, c, set.want)
+		}
+	}
+
+	for _, bad := range []struct {
+		name  string
+		value interface{}
+		err   string
+	}{
+		{"F", nil, "gfx: invalid uniform value for 'F'"},
+		{"F", (*float32)(nil), "gfx: invalid uniform value for 'F'"},
+		{"Missing", float32(1), "gfx: unknown uniform variable 'Missing'"},
+		{"F", "one", "gfx: invalid uniform type string"},
+	} {
+		rec.Reset()
+		err := s.SetUniform(bad.name, bad.value)
+		if err == nil || err.Error() != bad.err {
+			t.Errorf("%#v: got error %v, want %s", bad.value, err, bad.err)
+		}
+		if c, ok := rec.Uniform(bad.name); ok {
+			t.Errorf("%#v: got %v", bad.value, c)
+		}
+	}
+}
+
+func TestBuildShaderError(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	const broken gfx.FragmentShader = `
+void main() {
+	#error missing color
+}`
+	rec.Reset()
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, broken)
+	if s != nil {
+		t.Error("got a shader from a failed build")
+	}
+	serr, ok := err.(*gfx.ShaderError)
+	if !ok {
+		t.Fatalf("got error %v, want a *gfx.ShaderError", err)
+	}
+	if serr.Stage != "fragment" || serr.Source != "\t#error missing color" || !strings.Contains(serr.Log, "missing color") {
+		t.Errorf("got %+v", serr)
+	}
+	if want := "gfx: fragment shader error at line " + fmt.Sprint(serr.Line) + ": #error missing color"; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("got %q, want it to start with %q", err, want)
+	}
+
+	// nothing is linked, and the shaders and program are deleted
+	if n := len(rec.Ops("LinkProgram")); n != 0 {
+		t.Errorf("linked %d times", n)
+	}
+	created := len(rec.Ops("CreateShader"))
+	if deleted := len(rec.Ops("DeleteShader")); created != 2 || deleted != created {
+		t.Errorf("created %d shaders and deleted %d", created, deleted)
+	}
+	if n := len(rec.Ops("DeleteProgram")); n != 1 {
+		t.Errorf("deleted %d programs, want 1", n)
+	}
+}
+
+func TestUniformLocationCache(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	s.Use()
+	rec.Reset()
+	u := &tint{[4]float32{1, 1, 1, 1}}
+	for i := 0; i < 3; i++ {
+		if err := s.AssignUniforms(u); err != nil {
+			t.Fatal(err)
+		}
+		if s.HasUniform("Missing") {
+			t.Error("got a location for an undeclared uniform")
+		}
+	}
+	// each name is looked up once, including the unknown one
+	var queried []interface{}
+	for _, c := range rec.Ops("GetUniformLocation") {
+		queried = append(queried, c.Args[1])
+	}
+	if got := fmt.Sprint(queried); got != "[Tint Missing]" {
+		t.Errorf("got queries of %v, want [Tint Missing]", got)
+	}
+	if n := len(rec.Ops("Uniform4fv")); n != 3 {
+		t.Errorf("assigned Tint %d times, want 3", n)
+	}
+}
+
+func TestTessellation(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	const (
+		vert gfx.VertexShader = `
+attribute vec3 Position;
+
+void main() {
+	gl_Position = vec4(Position, 1.0);
+}`
+		ctrl gfx.TessControlShader = `
+layout(vertices = 3) out;
+
+void main() {
+	gl_TessLevelOuter[0] = 4.0;
+	gl_out[gl_InvocationID].gl_Position = gl_in[gl_InvocationID].gl_Position;
+}`
+		eval gfx.TessEvalShader = `
+layout(triangles) in;
+
+void main() {
+	gl_Position = gl_in[0].gl_Position * gl_TessCoord.x;
+}`
+	)
+	rec.Reset()
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, vert, ctrl, eval, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	var stages []interface{}
+	for _, c := range rec.Ops("CreateShader") {
+		stages = append(stages, c.Args[0])
+	}
+	if got := fmt.Sprint(stages); got != "[VERTEX_SHADER TESS_CONTROL_SHADER TESS_EVALUATION_SHADER FRAGMENT_SHADER]" {
+		t.Errorf("got stages %v", got)
+	}
+
+	geom := quad(t)
+	defer geom.Delete()
+	s.Use()
+	if err := s.SetGeometry(geom); err != nil {
+		t.Fatal(err)
+	}
+	rec.Reset()
+	s.Draw()
+	s.SetPatchVertices(6)
+	s.Draw()
+	var got []string
+	for _, c := range rec.Ops("PatchParameteri", "DrawElements") {
+		got = append(got, c.String())
+	}
+	// each triangle is a patch by default
+	want := fmt.Sprint([]string{
+		"PatchParameteri(PATCH_VERTICES, 3)", "DrawElements(PATCHES, 6, UNSIGNED_SHORT, 0)",
+		"PatchParameteri(PATCH_VERTICES, 6)", "DrawElements(PATCHES, 6, UNSIGNED_SHORT, 0)",
+	})
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDrawArrays(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	b := geometry.NewBuilder(gfx.VertexPosition)
+	for i := 0; i < 5; i++ {
+		b.Position(float32(i), 0, 0)
+	}
+	points, err := gfx.NewGeometry(b, gfx.StaticDraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer points.Delete()
+	points.Primitive = gfx.Points
+
+	s.Use()
+	if err := s.SetGeometry(points); err != nil {
+		t.Fatal(err)
+	}
+	rec.Reset()
+	s.Draw()
+	s.DrawRange(2, 0)
+	s.DrawInstanced(3)
+	// 0x0000 is POINTS
+	want := fmt.Sprint([]string{
+		"DrawArrays(0x0000, 0, 5)",
+		"DrawArrays(0x0000, 2, 3)",
+		"DrawArraysInstanced(0x0000, 0, 5, 3)",
+	})
+	var got []string
+	for _, c := range rec.Draws() {
+		got = append(got, c.String())
+	}
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if ops := rec.Ops("DrawElements", "BindBuffer"); len(ops) != 0 {
+		t.Errorf("got %v drawing without indices", ops)
+	}
+}
+
+func TestLayoutGeometry(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	// Color is not among the default attributes, which name it VertexColor
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, colorVertexShader, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	var geoms []*gfx.Geometry
+	for _, vf := range []gfx.VertexFormat{gfx.VertexPosition | gfx.VertexNormal, gfx.VertexPosition} {
+		b := geometry.NewBuilder(vf)
+		b.Position(0, 0, 0)
+		if vf&gfx.VertexNormal != 0 {
+			b.Normal(0, 0, 1)
+		}
+		geom, err := gfx.NewGeometry(b, gfx.StaticDraw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer geom.Delete()
+		geoms = append(geoms, geom)
+	}
+
+	rec.Reset()
+	want := "gfx: geometry vertex format Position|Normal does not match shader format Position; unmapped Normal"
+	if _, err := gfx.LayoutGeometry(s, geoms[0]); err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+	if len(rec.Calls) != 0 {
+		t.Errorf("got %v for a mismatched geometry, want no calls", rec.Calls)
+	}
+
+	// debug mode logs the attribute left without data
+	var logged strings.Builder
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+	log.SetFlags(0)
+	defer log.SetFlags(log.LstdFlags)
+	gfx.SetDebug(true)
+	layout, err := gfx.LayoutGeometry(s, geoms[1])
+	gfx.SetDebug(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer layout.Delete()
+	if want := "gfx: shader attribute \"Color\" is not in the vertex format\n"; logged.String() != want {
+		t.Errorf("logged %q, want %q", logged.String(), want)
+	}
+	if n := len(rec.Ops("GenVertexArray")); n != 1 {
+		t.Errorf("created %d vertex arrays, want 1", n)
+	}
+}
+
+func TestNoVertexArrays(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+	rec.Version = "2.1"
+	gfx.Init()
+
+	attrs := gfx.VertexAttributes{gfx.VertexPosition: "Position", gfx.VertexColor: "Color"}
+	colored, err := gfx.BuildShader(attrs, colorVertexShader, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer colored.Delete()
+	plain, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, tintShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Delete()
+	b := geometry.NewBuilder(gfx.VertexPosition | gfx.VertexColor)
+	b.Position(0, 0, 0).Color(255, 0, 0, 255)
+	b.Position(1, 0, 0).Color(0, 255, 0, 255)
+	b.Position(0, 1, 0).Color(0, 0, 255, 255)
+	b.Indices(0, 1, 2)
+	geom, err := gfx.NewGeometry(b, gfx.StaticDraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geom.Delete()
+	pos := quad(t)
+	defer pos.Delete()
+
+	rec.Reset()
+	colored.Use()
+	if err := colored.SetGeometry(geom); err != nil {
+		t.Fatal(err)
+	}
+	colored.Draw()
+	plain.Use()
+	if err := plain.SetGeometry(pos); err != nil {
+		t.Fatal(err)
+	}
+	plain.Draw()
+	if calls := rec.Ops("GenVertexArray", "BindVertexArray"); len(calls) != 0 {
+		t.Errorf("got %v without vertex array objects", calls)
+	}
+	// the pointers are set each time, and the color array is disabled when
+	// the next geometry has no colors
+	var got []string
+	for _, c := range rec.Ops("BindBuffer", "VertexAttribPointer", "EnableVertexAttribArray", "DisableVertexAttribArray", "DrawElements") {
+		if c.Op == "BindBuffer" {
+			// leave out the buffer names
+			got = append(got, fmt.Sprint("BindBuffer(", c.Args[0], ")"))
+		} else {
+			got = append(got, c.String())
+		}
+	}
+	want := fmt.Sprint([]string{
+		"BindBuffer(ARRAY_BUFFER)",
+		"VertexAttribPointer(0, 3, FLOAT, false, 16, 0)",
+		"EnableVertexAttribArray(0)",
+		"VertexAttribPointer(1, 4, UNSIGNED_BYTE, true, 16, 12)",
+		"EnableVertexAttribArray(1)",
+		"BindBuffer(ELEMENT_ARRAY_BUFFER)",
+		"DrawElements(TRIANGLES, 3, UNSIGNED_SHORT, 0)",
+		"BindBuffer(ARRAY_BUFFER)",
+		"VertexAttribPointer(0, 3, FLOAT, false, 12, 0)",
+		"EnableVertexAttribArray(0)",
+		"BindBuffer(ELEMENT_ARRAY_BUFFER)",
+		"DisableVertexAttribArray(1)",
+		"DrawElements(TRIANGLES, 6, UNSIGNED_SHORT, 0)",
+	})
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v,\nwant %v", got, want)
+	}
+}
@@ -0,0 +1,88 @@
+package gfx
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"j4k.co/gfx/internal/gl"
+	"os"
+	"path/filepath"
+)
+
+// ShaderCacheDir, if not empty, is a directory in which BuildShader saves
+// the binaries of the programs it links, and loads them from to skip
+// compiling the same sources again. Binaries are keyed by the preprocessed
+// sources and the driver, so a driver update invalidates them. The
+// directory must exist; failing to read or write it only disables caching,
+// as does a context without program binaries, which need OpenGL 4.1,
+// ARB_get_program_binary or OpenGL ES 3.0.
+var ShaderCacheDir string
+
+// shaderCacheKey returns the file name of the binary of the program built
+// from srcs, whose preprocessed text is in texts, capturing varyings.
+func shaderCacheKey(srcs []ShaderSource, texts []string, varyings []string) string {
+	h := sha256.New()
+	for _, name := range []gl.GLenum{gl.VENDOR, gl.RENDERER, gl.VERSION} {
+		h.Write([]byte(gl.GetString(name)))
+		h.Write([]byte{0})
+	}
+	for i, src := range srcs {
+		var typ [4]byte
+		binary.LittleEndian.PutUint32(typ[:], uint32(src.typ()))
+		h.Write(typ[:])
+		h.Write([]byte(texts[i]))
+		h.Write([]byte{0})
+	}
+	for _, name := range varyings {
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)) + ".bin"
+}
+
+// loadProgramBinary loads the cached binary named key into prog, and
+// reports whether prog is then linked. A binary the driver rejects is
+// removed.
+func loadProgramBinary(prog gl.Program, key string) bool {
+	file := filepath.Join(ShaderCacheDir, key)
+	data, err := os.ReadFile(file)
+	if err != nil || len(data) <= 4 {
+		return false
+	}
+	format := gl.GLenum(binary.LittleEndian.Uint32(data))
+	gl.ProgramBinary(prog, format, data[4:], len(data)-4)
+	if prog.Get(gl.LINK_STATUS) == 0 {
+		os.Remove(file)
+		return false
+	}
+	return true
+}
+
+// saveProgramBinary writes the binary of the linked prog to the cache as
+// key, prefixed with its format.
+func saveProgramBinary(prog gl.Program, key string) {
+	n := prog.Get(gl.PROGRAM_BINARY_LENGTH)
+	if n == 0 {
+		return
+	}
+	data := make([]byte, 4+n)
+	var format gl.GLenum
+	gl.GetProgramBinary(prog, n, nil, &format, data[4:])
+	binary.LittleEndian.PutUint32(data, uint32(format))
+	// write to a temporary file first, so that a binary is never loaded
+	// half written
+	f, err := os.CreateTemp(ShaderCacheDir, key+".*")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(ShaderCacheDir, key))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
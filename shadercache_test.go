@@ -0,0 +1,35 @@
+package gfx_test
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+func TestShaderCacheCaps(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+	defer func(dir string) { gfx.ShaderCacheDir = dir }(gfx.ShaderCacheDir)
+	gfx.ShaderCacheDir = t.TempDir()
+
+	for _, ext := range []string{"", "GL_ARB_get_program_binary"} {
+		rec.Extensions = nil
+		if ext != "" {
+			rec.Extensions = []string{ext}
+		}
+		gfx.Init()
+		rec.Reset()
+		s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, tintShader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Delete()
+		hints := rec.Ops("ProgramParameteri")
+		if ext == "" && len(hints) != 0 {
+			t.Errorf("got %v without program binaries", hints)
+		}
+		if ext != "" && len(hints) != 1 {
+			t.Errorf("got %v with %s, want the retrievable hint", hints, ext)
+		}
+	}
+}
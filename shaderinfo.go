@@ -0,0 +1,276 @@
+package gfx
+
+import (
+	"fmt"
+	"j4k.co/gfx/internal/gl"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ShaderVariable describes an active uniform or vertex attribute of a
+// shader program.
+type ShaderVariable struct {
+	Name     string    // without the "[0]" suffix of arrays
+	Type     gl.GLenum // such as gl.FLOAT_VEC3 or gl.SAMPLER_2D
+	Size     int       // the length of an array, or 1
+	Location int       // -1 for uniforms in uniform blocks
+}
+
+// GLSLType returns the GLSL name of the variable's type, such as "vec3", or
+// "" for types not listed in glslTypes.
+func (v ShaderVariable) GLSLType() string {
+	return glslTypes[v.Type]
+}
+
+var glslTypes = map[gl.GLenum]string{
+	gl.FLOAT:             "float",
+	gl.FLOAT_VEC2:        "vec2",
+	gl.FLOAT_VEC3:        "vec3",
+	gl.FLOAT_VEC4:        "vec4",
+	gl.DOUBLE:            "double",
+	gl.INT:               "int",
+	gl.INT_VEC2:          "ivec2",
+	gl.INT_VEC3:          "ivec3",
+	gl.INT_VEC4:          "ivec4",
+	gl.UNSIGNED_INT:      "uint",
+	gl.UNSIGNED_INT_VEC2: "uvec2",
+	gl.UNSIGNED_INT_VEC3: "uvec3",
+	gl.UNSIGNED_INT_VEC4: "uvec4",
+	gl.BOOL:              "bool",
+	gl.BOOL_VEC2:         "bvec2",
+	gl.BOOL_VEC3:         "bvec3",
+	gl.BOOL_VEC4:         "bvec4",
+	gl.FLOAT_MAT2:        "mat2",
+	gl.FLOAT_MAT3:        "mat3",
+	gl.FLOAT_MAT4:        "mat4",
+	gl.FLOAT_MAT2x3:      "mat2x3",
+	gl.FLOAT_MAT2x4:      "mat2x4",
+	gl.FLOAT_MAT3x2:      "mat3x2",
+	gl.FLOAT_MAT3x4:      "mat3x4",
+	gl.FLOAT_MAT4x2:      "mat4x2",
+	gl.FLOAT_MAT4x3:      "mat4x3",
+	gl.SAMPLER_2D:        "sampler2D",
+	gl.SAMPLER_3D:        "sampler3D",
+	gl.SAMPLER_CUBE:      "samplerCube",
+	gl.SAMPLER_2D_SHADOW: "sampler2DShadow",
+	gl.SAMPLER_2D_ARRAY:  "sampler2DArray",
+}
+
+// Uniforms lists the active uniforms of the program, sorted by name.
+// Uniforms that are declared but unused may be optimized away by the
+// driver.
+func (s *Shader) Uniforms() []ShaderVariable {
+	n := s.prog.Get(gl.ACTIVE_UNIFORMS)
+	vars := make([]ShaderVariable, n)
+	for i := range vars {
+		size, typ, name := s.prog.GetActiveUniform(i)
+		name = strings.TrimSuffix(name, "[0]")
+		vars[i] = ShaderVariable{
+			Name:     name,
+			Type:     typ,
+			Size:     size,
+			Location: int(s.uniformLocation(name)),
+		}
+	}
+	sort.Stable(variablesByName(vars))
+	checkError("Shader.Uniforms")
+	return vars
+}
+
+// Attributes lists the active vertex attributes of the program, sorted by
+// name.
+func (s *Shader) Attributes() []ShaderVariable {
+	n := s.prog.Get(gl.ACTIVE_ATTRIBUTES)
+	vars := make([]ShaderVariable, n)
+	for i := range vars {
+		size, typ, name := s.prog.GetActiveAttrib(i)
+		name = strings.TrimSuffix(name, "[0]")
+		vars[i] = ShaderVariable{
+			Name:     name,
+			Type:     typ,
+			Size:     size,
+			Location: int(s.prog.GetAttribLocation(name)),
+		}
+	}
+	sort.Stable(variablesByName(vars))
+	checkError("Shader.Attributes")
+	return vars
+}
+
+type variablesByName []ShaderVariable
+
+func (v variablesByName) Len() int           { return len(v) }
+func (v variablesByName) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
+func (v variablesByName) Less(i, j int) bool { return v[i].Name < v[j].Name }
+
+// checkUniforms checks the "uniform" fields of the struct type typ against
+// the active uniforms of the program, caching the result.
+func (s *Shader) checkUniforms(typ reflect.Type) error {
+	if err, ok := s.checked[typ]; ok {
+		return err
+	}
+	active := make(map[string]ShaderVariable)
+	for _, v := range s.Uniforms() {
+		active[v.Name] = v
+	}
+	var problems []string
+	checkUniformFields(typ, active, &problems)
+	var err error
+	if len(problems) > 0 {
+		err = fmt.Errorf("gfx: uniforms of %v do not match the shader: %s", typ, strings.Join(problems, "; "))
+	}
+	if s.checked == nil {
+		s.checked = make(map[reflect.Type]error)
+	}
+	s.checked[typ] = err
+	return err
+}
+
+func checkUniformFields(typ reflect.Type, active map[string]ShaderVariable, problems *[]string) {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		if f.Anonymous {
+			switch {
+			case f.Type.Kind() == reflect.Struct:
+				checkUniformFields(f.Type, active, problems)
+			case f.Type.Kind() == reflect.Ptr && f.Type.Elem().Kind() == reflect.Struct:
+				checkUniformFields(f.Type.Elem(), active, problems)
+			}
+		}
+		name := f.Tag.Get("uniform")
+		if name == "" {
+			continue
+		}
+		v, ok := active[name]
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("field %s: unknown uniform variable '%s'", f.Name, name))
+			continue
+		}
+		types := uniformTypes(f.Type)
+		if types == nil {
+			continue
+		}
+		match := false
+		for _, t := range types {
+			match = match || t == v.Type
+		}
+		if !match {
+			*problems = append(*problems, fmt.Sprintf("field %s: %v cannot be assigned to %s '%s'", f.Name, f.Type, v.GLSLType(), name))
+		}
+	}
+}
+
+// uniformTypes returns the types of uniform that values of the Go type typ
+// can be assigned to, or nil if unknown.
+func uniformTypes(typ reflect.Type) []gl.GLenum {
+	switch typ {
+	case reflect.TypeOf((*Sampler2D)(nil)):
+		return []gl.GLenum{gl.SAMPLER_2D, gl.SAMPLER_2D_SHADOW}
+	case reflect.TypeOf((*SamplerCube)(nil)):
+		return []gl.GLenum{gl.SAMPLER_CUBE}
+	}
+	if t, ok := dataTypes(typ); ok {
+		return []gl.GLenum{t}
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Slice:
+		// uniform arrays have the type of their elements
+		if e := typ.Elem(); e.Kind() == reflect.Array && (e.Len() == 4 || e.Len() == 16) {
+			return uniformTypes(e)
+		}
+	case reflect.Bool:
+		return []gl.GLenum{gl.BOOL}
+	case reflect.Int, reflect.Int32:
+		return []gl.GLenum{gl.INT, gl.BOOL, gl.SAMPLER_2D, gl.SAMPLER_3D, gl.SAMPLER_CUBE, gl.SAMPLER_2D_SHADOW, gl.SAMPLER_2D_ARRAY}
+	case reflect.Uint, reflect.Uint32:
+		return []gl.GLenum{gl.UNSIGNED_INT, gl.BOOL}
+	case reflect.Float32, reflect.Float64:
+		return []gl.GLenum{gl.FLOAT}
+	case reflect.Array:
+		n := typ.Len()
+		switch typ.Elem().Kind() {
+		case reflect.Int32:
+			return vectorTypes(n, gl.INT_VEC2, gl.INT_VEC3, gl.INT_VEC4, gl.BOOL_VEC2, gl.BOOL_VEC3, gl.BOOL_VEC4)
+		case reflect.Uint32:
+			return vectorTypes(n, gl.UNSIGNED_INT_VEC2, gl.UNSIGNED_INT_VEC3, gl.UNSIGNED_INT_VEC4)
+		case reflect.Float32, reflect.Float64:
+			switch n {
+			case 9:
+				return []gl.GLenum{gl.FLOAT_MAT3}
+			case 16:
+				return []gl.GLenum{gl.FLOAT_MAT4}
+			}
+			return vectorTypes(n, gl.FLOAT_VEC2, gl.FLOAT_VEC3, gl.FLOAT_VEC4)
+		case reflect.Array:
+			if typ.Elem().Elem().Kind() != reflect.Float32 {
+				return nil
+			}
+			if t, ok := matrixTypes[[2]int{n, typ.Elem().Len()}]; ok {
+				return []gl.GLenum{t}
+			}
+		}
+	}
+	return nil
+}
+
+// vectorTypes returns the types of types, in groups of vec2, vec3, and vec4,
+// for vectors of n components.
+func vectorTypes(n int, types ...gl.GLenum) []gl.GLenum {
+	if n < 2 || n > 4 {
+		return nil
+	}
+	var vt []gl.GLenum
+	for i := n - 2; i < len(types); i += 3 {
+		vt = append(vt, types[i])
+	}
+	return vt
+}
+
+// matrixTypes maps columns and rows to matrix types.
+var matrixTypes = map[[2]int]gl.GLenum{
+	{2, 2}: gl.FLOAT_MAT2,
+	{3, 3}: gl.FLOAT_MAT3,
+	{4, 4}: gl.FLOAT_MAT4,
+	{2, 3}: gl.FLOAT_MAT2x3,
+	{3, 2}: gl.FLOAT_MAT3x2,
+	{2, 4}: gl.FLOAT_MAT2x4,
+	{4, 2}: gl.FLOAT_MAT4x2,
+	{3, 4}: gl.FLOAT_MAT3x4,
+	{4, 3}: gl.FLOAT_MAT4x3,
+}
+
+var (
+	vec2Type = reflect.TypeOf((*Vec2Data)(nil)).Elem()
+	vec3Type = reflect.TypeOf((*Vec3Data)(nil)).Elem()
+	vec4Type = reflect.TypeOf((*Vec4Data)(nil)).Elem()
+	mat3Type = reflect.TypeOf((*Mat3Data)(nil)).Elem()
+	mat4Type = reflect.TypeOf((*Mat4Data)(nil)).Elem()
+)
+
+// dataTypes returns the uniform type of typ if it or a pointer to it
+// implements one of the data interfaces.
+func dataTypes(typ reflect.Type) (gl.GLenum, bool) {
+	if typ.Kind() != reflect.Ptr {
+		typ = reflect.PtrTo(typ)
+	}
+	switch {
+	case typ.Implements(vec2Type):
+		return gl.FLOAT_VEC2, true
+	case typ.Implements(vec3Type):
+		return gl.FLOAT_VEC3, true
+	case typ.Implements(vec4Type):
+		return gl.FLOAT_VEC4, true
+	case typ.Implements(mat3Type):
+		return gl.FLOAT_MAT3, true
+	case typ.Implements(mat4Type):
+		return gl.FLOAT_MAT4, true
+	}
+	return 0, false
+}
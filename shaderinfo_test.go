@@ -0,0 +1,109 @@
+package gfx_test
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+const samplersShader gfx.FragmentShader = `
+uniform sampler2D Texture;
+uniform samplerCube Cube;
+uniform int Unit;
+uniform bool B;
+
+varying vec2 TexCoord;
+
+void main() {
+	gl_FragColor = texture2D(Texture, TexCoord);
+}`
+
+type strictBase struct {
+	Tex *gfx.Sampler2D `uniform:"Texture"`
+}
+
+type strictCube struct {
+	Cube *gfx.SamplerCube `uniform:"Cube"`
+}
+
+// strictGood assigns each uniform of samplersShader a type it accepts.
+type strictGood struct {
+	strictBase
+	*strictCube
+	Unit int32 `uniform:"Unit"`
+	B    uint  `uniform:"B"`
+}
+
+type strictTypo struct {
+	Tex *gfx.Sampler2D `uniform:"Textur"`
+}
+
+type strictMismatch struct {
+	strictBase
+	Unit float32          `uniform:"Unit"`
+	Tex  *gfx.SamplerCube `uniform:"Texture"`
+	B    [2]float32       `uniform:"B"`
+}
+
+type strictEmbedded struct {
+	*strictTypo
+	Unit int `uniform:"Unit"`
+}
+
+func TestStrictUniforms(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, samplersShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	tex, err := gfx.NewSampler2D(4, 4, gfx.PixelRGBA8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tex.Delete()
+	cube, err := gfx.NewSamplerCube(4, gfx.PixelRGBA8, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cube.Delete()
+	s.Use()
+
+	// without strict mode, a mismatched type is assigned all the same
+	if err := s.AssignUniforms(&strictMismatch{strictBase{tex}, 1, cube, [2]float32{}}); err != nil {
+		t.Errorf("got %v without strict mode", err)
+	}
+
+	s.SetStrictUniforms(true)
+	if err := s.AssignUniforms(&strictGood{strictBase{tex}, &strictCube{cube}, 1, 1}); err != nil {
+		t.Errorf("got %v assigning accepted types", err)
+	}
+	for _, bad := range []struct {
+		data interface{}
+		err  string
+	}{
+		{&strictTypo{tex}, "gfx: uniforms of gfx_test.strictTypo do not match the shader: " +
+			"field Tex: unknown uniform variable 'Textur'"},
+		{&strictMismatch{strictBase{tex}, 1, cube, [2]float32{}}, "gfx: uniforms of gfx_test.strictMismatch do not match the shader: " +
+			"field Unit: float32 cannot be assigned to int 'Unit'; " +
+			"field Tex: *gfx.SamplerCube cannot be assigned to sampler2D 'Texture'; " +
+			"field B: [2]float32 cannot be assigned to bool 'B'"},
+		// fields of embedded structs are checked even when nil
+		{&strictEmbedded{Unit: 1}, "gfx: uniforms of gfx_test.strictEmbedded do not match the shader: " +
+			"field Tex: unknown uniform variable 'Textur'"},
+	} {
+		// the check is cached per type, so the second time is the same
+		for i := 0; i < 2; i++ {
+			rec.Reset()
+			err := s.AssignUniforms(bad.data)
+			if err == nil || err.Error() != bad.err {
+				t.Errorf("got error %v,\nwant %s", err, bad.err)
+			}
+			if len(rec.Calls) != 0 {
+				t.Errorf("%T: assigned %v before failing", bad.data, rec.Calls)
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package gfx
+
+import (
+	"j4k.co/gfx/internal/gl"
+)
+
+// DepthFragmentShader writes nothing but depth, for building depth-only
+// variants of shaders, such as for shadow casters.
+const DepthFragmentShader FragmentShader = `
+void main() {
+}`
+
+// ShadowMap is a depth-only render target for shadow mapping. Shadow
+// casters are drawn into it from the light's point of view, and lit shaders
+// then sample it through a sampler2DShadow, using the coordinates given by
+// ShadowMatrix.
+//
+// Typical use is to call Begin, draw the shadow casters with the light's
+// view and projection, then call End and bind Texture to the lit shaders,
+// or to add the same steps to a RenderGraph with AddPass.
+type ShadowMap struct {
+	fb    *Framebuffer
+	depth *Sampler2D
+
+	// SlopeBias and ConstantBias offset the depth of drawn polygons away
+	// from the light, to keep surfaces from shadowing themselves. They are
+	// the factor and units of glPolygonOffset.
+	SlopeBias, ConstantBias float32
+}
+
+// NewShadowMap allocates a size by size shadow map. Its texture compares
+// with CompareLessEqual and is linearly filtered.
+func NewShadowMap(size int) (*ShadowMap, error) {
+	depth, err := newSampler2D(nil, size, size, PixelDepth24, &SamplerOptions{
+		WrapS:       WrapClamp,
+		WrapT:       WrapClamp,
+		Compare:     true,
+		CompareFunc: CompareLessEqual,
+	})
+	if err != nil {
+		return nil, err
+	}
+	fb, err := NewFramebuffer(depth)
+	if err != nil {
+		depth.Delete()
+		return nil, err
+	}
+	return &ShadowMap{
+		fb:           fb,
+		depth:        depth,
+		SlopeBias:    2,
+		ConstantBias: 4,
+	}, nil
+}
+
+// Delete frees the shadow map and its texture.
+func (m *ShadowMap) Delete() {
+	m.fb.Delete()
+	m.depth.Delete()
+}
+
+// Texture returns the depth texture.
+func (m *ShadowMap) Texture() *Sampler2D {
+	return m.depth
+}
+
+// Size returns the width and height of the shadow map in pixels.
+func (m *ShadowMap) Size() int {
+	w, _ := m.fb.Size()
+	return w
+}
+
+// Begin binds and clears the shadow map and enables the depth bias. It
+// applies the zero State so that depth is tested and written.
+func (m *ShadowMap) Begin() {
+	m.fb.Bind()
+	m.begin()
+}
+
+func (m *ShadowMap) begin() {
+	var s State
+	s.Apply()
+	gl.Clear(gl.DEPTH_BUFFER_BIT)
+	gl.Enable(gl.POLYGON_OFFSET_FILL)
+	gl.PolygonOffset(m.SlopeBias, m.ConstantBias)
+}
+
+// End disables the depth bias and restores the default framebuffer. The
+// caller must set the viewport again.
+func (m *ShadowMap) End() {
+	gl.Disable(gl.POLYGON_OFFSET_FILL)
+	gl.Framebuffer(0).Bind()
+	checkError("ShadowMap")
+}
+
+// AddPass adds a pass to g that draws the shadow casters into the shadow
+// map with draw, as between Begin and End. It returns the pass and the
+// shadow map's target, for the lit passes to Read.
+func (m *ShadowMap) AddPass(g *RenderGraph, draw func(*PassContext)) (*RenderPass, RenderTarget) {
+	depth := g.adopt("shadow map", m.fb)[0]
+	p := g.AddPass("shadow map", func(c *PassContext) {
+		m.begin()
+		draw(c)
+		gl.Disable(gl.POLYGON_OFFSET_FILL)
+		checkError("ShadowMap")
+	}).Write(depth)
+	return p, depth
+}
+
+// ShadowMatrix maps world positions to shadow map coordinates, given the
+// light's view-projection matrix. The x and y of the result are the texture
+// coordinates and z the reference depth, ready for shadow2DProj or, after
+// dividing by w, shadow2D.
+func ShadowMatrix(viewProjection *[16]float32) [16]float32 {
+	// scale and translate clip space [-1, 1] to [0, 1]
+	var m [16]float32
+	for col := 0; col < 4; col++ {
+		c := viewProjection[col*4 : col*4+4]
+		for row := 0; row < 3; row++ {
+			m[col*4+row] = 0.5*c[row] + 0.5*c[3]
+		}
+		m[col*4+3] = c[3]
+	}
+	return m
+}
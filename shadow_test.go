@@ -0,0 +1,77 @@
+package gfx_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+func TestShadowMap(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	m, err := gfx.NewShadowMap(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Delete()
+	// parameter values are recorded as ints: 0x884E is COMPARE_REF_TO_TEXTURE
+	// and 0x0203 is LEQUAL
+	params := map[string]interface{}{}
+	for _, c := range rec.Ops("TexParameteri") {
+		params[fmt.Sprint(c.Args[1])] = c.Args[2]
+	}
+	if params["TEXTURE_COMPARE_MODE"] != 0x884E || params["TEXTURE_COMPARE_FUNC"] != 0x0203 {
+		t.Errorf("got texture parameters %v", params)
+	}
+	if img := rec.Ops("TexImage2D"); len(img) != 1 || fmt.Sprint(img[0].Args[2:5]) != "[DEPTH_COMPONENT24 256 256]" {
+		t.Errorf("got %v, want a 256x256 depth texture", img)
+	}
+	if att := rec.Ops("FramebufferTexture2D"); len(att) != 1 || fmt.Sprint(att[0].Args[1]) != "DEPTH_ATTACHMENT" {
+		t.Errorf("got %v, want the texture as the depth attachment", att)
+	}
+
+	rec.Reset()
+	m.Begin()
+	m.End()
+	var got []string
+	for _, c := range rec.Ops("BindFramebuffer", "Clear", "Enable", "Disable", "PolygonOffset") {
+		if c.Op != "Enable" && c.Op != "Disable" || fmt.Sprint(c.Args[0]) == "POLYGON_OFFSET_FILL" {
+			got = append(got, c.String())
+		}
+	}
+	// 256 is DEPTH_BUFFER_BIT
+	want := fmt.Sprint([]string{
+		fmt.Sprint("BindFramebuffer(FRAMEBUFFER, ", rec.Ops("BindFramebuffer")[0].Args[1], ")"),
+		"Clear(256)",
+		"Enable(POLYGON_OFFSET_FILL)",
+		"PolygonOffset(2, 4)",
+		"Disable(POLYGON_OFFSET_FILL)",
+		"BindFramebuffer(FRAMEBUFFER, 0)",
+	})
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v,\nwant %v", got, want)
+	}
+
+	// the texture is accepted by shadow samplers in strict mode
+	const shadowShader gfx.FragmentShader = `
+uniform sampler2DShadow Shadow;
+
+void main() {
+	gl_FragColor = vec4(shadow2D(Shadow, vec3(0.5)).r);
+}`
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, shadowShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	s.SetStrictUniforms(true)
+	s.Use()
+	u := &struct {
+		Shadow *gfx.Sampler2D `uniform:"Shadow"`
+	}{m.Texture()}
+	if err := s.AssignUniforms(u); err != nil {
+		t.Error(err)
+	}
+}
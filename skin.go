@@ -0,0 +1,121 @@
+package gfx
+
+import (
+	"fmt"
+	"j4k.co/gfx/internal/gl"
+)
+
+// MaxBones is the most joints a BonePalette uploads to the Bones block.
+const MaxBones = 128
+
+// BoneBinding is the uniform buffer binding point of the bone palette given
+// to shaders declaring the Bones block:
+//
+//	layout(std140) uniform Bones {
+//		mat4 BoneMatrices[128];
+//	};
+//
+// Shaders without it may declare BoneMatrices as a plain uniform array of
+// up to MaxBones matrices instead, which is more limited in size.
+const BoneBinding = 1
+
+// SkinAttributes maps the attributes of SkinVertexShader.
+var SkinAttributes = VertexAttributes{
+	VertexPosition:    "Position",
+	VertexNormal:      "Normal",
+	VertexTexcoord:    "TexCoord",
+	VertexBoneIndices: "BoneIndices",
+	VertexBoneWeights: "BoneWeights",
+}
+
+// SkinVertexShader deforms vertices by the bone palette with linear blend
+// skinning, and transforms them by the WorldM and WorldViewProjectionM
+// uniforms, as the scenes renderer assigns them. It passes the world space
+// normal and the texture coordinates to the fragment shader in the
+// WorldNormal and TexCoord0 varyings. It is built with SkinAttributes and
+// requires GLSL 1.50.
+const SkinVertexShader VertexShader = `#version 150
+
+in vec3 Position;
+in vec3 Normal;
+in vec2 TexCoord;
+in uvec4 BoneIndices;
+in vec4 BoneWeights;
+
+layout(std140) uniform Bones {
+	mat4 BoneMatrices[128];
+};
+
+uniform mat4 WorldM;
+uniform mat4 WorldViewProjectionM;
+
+out vec3 WorldNormal;
+out vec2 TexCoord0;
+
+void main() {
+	mat4 skin = BoneWeights.x * BoneMatrices[BoneIndices.x] +
+		BoneWeights.y * BoneMatrices[BoneIndices.y] +
+		BoneWeights.z * BoneMatrices[BoneIndices.z] +
+		BoneWeights.w * BoneMatrices[BoneIndices.w];
+	vec4 pos = skin * vec4(Position, 1.0);
+	WorldNormal = mat3(WorldM) * mat3(skin) * Normal;
+	TexCoord0 = TexCoord;
+	gl_Position = WorldViewProjectionM * pos;
+}`
+
+// BonePalette holds the matrices of the joints of a skeleton, which move
+// skinned vertices from the space of the mesh at rest to their animated
+// place in the same space. Joint i deforms the vertices whose
+// VertexBoneIndices refer to i.
+type BonePalette struct {
+	Matrices [][16]float32
+
+	block *UniformBlock
+	data  bonesBlock
+}
+
+type bonesBlock struct {
+	Matrices [MaxBones][16]float32 `uniform:"BoneMatrices"`
+}
+
+// NewBonePalette returns a palette of n identity matrices.
+func NewBonePalette(n int) *BonePalette {
+	p := &BonePalette{Matrices: make([][16]float32, n)}
+	for i := range p.Matrices {
+		p.Matrices[i] = Ident4()
+	}
+	return p
+}
+
+// Apply uploads the matrices for s, which must be in use, to the Bones
+// block if s declares it, binding the block to BoneBinding, or otherwise to
+// the BoneMatrices uniform array. It is called before each draw, as the
+// palette changes from frame to frame.
+func (p *BonePalette) Apply(s *Shader) error {
+	if len(p.Matrices) > MaxBones {
+		return fmt.Errorf("gfx: %d bones exceed MaxBones", len(p.Matrices))
+	}
+	if s.prog.GetUniformBlockIndex("Bones") == gl.INVALID_INDEX {
+		return s.SetUniform("BoneMatrices", p.Matrices)
+	}
+	copy(p.data.Matrices[:], p.Matrices)
+	var err error
+	if p.block == nil {
+		p.block, err = NewUniformBlock(BoneBinding, &p.data)
+	} else {
+		p.block.buf.BindBufferBase(gl.UNIFORM_BUFFER, uint(BoneBinding))
+		err = p.block.Update(&p.data)
+	}
+	if err != nil {
+		return err
+	}
+	return s.SetUniformBlock("Bones", p.block)
+}
+
+// Delete frees the uniform buffer of the palette, if it has one.
+func (p *BonePalette) Delete() {
+	if p.block != nil {
+		p.block.Delete()
+		p.block = nil
+	}
+}
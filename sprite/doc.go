@@ -0,0 +1,6 @@
+/*
+Package sprite draws batches of textured 2D quads. Sprites sharing a
+texture are accumulated into a streamed vertex buffer and drawn together
+with an orthographic projection.
+*/
+package sprite
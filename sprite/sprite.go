@@ -0,0 +1,234 @@
+package sprite
+
+import (
+	"image/color"
+	"j4k.co/gfx"
+	"math"
+	"unsafe"
+)
+
+// BatchSize is the number of sprites a Batch holds before it flushes.
+const BatchSize = 2048
+
+// White draws textures untinted.
+var White = color.NRGBA{255, 255, 255, 255}
+
+// Sprite is a textured quad.
+type Sprite struct {
+	// X and Y position the origin of the sprite.
+	X, Y float32
+
+	Width, Height float32
+
+	// OriginX and OriginY give the point, relative to the top left corner
+	// of the sprite, that is placed at X, Y and rotated about.
+	OriginX, OriginY float32
+
+	// Rotation is in radians, clockwise on screen.
+	Rotation float32
+
+	// UV is the texture rectangle u0, v0, u1, v1, where u0, v0 maps to the
+	// top left corner. The zero value selects the whole texture.
+	UV [4]float32
+
+	// Color tints the texture. Use White for none.
+	Color color.NRGBA
+}
+
+// vertex matches VertexFormat.
+type vertex struct {
+	x, y, z float32
+	color   color.NRGBA
+	u, v    float32
+}
+
+// VertexFormat is the format of sprite vertices.
+const VertexFormat = gfx.VertexPosition | gfx.VertexColor | gfx.VertexTexcoord
+
+var attrs = gfx.VertexAttributes{
+	gfx.VertexPosition: "Position",
+	gfx.VertexColor:    "Color",
+	gfx.VertexTexcoord: "UV",
+}
+
+const vertexShader gfx.VertexShader = `
+uniform mat4 ProjectionM;
+
+attribute vec3 Position;
+attribute vec4 Color;
+attribute vec2 UV;
+
+varying vec2 uv;
+varying vec4 color;
+
+void main() {
+	uv = UV;
+	color = Color;
+	gl_Position = ProjectionM * vec4(Position, 1.0);
+}`
+
+const fragmentShader gfx.FragmentShader = `
+uniform sampler2D Texture;
+
+varying vec2 uv;
+varying vec4 color;
+
+void main() {
+	gl_FragColor = texture2D(Texture, uv) * color;
+}`
+
+type uniforms struct {
+	Projection [16]float32    `uniform:"ProjectionM"`
+	Texture    *gfx.Sampler2D `uniform:"Texture"`
+}
+
+// Batch accumulates sprites and draws them in as few draw calls as it can.
+// Sprites are drawn in the order they are added; the batch flushes whenever
+// the texture changes or it is full. Blending is left to the caller, who
+// typically enables alpha blending and disables depth testing.
+type Batch struct {
+	shader *gfx.Shader
+	geom   *gfx.Geometry
+	layout *gfx.GeometryLayout
+
+	verts    []vertex
+	uniforms uniforms
+}
+
+// emptyData creates the initially empty vertex buffer.
+type emptyData struct{}
+
+func (emptyData) VertexCount() int               { return 0 }
+func (emptyData) VertexFormat() gfx.VertexFormat { return VertexFormat }
+func (emptyData) CopyVertices(dest *gfx.VertexBuffer, usage gfx.Usage) error {
+	return dest.SetVertices(nil, usage)
+}
+
+// NewBatch builds the sprite shader and allocates a batch.
+func NewBatch() (*Batch, error) {
+	shader, err := gfx.BuildShader(attrs, vertexShader, fragmentShader)
+	if err != nil {
+		return nil, err
+	}
+	geom, err := gfx.NewGeometry(emptyData{}, gfx.StreamDraw)
+	if err != nil {
+		shader.Delete()
+		return nil, err
+	}
+	layout, err := gfx.LayoutGeometry(shader, geom)
+	if err != nil {
+		geom.Delete()
+		shader.Delete()
+		return nil, err
+	}
+	return &Batch{
+		shader: shader,
+		geom:   geom,
+		layout: layout,
+		verts:  make([]vertex, 0, BatchSize*6),
+	}, nil
+}
+
+// Delete frees the shader and buffers of the batch.
+func (b *Batch) Delete() {
+	b.layout.Delete()
+	b.geom.Delete()
+	b.shader.Delete()
+}
+
+// Begin starts a batch drawn to a viewport of the given size, with the
+// origin at the top left and y pointing down.
+func (b *Batch) Begin(width, height float32) error {
+	return b.SetProjection([16]float32{
+		2 / width, 0, 0, 0,
+		0, -2 / height, 0, 0,
+		0, 0, -1, 0,
+		-1, 1, 0, 1,
+	})
+}
+
+// SetProjection flushes the batch and sets the projection matrix of
+// subsequent sprites.
+func (b *Batch) SetProjection(m [16]float32) error {
+	if err := b.Flush(); err != nil {
+		return err
+	}
+	b.uniforms.Projection = m
+	return nil
+}
+
+// Add draws tex at x, y with its own size.
+func (b *Batch) Add(tex *gfx.Sampler2D, x, y float32) error {
+	w, h := tex.Size()
+	return b.AddSprite(tex, &Sprite{
+		X: x, Y: y,
+		Width: float32(w), Height: float32(h),
+		Color: White,
+	})
+}
+
+// AddSprite queues a sprite textured with tex.
+func (b *Batch) AddSprite(tex *gfx.Sampler2D, s *Sprite) error {
+	if tex != b.uniforms.Texture || len(b.verts) == cap(b.verts) {
+		if err := b.Flush(); err != nil {
+			return err
+		}
+		b.uniforms.Texture = tex
+	}
+	uv := s.UV
+	if uv == [4]float32{} {
+		uv = [4]float32{0, 0, 1, 1}
+	}
+	sin, cos := float32(0), float32(1)
+	if s.Rotation != 0 {
+		sin = float32(math.Sin(float64(s.Rotation)))
+		cos = float32(math.Cos(float64(s.Rotation)))
+	}
+	corner := func(x, y, u, v float32) vertex {
+		x, y = x-s.OriginX, y-s.OriginY
+		return vertex{
+			x:     s.X + x*cos - y*sin,
+			y:     s.Y + x*sin + y*cos,
+			color: s.Color,
+			u:     u,
+			v:     v,
+		}
+	}
+	tl := corner(0, 0, uv[0], uv[1])
+	tr := corner(s.Width, 0, uv[2], uv[1])
+	br := corner(s.Width, s.Height, uv[2], uv[3])
+	bl := corner(0, s.Height, uv[0], uv[3])
+	b.verts = append(b.verts, tl, bl, br, br, tr, tl)
+	return nil
+}
+
+// Flush draws the queued sprites.
+func (b *Batch) Flush() error {
+	if len(b.verts) == 0 {
+		return nil
+	}
+	size := len(b.verts) * int(unsafe.Sizeof(vertex{}))
+	src := unsafe.Slice((*byte)(unsafe.Pointer(&b.verts[0])), size)
+	offset, err := b.geom.VertexBuffer.StreamWrite(size, func(dest []byte) error {
+		copy(dest, src)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	b.shader.Use()
+	if err := b.shader.SetLayout(b.layout); err != nil {
+		return err
+	}
+	if err := b.shader.AssignUniforms(&b.uniforms); err != nil {
+		return err
+	}
+	b.shader.DrawRange(offset/VertexFormat.Stride(), len(b.verts))
+	b.verts = b.verts[:0]
+	return nil
+}
+
+// End draws any remaining sprites.
+func (b *Batch) End() error {
+	return b.Flush()
+}
@@ -0,0 +1,108 @@
+package sprite_test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"j4k.co/gfx/sprite"
+	"math"
+	"testing"
+)
+
+func newTexture(t *testing.T) *gfx.Sampler2D {
+	tex, err := gfx.NewSampler2D(16, 8, gfx.PixelRGBA8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tex.Delete)
+	return tex
+}
+
+func TestBatch(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	b, err := sprite.NewBatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Delete()
+	a, c := newTexture(t), newTexture(t)
+	if err := b.Begin(640, 480); err != nil {
+		t.Fatal(err)
+	}
+	rec.Reset()
+	for _, tex := range []*gfx.Sampler2D{a, a, c} {
+		if err := b.Add(tex, 10, 20); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the change of texture flushed the sprites drawn with a
+	if n := len(rec.Draws()); n != 1 {
+		t.Fatalf("got %d draws before End, want 1", n)
+	}
+	if err := b.End(); err != nil {
+		t.Fatal(err)
+	}
+	// each sprite is two triangles
+	var counts []interface{}
+	for _, c := range rec.Draws() {
+		counts = append(counts, c.Args[2])
+	}
+	if fmt.Sprint(counts) != "[12 6]" {
+		t.Errorf("got draws of %v vertices, want [12 6]", counts)
+	}
+
+	writes := rec.Ops("UnmapBuffer")
+	if len(writes) != 2 {
+		t.Fatalf("got writes %v, want one per flush", writes)
+	}
+	data := writes[1].Args[1].([]byte)
+	if len(data) != 6*sprite.VertexFormat.Stride() {
+		t.Fatalf("uploaded %d bytes, want 6 vertices", len(data))
+	}
+	pos := func(i int) [2]float32 {
+		v := data[i*sprite.VertexFormat.Stride():]
+		return [2]float32{
+			math.Float32frombits(binary.LittleEndian.Uint32(v)),
+			math.Float32frombits(binary.LittleEndian.Uint32(v[4:])),
+		}
+	}
+	// corners in the order top left, bottom left, bottom right
+	if got := [3][2]float32{pos(0), pos(1), pos(2)}; got != [3][2]float32{{10, 20}, {10, 28}, {26, 28}} {
+		t.Errorf("got corners %v, want the texture's size at 10, 20", got)
+	}
+}
+
+func TestBatchFull(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	b, err := sprite.NewBatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Delete()
+	tex := newTexture(t)
+	rec.Reset()
+	for i := 0; i <= sprite.BatchSize; i++ {
+		if err := b.Add(tex, 0, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// an empty batch draws nothing
+	if err := b.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	var counts []interface{}
+	for _, c := range rec.Draws() {
+		counts = append(counts, c.Args[2])
+	}
+	if want := fmt.Sprint([]int{sprite.BatchSize * 6, 6}); fmt.Sprint(counts) != want {
+		t.Errorf("got draws of %v vertices, want %v", counts, want)
+	}
+}
@@ -0,0 +1,387 @@
+package gfx
+
+import (
+	"j4k.co/gfx/internal/gl"
+)
+
+// State is the fixed-function state draws are made with. The zero value
+// draws opaque geometry with depth testing, depth writes, and back-face
+// culling, which suits most 3D meshes.
+//
+// gfx tracks the state last applied and only changes what differs, so
+// ResetState must be called after changing any of this state with GL
+// directly. Program, vertex array object and texture bindings are tracked
+// the same way.
+type State struct {
+	Blend BlendMode
+
+	NoDepthTest  bool
+	NoDepthWrite bool
+	DepthFunc    CompareFunc
+
+	Cull CullMode
+
+	// Scissor restricts drawing to ScissorBox, which holds x, y, width, and
+	// height in window pixels from the bottom left.
+	Scissor    bool
+	ScissorBox [4]int
+
+	// NoColorWrite masks out the given color channels.
+	NoColorWrite ColorMask
+
+	// FramebufferSRGB encodes colors written to sRGB render targets, such as
+	// PixelSRGBA8 textures or an sRGB default framebuffer, from linear to
+	// sRGB, for gamma-correct rendering.
+	FramebufferSRGB bool
+}
+
+// BlendMode describes how fragments are blended with the framebuffer. The
+// result is Equation(Src * source, Dst * destination), with the alpha
+// channel using the Alpha factors and equation.
+type BlendMode struct {
+	Enabled                 bool
+	SrcRGB, DstRGB          BlendFactor
+	SrcAlpha, DstAlpha      BlendFactor
+	Equation, EquationAlpha BlendEquation
+}
+
+// Blend mode presets. AlphaBlend suits colors with straight alpha, and
+// PremultipliedAlpha colors already multiplied by their alpha. Multiply and
+// Screen leave the destination alpha unchanged.
+var (
+	Opaque = BlendMode{}
+
+	AlphaBlend = BlendMode{
+		Enabled: true,
+		SrcRGB:  BlendSrcAlpha, DstRGB: BlendOneMinusSrcAlpha,
+		SrcAlpha: BlendOne, DstAlpha: BlendOneMinusSrcAlpha,
+	}
+
+	PremultipliedAlpha = BlendMode{
+		Enabled: true,
+		SrcRGB:  BlendOne, DstRGB: BlendOneMinusSrcAlpha,
+		SrcAlpha: BlendOne, DstAlpha: BlendOneMinusSrcAlpha,
+	}
+
+	Additive = BlendMode{
+		Enabled: true,
+		SrcRGB:  BlendSrcAlpha, DstRGB: BlendOne,
+		SrcAlpha: BlendZero, DstAlpha: BlendOne,
+	}
+
+	Multiply = BlendMode{
+		Enabled: true,
+		SrcRGB:  BlendDstColor, DstRGB: BlendZero,
+		SrcAlpha: BlendZero, DstAlpha: BlendOne,
+	}
+
+	Screen = BlendMode{
+		Enabled: true,
+		SrcRGB:  BlendOne, DstRGB: BlendOneMinusSrcColor,
+		SrcAlpha: BlendZero, DstAlpha: BlendOne,
+	}
+)
+
+type BlendFactor uint8
+
+const (
+	BlendZero BlendFactor = iota
+	BlendOne
+	BlendSrcColor
+	BlendOneMinusSrcColor
+	BlendDstColor
+	BlendOneMinusDstColor
+	BlendSrcAlpha
+	BlendOneMinusSrcAlpha
+	BlendDstAlpha
+	BlendOneMinusDstAlpha
+)
+
+func (f BlendFactor) gl() gl.GLenum {
+	switch f {
+	case BlendOne:
+		return gl.ONE
+	case BlendSrcColor:
+		return gl.SRC_COLOR
+	case BlendOneMinusSrcColor:
+		return gl.ONE_MINUS_SRC_COLOR
+	case BlendDstColor:
+		return gl.DST_COLOR
+	case BlendOneMinusDstColor:
+		return gl.ONE_MINUS_DST_COLOR
+	case BlendSrcAlpha:
+		return gl.SRC_ALPHA
+	case BlendOneMinusSrcAlpha:
+		return gl.ONE_MINUS_SRC_ALPHA
+	case BlendDstAlpha:
+		return gl.DST_ALPHA
+	case BlendOneMinusDstAlpha:
+		return gl.ONE_MINUS_DST_ALPHA
+	default:
+		return gl.ZERO
+	}
+}
+
+type BlendEquation uint8
+
+const (
+	BlendAdd BlendEquation = iota
+	BlendSubtract
+	BlendReverseSubtract
+	BlendMin
+	BlendMax
+)
+
+func (e BlendEquation) gl() gl.GLenum {
+	switch e {
+	case BlendSubtract:
+		return gl.FUNC_SUBTRACT
+	case BlendReverseSubtract:
+		return gl.FUNC_REVERSE_SUBTRACT
+	case BlendMin:
+		return gl.MIN
+	case BlendMax:
+		return gl.MAX
+	default:
+		return gl.FUNC_ADD
+	}
+}
+
+// CompareFunc is a comparison for depth testing.
+type CompareFunc uint8
+
+const (
+	CompareLess CompareFunc = iota
+	CompareLessEqual
+	CompareEqual
+	CompareGreater
+	CompareGreaterEqual
+	CompareNotEqual
+	CompareAlways
+	CompareNever
+)
+
+func (c CompareFunc) gl() gl.GLenum {
+	switch c {
+	case CompareLessEqual:
+		return gl.LEQUAL
+	case CompareEqual:
+		return gl.EQUAL
+	case CompareGreater:
+		return gl.GREATER
+	case CompareGreaterEqual:
+		return gl.GEQUAL
+	case CompareNotEqual:
+		return gl.NOTEQUAL
+	case CompareAlways:
+		return gl.ALWAYS
+	case CompareNever:
+		return gl.NEVER
+	default:
+		return gl.LESS
+	}
+}
+
+type CullMode uint8
+
+const (
+	CullBack CullMode = iota
+	CullFront
+	CullNone
+)
+
+// ColorMask is a set of color channels.
+type ColorMask uint8
+
+const (
+	ColorRed ColorMask = 1 << iota
+	ColorGreen
+	ColorBlue
+	ColorAlpha
+)
+
+// appliedState is the state last set by Apply, if known.
+var (
+	appliedState State
+	stateKnown   bool
+)
+
+// ResetState forgets the tracked state, so that the next Apply sets all of
+// it, as well as the tracked program, vertex array object, and texture
+// bindings. Call it after changing blending, depth, culling, scissor, color
+// mask, or sRGB state, or binding programs, vertex array objects or
+// textures, with GL directly.
+func ResetState() {
+	stateKnown = false
+	forgetBindings()
+}
+
+// Apply sets the GL state to s, skipping whatever is already set.
+func (s *State) Apply() {
+	cur := &appliedState
+	all := !stateKnown
+	if all || s.Blend != cur.Blend {
+		b := &s.Blend
+		if b.Enabled {
+			gl.Enable(gl.BLEND)
+			gl.BlendFuncSeparate(b.SrcRGB.gl(), b.DstRGB.gl(), b.SrcAlpha.gl(), b.DstAlpha.gl())
+			gl.BlendEquationSeparate(b.Equation.gl(), b.EquationAlpha.gl())
+		} else {
+			gl.Disable(gl.BLEND)
+		}
+	}
+	if all || s.NoDepthTest != cur.NoDepthTest {
+		if s.NoDepthTest {
+			gl.Disable(gl.DEPTH_TEST)
+		} else {
+			gl.Enable(gl.DEPTH_TEST)
+		}
+	}
+	if all || s.NoDepthWrite != cur.NoDepthWrite {
+		gl.DepthMask(!s.NoDepthWrite)
+	}
+	if all || s.DepthFunc != cur.DepthFunc {
+		gl.DepthFunc(s.DepthFunc.gl())
+	}
+	if all || s.Cull != cur.Cull {
+		switch s.Cull {
+		case CullNone:
+			gl.Disable(gl.CULL_FACE)
+		case CullFront:
+			gl.Enable(gl.CULL_FACE)
+			gl.CullFace(gl.FRONT)
+		default:
+			gl.Enable(gl.CULL_FACE)
+			gl.CullFace(gl.BACK)
+		}
+	}
+	if all || s.Scissor != cur.Scissor {
+		if s.Scissor {
+			gl.Enable(gl.SCISSOR_TEST)
+		} else {
+			gl.Disable(gl.SCISSOR_TEST)
+		}
+	}
+	if s.Scissor && (all || !cur.Scissor || s.ScissorBox != cur.ScissorBox) {
+		b := s.ScissorBox
+		gl.Scissor(b[0], b[1], b[2], b[3])
+	}
+	if all || s.NoColorWrite != cur.NoColorWrite {
+		m := s.NoColorWrite
+		gl.ColorMask(m&ColorRed == 0, m&ColorGreen == 0, m&ColorBlue == 0, m&ColorAlpha == 0)
+	}
+	if all || s.FramebufferSRGB != cur.FramebufferSRGB {
+		if s.FramebufferSRGB {
+			gl.Enable(gl.FRAMEBUFFER_SRGB)
+		} else {
+			gl.Disable(gl.FRAMEBUFFER_SRGB)
+		}
+	}
+	*cur = *s
+	stateKnown = true
+	checkError("State.Apply")
+}
+
+// maxTrackedUnits is the number of texture units whose bindings are
+// tracked; binds to higher units always reach GL.
+const maxTrackedUnits = 32
+
+// unknownBinding marks a binding gfx does not know, which the next bind
+// sets regardless.
+const unknownBinding = ^uint32(0)
+
+// bound is the program, vertex array object, active texture unit, and 2D
+// textures of each unit last bound by gfx, so that binding them again is
+// skipped.
+var bound struct {
+	program  gl.Program
+	vao      gl.VertexArray
+	unit     int
+	textures [maxTrackedUnits]gl.Texture
+}
+
+func init() {
+	forgetBindings()
+}
+
+// forgetBindings marks all tracked bindings unknown.
+func forgetBindings() {
+	bound.program = gl.Program(unknownBinding)
+	bound.vao = gl.VertexArray(unknownBinding)
+	bound.unit = -1
+	for i := range bound.textures {
+		bound.textures[i] = gl.Texture(unknownBinding)
+	}
+	forgetUnits()
+}
+
+func useProgram(p gl.Program) {
+	if p != bound.program {
+		p.Use()
+		bound.program = p
+	}
+}
+
+func bindVertexArray(vao gl.VertexArray) {
+	if vao != bound.vao {
+		vao.Bind()
+		bound.vao = vao
+	}
+}
+
+func activeTexture(unit int) {
+	if unit != bound.unit {
+		gl.ActiveTexture(gl.TEXTURE0 + gl.GLenum(unit))
+		bound.unit = unit
+	}
+}
+
+// bindTexture binds tex to target of the active texture unit.
+func bindTexture(tex gl.Texture, target gl.GLenum) {
+	if bound.unit < 0 || bound.unit >= maxTrackedUnits {
+		tex.Bind(target)
+		return
+	}
+	if tex != bound.textures[bound.unit] {
+		tex.Bind(target)
+		bound.textures[bound.unit] = tex
+	}
+}
+
+// unbindTexture unbinds tex from every tracked unit it is bound to, such as
+// before rendering into it, so that no draw samples it meanwhile.
+func unbindTexture(tex gl.Texture) {
+	for i, t := range bound.textures {
+		if t == tex {
+			activeTexture(i)
+			bindTexture(0, gl.TEXTURE_2D)
+			units.used[i] = 0
+		}
+	}
+}
+
+// deleteProgram, deleteVertexArray and deleteTexture delete objects and
+// forget their bindings, as the names may be reused by new objects.
+func deleteProgram(p gl.Program) {
+	p.Delete()
+	if p == bound.program {
+		bound.program = gl.Program(unknownBinding)
+	}
+}
+
+func deleteVertexArray(vao gl.VertexArray) {
+	vao.Delete()
+	if vao == bound.vao {
+		bound.vao = 0
+	}
+}
+
+func deleteTexture(tex gl.Texture) {
+	tex.Delete()
+	for i, t := range bound.textures {
+		if t == tex {
+			bound.textures[i] = 0
+			units.used[i] = 0
+		}
+	}
+}
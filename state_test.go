@@ -0,0 +1,155 @@
+package gfx_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+func TestRedundantBinds(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, textureShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	tex, err := gfx.NewSampler2D(4, 4, gfx.PixelRGBA8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tex.Delete()
+	b := geometry.NewBuilder(gfx.VertexPosition)
+	b.Position(0, 0, 0).Position(1, 0, 0).Position(0, 1, 0)
+	geom, err := gfx.NewGeometry(b, gfx.StaticDraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geom.Delete()
+
+	rec.Reset()
+	for i := 0; i < 3; i++ {
+		s.Use()
+		if err := s.SetTexture("Texture", tex); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetGeometry(geom); err != nil {
+			t.Fatal(err)
+		}
+		s.Draw()
+	}
+	for _, op := range []string{"UseProgram", "ActiveTexture", "BindTexture", "BindVertexArray"} {
+		if n := len(rec.Ops(op)); n > 1 {
+			t.Errorf("got %d %s calls, want at most 1", n, op)
+		}
+	}
+	if n := len(rec.Draws()); n != 3 {
+		t.Errorf("got %d draws, want 3", n)
+	}
+
+	rec.Reset()
+	gfx.ResetState()
+	s.Use()
+	if n := len(rec.Ops("UseProgram")); n != 1 {
+		t.Errorf("got %d UseProgram calls after ResetState, want 1", n)
+	}
+}
+
+func TestStateApply(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	calls := func() string {
+		var s []string
+		for _, c := range rec.Calls {
+			s = append(s, c.String())
+		}
+		rec.Reset()
+		return fmt.Sprint(s)
+	}
+	gfx.ResetState()
+	var s gfx.State
+	s.Apply()
+	want := "[Disable(BLEND) Enable(DEPTH_TEST) DepthMask(true) DepthFunc(LESS) Enable(CULL_FACE) CullFace(BACK) " +
+		"Disable(SCISSOR_TEST) ColorMask(true, true, true, true) Disable(FRAMEBUFFER_SRGB)]"
+	if got := calls(); got != want {
+		t.Errorf("got %v applying all state,\nwant %v", got, want)
+	}
+	s.Apply()
+	if got := calls(); got != "[]" {
+		t.Errorf("got %v applying the same state", got)
+	}
+
+	// only what changed is set
+	s = gfx.State{
+		NoDepthWrite: true,
+		DepthFunc:    gfx.CompareLessEqual,
+		Cull:         gfx.CullFront,
+		Scissor:      true,
+		ScissorBox:   [4]int{1, 2, 3, 4},
+		NoColorWrite: gfx.ColorAlpha,
+	}
+	s.Apply()
+	want = "[DepthMask(false) DepthFunc(LEQUAL) Enable(CULL_FACE) CullFace(FRONT) " +
+		"Enable(SCISSOR_TEST) Scissor(1, 2, 3, 4) ColorMask(true, true, true, false)]"
+	if got := calls(); got != want {
+		t.Errorf("got %v,\nwant %v", got, want)
+	}
+	s.ScissorBox = [4]int{0, 0, 8, 8}
+	s.Cull = gfx.CullNone
+	s.Apply()
+	if got := calls(); got != "[Disable(CULL_FACE) Scissor(0, 0, 8, 8)]" {
+		t.Errorf("got %v moving the scissor box", got)
+	}
+
+	// after a reset, everything is set again
+	gfx.ResetState()
+	s.Apply()
+	want = "[Disable(BLEND) Enable(DEPTH_TEST) DepthMask(false) DepthFunc(LEQUAL) Disable(CULL_FACE) " +
+		"Enable(SCISSOR_TEST) Scissor(0, 0, 8, 8) ColorMask(true, true, true, false) Disable(FRAMEBUFFER_SRGB)]"
+	if got := calls(); got != want {
+		t.Errorf("got %v after ResetState,\nwant %v", got, want)
+	}
+}
+
+func TestBlendPresets(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	// 0x0000 is ZERO and 0x0001 is ONE
+	for _, c := range []struct {
+		name  string
+		blend gfx.BlendMode
+		want  string
+	}{
+		{"AlphaBlend", gfx.AlphaBlend, "BlendFuncSeparate(SRC_ALPHA, ONE_MINUS_SRC_ALPHA, 0x0001, ONE_MINUS_SRC_ALPHA)"},
+		{"PremultipliedAlpha", gfx.PremultipliedAlpha, "BlendFuncSeparate(0x0001, ONE_MINUS_SRC_ALPHA, 0x0001, ONE_MINUS_SRC_ALPHA)"},
+		{"Additive", gfx.Additive, "BlendFuncSeparate(SRC_ALPHA, 0x0001, 0x0000, 0x0001)"},
+		{"Multiply", gfx.Multiply, "BlendFuncSeparate(DST_COLOR, 0x0000, 0x0000, 0x0001)"},
+		{"Screen", gfx.Screen, "BlendFuncSeparate(0x0001, ONE_MINUS_SRC_COLOR, 0x0000, 0x0001)"},
+	} {
+		gfx.ResetState()
+		rec.Reset()
+		s := gfx.State{Blend: c.blend}
+		s.Apply()
+		var got []string
+		for _, call := range rec.Ops("Enable", "Disable", "BlendFuncSeparate", "BlendEquationSeparate")[:3] {
+			got = append(got, call.String())
+		}
+		want := fmt.Sprint([]string{"Enable(BLEND)", c.want, "BlendEquationSeparate(FUNC_ADD, FUNC_ADD)"})
+		if fmt.Sprint(got) != want {
+			t.Errorf("%s: got %v, want %v", c.name, got, want)
+		}
+	}
+
+	// Opaque disables blending
+	rec.Reset()
+	s := gfx.State{Blend: gfx.Opaque}
+	s.Apply()
+	if got := fmt.Sprint(rec.Ops("Disable", "Enable")); got != "[Disable(BLEND)]" {
+		t.Errorf("got %v switching to Opaque", got)
+	}
+}
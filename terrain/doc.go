@@ -0,0 +1,9 @@
+/*
+Package terrain renders heightmap terrain as a quadtree of chunks. Distant
+chunks are drawn at lower detail, chunks outside the view frustum are
+skipped, and the number of triangles drawn can be bounded. Edges between
+chunks one level of detail apart are stitched, and any remaining cracks are
+hidden by skirts. Terrain is drawn directly with Draw, or attached to a
+scenes.Node as a Renderable.
+*/
+package terrain
@@ -0,0 +1,99 @@
+package terrain
+
+import (
+	"image"
+	"math"
+)
+
+// Heightmap is a grid of height samples, stored row by row.
+type Heightmap struct {
+	Width   int
+	Depth   int
+	Heights []float32
+}
+
+// NewHeightmap returns a flat heightmap of width x depth samples.
+func NewHeightmap(width, depth int) *Heightmap {
+	return &Heightmap{
+		Width:   width,
+		Depth:   depth,
+		Heights: make([]float32, width*depth),
+	}
+}
+
+// HeightmapFromImage converts the luminance of img into heights in the range
+// [0, 1]. *image.Gray and *image.Gray16 are read directly; other images go
+// through the color model.
+func HeightmapFromImage(img image.Image) *Heightmap {
+	b := img.Bounds()
+	hm := NewHeightmap(b.Dx(), b.Dy())
+	switch img := img.(type) {
+	case *image.Gray:
+		for z := 0; z < hm.Depth; z++ {
+			row := img.Pix[z*img.Stride:]
+			for x := 0; x < hm.Width; x++ {
+				hm.Heights[z*hm.Width+x] = float32(row[x]) / 255
+			}
+		}
+	case *image.Gray16:
+		for z := 0; z < hm.Depth; z++ {
+			row := img.Pix[z*img.Stride:]
+			for x := 0; x < hm.Width; x++ {
+				v := uint16(row[2*x])<<8 | uint16(row[2*x+1])
+				hm.Heights[z*hm.Width+x] = float32(v) / 65535
+			}
+		}
+	default:
+		for z := 0; z < hm.Depth; z++ {
+			for x := 0; x < hm.Width; x++ {
+				r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+z).RGBA()
+				lum := (299*r + 587*g + 114*bl) / 1000
+				hm.Heights[z*hm.Width+x] = float32(lum) / 65535
+			}
+		}
+	}
+	return hm
+}
+
+// At returns the height at sample x, z, clamping coordinates to the edges.
+func (h *Heightmap) At(x, z int) float32 {
+	if x < 0 {
+		x = 0
+	} else if x >= h.Width {
+		x = h.Width - 1
+	}
+	if z < 0 {
+		z = 0
+	} else if z >= h.Depth {
+		z = h.Depth - 1
+	}
+	return h.Heights[z*h.Width+x]
+}
+
+// Normal returns the unit surface normal at sample x, z for the given
+// horizontal sample spacing and vertical scale.
+func (h *Heightmap) Normal(x, z int, spacing, scale float32) [3]float32 {
+	dx := (h.At(x+1, z) - h.At(x-1, z)) * scale
+	dz := (h.At(x, z+1) - h.At(x, z-1)) * scale
+	n := [3]float32{-dx, 2 * spacing, -dz}
+	l := float32(math.Sqrt(float64(n[0]*n[0] + n[1]*n[1] + n[2]*n[2])))
+	return [3]float32{n[0] / l, n[1] / l, n[2] / l}
+}
+
+// rangeAt returns the minimum and maximum height within the square region
+// of size samples starting at x, z.
+func (h *Heightmap) rangeAt(x, z, size int) (min, max float32) {
+	min, max = float32(math.Inf(1)), float32(math.Inf(-1))
+	for j := z; j <= z+size; j++ {
+		for i := x; i <= x+size; i++ {
+			v := h.At(i, j)
+			if v < min {
+				min = v
+			}
+			if v > max {
+				max = v
+			}
+		}
+	}
+	return min, max
+}
@@ -0,0 +1,60 @@
+package terrain
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/scenes"
+)
+
+// Renderable is a component that draws a terrain in a scene. It implements
+// scenes.Drawable: for each camera, the chunks of the terrain are selected
+// in the local space of its node, and drawn by the scenes renderer with
+// Material, whose shader must accept VertexFormat.
+type Renderable struct {
+	Terrain  *Terrain
+	Material *scenes.Material
+
+	chunks []Chunk
+	parts  []gfx.IndexBuffer
+}
+
+// AppendParts implements scenes.Drawable, building the geometry of newly
+// selected chunks.
+func (r *Renderable) AppendParts(dst []scenes.Part, n *scenes.Node, cam *scenes.Camera) ([]scenes.Part, error) {
+	if r.Terrain == nil {
+		return dst, nil
+	}
+	world := n.WorldMatrix()
+	inv, ok := scenes.Invert(&world)
+	if !ok {
+		return dst, nil
+	}
+	var eye [3]float32
+	if cn := cam.Node(); cn != nil {
+		p := cn.WorldPosition()
+		for i := range eye {
+			eye[i] = inv[i]*p[0] + inv[4+i]*p[1] + inv[8+i]*p[2] + inv[12+i]
+		}
+	}
+	viewProj := cam.ViewProjection()
+	frustum := gfx.FrustumFromMatrix(scenes.MulMatrix(&viewProj, &world))
+	r.chunks = r.Terrain.Select(r.chunks[:0], eye, &frustum)
+	for _, c := range r.chunks {
+		var err error
+		if r.parts, err = r.Terrain.appendParts(r.parts[:0], c); err != nil {
+			return dst, err
+		}
+		geom := c.n.geom
+		for _, p := range r.parts {
+			if p.Count() == 0 {
+				continue
+			}
+			dst = append(dst, scenes.Part{
+				Geometry: geom,
+				Material: r.Material,
+				First:    p.Offset() - geom.IndexBuffer.Offset(),
+				Count:    p.Count(),
+			})
+		}
+	}
+	return dst, nil
+}
@@ -0,0 +1,84 @@
+package terrain_test
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"j4k.co/gfx/scenes"
+	"j4k.co/gfx/terrain"
+	"math"
+	"testing"
+)
+
+const sceneVertexShader gfx.VertexShader = `
+attribute vec3 Position;
+attribute vec3 Normal;
+attribute vec2 TexCoord;
+
+uniform mat4 WorldViewProjectionM;
+
+varying vec2 uv;
+varying vec3 normal;
+
+void main() {
+	uv = TexCoord;
+	normal = Normal;
+	gl_Position = WorldViewProjectionM * vec4(Position, 1.0);
+}`
+
+func TestRenderable(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	attrs := gfx.VertexAttributes{
+		gfx.VertexPosition: "Position",
+		gfx.VertexNormal:   "Normal",
+		gfx.VertexTexcoord: "TexCoord",
+	}
+	s, err := gfx.BuildShader(attrs, sceneVertexShader, terrain.SplatFragmentShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	tr := mustNew(t, terrain.NewHeightmap(65, 65), terrain.Config{ChunkCells: 32, LODDistance: 1})
+	defer tr.Delete()
+
+	root := scenes.NewNode("root")
+	ground := scenes.NewNode("terrain")
+	ground.Attach(&terrain.Renderable{Terrain: tr, Material: &scenes.Material{Shader: s}})
+	root.Add(ground)
+	eye := scenes.NewNode("camera")
+	cam := scenes.NewPerspective(math.Pi/2, 1, 0.1, 1000)
+	eye.Attach(cam)
+	root.Add(eye)
+
+	// far away, the whole terrain is one chunk drawn in its five parts
+	eye.SetPosition(32, 500, 32)
+	cam.LookAt([3]float32{32, 0, 32}, [3]float32{0, 0, -1})
+	r := scenes.NewRenderer()
+	defer r.Delete()
+	rec.Reset()
+	if err := r.Render(root, cam); err != nil {
+		t.Fatal(err)
+	}
+	draws := rec.Draws()
+	if len(draws) != 1 || draws[0].Op != "MultiDrawElements" || len(draws[0].Args[1].([]int32)) != 5 {
+		t.Fatalf("got draws %v, want one multi-draw of the interior and edges", draws)
+	}
+
+	// close to a corner, the chunks near it are split, and are selected in
+	// the space of the terrain's node when both move
+	var counts []int
+	for _, offset := range []float32{0, 1000} {
+		ground.SetPosition(offset, 0, offset)
+		eye.SetPosition(offset, 1, offset)
+		cam.LookAt([3]float32{offset + 32, 0, offset + 32}, [3]float32{0, 1, 0})
+		rec.Reset()
+		if err := r.Render(root, cam); err != nil {
+			t.Fatal(err)
+		}
+		counts = append(counts, len(rec.Draws()))
+	}
+	if counts[0] <= 1 || counts[1] != counts[0] {
+		t.Errorf("got %v draws near the terrain, want the same number above 1 at each offset", counts)
+	}
+}
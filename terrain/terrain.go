@@ -0,0 +1,612 @@
+package terrain
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"math"
+)
+
+// VertexFormat is the vertex format of terrain chunks. Texcoords span the
+// whole terrain from 0 to 1, so they can address a splat map directly.
+const VertexFormat = gfx.VertexPosition | gfx.VertexNormal | gfx.VertexTexcoord
+
+// Config describes how a heightmap is turned into terrain.
+type Config struct {
+	// ChunkCells is the number of quads along each side of a chunk. It must
+	// be a power of two of at most MaxChunkCells; 32 is a good default.
+	ChunkCells int
+
+	// Spacing is the horizontal distance between height samples, and
+	// HeightScale multiplies each sample.
+	Spacing     float32
+	HeightScale float32
+
+	// LODDistance scales the distance at which a chunk is split into its
+	// four children. Larger values keep more detail further away.
+	LODDistance float32
+
+	// MaxTriangles bounds the triangles of the chunks Select returns,
+	// including their skirts, by splitting the chunks nearest the eye for
+	// their size first and stopping when the next split would exceed it.
+	// Zero leaves the detail unbounded. At least the root chunk is
+	// selected.
+	MaxTriangles int
+}
+
+// Terrain is a quadtree of chunks over a heightmap. Chunk geometry is built
+// on first use and kept until Delete.
+type Terrain struct {
+	hm   *Heightmap
+	conf Config
+	root *node
+
+	selected map[*node]bool
+	parts    []gfx.IndexBuffer
+}
+
+type node struct {
+	x, z     int // first sample
+	cells    int // samples covered along each side, minus one
+	step     int // samples between adjacent vertices
+	min, max [3]float32
+	children [4]*node
+	geom     *gfx.Geometry
+	layout   *gfx.GeometryLayout
+	shader   *gfx.Shader
+
+	// parts are the index ranges of geom: the interior, then each edge
+	// plain and stitched
+	parts [numParts]gfx.IndexBuffer
+}
+
+const numParts = 1 + 2*4
+
+// MaxChunkCells is the largest ChunkCells whose vertices, with their
+// skirts, can be indexed with 16 bits.
+const MaxChunkCells = 128
+
+// New builds the quadtree for hm. No GL objects are created until the
+// terrain is drawn. It returns an error if ChunkCells is not a power of two
+// of at most MaxChunkCells.
+func New(hm *Heightmap, conf Config) (*Terrain, error) {
+	if conf.ChunkCells == 0 {
+		conf.ChunkCells = 32
+	}
+	if c := conf.ChunkCells; c < 1 || c > MaxChunkCells || c&(c-1) != 0 {
+		return nil, fmt.Errorf("terrain: ChunkCells %d is not a power of two of at most %d", c, MaxChunkCells)
+	}
+	if conf.Spacing == 0 {
+		conf.Spacing = 1
+	}
+	if conf.HeightScale == 0 {
+		conf.HeightScale = 1
+	}
+	if conf.LODDistance == 0 {
+		conf.LODDistance = 2
+	}
+	size := hm.Width - 1
+	if hm.Depth-1 > size {
+		size = hm.Depth - 1
+	}
+	cells := conf.ChunkCells
+	for cells < size {
+		cells *= 2
+	}
+	t := &Terrain{hm: hm, conf: conf}
+	t.root = t.newNode(0, 0, cells)
+	return t, nil
+}
+
+func (t *Terrain) newNode(x, z, cells int) *node {
+	if x >= t.hm.Width-1 || z >= t.hm.Depth-1 {
+		return nil
+	}
+	n := &node{x: x, z: z, cells: cells, step: 1}
+	if cells > t.conf.ChunkCells {
+		n.step = cells / t.conf.ChunkCells
+	}
+	lo, hi := t.hm.rangeAt(x, z, cells)
+	s := t.conf.Spacing
+	n.min = [3]float32{float32(x) * s, lo * t.conf.HeightScale, float32(z) * s}
+	n.max = [3]float32{float32(x+cells) * s, hi * t.conf.HeightScale, float32(z+cells) * s}
+	if cells > t.conf.ChunkCells {
+		half := cells / 2
+		n.children[0] = t.newNode(x, z, half)
+		n.children[1] = t.newNode(x+half, z, half)
+		n.children[2] = t.newNode(x, z+half, half)
+		n.children[3] = t.newNode(x+half, z+half, half)
+	}
+	return n
+}
+
+// The edges of a chunk, as given to Chunk.Stitched.
+const (
+	EdgeMinZ = iota
+	EdgeMaxX
+	EdgeMaxZ
+	EdgeMinX
+)
+
+// Chunk is a piece of terrain selected for drawing.
+type Chunk struct {
+	n *node
+
+	// stitch has bit i set if edge i meets a neighbor of half the detail
+	stitch uint8
+}
+
+// Bounds returns the world space bounding box of the chunk.
+func (c Chunk) Bounds() (min, max [3]float32) {
+	return c.n.min, c.n.max
+}
+
+// Step returns the number of heightmap samples between adjacent vertices of
+// the chunk; 1 is full detail.
+func (c Chunk) Step() int {
+	return c.n.step
+}
+
+// Stitched reports whether an edge of the chunk, such as EdgeMinZ, meets a
+// selected chunk of half its detail, and so skips every other vertex along
+// it to match the vertices of its neighbor.
+func (c Chunk) Stitched(edge int) bool {
+	return c.stitch&(1<<uint(edge)) != 0
+}
+
+// Select appends the chunks to draw for a camera at eye with the given
+// frustum. Chunks outside the frustum are skipped; chunks near the eye are
+// replaced by their higher detail children, within MaxTriangles if set.
+// Edges against chunks of half the detail are stitched to them; the skirts
+// hide cracks against chunks further apart in detail.
+func (t *Terrain) Select(dst []Chunk, eye [3]float32, frustum *gfx.Frustum) []Chunk {
+	start := len(dst)
+	if t.conf.MaxTriangles > 0 {
+		dst = t.selectBudget(dst, eye, frustum)
+	} else {
+		dst = t.selectNode(dst, t.root, eye, frustum)
+	}
+	t.stitch(dst[start:])
+	return dst
+}
+
+func (t *Terrain) selectNode(dst []Chunk, n *node, eye [3]float32, frustum *gfx.Frustum) []Chunk {
+	if !visible(n, frustum) {
+		return dst
+	}
+	if !t.split(n, eye) {
+		return append(dst, Chunk{n: n})
+	}
+	for _, c := range n.children {
+		dst = t.selectNode(dst, c, eye, frustum)
+	}
+	return dst
+}
+
+// selectBudget selects chunks like selectNode, splitting the chunk nearest
+// the eye relative to its size first, until the next split would select
+// more than MaxTriangles.
+func (t *Terrain) selectBudget(dst []Chunk, eye [3]float32, frustum *gfx.Frustum) []Chunk {
+	max := t.conf.MaxTriangles / t.chunkTriangles()
+	sel := []*node{t.root}
+	if !visible(t.root, frustum) {
+		sel = nil
+	}
+	for {
+		best, bestRatio := -1, float32(math.Inf(1))
+		for i, n := range sel {
+			if !t.split(n, eye) {
+				continue
+			}
+			ratio := boxDistance(eye, n.min, n.max) / float32(n.cells)
+			if ratio < bestRatio {
+				best, bestRatio = i, ratio
+			}
+		}
+		if best < 0 {
+			break
+		}
+		var children []*node
+		for _, c := range sel[best].children {
+			if visible(c, frustum) {
+				children = append(children, c)
+			}
+		}
+		if len(sel)-1+len(children) > max {
+			break
+		}
+		sel = append(append(sel[:best:best], children...), sel[best+1:]...)
+	}
+	for _, n := range sel {
+		dst = append(dst, Chunk{n: n})
+	}
+	return dst
+}
+
+// visible reports whether n exists and is at least partly inside frustum,
+// if any.
+func visible(n *node, frustum *gfx.Frustum) bool {
+	return n != nil && (frustum == nil || frustum.ContainsBox(n.min, n.max))
+}
+
+// split reports whether n is close enough to eye to be replaced by its
+// children.
+func (t *Terrain) split(n *node, eye [3]float32) bool {
+	leaf := true
+	for _, c := range n.children {
+		if c != nil {
+			leaf = false
+		}
+	}
+	size := float32(n.cells) * t.conf.Spacing
+	return !leaf && boxDistance(eye, n.min, n.max) <= size*t.conf.LODDistance
+}
+
+// chunkTriangles returns the number of triangles drawn for each chunk.
+func (t *Terrain) chunkTriangles() int {
+	cells := t.conf.ChunkCells
+	return 2*cells*cells + 8*cells
+}
+
+// stitch marks the edges of chunks that meet a chunk of half their detail.
+func (t *Terrain) stitch(chunks []Chunk) {
+	if t.conf.ChunkCells < 2 {
+		return
+	}
+	if t.selected == nil {
+		t.selected = make(map[*node]bool)
+	}
+	for _, c := range chunks {
+		t.selected[c.n] = true
+	}
+	for i := range chunks {
+		n := chunks[i].n
+		half := n.cells / 2
+		// a sample just outside the middle of each edge
+		probes := [4][2]int{
+			EdgeMinZ: {n.x + half, n.z - 1},
+			EdgeMaxX: {n.x + n.cells, n.z + half},
+			EdgeMaxZ: {n.x + half, n.z + n.cells},
+			EdgeMinX: {n.x - 1, n.z + half},
+		}
+		for e, p := range probes {
+			if nb := t.selectedAt(p[0], p[1]); nb != nil && nb.step == 2*n.step {
+				chunks[i].stitch |= 1 << uint(e)
+			}
+		}
+	}
+	for _, c := range chunks {
+		delete(t.selected, c.n)
+	}
+}
+
+// selectedAt returns the selected chunk covering sample x, z, or nil.
+func (t *Terrain) selectedAt(x, z int) *node {
+	n := t.root
+	if x < n.x || z < n.z || x >= n.x+n.cells || z >= n.z+n.cells {
+		return nil
+	}
+	for n != nil && !t.selected[n] {
+		half := n.cells / 2
+		i := 0
+		if x >= n.x+half {
+			i |= 1
+		}
+		if z >= n.z+half {
+			i |= 2
+		}
+		n = n.children[i]
+	}
+	return n
+}
+
+// boxDistance returns the distance from p to the nearest point of a box.
+func boxDistance(p, min, max [3]float32) float32 {
+	var d2 float32
+	for i := 0; i < 3; i++ {
+		var d float32
+		if p[i] < min[i] {
+			d = min[i] - p[i]
+		} else if p[i] > max[i] {
+			d = p[i] - max[i]
+		}
+		d2 += d * d
+	}
+	return float32(math.Sqrt(float64(d2)))
+}
+
+// BuildChunk emits the vertices and indices of a chunk into b, which must
+// have been created with VertexFormat. Each edge has a skirt hanging below
+// the surface to hide cracks against neighbors of a different detail level,
+// and stitched edges skip every other vertex.
+func (t *Terrain) BuildChunk(b *geometry.Builder, c Chunk) {
+	ci := t.buildNode(b, c.n)
+	idxs := ci.interior
+	for e, variants := range ci.edges {
+		if c.Stitched(e) {
+			idxs = append(idxs, variants[1]...)
+		} else {
+			idxs = append(idxs, variants[0]...)
+		}
+	}
+	b.Indices(idxs...)
+}
+
+// chunkIndices are the triangles of a chunk in the parts drawn for it.
+type chunkIndices struct {
+	interior []uint16
+	// edges hold the ring of quads along each edge and its skirt, plain
+	// and stitched
+	edges [4][2][]uint16
+}
+
+// buildNode emits the vertices of n into b, and returns its triangles.
+// Edges are in the order of the Edge constants.
+func (t *Terrain) buildNode(b *geometry.Builder, n *node) *chunkIndices {
+	step := n.step
+	cells := n.cells / step
+	verts := cells + 1
+	s := t.conf.Spacing
+	hs := t.conf.HeightScale
+	invw := 1 / float32(t.hm.Width-1)
+	invd := 1 / float32(t.hm.Depth-1)
+	vertex := func(sx, sz int, drop float32) {
+		y := t.hm.At(sx, sz)*hs - drop
+		nrm := t.hm.Normal(sx, sz, s*float32(step), hs)
+		b.Position(float32(sx)*s, y, float32(sz)*s).
+			Normal(nrm[0], nrm[1], nrm[2]).
+			Texcoord(float32(sx)*invw, float32(sz)*invd)
+	}
+	for j := 0; j < verts; j++ {
+		for i := 0; i < verts; i++ {
+			vertex(n.x+i*step, n.z+j*step, 0)
+		}
+	}
+	ci := surfaceIndices(cells)
+
+	// skirts along each edge, wound to face outward
+	drop := float32(step) * s
+	edges := [4]struct{ x, z, dx, dz int }{
+		{0, 0, 1, 0},
+		{cells, 0, 0, 1},
+		{cells, cells, -1, 0},
+		{0, cells, 0, -1},
+	}
+	base := uint16(verts * verts)
+	for i, e := range edges {
+		start := base
+		for k := 0; k <= cells; k++ {
+			gx, gz := e.x+k*e.dx, e.z+k*e.dz
+			vertex(n.x+gx*step, n.z+gz*step, drop)
+			base++
+		}
+		// stitched skirts hang from the vertices of the stitched edge
+		for v, by := range [2]int{1, 2} {
+			if by > cells {
+				continue
+			}
+			for k := 0; k < cells; k += by {
+				gx, gz := e.x+k*e.dx, e.z+k*e.dz
+				top0 := uint16(gz*verts + gx)
+				top1 := uint16((gz+by*e.dz)*verts + gx + by*e.dx)
+				bot0, bot1 := start+uint16(k), start+uint16(k+by)
+				ci.edges[i][v] = append(ci.edges[i][v], top0, top1, bot0, top1, bot1, bot0)
+			}
+		}
+	}
+	return ci
+}
+
+// surfaceIndices returns the triangles of the surface of a chunk of cells
+// quads along each side: the interior, and the ring of quads along each
+// edge, whose stitched variant joins pairs of edge quads into one triangle,
+// skipping the vertex between them.
+func surfaceIndices(cells int) *chunkIndices {
+	verts := cells + 1
+	ci := new(chunkIndices)
+	// tri appends the triangle of grid points a, b and c, wound like the
+	// quads of the interior, unless it is degenerate
+	tri := func(dst []uint16, a, b, c [2]int) []uint16 {
+		switch cross := (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0]); {
+		case cross == 0:
+			return dst
+		case cross > 0:
+			b, c = c, b
+		}
+		for _, p := range [3][2]int{a, b, c} {
+			dst = append(dst, uint16(p[1]*verts+p[0]))
+		}
+		return dst
+	}
+	if cells < 2 {
+		// too small to stitch; the interior is the whole chunk
+		ci.interior = tri(ci.interior, [2]int{0, 0}, [2]int{0, 1}, [2]int{1, 0})
+		ci.interior = tri(ci.interior, [2]int{1, 0}, [2]int{0, 1}, [2]int{1, 1})
+		return ci
+	}
+	ci.interior = make([]uint16, 0, (cells-2)*(cells-2)*6)
+	for j := 1; j < cells-1; j++ {
+		for i := 1; i < cells-1; i++ {
+			a := uint16(j*verts + i)
+			ci.interior = append(ci.interior, a, a+uint16(verts), a+1, a+1, a+uint16(verts), a+uint16(verts)+1)
+		}
+	}
+	// at returns the grid point k along edge e, on the edge if d is 0 and
+	// one row in if 1
+	at := func(e, k, d int) [2]int {
+		switch e {
+		case EdgeMinZ:
+			return [2]int{k, d}
+		case EdgeMaxX:
+			return [2]int{cells - d, k}
+		case EdgeMaxZ:
+			return [2]int{cells - k, cells - d}
+		default:
+			return [2]int{d, cells - k}
+		}
+	}
+	// inner returns the grid point one row in from k, within the interior
+	inner := func(e, k int) [2]int {
+		if k < 1 {
+			k = 1
+		} else if k > cells-1 {
+			k = cells - 1
+		}
+		return at(e, k, 1)
+	}
+	for e := range ci.edges {
+		var plain, stitched []uint16
+		for k := 0; k < cells; k++ {
+			plain = tri(plain, at(e, k, 0), at(e, k+1, 0), inner(e, k+1))
+			plain = tri(plain, at(e, k, 0), inner(e, k+1), inner(e, k))
+		}
+		for k := 0; k < cells; k += 2 {
+			stitched = tri(stitched, at(e, k, 0), at(e, k+2, 0), inner(e, k+1))
+			stitched = tri(stitched, at(e, k, 0), inner(e, k+1), inner(e, k))
+			stitched = tri(stitched, at(e, k+2, 0), inner(e, k+2), inner(e, k+1))
+		}
+		ci.edges[e] = [2][]uint16{plain, stitched}
+	}
+	return ci
+}
+
+// Draw draws the selected chunks with s, building their geometry as
+// needed. The shader must be in use with its uniforms assigned, and must
+// accept VertexFormat. Each chunk is drawn with one multi-draw call of the
+// parts of its index buffer that its stitching needs.
+func (t *Terrain) Draw(s *gfx.Shader, chunks []Chunk) error {
+	for _, c := range chunks {
+		n := c.n
+		var err error
+		if t.parts, err = t.appendParts(t.parts[:0], c); err != nil {
+			return err
+		}
+		if n.shader != s {
+			if n.layout != nil {
+				n.layout.Delete()
+			}
+			layout, err := gfx.LayoutGeometry(s, n.geom)
+			if err != nil {
+				n.layout, n.shader = nil, nil
+				return err
+			}
+			n.layout = layout
+			n.shader = s
+		}
+		if err := s.SetLayout(n.layout); err != nil {
+			return err
+		}
+		if err := s.DrawSlices(t.parts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendParts appends the parts of the index buffer of c that its stitching
+// needs, building its geometry if it has not been yet.
+func (t *Terrain) appendParts(dst []gfx.IndexBuffer, c Chunk) ([]gfx.IndexBuffer, error) {
+	n := c.n
+	if n.geom == nil {
+		if err := t.buildGeometry(n); err != nil {
+			return dst, err
+		}
+	}
+	dst = append(dst, n.parts[0])
+	for e := 0; e < 4; e++ {
+		if c.Stitched(e) {
+			dst = append(dst, n.parts[2+2*e])
+		} else {
+			dst = append(dst, n.parts[1+2*e])
+		}
+	}
+	return dst, nil
+}
+
+// buildGeometry builds the geometry of n, with the indices of every edge
+// both plain and stitched, and records where each part of them is.
+func (t *Terrain) buildGeometry(n *node) error {
+	b := geometry.NewBuilder(VertexFormat)
+	ci := t.buildNode(b, n)
+	parts := [numParts][]uint16{ci.interior}
+	for e, variants := range ci.edges {
+		parts[1+2*e], parts[2+2*e] = variants[0], variants[1]
+	}
+	var idxs []uint16
+	var ends [numParts]int
+	for i, p := range parts {
+		idxs = append(idxs, p...)
+		ends[i] = len(idxs)
+	}
+	// all at once, as Indices offsets each call past the last
+	b.Indices(idxs...)
+	geom, err := gfx.NewGeometry(b, gfx.StaticDraw)
+	if err != nil {
+		return err
+	}
+	n.geom = geom
+	start := 0
+	for i, end := range ends {
+		n.parts[i] = geom.IndexBuffer.Slice(start, end)
+		start = end
+	}
+	return nil
+}
+
+// Delete frees the geometry of every chunk built so far.
+func (t *Terrain) Delete() {
+	var del func(n *node)
+	del = func(n *node) {
+		if n == nil {
+			return
+		}
+		if n.layout != nil {
+			n.layout.Delete()
+			n.layout = nil
+		}
+		if n.geom != nil {
+			n.geom.Delete()
+			n.geom = nil
+		}
+		n.shader = nil
+		for _, c := range n.children {
+			del(c)
+		}
+	}
+	del(t.root)
+}
+
+// SplatMaterial holds uniforms for splat-mapped terrain: the red, green,
+// blue, and alpha channels of Splat weight four tiled detail layers.
+type SplatMaterial struct {
+	Splat  *gfx.Sampler2D `uniform:"Splat"`
+	Layer0 *gfx.Sampler2D `uniform:"Layer0"`
+	Layer1 *gfx.Sampler2D `uniform:"Layer1"`
+	Layer2 *gfx.Sampler2D `uniform:"Layer2"`
+	Layer3 *gfx.Sampler2D `uniform:"Layer3"`
+	Tiling float32        `uniform:"Tiling"`
+}
+
+// SplatFragmentShader blends the layers of a SplatMaterial. It expects uv
+// and normal varyings from the vertex shader.
+const SplatFragmentShader gfx.FragmentShader = `
+uniform sampler2D Splat;
+uniform sampler2D Layer0;
+uniform sampler2D Layer1;
+uniform sampler2D Layer2;
+uniform sampler2D Layer3;
+uniform float Tiling;
+
+varying vec2 uv;
+varying vec3 normal;
+
+void main() {
+	vec4 w = texture2D(Splat, uv);
+	vec2 tuv = uv * Tiling;
+	vec3 c = texture2D(Layer0, tuv).rgb * w.r +
+		texture2D(Layer1, tuv).rgb * w.g +
+		texture2D(Layer2, tuv).rgb * w.b +
+		texture2D(Layer3, tuv).rgb * w.a;
+	float light = 0.4 + 0.6 * clamp(dot(normalize(normal), vec3(0.0, 0.7, 0.7)), 0.0, 1.0);
+	gl_FragColor = vec4(c * light, 1.0);
+}`
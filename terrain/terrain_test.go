@@ -0,0 +1,186 @@
+package terrain_test
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/gfxtest"
+	"j4k.co/gfx/terrain"
+	"testing"
+)
+
+func TestSelectLOD(t *testing.T) {
+	hm := terrain.NewHeightmap(129, 129)
+	tr := mustNew(t, hm, terrain.Config{ChunkCells: 32, LODDistance: 1})
+
+	// far away, the root chunk covers everything
+	chunks := tr.Select(nil, [3]float32{1e6, 0, 1e6}, nil)
+	if len(chunks) != 1 || chunks[0].Step() != 4 {
+		t.Fatalf("far: got %d chunks, want 1 at step 4", len(chunks))
+	}
+
+	// standing in a corner refines nearby chunks only
+	chunks = tr.Select(nil, [3]float32{0, 0, 0}, nil)
+	full := 0
+	for _, c := range chunks {
+		if c.Step() == 1 {
+			full++
+		}
+	}
+	if full == 0 || full == 16 {
+		t.Fatalf("near: got %d full detail chunks of %d", full, len(chunks))
+	}
+}
+
+func TestSelectCulls(t *testing.T) {
+	hm := terrain.NewHeightmap(65, 65)
+	tr := mustNew(t, hm, terrain.Config{ChunkCells: 32})
+	// a frustum that only accepts x <= 10
+	f := gfx.Frustum{
+		{-1, 0, 0, 10},
+		{0, 1, 0, 1e6},
+		{0, -1, 0, 1e6},
+		{0, 0, 1, 1e6},
+		{0, 0, -1, 1e6},
+		{1, 0, 0, 1e6},
+	}
+	chunks := tr.Select(nil, [3]float32{0, 0, 0}, &f)
+	for _, c := range chunks {
+		if min, _ := c.Bounds(); min[0] > 10 {
+			t.Fatalf("chunk at x=%v not culled", min[0])
+		}
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(chunks))
+	}
+}
+
+func TestBuildChunk(t *testing.T) {
+	hm := terrain.NewHeightmap(33, 33)
+	tr := mustNew(t, hm, terrain.Config{ChunkCells: 32})
+	chunks := tr.Select(nil, [3]float32{}, nil)
+	b := geometry.NewBuilder(terrain.VertexFormat)
+	tr.BuildChunk(b, chunks[0])
+	if got, want := b.VertexCount(), 33*33+4*33; got != want {
+		t.Errorf("got %d vertices, want %d", got, want)
+	}
+	if got, want := b.IndexCount(), 32*32*6+4*32*6; got != want {
+		t.Errorf("got %d indices, want %d", got, want)
+	}
+}
+
+func TestSelectBudget(t *testing.T) {
+	hm := terrain.NewHeightmap(129, 129)
+	// each chunk is 2*32*32 triangles and 4*2*32 of skirts
+	const chunk = 2*32*32 + 8*32
+	tr := mustNew(t, hm, terrain.Config{ChunkCells: 32, LODDistance: 1, MaxTriangles: 7 * chunk})
+	chunks := tr.Select(nil, [3]float32{0, 0, 0}, nil)
+	// the root is split, then the quarter nearest the eye
+	if len(chunks) != 7 {
+		t.Fatalf("got %d chunks, want 7", len(chunks))
+	}
+	full := 0
+	for _, c := range chunks {
+		if c.Step() == 1 {
+			full++
+			if min, _ := c.Bounds(); min[0] >= 64 || min[2] >= 64 {
+				t.Errorf("split a chunk at %v, away from the eye", min)
+			}
+		}
+	}
+	if full != 4 {
+		t.Errorf("got %d full detail chunks, want 4", full)
+	}
+
+	tr = mustNew(t, hm, terrain.Config{ChunkCells: 32, LODDistance: 1, MaxTriangles: 1})
+	if chunks := tr.Select(nil, [3]float32{0, 0, 0}, nil); len(chunks) != 1 {
+		t.Errorf("got %d chunks, want the root only", len(chunks))
+	}
+}
+
+func TestStitch(t *testing.T) {
+	hm := terrain.NewHeightmap(129, 129)
+	tr := mustNew(t, hm, terrain.Config{ChunkCells: 32, LODDistance: 1, MaxTriangles: 7 * (2*32*32 + 8*32)})
+	chunks := tr.Select(nil, [3]float32{0, 0, 0}, nil)
+	stitched := 0
+	for _, c := range chunks {
+		n := 0
+		for e := terrain.EdgeMinZ; e <= terrain.EdgeMinX; e++ {
+			if c.Stitched(e) {
+				n++
+			}
+		}
+		if n > 0 && c.Step() != 1 {
+			t.Errorf("chunk of step %d stitched to a coarser one", c.Step())
+		}
+		stitched += n
+		// a stitched edge and its skirt take 78 triangles in place of 126
+		b := geometry.NewBuilder(terrain.VertexFormat)
+		tr.BuildChunk(b, c)
+		if got, want := b.IndexCount(), 32*32*6+4*32*6-n*48*3; got != want {
+			t.Errorf("got %d indices with %d stitched edges, want %d", got, n, want)
+		}
+	}
+	// the full detail quarter meets the coarse ones along two edges of two
+	// chunks each
+	if stitched != 4 {
+		t.Errorf("got %d stitched edges, want 4", stitched)
+	}
+
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+	s, err := gfx.BuildShader(gfx.VertexAttributes{
+		gfx.VertexPosition: "Position",
+		gfx.VertexNormal:   "Normal",
+		gfx.VertexTexcoord: "Texcoord",
+	}, gfx.VertexShader(`
+attribute vec3 Position;
+
+void main() {
+	gl_Position = vec4(Position, 1.0);
+}`), gfx.FragmentShader(`
+void main() {
+	gl_FragColor = vec4(1.0);
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	defer tr.Delete()
+	s.Use()
+	rec.Reset()
+	if err := tr.Draw(s, chunks); err != nil {
+		t.Fatal(err)
+	}
+	draws := rec.Draws()
+	if len(draws) != len(chunks) {
+		t.Fatalf("got %d draws, want one per chunk", len(draws))
+	}
+	for _, d := range draws {
+		if d.Op != "MultiDrawElements" {
+			t.Errorf("got %v, want MultiDrawElements", d)
+		}
+	}
+}
+
+func TestNewChunkCells(t *testing.T) {
+	hm := terrain.NewHeightmap(33, 33)
+	for _, cells := range []int{-1, 3, 48, 2 * terrain.MaxChunkCells} {
+		if _, err := terrain.New(hm, terrain.Config{ChunkCells: cells}); err == nil {
+			t.Errorf("ChunkCells %d was accepted", cells)
+		}
+	}
+	for _, cells := range []int{0, 1, terrain.MaxChunkCells} {
+		if _, err := terrain.New(hm, terrain.Config{ChunkCells: cells}); err != nil {
+			t.Errorf("ChunkCells %d: %v", cells, err)
+		}
+	}
+}
+
+func mustNew(t *testing.T, hm *terrain.Heightmap, conf terrain.Config) *terrain.Terrain {
+	t.Helper()
+	tr, err := terrain.New(hm, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
@@ -0,0 +1,167 @@
+package texfile
+
+import (
+	"encoding/binary"
+	"j4k.co/gfx"
+)
+
+// DDS header flags.
+const (
+	ddsdMipmapCount = 0x20000
+
+	ddpfAlphaPixels = 0x1
+	ddpfFourCC      = 0x4
+	ddpfRGB         = 0x40
+	ddpfLuminance   = 0x20000
+
+	ddsCaps2Cubemap     = 0x200
+	ddsCaps2AllFaces    = 0xFC00
+	ddsCaps2Volume      = 0x200000
+	ddsResourceMiscCube = 0x4
+	ddsDimTexture2D     = 3
+
+	ddsHeaderBytes  = 4 + 124
+	dx10HeaderBytes = 20
+)
+
+func fourCC(s string) uint32 {
+	return binary.LittleEndian.Uint32([]byte(s))
+}
+
+// DXGI formats found in DDS files with a DX10 header.
+const (
+	dxgiRGBA32Float = 2
+	dxgiRGBA8Unorm  = 28
+	dxgiRGBA8SRGB   = 29
+	dxgiR8Unorm     = 61
+	dxgiBC1Unorm    = 71
+	dxgiBC2Unorm    = 74
+	dxgiBC3Unorm    = 77
+	dxgiBC4Unorm    = 80
+	dxgiBC5Unorm    = 83
+	dxgiBC7Unorm    = 98
+)
+
+func dxgiFormat(f uint32) (gfx.PixelFormat, bool) {
+	switch f {
+	case dxgiRGBA32Float:
+		return gfx.PixelRGBA32F, true
+	case dxgiRGBA8Unorm:
+		return gfx.PixelRGBA8, true
+	case dxgiRGBA8SRGB:
+		return gfx.PixelSRGBA8, true
+	case dxgiR8Unorm:
+		return gfx.PixelR8, true
+	case dxgiBC1Unorm:
+		return gfx.PixelBC1, true
+	case dxgiBC2Unorm:
+		return gfx.PixelBC2, true
+	case dxgiBC3Unorm:
+		return gfx.PixelBC3, true
+	case dxgiBC4Unorm:
+		return gfx.PixelBC4, true
+	case dxgiBC5Unorm:
+		return gfx.PixelBC5, true
+	case dxgiBC7Unorm:
+		return gfx.PixelBC7, true
+	}
+	return 0, false
+}
+
+// ddsPixelFormat maps the legacy pixel format of a DDS header.
+func ddsPixelFormat(pf []byte) (gfx.PixelFormat, bool) {
+	le := binary.LittleEndian
+	flags, cc, bits := le.Uint32(pf[4:]), le.Uint32(pf[8:]), le.Uint32(pf[12:])
+	r, g, b, a := le.Uint32(pf[16:]), le.Uint32(pf[20:]), le.Uint32(pf[24:]), le.Uint32(pf[28:])
+	switch {
+	case flags&ddpfFourCC != 0:
+		switch cc {
+		case fourCC("DXT1"):
+			return gfx.PixelBC1, true
+		case fourCC("DXT3"):
+			return gfx.PixelBC2, true
+		case fourCC("DXT5"):
+			return gfx.PixelBC3, true
+		case fourCC("ATI1"), fourCC("BC4U"):
+			return gfx.PixelBC4, true
+		case fourCC("ATI2"), fourCC("BC5U"):
+			return gfx.PixelBC5, true
+		}
+	case flags&ddpfRGB != 0 && flags&ddpfAlphaPixels != 0:
+		if bits == 32 && r == 0xff && g == 0xff00 && b == 0xff0000 && a == 0xff000000 {
+			return gfx.PixelRGBA8, true
+		}
+	case flags&ddpfLuminance != 0 || flags&ddpfRGB != 0:
+		if bits == 8 && r == 0xff {
+			return gfx.PixelR8, true
+		}
+	}
+	return 0, false
+}
+
+// decodeDDS decodes a DDS file, whose images are stored for each layer, for
+// each face, for each level.
+func decodeDDS(b []byte) (*Texture, error) {
+	hdr, err := slice(b, 0, ddsHeaderBytes)
+	if err != nil {
+		return nil, err
+	}
+	le := binary.LittleEndian
+	hdr = hdr[4:]
+	flags := le.Uint32(hdr[4:])
+	height, width := int(le.Uint32(hdr[8:])), int(le.Uint32(hdr[12:]))
+	levels := 1
+	if flags&ddsdMipmapCount != 0 {
+		levels = int(le.Uint32(hdr[24:]))
+	}
+	pf := hdr[72:104]
+	caps2 := le.Uint32(hdr[108:])
+	if caps2&ddsCaps2Volume != 0 {
+		return nil, ErrUnsupported
+	}
+	faces := 1
+	if caps2&ddsCaps2Cubemap != 0 {
+		if caps2&ddsCaps2AllFaces != ddsCaps2AllFaces {
+			return nil, ErrUnsupported
+		}
+		faces = 6
+	}
+	layers := 1
+	off := ddsHeaderBytes
+	format, ok := ddsPixelFormat(pf)
+	if le.Uint32(pf[4:])&ddpfFourCC != 0 && le.Uint32(pf[8:]) == fourCC("DX10") {
+		dx10, err := slice(b, off, dx10HeaderBytes)
+		if err != nil {
+			return nil, err
+		}
+		off += dx10HeaderBytes
+		format, ok = dxgiFormat(le.Uint32(dx10))
+		if le.Uint32(dx10[4:]) != ddsDimTexture2D {
+			return nil, ErrUnsupported
+		}
+		if le.Uint32(dx10[8:])&ddsResourceMiscCube != 0 {
+			faces = 6
+		}
+		layers = int(le.Uint32(dx10[12:]))
+	}
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	t, err := newTexture(format, width, height, levels, layers, faces, len(b)-off)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < t.Layers*t.Faces; i++ {
+		for level := 0; level < t.Levels; level++ {
+			w, h := t.LevelSize(level)
+			n := format.DataSize(w, h)
+			img, err := slice(b, off, n)
+			if err != nil {
+				return nil, err
+			}
+			t.images[t.index(level, i/t.Faces, i%t.Faces)] = img
+			off += n
+		}
+	}
+	return t, nil
+}
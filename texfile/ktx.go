@@ -0,0 +1,221 @@
+package texfile
+
+import (
+	"encoding/binary"
+	"j4k.co/gfx"
+)
+
+// GL formats and types found in KTX files.
+const (
+	glUnsignedByte = 0x1401
+	glFloat        = 0x1406
+
+	glR8          = 0x8229
+	glRGBA8       = 0x8058
+	glSRGB8Alpha8 = 0x8C43
+	glRGBA32F     = 0x8814
+	glRGBDXT1     = 0x83F0
+	glRGBADXT1    = 0x83F1
+	glRGBADXT3    = 0x83F2
+	glRGBADXT5    = 0x83F3
+	glRedRGTC1    = 0x8DBB
+	glRGRGTC2     = 0x8DBD
+	glRGBABPTC    = 0x8E8C
+)
+
+// ktxFormat maps the internal format and type of a KTX file.
+func ktxFormat(internalFormat, typ uint32) (gfx.PixelFormat, bool) {
+	switch internalFormat {
+	case glR8:
+		return gfx.PixelR8, typ == glUnsignedByte
+	case glRGBA8:
+		return gfx.PixelRGBA8, typ == glUnsignedByte
+	case glSRGB8Alpha8:
+		return gfx.PixelSRGBA8, typ == glUnsignedByte
+	case glRGBA32F:
+		return gfx.PixelRGBA32F, typ == glFloat
+	case glRGBDXT1, glRGBADXT1:
+		return gfx.PixelBC1, true
+	case glRGBADXT3:
+		return gfx.PixelBC2, true
+	case glRGBADXT5:
+		return gfx.PixelBC3, true
+	case glRedRGTC1:
+		return gfx.PixelBC4, true
+	case glRGRGTC2:
+		return gfx.PixelBC5, true
+	case glRGBABPTC:
+		return gfx.PixelBC7, true
+	}
+	return 0, false
+}
+
+// decodeKTX decodes a KTX 1.1 file, whose header is followed by key/value
+// data and then, for each level, its size and images.
+func decodeKTX(b []byte) (*Texture, error) {
+	hdr, err := slice(b, len(ktx1Magic), 13*4)
+	if err != nil {
+		return nil, err
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(hdr) != 0x04030201 {
+		order = binary.BigEndian
+	}
+	field := func(i int) uint32 {
+		return order.Uint32(hdr[4*i:])
+	}
+	typ, internalFormat := field(1), field(4)
+	width, height, depth := int(field(6)), int(field(7)), int(field(8))
+	layers, faces, levels := int(field(9)), int(field(10)), int(field(11))
+	kvlen := int(field(12))
+
+	format, ok := ktxFormat(internalFormat, typ)
+	if !ok || depth > 0 || (faces != 1 && faces != 6) {
+		return nil, ErrUnsupported
+	}
+	if height == 0 {
+		height = 1
+	}
+	cube := faces == 6 && layers == 0
+	off := len(ktx1Magic) + len(hdr) + kvlen
+	t, err := newTexture(format, width, height, levels, layers, faces, len(b)-off)
+	if err != nil {
+		return nil, err
+	}
+	for level := 0; level < t.Levels; level++ {
+		sizeb, err := slice(b, off, 4)
+		if err != nil {
+			return nil, err
+		}
+		size := int(order.Uint32(sizeb))
+		off += 4
+		w, h := t.LevelSize(level)
+		n := format.DataSize(w, h)
+		// rows of uncompressed images are padded to 4 bytes, which keeps
+		// images, and so the faces and levels, 4 byte aligned
+		rowBytes, rows := n/h, h
+		if format.IsCompressed() {
+			rowBytes, rows = n, 1
+		}
+		stride := (rowBytes + 3) &^ 3
+		for i := 0; i < t.Layers*t.Faces; i++ {
+			img, err := slice(b, off, stride*rows)
+			if err != nil {
+				return nil, err
+			}
+			t.images[t.index(level, i/t.Faces, i%t.Faces)] = unpad(img, rowBytes, stride, rows)
+			off += stride * rows
+		}
+		// the size is of one face for cube maps, and of the whole level
+		// otherwise
+		want := stride * rows
+		if !cube {
+			want *= t.Layers * t.Faces
+		}
+		if size != want {
+			return nil, ErrUnsupported
+		}
+	}
+	return t, nil
+}
+
+// unpad removes the padding from the ends of rows.
+func unpad(img []byte, rowBytes, stride, rows int) []byte {
+	if rowBytes == stride {
+		return img
+	}
+	out := make([]byte, 0, rowBytes*rows)
+	for y := 0; y < rows; y++ {
+		out = append(out, img[y*stride:y*stride+rowBytes]...)
+	}
+	return out
+}
+
+// Vulkan formats found in KTX2 files.
+const (
+	vkR8Unorm       = 9
+	vkRGBA8Unorm    = 37
+	vkRGBA8SRGB     = 43
+	vkRGBA32Sfloat  = 109
+	vkBC1RGBUnorm   = 131
+	vkBC1RGBAUnorm  = 133
+	vkBC2Unorm      = 135
+	vkBC3Unorm      = 137
+	vkBC4Unorm      = 139
+	vkBC5Unorm      = 141
+	vkBC7Unorm      = 145
+	ktx2HeaderBytes = 80
+)
+
+func vkFormat(f uint32) (gfx.PixelFormat, bool) {
+	switch f {
+	case vkR8Unorm:
+		return gfx.PixelR8, true
+	case vkRGBA8Unorm:
+		return gfx.PixelRGBA8, true
+	case vkRGBA8SRGB:
+		return gfx.PixelSRGBA8, true
+	case vkRGBA32Sfloat:
+		return gfx.PixelRGBA32F, true
+	case vkBC1RGBUnorm, vkBC1RGBAUnorm:
+		return gfx.PixelBC1, true
+	case vkBC2Unorm:
+		return gfx.PixelBC2, true
+	case vkBC3Unorm:
+		return gfx.PixelBC3, true
+	case vkBC4Unorm:
+		return gfx.PixelBC4, true
+	case vkBC5Unorm:
+		return gfx.PixelBC5, true
+	case vkBC7Unorm:
+		return gfx.PixelBC7, true
+	}
+	return 0, false
+}
+
+// decodeKTX2 decodes a KTX 2.0 file, whose header is followed by an index
+// giving the offset of each level.
+func decodeKTX2(b []byte) (*Texture, error) {
+	hdr, err := slice(b, 0, ktx2HeaderBytes)
+	if err != nil {
+		return nil, err
+	}
+	le := binary.LittleEndian
+	field := func(i int) uint32 {
+		return le.Uint32(hdr[len(ktx2Magic)+4*i:])
+	}
+	width, height, depth := int(field(2)), int(field(3)), int(field(4))
+	layers, faces, levels := int(field(5)), int(field(6)), int(field(7))
+	format, ok := vkFormat(field(0))
+	if !ok || field(8) != 0 || depth > 0 || (faces != 1 && faces != 6) {
+		return nil, ErrUnsupported
+	}
+	if height == 0 {
+		height = 1
+	}
+	t, err := newTexture(format, width, height, levels, layers, faces, len(b)-ktx2HeaderBytes)
+	if err != nil {
+		return nil, err
+	}
+	index, err := slice(b, ktx2HeaderBytes, t.Levels*24)
+	if err != nil {
+		return nil, err
+	}
+	for level := 0; level < t.Levels; level++ {
+		off := int(le.Uint64(index[level*24:]))
+		length := int(le.Uint64(index[level*24+8:]))
+		w, h := t.LevelSize(level)
+		n := format.DataSize(w, h)
+		if length != n*t.Layers*t.Faces {
+			return nil, ErrUnsupported
+		}
+		data, err := slice(b, off, length)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < t.Layers*t.Faces; i++ {
+			t.images[t.index(level, i/t.Faces, i%t.Faces)] = data[i*n : (i+1)*n]
+		}
+	}
+	return t, nil
+}
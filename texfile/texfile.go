@@ -0,0 +1,163 @@
+/*
+Package texfile decodes KTX, KTX2, and DDS texture containers, including
+their mipmap levels, cube map faces, and array layers, and uploads them as
+gfx textures.
+
+Only formats with a matching gfx.PixelFormat are supported: RGBA8, sRGB
+RGBA8, R8, RGBA32F, and the BC1 through BC5 and BC7 block compressed
+formats. Volume textures and KTX2 supercompression are not supported.
+*/
+package texfile
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"j4k.co/gfx"
+	"math/bits"
+)
+
+// ErrFormat is returned for data that is not a known texture container.
+var ErrFormat = errors.New("texfile: unknown container format")
+
+// ErrUnsupported is returned for textures using features or pixel formats
+// that are not supported.
+var ErrUnsupported = errors.New("texfile: unsupported texture")
+
+// ErrNot2D is returned when uploading a cube map or array texture as a
+// Sampler2D.
+var ErrNot2D = errors.New("texfile: texture is not a single 2D texture")
+
+// ErrNotCube is returned when uploading a 2D or array texture, or a cube map
+// with faces that are not square, as a SamplerCube.
+var ErrNotCube = errors.New("texfile: texture is not a single cube map")
+
+var (
+	ktx1Magic = []byte{0xAB, 'K', 'T', 'X', ' ', '1', '1', 0xBB, '\r', '\n', 0x1A, '\n'}
+	ktx2Magic = []byte{0xAB, 'K', 'T', 'X', ' ', '2', '0', 0xBB, '\r', '\n', 0x1A, '\n'}
+	ddsMagic  = []byte("DDS ")
+)
+
+// Texture is a decoded texture. Its images are stored for each mipmap
+// level, for each array layer, for each cube map face, in the order GL
+// expects for the faces: +X, -X, +Y, -Y, +Z, -Z.
+type Texture struct {
+	Format        gfx.PixelFormat
+	Width, Height int
+
+	// Levels is the number of mipmap levels, Layers the number of array
+	// layers, and Faces is 6 for cube maps and 1 otherwise.
+	Levels, Layers, Faces int
+
+	images [][]byte
+}
+
+// Decode reads a KTX, KTX2, or DDS file.
+func Decode(r io.Reader) (*Texture, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(b, ktx1Magic):
+		return decodeKTX(b)
+	case bytes.HasPrefix(b, ktx2Magic):
+		return decodeKTX2(b)
+	case bytes.HasPrefix(b, ddsMagic):
+		return decodeDDS(b)
+	default:
+		return nil, ErrFormat
+	}
+}
+
+// maxSize bounds the width and height of decoded textures.
+const maxSize = 1 << 16
+
+// newTexture checks the dimensions read from a header and allocates a
+// texture for them. Every image takes at least a byte, so a texture with more
+// images than the remain bytes left in the file is truncated.
+func newTexture(format gfx.PixelFormat, width, height, levels, layers, faces, remain int) (*Texture, error) {
+	if levels < 1 {
+		levels = 1
+	}
+	if layers < 1 {
+		layers = 1
+	}
+	if width < 1 || height < 1 || width > maxSize || height > maxSize {
+		return nil, ErrUnsupported
+	}
+	if levels > bits.Len(uint(width|height)) {
+		return nil, ErrUnsupported
+	}
+	if layers > remain || levels*layers*faces > remain {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return &Texture{
+		Format: format,
+		Width:  width,
+		Height: height,
+		Levels: levels,
+		Layers: layers,
+		Faces:  faces,
+		images: make([][]byte, levels*layers*faces),
+	}, nil
+}
+
+// Image returns the pixel data of a level of a layer and face.
+func (t *Texture) Image(level, layer, face int) []byte {
+	return t.images[t.index(level, layer, face)]
+}
+
+func (t *Texture) index(level, layer, face int) int {
+	return (level*t.Layers+layer)*t.Faces + face
+}
+
+// LevelSize returns the size of a mipmap level.
+func (t *Texture) LevelSize(level int) (width, height int) {
+	width, height = t.Width>>uint(level), t.Height>>uint(level)
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	return width, height
+}
+
+// Sampler2D uploads the texture with all its levels. It returns ErrNot2D
+// for cube maps and array textures.
+func (t *Texture) Sampler2D(opts *gfx.SamplerOptions) (*gfx.Sampler2D, error) {
+	if t.Layers != 1 || t.Faces != 1 {
+		return nil, ErrNot2D
+	}
+	levels := make([][]byte, t.Levels)
+	for i := range levels {
+		levels[i] = t.Image(i, 0, 0)
+	}
+	return gfx.NewSampler2DLevels(levels, t.Width, t.Height, t.Format, opts)
+}
+
+// SamplerCube uploads a cube map with all its levels. It returns ErrNotCube
+// for other textures.
+func (t *Texture) SamplerCube() (*gfx.SamplerCube, error) {
+	if t.Layers != 1 || t.Faces != gfx.CubeFaces || t.Width != t.Height {
+		return nil, ErrNotCube
+	}
+	var faces [gfx.CubeFaces][][]byte
+	for face := range faces {
+		faces[face] = make([][]byte, t.Levels)
+		for i := range faces[face] {
+			faces[face][i] = t.Image(i, 0, face)
+		}
+	}
+	return gfx.NewSamplerCubeLevels(faces, t.Width, t.Format)
+}
+
+// slice returns n bytes of b at off, or an error if b is too short.
+func slice(b []byte, off, n int) ([]byte, error) {
+	if off < 0 || n < 0 || off+n > len(b) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return b[off : off+n], nil
+}
@@ -0,0 +1,216 @@
+package texfile_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"j4k.co/gfx/texfile"
+	"testing"
+)
+
+func put(buf *bytes.Buffer, v ...interface{}) {
+	for _, v := range v {
+		binary.Write(buf, binary.LittleEndian, v)
+	}
+}
+
+func fill(n int, b byte) []byte {
+	return bytes.Repeat([]byte{b}, n)
+}
+
+func TestKTX(t *testing.T) {
+	// a 3x2 R8 texture with two levels, whose rows are padded
+	var buf bytes.Buffer
+	buf.Write([]byte{0xAB, 'K', 'T', 'X', ' ', '1', '1', 0xBB, '\r', '\n', 0x1A, '\n'})
+	put(&buf, uint32(0x04030201),
+		uint32(0x1401), uint32(1), uint32(0x1903), uint32(0x8229), uint32(0x1903),
+		uint32(3), uint32(2), uint32(0), uint32(0), uint32(1), uint32(2), uint32(4))
+	put(&buf, uint32(0))
+	put(&buf, uint32(8), []byte{1, 2, 3, 0, 4, 5, 6, 0})
+	put(&buf, uint32(4), []byte{7, 0, 0, 0})
+
+	tex, err := texfile.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tex.SamplerCube(); err != texfile.ErrNotCube {
+		t.Errorf("SamplerCube of 2D texture: got %v, want ErrNotCube", err)
+	}
+	if tex.Format != gfx.PixelR8 || tex.Width != 3 || tex.Height != 2 || tex.Levels != 2 {
+		t.Fatalf("got %v %dx%d with %d levels", tex.Format, tex.Width, tex.Height, tex.Levels)
+	}
+	if got := tex.Image(0, 0, 0); !bytes.Equal(got, []byte{1, 2, 3, 4, 5, 6}) {
+		t.Errorf("level 0: got %v", got)
+	}
+	if got := tex.Image(1, 0, 0); !bytes.Equal(got, []byte{7}) {
+		t.Errorf("level 1: got %v", got)
+	}
+}
+
+func TestKTX2Cube(t *testing.T) {
+	// a 4x4 BC1 cube map
+	var buf bytes.Buffer
+	buf.Write([]byte{0xAB, 'K', 'T', 'X', ' ', '2', '0', 0xBB, '\r', '\n', 0x1A, '\n'})
+	put(&buf, uint32(133), uint32(1), uint32(4), uint32(4), uint32(0),
+		uint32(0), uint32(6), uint32(1), uint32(0))
+	put(&buf, uint32(0), uint32(0), uint32(0), uint32(0), uint64(0), uint64(0))
+	put(&buf, uint64(104), uint64(48), uint64(48))
+	for face := 0; face < 6; face++ {
+		buf.Write(fill(8, byte(face)))
+	}
+
+	tex, err := texfile.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tex.Format != gfx.PixelBC1 || tex.Faces != 6 || tex.Layers != 1 || tex.Levels != 1 {
+		t.Fatalf("got %v with %d faces, %d layers, %d levels", tex.Format, tex.Faces, tex.Layers, tex.Levels)
+	}
+	for face := 0; face < 6; face++ {
+		if got := tex.Image(0, 0, face); !bytes.Equal(got, fill(8, byte(face))) {
+			t.Errorf("face %d: got %v", face, got)
+		}
+	}
+	if _, err := tex.Sampler2D(nil); err != texfile.ErrNot2D {
+		t.Errorf("Sampler2D of cube map: got %v, want ErrNot2D", err)
+	}
+
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+	cube, err := tex.SamplerCube()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cube.Delete()
+	var uploads []string
+	for _, c := range rec.Ops("CompressedTexImage2D", "TexImage2D") {
+		uploads = append(uploads, c.String())
+	}
+	var want []string
+	for _, face := range []string{"POSITIVE_X", "NEGATIVE_X", "POSITIVE_Y", "NEGATIVE_Y", "POSITIVE_Z", "NEGATIVE_Z"} {
+		want = append(want, "CompressedTexImage2D(TEXTURE_CUBE_MAP_"+face+", 0, COMPRESSED_RGBA_S3TC_DXT1_EXT, 4, 4, 0, 8, [8 bytes])")
+	}
+	if fmt.Sprint(uploads) != fmt.Sprint(want) {
+		t.Errorf("got uploads %v,\nwant %v", uploads, want)
+	}
+}
+
+func ddsHeader(buf *bytes.Buffer, width, height, levels int, fourcc string) {
+	buf.WriteString("DDS ")
+	put(buf, uint32(124), uint32(0x1|0x2|0x4|0x1000|0x20000), uint32(height), uint32(width),
+		uint32(0), uint32(0), uint32(levels))
+	put(buf, [11]uint32{})
+	put(buf, uint32(32), uint32(0x4), []byte(fourcc), [5]uint32{})
+	put(buf, uint32(0x1000), uint32(0), uint32(0), uint32(0), uint32(0))
+}
+
+func TestDDS(t *testing.T) {
+	// an 8x8 DXT5 texture with levels down to 1x1
+	var buf bytes.Buffer
+	ddsHeader(&buf, 8, 8, 4, "DXT5")
+	buf.Write(fill(4*16, 1))
+	buf.Write(fill(16, 2))
+	buf.Write(fill(16, 3))
+	buf.Write(fill(16, 4))
+
+	tex, err := texfile.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tex.Format != gfx.PixelBC3 || tex.Levels != 4 {
+		t.Fatalf("got %v with %d levels", tex.Format, tex.Levels)
+	}
+	for level, want := range []int{64, 16, 16, 16} {
+		if got := tex.Image(level, 0, 0); len(got) != want || got[0] != byte(level+1) {
+			t.Errorf("level %d: got %d bytes starting with %d", level, len(got), got[0])
+		}
+	}
+}
+
+func TestDDSArray(t *testing.T) {
+	// a 4x4 BC7 array of 2 layers with 2 levels each
+	var buf bytes.Buffer
+	ddsHeader(&buf, 4, 4, 2, "DX10")
+	put(&buf, uint32(98), uint32(3), uint32(0), uint32(2), uint32(0))
+	for i := 1; i <= 4; i++ {
+		buf.Write(fill(16, byte(i)))
+	}
+
+	tex, err := texfile.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tex.Format != gfx.PixelBC7 || tex.Layers != 2 || tex.Levels != 2 {
+		t.Fatalf("got %v with %d layers, %d levels", tex.Format, tex.Layers, tex.Levels)
+	}
+	if tex.Image(1, 0, 0)[0] != 2 || tex.Image(0, 1, 0)[0] != 3 {
+		t.Errorf("images out of order")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	if _, err := texfile.Decode(bytes.NewReader([]byte("not a texture"))); err != texfile.ErrFormat {
+		t.Errorf("got %v, want ErrFormat", err)
+	}
+	var buf bytes.Buffer
+	ddsHeader(&buf, 8, 8, 1, "DXT5")
+	buf.Write(fill(10, 0))
+	if _, err := texfile.Decode(&buf); err == nil {
+		t.Error("truncated file decoded without error")
+	}
+	buf.Reset()
+	ddsHeader(&buf, 8, 8, 1, "ETC2")
+	if _, err := texfile.Decode(&buf); err != texfile.ErrUnsupported {
+		t.Errorf("got %v, want ErrUnsupported", err)
+	}
+}
+
+func TestMalformedHeaders(t *testing.T) {
+	ktx2 := func(width, height, layers, faces, levels uint32) []byte {
+		var buf bytes.Buffer
+		buf.Write([]byte{0xAB, 'K', 'T', 'X', ' ', '2', '0', 0xBB, '\r', '\n', 0x1A, '\n'})
+		put(&buf, uint32(37), uint32(1), width, height, uint32(0), layers, faces, levels, uint32(0))
+		put(&buf, [4]uint32{}, [2]uint64{})
+		buf.Write(fill(24, 0))
+		return buf.Bytes()
+	}
+	ktx := func(width, height, layers, faces, levels uint32) []byte {
+		var buf bytes.Buffer
+		buf.Write([]byte{0xAB, 'K', 'T', 'X', ' ', '1', '1', 0xBB, '\r', '\n', 0x1A, '\n'})
+		put(&buf, uint32(0x04030201),
+			uint32(0x1401), uint32(1), uint32(0x1903), uint32(0x8229), uint32(0x1903),
+			width, height, uint32(0), layers, faces, levels, uint32(0))
+		put(&buf, uint32(4), fill(4, 0))
+		return buf.Bytes()
+	}
+	dds := func(width, height, levels, layers uint32) []byte {
+		var buf bytes.Buffer
+		ddsHeader(&buf, int(width), int(height), int(levels), "DX10")
+		put(&buf, uint32(98), uint32(3), uint32(0), layers, uint32(0))
+		buf.Write(fill(16, 0))
+		return buf.Bytes()
+	}
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"ktx2 levels", ktx2(1, 1, 0, 1, 0xffffffff), texfile.ErrUnsupported},
+		{"ktx2 layers", ktx2(1, 1, 0xffffffff, 1, 1), io.ErrUnexpectedEOF},
+		{"ktx2 width", ktx2(0xffffffff, 1, 0, 1, 1), texfile.ErrUnsupported},
+		{"ktx2 zero width", ktx2(0, 1, 0, 1, 1), texfile.ErrUnsupported},
+		{"ktx levels", ktx(1, 1, 0, 1, 0xffffffff), texfile.ErrUnsupported},
+		{"ktx layers", ktx(1, 1, 0xfffffff, 1, 1), io.ErrUnexpectedEOF},
+		{"ktx height", ktx(1, 0x7fffffff, 0, 1, 1), texfile.ErrUnsupported},
+		{"dds levels", dds(4, 4, 0xffffffff, 1), texfile.ErrUnsupported},
+		{"dds layers", dds(4, 4, 1, 0xffffffff), io.ErrUnexpectedEOF},
+		{"dds size", dds(0x80000000, 0x80000000, 1, 1), texfile.ErrUnsupported},
+	} {
+		if _, err := texfile.Decode(bytes.NewReader(tc.data)); err != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+/*
+Package text draws strings with fonts rasterized into a texture atlas.
+Glyphs come from any golang.org/x/image/font.Face, such as one loaded with
+golang.org/x/image/font/opentype, and are added to the atlas as they are
+first used.
+*/
+package text
@@ -0,0 +1,193 @@
+package text
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+)
+
+var attrs = gfx.VertexAttributes{
+	gfx.VertexPosition: "Position",
+	gfx.VertexTexcoord: "UV",
+}
+
+// VertexShader and FragmentShader are the stock text shaders. They draw
+// text in the Color uniform, with coverage from the Atlas uniform.
+const VertexShader gfx.VertexShader = `
+uniform mat4 ProjectionM;
+
+attribute vec3 Position;
+attribute vec2 UV;
+
+varying vec2 uv;
+
+void main() {
+	uv = UV;
+	gl_Position = ProjectionM * vec4(Position, 1.0);
+}`
+
+const FragmentShader gfx.FragmentShader = `
+uniform sampler2D Atlas;
+uniform vec4 Color;
+
+varying vec2 uv;
+
+void main() {
+	gl_FragColor = vec4(Color.rgb, Color.a * texture2D(Atlas, uv).r);
+}`
+
+// SDFFragmentShader draws text from a signed distance field atlas (see
+// NewSDFFont) with the uniforms of Effects as well as Uniforms. It requires
+// derivatives, which desktop GLSL always has.
+const SDFFragmentShader gfx.FragmentShader = `
+uniform sampler2D Atlas;
+uniform vec4 Color;
+uniform vec4 OutlineColor;
+uniform float OutlineWidth;
+uniform vec4 GlowColor;
+uniform float GlowWidth;
+
+varying vec2 uv;
+
+void main() {
+	float d = texture2D(Atlas, uv).r;
+	float aa = fwidth(d);
+	float edge = 0.5 - OutlineWidth;
+	float fill = smoothstep(0.5 - aa, 0.5 + aa, d);
+	vec4 fg = mix(OutlineColor, Color, fill);
+	fg.a *= smoothstep(edge - aa, edge + aa, d);
+	float glow = GlowColor.a * smoothstep(edge - GlowWidth - aa, edge, d);
+	float a = fg.a + glow * (1.0 - fg.a);
+	vec3 rgb = mix(GlowColor.rgb, fg.rgb, a > 0.0 ? fg.a / a : 0.0);
+	gl_FragColor = vec4(rgb, a);
+}`
+
+// Uniforms are the uniforms of the stock shaders.
+type Uniforms struct {
+	Projection [16]float32    `uniform:"ProjectionM"`
+	Color      [4]float32     `uniform:"Color"`
+	Atlas      *gfx.Sampler2D `uniform:"Atlas"`
+}
+
+// Effects are the extra uniforms of SDFFragmentShader. Widths are in units
+// of the distance field, where 0.5 spans the spread of the font.
+type Effects struct {
+	OutlineColor [4]float32 `uniform:"OutlineColor"`
+	OutlineWidth float32    `uniform:"OutlineWidth"`
+	GlowColor    [4]float32 `uniform:"GlowColor"`
+	GlowWidth    float32    `uniform:"GlowWidth"`
+}
+
+// Drawer draws strings with the stock shaders, rebuilding their geometry on
+// every call, which suits text that changes often. Blending is left to the
+// caller, who typically enables alpha blending.
+type Drawer struct {
+	// Projection and Color are used by subsequent draws. Projection
+	// defaults to Ortho of a 1x1 viewport.
+	Uniforms
+
+	// Effects are used by drawers from NewSDFDrawer.
+	Effects
+
+	sdf     bool
+	shader  *gfx.Shader
+	builder *geometry.Builder
+	geom    *gfx.Geometry
+	layout  *gfx.GeometryLayout
+}
+
+// NewDrawer builds the stock shaders and returns a drawer drawing white
+// text.
+func NewDrawer() (*Drawer, error) {
+	return newDrawer(FragmentShader, false)
+}
+
+// NewSDFDrawer returns a drawer for fonts with signed distance field
+// atlases, drawing white text without an outline or glow.
+func NewSDFDrawer() (*Drawer, error) {
+	return newDrawer(SDFFragmentShader, true)
+}
+
+func newDrawer(fs gfx.FragmentShader, sdf bool) (*Drawer, error) {
+	shader, err := gfx.BuildShader(attrs, VertexShader, fs)
+	if err != nil {
+		return nil, err
+	}
+	d := &Drawer{
+		sdf:     sdf,
+		shader:  shader,
+		builder: geometry.NewBuilder(VertexFormat),
+	}
+	d.Projection = Ortho(1, 1)
+	d.Color = [4]float32{1, 1, 1, 1}
+	return d, nil
+}
+
+// Delete frees the shaders and geometry of the drawer.
+func (d *Drawer) Delete() {
+	if d.layout != nil {
+		d.layout.Delete()
+		d.geom.Delete()
+	}
+	d.shader.Delete()
+}
+
+// Draw draws s with its top left corner at x, y. Lines wrap at maxWidth if
+// it is positive, as with Font.Build. The font must have a signed distance
+// field atlas if and only if the drawer is from NewSDFDrawer.
+func (d *Drawer) Draw(f *Font, s string, x, y, maxWidth float32) error {
+	if f.SDF() != d.sdf {
+		return ErrFontKind
+	}
+	d.builder.Clear()
+	if _, _, err := f.Build(d.builder, s, x, y, maxWidth); err != nil {
+		return err
+	}
+	if d.builder.IndexCount() == 0 {
+		return nil
+	}
+	if d.geom == nil {
+		geom, err := gfx.NewGeometry(d.builder, gfx.StreamDraw)
+		if err != nil {
+			return err
+		}
+		layout, err := gfx.LayoutGeometry(d.shader, geom)
+		if err != nil {
+			geom.Delete()
+			return err
+		}
+		d.geom = geom
+		d.layout = layout
+	} else if err := d.geom.CopyFrom(d.builder); err != nil {
+		return err
+	}
+	tex, err := f.Texture()
+	if err != nil {
+		return err
+	}
+	d.Atlas = tex
+	d.shader.Use()
+	if err := d.shader.SetLayout(d.layout); err != nil {
+		return err
+	}
+	if err := d.shader.AssignUniforms(&d.Uniforms); err != nil {
+		return err
+	}
+	if d.sdf {
+		if err := d.shader.AssignUniforms(&d.Effects); err != nil {
+			return err
+		}
+	}
+	d.shader.Draw()
+	return nil
+}
+
+// Ortho returns a projection for a viewport of the given size, with the
+// origin at the top left and y pointing down, as text is laid out.
+func Ortho(width, height float32) [16]float32 {
+	return [16]float32{
+		2 / width, 0, 0, 0,
+		0, -2 / height, 0, 0,
+		0, 0, -1, 0,
+		-1, 1, 0, 1,
+	}
+}
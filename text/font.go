@@ -0,0 +1,240 @@
+package text
+
+import (
+	"errors"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"image/draw"
+	"j4k.co/gfx"
+)
+
+// ErrAtlasFull is returned when a glyph does not fit in the atlas.
+var ErrAtlasFull = errors.New("text: font atlas is full")
+
+// ErrFontKind is returned when drawing a font with the wrong kind of
+// shader, such as a signed distance field font with the coverage shader.
+var ErrFontKind = errors.New("text: font atlas does not suit the shader")
+
+// Glyph locates a glyph in a font atlas. Its quad X0, Y0, X1, Y1 is
+// relative to the pen position on the baseline, with y pointing down, and is
+// empty for glyphs that draw nothing, such as spaces.
+type Glyph struct {
+	X0, Y0, X1, Y1 float32
+	U0, V0, U1, V1 float32
+	Advance        float32
+}
+
+func (g *Glyph) visible() bool {
+	return g.X1 > g.X0 && g.Y1 > g.Y0
+}
+
+// Font is a font face with an atlas of its rasterized glyphs.
+type Font struct {
+	face font.Face
+	img  *image.Alpha
+	tex  *gfx.Sampler2D
+
+	glyphs map[rune]Glyph
+
+	// shelf packing: the next free spot, and the height of the current row
+	x, y, rowHeight int
+
+	// dirty holds the rows of img not yet uploaded
+	dirty image.Rectangle
+
+	ascent, lineHeight float32
+
+	// spread is the distance in pixels covered by a signed distance field
+	// atlas, or 0 for coverage.
+	spread int
+}
+
+// padding separates glyphs in the atlas so filtering doesn't bleed.
+const padding = 1
+
+// NewFont returns a font drawing face, with an atlas of size by size
+// pixels.
+func NewFont(face font.Face, size int) *Font {
+	m := face.Metrics()
+	lineHeight := m.Height
+	if lineHeight == 0 {
+		lineHeight = m.Ascent + m.Descent
+	}
+	return &Font{
+		face:       face,
+		img:        image.NewAlpha(image.Rect(0, 0, size, size)),
+		glyphs:     make(map[rune]Glyph),
+		x:          padding,
+		y:          padding,
+		ascent:     fix(m.Ascent),
+		lineHeight: fix(lineHeight),
+	}
+}
+
+// NewSDFFont returns a font drawing face with a signed distance field
+// atlas, for use with SDFFragmentShader. Each atlas pixel holds the distance
+// to the nearest glyph edge, mapped from -spread..spread pixels to 0..1, so
+// 0.5 lies on the edge. Glyphs stay crisp when scaled well beyond the size
+// of face, which should be rasterized fairly large, such as at 32 pixels.
+func NewSDFFont(face font.Face, size, spread int) *Font {
+	f := NewFont(face, size)
+	f.spread = spread
+	return f
+}
+
+// NewFontFromAtlas returns a font using a previously generated atlas, such
+// as one saved from Atlas. It draws only the glyphs in glyphs, without
+// kerning. spread is that of NewSDFFont, or 0 for a coverage atlas.
+func NewFontFromAtlas(img *image.Alpha, glyphs map[rune]Glyph, ascent, lineHeight float32, spread int) *Font {
+	f := &Font{
+		img:        img,
+		glyphs:     make(map[rune]Glyph, len(glyphs)),
+		ascent:     ascent,
+		lineHeight: lineHeight,
+		spread:     spread,
+	}
+	for r, g := range glyphs {
+		f.glyphs[r] = g
+	}
+	return f
+}
+
+// Atlas returns the atlas image and the glyphs rasterized into it so far,
+// for saving and later loading with NewFontFromAtlas. The image must not be
+// modified.
+func (f *Font) Atlas() (*image.Alpha, map[rune]Glyph) {
+	glyphs := make(map[rune]Glyph, len(f.glyphs))
+	for r, g := range f.glyphs {
+		glyphs[r] = g
+	}
+	return f.img, glyphs
+}
+
+// Ascent returns the distance from the top of a line to its baseline.
+func (f *Font) Ascent() float32 {
+	return f.ascent
+}
+
+// SDF reports whether the atlas is a signed distance field.
+func (f *Font) SDF() bool {
+	return f.spread > 0
+}
+
+// Delete frees the atlas texture.
+func (f *Font) Delete() {
+	if f.tex != nil {
+		f.tex.Delete()
+		f.tex = nil
+	}
+}
+
+// LineHeight returns the distance between baselines of consecutive lines.
+func (f *Font) LineHeight() float32 {
+	return f.lineHeight
+}
+
+// Texture returns the atlas, uploading glyphs added since the last call.
+// The atlas has a single red channel holding glyph coverage.
+func (f *Font) Texture() (*gfx.Sampler2D, error) {
+	if f.tex == nil {
+		tex, err := gfx.Image(f.img, &gfx.SamplerOptions{
+			WrapS: gfx.WrapClamp,
+			WrapT: gfx.WrapClamp,
+		})
+		if err != nil {
+			return nil, err
+		}
+		f.tex = tex
+		f.dirty = image.Rectangle{}
+		return f.tex, nil
+	}
+	if !f.dirty.Empty() {
+		// upload whole rows, which are contiguous in img
+		y0, y1 := f.dirty.Min.Y, f.dirty.Max.Y
+		pix := f.img.Pix[y0*f.img.Stride : y1*f.img.Stride]
+		if err := f.tex.SetPixels(0, y0, f.img.Rect.Dx(), y1-y0, pix); err != nil {
+			return nil, err
+		}
+		f.dirty = image.Rectangle{}
+	}
+	return f.tex, nil
+}
+
+// glyph returns the glyph for r, rasterizing it into the atlas the first
+// time it is used.
+func (f *Font) glyph(r rune) (Glyph, error) {
+	if g, ok := f.glyphs[r]; ok {
+		return g, nil
+	}
+	if f.face == nil {
+		return Glyph{}, nil
+	}
+	dr, mask, maskp, advance, ok := f.face.Glyph(fixed.Point26_6{}, r)
+	if !ok {
+		// draw nothing, but remember so the face isn't asked again
+		f.glyphs[r] = Glyph{}
+		return Glyph{}, nil
+	}
+	g := Glyph{Advance: fix(advance)}
+	w, h := dr.Dx(), dr.Dy()
+	if w > 0 && h > 0 && !blank(mask, maskp, w, h) {
+		if f.spread > 0 {
+			mask = distanceField(mask, maskp, w, h, f.spread)
+			maskp = image.Point{}
+			dr = dr.Inset(-f.spread)
+			w, h = dr.Dx(), dr.Dy()
+		}
+		pos, err := f.place(w, h)
+		if err != nil {
+			return Glyph{}, err
+		}
+		dst := image.Rectangle{pos, pos.Add(image.Pt(w, h))}
+		draw.Draw(f.img, dst, mask, maskp, draw.Src)
+		f.dirty = f.dirty.Union(dst)
+
+		size := float32(f.img.Rect.Dx())
+		g.X0, g.Y0 = float32(dr.Min.X), float32(dr.Min.Y)
+		g.X1, g.Y1 = float32(dr.Max.X), float32(dr.Max.Y)
+		g.U0, g.V0 = float32(dst.Min.X)/size, float32(dst.Min.Y)/size
+		g.U1, g.V1 = float32(dst.Max.X)/size, float32(dst.Max.Y)/size
+	}
+	f.glyphs[r] = g
+	return g, nil
+}
+
+// place finds room for a w by h glyph, filling the atlas row by row.
+func (f *Font) place(w, h int) (image.Point, error) {
+	size := f.img.Rect.Dx()
+	if f.x+w+padding > size {
+		f.x = padding
+		f.y += f.rowHeight + padding
+		f.rowHeight = 0
+	}
+	if f.x+w+padding > size || f.y+h+padding > size {
+		return image.Point{}, ErrAtlasFull
+	}
+	pos := image.Pt(f.x, f.y)
+	f.x += w + padding
+	if h > f.rowHeight {
+		f.rowHeight = h
+	}
+	return pos, nil
+}
+
+// blank reports whether the w by h region of mask at p has no coverage, as
+// with the spaces of some faces.
+func blank(mask image.Image, p image.Point, w, h int) bool {
+	for y := p.Y; y < p.Y+h; y++ {
+		for x := p.X; x < p.X+w; x++ {
+			if _, _, _, a := mask.At(x, y).RGBA(); a != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func fix(v fixed.Int26_6) float32 {
+	return float32(v) / 64
+}
@@ -0,0 +1,135 @@
+package text
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"strings"
+	"unicode/utf8"
+)
+
+// VertexFormat is the format of vertices built for text.
+const VertexFormat = gfx.VertexPosition | gfx.VertexTexcoord
+
+// Measure returns the size of s as laid out by Build.
+func (f *Font) Measure(s string, maxWidth float32) (width, height float32, err error) {
+	return f.layout(s, maxWidth, func(g *Glyph, x, y float32) {})
+}
+
+// Build appends a quad for each visible glyph of s to b, which must have
+// VertexFormat or a superset of it. Text starts with its top left corner at
+// x, y, with y pointing down. Lines break at newlines, and if maxWidth is
+// positive, at the last space before a line would grow wider than maxWidth.
+// It returns the size of the laid out text.
+func (f *Font) Build(b *geometry.Builder, s string, x, y, maxWidth float32) (width, height float32, err error) {
+	return f.layout(s, maxWidth, func(g *Glyph, gx, gy float32) {
+		gx += x
+		gy += y
+		b.Position(gx+g.X0, gy+g.Y0, 0).Texcoord(g.U0, g.V0)
+		b.Position(gx+g.X0, gy+g.Y1, 0).Texcoord(g.U0, g.V1)
+		b.Position(gx+g.X1, gy+g.Y1, 0).Texcoord(g.U1, g.V1)
+		b.Position(gx+g.X1, gy+g.Y0, 0).Texcoord(g.U1, g.V0)
+		b.Quad(0, 1, 2, 3)
+	})
+}
+
+// layout calls fn with each visible glyph of s and its pen position
+// relative to the top left of the text.
+func (f *Font) layout(s string, maxWidth float32, fn func(g *Glyph, x, y float32)) (width, height float32, err error) {
+	var n int
+	for _, line := range strings.Split(s, "\n") {
+		for {
+			head, rest := line, ""
+			var w float32
+			if maxWidth > 0 {
+				head, rest, w, err = f.wrap(line, maxWidth)
+			} else {
+				w, err = f.advance(line, nil)
+			}
+			if err != nil {
+				return 0, 0, err
+			}
+			if w > width {
+				width = w
+			}
+			y := f.ascent + float32(n)*f.lineHeight
+			f.advance(head, func(g *Glyph, x float32) {
+				fn(g, x, y)
+			})
+			n++
+			if rest == "" {
+				break
+			}
+			line = rest
+		}
+	}
+	return width, float32(n) * f.lineHeight, nil
+}
+
+// advance returns the width of a single line, calling fn with each visible
+// glyph and its pen position if fn is non-nil.
+func (f *Font) advance(line string, fn func(g *Glyph, x float32)) (float32, error) {
+	var (
+		x    float32
+		prev rune = -1
+	)
+	for _, r := range line {
+		g, err := f.glyph(r)
+		if err != nil {
+			return 0, err
+		}
+		if prev >= 0 && f.face != nil {
+			x += fix(f.face.Kern(prev, r))
+		}
+		if g.visible() && fn != nil {
+			fn(&g, x)
+		}
+		x += g.Advance
+		prev = r
+	}
+	return x, nil
+}
+
+// wrap splits off the longest prefix of line that fits in maxWidth,
+// breaking at the last space. A word wider than maxWidth is broken between
+// characters. Spaces at the break are dropped.
+func (f *Font) wrap(line string, maxWidth float32) (head, rest string, width float32, err error) {
+	var (
+		x         float32
+		prev      rune = -1
+		fit       int
+		lastSpace = -1
+	)
+	for i, r := range line {
+		g, err := f.glyph(r)
+		if err != nil {
+			return "", "", 0, err
+		}
+		if prev >= 0 && f.face != nil {
+			x += fix(f.face.Kern(prev, r))
+		}
+		if x+g.Advance > maxWidth && r != ' ' {
+			break
+		}
+		x += g.Advance
+		fit = i + utf8.RuneLen(r)
+		if r == ' ' {
+			lastSpace = i
+		}
+		prev = r
+	}
+	if fit == len(line) {
+		return line, "", x, nil
+	}
+	end := lastSpace
+	if end <= 0 {
+		end = fit
+		if end == 0 {
+			// always make progress
+			_, end = utf8.DecodeRuneInString(line)
+		}
+	}
+	head = strings.TrimRight(line[:end], " ")
+	rest = strings.TrimLeft(line[end:], " ")
+	width, err = f.advance(head, nil)
+	return head, rest, width, err
+}
@@ -0,0 +1,85 @@
+package text_test
+
+import (
+	"golang.org/x/image/font/basicfont"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/text"
+	"testing"
+)
+
+// basicfont.Face7x13 advances 7 pixels per glyph, with 13 pixel lines.
+
+func TestMeasure(t *testing.T) {
+	f := text.NewFont(basicfont.Face7x13, 128)
+	w, h, err := f.Measure("hello\nhi", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w != 35 || h != 26 {
+		t.Fatalf("got %vx%v, want 35x26", w, h)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	f := text.NewFont(basicfont.Face7x13, 128)
+	cases := []struct {
+		s    string
+		max  float32
+		w, h float32
+	}{
+		{"aaa bbb", 30, 21, 26},
+		{"aaa bbb", 49, 49, 13},
+		{"aaaaaa", 30, 28, 26}, // broken within the word
+		{"a b c", 7, 7, 39},
+	}
+	for _, c := range cases {
+		w, h, err := f.Measure(c.s, c.max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w != c.w || h != c.h {
+			t.Errorf("%q in %v: got %vx%v, want %vx%v", c.s, c.max, w, h, c.w, c.h)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	f := text.NewFont(basicfont.Face7x13, 128)
+	b := geometry.NewBuilder(text.VertexFormat)
+	if _, _, err := f.Build(b, "a b", 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	// spaces have no quad
+	if b.VertexCount() != 8 || b.IndexCount() != 12 {
+		t.Fatalf("got %d vertices and %d indices, want 8 and 12", b.VertexCount(), b.IndexCount())
+	}
+}
+
+func TestAtlasFull(t *testing.T) {
+	f := text.NewFont(basicfont.Face7x13, 16)
+	if _, _, err := f.Measure("abcdef", 0); err != text.ErrAtlasFull {
+		t.Fatalf("got %v, want ErrAtlasFull", err)
+	}
+}
+
+func TestSDF(t *testing.T) {
+	f := text.NewSDFFont(basicfont.Face7x13, 128, 3)
+	b := geometry.NewBuilder(text.VertexFormat)
+	if _, _, err := f.Build(b, "l", 0, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	img, glyphs := f.Atlas()
+	g := glyphs['l']
+	// the quad grows by the spread on each side of the glyph's own bounds
+	plain := text.NewFont(basicfont.Face7x13, 128)
+	plain.Build(geometry.NewBuilder(text.VertexFormat), "l", 0, 0, 0)
+	_, pg := plain.Atlas()
+	if g.X1-g.X0 != pg['l'].X1-pg['l'].X0+6 {
+		t.Fatalf("got quad width %v, want %v", g.X1-g.X0, pg['l'].X1-pg['l'].X0+6)
+	}
+	// the corner of the field is far outside the glyph
+	x, y := int(g.U0*128), int(g.V0*128)
+	if a := img.AlphaAt(x, y).A; a != 0 {
+		t.Errorf("got %d at the field corner, want 0", a)
+	}
+}
@@ -0,0 +1,49 @@
+package text
+
+import (
+	"image"
+	"math"
+)
+
+// distanceField returns a signed distance field of the w by h region of
+// mask at p, grown by spread pixels on each side. Pixels with at least half
+// coverage are inside. Distances are found by brute force within spread,
+// which is fast enough for glyph-sized images.
+func distanceField(mask image.Image, p image.Point, w, h, spread int) *image.Alpha {
+	inside := func(x, y int) bool {
+		if x < 0 || y < 0 || x >= w || y >= h {
+			return false
+		}
+		_, _, _, a := mask.At(p.X+x, p.Y+y).RGBA()
+		return a >= 0x8000
+	}
+	out := image.NewAlpha(image.Rect(0, 0, w+2*spread, h+2*spread))
+	maxDist := float64(spread)
+	for oy := 0; oy < h+2*spread; oy++ {
+		for ox := 0; ox < w+2*spread; ox++ {
+			x, y := ox-spread, oy-spread
+			in := inside(x, y)
+			// distance to the nearest pixel of the other state, if
+			// within spread
+			best := maxDist + 0.5
+			for dy := -spread; dy <= spread; dy++ {
+				for dx := -spread; dx <= spread; dx++ {
+					if inside(x+dx, y+dy) == in {
+						continue
+					}
+					if d := math.Hypot(float64(dx), float64(dy)); d < best {
+						best = d
+					}
+				}
+			}
+			// the edge lies halfway between the two pixels
+			d := best - 0.5
+			if !in {
+				d = -d
+			}
+			v := 0.5 + d/(2*maxDist)
+			out.Pix[oy*out.Stride+ox] = uint8(math.Max(0, math.Min(1, v))*255 + 0.5)
+		}
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package gfx
+
+import (
+	"fmt"
+	"j4k.co/gfx/internal/gl"
+)
+
+// units allocates texture units to textures across all shaders. A texture
+// keeps its unit while it is used, so that switching shaders does not
+// rebind it, and the least recently used unit is given to a texture that
+// has none. Units used since the last draw are kept for the next one.
+var units struct {
+	clock uint64
+	draw  uint64 // the draw being prepared, counted by countDraw
+
+	used  [maxTrackedUnits]uint64 // clock at which each unit was last used
+	drawn [maxTrackedUnits]uint64 // draw each unit was last used for
+}
+
+// textureUnits returns the number of texture units managed, which is
+// MAX_COMBINED_TEXTURE_IMAGE_UNITS up to the number of tracked units.
+func textureUnits() int {
+	detectCaps()
+	n := caps.maxTexUnits
+	if n <= 0 {
+		// the minimum of OpenGL ES 2.0
+		n = 8
+	}
+	if n > maxTrackedUnits {
+		n = maxTrackedUnits
+	}
+	return n
+}
+
+// textureUnit makes the unit tex is bound to active and returns it,
+// binding tex to target of the least recently used unit if it has none. If pin is
+// set, the unit is kept for the next draw, and an error is returned if
+// every unit is already kept for it. Otherwise units kept for the next draw
+// are only taken if there are no others.
+func textureUnit(tex gl.Texture, target gl.GLenum, pin bool) (int, error) {
+	n := textureUnits()
+	units.clock++
+	unit := -1
+	for i := 0; i < n; i++ {
+		if bound.textures[i] == tex {
+			unit = i
+			break
+		}
+	}
+	if unit < 0 {
+		unit = leastRecentUnit(n, true)
+		if unit < 0 && !pin {
+			unit = leastRecentUnit(n, false)
+		}
+		if unit < 0 {
+			return 0, fmt.Errorf("gfx: draw needs more than the %d texture units available", n)
+		}
+		activeTexture(unit)
+		bindTexture(tex, target)
+		frameStats.TextureBinds++
+	} else {
+		activeTexture(unit)
+	}
+	units.used[unit] = units.clock
+	if pin {
+		units.drawn[unit] = units.draw
+	}
+	return unit, nil
+}
+
+// leastRecentUnit returns the least recently used of the first n units,
+// skipping those kept for the next draw if unkept is set, or -1 if there
+// is none.
+func leastRecentUnit(n int, unkept bool) int {
+	unit := -1
+	for i := 0; i < n; i++ {
+		if unkept && units.drawn[i] == units.draw {
+			continue
+		}
+		if unit < 0 || units.used[i] < units.used[unit] {
+			unit = i
+		}
+	}
+	return unit
+}
+
+// forgetUnits releases all units, after their bindings are forgotten.
+func forgetUnits() {
+	units.used = [maxTrackedUnits]uint64{}
+	units.drawn = [maxTrackedUnits]uint64{}
+	// no unit is kept for the next draw
+	units.draw++
+}
+
+// bindSampler binds the texture tex of target to a texture unit for the
+// next draw and assigns the unit to the sampler uniform u.
+func (s *Shader) bindSampler(u gl.UniformLocation, tex gl.Texture, target gl.GLenum) error {
+	unit, err := textureUnit(tex, target, true)
+	if err != nil {
+		return err
+	}
+	if cur, ok := s.samplerUnits[u]; !ok || cur != unit {
+		u.Uniform1i(unit)
+		if s.samplerUnits == nil {
+			s.samplerUnits = make(map[gl.UniformLocation]int)
+		}
+		s.samplerUnits[u] = unit
+	}
+	return nil
+}
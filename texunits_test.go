@@ -0,0 +1,69 @@
+package gfx_test
+
+import (
+	"fmt"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"strings"
+	"testing"
+)
+
+func TestTextureUnits(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	// more samplers than the 32 units gfx manages
+	var src strings.Builder
+	for i := 0; i <= 32; i++ {
+		fmt.Fprintf(&src, "uniform sampler2D T%d;\n", i)
+	}
+	src.WriteString("void main() {\n\tgl_FragColor = vec4(0.0);\n}")
+	many, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, gfx.FragmentShader(src.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer many.Delete()
+	s, err := gfx.BuildShader(gfx.DefaultVertexAttributes, gfx.FullscreenVertexShader, textureShader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete()
+	texs := make([]*gfx.Sampler2D, 33)
+	for i := range texs {
+		texs[i], err = gfx.NewSampler2D(1, 1, gfx.PixelRGBA8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer texs[i].Delete()
+	}
+
+	many.Use()
+	for i, tex := range texs {
+		err := many.SetTexture(fmt.Sprintf("T%d", i), tex)
+		if i < 32 && err != nil {
+			t.Fatal(err)
+		} else if i == 32 && err == nil {
+			t.Error("no error binding a 33rd texture for one draw")
+		}
+	}
+	gfx.DrawFullscreen(many)
+
+	// a texture keeps its unit across shaders, and the least recently used
+	// unit is taken by a texture without one
+	s.Use()
+	rec.Reset()
+	if err := s.SetTexture("Texture", texs[31]); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rec.Ops("BindTexture")); n != 0 {
+		t.Errorf("got %d binds of a bound texture, want 0", n)
+	}
+	gfx.DrawFullscreen(s)
+	rec.Reset()
+	if err := s.SetTexture("Texture", texs[32]); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rec.Ops("BindTexture")); n != 1 {
+		t.Errorf("got %d binds of an unbound texture, want 1", n)
+	}
+}
@@ -0,0 +1,197 @@
+package gfx
+
+import (
+	"fmt"
+	"j4k.co/gfx/internal/gl"
+	"reflect"
+	"unsafe"
+)
+
+// TypedBuffer holds vertices of a struct type V, whose fields are the
+// attributes of a VertexFormat in order, for example:
+//
+//	type vertex struct {
+//		Position [3]float32
+//		Color    [4]uint8
+//		Texcoord [2]float32
+//	}
+//	buf, err := gfx.NewTypedBuffer[vertex](gfx.VertexPosition | gfx.VertexColor | gfx.VertexTexcoord)
+//
+// Its layout is checked against the format once, by NewTypedBuffer, after
+// which vertices are copied to GL as they are in memory. A TypedBuffer is
+// VertexData, so it can create geometry with NewGeometry, and Upload then
+// copies the vertices changed since to it.
+type TypedBuffer[V any] struct {
+	// Vertices may be modified directly, in which case Mark must be called
+	// with the changed range for Upload to copy it.
+	Vertices []V
+
+	format VertexFormat
+	dirty  [2]int // range of vertices changed since the last upload
+}
+
+// NewTypedBuffer returns an empty buffer of vertices of type V in the given
+// format, or an error if V is not laid out as the format. Each field must
+// be an array with the elements of its attribute, such as [3]float32 for
+// positions, [4]uint8 for colors, [2]uint16 for half float texture
+// coordinates, or uint32 for packed normals. V must have no other fields.
+func NewTypedBuffer[V any](format VertexFormat) (*TypedBuffer[V], error) {
+	var v V
+	if err := checkVertexType(reflect.TypeOf(v), format); err != nil {
+		return nil, err
+	}
+	return &TypedBuffer[V]{format: format}, nil
+}
+
+// checkVertexType returns an error if t is not a struct laid out as format.
+func checkVertexType(t reflect.Type, format VertexFormat) error {
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("gfx: vertex type %v is not a struct", t)
+	}
+	if int(t.Size()) != format.Stride() {
+		return fmt.Errorf("gfx: vertex type %v is %d bytes, %v vertices are %d", t, t.Size(), format, format.Stride())
+	}
+	field, offset := 0, 0
+	for attrib := VertexFormat(1); attrib <= MaxVertexFormat; attrib <<= 1 {
+		if format&attrib == 0 {
+			continue
+		}
+		if field == t.NumField() {
+			return fmt.Errorf("gfx: vertex type %v has no field for %v", t, attrib)
+		}
+		f := t.Field(field)
+		if int(f.Offset) != offset {
+			return fmt.Errorf("gfx: field %s of %v is at offset %d, %v is at %d", f.Name, t, f.Offset, attrib, offset)
+		}
+		kinds, n := format.attribElemKinds(attrib)
+		if !elemsMatch(f.Type, kinds, n) {
+			return fmt.Errorf("gfx: field %s of %v is %v, %v is %d %v", f.Name, t, f.Type, attrib, n, kinds[0])
+		}
+		field++
+		offset += format.AttribSize(attrib)
+	}
+	if field != t.NumField() {
+		return fmt.Errorf("gfx: vertex type %v has more fields than %v", t, format)
+	}
+	return nil
+}
+
+// attribElemKinds returns the kinds of Go values that may hold the elements
+// of attrib in vertices of format v, and how many elements it has.
+func (v VertexFormat) attribElemKinds(attrib VertexFormat) ([]reflect.Kind, int) {
+	switch {
+	case v&VertexHalfTexcoords != 0 && attrib.isTexcoord():
+		return []reflect.Kind{reflect.Uint16}, 2
+	case v&VertexPackedNormals != 0 && attrib.isNormal():
+		return []reflect.Kind{reflect.Uint32, reflect.Int32}, 1
+	case v&VertexIntUserData != 0 && attrib >= VertexUserData && attrib <= VertexUserData3:
+		return []reflect.Kind{reflect.Int32, reflect.Uint32}, 4
+	case attrib.attribType() == gl.UNSIGNED_BYTE:
+		return []reflect.Kind{reflect.Uint8}, 4
+	}
+	return []reflect.Kind{reflect.Float32}, int(attrib.attribElems())
+}
+
+// elemsMatch reports whether t is an array of n elements of one of kinds,
+// or a single such value if n is 1.
+func elemsMatch(t reflect.Type, kinds []reflect.Kind, n int) bool {
+	if t.Kind() == reflect.Array {
+		if t.Len() != n {
+			return false
+		}
+		t = t.Elem()
+	} else if n != 1 {
+		return false
+	}
+	for _, k := range kinds {
+		if t.Kind() == k {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of vertices in the buffer.
+func (b *TypedBuffer[V]) Len() int {
+	return len(b.Vertices)
+}
+
+// Append adds vertices to the end of the buffer.
+func (b *TypedBuffer[V]) Append(v ...V) {
+	b.Mark(len(b.Vertices), len(b.Vertices)+len(v))
+	b.Vertices = append(b.Vertices, v...)
+}
+
+// Set replaces vertex i.
+func (b *TypedBuffer[V]) Set(i int, v V) {
+	b.Vertices[i] = v
+	b.Mark(i, i+1)
+}
+
+// Reset empties the buffer, keeping its memory.
+func (b *TypedBuffer[V]) Reset() {
+	b.Vertices = b.Vertices[:0]
+	b.dirty = [2]int{}
+}
+
+// Mark records that vertices i through j-1 were changed, for Upload.
+func (b *TypedBuffer[V]) Mark(i, j int) {
+	if b.dirty[0] == b.dirty[1] {
+		b.dirty = [2]int{i, j}
+		return
+	}
+	if i < b.dirty[0] {
+		b.dirty[0] = i
+	}
+	if j > b.dirty[1] {
+		b.dirty[1] = j
+	}
+}
+
+// VertexCount implements VertexData.
+func (b *TypedBuffer[V]) VertexCount() int {
+	return len(b.Vertices)
+}
+
+// VertexFormat implements VertexData.
+func (b *TypedBuffer[V]) VertexFormat() VertexFormat {
+	return b.format
+}
+
+// CopyVertices implements VertexData, copying every vertex to dest, which
+// must have the format of the buffer.
+func (b *TypedBuffer[V]) CopyVertices(dest *VertexBuffer, usage Usage) error {
+	if dest.Format() != b.format {
+		return ErrBadVertexFormat
+	}
+	b.dirty = [2]int{}
+	return dest.SetVertices(b.bytes(0, len(b.Vertices)), usage)
+}
+
+// Upload copies the vertices changed since the last upload to dest, which
+// must have the format of the buffer and have been filled from it, such as
+// the VertexBuffer of geometry created from it. If the number of vertices
+// has changed since, all of them are copied, reallocating dest with usage.
+func (b *TypedBuffer[V]) Upload(dest *VertexBuffer, usage Usage) error {
+	if dest.Format() != b.format {
+		return ErrBadVertexFormat
+	}
+	if dest.Count() != len(b.Vertices) {
+		return b.CopyVertices(dest, usage)
+	}
+	i, j := b.dirty[0], b.dirty[1]
+	if i == j {
+		return nil
+	}
+	b.dirty = [2]int{}
+	return dest.SetVerticesAt(i*b.format.Stride(), b.bytes(i, j))
+}
+
+// bytes returns the memory of vertices i through j-1.
+func (b *TypedBuffer[V]) bytes(i, j int) []byte {
+	if i == j {
+		return nil
+	}
+	stride := b.format.Stride()
+	return unsafe.Slice((*byte)(unsafe.Pointer(&b.Vertices[i])), (j-i)*stride)
+}
@@ -0,0 +1,50 @@
+package gfx_test
+
+import (
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+type colorVertex struct {
+	Position [3]float32
+	Color    [4]uint8
+}
+
+func TestTypedBuffer(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	if _, err := gfx.NewTypedBuffer[colorVertex](gfx.VertexPosition | gfx.VertexNormal); err == nil {
+		t.Fatal("no error for a mismatched format")
+	}
+	buf, err := gfx.NewTypedBuffer[colorVertex](gfx.VertexPosition | gfx.VertexColor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Append(
+		colorVertex{[3]float32{0, 0, 0}, [4]uint8{255, 0, 0, 255}},
+		colorVertex{[3]float32{1, 0, 0}, [4]uint8{0, 255, 0, 255}},
+	)
+	geom, err := gfx.NewGeometry(buf, gfx.DynamicDraw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geom.Delete()
+	if geom.VertexBuffer.Count() != 2 {
+		t.Fatalf("got %d vertices, want 2", geom.VertexBuffer.Count())
+	}
+
+	rec.Reset()
+	buf.Set(1, colorVertex{[3]float32{2, 0, 0}, [4]uint8{0, 0, 255, 255}})
+	if err := buf.Upload(&geom.VertexBuffer, gfx.DynamicDraw); err != nil {
+		t.Fatal(err)
+	}
+	uploads := rec.Uploads()
+	if len(uploads) != 1 || uploads[0].Op != "BufferSubData" {
+		t.Fatalf("got uploads %v, want one BufferSubData", uploads)
+	}
+	if off, size := uploads[0].Args[1], uploads[0].Args[2]; off != 16 || size != 16 {
+		t.Errorf("updated %v bytes at %v, want 16 at 16", size, off)
+	}
+}
@@ -0,0 +1,190 @@
+package gfx
+
+import (
+	"fmt"
+	"j4k.co/gfx/internal/gl"
+	"reflect"
+	"unsafe"
+)
+
+// UniformBlock is a uniform buffer object holding a Go struct in std140
+// layout. Fields with a "uniform" tag are laid out in declaration order, so
+// they must be declared in the same order as the members of the GLSL block;
+// the tag names are only for readability. Supported field types are int32,
+// uint32, float32, and arrays of them, with [2], [3], and [4] arrays being
+// vectors, [9]float32 a mat3, and [16]float32 a mat4. Other arrays, arrays
+// of vectors and matrices, and nested structs follow the std140 array and
+// structure rules.
+type UniformBlock struct {
+	buf     gl.Buffer
+	typ     reflect.Type
+	copies  []blockCopy
+	data    []byte
+	binding int
+}
+
+// blockCopy moves n bytes from offset src of the Go struct to offset dst of
+// the std140 buffer.
+type blockCopy struct {
+	src, dst, n uintptr
+}
+
+// NewUniformBlock creates a uniform buffer for structs of the type pointed
+// to by data, uploads data, and binds the buffer to the given binding point.
+func NewUniformBlock(binding int, data interface{}) (*UniformBlock, error) {
+	val := reflect.ValueOf(data)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("gfx: uniform block data must be a pointer to a struct")
+	}
+	typ := val.Elem().Type()
+	copies, size, _, err := layoutStruct(typ, true)
+	if err != nil {
+		return nil, err
+	}
+	b := &UniformBlock{typ: typ, copies: copies, binding: binding}
+	b.data = make([]byte, size)
+	b.buf = gl.GenBuffer()
+	setFinalizer(b, (*UniformBlock).finalize)
+	b.buf.Bind(gl.UNIFORM_BUFFER)
+	b.SetLabel(autoLabel("UniformBlock"))
+	gl.BufferData(gl.UNIFORM_BUFFER, len(b.data), nil, gl.DYNAMIC_DRAW)
+	b.buf.BindBufferBase(gl.UNIFORM_BUFFER, uint(binding))
+	gl.Buffer(0).Bind(gl.UNIFORM_BUFFER)
+	if err := b.Update(data); err != nil {
+		b.Delete()
+		return nil, err
+	}
+	return b, nil
+}
+
+// SetLabel names the buffer in GPU debuggers, such as RenderDoc and
+// apitrace, when the context has KHR_debug. Uniform blocks are otherwise
+// named like "UniformBlock#2".
+func (b *UniformBlock) SetLabel(name string) {
+	labelObject(gl.BUFFER, uint32(b.buf), name)
+}
+
+// Delete frees the buffer.
+func (b *UniformBlock) Delete() {
+	setFinalizer(b, nil)
+	b.buf.Delete()
+}
+
+// Size returns the size of the buffer in bytes.
+func (b *UniformBlock) Size() int {
+	return len(b.data)
+}
+
+// Binding returns the binding point of the buffer.
+func (b *UniformBlock) Binding() int {
+	return b.binding
+}
+
+// Update packs data, which must point to the struct type the block was
+// created with, and uploads it with a single BufferSubData call.
+func (b *UniformBlock) Update(data interface{}) error {
+	val := reflect.ValueOf(data)
+	if val.Kind() != reflect.Ptr || val.Elem().Type() != b.typ {
+		return fmt.Errorf("gfx: uniform block expects *%v, got %T", b.typ, data)
+	}
+	base := unsafe.Pointer(val.Pointer())
+	for _, c := range b.copies {
+		copy(b.data[c.dst:c.dst+c.n], unsafe.Slice((*byte)(unsafe.Add(base, c.src)), c.n))
+	}
+	b.buf.Bind(gl.UNIFORM_BUFFER)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 0, len(b.data), b.data)
+	countUpload(len(b.data))
+	gl.Buffer(0).Bind(gl.UNIFORM_BUFFER)
+	checkError("UniformBlock update %d bytes", len(b.data))
+	return nil
+}
+
+// SetUniformBlock binds the named uniform block of the shader to the binding
+// point of b.
+func (s *Shader) SetUniformBlock(name string, b *UniformBlock) error {
+	idx := s.prog.GetUniformBlockIndex(name)
+	if idx == gl.INVALID_INDEX {
+		return fmt.Errorf("gfx: unknown uniform block '%s'", name)
+	}
+	s.prog.UniformBlockBinding(idx, uint(b.binding))
+	checkError("SetUniformBlock %s", name)
+	return nil
+}
+
+func roundUp(n, align uintptr) uintptr {
+	return (n + align - 1) / align * align
+}
+
+// shift offsets copies by src and dst.
+func shift(copies []blockCopy, src, dst uintptr) []blockCopy {
+	for i := range copies {
+		copies[i].src += src
+		copies[i].dst += dst
+	}
+	return copies
+}
+
+// layoutStruct returns the copies for the fields of typ, and its padded
+// size and alignment. Only tagged fields are included at the top level;
+// nested structs include all of their exported fields.
+func layoutStruct(typ reflect.Type, top bool) (copies []blockCopy, size, align uintptr, err error) {
+	var off uintptr
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		if top && f.Tag.Get("uniform") == "" {
+			continue
+		}
+		fc, fsize, falign, err := layout(f.Type)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("gfx: uniform block field %s: %v", f.Name, err)
+		}
+		off = roundUp(off, falign)
+		copies = append(copies, shift(fc, f.Offset, off)...)
+		off += fsize
+	}
+	return copies, roundUp(off, 16), 16, nil
+}
+
+// layout returns the copies for a value of type typ, relative to the start
+// of the value, and its std140 size and alignment.
+func layout(typ reflect.Type) (copies []blockCopy, size, align uintptr, err error) {
+	switch typ.Kind() {
+	case reflect.Int32, reflect.Uint32, reflect.Float32:
+		return []blockCopy{{0, 0, 4}}, 4, 4, nil
+	case reflect.Struct:
+		return layoutStruct(typ, false)
+	case reflect.Array:
+		elem := typ.Elem()
+		n := uintptr(typ.Len())
+		switch elem.Kind() {
+		case reflect.Int32, reflect.Uint32, reflect.Float32:
+			switch {
+			case n == 2:
+				return []blockCopy{{0, 0, 8}}, 8, 8, nil
+			case n == 3 || n == 4:
+				return []blockCopy{{0, 0, n * 4}}, n * 4, 16, nil
+			case n == 9 && elem.Kind() == reflect.Float32:
+				// mat3: three columns, each padded to a vec4
+				return []blockCopy{{0, 0, 12}, {12, 16, 12}, {24, 32, 12}}, 48, 16, nil
+			case n == 16 && elem.Kind() == reflect.Float32:
+				return []blockCopy{{0, 0, 64}}, 64, 16, nil
+			}
+		}
+		// arrays have their element stride rounded up to a vec4
+		ec, esize, _, err := layout(elem)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		stride := roundUp(esize, 16)
+		for i := uintptr(0); i < n; i++ {
+			c := make([]blockCopy, len(ec))
+			copy(c, ec)
+			copies = append(copies, shift(c, i*elem.Size(), i*stride)...)
+		}
+		return copies, stride * n, 16, nil
+	}
+	return nil, 0, 0, fmt.Errorf("unsupported type %v", typ)
+}
@@ -0,0 +1,180 @@
+package gfx
+
+import (
+	"fmt"
+	"image"
+	"j4k.co/gfx/internal/gl"
+	"time"
+)
+
+// Fence marks a point in the GL command stream, to find out when the GPU
+// has finished the commands issued before it.
+type Fence struct {
+	sync uintptr
+}
+
+// NewFence inserts a fence after the commands issued so far.
+func NewFence() *Fence {
+	f := &Fence{sync: gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)}
+	checkError("NewFence")
+	return f
+}
+
+// Signaled reports whether the commands before the fence have completed,
+// without blocking.
+func (f *Fence) Signaled() bool {
+	return f.Wait(0)
+}
+
+// Wait blocks until the commands before the fence have completed or the
+// timeout passes, and reports whether they completed.
+func (f *Fence) Wait(timeout time.Duration) bool {
+	if f.sync == 0 {
+		return true
+	}
+	switch gl.ClientWaitSync(f.sync, gl.SYNC_FLUSH_COMMANDS_BIT, uint64(timeout)) {
+	case gl.ALREADY_SIGNALED, gl.CONDITION_SATISFIED:
+		return true
+	default:
+		return false
+	}
+}
+
+// Delete frees the fence.
+func (f *Fence) Delete() {
+	if f.sync != 0 {
+		gl.DeleteSync(f.sync)
+		f.sync = 0
+	}
+}
+
+// Upload is an asynchronous update of a texture region through a pixel
+// buffer object. The pixels are written into mapped buffer memory, possibly
+// from another goroutine, and then copied into the texture by the GPU
+// without stalling the caller.
+//
+// Typical use is to call BeginUpload on the GL thread, fill Pixels on any
+// goroutine, then call Submit on the GL thread and poll Done once per frame
+// until the texture has been updated.
+type Upload struct {
+	tex   *Sampler2D
+	rect  image.Rectangle
+	pbo   gl.Buffer
+	pix   []byte
+	fence *Fence
+}
+
+// BeginUpload starts an upload replacing the region r of the texture, in
+// the coordinates of SetPixels, and maps a buffer to write its pixels into.
+func (s *Sampler2D) BeginUpload(r image.Rectangle) (*Upload, error) {
+	if s.format.IsCompressed() {
+		return nil, fmt.Errorf("gfx: cannot set pixels of %v texture", s.format)
+	}
+	if !r.In(image.Rect(0, 0, s.width, s.height)) {
+		return nil, fmt.Errorf("gfx: region %v outside %dx%d texture", r, s.width, s.height)
+	}
+	size := s.format.DataSize(r.Dx(), r.Dy())
+	u := &Upload{
+		tex:  s,
+		rect: r,
+		pbo:  gl.GenBuffer(),
+	}
+	setFinalizer(u, (*Upload).finalize)
+	u.pbo.Bind(gl.PIXEL_UNPACK_BUFFER)
+	gl.BufferData(gl.PIXEL_UNPACK_BUFFER, size, nil, gl.STREAM_DRAW)
+	ptr := gl.MapBufferRange(gl.PIXEL_UNPACK_BUFFER, 0, size, gl.MAP_WRITE_BIT|gl.MAP_INVALIDATE_BUFFER_BIT)
+	gl.Buffer(0).Bind(gl.PIXEL_UNPACK_BUFFER)
+	if ptr == nil {
+		u.Delete()
+		return nil, errMapBufferFailed
+	}
+	u.pix = bytesAt(ptr, size)
+	checkError("Sampler2D.BeginUpload %v", r)
+	return u, nil
+}
+
+// UploadAsync starts replacing the whole texture with img, which must be
+// the size of the texture, and submits the upload. img is converted to the
+// format of the texture as it would be by Update.
+func (s *Sampler2D) UploadAsync(img image.Image) (*Upload, error) {
+	var pix []byte
+	switch s.format {
+	case PixelRGBA8, PixelSRGBA8:
+		pix = rgbaPixels(img)
+	case PixelR8:
+		pix = alphaPixels(img)
+	default:
+		return nil, fmt.Errorf("gfx: cannot update %v texture from an image", s.format)
+	}
+	if size := img.Bounds().Size(); size.X != s.width || size.Y != s.height {
+		return nil, fmt.Errorf("gfx: %v image does not fit %dx%d texture", size, s.width, s.height)
+	}
+	u, err := s.BeginUpload(image.Rect(0, 0, s.width, s.height))
+	if err != nil {
+		return nil, err
+	}
+	copy(u.Pixels(), pix)
+	if err := u.Submit(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Pixels returns the mapped memory to write tightly packed rows of pixels
+// into, in the texture's format. It must not be used after Submit.
+func (u *Upload) Pixels() []byte {
+	return u.pix
+}
+
+// Submit unmaps the pixels and queues the copy into the texture.
+func (u *Upload) Submit() error {
+	u.pix = nil
+	u.pbo.Bind(gl.PIXEL_UNPACK_BUFFER)
+	ok := gl.UnmapBuffer(gl.PIXEL_UNPACK_BUFFER)
+	if ok {
+		r, format := u.rect, u.tex.format
+		u.tex.bind()
+		gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+		gl.TexSubImage2D(gl.TEXTURE_2D, 0, r.Min.X, r.Min.Y, r.Dx(), r.Dy(), format.format(), format.typ(), nil)
+		gl.PixelStorei(gl.UNPACK_ALIGNMENT, 4)
+		if u.tex.mipmaps {
+			gl.GenerateMipmap(gl.TEXTURE_2D)
+		}
+	}
+	gl.Buffer(0).Bind(gl.PIXEL_UNPACK_BUFFER)
+	if !ok {
+		u.Delete()
+		return errMapBufferFailed
+	}
+	u.fence = NewFence()
+	checkError("Upload.Submit %v", u.rect)
+	return nil
+}
+
+// Done reports whether the texture has been updated, freeing the buffer
+// once it has.
+func (u *Upload) Done() bool {
+	if u.fence == nil || !u.fence.Signaled() {
+		return false
+	}
+	u.Delete()
+	return true
+}
+
+// Delete frees the buffer and fence of the upload. A submitted upload still
+// updates the texture, as GL keeps the buffer until the copy is done.
+func (u *Upload) Delete() {
+	setFinalizer(u, nil)
+	if u.pbo != 0 {
+		u.pbo.Delete()
+		u.pbo = 0
+	}
+	if u.fence != nil {
+		u.fence.Delete()
+	}
+}
+
+func (u *Upload) finalize() {
+	collected(u)
+	trashBuffers(u.pbo)
+}
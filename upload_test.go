@@ -0,0 +1,86 @@
+package gfx_test
+
+import (
+	"fmt"
+	"image"
+	"j4k.co/gfx"
+	"j4k.co/gfx/gfxtest"
+	"testing"
+)
+
+func TestUploadAsync(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	tex, err := gfx.NewSampler2D(2, 1, gfx.PixelRGBA8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tex.Delete()
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	for i := range img.Pix {
+		img.Pix[i] = byte(i)
+	}
+	rec.Reset()
+	u, err := tex.UploadAsync(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the pixels go through the mapped buffer, and the texture is updated
+	// from the buffer bound while it is
+	var got []string
+	for _, c := range rec.Ops("BindBuffer", "UnmapBuffer", "TexSubImage2D", "FenceSync") {
+		got = append(got, c.String())
+	}
+	want := fmt.Sprint([]string{
+		"BindBuffer(PIXEL_UNPACK_BUFFER, 2)",
+		"BindBuffer(PIXEL_UNPACK_BUFFER, 0)",
+		"BindBuffer(PIXEL_UNPACK_BUFFER, 2)",
+		"UnmapBuffer(PIXEL_UNPACK_BUFFER, [8 bytes])",
+		"TexSubImage2D(TEXTURE_2D, 0, 0, 0, 2, 1, RGBA, UNSIGNED_BYTE, [0 bytes])",
+		"BindBuffer(PIXEL_UNPACK_BUFFER, 0)",
+		"FenceSync(SYNC_GPU_COMMANDS_COMPLETE, 0)",
+	})
+	if fmt.Sprint(got) != want {
+		t.Errorf("got %v,\nwant %v", got, want)
+	}
+	if data := rec.Ops("UnmapBuffer")[0].Args[1]; fmt.Sprint(data) != "[0 1 2 3 4 5 6 7]" {
+		t.Errorf("wrote %v, want the image's pixels", data)
+	}
+
+	rec.Reset()
+	if !u.Done() {
+		t.Fatal("upload not done after its fence was signaled")
+	}
+	got = nil
+	for _, c := range rec.Calls {
+		got = append(got, c.String())
+	}
+	if want := "[DeleteBuffers([2]) DeleteSync(1)]"; fmt.Sprint(got) != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// a failed unmap frees the buffer without updating the texture
+	rec.Reset()
+	rec.UnmapFailures = 1
+	if _, err := tex.UploadAsync(img); err == nil {
+		t.Error("no error when unmapping failed")
+	}
+	if n := len(rec.Ops("TexSubImage2D", "FenceSync")); n != 0 {
+		t.Errorf("got %d texture updates and fences, want none", n)
+	}
+	if n := len(rec.Ops("DeleteBuffers")); n != 1 {
+		t.Errorf("deleted %d buffers, want 1", n)
+	}
+
+	rec.Reset()
+	if _, err := tex.UploadAsync(image.NewNRGBA(image.Rect(0, 0, 1, 1))); err == nil {
+		t.Error("no error for an image of another size than the texture")
+	}
+	if _, err := tex.BeginUpload(image.Rect(1, 0, 3, 1)); err == nil {
+		t.Error("no error for a region outside the texture")
+	}
+	if len(rec.Calls) != 0 {
+		t.Errorf("got %v for failed uploads, want no calls", rec.Calls)
+	}
+}
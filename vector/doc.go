@@ -0,0 +1,26 @@
+/*
+Package vector draws 2D paths of lines, Bézier curves and arcs, filled by
+a fill rule or stroked with caps and joins. Paths are flattened into
+polylines as they are built, then tessellated into triangles on the CPU
+and appended to a geometry.Builder, so they can be drawn by any shader
+through the usual Geometry pipeline, or by a Drawer, which batches them
+into one draw call:
+
+	d, err := vector.NewDrawer()
+	...
+	var p vector.Path
+	p.MoveTo(10, 10)
+	p.CubicTo(40, 0, 60, 80, 90, 40)
+	d.Stroke(&p, vector.Stroke{Width: 4, Cap: vector.RoundCap}, color.NRGBA{255, 255, 255, 255})
+	...
+	st := gfx.State{Blend: gfx.AlphaBlend, NoDepthTest: true}
+	st.Apply()
+	err = d.Flush(text.Ortho(width, height))
+
+Shapes are not antialiased, except by multisampled render targets. Strokes
+and overlapping fills draw some pixels more than once, which shows when
+they are translucent. Triangles are wound to face the viewer through
+projections with y pointing down, such as text.Ortho, so they survive the
+default back-face culling.
+*/
+package vector
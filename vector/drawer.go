@@ -0,0 +1,99 @@
+package vector
+
+import (
+	"image/color"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+)
+
+var attrs = gfx.VertexAttributes{
+	gfx.VertexPosition: "Position",
+	gfx.VertexColor:    "Color",
+}
+
+const vertexShader gfx.VertexShader = `
+uniform mat4 ProjectionM;
+
+attribute vec3 Position;
+attribute vec4 Color;
+
+varying vec4 color;
+
+void main() {
+	color = Color;
+	gl_Position = ProjectionM * vec4(Position, 1.0);
+}`
+
+const fragmentShader gfx.FragmentShader = `
+varying vec4 color;
+
+void main() {
+	gl_FragColor = color;
+}`
+
+// Drawer accumulates filled and stroked paths, and draws them together
+// with one draw call by Flush, in the order they were added. Blending and
+// depth testing are left to the caller, who typically enables alpha
+// blending and disables depth testing.
+type Drawer struct {
+	shader  *gfx.Shader
+	builder *geometry.Builder
+	dyn     *gfx.DynamicGeometry
+}
+
+// NewDrawer builds the shader of the drawer and returns it empty.
+func NewDrawer() (*Drawer, error) {
+	shader, err := gfx.BuildShader(attrs, vertexShader, fragmentShader)
+	if err != nil {
+		return nil, err
+	}
+	dyn, err := gfx.NewDynamicGeometry(VertexFormat, false, 2)
+	if err != nil {
+		shader.Delete()
+		return nil, err
+	}
+	return &Drawer{
+		shader:  shader,
+		builder: geometry.NewBuilder(VertexFormat),
+		dyn:     dyn,
+	}, nil
+}
+
+// Delete frees the shader and buffers of the drawer.
+func (d *Drawer) Delete() {
+	d.shader.Delete()
+	d.dyn.Delete()
+}
+
+// Fill adds the inside of p by rule, in color c.
+func (d *Drawer) Fill(p *Path, rule FillRule, c color.NRGBA) {
+	p.Fill(d.builder, rule, c)
+}
+
+// Stroke adds the outline of p described by s, in color c.
+func (d *Drawer) Stroke(p *Path, s Stroke, c color.NRGBA) {
+	p.Stroke(d.builder, s, c)
+}
+
+// Flush draws the paths added since the last flush with the projection
+// matrix proj, such as text.Ortho(width, height) for paths in pixels with
+// y down, and clears them.
+func (d *Drawer) Flush(proj [16]float32) error {
+	defer d.builder.Clear()
+	if d.builder.VertexCount() == 0 {
+		return nil
+	}
+	geom, err := d.dyn.Update(d.builder)
+	if err != nil {
+		return err
+	}
+	d.shader.Use()
+	if err := d.shader.SetGeometry(geom); err != nil {
+		return err
+	}
+	if err := d.shader.SetUniform("ProjectionM", proj); err != nil {
+		return err
+	}
+	d.shader.Draw()
+	return nil
+}
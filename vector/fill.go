@@ -0,0 +1,164 @@
+package vector
+
+import (
+	"image/color"
+	"j4k.co/gfx"
+	"j4k.co/gfx/geometry"
+	"sort"
+)
+
+// VertexFormat is the format of the triangles of fills and strokes.
+const VertexFormat = gfx.VertexPosition | gfx.VertexColor
+
+// FillRule decides which regions of a path are inside it, from the number
+// of times the contours wind around them.
+type FillRule uint8
+
+const (
+	NonZero FillRule = iota // inside where the winding number is not zero
+	EvenOdd                 // inside where the winding number is odd
+)
+
+func (r FillRule) inside(winding int) bool {
+	if r == EvenOdd {
+		return winding%2 != 0
+	}
+	return winding != 0
+}
+
+// edge is a line of a contour with y0 < y1, and dir 1 if the contour runs
+// down it, or -1 if up.
+type edge struct {
+	x0, y0, x1, y1 float32
+	dir            int
+}
+
+func (e *edge) xAt(y float32) float32 {
+	return e.x0 + (e.x1-e.x0)*(y-e.y0)/(e.y1-e.y0)
+}
+
+// triangles appends triangles to a geometry.Builder in one color, wound
+// counter-clockwise on screen with y pointing down, so they face the viewer
+// through text.Ortho and survive back-face culling.
+type triangles struct {
+	b *geometry.Builder
+	c color.NRGBA
+}
+
+func (t triangles) add(a, b, c point) {
+	if (b[0]-a[0])*(c[1]-a[1])-(b[1]-a[1])*(c[0]-a[0]) > 0 {
+		b, c = c, b
+	}
+	for _, p := range [3]point{a, b, c} {
+		t.b.Position(p[0], p[1], 0).Color(t.c.R, t.c.G, t.c.B, t.c.A)
+	}
+}
+
+// Fill appends triangles covering the inside of p by rule to b, which must
+// have been created with VertexFormat, as unindexed vertices of color c.
+// Open contours are filled as if closed.
+//
+// The path is cut into horizontal bands at each vertex and crossing of its
+// lines, which leaves no crossings within a band, and the spans of each
+// band that are inside are covered by trapezoids. Finding the crossings
+// takes time quadratic in the number of lines, which suits the paths of
+// icons and user interfaces rather than large maps.
+func (p *Path) Fill(b *geometry.Builder, rule FillRule, c color.NRGBA) {
+	var edges []edge
+	for _, ct := range p.contours {
+		n := len(ct.pts)
+		if n < 3 {
+			continue
+		}
+		for i, p0 := range ct.pts {
+			p1 := ct.pts[(i+1)%n]
+			switch {
+			case p0[1] < p1[1]:
+				edges = append(edges, edge{p0[0], p0[1], p1[0], p1[1], 1})
+			case p0[1] > p1[1]:
+				edges = append(edges, edge{p1[0], p1[1], p0[0], p0[1], -1})
+			}
+		}
+	}
+	if len(edges) == 0 {
+		return
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].y0 < edges[j].y0 })
+
+	ys := make([]float32, 0, 2*len(edges))
+	for i := range edges {
+		e := &edges[i]
+		ys = append(ys, e.y0, e.y1)
+		for j := i + 1; j < len(edges) && edges[j].y0 < e.y1; j++ {
+			if y, ok := crossing(e, &edges[j]); ok {
+				ys = append(ys, y)
+			}
+		}
+	}
+	sort.Slice(ys, func(i, j int) bool { return ys[i] < ys[j] })
+
+	tris := triangles{b, c}
+	var (
+		active []*edge
+		next   int
+	)
+	for k := 0; k+1 < len(ys); k++ {
+		y0, y1 := ys[k], ys[k+1]
+		if y0 == y1 {
+			continue
+		}
+		// drop the edges ending above the band, and add those starting
+		// at its top
+		live := active[:0]
+		for _, e := range active {
+			if e.y1 > y0 {
+				live = append(live, e)
+			}
+		}
+		active = live
+		for next < len(edges) && edges[next].y0 <= y0 {
+			if edges[next].y1 > y0 {
+				active = append(active, &edges[next])
+			}
+			next++
+		}
+		mid := (y0 + y1) / 2
+		sort.Slice(active, func(i, j int) bool { return active[i].xAt(mid) < active[j].xAt(mid) })
+		winding := 0
+		var start *edge
+		for _, e := range active {
+			was := rule.inside(winding)
+			winding += e.dir
+			switch is := rule.inside(winding); {
+			case !was && is:
+				start = e
+			case was && !is:
+				l0, l1 := start.xAt(y0), start.xAt(y1)
+				r0, r1 := e.xAt(y0), e.xAt(y1)
+				if r0 > l0 {
+					tris.add(point{l0, y0}, point{r0, y0}, point{r1, y1})
+				}
+				if r1 > l1 {
+					tris.add(point{l0, y0}, point{r1, y1}, point{l1, y1})
+				}
+			}
+		}
+	}
+}
+
+// crossing returns the y at which the lines of a and b cross, if they
+// cross away from their ends.
+func crossing(a, b *edge) (float32, bool) {
+	dax, day := a.x1-a.x0, a.y1-a.y0
+	dbx, dby := b.x1-b.x0, b.y1-b.y0
+	den := dax*dby - day*dbx
+	if den == 0 {
+		return 0, false
+	}
+	t := ((b.x0-a.x0)*dby - (b.y0-a.y0)*dbx) / den
+	u := ((b.x0-a.x0)*day - (b.y0-a.y0)*dax) / den
+	if t <= 0 || t >= 1 || u <= 0 || u >= 1 {
+		return 0, false
+	}
+	return a.y0 + t*day, true
+}
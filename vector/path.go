@@ -0,0 +1,215 @@
+package vector
+
+import (
+	"math"
+)
+
+// DefaultTolerance is the tolerance of paths whose Tolerance is zero.
+const DefaultTolerance = 0.25
+
+// Path is a set of contours of connected points, with curves flattened
+// into lines as they are added. The zero value is an empty path.
+type Path struct {
+	// Tolerance is the greatest distance that the lines approximating
+	// curves, arcs, and round caps and joins stray from the true curve, in
+	// path units, which are typically pixels.
+	Tolerance float32
+
+	contours []contour
+}
+
+type point [2]float32
+
+type contour struct {
+	pts    []point
+	closed bool
+}
+
+func (p *Path) tolerance() float32 {
+	if p.Tolerance > 0 {
+		return p.Tolerance
+	}
+	return DefaultTolerance
+}
+
+// Clear removes every contour from the path.
+func (p *Path) Clear() {
+	p.contours = p.contours[:0]
+}
+
+// current returns the contour being added to, or nil if there is none.
+func (p *Path) current() *contour {
+	if len(p.contours) == 0 {
+		return nil
+	}
+	c := &p.contours[len(p.contours)-1]
+	if c.closed {
+		return nil
+	}
+	return c
+}
+
+// last returns the current point, the start of the last contour if it is
+// closed, and false if the path is empty.
+func (p *Path) last() (point, bool) {
+	if len(p.contours) == 0 {
+		return point{}, false
+	}
+	c := &p.contours[len(p.contours)-1]
+	if c.closed {
+		return c.pts[0], true
+	}
+	return c.pts[len(c.pts)-1], true
+}
+
+// MoveTo begins a new contour at x, y.
+func (p *Path) MoveTo(x, y float32) {
+	p.contours = append(p.contours, contour{pts: []point{{x, y}}})
+}
+
+// LineTo adds a line from the current point to x, y, unless they are the
+// same. After Close, the line begins a new contour at the start of the
+// closed one, and in an empty path LineTo acts as MoveTo.
+func (p *Path) LineTo(x, y float32) {
+	c := p.current()
+	if c == nil {
+		start, ok := p.last()
+		if !ok {
+			p.MoveTo(x, y)
+			return
+		}
+		p.MoveTo(start[0], start[1])
+		c = p.current()
+	}
+	if c.pts[len(c.pts)-1] != (point{x, y}) {
+		c.pts = append(c.pts, point{x, y})
+	}
+}
+
+// QuadTo adds a quadratic Bézier curve from the current point to x, y with
+// the control point cx, cy.
+func (p *Path) QuadTo(cx, cy, x, y float32) {
+	p0, ok := p.last()
+	if !ok {
+		p.MoveTo(x, y)
+		return
+	}
+	d := length(p0[0]-2*cx+x, p0[1]-2*cy+y)
+	// the flattening error of n segments is at most |d| / (4n²)
+	n := segments(float32(math.Sqrt(float64(d / (4 * p.tolerance())))))
+	for i := 1; i <= n; i++ {
+		t := float32(i) / float32(n)
+		u := 1 - t
+		p.LineTo(u*u*p0[0]+2*u*t*cx+t*t*x, u*u*p0[1]+2*u*t*cy+t*t*y)
+	}
+}
+
+// CubicTo adds a cubic Bézier curve from the current point to x, y with the
+// control points c1x, c1y and c2x, c2y.
+func (p *Path) CubicTo(c1x, c1y, c2x, c2y, x, y float32) {
+	p0, ok := p.last()
+	if !ok {
+		p.MoveTo(x, y)
+		return
+	}
+	d := length(p0[0]-2*c1x+c2x, p0[1]-2*c1y+c2y)
+	if d2 := length(c1x-2*c2x+x, c1y-2*c2y+y); d2 > d {
+		d = d2
+	}
+	// the flattening error of n segments is at most 3|d| / (4n²)
+	n := segments(float32(math.Sqrt(float64(3 * d / (4 * p.tolerance())))))
+	for i := 1; i <= n; i++ {
+		t := float32(i) / float32(n)
+		u := 1 - t
+		a, b, c, e := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+		p.LineTo(a*p0[0]+b*c1x+c*c2x+e*x, a*p0[1]+b*c1y+c*c2y+e*y)
+	}
+}
+
+// Arc adds an arc of the circle of radius r around cx, cy, from angle a0
+// to a1 in radians, counterclockwise if a1 is greater in y-up coordinates,
+// and clockwise on screen with y down. A line joins the current point to
+// the start of the arc, which begins a contour if there is none.
+func (p *Path) Arc(cx, cy, r, a0, a1 float32) {
+	n := arcSegments(abs(a1-a0), r, p.tolerance())
+	for i := 0; i <= n; i++ {
+		a := float64(a0 + (a1-a0)*float32(i)/float32(n))
+		x := cx + r*float32(math.Cos(a))
+		y := cy + r*float32(math.Sin(a))
+		if i == 0 && p.current() == nil {
+			p.MoveTo(x, y)
+		} else {
+			p.LineTo(x, y)
+		}
+	}
+}
+
+// Close closes the current contour with a line back to its start.
+func (p *Path) Close() {
+	if c := p.current(); c != nil {
+		c.closed = true
+	}
+}
+
+// Rect adds a closed rectangle contour with its corner at x, y.
+func (p *Path) Rect(x, y, w, h float32) {
+	p.Polygon(x, y, x+w, y, x+w, y+h, x, y+h)
+}
+
+// Circle adds a closed circle contour of radius r around cx, cy.
+func (p *Path) Circle(cx, cy, r float32) {
+	p.MoveTo(cx+r, cy)
+	p.Arc(cx, cy, r, 0, 2*math.Pi)
+	// the end of the arc meets the start up to rounding, which Close joins
+	c := p.current()
+	if n := len(c.pts); n > 1 && length(c.pts[n-1][0]-c.pts[0][0], c.pts[n-1][1]-c.pts[0][1]) < r*1e-4 {
+		c.pts = c.pts[:n-1]
+	}
+	p.Close()
+}
+
+// Polygon adds a closed contour through the points given by pairs of
+// coordinates x0, y0, x1, y1 and so on.
+func (p *Path) Polygon(coords ...float32) {
+	if len(coords) < 2 {
+		return
+	}
+	p.MoveTo(coords[0], coords[1])
+	for i := 2; i+1 < len(coords); i += 2 {
+		p.LineTo(coords[i], coords[i+1])
+	}
+	p.Close()
+}
+
+// segments returns the number of lines for a flattening estimate of n.
+func segments(n float32) int {
+	const max = 256
+	switch {
+	case n < 1:
+		return 1
+	case n > max:
+		return max
+	}
+	return int(math.Ceil(float64(n)))
+}
+
+// arcSegments returns the number of lines approximating an arc of angle
+// radians and radius r within tol.
+func arcSegments(angle, r, tol float32) int {
+	if r <= tol {
+		return segments(angle / (math.Pi / 2))
+	}
+	step := 2 * math.Acos(float64(1-tol/r))
+	return segments(angle / float32(step))
+}
+
+func length(x, y float32) float32 {
+	return float32(math.Sqrt(float64(x*x + y*y)))
+}
+
+func abs(x float32) float32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,194 @@
+package vector
+
+import (
+	"image/color"
+	"j4k.co/gfx/geometry"
+	"math"
+)
+
+// Cap is the shape of the ends of open contours.
+type Cap uint8
+
+const (
+	ButtCap   Cap = iota // ends square at the end point
+	RoundCap             // ends in a half circle around the end point
+	SquareCap            // ends square, half the width past the end point
+)
+
+// Join is the shape of the outside of corners.
+type Join uint8
+
+const (
+	MiterJoin Join = iota // extends the edges to meet at a point
+	RoundJoin             // rounds the corner around the point
+	BevelJoin             // cuts the corner off
+)
+
+// Stroke describes the outline drawn along the contours of a path.
+type Stroke struct {
+	Width float32
+	Cap   Cap
+	Join  Join
+
+	// MiterLimit is the greatest ratio of the length of a miter, from the
+	// point of the corner to the tip, to half the width, beyond which the
+	// corner is beveled instead. It defaults to 4 if zero.
+	MiterLimit float32
+}
+
+// Stroke appends triangles covering the outline of the contours of p to b,
+// which must have been created with VertexFormat, as unindexed vertices of
+// color c. Closed contours are joined all around; open ones end in caps.
+// The segments and joins overlap on the inside of corners.
+func (p *Path) Stroke(b *geometry.Builder, s Stroke, c color.NRGBA) {
+	if s.Width <= 0 {
+		return
+	}
+	if s.MiterLimit == 0 {
+		s.MiterLimit = 4
+	}
+	st := stroker{
+		triangles: triangles{b, c},
+		Stroke:    s,
+		hw:        s.Width / 2,
+		tol:       p.tolerance(),
+	}
+	for _, ct := range p.contours {
+		st.contour(ct)
+	}
+}
+
+type stroker struct {
+	triangles
+	Stroke
+	hw, tol float32
+}
+
+func (st *stroker) contour(ct contour) {
+	pts := ct.pts
+	if ct.closed && len(pts) > 1 && pts[0] == pts[len(pts)-1] {
+		pts = pts[:len(pts)-1]
+	}
+	if len(pts) == 1 {
+		// a dot, drawn as its caps
+		st.cap(pts[0], point{1, 0})
+		st.cap(pts[0], point{-1, 0})
+		return
+	}
+	n := len(pts)
+	segs := n - 1
+	if ct.closed {
+		segs = n
+	}
+	for i := 0; i < segs; i++ {
+		p0, p1 := pts[i], pts[(i+1)%n]
+		o := st.normal(dir(p0, p1))
+		st.add(add(p0, o), add(p1, o), sub(p1, o))
+		st.add(add(p0, o), sub(p1, o), sub(p0, o))
+	}
+	for i := 0; i < n; i++ {
+		if !ct.closed && (i == 0 || i == n-1) {
+			continue
+		}
+		prev, next := pts[(i+n-1)%n], pts[(i+1)%n]
+		st.join(pts[i], dir(prev, pts[i]), dir(pts[i], next))
+	}
+	if !ct.closed {
+		st.cap(pts[0], dir(pts[1], pts[0]))
+		st.cap(pts[n-1], dir(pts[n-2], pts[n-1]))
+	}
+}
+
+// normal returns the left normal of the unit direction d, half the width
+// long.
+func (st *stroker) normal(d point) point {
+	return point{-d[1] * st.hw, d[0] * st.hw}
+}
+
+// join covers the outside of the corner at p between lines in the unit
+// directions d0 and d1.
+func (st *stroker) join(p, d0, d1 point) {
+	cross := d0[0]*d1[1] - d0[1]*d1[0]
+	if cross == 0 && d0[0]*d1[0]+d0[1]*d1[1] > 0 {
+		// straight on
+		return
+	}
+	o0, o1 := st.normal(d0), st.normal(d1)
+	if cross > 0 {
+		// turning towards the left normals, so the outside is on the right
+		o0, o1 = scale(o0, -1), scale(o1, -1)
+	}
+	switch st.Join {
+	case RoundJoin:
+		st.fan(p, o0, o1)
+	case MiterJoin:
+		bis := add(o0, o1)
+		if l := length(bis[0], bis[1]); l > 0 {
+			bis = scale(bis, 1/l)
+			// the tip is where the offset lines meet, along the bisector
+			if cos := (bis[0]*o0[0] + bis[1]*o0[1]) / st.hw; cos > 0 && 1/cos <= st.MiterLimit {
+				tip := add(p, scale(bis, st.hw/cos))
+				st.add(p, add(p, o0), tip)
+				st.add(p, tip, add(p, o1))
+				return
+			}
+		}
+		fallthrough
+	default:
+		st.add(p, add(p, o0), add(p, o1))
+	}
+}
+
+// cap covers the end of a line at p, which runs in the unit direction d out
+// of the line.
+func (st *stroker) cap(p, d point) {
+	o := st.normal(d)
+	switch st.Cap {
+	case RoundCap:
+		st.fan(p, o, scale(o, -1))
+	case SquareCap:
+		e := scale(d, st.hw)
+		st.add(add(p, o), add(add(p, o), e), add(sub(p, o), e))
+		st.add(add(p, o), add(sub(p, o), e), sub(p, o))
+	}
+}
+
+// fan covers the arc around p from p+o0 to p+o1 the shorter way around. If
+// o1 is -o0, the half turn passes o0 turned back by a quarter turn, which is
+// the direction of the line a cap ends.
+func (st *stroker) fan(p, o0, o1 point) {
+	a0 := math.Atan2(float64(o0[1]), float64(o0[0]))
+	sweep := math.Atan2(float64(o0[0]*o1[1]-o0[1]*o1[0]), float64(o0[0]*o1[0]+o0[1]*o1[1]))
+	if o1 == scale(o0, -1) {
+		sweep = -math.Pi
+	}
+	n := arcSegments(float32(math.Abs(sweep)), st.hw, st.tol)
+	prev := add(p, o0)
+	for i := 1; i <= n; i++ {
+		a := a0 + sweep*float64(i)/float64(n)
+		q := point{p[0] + st.hw*float32(math.Cos(a)), p[1] + st.hw*float32(math.Sin(a))}
+		if i == n {
+			q = add(p, o1)
+		}
+		st.add(p, prev, q)
+		prev = q
+	}
+}
+
+func dir(a, b point) point {
+	d := sub(b, a)
+	l := length(d[0], d[1])
+	return point{d[0] / l, d[1] / l}
+}
+
+func add(a, b point) point {
+	return point{a[0] + b[0], a[1] + b[1]}
+}
+
+func sub(a, b point) point {
+	return point{a[0] - b[0], a[1] - b[1]}
+}
+
+func scale(a point, s float32) point {
+	return point{a[0] * s, a[1] * s}
+}
@@ -0,0 +1,154 @@
+package vector_test
+
+import (
+	"fmt"
+	"image/color"
+	"j4k.co/gfx/geometry"
+	"j4k.co/gfx/gfxtest"
+	"j4k.co/gfx/text"
+	"j4k.co/gfx/vector"
+	"math"
+	"testing"
+)
+
+var white = color.NRGBA{255, 255, 255, 255}
+
+// covered reports whether the triangles of b cover x, y.
+func covered(b *geometry.Builder, x, y float32) bool {
+	_, ok := geometry.Raycast(b, [3]float32{x, y, 1}, [3]float32{0, 0, -1})
+	return ok
+}
+
+type sample struct {
+	x, y float32
+	in   bool
+}
+
+func checkSamples(t *testing.T, name string, b *geometry.Builder, samples []sample) {
+	t.Helper()
+	for _, s := range samples {
+		if got := covered(b, s.x, s.y); got != s.in {
+			t.Errorf("%s: covered(%v, %v) = %v, want %v", name, s.x, s.y, got, s.in)
+		}
+	}
+}
+
+func TestFill(t *testing.T) {
+	var nested, hole, star vector.Path
+	nested.Rect(0, 0, 10, 10)
+	nested.Rect(2, 2, 6, 6)
+	hole.Rect(0, 0, 10, 10)
+	hole.Polygon(2, 2, 2, 8, 8, 8, 8, 2)
+	// a pentagram, whose center is wound twice
+	for i := 0; i < 5; i++ {
+		a := float64(i) * 4 * math.Pi / 5
+		star.LineTo(float32(10*math.Sin(a)), float32(-10*math.Cos(a)))
+	}
+	star.Close()
+
+	tests := []struct {
+		name    string
+		p       *vector.Path
+		rule    vector.FillRule
+		samples []sample
+	}{
+		{"nested nonzero", &nested, vector.NonZero, []sample{{1, 1, true}, {5, 5, true}, {11, 5, false}}},
+		{"nested evenodd", &nested, vector.EvenOdd, []sample{{1, 1, true}, {5, 5, false}, {11, 5, false}}},
+		{"hole nonzero", &hole, vector.NonZero, []sample{{1, 1, true}, {5, 5, false}}},
+		{"star nonzero", &star, vector.NonZero, []sample{{0, 0, true}, {0, -8, true}, {0, 9, false}}},
+		{"star evenodd", &star, vector.EvenOdd, []sample{{0, 0, false}, {0, -8, true}, {0, 9, false}}},
+	}
+	for _, test := range tests {
+		b := geometry.NewBuilder(vector.VertexFormat)
+		test.p.Fill(b, test.rule, white)
+		if b.VertexCount()%3 != 0 {
+			t.Errorf("%s: got %d vertices, want whole triangles", test.name, b.VertexCount())
+		}
+		checkSamples(t, test.name, b, test.samples)
+	}
+}
+
+func TestFillCircle(t *testing.T) {
+	var p vector.Path
+	p.Circle(0, 0, 10)
+	b := geometry.NewBuilder(vector.VertexFormat)
+	p.Fill(b, vector.NonZero, white)
+	min, max := b.Bounds()
+	for i := 0; i < 2; i++ {
+		if min[i] < -10.001 || min[i] > -10+vector.DefaultTolerance || max[i] > 10.001 || max[i] < 10-vector.DefaultTolerance {
+			t.Fatalf("got bounds %v, %v, want about ±10", min, max)
+		}
+	}
+	for a := 0.1; a < 2*math.Pi; a += 0.7 {
+		x, y := float32(math.Cos(a)), float32(math.Sin(a))
+		checkSamples(t, "circle", b, []sample{{9 * x, 9 * y, true}, {10.1 * x, 10.1 * y, false}})
+	}
+}
+
+func TestStroke(t *testing.T) {
+	var line, corner vector.Path
+	line.MoveTo(0, 0)
+	line.LineTo(10, 0)
+	corner.MoveTo(0, 0)
+	corner.LineTo(10, 0)
+	corner.LineTo(10, 10)
+
+	tests := []struct {
+		name    string
+		p       *vector.Path
+		s       vector.Stroke
+		samples []sample
+	}{
+		{"butt", &line, vector.Stroke{Width: 2}, []sample{{5, 0.9, true}, {5, -1.1, false}, {-0.1, 0, false}, {10.1, 0, false}}},
+		{"square", &line, vector.Stroke{Width: 2, Cap: vector.SquareCap}, []sample{{-0.9, 0.9, true}, {10.9, -0.9, true}, {-1.1, 0, false}}},
+		{"round", &line, vector.Stroke{Width: 2, Cap: vector.RoundCap}, []sample{{-0.8, 0, true}, {10, 0.9, true}, {-0.8, 0.8, false}}},
+		{"miter", &corner, vector.Stroke{Width: 2}, []sample{{10.9, -0.9, true}, {9.1, 0.9, true}, {11.1, 0, false}}},
+		{"miter limit", &corner, vector.Stroke{Width: 2, MiterLimit: 1.2}, []sample{{10.9, -0.9, false}, {10.4, -0.4, true}}},
+		{"bevel", &corner, vector.Stroke{Width: 2, Join: vector.BevelJoin}, []sample{{10.9, -0.9, false}, {10.4, -0.4, true}}},
+		{"round join", &corner, vector.Stroke{Width: 2, Join: vector.RoundJoin}, []sample{{10.6, -0.6, true}, {10.8, -0.8, false}}},
+	}
+	for _, test := range tests {
+		b := geometry.NewBuilder(vector.VertexFormat)
+		test.p.Stroke(b, test.s, white)
+		checkSamples(t, test.name, b, test.samples)
+	}
+}
+
+func TestDrawer(t *testing.T) {
+	rec := gfxtest.Install()
+	defer rec.Uninstall()
+
+	d, err := vector.NewDrawer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Delete()
+
+	var p vector.Path
+	p.MoveTo(10, 10)
+	p.QuadTo(50, 0, 90, 40)
+	p.Close()
+	d.Fill(&p, vector.NonZero, white)
+	d.Stroke(&p, vector.Stroke{Width: 3, Join: vector.RoundJoin}, white)
+
+	b := geometry.NewBuilder(vector.VertexFormat)
+	p.Fill(b, vector.NonZero, white)
+	p.Stroke(b, vector.Stroke{Width: 3, Join: vector.RoundJoin}, white)
+
+	rec.Reset()
+	if err := d.Flush(text.Ortho(640, 480)); err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("DrawArrays(TRIANGLES, 0, %d)", b.VertexCount())
+	if draws := rec.Draws(); len(draws) != 1 || draws[0].String() != want {
+		t.Fatalf("got draws %v, want one %s", draws, want)
+	}
+
+	rec.Reset()
+	if err := d.Flush(text.Ortho(640, 480)); err != nil {
+		t.Fatal(err)
+	}
+	if draws := rec.Draws(); len(draws) != 0 {
+		t.Errorf("got draws %v after the paths were flushed", draws)
+	}
+}